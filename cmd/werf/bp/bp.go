@@ -6,9 +6,12 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/download"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/logger"
@@ -31,24 +34,35 @@ var CmdData struct {
 
 	IntrospectBeforeError bool
 	IntrospectAfterError  bool
+
+	ReadOnlyStages bool
+
+	RefreshBaseImages bool
+
+	CacheFromTag string
+
+	ReportPath string
 }
 
 var CommonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "bp [IMAGE_NAME...]",
-		Short: "Build then push built images into Docker registry",
+		Use:     "bp [IMAGE_NAME...]",
+		Aliases: []string{"build-and-publish"},
+		Short:   "Build then push built images into Docker registry",
 		Long: common.GetLongCommandDescription(`Build then push images from werf.yaml.
 
 Images will be tagged automatically with the names REPO/IMAGE_NAME:TAG. These tags will be deleted after push. See more info about images naming: https://flant.github.io/werf/reference/registry/image_naming.html.
 
 The result of bp command is a stages cache for images and named images pushed into the docker registry.
 
-If one or more IMAGE_NAME parameters specified, werf will build and push only these images from werf.yaml.`),
+If one or more IMAGE_NAME parameters specified, werf will build and push only these images from werf.yaml.
+
+Command is also available as werf build-and-publish. With option --report-path werf writes the report of the published images (image name, repository, tags and full references with digests) in json format, which can be consumed by the deploy stage of the pipeline or by external CD tools.`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfHome, common.WerfTmp),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfContainerRuntime, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfHomeQuota, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch, common.WerfTakeSubmodulesFromWorkTree, common.WerfRefreshDownloads, common.WerfForceRefresh, common.WerfPatchesTmpDir, common.WerfArchivesTmpDir, common.WerfWorkTreesDir, common.WerfAutoCreateRepo, common.WerfAutoCreateRepoLifecyclePolicy, common.WerfScanner, common.WerfSignImages, common.WerfSignKey, common.WerfHostMaxBuilds, common.WerfProjectMaxBuilds, common.WerfHostMaxPushes, common.WerfProjectMaxPushes, common.WerfStageTimeout, common.WerfStageRetries),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if CmdData.PullUsername == "" {
@@ -73,9 +87,28 @@ If one or more IMAGE_NAME parameters specified, werf will build and push only th
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupTakeSubmodulesFromWorkTree(&CommonCmdData, cmd)
+	common.SetupRefreshDownloads(&CommonCmdData, cmd)
+	common.SetupForceRefresh(&CommonCmdData, cmd)
+	common.SetupGitTmpDirs(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+	common.SetupAutoCreateRepo(&CommonCmdData, cmd)
+	common.SetupSign(&CommonCmdData, cmd)
+	common.SetupStagesRepo(&CommonCmdData, cmd)
+	common.SetupAddLabels(&CommonCmdData, cmd)
+	common.SetupCommitSignatureKeyrings(&CommonCmdData, cmd)
+	common.SetupImagesOrder(&CommonCmdData, cmd)
+	common.SetupBuildSecrets(&CommonCmdData, cmd)
+	common.SetupScan(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name to push images to. CI_REGISTRY_IMAGE will be used by default if available.")
 	cmd.Flags().BoolVarP(&CmdData.WithStages, "with-stages", "", false, "Push images with stages cache")
@@ -90,12 +123,26 @@ If one or more IMAGE_NAME parameters specified, werf will build and push only th
 	cmd.Flags().BoolVarP(&CmdData.IntrospectAfterError, "introspect-error", "", false, "Introspect failed stage in the state, right after running failed assembly instruction")
 	cmd.Flags().BoolVarP(&CmdData.IntrospectBeforeError, "introspect-before-error", "", false, "Introspect failed stage in the clean state, before running all assembly instructions of the stage")
 
+	common.SetupStageTimeoutAndRetries(&CommonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&CmdData.ReadOnlyStages, "read-only-stages", "", false, "Forbid building of stages: command fails with the list of missing stages signatures if some stages do not exist locally or in the stages repo")
+
+	cmd.Flags().StringVarP(&CmdData.CacheFromTag, "cache-from-tag", "", "", "Before building pull previously published TAG of each image from the repo and import it as the stage, which the image has been built from, if signature of this stage is not changed (for hosts without local stages cache)")
+
+	cmd.Flags().StringVarP(&CmdData.ReportPath, "report-path", "", "", "Write report of the published images with digests into specified json file")
+
+	cmd.Flags().BoolVarP(&CmdData.RefreshBaseImages, "refresh-base-images", "", false, "Resolve digests of base images specified by tag again (pull actual images) instead of using digests pinned in werf-base-images.lock file of the project directory")
+
 	common.SetupTag(&CommonCmdData, cmd)
 
 	return cmd
 }
 
 func runBP(imagesToProcess []string) error {
+	if CmdData.ReadOnlyStages && CmdData.CacheFromTag != "" {
+		return fmt.Errorf("--cache-from-tag option cannot be used with --read-only-stages")
+	}
+
 	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
 		return fmt.Errorf("initialization error: %s", err)
 	}
@@ -108,6 +155,10 @@ func runBP(imagesToProcess []string) error {
 		return err
 	}
 
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
 		return err
 	}
@@ -117,14 +168,22 @@ func runBP(imagesToProcess []string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	projectBuildDir, err := common.GetProjectBuildDir(projectName)
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
 	if err != nil {
 		return fmt.Errorf("getting project build dir failed: %s", err)
 	}
@@ -135,7 +194,7 @@ func runBP(imagesToProcess []string) error {
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	repo, err := common.GetRequiredRepoName(projectName, CmdData.Repo)
+	repo, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 	if err != nil {
 		return err
 	}
@@ -145,7 +204,7 @@ func runBP(imagesToProcess []string) error {
 		return err
 	}
 
-	if err := ssh_agent.Init(*CommonCmdData.SSHKeys); err != nil {
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
 		return fmt.Errorf("cannot initialize ssh agent: %s", err)
 	}
 	defer func() {
@@ -155,7 +214,17 @@ func runBP(imagesToProcess []string) error {
 		}
 	}()
 
-	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir)
+	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	stageTimeout, err := common.GetStageTimeout(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stageRetries, err := common.GetStageRetries(&CommonCmdData)
 	if err != nil {
 		return err
 	}
@@ -164,15 +233,82 @@ func runBP(imagesToProcess []string) error {
 		ImageBuildOptions: image.BuildOptions{
 			IntrospectAfterError:  CmdData.IntrospectAfterError,
 			IntrospectBeforeError: CmdData.IntrospectBeforeError,
+			Timeout:               stageTimeout,
+			Retries:               stageRetries,
 		},
+		ReadOnlyStages:    CmdData.ReadOnlyStages,
+		RefreshBaseImages: CmdData.RefreshBaseImages,
+		CacheFromRepo:     repo,
+		CacheFromTag:      CmdData.CacheFromTag,
 	}
 
 	pushOpts := build.PushOptions{TagOptions: tagOpts, WithStages: CmdData.WithStages}
 
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	labels, err := common.GetAddLabels(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	buildSecrets, err := common.GetBuildSecrets(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	scanOpts, err := common.GetScanOptions(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	signOpts, err := common.GetSignOptions(&CommonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+	download.Refresh = common.GetRefreshDownloads(&CommonCmdData)
+	git_repo.ForceRefresh = common.GetForceRefresh(&CommonCmdData)
+	common.SetGitTmpDirs(&CommonCmdData)
+
 	c := build.NewConveyor(werfConfig, imagesToProcess, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
-	if err = c.BP(repo, buildOpts, pushOpts); err != nil {
+	c.SetPlatforms(platforms)
+	if scanOpts != nil {
+		c.SetScanOptions(*scanOpts)
+	}
+	if opts := common.GetAutoCreateRepoOptions(&CommonCmdData); opts != nil {
+		c.SetAutoCreateRepo(*opts)
+	}
+	if signOpts != nil {
+		c.SetSignOptions(*signOpts)
+	}
+	c.SetLabels(labels)
+	c.SetCommitSignatureKeyrings(*CommonCmdData.CommitSignatureKeyrings)
+	c.SetBuildSecrets(buildSecrets)
+	c.SetImagesOrder(*CommonCmdData.ImagesOrder)
+	c.SetTakeSubmodulesFromWorkTree(common.GetTakeSubmodulesFromWorkTree(&CommonCmdData))
+	if stagesRepo := common.GetStagesRepo(&CommonCmdData, werfConfig); stagesRepo != "" {
+		c.SetStagesRepo(stagesRepo)
+	}
+	if err = c.BP(werf.GetContext(), repo, buildOpts, pushOpts); err != nil {
 		return err
 	}
 
+	if CmdData.ReportPath != "" {
+		report, err := c.GetImagesReport()
+		if err != nil {
+			return fmt.Errorf("cannot get images report: %s", err)
+		}
+
+		if err := build.WriteImagesReport(CmdData.ReportPath, report); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -2,13 +2,19 @@ package build
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/download"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/logger"
@@ -24,6 +30,23 @@ var CmdData struct {
 
 	IntrospectBeforeError bool
 	IntrospectAfterError  bool
+
+	ReadOnlyStages bool
+
+	RefreshBaseImages bool
+
+	PrintOrder bool
+
+	ShowStageLogs string
+
+	StagesFrom string
+
+	ReportChanged     string
+	ReportChangedFile string
+
+	Follow         bool
+	FollowInterval time.Duration
+	FollowDebounce time.Duration
 }
 
 var CommonCmdData common.CmdData
@@ -36,10 +59,20 @@ func NewCmd() *cobra.Command {
 
 The result of build command is a stages cache for images.
 
-If one or more IMAGE_NAME parameters specified, werf will build only these images from werf.yaml.`),
+If one or more IMAGE_NAME parameters specified, werf will build only these images from werf.yaml.
+
+With options --git-url and --git-commit werf reads werf.yaml and sources directly from the specified commit of the remote git repo, project directory is not used.
+
+With option --follow werf builds images and then keeps watching the project git repo: when new commits change files of local git mappings of the images (or werf.yaml changes), werf rebuilds images and prints a short summary of each rebuild. Uncommitted changes are not built, because git stages are built from commits (use werf dev command to sync uncommitted changes into a running container).
+
+With option --stages-from STAGE werf builds the specified stage and all following stages of IMAGE_NAME images again, even if these stages exist in the stages cache, without bumping cache versions in werf.yaml. Stages of the images, which are based on or import the rebuilt images, are rebuilt as well.
+
+Output of the assembly instructions of each built stage is saved in werf home, so that output of the failed stage can be inspected without rebuilding: option --show-stage-logs STAGE prints saved output of the latest build of the stage.
+
+With option --report-changed FROM..TO werf compares werf.yaml images of two commits of the project git repo without building and docker: image is changed if its config or files of its git mappings are changed between commits, so CI can test and deploy only affected images. Option --report-changed-file saves the report in json format.`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfHome, common.WerfTmp),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfContainerRuntime, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfHomeQuota, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch, common.WerfTakeSubmodulesFromWorkTree, common.WerfRefreshDownloads, common.WerfForceRefresh, common.WerfPatchesTmpDir, common.WerfArchivesTmpDir, common.WerfWorkTreesDir, common.WerfScanner, common.WerfHostMaxBuilds, common.WerfProjectMaxBuilds, common.WerfHostMaxPushes, common.WerfProjectMaxPushes, common.WerfStageTimeout, common.WerfStageRetries),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runBuild(args)
@@ -51,9 +84,26 @@ If one or more IMAGE_NAME parameters specified, werf will build only these image
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupTakeSubmodulesFromWorkTree(&CommonCmdData, cmd)
+	common.SetupRefreshDownloads(&CommonCmdData, cmd)
+	common.SetupForceRefresh(&CommonCmdData, cmd)
+	common.SetupGitTmpDirs(&CommonCmdData, cmd)
+	common.SetupGitSource(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+	common.SetupStagesRepo(&CommonCmdData, cmd)
+	common.SetupCommitSignatureKeyrings(&CommonCmdData, cmd)
+	common.SetupImagesOrder(&CommonCmdData, cmd)
+	common.SetupBuildSecrets(&CommonCmdData, cmd)
+	common.SetupScan(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.PullUsername, "pull-username", "", "", "Docker registry username to authorize pull of base images")
 	cmd.Flags().StringVarP(&CmdData.PullPassword, "pull-password", "", "", "Docker registry password to authorize pull of base images")
@@ -63,6 +113,25 @@ If one or more IMAGE_NAME parameters specified, werf will build only these image
 	cmd.Flags().BoolVarP(&CmdData.IntrospectAfterError, "introspect-error", "", false, "Introspect failed stage in the state, right after running failed assembly instruction")
 	cmd.Flags().BoolVarP(&CmdData.IntrospectBeforeError, "introspect-before-error", "", false, "Introspect failed stage in the clean state, before running all assembly instructions of the stage")
 
+	common.SetupStageTimeoutAndRetries(&CommonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&CmdData.ReadOnlyStages, "read-only-stages", "", false, "Forbid building of stages: command fails with the list of missing stages signatures if some stages do not exist locally or in the stages repo")
+
+	cmd.Flags().BoolVarP(&CmdData.RefreshBaseImages, "refresh-base-images", "", false, "Resolve digests of base images specified by tag again (pull actual images) instead of using digests pinned in werf-base-images.lock file of the project directory")
+
+	cmd.Flags().BoolVarP(&CmdData.PrintOrder, "print-order", "", false, "Print images build order with reasons and exit without building")
+
+	cmd.Flags().StringVarP(&CmdData.ShowStageLogs, "show-stage-logs", "", "", "Print saved output of the latest build of the specified STAGE (e.g. install or setup) of the images and exit without building")
+
+	cmd.Flags().StringVarP(&CmdData.StagesFrom, "stages-from", "", "", "Build the specified STAGE (e.g. install or setup) and all following stages of the specified images again ignoring existing stages cache")
+
+	cmd.Flags().StringVarP(&CmdData.ReportChanged, "report-changed", "", "", "Report images, which config or git mappings files differ between FROM and TO commits of the project git repo (FROM..TO or FROM, TO is HEAD by default), and exit without building")
+	cmd.Flags().StringVarP(&CmdData.ReportChangedFile, "report-changed-file", "", "", "Write --report-changed report into specified json file")
+
+	cmd.Flags().BoolVarP(&CmdData.Follow, "follow", "", false, "Rebuild images on new commits of the project git repo and werf.yaml changes until command is terminated")
+	cmd.Flags().DurationVarP(&CmdData.FollowInterval, "follow-interval", "", 2*time.Second, "Interval of checking the project git repo for changes in --follow mode")
+	cmd.Flags().DurationVarP(&CmdData.FollowDebounce, "follow-debounce", "", time.Second, "Delay of rebuild in --follow mode: rebuild starts when the project git repo has not changed during this period")
+
 	return cmd
 }
 
@@ -79,6 +148,10 @@ func runBuild(imagesToProcess []string) error {
 		return err
 	}
 
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
 		return err
 	}
@@ -88,48 +161,182 @@ func runBuild(imagesToProcess []string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	ownGitRepo, err := common.GetOwnRemoteGitRepo(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	if CmdData.StagesFrom != "" {
+		if !isStageName(CmdData.StagesFrom) {
+			return fmt.Errorf("bad stage name '%s' specified: expected one of %s", CmdData.StagesFrom, strings.Join(config.StageNames, ", "))
+		}
+
+		if len(imagesToProcess) == 0 {
+			return fmt.Errorf("--stages-from option requires IMAGE_NAME parameters")
+		}
+
+		if CmdData.Follow {
+			return fmt.Errorf("--stages-from option cannot be used with --follow option")
+		}
+	}
+
+	if CmdData.ReportChanged != "" {
+		if ownGitRepo != nil {
+			return fmt.Errorf("--report-changed option cannot be used with --git-url option")
+		}
+
+		return reportChanged(projectDir, imagesToProcess, CmdData.ReportChanged)
+	}
+
+	if CmdData.ReportChangedFile != "" {
+		return fmt.Errorf("--report-changed-file option requires --report-changed")
+	}
+
+	// printing of the build order and stage logs does not require follow mode
+	if CmdData.Follow && !CmdData.PrintOrder && CmdData.ShowStageLogs == "" {
+		if ownGitRepo != nil {
+			return fmt.Errorf("--follow option cannot be used with --git-url option")
+		}
+
+		return followProject(projectDir, imagesToProcess)
+	}
+
+	return buildProject(projectDir, ownGitRepo, imagesToProcess)
+}
+
+func buildProject(projectDir string, ownGitRepo *git_repo.Remote, imagesToProcess []string) error {
+	var werfConfig *config.WerfConfig
+	var err error
+	if ownGitRepo != nil {
+		werfConfig, err = config.ParseWerfConfigFromGitCommit(ownGitRepo, *CommonCmdData.GitCommit, common.GetConfigPath(&CommonCmdData), config.ResolveConfigOverlayPaths(projectDir, common.GetConfigOverlays(&CommonCmdData))...)
+	} else {
+		werfConfig, err = config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	projectBuildDir, err := common.GetProjectBuildDir(projectName)
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
 	if err != nil {
 		return fmt.Errorf("getting project build dir failed: %s", err)
 	}
 
+	if CmdData.ShowStageLogs != "" {
+		return showStageLogs(projectBuildDir, werfConfig, imagesToProcess, CmdData.ShowStageLogs)
+	}
+
 	projectTmpDir, err := project_tmp_dir.Get()
 	if err != nil {
 		return fmt.Errorf("getting project tmp dir failed: %s", err)
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	dockerAuthorizer, err := docker_authorizer.GetBuildDockerAuthorizer(projectTmpDir, CmdData.PullUsername, CmdData.PullPassword)
+	stagesRepo := common.GetStagesRepo(&CommonCmdData, werfConfig)
+
+	var dockerAuthorizer *docker_authorizer.DockerAuthorizer
+	if stagesRepo != "" {
+		dockerAuthorizer, err = docker_authorizer.GetBPDockerAuthorizer(projectTmpDir, CmdData.PullUsername, CmdData.PullPassword, CmdData.PullUsername, CmdData.PullPassword, stagesRepo)
+	} else {
+		dockerAuthorizer, err = docker_authorizer.GetBuildDockerAuthorizer(projectTmpDir, CmdData.PullUsername, CmdData.PullPassword)
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := ssh_agent.Init(*CommonCmdData.SSHKeys); err != nil {
-		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	stageTimeout, err := common.GetStageTimeout(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stageRetries, err := common.GetStageRetries(&CommonCmdData)
+	if err != nil {
+		return err
 	}
-	defer func() {
-		err := ssh_agent.Terminate()
-		if err != nil {
-			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
-		}
-	}()
 
 	buildOpts := build.BuildOptions{
 		ImageBuildOptions: image.BuildOptions{
 			IntrospectAfterError:  CmdData.IntrospectAfterError,
 			IntrospectBeforeError: CmdData.IntrospectBeforeError,
+			Timeout:               stageTimeout,
+			Retries:               stageRetries,
 		},
+		ReadOnlyStages:    CmdData.ReadOnlyStages,
+		RefreshBaseImages: CmdData.RefreshBaseImages,
+	}
+
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	buildSecrets, err := common.GetBuildSecrets(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	scanOpts, err := common.GetScanOptions(&CommonCmdData)
+	if err != nil {
+		return err
 	}
 
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+	download.Refresh = common.GetRefreshDownloads(&CommonCmdData)
+	git_repo.ForceRefresh = common.GetForceRefresh(&CommonCmdData)
+	common.SetGitTmpDirs(&CommonCmdData)
+
 	c := build.NewConveyor(werfConfig, imagesToProcess, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
-	if err = c.Build(buildOpts); err != nil {
+	c.SetPlatforms(platforms)
+	if scanOpts != nil {
+		c.SetScanOptions(*scanOpts)
+	}
+	c.SetCommitSignatureKeyrings(*CommonCmdData.CommitSignatureKeyrings)
+	c.SetBuildSecrets(buildSecrets)
+	c.SetImagesOrder(*CommonCmdData.ImagesOrder)
+	c.SetTakeSubmodulesFromWorkTree(common.GetTakeSubmodulesFromWorkTree(&CommonCmdData))
+	if CmdData.StagesFrom != "" {
+		c.SetRebuildStagesFrom(stage.StageName(CmdData.StagesFrom))
+	}
+	if stagesRepo != "" {
+		c.SetStagesRepo(stagesRepo)
+	}
+	if ownGitRepo != nil {
+		c.SetOwnGitRepo(ownGitRepo, *CommonCmdData.GitCommit)
+	}
+
+	if CmdData.PrintOrder {
+		imagesOrder, err := c.ImagesOrder()
+		if err != nil {
+			return err
+		}
+
+		build.LogImagesOrder(imagesOrder)
+
+		return nil
+	}
+
+	if err = c.Build(werf.GetContext(), buildOpts); err != nil {
 		return err
 	}
 
@@ -0,0 +1,208 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// followState is a state of the project, which is used by the build: HEAD commit and rendered werf config
+type followState struct {
+	HeadCommit string
+	WerfConfig *config.WerfConfig
+}
+
+func (s *followState) Equal(other *followState) bool {
+	return s.HeadCommit == other.HeadCommit && s.WerfConfig.Checksum() == other.WerfConfig.Checksum()
+}
+
+func followProject(projectDir string, imagesToProcess []string) error {
+	ctx := werf.GetContext()
+	localGitRepo := &git_repo.Local{Path: projectDir, GitDir: filepath.Join(projectDir, ".git")}
+
+	state, err := getFollowState(projectDir, localGitRepo)
+	if err != nil {
+		return err
+	}
+
+	rebuildNumber := 0
+	rebuild := func(reason string) {
+		rebuildNumber++
+		startedAt := time.Now()
+
+		err := buildProject(projectDir, nil, imagesToProcess)
+
+		summary := fmt.Sprintf("Build #%d (commit %s, %s)", rebuildNumber, shortCommit(state.HeadCommit), reason)
+		duration := time.Since(startedAt).Seconds()
+		if err != nil {
+			logger.LogWarningF("%s: FAILED in %0.2f seconds: %s\n", summary, duration, err)
+		} else {
+			logger.LogInfoF("%s: DONE in %0.2f seconds\n", summary, duration)
+		}
+	}
+
+	rebuild("initial build")
+
+	logger.LogInfoF("Following changes of %s\n", projectDir)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(CmdData.FollowInterval):
+		}
+
+		newState, err := getFollowState(projectDir, localGitRepo)
+		if err != nil {
+			logger.LogWarningF("WARNING: %s\n", err)
+			continue
+		}
+
+		if newState.Equal(state) {
+			continue
+		}
+
+		newState, err = waitFollowStateSettled(projectDir, localGitRepo, newState)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			logger.LogWarningF("WARNING: %s\n", err)
+			continue
+		}
+
+		reason, err := getRebuildReason(localGitRepo, state, newState, imagesToProcess)
+		if err != nil {
+			logger.LogWarningF("WARNING: %s\n", err)
+			continue
+		}
+
+		oldCommit := state.HeadCommit
+		state = newState
+
+		if reason == "" {
+			logger.LogInfoF("Commit %s has no changes in git mappings since %s: skipping build\n", shortCommit(state.HeadCommit), shortCommit(oldCommit))
+			continue
+		}
+
+		rebuild(reason)
+	}
+}
+
+func getFollowState(projectDir string, localGitRepo *git_repo.Local) (*followState, error) {
+	headCommit, err := localGitRepo.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	return &followState{HeadCommit: headCommit, WerfConfig: werfConfig}, nil
+}
+
+// waitFollowStateSettled debounces series of changes (e.g. rebase or several commits in a row)
+func waitFollowStateSettled(projectDir string, localGitRepo *git_repo.Local, state *followState) (*followState, error) {
+	ctx := werf.GetContext()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(CmdData.FollowDebounce):
+		}
+
+		newState, err := getFollowState(projectDir, localGitRepo)
+		if err != nil {
+			return nil, err
+		}
+
+		if newState.Equal(state) {
+			return newState, nil
+		}
+
+		state = newState
+	}
+}
+
+// getRebuildReason returns empty string if changes between states do not affect images
+func getRebuildReason(localGitRepo *git_repo.Local, oldState, newState *followState, imagesToProcess []string) (string, error) {
+	if oldState.WerfConfig.Checksum() != newState.WerfConfig.Checksum() {
+		return "werf config changed", nil
+	}
+
+	changedFiles, err := true_git.CommitsChangedFiles(werf.GetContext(), localGitRepo.GitDir, oldState.HeadCommit, newState.HeadCommit)
+	if err != nil {
+		return "", err
+	}
+
+	var relevantFiles int
+	for _, changedFile := range changedFiles {
+		for _, localGit := range getLocalGitMappings(newState.WerfConfig, imagesToProcess) {
+			if true_git.IsFilePathValid("/"+changedFile, localGit.Add, localGit.IncludePaths, localGit.ExcludePaths) {
+				relevantFiles++
+				break
+			}
+		}
+	}
+
+	if relevantFiles == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%d changed files", relevantFiles), nil
+}
+
+func getLocalGitMappings(werfConfig *config.WerfConfig, imagesToProcess []string) []*config.GitLocal {
+	var res []*config.GitLocal
+
+	for _, imageConfig := range werfConfig.Images {
+		if len(imagesToProcess) != 0 && !isImageToProcess(imageConfig.Name, imagesToProcess) {
+			continue
+		}
+
+		for _, imageInterface := range imageConfig.ImageTree() {
+			var imageBaseConfig *config.ImageBase
+			switch imageInterfaceConfig := imageInterface.(type) {
+			case *config.Image:
+				imageBaseConfig = imageInterfaceConfig.ImageBase
+			case *config.ImageArtifact:
+				imageBaseConfig = imageInterfaceConfig.ImageBase
+			}
+
+			if imageBaseConfig != nil && imageBaseConfig.Git != nil {
+				res = append(res, imageBaseConfig.Git.Local...)
+			}
+		}
+	}
+
+	return res
+}
+
+func isImageToProcess(name string, imagesToProcess []string) bool {
+	for _, imageName := range imagesToProcess {
+		if imageName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+
+	return commit
+}
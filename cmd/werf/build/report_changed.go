@@ -0,0 +1,65 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// reportChanged compares images of werf.yaml for two commits of the project git repo without building and without docker.
+// Commits are specified as FROM..TO or FROM, TO is HEAD by default.
+func reportChanged(projectDir string, imagesToProcess []string, commits string) error {
+	localGitRepo := &git_repo.Local{
+		Base:   git_repo.Base{Name: "own"},
+		Path:   projectDir,
+		GitDir: filepath.Join(projectDir, ".git"),
+	}
+
+	fromRevision, toRevision := commits, "HEAD"
+	if parts := strings.SplitN(commits, "..", 2); len(parts) == 2 {
+		fromRevision, toRevision = parts[0], parts[1]
+	}
+
+	if fromRevision == "" || toRevision == "" {
+		return fmt.Errorf("bad --report-changed '%s': expected FROM..TO or FROM", commits)
+	}
+
+	fromCommit, err := localGitRepo.ResolveCommit(werf.GetContext(), fromRevision)
+	if err != nil {
+		return fmt.Errorf("cannot resolve commit `%s`: %s", fromRevision, err)
+	}
+
+	toCommit, err := localGitRepo.ResolveCommit(werf.GetContext(), toRevision)
+	if err != nil {
+		return fmt.Errorf("cannot resolve commit `%s`: %s", toRevision, err)
+	}
+
+	fromConfig, err := config.ParseWerfConfigFromLocalGitCommit(localGitRepo, fromCommit, common.GetConfigPath(&CommonCmdData), config.ResolveConfigOverlayPaths(projectDir, common.GetConfigOverlays(&CommonCmdData))...)
+	if err != nil {
+		return fmt.Errorf("commit `%s`: cannot parse werf config: %s", fromCommit, err)
+	}
+
+	toConfig, err := config.ParseWerfConfigFromLocalGitCommit(localGitRepo, toCommit, common.GetConfigPath(&CommonCmdData), config.ResolveConfigOverlayPaths(projectDir, common.GetConfigOverlays(&CommonCmdData))...)
+	if err != nil {
+		return fmt.Errorf("commit `%s`: cannot parse werf config: %s", toCommit, err)
+	}
+
+	report, err := build.NewChangedImagesReport(werf.GetContext(), localGitRepo, fromCommit, toCommit, fromConfig, toConfig, imagesToProcess)
+	if err != nil {
+		return err
+	}
+
+	build.LogChangedImagesReport(report)
+
+	if CmdData.ReportChangedFile != "" {
+		return build.WriteChangedImagesReport(CmdData.ReportChangedFile, report)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+)
+
+// showStageLogs prints saved output of the latest build of the stage for the specified images or all images of werf.yaml
+func showStageLogs(projectBuildDir string, werfConfig *config.WerfConfig, imagesToProcess []string, stageName string) error {
+	if !isStageName(stageName) {
+		return fmt.Errorf("bad stage name '%s' specified: expected one of %s", stageName, strings.Join(config.StageNames, ", "))
+	}
+
+	imageNames := imagesToProcess
+	if len(imageNames) == 0 {
+		for _, image := range werfConfig.Images {
+			imageNames = append(imageNames, image.Name)
+		}
+	}
+
+	found := false
+	for _, imageName := range imageNames {
+		logPath := build.GetStageLogPath(projectBuildDir, imageName, stageName)
+
+		finfo, err := os.Stat(logPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(logPath)
+		if err != nil {
+			return fmt.Errorf("cannot read stage log %s: %s", logPath, err)
+		}
+
+		displayName := imageName
+		if displayName == "" {
+			displayName = "~"
+		}
+
+		fmt.Printf("# Image %s stage %s log (built at %s)\n", displayName, stageName, finfo.ModTime().Format(time.RFC3339))
+		fmt.Print(string(data))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			fmt.Println()
+		}
+
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("no saved logs of stage '%s': stage has not been built on this host yet", stageName)
+	}
+
+	return nil
+}
+
+func isStageName(name string) bool {
+	for _, stageName := range config.StageNames {
+		if stageName == name {
+			return true
+		}
+	}
+
+	return false
+}
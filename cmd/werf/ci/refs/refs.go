@@ -0,0 +1,101 @@
+package refs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	WithoutBranches bool
+	WithoutTags     bool
+}
+
+var CommonCmdData common.CmdData
+
+type refsReport struct {
+	Branches []*git_repo.Ref `json:"branches"`
+	Tags     []*git_repo.Ref `json:"tags"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "refs",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print branches and tags of the project git repo as JSON",
+		Long: common.GetLongCommandDescription(`Print branches and tags of the project git repo as JSON.
+
+Branches of origin remote and lightweight and annotated tags are printed with the commit, which they point to, commit author and committer time. Annotated tags also contain the tag object with the tagger and the original target.
+
+Output is used by custom cleanup policies and other tools, which need the same view of the repo as werf cleanup (e.g. to decide, which published images are still needed).`),
+		Example: `  $ werf ci refs --without-tags | jq -r '.branches[] | "\(.name) \(.commit) \(.committerTime)"'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRefs()
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&CmdData.WithoutBranches, "without-branches", "", false, "Do not print branches")
+	cmd.Flags().BoolVarP(&CmdData.WithoutTags, "without-tags", "", false, "Do not print tags")
+
+	return cmd
+}
+
+func runRefs() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	gitDir := path.Join(projectDir, ".git")
+	if exist, err := util.DirExists(gitDir); err != nil {
+		return err
+	} else if !exist {
+		return fmt.Errorf("project dir %s is not a git repo", projectDir)
+	}
+
+	localGitRepo := &git_repo.Local{Path: projectDir, GitDir: gitDir}
+
+	report := &refsReport{Branches: []*git_repo.Ref{}, Tags: []*git_repo.Ref{}}
+
+	if !CmdData.WithoutBranches {
+		report.Branches, err = localGitRepo.RemoteBranchesRefs()
+		if err != nil {
+			return fmt.Errorf("cannot get git branches: %s", err)
+		}
+	}
+
+	if !CmdData.WithoutTags {
+		report.Tags, err = localGitRepo.TagsRefs()
+		if err != nil {
+			return fmt.Errorf("cannot get git tags: %s", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", data)
+
+	return nil
+}
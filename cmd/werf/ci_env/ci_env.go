@@ -0,0 +1,64 @@
+package ci_env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/ci_env"
+)
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "ci-env",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print recommended werf envs for the detected CI system",
+		Long: common.GetLongCommandDescription(`Print recommended werf envs for the detected CI system (GitLab CI, GitHub Actions or CircleCI).
+
+Command prints export statements, which should be evaluated in the shell before running other werf commands: werf uses GitLab CI variables natively, so variables of other CI systems are converted into them.`),
+		Example: `  $ eval $(werf ci-env)`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCIEnv()
+		},
+	}
+
+	return cmd
+}
+
+func runCIEnv() error {
+	system := ci_env.Detect()
+	if system == nil {
+		return fmt.Errorf("no supported CI system detected")
+	}
+
+	fmt.Printf("# Detected CI system: %s\n", system.Name())
+
+	envs := system.Envs()
+
+	var names []string
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("export %s=%s\n", name, shellQuote(envs[name]))
+	}
+
+	if registry := system.Registry(); registry != "" {
+		fmt.Printf("# Docker registry %s is used for autologin\n", registry)
+	}
+
+	if envs["CI_COMMIT_TAG"] != "" || envs["CI_COMMIT_REF_NAME"] != "" {
+		fmt.Printf("# Recommended tag option: --tag-ci\n")
+	}
+
+	return nil
+}
+
+func shellQuote(value string) string {
+	return fmt.Sprintf("'%s'", strings.Replace(value, "'", `'\''`, -1))
+}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"path"
 
-	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker_authorizer"
 	"github.com/flant/werf/pkg/cleanup"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/git_repo"
@@ -23,7 +23,11 @@ var CmdData struct {
 	RegistryUsername string
 	RegistryPassword string
 
-	WithoutKube bool
+	WithoutKube     bool
+	KubeContexts    []string
+	AllKubeContexts bool
+
+	DiscoverImagesRepos bool
 
 	DryRun bool
 }
@@ -42,7 +46,7 @@ See more info about cleanup: https://flant.github.io/werf/reference/registry/cle
 
 Command should run from the project directory, where werf.yaml file reside.`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfGitTagsExpiryDatePeriodPolicy, common.WerfGitTagsLimitPolicy, common.WerfGitCommitsExpiryDatePeriodPolicy, common.WerfGitCommitsLimitPolicy, common.WerfCleanupRegistryPassword, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfHome),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfGitTagsExpiryDatePeriodPolicy, common.WerfGitTagsLimitPolicy, common.WerfGitCommitsExpiryDatePeriodPolicy, common.WerfGitCommitsLimitPolicy, common.WerfCleanupRegistryPassword, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfRegistryConcurrency, common.WerfHome),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runCleanup()
@@ -54,14 +58,19 @@ Command should run from the project directory, where werf.yaml file reside.`),
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name")
 	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username (granted read-write permission)")
 	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password (granted read-write permission)")
+	cmd.Flags().BoolVarP(&CmdData.DiscoverImagesRepos, "discover-images-repos", "", false, "Also process repositories REPO/IMAGE_NAME found in the registry catalog (or with the registry provider API), which are not described in werf.yaml")
 
 	cmd.Flags().BoolVarP(&CmdData.WithoutKube, "without-kube", "", false, "Do not skip deployed kubernetes images")
+	cmd.Flags().StringArrayVarP(&CmdData.KubeContexts, "kube-context", "", []string{}, "Kubernetes config context of the cluster to skip images deployed into (can be used one or more times, current context of kubernetes config is used by default)")
+	cmd.Flags().BoolVarP(&CmdData.AllKubeContexts, "all-kube-contexts", "", false, "Skip images deployed into clusters of all contexts of kubernetes config")
 
 	cmd.Flags().BoolVarP(&CmdData.DryRun, "dry-run", "", false, "Indicate what the command would do without actually doing that")
 
@@ -81,27 +90,31 @@ func runCleanup() error {
 		return err
 	}
 
-	kube.Init(kube.InitOptions{})
-
 	projectDir, err := common.GetProjectDir(&CommonCmdData)
 	if err != nil {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
 	projectTmpDir, err := project_tmp_dir.Get()
 	if err != nil {
 		return fmt.Errorf("getting project tmp dir failed: %s", err)
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
-	projectName := werfConfig.Meta.Project
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
 
-	repoName, err := common.GetRequiredRepoName(projectName, CmdData.Repo)
+	repoName, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 	if err != nil {
 		return err
 	}
@@ -125,9 +138,10 @@ func runCleanup() error {
 	}
 
 	commonRepoOptions := cleanup.CommonRepoOptions{
-		Repository:  repoName,
-		ImagesNames: imagesNames,
-		DryRun:      CmdData.DryRun,
+		Repository:          repoName,
+		ImagesNames:         imagesNames,
+		DryRun:              CmdData.DryRun,
+		DiscoverImagesRepos: CmdData.DiscoverImagesRepos,
 	}
 
 	var localRepo *git_repo.Local
@@ -145,6 +159,14 @@ func runCleanup() error {
 		CommonRepoOptions: commonRepoOptions,
 		LocalRepo:         localRepo,
 		WithoutKube:       CmdData.WithoutKube,
+		KubeContexts:      CmdData.KubeContexts,
+		AllKubeContexts:   CmdData.AllKubeContexts,
+		Policies: cleanup.CleanupPolicies{
+			GitTagsExpiryDatePeriod:    werfConfig.Meta.Publish.Cleanup.GitTagsExpiryDatePeriod,
+			GitTagsLimit:               werfConfig.Meta.Publish.Cleanup.GitTagsLimit,
+			GitCommitsExpiryDatePeriod: werfConfig.Meta.Publish.Cleanup.GitCommitsExpiryDatePeriod,
+			GitCommitsLimit:            werfConfig.Meta.Publish.Cleanup.GitCommitsLimit,
+		},
 	}
 
 	if err := cleanup.Cleanup(cleanupOptions); err != nil {
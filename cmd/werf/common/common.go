@@ -1,36 +1,108 @@
 package common
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"k8s.io/kubernetes/pkg/util/file"
 
 	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/werf/pkg/build"
 	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/logger/terminal"
+	"github.com/flant/werf/pkg/scan"
+	"github.com/flant/werf/pkg/sign"
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/util"
 	"github.com/flant/werf/pkg/werf"
 )
 
 type CmdData struct {
-	Dir     *string
-	TmpDir  *string
-	HomeDir *string
-	SSHKeys *[]string
+	Dir            *string
+	ConfigPath     *string
+	ConfigOverlays *[]string
+	TmpDir         *string
+	HomeDir        *string
+	SSHKeys        *[]string
+
+	SSHKnownHosts            *[]string
+	SSHKnownHostsFiles       *[]string
+	SSHStrictHostKeyChecking *bool
+
+	AllowCaseCollisions *bool
+
+	Offline      *bool
+	SkipGitFetch *bool
+
+	TakeSubmodulesFromWorkTree *bool
+
+	RefreshDownloads *bool
+	ForceRefresh     *bool
+
+	PatchesTmpDir  *string
+	ArchivesTmpDir *string
+	WorkTreesDir   *string
+
+	AutoCreateRepo                *bool
+	AutoCreateRepoLifecyclePolicy *string
+
+	Scanner               *string
+	ScannerImage          *string
+	ScanSeverityThreshold *string
+	ScanReport            *string
+
+	SignImages *bool
+	Signer     *string
+	SignKey    *string
+
+	GitUrl    *string
+	GitCommit *string
+
+	Platforms *[]string
+
+	StagesRepo *string
+
+	ContainerRuntime *string
+
+	AddLabels      *[]string
+	AddAnnotations *[]string
+
+	CommitSignatureKeyrings *[]string
+
+	ImagesOrder *[]string
+
+	BuildSecrets *[]string
+
+	StageTimeout *string
+	StageRetries *string
+
+	InsecureRegistries      *[]string
+	SkipTLSVerifyRegistries *[]string
+	RegistriesCAFiles       *[]string
 
 	Tag        *[]string
 	TagBranch  *bool
 	TagBuildID *bool
 	TagCI      *bool
 	TagCommit  *bool
+	TagCustom  *[]string
 
 	Environment *string
 	Release     *string
 	Namespace   *string
 	KubeContext *string
+
+	HelmReleaseStorageNamespace *string
+	HelmReleaseStorageType      *string
 }
 
 func GetLongCommandDescription(text string) string {
@@ -42,6 +114,14 @@ func SetupDir(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.Dir, "dir", "", "", "Change to the specified directory to find werf.yaml config")
 }
 
+func SetupConfigPath(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ConfigPath = new(string)
+	cmd.Flags().StringVarP(cmdData.ConfigPath, "config", "", "", "Use custom configuration file instead of werf.yaml in the project directory, path is relative to the project directory (e.g. one of werf.yaml configs of monorepo services)")
+
+	cmdData.ConfigOverlays = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.ConfigOverlays, "config-overlay", "", []string{}, "Merge config overlay FILE (relative to the project dir) or https url over werf.yaml: meta, images and artifacts sections of the overlay are merged over the meta doc and every image and artifact doc (can be used one or more times, overlays are applied in the specified order)")
+}
+
 func SetupTmpDir(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.TmpDir = new(string)
 	cmd.Flags().StringVarP(cmdData.TmpDir, "tmp-dir", "", "", "Use specified dir to store tmp files and dirs (use system tmp dir by default)")
@@ -55,6 +135,325 @@ func SetupHomeDir(cmdData *CmdData, cmd *cobra.Command) {
 func SetupSSHKey(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.SSHKeys = new([]string)
 	cmd.Flags().StringArrayVarP(cmdData.SSHKeys, "ssh-key", "", []string{}, "Enable only specified ssh keys (use system ssh-agent by default)")
+
+	cmdData.SSHKnownHosts = new([]string)
+	cmdData.SSHKnownHostsFiles = new([]string)
+	cmdData.SSHStrictHostKeyChecking = new(bool)
+
+	cmd.Flags().StringArrayVarP(cmdData.SSHKnownHosts, "ssh-known-hosts", "", []string{}, "Add known_hosts lines to check host keys of remote git repos, e.g. output of ssh-keyscan (can be used one or more times, use $WERF_SSH_KNOWN_HOSTS by default)")
+	cmd.Flags().StringArrayVarP(cmdData.SSHKnownHostsFiles, "ssh-known-hosts-file", "", []string{}, "Use specified known_hosts file in addition to ~/.ssh/known_hosts and /etc/ssh/ssh_known_hosts (can be used one or more times)")
+	cmd.Flags().BoolVarP(cmdData.SSHStrictHostKeyChecking, "ssh-strict-host-key-checking", "", true, "Reject remote git repos hosts, which keys are not in known_hosts (changed keys are rejected anyway)")
+}
+
+func GetSSHHostKeyCheckingOptions(cmdData *CmdData) ssh_agent.HostKeyCheckingOptions {
+	knownHosts := *cmdData.SSHKnownHosts
+	if len(knownHosts) == 0 && os.Getenv(string(WerfSSHKnownHosts)) != "" {
+		knownHosts = []string{os.Getenv(string(WerfSSHKnownHosts))}
+	}
+
+	return ssh_agent.HostKeyCheckingOptions{
+		KnownHosts:            knownHosts,
+		KnownHostsFiles:       *cmdData.SSHKnownHostsFiles,
+		StrictHostKeyChecking: *cmdData.SSHStrictHostKeyChecking,
+	}
+}
+
+func SetupAllowCaseCollisions(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AllowCaseCollisions = new(bool)
+	cmd.Flags().BoolVarP(cmdData.AllowCaseCollisions, "allow-case-collisions", "", false, "Continue with a warning when git paths differ only by case on case-insensitive filesystem (macOS, Windows), only one of such files is added to the image (use $WERF_ALLOW_CASE_COLLISIONS by default)")
+}
+
+// GetAllowCaseCollisions returns --allow-case-collisions option or $WERF_ALLOW_CASE_COLLISIONS
+func GetAllowCaseCollisions(cmdData *CmdData) bool {
+	return *cmdData.AllowCaseCollisions || os.Getenv(string(WerfAllowCaseCollisions)) == "1"
+}
+
+func SetupTakeSubmodulesFromWorkTree(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.TakeSubmodulesFromWorkTree = new(bool)
+	cmd.Flags().BoolVarP(cmdData.TakeSubmodulesFromWorkTree, "take-submodules-from-worktree", "", false, "Build submodules of the project directory at commits checked out in the work tree instead of commits recorded in the HEAD commit, uncommitted changes of submodules are not built (use $WERF_TAKE_SUBMODULES_FROM_WORKTREE by default)")
+}
+
+// GetTakeSubmodulesFromWorkTree returns --take-submodules-from-worktree option or $WERF_TAKE_SUBMODULES_FROM_WORKTREE
+func GetTakeSubmodulesFromWorkTree(cmdData *CmdData) bool {
+	return *cmdData.TakeSubmodulesFromWorkTree || os.Getenv(string(WerfTakeSubmodulesFromWorkTree)) == "1"
+}
+
+func SetupOffline(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Offline = new(bool)
+	cmd.Flags().BoolVarP(cmdData.Offline, "offline", "", false, "Forbid network operations: use existing clones of remote git repos without fetch, local base images without pull and cached downloads, fail on push (use $WERF_OFFLINE by default)")
+}
+
+// GetOffline returns --offline option or $WERF_OFFLINE
+func GetOffline(cmdData *CmdData) bool {
+	return *cmdData.Offline || os.Getenv(string(WerfOffline)) == "1"
+}
+
+func SetupSkipGitFetch(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SkipGitFetch = new(bool)
+	cmd.Flags().BoolVarP(cmdData.SkipGitFetch, "skip-git-fetch", "", false, "Use existing clones of remote git repos without fetch, missing clones are cloned (use $WERF_SKIP_GIT_FETCH by default)")
+}
+
+// GetSkipGitFetch returns --skip-git-fetch option or $WERF_SKIP_GIT_FETCH
+func GetSkipGitFetch(cmdData *CmdData) bool {
+	return *cmdData.SkipGitFetch || os.Getenv(string(WerfSkipGitFetch)) == "1"
+}
+
+func SetupRefreshDownloads(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.RefreshDownloads = new(bool)
+	cmd.Flags().BoolVarP(cmdData.RefreshDownloads, "refresh-downloads", "", false, "Download files of werf.yaml download directives without checksum again, stages are rebuilt if content is changed (use $WERF_REFRESH_DOWNLOADS by default)")
+}
+
+// GetRefreshDownloads returns --refresh-downloads option or $WERF_REFRESH_DOWNLOADS
+func GetRefreshDownloads(cmdData *CmdData) bool {
+	return *cmdData.RefreshDownloads || os.Getenv(string(WerfRefreshDownloads)) == "1"
+}
+
+func SetupForceRefresh(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ForceRefresh = new(bool)
+	cmd.Flags().BoolVarP(cmdData.ForceRefresh, "force-refresh", "", false, "Check clones of remote git repos: broken clone is cloned again from scratch, head branch of the clone is updated to the remote default branch (use $WERF_FORCE_REFRESH by default)")
+}
+
+// GetForceRefresh returns --force-refresh option or $WERF_FORCE_REFRESH
+func GetForceRefresh(cmdData *CmdData) bool {
+	return *cmdData.ForceRefresh || os.Getenv(string(WerfForceRefresh)) == "1"
+}
+
+func SetupGitTmpDirs(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.PatchesTmpDir = new(string)
+	cmdData.ArchivesTmpDir = new(string)
+	cmdData.WorkTreesDir = new(string)
+
+	cmd.Flags().StringVarP(cmdData.PatchesTmpDir, "patches-tmp-dir", "", "", "Use specified dir to store git patches between commits (use $WERF_PATCHES_TMP_DIR or tmp dir by default)")
+	cmd.Flags().StringVarP(cmdData.ArchivesTmpDir, "archives-tmp-dir", "", "", "Use specified dir to store git archives of commits (use $WERF_ARCHIVES_TMP_DIR or tmp dir by default)")
+	cmd.Flags().StringVarP(cmdData.WorkTreesDir, "worktrees-dir", "", "", "Use specified dir to store git work trees (use $WERF_WORKTREES_DIR or ~/.werf/git/worktrees by default)")
+}
+
+// SetGitTmpDirs makes git repos create patches, archives and work trees in dirs specified by options or environment variables
+func SetGitTmpDirs(cmdData *CmdData) {
+	git_repo.PatchesDir = getOptionValue(*cmdData.PatchesTmpDir, WerfPatchesTmpDir)
+	git_repo.ArchivesDir = getOptionValue(*cmdData.ArchivesTmpDir, WerfArchivesTmpDir)
+	git_repo.WorkTreesDir = getOptionValue(*cmdData.WorkTreesDir, WerfWorkTreesDir)
+}
+
+func getOptionValue(value string, env Env) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(string(env))
+}
+
+func SetupAutoCreateRepo(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AutoCreateRepo = new(bool)
+	cmdData.AutoCreateRepoLifecyclePolicy = new(string)
+
+	cmd.Flags().BoolVarP(cmdData.AutoCreateRepo, "auto-create-repo", "", false, "Create missing repositories before push in the registries, which do not create them on the first push: AWS ECR and GCP Artifact Registry (use $WERF_AUTO_CREATE_REPO by default)")
+	cmd.Flags().StringVarP(cmdData.AutoCreateRepoLifecyclePolicy, "auto-create-repo-lifecycle-policy", "", "", "Apply lifecycle policy from specified Go template file to the created repositories: ECR lifecycle policy or Artifact Registry cleanup policies JSON (use $WERF_AUTO_CREATE_REPO_LIFECYCLE_POLICY by default)")
+}
+
+// GetAutoCreateRepoOptions returns nil if repositories auto creation is not enabled by --auto-create-repo option or $WERF_AUTO_CREATE_REPO
+func GetAutoCreateRepoOptions(cmdData *CmdData) *docker_registry.AutoCreateRepoOptions {
+	if !*cmdData.AutoCreateRepo && os.Getenv(string(WerfAutoCreateRepo)) != "1" {
+		return nil
+	}
+
+	opts := &docker_registry.AutoCreateRepoOptions{LifecyclePolicyFile: *cmdData.AutoCreateRepoLifecyclePolicy}
+	if opts.LifecyclePolicyFile == "" {
+		opts.LifecyclePolicyFile = os.Getenv(string(WerfAutoCreateRepoLifecyclePolicy))
+	}
+
+	return opts
+}
+
+func SetupScan(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Scanner = new(string)
+	cmdData.ScannerImage = new(string)
+	cmdData.ScanSeverityThreshold = new(string)
+	cmdData.ScanReport = new(string)
+
+	cmd.Flags().StringVarP(cmdData.Scanner, "scanner", "", "", "Scan built images for vulnerabilities with specified scanner running in the container: trivy or clair (use $WERF_SCANNER by default, images are not scanned if not specified)")
+	cmd.Flags().StringVarP(cmdData.ScannerImage, "scanner-image", "", "", "Use specified image of the scanner instead of the default one")
+	cmd.Flags().StringVarP(cmdData.ScanSeverityThreshold, "scan-severity-threshold", "", "HIGH", "Fail if vulnerabilities of specified or higher severity are found: UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL")
+	cmd.Flags().StringVarP(cmdData.ScanReport, "scan-report", "", "", "Write findings of all scanned images into specified json file")
+}
+
+// GetScanOptions returns nil if scanner is not specified by --scanner option or $WERF_SCANNER
+func GetScanOptions(cmdData *CmdData) (*build.ScanOptions, error) {
+	scanner := *cmdData.Scanner
+	if scanner == "" {
+		scanner = os.Getenv(string(WerfScanner))
+	}
+
+	if scanner == "" {
+		return nil, nil
+	}
+
+	if err := scan.ValidateScanner(scanner); err != nil {
+		return nil, err
+	}
+
+	if err := scan.ValidateSeverity(*cmdData.ScanSeverityThreshold); err != nil {
+		return nil, fmt.Errorf("bad --scan-severity-threshold: %s", err)
+	}
+
+	return &build.ScanOptions{
+		Options:           scan.Options{Scanner: scanner, ScannerImage: *cmdData.ScannerImage},
+		SeverityThreshold: *cmdData.ScanSeverityThreshold,
+		ReportPath:        *cmdData.ScanReport,
+	}, nil
+}
+
+func SetupStageTimeoutAndRetries(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.StageTimeout = new(string)
+	cmdData.StageRetries = new(string)
+
+	cmd.Flags().StringVarP(cmdData.StageTimeout, "stage-timeout", "", "", "Kill the stage container, which runs longer than specified duration (e.g. 30m), and fail the stage (default $WERF_STAGE_TIMEOUT, no timeout if not specified). Timeouts of image stages from stagesTimeout directive of werf.yaml take precedence")
+	cmd.Flags().StringVarP(cmdData.StageRetries, "stage-retries", "", "", "Run failed stage container again up to specified number of times before failing the build (default $WERF_STAGE_RETRIES or 0). Retries of image stages from stagesRetries directive of werf.yaml take precedence")
+}
+
+// GetStageTimeout returns --stage-timeout option or $WERF_STAGE_TIMEOUT value, 0 means no timeout
+func GetStageTimeout(cmdData *CmdData) (time.Duration, error) {
+	value := getOptionValue(*cmdData.StageTimeout, WerfStageTimeout)
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("bad --stage-timeout '%s': non-negative duration expected (e.g. 30m)", value)
+	}
+
+	return timeout, nil
+}
+
+// GetStageRetries returns --stage-retries option or $WERF_STAGE_RETRIES value
+func GetStageRetries(cmdData *CmdData) (int, error) {
+	value := getOptionValue(*cmdData.StageRetries, WerfStageRetries)
+	if value == "" {
+		return 0, nil
+	}
+
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("bad --stage-retries '%s': non-negative integer expected", value)
+	}
+
+	return retries, nil
+}
+
+func SetupSign(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SignImages = new(bool)
+	cmd.Flags().BoolVarP(cmdData.SignImages, "sign-images", "", false, "Sign digests of the published images after push with the key specified by --sign-key or publish.sign.key in werf.yaml (use $WERF_SIGN_IMAGES by default)")
+
+	SetupSignKey(cmdData, cmd)
+}
+
+func SetupSignKey(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Signer = new(string)
+	cmdData.SignKey = new(string)
+
+	cmd.Flags().StringVarP(cmdData.Signer, "signer", "", "", "Signer binary to sign and verify images: cosign or notation (publish.sign.signer in werf.yaml or cosign by default)")
+	cmd.Flags().StringVarP(cmdData.SignKey, "sign-key", "", "", "Key reference passed to the signer: key file, env://VAR or KMS URI for cosign, key name for notation (use $WERF_SIGN_KEY or publish.sign.key in werf.yaml by default)")
+}
+
+// GetSignKeyOptions returns signer and key from options, environment or werf.yaml publish.sign section
+func GetSignKeyOptions(cmdData *CmdData, werfConfig *config.WerfConfig) (sign.Options, error) {
+	opts := sign.Options{Signer: *cmdData.Signer, Key: *cmdData.SignKey}
+
+	if opts.Key == "" {
+		opts.Key = os.Getenv(string(WerfSignKey))
+	}
+
+	if werfConfig != nil {
+		if opts.Signer == "" {
+			opts.Signer = werfConfig.Meta.Publish.Sign.Signer
+		}
+
+		if opts.Key == "" {
+			opts.Key = werfConfig.Meta.Publish.Sign.Key
+		}
+	}
+
+	if opts.Signer == "" {
+		opts.Signer = sign.CosignSigner
+	}
+
+	if err := opts.Validate(); err != nil {
+		return sign.Options{}, err
+	}
+
+	return opts, nil
+}
+
+// GetSignOptions returns nil if signing is not enabled by --sign-images option or $WERF_SIGN_IMAGES
+func GetSignOptions(cmdData *CmdData, werfConfig *config.WerfConfig) (*sign.Options, error) {
+	if !*cmdData.SignImages && os.Getenv(string(WerfSignImages)) != "1" {
+		return nil, nil
+	}
+
+	opts, err := GetSignKeyOptions(cmdData, werfConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+func SetupGitSource(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.GitUrl = new(string)
+	cmdData.GitCommit = new(string)
+
+	cmd.Flags().StringVarP(cmdData.GitUrl, "git-url", "", "", "Use werf.yaml and sources from the specified commit of remote git repo instead of project directory (--git-commit is required)")
+	cmd.Flags().StringVarP(cmdData.GitCommit, "git-commit", "", "", "Commit of the git repo specified by --git-url: full or abbreviated commit hash, branch, tag or an expression like origin/master~2")
+}
+
+func SetupPlatform(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Platforms = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.Platforms, "platform", "", []string{}, "Build images for specified platform os/arch[/variant], e.g. linux/amd64 or linux/arm64 (can be used one or more times, docker daemon platform by default). Images for multiple platforms are published as a manifest list.")
+}
+
+func SetupStagesRepo(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.StagesRepo = new(string)
+	cmd.Flags().StringVarP(cmdData.StagesRepo, "stages-repo", "", "", "Docker repo to pull missing stages from and to push built stages to. Build continues with local stages cache while repo is not available, postponed stages are pushed when repo becomes available")
+}
+
+func SetupContainerRuntime(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ContainerRuntime = new(string)
+	cmd.Flags().StringVarP(cmdData.ContainerRuntime, "container-runtime", "", "", "Container runtime to build, store and push images: docker or buildah (use $WERF_CONTAINER_RUNTIME or docker by default). Buildah allows daemonless builds without privileged docker-in-docker")
+}
+
+func SetupAddLabels(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AddLabels = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.AddLabels, "add-label", "", []string{}, "Add label NAME=VALUE to the tagged and pushed images in addition to the labels from werf.yaml (can be used one or more times)")
+}
+
+func SetupBuildSecrets(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.BuildSecrets = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.BuildSecrets, "secret", "", []string{}, "Mount secret into stages containers as /run/secrets/ID file during build: id=ID,src=PATH for a file or id=ID,env=VAR for an env variable value. Secrets are not saved into stages images and do not affect stages signatures (can be used one or more times)")
+}
+
+func SetupRegistriesOptions(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.InsecureRegistries = new([]string)
+	cmdData.SkipTLSVerifyRegistries = new([]string)
+	cmdData.RegistriesCAFiles = new([]string)
+
+	cmd.Flags().StringArrayVarP(cmdData.InsecureRegistries, "insecure-registry", "", []string{}, "Use plain http for specified registry HOST[:PORT] (can be used one or more times, in addition to registries from werf.yaml)")
+	cmd.Flags().StringArrayVarP(cmdData.SkipTLSVerifyRegistries, "skip-tls-verify-registry", "", []string{}, "Skip tls certificate verification for specified registry HOST[:PORT] (can be used one or more times, in addition to registries from werf.yaml)")
+	cmd.Flags().StringArrayVarP(cmdData.RegistriesCAFiles, "registry-ca-file", "", []string{}, "Trust CA certificates from PEM file for registry: HOST[:PORT]=PATH (can be used one or more times, overrides caFile from werf.yaml)")
+}
+
+func SetupCommitSignatureKeyrings(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.CommitSignatureKeyrings = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.CommitSignatureKeyrings, "commit-signature-keyring", "", []string{}, "Require commits of git mappings to be signed by GPG keys from specified armored keyring file (can be used one or more times, in addition to build.commitSignatureKeyrings from werf.yaml)")
+}
+
+func SetupImagesOrder(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ImagesOrder = new([]string)
+	cmd.Flags().StringSliceVarP(cmdData.ImagesOrder, "images-order", "", []string{}, "Process specified images first in the specified order (comma separated names), other images follow in werf.yaml order. Images used by fromImage, import and dependsOn directives are always built before the image")
+}
+
+func SetupAddAnnotations(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AddAnnotations = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.AddAnnotations, "add-annotation", "", []string{}, "Add annotation NAME=VALUE to the deployed resources in addition to the annotations from werf.yaml (can be used one or more times)")
 }
 
 func SetupTag(cmdData *CmdData, cmd *cobra.Command) {
@@ -63,27 +462,30 @@ func SetupTag(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.TagBuildID = new(bool)
 	cmdData.TagCI = new(bool)
 	cmdData.TagCommit = new(bool)
+	cmdData.TagCustom = new([]string)
 
 	cmd.Flags().StringArrayVarP(cmdData.Tag, "tag", "", []string{}, "Add tag (can be used one or more times)")
 	cmd.Flags().BoolVarP(cmdData.TagBranch, "tag-branch", "", false, "Tag by git branch")
 	cmd.Flags().BoolVarP(cmdData.TagBuildID, "tag-build-id", "", false, "Tag by CI build id")
 	cmd.Flags().BoolVarP(cmdData.TagCI, "tag-ci", "", false, "Tag by CI branch and tag")
 	cmd.Flags().BoolVarP(cmdData.TagCommit, "tag-commit", "", false, "Tag by git commit")
+	cmd.Flags().StringArrayVarP(cmdData.TagCustom, "tag-custom", "", []string{}, "Tag by go template expression with git metadata functions branch, tag, commit, commit_short, date, timestamp and env (can be used one or more times)")
 }
 
 func SetupEnvironment(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.Environment = new(string)
-	cmd.Flags().StringVarP(cmdData.Environment, "environment", "", "", "Use specified environment (use CI_ENVIRONMENT_SLUG by default). Environment is a required parameter and should be specified with option or CI_ENVIRONMENT_SLUG variable.")
+	cmd.Flags().StringVarP(cmdData.Environment, "env", "", "", "Use specified environment (use CI_ENVIRONMENT_SLUG by default). Environment is used to construct release name and namespace, to load .helm/values-ENV.yaml and .helm/secret-values-ENV.yaml and is available in templates as .Values.global.env")
+	cmd.Flags().StringVarP(cmdData.Environment, "environment", "", "", "Alias for --env")
 }
 
 func SetupRelease(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.Release = new(string)
-	cmd.Flags().StringVarP(cmdData.Release, "release", "", "", "Use specified Helm release name (use %project-%environment template by default)")
+	cmd.Flags().StringVarP(cmdData.Release, "release", "", "", "Use specified Helm release name (use %project-%env template by default)")
 }
 
 func SetupNamespace(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.Namespace = new(string)
-	cmd.Flags().StringVarP(cmdData.Namespace, "namespace", "", "", "Use specified Kubernetes namespace (use %project-%environment template by default)")
+	cmd.Flags().StringVarP(cmdData.Namespace, "namespace", "", "", "Use specified Kubernetes namespace (use %project-%env template by default)")
 }
 
 func SetupKubeContext(cmdData *CmdData, cmd *cobra.Command) {
@@ -91,63 +493,132 @@ func SetupKubeContext(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.KubeContext, "kube-context", "", "", "Kubernetes config context")
 }
 
-func GetWerfConfig(projectDir string) (*config.WerfConfig, error) {
-	for _, werfConfigName := range []string{"werf.yml", "werf.yaml"} {
-		werfConfigPath := path.Join(projectDir, werfConfigName)
-		if exist, err := file.FileExists(werfConfigPath); err != nil {
-			return nil, err
-		} else if exist {
-			return config.ParseWerfConfig(werfConfigPath)
-		}
-	}
+func SetupHelmReleaseStorage(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.HelmReleaseStorageNamespace = new(string)
+	cmd.Flags().StringVarP(cmdData.HelmReleaseStorageNamespace, "helm-release-storage-namespace", "", "", "Helm release storage namespace, the same as --tiller-namespace for helm (use $WERF_HELM_RELEASE_STORAGE_NAMESPACE, $TILLER_NAMESPACE or kube-system by default)")
 
-	return nil, errors.New("werf.yaml not found")
+	cmdData.HelmReleaseStorageType = new(string)
+	cmd.Flags().StringVarP(cmdData.HelmReleaseStorageType, "helm-release-storage-type", "", "", "Helm release storage type: configmap or secret, should match --storage option of tiller (use $WERF_HELM_RELEASE_STORAGE_TYPE or configmap by default)")
 }
 
-func GetProjectDir(cmdData *CmdData) (string, error) {
-	if *cmdData.Dir != "" {
-		return *cmdData.Dir, nil
+func GetHelmReleaseStorageOptions(cmdData *CmdData) deploy.ReleaseStorageOptions {
+	opts := deploy.ReleaseStorageOptions{
+		Namespace: *cmdData.HelmReleaseStorageNamespace,
+		Type:      *cmdData.HelmReleaseStorageType,
 	}
 
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return "", err
+	if opts.Namespace == "" {
+		opts.Namespace = os.Getenv(string(WerfHelmReleaseStorageNamespace))
+	}
+	if opts.Type == "" {
+		opts.Type = os.Getenv(string(WerfHelmReleaseStorageType))
 	}
 
-	return currentDir, nil
+	return opts
 }
 
-func GetProjectBuildDir(projectName string) (string, error) {
-	projectBuildDir := path.Join(werf.GetHomeDir(), "builds", projectName)
+// GetEnvironment returns --env (--environment) option or CI_ENVIRONMENT_SLUG variable
+func GetEnvironment(cmdData *CmdData) string {
+	if *cmdData.Environment != "" {
+		return *cmdData.Environment
+	}
+	return os.Getenv("CI_ENVIRONMENT_SLUG")
+}
 
-	if err := os.MkdirAll(projectBuildDir, os.ModePerm); err != nil {
-		return "", err
+// GetConfigPath returns --config option value or empty string if option is not specified
+func GetConfigPath(cmdData *CmdData) string {
+	if cmdData.ConfigPath == nil {
+		return ""
 	}
+	return *cmdData.ConfigPath
+}
 
-	return projectBuildDir, nil
+// GetConfigOverlays returns --config-overlay option values
+func GetConfigOverlays(cmdData *CmdData) []string {
+	if cmdData.ConfigOverlays == nil {
+		return nil
+	}
+	return *cmdData.ConfigOverlays
 }
 
-func GetRequiredRepoName(projectName, repoOption string) (string, error) {
-	res := GetOptionalRepoName(projectName, repoOption)
-	if res == "" {
-		return "", fmt.Errorf("CI_REGISTRY_IMAGE variable or --repo option required!")
+// GetOwnRemoteGitRepo clones or fetches the repo specified by --git-url option.
+// Returns nil if option is not specified.
+func GetOwnRemoteGitRepo(cmdData *CmdData) (*git_repo.Remote, error) {
+	if cmdData.GitUrl == nil || *cmdData.GitUrl == "" {
+		if cmdData.GitCommit != nil && *cmdData.GitCommit != "" {
+			return nil, fmt.Errorf("--git-commit option requires --git-url")
+		}
+
+		return nil, nil
 	}
-	return res, nil
+
+	if *cmdData.GitCommit == "" {
+		return nil, fmt.Errorf("--git-commit option required with --git-url")
+	}
+
+	clonePath, err := git_repo.GetClonePath(*cmdData.GitUrl, false)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &git_repo.Remote{
+		Base:      git_repo.Base{Name: "own"},
+		Url:       *cmdData.GitUrl,
+		ClonePath: clonePath,
+		LegacyClonePath: path.Join(
+			werf.GetHomeDir(),
+			"own_git_repo",
+			fmt.Sprintf("%v", git_repo.RemoteGitRepoCacheVersion),
+			slug.Slug(*cmdData.GitUrl),
+		),
+	}
+
+	if err := repo.CloneAndFetch(werf.GetContext()); err != nil {
+		return nil, err
+	}
+
+	// abbreviated hash, branch, tag or expression is replaced with the full commit hash
+	commit, err := repo.ResolveCommit(werf.GetContext(), *cmdData.GitCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	if commit != *cmdData.GitCommit {
+		fmt.Printf("Using commit `%s` of repo `%s` (resolved from `%s`)\n", commit, *cmdData.GitUrl, *cmdData.GitCommit)
+		*cmdData.GitCommit = commit
+	}
+
+	return repo, nil
 }
 
-func GetOptionalRepoName(projectName, repoOption string) string {
-	if repoOption == ":minikube" {
-		return fmt.Sprintf("werf-registry.kube-system.svc.cluster.local:5000/%s", projectName)
-	} else if repoOption != "" {
-		return repoOption
+func GetProjectDir(cmdData *CmdData) (string, error) {
+	projectDir := *cmdData.Dir
+	if projectDir == "" {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+
+		projectDir = currentDir
 	}
 
-	ciRegistryImage := os.Getenv("CI_REGISTRY_IMAGE")
-	if ciRegistryImage != "" {
-		return ciRegistryImage
+	return projectDir, nil
+}
+
+// InitProjectHome switches werf home to the project subtree if --werf-project-home is enabled.
+// Locks are kept in the base werf home, because they guard host-wide state (docker images, host slots).
+func InitProjectHome(projectDir string) error {
+	_, err := werf.InitProjectHome(projectDir)
+	return err
+}
+
+// GetStagesRepo returns --stages-repo option or publish.stagesRepo from werf.yaml
+func GetStagesRepo(cmdData *CmdData, werfConfig *config.WerfConfig) string {
+	if *cmdData.StagesRepo != "" {
+		return *cmdData.StagesRepo
 	}
 
-	return ""
+	return werfConfig.Meta.Publish.StagesRepo
 }
 
 func GetNamespace(namespaceOption string) string {
@@ -156,3 +627,116 @@ func GetNamespace(namespaceOption string) string {
 	}
 	return namespaceOption
 }
+
+func GetPlatforms(cmdData *CmdData) ([]string, error) {
+	var platforms []string
+	for _, platform := range *cmdData.Platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("bad --platform '%s': expected os/arch[/variant]", platform)
+		}
+
+		if util.IsStringsContainValue(platforms, platform) {
+			continue
+		}
+
+		platforms = append(platforms, platform)
+	}
+
+	return platforms, nil
+}
+
+func GetContainerRuntime(cmdData *CmdData) string {
+	if *cmdData.ContainerRuntime != "" {
+		return *cmdData.ContainerRuntime
+	}
+	return os.Getenv("WERF_CONTAINER_RUNTIME")
+}
+
+func GetBuildSecrets(cmdData *CmdData) ([]*build.BuildSecret, error) {
+	var secrets []*build.BuildSecret
+	for _, spec := range *cmdData.BuildSecrets {
+		secret, err := build.ParseBuildSecret(spec)
+		if err != nil {
+			return nil, fmt.Errorf("bad --secret option: %s", err)
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+// InitRegistriesOptions configures registries connection settings from werf.yaml registries and options.
+// Relative caFile paths from werf.yaml are resolved from the project dir.
+func InitRegistriesOptions(cmdData *CmdData, werfConfig *config.WerfConfig, projectDir string) error {
+	options := map[string]docker_registry.RegistryOptions{}
+
+	if werfConfig != nil {
+		for _, registry := range werfConfig.Meta.Registries {
+			caFile := registry.CAFile
+			if caFile != "" && !filepath.IsAbs(caFile) {
+				caFile = filepath.Join(projectDir, caFile)
+			}
+
+			options[registry.Address] = docker_registry.RegistryOptions{
+				Insecure:      registry.Insecure,
+				SkipTLSVerify: registry.SkipTLSVerify,
+				CAFile:        caFile,
+			}
+		}
+	}
+
+	for _, registry := range *cmdData.InsecureRegistries {
+		registryOptions := options[registry]
+		registryOptions.Insecure = true
+		options[registry] = registryOptions
+	}
+
+	for _, registry := range *cmdData.SkipTLSVerifyRegistries {
+		registryOptions := options[registry]
+		registryOptions.SkipTLSVerify = true
+		options[registry] = registryOptions
+	}
+
+	caFiles, err := parseNameValueOptions("--registry-ca-file", *cmdData.RegistriesCAFiles)
+	if err != nil {
+		return err
+	}
+
+	for registry, caFile := range caFiles {
+		if _, err := os.Stat(caFile); err != nil {
+			return fmt.Errorf("bad --registry-ca-file for registry %s: %s", registry, err)
+		}
+
+		registryOptions := options[registry]
+		registryOptions.CAFile = caFile
+		options[registry] = registryOptions
+	}
+
+	docker_registry.SetRegistriesOptions(options)
+
+	return nil
+}
+
+func GetAddLabels(cmdData *CmdData) (map[string]string, error) {
+	return parseNameValueOptions("--add-label", *cmdData.AddLabels)
+}
+
+func GetAddAnnotations(cmdData *CmdData) (map[string]string, error) {
+	return parseNameValueOptions("--add-annotation", *cmdData.AddAnnotations)
+}
+
+func parseNameValueOptions(optionName string, options []string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, option := range options {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("bad %s '%s': expected NAME=VALUE", optionName, option)
+		}
+
+		res[parts[0]] = parts[1]
+	}
+
+	return res, nil
+}
@@ -14,33 +14,99 @@ const (
 
 	WerfHome                                   Env = "WERF_HOME"
 	WerfTmp                                    Env = "WERF_TMP"
+	WerfHomeQuota                              Env = "WERF_HOME_QUOTA"
 	WerfAnsibleArgs                            Env = "WERF_ANSIBLE_ARGS"
 	WerfDockerConfig                           Env = "WERF_DOCKER_CONFIG"
+	WerfContainerRuntime                       Env = "WERF_CONTAINER_RUNTIME"
+	WerfDappdepsRepo                           Env = "WERF_DAPPDEPS_REPO"
+	WerfDappdepsVersions                       Env = "WERF_DAPPDEPS_VERSIONS"
+	WerfAllowCaseCollisions                    Env = "WERF_ALLOW_CASE_COLLISIONS"
+	WerfTakeSubmodulesFromWorkTree             Env = "WERF_TAKE_SUBMODULES_FROM_WORKTREE"
+	WerfRefreshDownloads                       Env = "WERF_REFRESH_DOWNLOADS"
+	WerfForceRefresh                           Env = "WERF_FORCE_REFRESH"
+	WerfOffline                                Env = "WERF_OFFLINE"
+	WerfSkipGitFetch                           Env = "WERF_SKIP_GIT_FETCH"
+	WerfPatchesTmpDir                          Env = "WERF_PATCHES_TMP_DIR"
+	WerfArchivesTmpDir                         Env = "WERF_ARCHIVES_TMP_DIR"
+	WerfWorkTreesDir                           Env = "WERF_WORKTREES_DIR"
 	WerfIgnoreCIDockerAutologin                Env = "WERF_IGNORE_CI_DOCKER_AUTOLOGIN"
 	WerfInsecureRegistry                       Env = "WERF_INSECURE_REGISTRY"
+	WerfRegistryConcurrency                    Env = "WERF_REGISTRY_CONCURRENCY"
+	WerfHostMaxBuilds                          Env = "WERF_HOST_MAX_BUILDS"
+	WerfHostMaxPushes                          Env = "WERF_HOST_MAX_PUSHES"
+	WerfProjectMaxBuilds                       Env = "WERF_PROJECT_MAX_BUILDS"
+	WerfProjectMaxPushes                       Env = "WERF_PROJECT_MAX_PUSHES"
+	WerfAutoCreateRepo                         Env = "WERF_AUTO_CREATE_REPO"
+	WerfAutoCreateRepoLifecyclePolicy          Env = "WERF_AUTO_CREATE_REPO_LIFECYCLE_POLICY"
+	WerfScanner                                Env = "WERF_SCANNER"
+	WerfSignImages                             Env = "WERF_SIGN_IMAGES"
+	WerfSignKey                                Env = "WERF_SIGN_KEY"
+	WerfSSHKnownHosts                          Env = "WERF_SSH_KNOWN_HOSTS"
+	WerfHelmReleaseStorageNamespace            Env = "WERF_HELM_RELEASE_STORAGE_NAMESPACE"
+	WerfHelmReleaseStorageType                 Env = "WERF_HELM_RELEASE_STORAGE_TYPE"
 	WerfSecretKey                              Env = "WERF_SECRET_KEY"
+	WerfSecretAgeIdentityFile                  Env = "WERF_SECRET_AGE_IDENTITY_FILE"
+	WerfSecretGPGKeyring                       Env = "WERF_SECRET_GPG_KEYRING"
+	WerfSecretGPGPassphrase                    Env = "WERF_SECRET_GPG_PASSPHRASE"
 	WerfCleanupRegistryPassword                Env = "WERF_CLEANUP_REGISTRY_PASSWORD"
 	WerfDisableSyncLocalStagesDatePeriodPolicy Env = "WERF_DISABLE_SYNC_LOCAL_STAGES_DATE_PERIOD_POLICY"
 	WerfGitTagsExpiryDatePeriodPolicy          Env = "WERF_GIT_TAGS_EXPIRY_DATE_PERIOD_POLICY"
 	WerfGitTagsLimitPolicy                     Env = "WERF_GIT_TAGS_LIMIT_POLICY"
 	WerfGitCommitsExpiryDatePeriodPolicy       Env = "WERF_GIT_COMMITS_EXPIRY_DATE_PERIOD_POLICY"
 	WerfGitCommitsLimitPolicy                  Env = "WERF_GIT_COMMITS_LIMIT_POLICY"
+	WerfGitClonesMaxAge                        Env = "WERF_GIT_CLONES_MAX_AGE"
+	WerfGitClonesMaxSize                       Env = "WERF_GIT_CLONES_MAX_SIZE"
+	WerfStageTimeout                           Env = "WERF_STAGE_TIMEOUT"
+	WerfStageRetries                           Env = "WERF_STAGE_RETRIES"
 )
 
 var envDescription = map[Env]string{
-	WerfHome:                    "",
-	WerfTmp:                     "",
-	WerfAnsibleArgs:             "",
-	WerfDockerConfig:            "",
-	WerfIgnoreCIDockerAutologin: "",
-	WerfInsecureRegistry:        "",
-	WerfSecretKey:               "",
-	WerfCleanupRegistryPassword: "",
+	WerfHome:                                   "",
+	WerfTmp:                                    "",
+	WerfHomeQuota:                              "",
+	WerfAnsibleArgs:                            "",
+	WerfDockerConfig:                           "",
+	WerfContainerRuntime:                       "",
+	WerfDappdepsRepo:                           "",
+	WerfDappdepsVersions:                       "",
+	WerfAllowCaseCollisions:                    "",
+	WerfTakeSubmodulesFromWorkTree:             "",
+	WerfRefreshDownloads:                       "",
+	WerfForceRefresh:                           "",
+	WerfOffline:                                "",
+	WerfSkipGitFetch:                           "",
+	WerfPatchesTmpDir:                          "",
+	WerfArchivesTmpDir:                         "",
+	WerfWorkTreesDir:                           "",
+	WerfIgnoreCIDockerAutologin:                "",
+	WerfInsecureRegistry:                       "",
+	WerfRegistryConcurrency:                    "",
+	WerfHostMaxBuilds:                          "",
+	WerfHostMaxPushes:                          "",
+	WerfProjectMaxBuilds:                       "",
+	WerfProjectMaxPushes:                       "",
+	WerfAutoCreateRepo:                         "",
+	WerfAutoCreateRepoLifecyclePolicy:          "",
+	WerfScanner:                                "",
+	WerfSignImages:                             "",
+	WerfSignKey:                                "",
+	WerfSSHKnownHosts:                          "",
+	WerfHelmReleaseStorageNamespace:            "",
+	WerfHelmReleaseStorageType:                 "",
+	WerfSecretKey:                              "",
+	WerfSecretAgeIdentityFile:                  "",
+	WerfSecretGPGKeyring:                       "",
+	WerfSecretGPGPassphrase:                    "",
+	WerfCleanupRegistryPassword:                "",
 	WerfDisableSyncLocalStagesDatePeriodPolicy: "",
 	WerfGitTagsExpiryDatePeriodPolicy:          "",
 	WerfGitTagsLimitPolicy:                     "",
 	WerfGitCommitsExpiryDatePeriodPolicy:       "",
 	WerfGitCommitsLimitPolicy:                  "",
+	WerfGitClonesMaxAge:                        "",
+	WerfGitClonesMaxSize:                       "",
+	WerfStageTimeout:                           "",
+	WerfStageRetries:                           "",
 }
 
 func EnvsDescription(envs ...Env) string {
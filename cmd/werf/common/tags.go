@@ -6,11 +6,13 @@ import (
 	"path"
 
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/tag_template"
 )
 
-func GetDeployTag(cmdData *CmdData, projectDir string) (string, error) {
+func GetDeployTag(cmdData *CmdData, projectDir string, werfConfig *config.WerfConfig) (string, error) {
 	optionsCount := 0
 	if len(*cmdData.Tag) > 0 {
 		optionsCount += len(*cmdData.Tag)
@@ -28,12 +30,13 @@ func GetDeployTag(cmdData *CmdData, projectDir string) (string, error) {
 	if *cmdData.TagCI {
 		optionsCount++
 	}
+	optionsCount += len(*cmdData.TagCustom)
 
 	if optionsCount > 1 {
 		return "", fmt.Errorf("exactly one tag should be specified for deploy")
 	}
 
-	opts, err := GetTagOptions(cmdData, projectDir)
+	opts, err := GetTagOptions(cmdData, projectDir, werfConfig)
 	if err != nil {
 		return "", err
 	}
@@ -48,12 +51,26 @@ func GetDeployTag(cmdData *CmdData, projectDir string) (string, error) {
 	return tags[0], nil
 }
 
-func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error) {
+// GetTagOptions returns tags specified by options, if there are no tag options, tag strategy from publish.tag of werf.yaml is used
+func GetTagOptions(cmdData *CmdData, projectDir string, werfConfig *config.WerfConfig) (build.TagOptions, error) {
+	tagStrategy := config.MetaPublishTag{
+		Tags:    *cmdData.Tag,
+		Branch:  *cmdData.TagBranch,
+		Commit:  *cmdData.TagCommit,
+		BuildID: *cmdData.TagBuildID,
+		CI:      *cmdData.TagCI,
+		Custom:  *cmdData.TagCustom,
+	}
+
+	if tagStrategy.IsEmpty() && werfConfig != nil {
+		tagStrategy = werfConfig.Meta.Publish.Tag
+	}
+
 	emptyTags := true
 
 	opts := build.TagOptions{}
 
-	for _, tag := range *cmdData.Tag {
+	for _, tag := range tagStrategy.Tags {
 		err := slug.ValidateDockerTag(tag)
 		if err != nil {
 			return build.TagOptions{}, fmt.Errorf("bad --tag parameter '%s' specified: %s", tag, err)
@@ -63,7 +80,7 @@ func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error
 		emptyTags = false
 	}
 
-	if *cmdData.TagBranch {
+	if tagStrategy.Branch {
 		localGitRepo := &git_repo.Local{
 			Path:   projectDir,
 			GitDir: path.Join(projectDir, ".git"),
@@ -78,7 +95,7 @@ func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error
 		emptyTags = false
 	}
 
-	if *cmdData.TagCommit {
+	if tagStrategy.Commit {
 		localGitRepo := &git_repo.Local{
 			Path:   projectDir,
 			GitDir: path.Join(projectDir, ".git"),
@@ -93,7 +110,7 @@ func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error
 		emptyTags = false
 	}
 
-	if *cmdData.TagBuildID {
+	if tagStrategy.BuildID {
 		var buildID string
 
 		if os.Getenv("GITLAB_CI") != "" {
@@ -113,7 +130,7 @@ func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error
 		}
 	}
 
-	if *cmdData.TagCI {
+	if tagStrategy.CI {
 		var gitBranch, gitTag string
 
 		if os.Getenv("GITLAB_CI") != "" {
@@ -143,6 +160,36 @@ func GetTagOptions(cmdData *CmdData, projectDir string) (build.TagOptions, error
 		}
 	}
 
+	if len(tagStrategy.Custom) > 0 {
+		localGitRepo := &git_repo.Local{
+			Path:   projectDir,
+			GitDir: path.Join(projectDir, ".git"),
+		}
+
+		metadata, err := localGitRepo.HeadMetadata()
+		if err != nil {
+			return build.TagOptions{}, fmt.Errorf("cannot get local git metadata for --tag-custom option: %s", err)
+		}
+
+		// HEAD is detached in CI jobs, so branch is taken from CI environment
+		if metadata.Branch == "" {
+			metadata.Branch = os.Getenv("CI_COMMIT_REF_NAME")
+		}
+		if metadata.Tag == "" {
+			metadata.Tag = os.Getenv("CI_COMMIT_TAG")
+		}
+
+		for _, tagTemplate := range tagStrategy.Custom {
+			tag, err := tag_template.Render(tagTemplate, metadata)
+			if err != nil {
+				return build.TagOptions{}, fmt.Errorf("bad --tag-custom parameter: %s", err)
+			}
+
+			opts.Tags = append(opts.Tags, tag)
+			emptyTags = false
+		}
+	}
+
 	if emptyTags {
 		opts.Tags = append(opts.Tags, "latest")
 	}
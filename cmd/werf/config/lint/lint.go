@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "lint",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check werf.yaml for common mistakes",
+		Long: common.GetLongCommandDescription(`Check werf.yaml for common mistakes, which do not break config parsing, but most likely lead to unexpected build result:
+* git mappings, which add or includePaths match no files at HEAD of the project git repo;
+* overlapping destinations of git mappings and imports of the same image;
+* stageDependencies patterns, which match no files of the git mapping, so the stage is never rebuilt on changes;
+* artifact imports of the paths, which are added into the artifact by git mapping, but do not exist in the mapping.
+
+Files are checked at HEAD commit of the project git repo, uncommitted changes are not taken into account. Remote git mappings are not checked.
+
+Command exits with non-zero code when problems are found.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint()
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runLint() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	var headFiles []string
+	if _, err := os.Stat(filepath.Join(projectDir, ".git")); err == nil {
+		localGitRepo := &git_repo.Local{Path: projectDir, GitDir: filepath.Join(projectDir, ".git")}
+
+		headFiles, err = getHeadFiles(localGitRepo)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.LogWarningF("WARNING: Project dir %s is not a git repo: git mappings are not checked\n", projectDir)
+	}
+
+	warnings := config.Lint(werfConfig, headFiles)
+	for _, warning := range warnings {
+		fmt.Printf("%s\n", warning)
+	}
+
+	if len(warnings) > 0 {
+		return fmt.Errorf("%d problem(s) found in werf config", len(warnings))
+	}
+
+	fmt.Printf("No problems found in werf config\n")
+
+	return nil
+}
+
+func getHeadFiles(localGitRepo *git_repo.Local) ([]string, error) {
+	if isEmpty, err := localGitRepo.IsEmpty(); err != nil {
+		return nil, fmt.Errorf("unable to check git repo %s: %s", localGitRepo.Path, err)
+	} else if isEmpty {
+		return []string{}, nil
+	}
+
+	commit, err := localGitRepo.HeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get head commit of git repo %s: %s", localGitRepo.Path, err)
+	}
+
+	files, err := localGitRepo.CommitFilesList(commit, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files of commit %s: %s", commit, err)
+	}
+
+	return files, nil
+}
@@ -7,9 +7,10 @@ import (
 
 	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/deploy"
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/project_tmp_dir"
 	"github.com/flant/werf/pkg/ssh_agent"
@@ -30,6 +31,10 @@ var CmdData struct {
 	RegistryUsername string
 	RegistryPassword string
 	WithoutRegistry  bool
+
+	Validate bool
+
+	AllowAdoptionByRelease bool
 }
 
 var CommonCmdData common.CmdData
@@ -46,12 +51,12 @@ Deploy needs the same parameters as push to construct image names: repo and tags
 
 Helm chart directory .helm should exists and contain valid Helm chart.
 
-Environment is a required param for the deploy by default, because it is needed to construct Helm Release name and Kubernetes Namespace. Either --environment or CI_ENVIRONMENT_SLUG should be specified for command.
+Environment is a required param for the deploy by default, because it is needed to construct Helm Release name and Kubernetes Namespace. Either --env or CI_ENVIRONMENT_SLUG should be specified for command.
 
 Read more info about Helm chart structure, Helm Release name, Kubernetes Namespace and how to change it: https://flant.github.io/werf/reference/deploy/deploy_to_kubernetes.html`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfHome, common.WerfTmp),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfSSHKnownHosts, common.WerfHelmReleaseStorageNamespace, common.WerfHelmReleaseStorageType, common.WerfHome, common.WerfTmp),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runDeploy()
@@ -66,6 +71,7 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	common.SetupDir(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 	common.SetupSSHKey(&CommonCmdData, cmd)
 
 	cmd.Flags().IntVarP(&CmdData.Timeout, "timeout", "t", 0, "watch timeout in seconds")
@@ -79,12 +85,16 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username")
 	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password")
 	cmd.Flags().BoolVarP(&CmdData.WithoutRegistry, "without-registry", "", false, "Do not get images info from registry")
+	cmd.Flags().BoolVarP(&CmdData.Validate, "validate", "", false, "Only validate rendered manifests by Kubernetes API server with server-side dry-run (Kubernetes 1.13+), do not deploy")
+	cmd.Flags().BoolVarP(&CmdData.AllowAdoptionByRelease, "allow-adoption-by-release", "", false, "Adopt existing resources of the chart into the release instead of failing with 'already exists' error, even if resources have no werf.io/allow-adoption-by-release annotation (resources of other releases are never adopted)")
 
 	common.SetupTag(&CommonCmdData, cmd)
 	common.SetupEnvironment(&CommonCmdData, cmd)
 	common.SetupRelease(&CommonCmdData, cmd)
 	common.SetupNamespace(&CommonCmdData, cmd)
 	common.SetupKubeContext(&CommonCmdData, cmd)
+	common.SetupHelmReleaseStorage(&CommonCmdData, cmd)
+	common.SetupAddAnnotations(&CommonCmdData, cmd)
 
 	return cmd
 }
@@ -106,6 +116,10 @@ func runDeploy() error {
 		return err
 	}
 
+	if err := deploy.SetReleaseStorage(common.GetHelmReleaseStorageOptions(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
 		return err
 	}
@@ -115,23 +129,29 @@ func runDeploy() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
 	projectTmpDir, err := project_tmp_dir.Get()
 	if err != nil {
 		return fmt.Errorf("getting project tmp dir failed: %s", err)
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
-	projectName := werfConfig.Meta.Project
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
 
 	var repo string
 	if !CmdData.WithoutRegistry {
 		var err error
-		repo, err = common.GetRequiredRepoName(projectName, CmdData.Repo)
+		repo, err = config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 		if err != nil {
 			return err
 		}
@@ -146,11 +166,11 @@ func runDeploy() error {
 		}
 	}
 
-	if err := ssh_agent.Init(*CommonCmdData.SSHKeys); err != nil {
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
 		return fmt.Errorf("cannot initialize ssh-agent: %s", err)
 	}
 
-	tag, err := common.GetDeployTag(&CommonCmdData, projectDir)
+	tag, err := common.GetDeployTag(&CommonCmdData, projectDir, werfConfig)
 	if err != nil {
 		return err
 	}
@@ -164,23 +184,33 @@ func runDeploy() error {
 		return fmt.Errorf("cannot initialize kube: %s", err)
 	}
 
-	release, err := common.GetHelmRelease(*CommonCmdData.Release, *CommonCmdData.Environment, werfConfig)
+	release, err := deploy.GetHelmRelease(*CommonCmdData.Release, common.GetEnvironment(&CommonCmdData), werfConfig)
 	if err != nil {
 		return err
 	}
 
-	namespace, err := common.GetKubernetesNamespace(*CommonCmdData.Namespace, *CommonCmdData.Environment, werfConfig)
+	namespace, err := deploy.GetKubernetesNamespace(*CommonCmdData.Namespace, common.GetEnvironment(&CommonCmdData), werfConfig)
+	if err != nil {
+		return err
+	}
+
+	annotations, err := common.GetAddAnnotations(&CommonCmdData)
 	if err != nil {
 		return err
 	}
 
 	return deploy.RunDeploy(projectDir, repo, tag, release, namespace, werfConfig, deploy.DeployOptions{
+		Environment:     common.GetEnvironment(&CommonCmdData),
 		Values:          CmdData.Values,
 		SecretValues:    CmdData.SecretValues,
 		Set:             CmdData.Set,
 		SetString:       CmdData.SetString,
 		Timeout:         time.Duration(CmdData.Timeout) * time.Second,
 		WithoutRegistry: CmdData.WithoutRegistry,
+		Validate:        CmdData.Validate,
+		Annotations:     annotations,
 		KubeContext:     kubeContext,
+
+		AllowAdoptionByRelease: CmdData.AllowAdoptionByRelease,
 	})
 }
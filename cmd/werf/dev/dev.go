@@ -0,0 +1,198 @@
+package dev
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/dev_mode"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/download"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	PullUsername string
+	PullPassword string
+
+	SyncInterval time.Duration
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev [IMAGE_NAME]",
+		Short: "Run image in developer mode syncing changed project files into the container",
+		Long: common.GetLongCommandDescription(`Run image in developer mode.
+
+Werf builds the image once, runs the dev container from the image and syncs changed project files into the container instead of building new git patch stage for every local edit. Files are mapped into the container according to local git mappings of the image. If changed files are in stageDependencies of user stages, werf re-runs the earliest affected stage and all the following stages in the container (only shell stages are supported).
+
+IMAGE_NAME parameter is required if werf.yaml contains more than one image.
+
+The dev container is labeled with werf-dev-mode label and is removed when command is terminated or by werf reset.`),
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.MaximumNArgs(1),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch, common.WerfTakeSubmodulesFromWorkTree, common.WerfRefreshDownloads, common.WerfForceRefresh, common.WerfPatchesTmpDir, common.WerfArchivesTmpDir, common.WerfWorkTreesDir, common.WerfHostMaxBuilds, common.WerfProjectMaxBuilds),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runDev(args)
+			if err != nil {
+				return fmt.Errorf("dev failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupTakeSubmodulesFromWorkTree(&CommonCmdData, cmd)
+	common.SetupRefreshDownloads(&CommonCmdData, cmd)
+	common.SetupForceRefresh(&CommonCmdData, cmd)
+	common.SetupGitTmpDirs(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.PullUsername, "registry-username", "", "", "Docker registry username to authorize pull of base images")
+	cmd.Flags().StringVarP(&CmdData.PullPassword, "registry-password", "", "", "Docker registry password to authorize pull of base images")
+
+	cmd.Flags().DurationVarP(&CmdData.SyncInterval, "sync-interval", "", time.Second, "Interval of checking project directory for changed files")
+
+	return cmd
+}
+
+func runDev(args []string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	imageConfig, err := getImageConfig(werfConfig, args)
+	if err != nil {
+		return err
+	}
+
+	if imageConfig.Ansible != nil {
+		logger.LogWarningF("WARNING: ansible stages of image %s are not re-run in dev mode, only files are synced\n", imageConfig.Name)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
+	if err != nil {
+		return fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	dockerAuthorizer, err := docker_authorizer.GetBuildDockerAuthorizer(projectTmpDir, CmdData.PullUsername, CmdData.PullPassword)
+	if err != nil {
+		return err
+	}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+	download.Refresh = common.GetRefreshDownloads(&CommonCmdData)
+	git_repo.ForceRefresh = common.GetForceRefresh(&CommonCmdData)
+	common.SetGitTmpDirs(&CommonCmdData)
+
+	c := build.NewConveyor(werfConfig, []string{imageConfig.Name}, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
+	c.SetTakeSubmodulesFromWorkTree(common.GetTakeSubmodulesFromWorkTree(&CommonCmdData))
+	if err = c.Build(werf.GetContext(), build.BuildOptions{}); err != nil {
+		return err
+	}
+
+	devContainer := &dev_mode.DevContainer{
+		Name:       dev_mode.GetDevContainerName(projectName, imageConfig.Name),
+		ImageName:  c.GetImageLatestStageImageName(imageConfig.Name),
+		ProjectDir: projectDir,
+		Mappings:   dev_mode.GetMappings(imageConfig),
+		UserStages: dev_mode.GetUserStages(imageConfig),
+	}
+
+	if err := devContainer.Run(projectName); err != nil {
+		return err
+	}
+	defer func() {
+		if err := devContainer.Remove(); err != nil {
+			logger.LogWarningF("WARNING: %s\n", err)
+		}
+	}()
+
+	logger.LogInfoF("Dev container %s is running, syncing changes of %s\n", devContainer.Name, projectDir)
+
+	return devContainer.Watch(werf.GetContext(), CmdData.SyncInterval)
+}
+
+func getImageConfig(werfConfig *config.WerfConfig, args []string) (*config.Image, error) {
+	if len(args) == 0 {
+		if len(werfConfig.Images) != 1 {
+			return nil, fmt.Errorf("IMAGE_NAME should be specified: werf.yaml contains %d images", len(werfConfig.Images))
+		}
+		return werfConfig.Images[0], nil
+	}
+
+	for _, imageConfig := range werfConfig.Images {
+		if imageConfig.Name == args[0] {
+			return imageConfig, nil
+		}
+	}
+
+	return nil, fmt.Errorf("image '%s' not found in werf.yaml", args[0])
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/deploy"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/werf"
@@ -26,10 +27,13 @@ func NewCmd() *cobra.Command {
 
 Helm Release will be purged and optionally Kubernetes Namespace.
 
-Environment is a required param for the dismiss by default, because it is needed to construct Helm Release name and Kubernetes Namespace. Either --environment or CI_ENVIRONMENT_SLUG should be specified for command.
+Environment is a required param for the dismiss by default, because it is needed to construct Helm Release name and Kubernetes Namespace. Either --env or CI_ENVIRONMENT_SLUG should be specified for command.
 
 Read more info about Helm Release name, Kubernetes Namespace and how to change it: https://flant.github.io/werf/reference/deploy/deploy_to_kubernetes.html`),
 		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHelmReleaseStorageNamespace, common.WerfHelmReleaseStorageType),
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runDismiss()
 			if err != nil {
@@ -50,6 +54,7 @@ Read more info about Helm Release name, Kubernetes Namespace and how to change i
 	common.SetupRelease(&CommonCmdData, cmd)
 	common.SetupNamespace(&CommonCmdData, cmd)
 	common.SetupKubeContext(&CommonCmdData, cmd)
+	common.SetupHelmReleaseStorage(&CommonCmdData, cmd)
 
 	return cmd
 }
@@ -67,12 +72,20 @@ func runDismiss() error {
 		return err
 	}
 
+	if err := deploy.SetReleaseStorage(common.GetHelmReleaseStorageOptions(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	projectDir, err := common.GetProjectDir(&CommonCmdData)
 	if err != nil {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
@@ -86,12 +99,12 @@ func runDismiss() error {
 		return fmt.Errorf("cannot initialize kube: %s", err)
 	}
 
-	release, err := common.GetHelmRelease(*CommonCmdData.Release, *CommonCmdData.Environment, werfConfig)
+	release, err := deploy.GetHelmRelease(*CommonCmdData.Release, common.GetEnvironment(&CommonCmdData), werfConfig)
 	if err != nil {
 		return err
 	}
 
-	namespace, err := common.GetKubernetesNamespace(*CommonCmdData.Namespace, *CommonCmdData.Environment, werfConfig)
+	namespace, err := deploy.GetKubernetesNamespace(*CommonCmdData.Namespace, common.GetEnvironment(&CommonCmdData), werfConfig)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Image string
+	To    string
+	Repo  string
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export built image without a registry",
+		Long: common.GetLongCommandDescription(`Export built image from werf.yaml into OCI image layout directory or docker archive file.
+
+Stages cache should exists for the image to be exported. I.e. image should be built with build command before exporting. Exported images names are constructed from parameters as REPO/IMAGE_NAME:TAG, project name is used as REPO by default.
+
+The result can be delivered into air-gapped environments and loaded with docker load (docker-archive format) or copied into a registry with OCI tools (oci format).`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfContainerRuntime, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runExport()
+			if err != nil {
+				return fmt.Errorf("export failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
+	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.Image, "image", "", "", "Image name from werf.yaml to export (nameless image by default)")
+	cmd.Flags().StringVarP(&CmdData.To, "to", "", "", "Export destination: oci:PATH for OCI image layout directory or docker-archive:FILE for docker save format (required)")
+	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name for exported image names. CI_REGISTRY_IMAGE will be used by default if available, otherwise project name.")
+
+	common.SetupTag(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runExport() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	if CmdData.To == "" {
+		return fmt.Errorf("--to option required!")
+	}
+
+	format, path, err := build.ParseExportDestination(CmdData.To)
+	if err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
+	if err != nil {
+		return fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	repo := config.GetOptionalRepoName(werfConfig, CmdData.Repo)
+	if repo == "" {
+		repo = projectName
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	exportOpts := build.ExportOptions{TagOptions: tagOpts, Format: format, Path: path}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+
+	c := build.NewConveyor(werfConfig, []string{CmdData.Image}, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, nil)
+	c.SetPlatforms(platforms)
+	if err = c.Export(werf.GetContext(), CmdData.Image, repo, exportOpts); err != nil {
+		return err
+	}
+
+	return nil
+}
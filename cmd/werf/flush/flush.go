@@ -6,7 +6,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker_authorizer"
 	"github.com/flant/werf/pkg/cleanup"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/lock"
@@ -21,6 +22,8 @@ var CmdData struct {
 
 	WithImages bool
 
+	DiscoverImagesRepos bool
+
 	DryRun bool
 }
 
@@ -44,7 +47,7 @@ Flush requires read-write permissions to delete images from Docker registry. Sta
 
 See more info about authorization: https://flant.github.io/werf/reference/registry/authorization.html`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfInsecureRegistry, common.WerfHome),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfInsecureRegistry, common.WerfRegistryConcurrency, common.WerfHome),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runFlush()
@@ -56,12 +59,15 @@ See more info about authorization: https://flant.github.io/werf/reference/regist
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name")
 	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username (granted read-write permission)")
 	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password (granted read-write permission)")
+	cmd.Flags().BoolVarP(&CmdData.DiscoverImagesRepos, "discover-images-repos", "", false, "Also process repositories REPO/IMAGE_NAME found in the registry catalog (or with the registry provider API), which are not described in werf.yaml")
 
 	cmd.Flags().BoolVarP(&CmdData.WithImages, "with-images", "", false, "Delete images (not only stages cache)")
 
@@ -84,14 +90,22 @@ func runFlush() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	repoName := common.GetOptionalRepoName(projectName, CmdData.Repo)
+	repoName := config.GetOptionalRepoName(werfConfig, CmdData.Repo)
 
 	if repoName != "" {
 		if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
@@ -119,9 +133,10 @@ func runFlush() error {
 		}
 
 		commonRepoOptions := cleanup.CommonRepoOptions{
-			Repository:  repoName,
-			ImagesNames: imageNames,
-			DryRun:      CmdData.DryRun,
+			Repository:          repoName,
+			ImagesNames:         imageNames,
+			DryRun:              CmdData.DryRun,
+			DiscoverImagesRepos: CmdData.DiscoverImagesRepos,
 		}
 
 		if err := cleanup.RepoImagesFlush(CmdData.WithImages, commonRepoOptions); err != nil {
@@ -134,8 +149,9 @@ func runFlush() error {
 	}
 
 	commonProjectOptions := cleanup.CommonProjectOptions{
-		ProjectName:   projectName,
-		CommonOptions: cleanup.CommonOptions{DryRun: CmdData.DryRun},
+		ProjectName:     projectName,
+		StagesNamespace: werfConfig.Meta.StagesNamespace,
+		CommonOptions:   cleanup.CommonOptions{DryRun: CmdData.DryRun},
 	}
 
 	if err := cleanup.ProjectImagesFlush(CmdData.WithImages, commonProjectOptions); err != nil {
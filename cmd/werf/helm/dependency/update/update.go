@@ -0,0 +1,66 @@
+package update
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update dependencies of the project helm chart",
+		Long: common.GetLongCommandDescription(`Update dependencies of the project helm chart.
+
+Dependencies of .helm/requirements.yaml (or .helm/Chart.yaml for apiVersion v2 charts) are resolved to the latest versions satisfying version constraints using fresh indexes of the chart repositories. Resolved versions are saved into the lock file .helm/requirements.lock (.helm/Chart.lock) and charts are downloaded into .helm/charts.
+
+Deploy, render and lint commands use versions from the lock file. Updates of the same chart by parallel jobs are serialized with werf lock.`),
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHome, common.WerfTmp),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runUpdate()
+			if err != nil {
+				return fmt.Errorf("helm dependency update failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runUpdate() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	return deploy.UpdateChartDependencies(werf.GetContext(), filepath.Join(projectDir, deploy.ProjectHelmChartDir))
+}
@@ -0,0 +1,130 @@
+package get
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Revision int
+}
+
+var CommonCmdData common.CmdData
+
+var getSubcommands = []string{"values", "manifest", "hooks", "notes"}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [values|manifest|hooks|notes] RELEASE",
+		Short: "Download details of the project helm release",
+		Long: common.GetLongCommandDescription(`Download details of the project helm release with helm get.
+
+Only releases deployed by werf for the project of werf.yaml are allowed: release values should contain werf service values of the project.`),
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.RangeArgs(1, 2),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHelmReleaseStorageNamespace, common.WerfHelmReleaseStorageType),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runGet(args)
+			if err != nil {
+				return fmt.Errorf("helm get failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupKubeContext(&CommonCmdData, cmd)
+	common.SetupHelmReleaseStorage(&CommonCmdData, cmd)
+
+	cmd.Flags().IntVarP(&CmdData.Revision, "revision", "", 0, "Get the named release with revision (use the latest revision by default)")
+
+	return cmd
+}
+
+func runGet(args []string) error {
+	var helmArgs []string
+	releaseName := args[len(args)-1]
+
+	if len(args) == 2 {
+		if !isGetSubcommand(args[0]) {
+			return fmt.Errorf("unknown helm get subcommand '%s': one of %v expected", args[0], getSubcommands)
+		}
+		helmArgs = append(helmArgs, args[0])
+	}
+
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.SetReleaseStorage(common.GetHelmReleaseStorageOptions(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	kubeContext := os.Getenv("KUBECONTEXT")
+	if kubeContext == "" {
+		kubeContext = *CommonCmdData.KubeContext
+	}
+	if err := kube.Init(kube.InitOptions{KubeContext: kubeContext}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := deploy.ValidateProjectRelease(releaseName, werfConfig.Meta.Project); err != nil {
+		return err
+	}
+
+	helmArgs = append([]string{"get"}, append(helmArgs, releaseName)...)
+	if CmdData.Revision != 0 {
+		helmArgs = append(helmArgs, "--revision", fmt.Sprintf("%d", CmdData.Revision))
+	}
+	if kubeContext != "" {
+		helmArgs = append(helmArgs, "--kube-context", kubeContext)
+	}
+
+	return deploy.HelmPassthroughCmd(helmArgs...)
+}
+
+func isGetSubcommand(name string) bool {
+	for _, subcommand := range getSubcommands {
+		if subcommand == name {
+			return true
+		}
+	}
+
+	return false
+}
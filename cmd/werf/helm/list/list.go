@@ -0,0 +1,115 @@
+package list
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List helm releases of the project",
+		Long: common.GetLongCommandDescription(`List helm releases of the project with helm list.
+
+Only releases deployed by werf for the project of werf.yaml are listed: release values should contain werf service values of the project. Releases in all states are listed.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHelmReleaseStorageNamespace, common.WerfHelmReleaseStorageType),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runList()
+			if err != nil {
+				return fmt.Errorf("helm list failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupKubeContext(&CommonCmdData, cmd)
+	common.SetupHelmReleaseStorage(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runList() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.SetReleaseStorage(common.GetHelmReleaseStorageOptions(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	kubeContext := os.Getenv("KUBECONTEXT")
+	if kubeContext == "" {
+		kubeContext = *CommonCmdData.KubeContext
+	}
+	if err := kube.Init(kube.InitOptions{KubeContext: kubeContext}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	releases, err := deploy.GetProjectReleases(werfConfig.Meta.Project)
+	if err != nil {
+		return err
+	}
+
+	if len(releases) == 0 {
+		fmt.Printf("No releases of project %s found\n", werfConfig.Meta.Project)
+		return nil
+	}
+
+	var quotedReleases []string
+	for _, release := range releases {
+		quotedReleases = append(quotedReleases, regexp.QuoteMeta(release))
+	}
+
+	// helm list filter is a regular expression of release names
+	helmArgs := []string{"list", "--all", fmt.Sprintf("^(%s)$", strings.Join(quotedReleases, "|"))}
+	if kubeContext != "" {
+		helmArgs = append(helmArgs, "--kube-context", kubeContext)
+	}
+
+	return deploy.HelmPassthroughCmd(helmArgs...)
+}
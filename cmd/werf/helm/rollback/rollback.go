@@ -0,0 +1,103 @@
+package rollback
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback RELEASE REVISION",
+		Short: "Roll back the project helm release to a previous revision",
+		Long: common.GetLongCommandDescription(`Roll back the project helm release to a previous revision with helm rollback.
+
+Only releases deployed by werf for the project of werf.yaml are allowed: release values should contain werf service values of the project. The release is locked during rollback, so rollback does not interfere with werf deploy of the same release on the host.`),
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(2),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHelmReleaseStorageNamespace, common.WerfHelmReleaseStorageType),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runRollback(args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("helm rollback failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupKubeContext(&CommonCmdData, cmd)
+	common.SetupHelmReleaseStorage(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runRollback(releaseName, revisionArg string) error {
+	revision, err := strconv.Atoi(revisionArg)
+	if err != nil || revision <= 0 {
+		return fmt.Errorf("bad revision '%s': positive integer expected", revisionArg)
+	}
+
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.SetReleaseStorage(common.GetHelmReleaseStorageOptions(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	kubeContext := os.Getenv("KUBECONTEXT")
+	if kubeContext == "" {
+		kubeContext = *CommonCmdData.KubeContext
+	}
+	if err := kube.Init(kube.InitOptions{KubeContext: kubeContext}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := deploy.ValidateProjectRelease(releaseName, werfConfig.Meta.Project); err != nil {
+		return err
+	}
+
+	return deploy.RollbackHelmRelease(releaseName, revision, deploy.CommonHelmOptions{KubeContext: kubeContext})
+}
@@ -0,0 +1,99 @@
+package cleanup
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	GitClonesMaxAge  string
+	GitClonesMaxSize string
+	DryRun           bool
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Cleanup werf cache of the host",
+		Long: common.GetLongCommandDescription(`Cleanup werf cache of the host.
+
+Command removes cached clones of remote git repos, which are shared between projects and branches and otherwise are kept forever:
+* clones, which were not used longer than --git-clones-max-age (e.g. 720h);
+* least recently used clones until total size of clones fits into --git-clones-max-size (e.g. 10GiB).
+
+Per-project clones of older werf versions are removed by the same rules.
+
+Clone is removed with the clone lock held, so clones used by running werf processes are not removed. Clones used during the last hour are always kept.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfGitClonesMaxAge, common.WerfGitClonesMaxSize),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runCleanup()
+			if err != nil {
+				return fmt.Errorf("host cleanup failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.GitClonesMaxAge, "git-clones-max-age", "", os.Getenv(string(common.WerfGitClonesMaxAge)), "Remove clones of remote git repos, which were not used longer than specified duration, e.g. 720h (default $WERF_GIT_CLONES_MAX_AGE)")
+	cmd.Flags().StringVarP(&CmdData.GitClonesMaxSize, "git-clones-max-size", "", os.Getenv(string(common.WerfGitClonesMaxSize)), "Remove least recently used clones of remote git repos until total size of clones fits into specified size, e.g. 10GiB (default $WERF_GIT_CLONES_MAX_SIZE)")
+	cmd.Flags().BoolVarP(&CmdData.DryRun, "dry-run", "", false, "Indicate what the command would do without actually doing that")
+
+	return cmd
+}
+
+func runCleanup() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	opts := git_repo.ClonesCleanupOptions{DryRun: CmdData.DryRun}
+
+	if CmdData.GitClonesMaxAge != "" {
+		maxAge, err := time.ParseDuration(CmdData.GitClonesMaxAge)
+		if err != nil {
+			return fmt.Errorf("bad --git-clones-max-age '%s': %s", CmdData.GitClonesMaxAge, err)
+		}
+		if maxAge < 0 {
+			return fmt.Errorf("bad --git-clones-max-age '%s': positive duration expected", CmdData.GitClonesMaxAge)
+		}
+		opts.MaxAge = maxAge
+	}
+
+	if CmdData.GitClonesMaxSize != "" {
+		maxSize, err := units.RAMInBytes(CmdData.GitClonesMaxSize)
+		if err != nil {
+			return fmt.Errorf("bad --git-clones-max-size '%s': %s", CmdData.GitClonesMaxSize, err)
+		}
+		if maxSize < 0 {
+			return fmt.Errorf("bad --git-clones-max-size '%s': positive size expected", CmdData.GitClonesMaxSize)
+		}
+		opts.MaxSize = maxSize
+	}
+
+	if opts.MaxAge == 0 && opts.MaxSize == 0 {
+		return fmt.Errorf("--git-clones-max-age or --git-clones-max-size option required")
+	}
+
+	return git_repo.CleanupClones(opts)
+}
@@ -0,0 +1,66 @@
+package pull
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Pull dappdeps images",
+		Long: common.GetLongCommandDescription(`Pull dappdeps images.
+
+Dappdeps images (toolchain, base, ansible and gitartifact) provide service tools to the stages containers and are pulled on demand during build. Command pulls all these images in advance, e.g. to check registry mirror specified by --dappdeps-repo option or to prepare the host before build in air-gapped environment.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDappdepsRepo, common.WerfDappdepsVersions, common.WerfContainerRuntime, common.WerfDockerConfig),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPull()
+			if err != nil {
+				return fmt.Errorf("dappdeps pull failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runPull() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	return dappdeps.PullImages(werf.GetContext())
+}
@@ -0,0 +1,92 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that dappdeps images are available on the host",
+		Long: common.GetLongCommandDescription(`Verify that dappdeps images are available on the host.
+
+Command checks that dappdeps images (toolchain, base, ansible and gitartifact) of the configured repo and versions exist locally, and that dappdeps containers, which provide service tools volumes to the stages containers, are created from these images. Command fails if some images are missing, so it can be used to check the host before build in air-gapped environment.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDappdepsRepo, common.WerfDappdepsVersions, common.WerfContainerRuntime, common.WerfDockerConfig),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runVerify()
+			if err != nil {
+				return fmt.Errorf("dappdeps verify failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runVerify() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	statuses, err := dappdeps.VerifyImages()
+	if err != nil {
+		return err
+	}
+
+	var notReady []string
+	for _, status := range statuses {
+		var state string
+		switch {
+		case !status.ImageExists:
+			state = "image not found, run werf host dappdeps pull"
+		case status.ContainerImageMismatch:
+			state = fmt.Sprintf("container %s is created from another image, remove the container", status.ContainerName)
+		case status.ContainerExists:
+			state = fmt.Sprintf("ok, container %s", status.ContainerName)
+		default:
+			state = "ok, container will be created on demand"
+		}
+
+		fmt.Printf("%s %s: %s\n", status.Name, status.ImageName, state)
+
+		if !status.IsReady() {
+			notReady = append(notReady, status.Name)
+		}
+	}
+
+	if len(notReady) > 0 {
+		return fmt.Errorf("dappdeps images are not ready: %s", strings.Join(notReady, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package df
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/home_usage"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "df",
+		Short: "Print disk usage of werf home",
+		Long: common.GetLongCommandDescription(`Print disk usage of werf home.
+
+Command reports total size of werf home, configured quota and size of werf home parts: cached clones of git repos, git worktrees, git checksums cache, helm data and tmp files. Tracked cache entries are listed from least to most recently used, in the order of eviction when werf home quota is exceeded.
+
+Quota is configured with homeQuota field of global config (~/.werf/config.yaml) or WERF_HOME_QUOTA (e.g. 10GiB). When quota is exceeded, least recently used clones and worktrees are evicted before build starts.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfHomeQuota),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runDf()
+			if err != nil {
+				return fmt.Errorf("df failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runDf() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	usage, err := home_usage.GetUsage()
+	if err != nil {
+		return err
+	}
+
+	if usage.Quota != 0 {
+		fmt.Printf("Werf home %s: %s of %s quota\n", usage.HomeDir, units.HumanSize(float64(usage.Size)), units.HumanSize(float64(usage.Quota)))
+	} else {
+		fmt.Printf("Werf home %s: %s, no quota\n", usage.HomeDir, units.HumanSize(float64(usage.Size)))
+	}
+
+	for _, category := range usage.Categories {
+		fmt.Printf("  %s: %s\n", category.Name, units.HumanSize(float64(category.Size)))
+	}
+
+	fmt.Printf("Cache entries (least recently used first): %d\n", len(usage.Entries))
+	for _, entry := range usage.Entries {
+		fmt.Printf("  %s %s %s, last used %s\n", entry.Kind, entry.Path, units.HumanSize(float64(entry.Size)), entry.LastUsed.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ls
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List werf locks held on the host",
+		Long: common.GetLongCommandDescription(`List werf locks held on the host.
+
+For each lock the holder process, host and acquisition time are printed. Locks held by processes that are not running anymore are marked as stale.`),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runLs()
+			if err != nil {
+				return fmt.Errorf("locks ls failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runLs() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	infos, err := lock.List()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsStale() {
+			fmt.Printf("%s %s [stale]\n", info.Name, info.String())
+		} else {
+			fmt.Printf("%s %s\n", info.Name, info.String())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package rm
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Stale bool
+	Force bool
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm [LOCK_NAME...]",
+		Short: "Remove holders info of werf locks",
+		Long: common.GetLongCommandDescription(`Remove holders info of werf locks, which is reported while waiting for the lock and by werf host locks ls command.
+
+Lock files are not removed: the lock is released by the system, when the holder process exits, so stale holders info is all that remains of the lock of the killed process. Info of the running holders is kept unless --force option is specified (holders of other hosts are considered running), the lock is still held by such processes.
+
+Use option --stale to remove info of all locks, which holders are not running anymore.`),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !CmdData.Stale {
+				return fmt.Errorf("LOCK_NAME or --stale option required")
+			}
+
+			err := runRm(args)
+			if err != nil {
+				return fmt.Errorf("locks rm failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&CmdData.Stale, "stale", "", false, "Remove info of all locks, which holders are not running anymore")
+	cmd.Flags().BoolVarP(&CmdData.Force, "force", "", false, "Remove info of the running holders too")
+
+	return cmd
+}
+
+func runRm(names []string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if CmdData.Stale {
+		infos, err := lock.List()
+		if err != nil {
+			return err
+		}
+
+		// lock is stale only when all its holders are not running
+		isStaleByName := map[string]bool{}
+		for _, info := range infos {
+			isStale, ok := isStaleByName[info.Name]
+			isStaleByName[info.Name] = info.IsStale() && (!ok || isStale)
+		}
+
+		for _, info := range infos {
+			if isStaleByName[info.Name] && !util.IsStringsContainValue(names, info.Name) {
+				names = append(names, info.Name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if err := lock.ForceRelease(name, CmdData.Force); err != nil {
+			return fmt.Errorf("cannot release lock `%s`: %s", name, err)
+		}
+
+		fmt.Printf("Removed lock `%s` holders info\n", name)
+	}
+
+	return nil
+}
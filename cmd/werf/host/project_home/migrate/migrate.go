@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Relocate project data from shared werf home into the project subtree",
+		Long: common.GetLongCommandDescription(`Relocate project data from shared werf home into the project subtree.
+
+Build dir of the project (including cached clones of remote git repos) and git worktree of the project directory are moved from shared werf home (~/.werf) into the project subtree (~/.werf/projects/PROJECT_DIR_HASH), which is used with --werf-project-home option. Data, which already exists in the project subtree, is not overwritten.
+
+Command should be run when there are no running werf processes of the project.`),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runMigrate()
+			if err != nil {
+				return fmt.Errorf("project home migrate failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runMigrate() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	// project dir is taken directly: werf home should not be switched to the project subtree
+	projectDir := *CommonCmdData.Dir
+	if projectDir == "" {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		projectDir = currentDir
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	baseHomeDir := werf.GetBaseHomeDir()
+
+	projectHomeDir, err := werf.PrepareProjectHomeDir(projectDir)
+	if err != nil {
+		return fmt.Errorf("cannot prepare project werf home: %s", err)
+	}
+
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return err
+	}
+
+	workTreeDir := (&git_repo.Local{Path: absProjectDir}).GetWorkTreeDir()
+	workTreeRelPath, err := filepath.Rel(baseHomeDir, workTreeDir)
+	if err != nil {
+		return err
+	}
+
+	relocations := []struct {
+		RelPath  string
+		LockName string
+	}{
+		{filepath.Join("builds", werfConfig.Meta.Project), ""},
+		{workTreeRelPath, git_repo.WorkTreeLockName(workTreeDir)},
+	}
+
+	for _, relocation := range relocations {
+		src := filepath.Join(baseHomeDir, relocation.RelPath)
+		dst := filepath.Join(projectHomeDir, relocation.RelPath)
+
+		f := func() error {
+			return relocate(src, dst)
+		}
+
+		if relocation.LockName == "" {
+			err = f()
+		} else {
+			err = lock.WithLock(relocation.LockName, lock.LockOptions{Timeout: 600 * time.Second}, f)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Project werf home: %s\n", projectHomeDir)
+
+	return nil
+}
+
+func relocate(src, dst string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		fmt.Printf("Nothing to relocate: %s does not exist\n", src)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dst); err == nil {
+		logger.LogWarningF("WARNING: %s is not relocated: %s already exists\n", src, dst)
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("cannot move %s to %s: %s", src, dst, err)
+	}
+
+	fmt.Printf("Relocated %s to %s\n", src, dst)
+
+	return nil
+}
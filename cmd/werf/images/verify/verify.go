@@ -0,0 +1,195 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/sign"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Repo string
+
+	RegistryUsername string
+	RegistryPassword string
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [IMAGE_NAME...]",
+		Short: "Verify signatures of the published images",
+		Long: common.GetLongCommandDescription(`Verify signatures of the published images.
+
+Images REPO/IMAGE_NAME:TAG are resolved into digests and verified by the signer: cosign checks signatures with the public key specified by --sign-key, notation checks signatures with the configured trust policy. Tags are specified the same way as for werf push.
+
+If one or more IMAGE_NAME parameters specified, werf will verify only these images from werf.yaml.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfHome, common.WerfTmp, common.WerfSignKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runVerify(args)
+			if err != nil {
+				return fmt.Errorf("images verify failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
+	common.SetupSignKey(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name of the published images. CI_REGISTRY_IMAGE will be used by default if available.")
+
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username to authorize pull from the docker repo")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password to authorize pull from the docker repo")
+
+	common.SetupTag(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runVerify(imagesToProcess []string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
+	for _, imageName := range imagesToProcess {
+		if !isImageInConfig(werfConfig, imageName) {
+			return fmt.Errorf("specified image '%s' isn't defined in werf.yaml", imageName)
+		}
+	}
+
+	signOpts, err := common.GetSignKeyOptions(&CommonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	repo, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
+	if err != nil {
+		return err
+	}
+
+	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	dockerAuthorizer, err := docker_authorizer.GetImagesPullDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, repo)
+	if err != nil {
+		return err
+	}
+
+	if err := dockerAuthorizer.LoginForPull(repo); err != nil {
+		return fmt.Errorf("login into '%s' for pull failed: %s", repo, err)
+	}
+
+	for _, image := range werfConfig.Images {
+		if len(imagesToProcess) > 0 && !util.IsStringsContainValue(imagesToProcess, image.Name) {
+			continue
+		}
+
+		imageRepository := repo
+		if image.Name != "" {
+			imageRepository = fmt.Sprintf("%s/%s", repo, image.Name)
+		}
+
+		for _, tag := range allTags(tagOpts) {
+			if err := verifyImage(fmt.Sprintf("%s:%s", imageRepository, tag), signOpts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func verifyImage(imageName string, opts sign.Options) error {
+	digest, err := docker_registry.ManifestDigest(imageName)
+	if err != nil {
+		return fmt.Errorf("unable to get image %s digest: %s", imageName, err)
+	}
+
+	reference := sign.DigestReference(imageName, digest)
+
+	logger.LogInfoF("# Verifying image %s (%s)\n", imageName, reference)
+
+	if err := sign.VerifyImage(werf.GetContext(), reference, opts); err != nil {
+		return fmt.Errorf("image %s signature verification failed: %s", imageName, err)
+	}
+
+	return nil
+}
+
+func allTags(opts build.TagOptions) []string {
+	var tags []string
+	for _, schemeTags := range [][]string{opts.Tags, opts.TagsByGitTag, opts.TagsByGitBranch, opts.TagsByGitCommit, opts.TagsByCI} {
+		for _, tag := range schemeTags {
+			if !util.IsStringsContainValue(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
+
+func isImageInConfig(werfConfig *config.WerfConfig, imageName string) bool {
+	for _, image := range werfConfig.Images {
+		if image.Name == imageName {
+			return true
+		}
+	}
+
+	return false
+}
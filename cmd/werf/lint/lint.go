@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/deploy"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/true_git"
@@ -26,7 +27,7 @@ func NewCmd() *cobra.Command {
 		Short: "Run lint procedure for the Helm chart",
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runLint()
@@ -40,6 +41,7 @@ func NewCmd() *cobra.Command {
 	common.SetupDir(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupEnvironment(&CommonCmdData, cmd)
 
 	cmd.Flags().StringArrayVarP(&CmdData.Values, "values", "", []string{}, "Additional helm values")
 	cmd.Flags().StringArrayVarP(&CmdData.SecretValues, "secret-values", "", []string{}, "Additional helm secret values")
@@ -71,12 +73,17 @@ func runLint() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
 	return deploy.RunLint(projectDir, werfConfig, deploy.LintOptions{
+		Environment:  common.GetEnvironment(&CommonCmdData),
 		Values:       CmdData.Values,
 		SecretValues: CmdData.SecretValues,
 		Set:          CmdData.Set,
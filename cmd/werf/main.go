@@ -1,36 +1,75 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/flant/werf/cmd/werf/bp"
 	"github.com/flant/werf/cmd/werf/build"
+	"github.com/flant/werf/cmd/werf/ci_env"
 	"github.com/flant/werf/cmd/werf/cleanup"
 	"github.com/flant/werf/cmd/werf/common"
 	"github.com/flant/werf/cmd/werf/common/templates"
 	"github.com/flant/werf/cmd/werf/completion"
 	"github.com/flant/werf/cmd/werf/deploy"
+	"github.com/flant/werf/cmd/werf/dev"
 	"github.com/flant/werf/cmd/werf/dismiss"
 	"github.com/flant/werf/cmd/werf/docs"
+	"github.com/flant/werf/cmd/werf/export"
 	"github.com/flant/werf/cmd/werf/flush"
 	"github.com/flant/werf/cmd/werf/gc"
 	"github.com/flant/werf/cmd/werf/lint"
+	"github.com/flant/werf/cmd/werf/purge"
 	"github.com/flant/werf/cmd/werf/push"
 	"github.com/flant/werf/cmd/werf/render"
 	"github.com/flant/werf/cmd/werf/reset"
+	"github.com/flant/werf/cmd/werf/server"
+	"github.com/flant/werf/cmd/werf/status"
 	"github.com/flant/werf/cmd/werf/sync"
 	"github.com/flant/werf/cmd/werf/tag"
 	"github.com/flant/werf/cmd/werf/version"
+	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/logger"
 	"github.com/flant/werf/pkg/process_exterminator"
+	"github.com/flant/werf/pkg/telemetry"
+	"github.com/flant/werf/pkg/werf"
 
 	secret_edit "github.com/flant/werf/cmd/werf/secret/edit"
 	secret_extract "github.com/flant/werf/cmd/werf/secret/extract"
 	secret_generate "github.com/flant/werf/cmd/werf/secret/generate"
 	secret_key_generate "github.com/flant/werf/cmd/werf/secret/key_generate"
+	secret_keys "github.com/flant/werf/cmd/werf/secret/keys"
 	secret_regenerate "github.com/flant/werf/cmd/werf/secret/regenerate"
+	secret_values_diff "github.com/flant/werf/cmd/werf/secret/values/diff"
+	secret_verify "github.com/flant/werf/cmd/werf/secret/verify"
+
+	config_lint "github.com/flant/werf/cmd/werf/config/lint"
+
+	ci_refs "github.com/flant/werf/cmd/werf/ci/refs"
+
+	images_verify "github.com/flant/werf/cmd/werf/images/verify"
+
+	helm_dependency_update "github.com/flant/werf/cmd/werf/helm/dependency/update"
+	helm_get "github.com/flant/werf/cmd/werf/helm/get"
+	helm_list "github.com/flant/werf/cmd/werf/helm/list"
+	helm_rollback "github.com/flant/werf/cmd/werf/helm/rollback"
+
+	host_cleanup "github.com/flant/werf/cmd/werf/host/cleanup"
+	host_dappdeps_pull "github.com/flant/werf/cmd/werf/host/dappdeps/pull"
+	host_dappdeps_verify "github.com/flant/werf/cmd/werf/host/dappdeps/verify"
+	host_df "github.com/flant/werf/cmd/werf/host/df"
+	host_locks_ls "github.com/flant/werf/cmd/werf/host/locks/ls"
+	host_locks_rm "github.com/flant/werf/cmd/werf/host/locks/rm"
+	host_project_home_migrate "github.com/flant/werf/cmd/werf/host/project_home/migrate"
+
+	stages_cleanup "github.com/flant/werf/cmd/werf/stages/cleanup"
+	stages_publish "github.com/flant/werf/cmd/werf/stages/publish"
+	stages_pull "github.com/flant/werf/cmd/werf/stages/pull"
 
 	slug_namespace "github.com/flant/werf/cmd/werf/slug/namespace"
 	slug_release "github.com/flant/werf/cmd/werf/slug/release"
@@ -56,6 +95,32 @@ Find more information at https://flant.github.io/werf`),
 		SilenceUsage: true,
 	}
 
+	var globalTimeout time.Duration
+	rootCmd.PersistentFlags().DurationVarP(&globalTimeout, "global-timeout", "", 0, "Terminate command after specified duration, e.g. 30m or 1h (no timeout by default)")
+	var logProgress string
+	rootCmd.PersistentFlags().StringVarP(&logProgress, "log-progress", "", os.Getenv("WERF_LOG_PROGRESS"), "Progress of clone, fetch, archive and push operations: auto, bar, lines or none (default $WERF_LOG_PROGRESS or auto: bars on TTY, periodic percentage lines otherwise)")
+	var dappdepsRepo string
+	rootCmd.PersistentFlags().StringVarP(&dappdepsRepo, "dappdeps-repo", "", os.Getenv("WERF_DAPPDEPS_REPO"), "Pull dappdeps toolchain, base, ansible and gitartifact images from specified repo, e.g. registry mirror in air-gapped environment (default $WERF_DAPPDEPS_REPO or dappdeps repo on Docker Hub)")
+	var dappdepsVersions []string
+	rootCmd.PersistentFlags().StringArrayVarP(&dappdepsVersions, "dappdeps-version", "", strings.Fields(os.Getenv("WERF_DAPPDEPS_VERSIONS")), "Override version of dappdeps image: NAME=VERSION, where NAME is one of toolchain, base, ansible or gitartifact (can be used one or more times, default space separated $WERF_DAPPDEPS_VERSIONS)")
+	var telemetryOptions telemetry.Options
+	rootCmd.PersistentFlags().StringVarP(&telemetryOptions.MetricsFile, "telemetry-metrics-file", "", os.Getenv("WERF_TELEMETRY_METRICS_FILE"), "Write build and deploy metrics in Prometheus text format into specified file on exit, e.g. for node_exporter textfile collector (default $WERF_TELEMETRY_METRICS_FILE)")
+	rootCmd.PersistentFlags().StringVarP(&telemetryOptions.PushgatewayUrl, "telemetry-pushgateway-url", "", os.Getenv("WERF_TELEMETRY_PUSHGATEWAY_URL"), "Push build and deploy metrics into specified Prometheus Pushgateway on exit (default $WERF_TELEMETRY_PUSHGATEWAY_URL)")
+	rootCmd.PersistentFlags().StringVarP(&telemetryOptions.OtlpEndpoint, "telemetry-otlp-endpoint", "", os.Getenv("WERF_TELEMETRY_OTLP_ENDPOINT"), "Export traces of conveyor runs and deploys into specified OTLP/HTTP collector on exit, e.g. http://localhost:4318 (default $WERF_TELEMETRY_OTLP_ENDPOINT)")
+	var projectHome bool
+	rootCmd.PersistentFlags().BoolVarP(&projectHome, "werf-project-home", "", os.Getenv("WERF_PROJECT_HOME") == "1", "Store caches, locks and tmp files of the project in the separate subtree of werf home (~/.werf/projects/PROJECT_DIR_HASH), so that unrelated projects do not interfere (default $WERF_PROJECT_HOME)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		werf.SetGlobalTimeout(globalTimeout)
+		werf.SetProjectHomeEnabled(projectHome)
+		if err := dappdeps.Init(dappdepsRepo, dappdepsVersions); err != nil {
+			return err
+		}
+		if err := telemetry.Init(telemetryOptions); err != nil {
+			return err
+		}
+		return logger.SetProgressMode(logProgress)
+	}
+
 	groups := templates.CommandGroups{
 		{
 			Message: "Build Commands:",
@@ -64,6 +129,10 @@ Find more information at https://flant.github.io/werf`),
 				push.NewCmd(),
 				bp.NewCmd(),
 				tag.NewCmd(),
+				export.NewCmd(),
+				dev.NewCmd(),
+				configCmd(),
+				imagesCmd(),
 			},
 		},
 		{
@@ -74,14 +143,17 @@ Find more information at https://flant.github.io/werf`),
 				lint.NewCmd(),
 				render.NewCmd(),
 				secretCmd(),
+				helmCmd(),
 			},
 		},
 		{
 			Message: "Project Cleanup Commands:",
 			Commands: []*cobra.Command{
 				flush.NewCmd(),
+				purge.NewCmd(),
 				sync.NewCmd(),
 				cleanup.NewCmd(),
+				stagesCmd(),
 			},
 		},
 		{
@@ -89,6 +161,8 @@ Find more information at https://flant.github.io/werf`),
 			Commands: []*cobra.Command{
 				reset.NewCmd(),
 				gc.NewCmd(),
+				status.NewCmd(),
+				hostCmd(),
 			},
 		},
 	}
@@ -98,12 +172,25 @@ Find more information at https://flant.github.io/werf`),
 
 	rootCmd.AddCommand(
 		slugCmd(),
+		ci_env.NewCmd(),
+		ciCmd(),
+		server.NewCmd(),
 		completion.NewCmd(rootCmd),
 		version.NewCmd(),
 		docs.NewCmd(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+
+	if err := telemetry.Flush(); err != nil {
+		logger.LogWarningF("WARNING: Telemetry export failed: %s\n", err)
+	}
+
+	if err != nil {
+		if werf.GetContext().Err() == context.Canceled {
+			os.Exit(17)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -119,6 +206,152 @@ func secretCmd() *cobra.Command {
 		secret_extract.NewCmd(),
 		secret_edit.NewCmd(),
 		secret_regenerate.NewCmd(),
+		secret_keys.NewCmd(),
+		secret_verify.NewCmd(),
+		secretValuesCmd(),
+	)
+
+	return cmd
+}
+
+func secretValuesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Commands to work with secret values files",
+	}
+	cmd.AddCommand(
+		secret_values_diff.NewCmd(),
+	)
+
+	return cmd
+}
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Commands to work with werf.yaml",
+	}
+	cmd.AddCommand(
+		config_lint.NewCmd(),
+	)
+
+	return cmd
+}
+
+func ciCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Commands to get project info for CI jobs and external tools",
+	}
+	cmd.AddCommand(
+		ci_refs.NewCmd(),
+	)
+
+	return cmd
+}
+
+func imagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Commands to work with published images of the project",
+	}
+	cmd.AddCommand(
+		images_verify.NewCmd(),
+	)
+
+	return cmd
+}
+
+func stagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stages",
+		Short: "Commands to work with stages of the project",
+	}
+	cmd.AddCommand(
+		stages_cleanup.NewCmd(),
+		stages_publish.NewCmd(),
+		stages_pull.NewCmd(),
+	)
+
+	return cmd
+}
+
+func helmCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "helm",
+		Short: "Work with helm releases of the project",
+	}
+	cmd.AddCommand(
+		helmDependencyCmd(),
+		helm_get.NewCmd(),
+		helm_list.NewCmd(),
+		helm_rollback.NewCmd(),
+	)
+
+	return cmd
+}
+
+func helmDependencyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dependency",
+		Short: "Manage dependencies of the project helm chart",
+	}
+	cmd.AddCommand(
+		helm_dependency_update.NewCmd(),
+	)
+
+	return cmd
+}
+
+func hostCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "host",
+		Short: "Commands to work with werf cache and data of the host",
+	}
+	cmd.AddCommand(
+		host_df.NewCmd(),
+		host_cleanup.NewCmd(),
+		hostLocksCmd(),
+		hostDappdepsCmd(),
+		hostProjectHomeCmd(),
+	)
+
+	return cmd
+}
+
+func hostDappdepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dappdeps",
+		Short: "Commands to prepare dappdeps images, which provide service tools to the stages containers",
+	}
+	cmd.AddCommand(
+		host_dappdeps_pull.NewCmd(),
+		host_dappdeps_verify.NewCmd(),
+	)
+
+	return cmd
+}
+
+func hostProjectHomeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "project-home",
+		Short: "Commands to work with project subtrees of werf home",
+	}
+	cmd.AddCommand(
+		host_project_home_migrate.NewCmd(),
+	)
+
+	return cmd
+}
+
+func hostLocksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "locks",
+		Short: "Commands to inspect and release werf locks",
+	}
+	cmd.AddCommand(
+		host_locks_ls.NewCmd(),
+		host_locks_rm.NewCmd(),
 	)
 
 	return cmd
@@ -135,6 +368,8 @@ func slugCmd() *cobra.Command {
 	return cmd
 }
 
+const terminationGracePeriod = 30 * time.Second
+
 func trapTerminationSignals() {
 	c := make(chan os.Signal, 1)
 	signals := []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}
@@ -142,6 +377,17 @@ func trapTerminationSignals() {
 	go func() {
 		<-c
 
+		fmt.Fprintf(os.Stderr, "Interrupted, terminating ...\n")
+
+		// cancel running operations to release locks and remove tmp files,
+		// force exit on the second signal or when grace period is over
+		werf.Terminate()
+
+		select {
+		case <-c:
+		case <-time.After(terminationGracePeriod):
+		}
+
 		fmt.Fprintf(os.Stderr, "Interrupted\n")
 
 		os.Exit(17)
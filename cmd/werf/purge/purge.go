@@ -0,0 +1,208 @@
+package purge
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/cleanup"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Repo             string
+	RegistryUsername string
+	RegistryPassword string
+
+	Force bool
+
+	DiscoverImagesRepos bool
+
+	DryRun bool
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "purge",
+		DisableFlagsInUseLine: true,
+		Short:                 "Delete all project images, stages and werf cache files of the project",
+		Long: common.GetLongCommandDescription(`Delete all project images, stages and werf cache files of the project.
+
+This command fully resets the project, which is decommissioned or should be rebuilt from scratch. It deletes:
+* project images and stages cache from Docker registry if --repo parameter has been specified;
+* project images, stages cache and containers from local Docker storage;
+* remote git repos clones and git worktrees of the project from werf home;
+* released werf tmp dirs.
+See more info about purge: https://flant.github.io/werf/reference/registry/cleaning.html#purge.
+
+Command should run from the project directory, where werf.yaml file reside.
+
+Command asks for confirmation interactively, use --force option to run command without confirmation (e.g. in CI).
+
+Purge requires read-write permissions to delete images from Docker registry. Standard Docker config or specified options --registry-username and --registry-password will be used to authorize in the Docker registry.
+
+See more info about authorization: https://flant.github.io/werf/reference/registry/authorization.html`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfInsecureRegistry, common.WerfRegistryConcurrency, common.WerfHome),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPurge()
+			if err != nil {
+				return fmt.Errorf("purge failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name")
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username (granted read-write permission)")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password (granted read-write permission)")
+	cmd.Flags().BoolVarP(&CmdData.DiscoverImagesRepos, "discover-images-repos", "", false, "Also process repositories REPO/IMAGE_NAME found in the registry catalog (or with the registry provider API), which are not described in werf.yaml")
+
+	cmd.Flags().BoolVarP(&CmdData.Force, "force", "", false, "Do not ask for confirmation")
+
+	cmd.Flags().BoolVarP(&CmdData.DryRun, "dry-run", "", false, "Indicate what the command would do without actually doing that")
+
+	return cmd
+}
+
+func runPurge() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	repoName := config.GetOptionalRepoName(werfConfig, CmdData.Repo)
+
+	if !CmdData.Force && !CmdData.DryRun {
+		target := "local Docker storage and werf home"
+		if repoName != "" {
+			target = fmt.Sprintf("Docker registry %s, %s", repoName, target)
+		}
+
+		ok, err := askForConfirmation(fmt.Sprintf("All images and stages of project %s will be deleted from %s.\nType project name to continue: ", projectName, target), projectName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("purge has not been confirmed")
+		}
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
+	if err != nil {
+		return fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	var imageNames []string
+	for _, image := range werfConfig.Images {
+		imageNames = append(imageNames, image.Name)
+	}
+
+	options := cleanup.ProjectPurgeOptions{
+		CommonProjectOptions: cleanup.CommonProjectOptions{
+			ProjectName:     projectName,
+			StagesNamespace: werfConfig.Meta.StagesNamespace,
+			CommonOptions:   cleanup.CommonOptions{DryRun: CmdData.DryRun},
+		},
+		ProjectBuildDir: projectBuildDir,
+	}
+
+	if _, err := os.Stat(path.Join(projectDir, ".git")); err == nil {
+		localGitRepo := &git_repo.Local{
+			Path:   projectDir,
+			GitDir: path.Join(projectDir, ".git"),
+		}
+
+		options.WorkTreeDirs = append(options.WorkTreeDirs, localGitRepo.GetWorkTreeDir())
+	}
+
+	if repoName != "" {
+		projectTmpDir, err := project_tmp_dir.Get()
+		if err != nil {
+			return fmt.Errorf("getting project tmp dir failed: %s", err)
+		}
+		defer project_tmp_dir.Release(projectTmpDir)
+
+		dockerAuthorizer, err := docker_authorizer.GetFlushDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := dockerAuthorizer.Login(repoName); err != nil {
+			return err
+		}
+
+		options.CommonRepoOptions = cleanup.CommonRepoOptions{
+			Repository:          repoName,
+			ImagesNames:         imageNames,
+			DryRun:              CmdData.DryRun,
+			DiscoverImagesRepos: CmdData.DiscoverImagesRepos,
+		}
+	}
+
+	return cleanup.ProjectPurge(options)
+}
+
+// askForConfirmation requires user to type expected answer, purge cannot be confirmed without terminal
+func askForConfirmation(prompt, expectedAnswer string) (bool, error) {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("cannot ask for confirmation: stdin is not a terminal, use --force option to run command without confirmation")
+	}
+
+	fmt.Print(prompt)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(answer) == expectedAnswer, nil
+}
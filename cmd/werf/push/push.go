@@ -6,9 +6,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/logger"
 	"github.com/flant/werf/pkg/project_tmp_dir"
@@ -38,7 +40,7 @@ Images will be tagged automatically with the names REPO/IMAGE_NAME:TAG. These ta
 If one or more IMAGE_NAME parameters specified, werf will push only these images from werf.yaml.`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfHome, common.WerfTmp),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfContainerRuntime, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch, common.WerfAutoCreateRepo, common.WerfAutoCreateRepoLifecyclePolicy, common.WerfSignImages, common.WerfSignKey, common.WerfHostMaxPushes, common.WerfProjectMaxPushes),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runPush(args)
@@ -50,9 +52,19 @@ If one or more IMAGE_NAME parameters specified, werf will push only these images
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+	common.SetupAutoCreateRepo(&CommonCmdData, cmd)
+	common.SetupSign(&CommonCmdData, cmd)
+	common.SetupAddLabels(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name to push images to. CI_REGISTRY_IMAGE will be used by default if available.")
 	cmd.Flags().BoolVarP(&CmdData.WithStages, "with-stages", "", false, "Push images with stages cache")
@@ -80,6 +92,10 @@ func runPush(imagesToProcess []string) error {
 		return err
 	}
 
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
 		return err
 	}
@@ -89,14 +105,22 @@ func runPush(imagesToProcess []string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	projectBuildDir, err := common.GetProjectBuildDir(projectName)
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
 	if err != nil {
 		return fmt.Errorf("getting project build dir failed: %s", err)
 	}
@@ -107,7 +131,7 @@ func runPush(imagesToProcess []string) error {
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	repo, err := common.GetRequiredRepoName(projectName, CmdData.Repo)
+	repo, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 	if err != nil {
 		return err
 	}
@@ -117,7 +141,7 @@ func runPush(imagesToProcess []string) error {
 		return err
 	}
 
-	if err := ssh_agent.Init(*CommonCmdData.SSHKeys); err != nil {
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
 		return fmt.Errorf("cannot initialize ssh agent: %s", err)
 	}
 	defer func() {
@@ -127,15 +151,42 @@ func runPush(imagesToProcess []string) error {
 		}
 	}()
 
-	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir)
+	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir, werfConfig)
 	if err != nil {
 		return err
 	}
 
 	pushOpts := build.PushOptions{TagOptions: tagOpts, WithStages: CmdData.WithStages}
 
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	signOpts, err := common.GetSignOptions(&CommonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	labels, err := common.GetAddLabels(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+
 	c := build.NewConveyor(werfConfig, imagesToProcess, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
-	if err = c.Push(repo, pushOpts); err != nil {
+	c.SetPlatforms(platforms)
+	if opts := common.GetAutoCreateRepoOptions(&CommonCmdData); opts != nil {
+		c.SetAutoCreateRepo(*opts)
+	}
+	if signOpts != nil {
+		c.SetSignOptions(*signOpts)
+	}
+	c.SetLabels(labels)
+	if err = c.Push(werf.GetContext(), repo, pushOpts); err != nil {
 		return err
 	}
 
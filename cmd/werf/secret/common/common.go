@@ -8,6 +8,8 @@ import (
 
 	"golang.org/x/crypto/ssh/terminal"
 	"k8s.io/kubernetes/pkg/util/file"
+
+	"github.com/flant/werf/pkg/config"
 )
 
 type GenerateOptions struct {
@@ -75,3 +77,62 @@ func SaveGeneratedData(filePath string, data []byte) error {
 
 	return nil
 }
+
+// GetSecretsConfig returns secrets settings from werf.yaml or nil if there is no werf.yaml in the project directory
+func GetSecretsConfig(projectDir string) (*config.MetaSecrets, error) {
+	for _, werfConfigName := range []string{"werf.yml", "werf.yaml"} {
+		if exist, err := file.FileExists(filepath.Join(projectDir, werfConfigName)); err != nil {
+			return nil, err
+		} else if exist {
+			werfConfig, err := config.GetWerfConfig(projectDir, "")
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse werf config: %s", err)
+			}
+
+			return &werfConfig.Meta.Secrets, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetProjectSecretFiles returns standard raw secret files in the .helm/secret folder and standard secret values yaml file .helm/secret-values.yaml
+func GetProjectSecretFiles(projectDir string) ([]string, []string, error) {
+	var secretFilesPaths, secretValuesPaths []string
+
+	helmChartPath := filepath.Join(projectDir, ".helm")
+
+	defaultSecretValuesPath := filepath.Join(helmChartPath, "secret-values.yaml")
+	if exist, err := file.FileExists(defaultSecretValuesPath); err != nil {
+		return nil, nil, err
+	} else if exist {
+		secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPath)
+	}
+
+	secretDirectory := filepath.Join(helmChartPath, "secret")
+	if exist, err := file.FileExists(secretDirectory); err != nil {
+		return nil, nil, err
+	} else if exist {
+		err := filepath.Walk(secretDirectory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			fileInfo, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			if !fileInfo.IsDir() {
+				secretFilesPaths = append(secretFilesPaths, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return secretFilesPaths, secretValuesPaths, nil
+}
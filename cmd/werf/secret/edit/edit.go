@@ -38,7 +38,7 @@ func NewCmd() *cobra.Command {
 
 The file can be raw secret file (by default) or secret values yaml file (with option --values).`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfTmp),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase, common.WerfTmp),
 		},
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -69,7 +69,16 @@ func runSecretEdit(filepPath string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	m, err := secret.GetManager(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := secret.GetManager(projectDir, secretsConfig)
 	if err != nil {
 		return err
 	}
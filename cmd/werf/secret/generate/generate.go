@@ -31,7 +31,7 @@ Provide data onto stdin by default.
 
 Data can be provided in file by specifying --file-path option. Option --values should be specified in the case when values yaml file provided.`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runSecretGenerate()
@@ -63,13 +63,22 @@ func runSecretGenerate() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
 	options := &secret_common.GenerateOptions{
 		FilePath:       CmdData.FilePath,
 		OutputFilePath: CmdData.OutputFilePath,
 		Values:         CmdData.Values,
 	}
 
-	m, err := secret.GetManager(projectDir)
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := secret.GetManager(projectDir, secretsConfig)
 	if err != nil {
 		return err
 	}
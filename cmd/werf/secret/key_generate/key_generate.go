@@ -2,6 +2,7 @@ package secret
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -11,14 +12,21 @@ import (
 	"github.com/flant/werf/pkg/deploy/secret"
 )
 
+var CmdData struct {
+	OutputFilePath string
+}
+
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use: "keygen",
+		Aliases: []string{"generate-secret-key"},
 		DisableFlagsInUseLine: true,
 		Short: "Generate hex encryption key that can be used as WERF_SECRET_KEY",
 		Long: common.GetLongCommandDescription(`Generate hex key that can be used as WERF_SECRET_KEY.
 
-16-bytes key will be generated (AES-128).`),
+16-bytes key will be generated (AES-128).
+
+Key can be saved into the file specified with the --output-file-path option (e.g. .werf_secret_key), existing file is not overwritten.`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runSecretKeyGenerate()
 			if err != nil {
@@ -28,6 +36,8 @@ func NewCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVarP(&CmdData.OutputFilePath, "output-file-path", "", "", "Save generated key into specified file")
+
 	return cmd
 }
 
@@ -37,6 +47,22 @@ func runSecretKeyGenerate() error {
 		return err
 	}
 
+	if CmdData.OutputFilePath != "" {
+		if _, err := os.Stat(CmdData.OutputFilePath); err == nil {
+			return fmt.Errorf("file %s already exists", CmdData.OutputFilePath)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := ioutil.WriteFile(CmdData.OutputFilePath, append(key, '\n'), 0600); err != nil {
+			return err
+		}
+
+		fmt.Printf("Key with fingerprint %s is saved into %s\n", secret.SecretKeyFingerprint(key), CmdData.OutputFilePath)
+
+		return nil
+	}
+
 	if terminal.IsTerminal(int(os.Stdout.Fd())) {
 		fmt.Printf("WERF_SECRET_KEY=%s\n", string(key))
 	} else {
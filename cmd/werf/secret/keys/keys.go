@@ -0,0 +1,117 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	secret_common "github.com/flant/werf/cmd/werf/secret/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy/secret"
+	secret_pkg "github.com/flant/werf/pkg/secret"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "keys",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print fingerprints of available secret keys",
+		Long: common.GetLongCommandDescription(`Print fingerprints of available secret keys.
+
+Aes keys are searched in the WERF_SECRET_KEY environment variable, project .werf_secret_key file and ~/.werf/.werf_secret_key file, the first found key is active. Fingerprint of aes key is the beginning of the key sha256 hash, so keys can be compared without disclosing them.
+
+Fingerprints of the gpg keyring keys (WERF_SECRET_GPG_KEYRING) and public keys of the age identity file (WERF_SECRET_AGE_IDENTITY_FILE) are printed if specified.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runSecretKeys()
+			if err != nil {
+				return fmt.Errorf("secret keys failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runSecretKeys() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	backend := config.AesSecretsBackend
+	if secretsConfig != nil && secretsConfig.Backend != "" {
+		backend = secretsConfig.Backend
+	}
+	fmt.Printf("Encryption backend: %s\n", backend)
+
+	keys, err := secret.GetSecretKeys(projectDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nAes keys:\n")
+	if len(keys) == 0 {
+		fmt.Printf("  not found\n")
+	}
+	for i, key := range keys {
+		if i == 0 {
+			fmt.Printf("  %s  %s (active)\n", secret.SecretKeyFingerprint(key.Key), key.Source)
+		} else {
+			fmt.Printf("  %s  %s\n", secret.SecretKeyFingerprint(key.Key), key.Source)
+		}
+	}
+
+	if keyring := os.Getenv("WERF_SECRET_GPG_KEYRING"); keyring != "" {
+		fmt.Printf("\nGpg keyring %s:\n", keyring)
+
+		fingerprints, err := secret_pkg.GPGKeyringFingerprints(keyring)
+		if err != nil {
+			return err
+		}
+
+		for _, fingerprint := range fingerprints {
+			fmt.Printf("  %s\n", fingerprint)
+		}
+	}
+
+	if identityFile := os.Getenv("WERF_SECRET_AGE_IDENTITY_FILE"); identityFile != "" {
+		fmt.Printf("\nAge identity file %s:\n", identityFile)
+
+		recipients, err := secret_pkg.AgeIdentityRecipients(identityFile)
+		if err != nil {
+			return err
+		}
+
+		for _, recipient := range recipients {
+			fmt.Printf("  %s\n", recipient)
+		}
+	}
+
+	return nil
+}
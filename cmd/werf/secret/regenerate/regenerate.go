@@ -8,9 +8,9 @@ import (
 	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"k8s.io/kubernetes/pkg/util/file"
 
 	"github.com/flant/werf/cmd/werf/common"
+	secret_common "github.com/flant/werf/cmd/werf/secret/common"
 	"github.com/flant/werf/pkg/deploy/secret"
 	"github.com/flant/werf/pkg/werf"
 )
@@ -36,7 +36,7 @@ Command will extract data with the old key, generate new secret data and rewrite
 * standard secret values yaml file .helm/secret-values.yaml;
 * additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runSecretRegenerate(args...)
@@ -67,7 +67,16 @@ func runSecretRegenerate(secretValuesPaths ...string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	newSecret, err := secret.GetManager(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	newSecret, err := secret.GetManager(projectDir, secretsConfig)
 	if err != nil {
 		return err
 	}
@@ -81,57 +90,15 @@ func runSecretRegenerate(secretValuesPaths ...string) error {
 }
 
 func secretsRegenerate(newManager, oldManager secret.Manager, projectPath string, secretValuesPaths ...string) error {
-	var secretFilesPaths []string
 	regeneratedFilesData := map[string][]byte{}
 	secretFilesData := map[string][]byte{}
 	secretValuesFilesData := map[string][]byte{}
 
-	helmChartPath := filepath.Join(projectPath, ".helm")
-	isHelmChartDirExist, err := file.FileExists(helmChartPath)
+	secretFilesPaths, defaultSecretValuesPaths, err := secret_common.GetProjectSecretFiles(projectPath)
 	if err != nil {
 		return err
 	}
-
-	if isHelmChartDirExist {
-		defaultSecretValuesPath := filepath.Join(helmChartPath, "secret-values.yaml")
-		isDefaultSecretValuesExist, err := file.FileExists(defaultSecretValuesPath)
-		if err != nil {
-			return err
-		}
-
-		if isDefaultSecretValuesExist {
-			secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPath)
-		}
-
-		secretDirectory := filepath.Join(helmChartPath, "secret")
-		isSecretDirectoryExist, err := file.FileExists(defaultSecretValuesPath)
-		if err != nil {
-			return err
-		}
-
-		if isSecretDirectoryExist {
-			err = filepath.Walk(secretDirectory,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-
-					fileInfo, err := os.Stat(path)
-					if err != nil {
-						return err
-					}
-
-					if !fileInfo.IsDir() {
-						secretFilesPaths = append(secretFilesPaths, path)
-					}
-
-					return nil
-				})
-			if err != nil {
-				return err
-			}
-		}
-	}
+	secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPaths...)
 
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,236 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/flant/werf/cmd/werf/common"
+	secret_common "github.com/flant/werf/cmd/werf/secret/common"
+	"github.com/flant/werf/pkg/deploy/secret"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	VsGit      string
+	ShowValues bool
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "diff FILE_PATH [FILE_PATH]",
+		DisableFlagsInUseLine: true,
+		Short: "Show key-level diff of secret values files",
+		Long: common.GetLongCommandDescription(`Show key-level diff of secret values files.
+
+Decrypted data is kept in memory only and never written to disk.
+
+Compare two secret values files or, with option --vs-git, the secret values file with its version from the specified git commit.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase),
+		},
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runSecretValuesDiff(args)
+			if err != nil {
+				return fmt.Errorf("secret values diff failed: %s", err)
+			}
+			return nil
+		},
+		Example: `  $ werf secret values diff .helm/secret-values.yaml .helm/secret-values-prod.yaml
+  ~ db.password
+  + db.user
+  - redis.password
+
+  $ werf secret values diff --vs-git origin/master .helm/secret-values.yaml`,
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.VsGit, "vs-git", "", "", "Compare FILE_PATH with its version from the specified git commit")
+	cmd.Flags().BoolVarP(&CmdData.ShowValues, "show-values", "", false, "Print decrypted values of changed keys")
+
+	return cmd
+}
+
+func runSecretValuesDiff(args []string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if CmdData.VsGit != "" && len(args) != 1 {
+		return fmt.Errorf("only one FILE_PATH should be specified with option --vs-git")
+	} else if CmdData.VsGit == "" && len(args) != 2 {
+		return fmt.Errorf("two FILE_PATH arguments should be specified")
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := secret.GetManager(projectDir, secretsConfig)
+	if err != nil {
+		return err
+	}
+
+	var oldEncodedData, newEncodedData []byte
+	if CmdData.VsGit != "" {
+		oldEncodedData, err = readGitFileData(CmdData.VsGit, args[0])
+		if err != nil {
+			return err
+		}
+
+		newEncodedData, err = secret_common.ReadFileData(args[0])
+		if err != nil {
+			return err
+		}
+	} else {
+		oldEncodedData, err = secret_common.ReadFileData(args[0])
+		if err != nil {
+			return err
+		}
+
+		newEncodedData, err = secret_common.ReadFileData(args[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	oldValues, err := extractValues(m, oldEncodedData)
+	if err != nil {
+		return err
+	}
+
+	newValues, err := extractValues(m, newEncodedData)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range diffValues(oldValues, newValues, CmdData.ShowValues) {
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+func readGitFileData(commit, filePath string) ([]byte, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "show", fmt.Sprintf("%s:./%s", commit, filepath.Base(absFilePath)))
+	cmd.Dir = filepath.Dir(absFilePath)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot get file '%s' from commit '%s': %s\n%s", filePath, commit, err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func extractValues(m secret.Manager, encodedData []byte) (map[string]string, error) {
+	data, err := m.ExtractYamlData(bytes.TrimSpace(encodedData))
+	if err != nil {
+		return nil, err
+	}
+
+	config := make(yaml.MapSlice, 0)
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	flattenValues(values, "", config)
+
+	return values, nil
+}
+
+func flattenValues(values map[string]string, prefix string, data interface{}) {
+	switch data := data.(type) {
+	case yaml.MapSlice:
+		for _, item := range data {
+			key := fmt.Sprintf("%v", item.Key)
+			if prefix != "" {
+				key = strings.Join([]string{prefix, key}, ".")
+			}
+
+			flattenValues(values, key, item.Value)
+		}
+	case []interface{}:
+		for ind, elm := range data {
+			flattenValues(values, fmt.Sprintf("%s[%d]", prefix, ind), elm)
+		}
+	default:
+		values[prefix] = fmt.Sprintf("%v", data)
+	}
+}
+
+func diffValues(oldValues, newValues map[string]string, showValues bool) []string {
+	keysMap := map[string]bool{}
+	for key := range oldValues {
+		keysMap[key] = true
+	}
+	for key := range newValues {
+		keysMap[key] = true
+	}
+
+	var keys []string
+	for key := range keysMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		oldValue, inOld := oldValues[key]
+		newValue, inNew := newValues[key]
+
+		switch {
+		case inOld && !inNew:
+			line := fmt.Sprintf("- %s", key)
+			if showValues {
+				line = fmt.Sprintf("%s: %s", line, oldValue)
+			}
+			lines = append(lines, line)
+		case !inOld && inNew:
+			line := fmt.Sprintf("+ %s", key)
+			if showValues {
+				line = fmt.Sprintf("%s: %s", line, newValue)
+			}
+			lines = append(lines, line)
+		case oldValue != newValue:
+			line := fmt.Sprintf("~ %s", key)
+			if showValues {
+				line = fmt.Sprintf("%s: %s -> %s", line, oldValue, newValue)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
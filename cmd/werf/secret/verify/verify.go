@@ -0,0 +1,112 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	secret_common "github.com/flant/werf/cmd/werf/secret/common"
+	"github.com/flant/werf/pkg/deploy/secret"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "verify [EXTRA_SECRET_VALUES_FILE_PATH...]",
+		DisableFlagsInUseLine: true,
+		Short: "Check that all secret files can be decrypted with the current secret key",
+		Long: common.GetLongCommandDescription(`Check that all secret files can be decrypted with the current secret key.
+
+Command will try to extract data of:
+* standard raw secret files in the .helm/secret folder;
+* standard secret values yaml file .helm/secret-values.yaml;
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+Command fails if any of the files cannot be decrypted, files are not changed.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretAgeIdentityFile, common.WerfSecretGPGKeyring, common.WerfSecretGPGPassphrase),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runSecretVerify(args...)
+			if err != nil {
+				return fmt.Errorf("secret verify failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runSecretVerify(secretValuesPaths ...string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	secretsConfig, err := secret_common.GetSecretsConfig(projectDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := secret.GetManager(projectDir, secretsConfig)
+	if err != nil {
+		return err
+	}
+
+	secretFilesPaths, defaultSecretValuesPaths, err := secret_common.GetProjectSecretFiles(projectDir)
+	if err != nil {
+		return err
+	}
+	secretValuesPaths = append(defaultSecretValuesPaths, secretValuesPaths...)
+
+	var failed int
+	for _, filePath := range secretFilesPaths {
+		if !verifyFile(filePath, m.Extract) {
+			failed++
+		}
+	}
+	for _, filePath := range secretValuesPaths {
+		if !verifyFile(filePath, m.ExtractYamlData) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secret files cannot be decrypted", failed, len(secretFilesPaths)+len(secretValuesPaths))
+	}
+
+	return nil
+}
+
+func verifyFile(filePath string, decodeFunc func([]byte) ([]byte, error)) bool {
+	fileData, err := ioutil.ReadFile(filePath)
+	if err == nil {
+		_, err = decodeFunc(bytes.TrimSpace(fileData))
+	}
+
+	if err != nil {
+		fmt.Printf("FAILED %s: %s\n", filePath, err)
+		return false
+	}
+
+	fmt.Printf("OK     %s\n", filePath)
+	return true
+}
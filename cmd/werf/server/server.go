@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/server"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Listen string
+
+	RegistryUsername string
+	RegistryPassword string
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "server",
+		Short: "Run HTTP API server to trigger build, push and deploy",
+		Long: common.GetLongCommandDescription(`Run HTTP API server to trigger build, push and deploy.
+
+Jobs are submitted with POST /jobs request with JSON body: action (build, push, bp or deploy), project source (dir or gitUrl with gitCommit) and action parameters (images, repo, tags, withStages, environment, release, namespace, values, set).
+
+Job status is available by GET /jobs/ID, job log is streamed by GET /jobs/ID/log until job is finished, DELETE /jobs/ID cancels job.
+
+Jobs are processed one by one in the order of submission within the same project source. Finished jobs are available for an hour.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfAnsibleArgs, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runServer()
+			if err != nil {
+				return fmt.Errorf("server failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupKubeContext(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.Listen, "listen", "", "127.0.0.1:8080", "Address to listen for HTTP API requests")
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username to authorize pull of base images and push to the docker repo")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password to authorize pull of base images and push to the docker repo")
+
+	return cmd
+}
+
+func runServer() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	srv := server.NewServer(werf.GetContext(), runJob)
+	httpServer := &http.Server{Addr: CmdData.Listen, Handler: srv.Handler()}
+
+	go func() {
+		<-werf.GetContext().Done()
+		httpServer.Close()
+	}()
+
+	fmt.Printf("Listening on %s ...\n", CmdData.Listen)
+
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+func runJob(ctx context.Context, job *server.Job) error {
+	req := job.Request
+
+	job.Log("Running %s job %s", req.Action, job.ID)
+
+	cmdData := common.CmdData{
+		Dir:        &req.Dir,
+		GitUrl:     &req.GitUrl,
+		GitCommit:  &req.GitCommit,
+		Tag:        &req.Tags,
+		TagBranch:  new(bool),
+		TagBuildID: new(bool),
+		TagCI:      new(bool),
+		TagCommit:  new(bool),
+	}
+
+	ownGitRepo, err := common.GetOwnRemoteGitRepo(&cmdData)
+	if err != nil {
+		return err
+	}
+
+	var werfConfig *config.WerfConfig
+	if ownGitRepo != nil {
+		werfConfig, err = config.ParseWerfConfigFromGitCommit(ownGitRepo, req.GitCommit, "")
+	} else {
+		werfConfig, err = config.GetWerfConfig(req.Dir, "")
+	}
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	if req.Action == server.DeployAction {
+		return runDeployJob(job, werfConfig, projectTmpDir)
+	}
+
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
+	if err != nil {
+		return fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	var repo string
+	var dockerAuthorizer *docker_authorizer.DockerAuthorizer
+	if req.Action == server.BuildAction {
+		dockerAuthorizer, err = docker_authorizer.GetBuildDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword)
+	} else {
+		repo, err = config.GetRequiredRepoName(werfConfig, req.Repo)
+		if err != nil {
+			return err
+		}
+
+		dockerAuthorizer, err = docker_authorizer.GetBPDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, CmdData.RegistryUsername, CmdData.RegistryPassword, repo)
+	}
+	if err != nil {
+		return err
+	}
+
+	c := build.NewConveyor(werfConfig, req.Images, req.Dir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
+	if ownGitRepo != nil {
+		c.SetOwnGitRepo(ownGitRepo, req.GitCommit)
+	}
+	c.AddEventListener(func(event build.Event) {
+		if msg := event.String(); msg != "" {
+			job.Log(msg)
+		}
+	})
+
+	switch req.Action {
+	case server.BuildAction:
+		return c.Build(ctx, build.BuildOptions{})
+	default:
+		tagOpts, err := common.GetTagOptions(&cmdData, req.Dir, werfConfig)
+		if err != nil {
+			return err
+		}
+
+		pushOpts := build.PushOptions{TagOptions: tagOpts, WithStages: req.WithStages}
+
+		if req.Action == server.PushAction {
+			return c.Push(ctx, repo, pushOpts)
+		}
+		return c.BP(ctx, repo, build.BuildOptions{}, pushOpts)
+	}
+}
+
+var initDeployOnce sync.Once
+var initDeployErr error
+
+func initDeploy() error {
+	initDeployOnce.Do(func() {
+		if err := deploy.Init(); err != nil {
+			initDeployErr = err
+			return
+		}
+
+		initDeployErr = kube.Init(kube.InitOptions{KubeContext: *CommonCmdData.KubeContext})
+	})
+
+	return initDeployErr
+}
+
+func runDeployJob(job *server.Job, werfConfig *config.WerfConfig, projectTmpDir string) error {
+	req := job.Request
+
+	if err := initDeploy(); err != nil {
+		return fmt.Errorf("cannot initialize deploy: %s", err)
+	}
+
+	repo, err := config.GetRequiredRepoName(werfConfig, req.Repo)
+	if err != nil {
+		return err
+	}
+
+	dockerAuthorizer, err := docker_authorizer.GetDeployDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, repo)
+	if err != nil {
+		return err
+	}
+
+	if err := dockerAuthorizer.Login(repo); err != nil {
+		return fmt.Errorf("docker login failed: %s", err)
+	}
+
+	environment := req.Environment
+	if environment == "" {
+		environment = os.Getenv("CI_ENVIRONMENT_SLUG")
+	}
+
+	release, err := deploy.GetHelmRelease(req.Release, environment, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := deploy.GetKubernetesNamespace(req.Namespace, environment, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	job.Log("Deploying release %s into namespace %s", release, namespace)
+
+	return deploy.RunDeploy(req.Dir, repo, req.Tags[0], release, namespace, werfConfig, deploy.DeployOptions{
+		Values:      req.Values,
+		Set:         req.Set,
+		Environment: environment,
+		KubeContext: *CommonCmdData.KubeContext,
+	})
+}
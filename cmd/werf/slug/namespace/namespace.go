@@ -4,17 +4,29 @@ import (
 	"fmt"
 
 	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/werf"
 	"github.com/spf13/cobra"
 )
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use: "namespace NAME",
+		Use: "namespace NAME...",
 		DisableFlagsInUseLine: true,
 		Args:  cobra.MinimumNArgs(1),
-		Short: "Prints name suitable for Kubernetes Namespace based on the specified NAME",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(slug.KubernetesNamespace(args[0]))
+		Short: "Prints names suitable for Kubernetes Namespace based on the specified NAMEs",
+		Long: `Prints names suitable for Kubernetes Namespace based on the specified NAMEs.
+
+Each result is printed on a separate line, a warning is printed to stderr when different NAMEs get the same result.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := werf.Init("", ""); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			for _, name := range args {
+				fmt.Println(slug.KubernetesNamespace(name))
+			}
+
+			return nil
 		},
 		Example: `  $ werf slug namespace feature-fix-2
   feature-fix-2
@@ -23,7 +35,11 @@ func NewCmd() *cobra.Command {
   branch-one-4-4-3-4fe08955
 
   $ werf slug namespace My_branch
-  my-branch-8ebf2d1d`,
+  my-branch-8ebf2d1d
+
+  $ werf slug namespace myproject review/feature_1
+  myproject
+  review-feature-1-762d12bd`,
 	}
 
 	return cmd
@@ -4,17 +4,29 @@ import (
 	"fmt"
 
 	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/werf"
 	"github.com/spf13/cobra"
 )
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use: "release NAME",
+		Use: "release NAME...",
 		DisableFlagsInUseLine: true,
 		Args:  cobra.MinimumNArgs(1),
-		Short: "Prints name suitable for Helm Release based on the specified NAME",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(slug.HelmRelease(args[0]))
+		Short: "Prints names suitable for Helm Release based on the specified NAMEs",
+		Long: `Prints names suitable for Helm Release based on the specified NAMEs.
+
+Each result is printed on a separate line, a warning is printed to stderr when different NAMEs get the same result.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := werf.Init("", ""); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			for _, name := range args {
+				fmt.Println(slug.HelmRelease(name))
+			}
+
+			return nil
 		},
 		Example: `  $ werf slug release my_release-NAME
   my_release-NAME
@@ -4,22 +4,38 @@ import (
 	"fmt"
 
 	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/werf"
 	"github.com/spf13/cobra"
 )
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use: "tag NAME",
+		Use: "tag NAME...",
 		DisableFlagsInUseLine: true,
 		Args:  cobra.MinimumNArgs(1),
-		Short: "Prints name suitable for Docker Tag based on the specified NAME",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(slug.DockerTag(args[0]))
+		Short: "Prints names suitable for Docker Tag based on the specified NAMEs",
+		Long: `Prints names suitable for Docker Tag based on the specified NAMEs.
+
+Each result is printed on a separate line, a warning is printed to stderr when different NAMEs get the same result.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := werf.Init("", ""); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			for _, name := range args {
+				fmt.Println(slug.DockerTag(name))
+			}
+
+			return nil
 		},
 		Example: `  $ werf slug tag helo/ehlo
   helo-ehlo-b6f6ab1f
 
   $ werf slug tag 16.04
+  16.04
+
+  $ werf slug tag feature/x 16.04
+  feature-x-8339ecf4
   16.04`,
 	}
 
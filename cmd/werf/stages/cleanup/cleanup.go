@@ -0,0 +1,188 @@
+package cleanup
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/cleanup"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	Repo             string
+	StagesRepo       string
+	RegistryUsername string
+	RegistryPassword string
+
+	GitCommitsPeriod time.Duration
+	KeepPeriod       time.Duration
+
+	DryRun bool
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "cleanup",
+		DisableFlagsInUseLine: true,
+		Short:                 "Remove local stages and stages from the stages repo, which are not used by final images",
+		Long: common.GetLongCommandDescription(`Remove local stages and stages from the stages repo, which are not used by final images.
+
+Stage is kept if it is a stage of the final image, which exists locally or in the Docker registry (lineage is determined by images parents and artifacts labels),
+if it has been built for a recent git commit of the project repo (--git-commits-period) or if it has been created recently (--keep-period).
+
+Project images are locked during cleanup, so concurrent builds wait for the command to finish.
+See more info about stages cleanup: https://flant.github.io/werf/reference/registry/cleaning.html#stages-cleanup
+
+Command should run from the project directory, where werf.yaml file reside.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfCleanupRegistryPassword, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfRegistryConcurrency, common.WerfHome),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runStagesCleanup()
+			if err != nil {
+				return fmt.Errorf("stages cleanup failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name to get final images information (publish.repo from werf.yaml or CI_REGISTRY_IMAGE by default, only local final images are used if not specified)")
+	cmd.Flags().StringVarP(&CmdData.StagesRepo, "stages-repo", "", "", "Docker repo of stages to cleanup besides local stages (publish.stagesRepo from werf.yaml by default)")
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username (granted read permission for the repo and read-write permission for the stages repo)")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password (granted read permission for the repo and read-write permission for the stages repo)")
+
+	cmd.Flags().DurationVarP(&CmdData.GitCommitsPeriod, "git-commits-period", "", 30*24*time.Hour, "Keep stages built for git commits, which have been committed during the period (0 to ignore git commits)")
+	cmd.Flags().DurationVarP(&CmdData.KeepPeriod, "keep-period", "", 2*time.Hour, "Keep stages, which have been created during the period")
+
+	cmd.Flags().BoolVarP(&CmdData.DryRun, "dry-run", "", false, "Indicate what the command would do without actually doing that")
+
+	return cmd
+}
+
+func runStagesCleanup() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
+	repoName := config.GetOptionalRepoName(werfConfig, CmdData.Repo)
+
+	stagesRepo := CmdData.StagesRepo
+	if stagesRepo == "" {
+		stagesRepo = werfConfig.Meta.Publish.StagesRepo
+	}
+
+	if repoName != "" || stagesRepo != "" {
+		authorizerRepo := stagesRepo
+		if authorizerRepo == "" {
+			authorizerRepo = repoName
+		}
+
+		dockerAuthorizer, err := docker_authorizer.GetCleanupDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, authorizerRepo)
+		if err != nil {
+			return err
+		}
+
+		for _, repo := range []string{repoName, stagesRepo} {
+			if repo == "" {
+				continue
+			}
+
+			if err := dockerAuthorizer.Login(repo); err != nil {
+				return err
+			}
+		}
+
+		if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+			return err
+		}
+	}
+
+	var imagesNames []string
+	for _, image := range werfConfig.Images {
+		imagesNames = append(imagesNames, image.Name)
+	}
+
+	commonProjectOptions := cleanup.CommonProjectOptions{
+		ProjectName:     werfConfig.Meta.Project,
+		StagesNamespace: werfConfig.Meta.StagesNamespace,
+		CommonOptions:   cleanup.CommonOptions{DryRun: CmdData.DryRun},
+	}
+
+	stagesCleanupOptions := cleanup.StagesCleanupOptions{
+		CommonRepoOptions: cleanup.CommonRepoOptions{
+			Repository:  repoName,
+			ImagesNames: imagesNames,
+			DryRun:      CmdData.DryRun,
+		},
+		StagesRepo:       stagesRepo,
+		GitCommitsPeriod: CmdData.GitCommitsPeriod,
+		KeepPeriod:       CmdData.KeepPeriod,
+	}
+
+	gitDir := path.Join(projectDir, ".git")
+	if exist, err := util.DirExists(gitDir); err != nil {
+		return err
+	} else if exist {
+		stagesCleanupOptions.LocalRepo = &git_repo.Local{
+			Path:   projectDir,
+			GitDir: gitDir,
+		}
+	}
+
+	if err := cleanup.StagesCleanup(commonProjectOptions, stagesCleanupOptions); err != nil {
+		return err
+	}
+
+	return nil
+}
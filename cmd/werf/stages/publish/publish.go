@@ -0,0 +1,170 @@
+package publish
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	SignaturesFile string
+
+	RegistryUsername string
+	RegistryPassword string
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "publish [IMAGE_NAME...]",
+		Short: "Push built stages into the stages repo and save the list of these stages into the signatures file",
+		Long: common.GetLongCommandDescription(`Push built stages into the stages repo and save the list of these stages into the signatures file.
+
+The signatures file allows another CI job to pull exactly the same stages with werf stages pull command without werf.yaml processing and stages signatures calculation.
+
+Stages should be built before publishing. If one or more IMAGE_NAME parameters specified, werf will publish only stages of these images from werf.yaml.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfContainerRuntime, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfSSHKnownHosts, common.WerfHome, common.WerfTmp, common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch, common.WerfAutoCreateRepo, common.WerfAutoCreateRepoLifecyclePolicy, common.WerfHostMaxPushes, common.WerfProjectMaxPushes),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPublish(args)
+			if err != nil {
+				return fmt.Errorf("stages publish failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
+	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupStagesRepo(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+	common.SetupAutoCreateRepo(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.SignaturesFile, "signatures", "", "", "Path to the signatures file to save the list of published stages (required)")
+
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username to authorize push to the stages repo")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password to authorize push to the stages repo")
+
+	return cmd
+}
+
+func runPublish(imagesToProcess []string) error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	if CmdData.SignaturesFile == "" {
+		return fmt.Errorf("--signatures option required")
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+	if err != nil {
+		return fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
+	stagesRepo := common.GetStagesRepo(&CommonCmdData, werfConfig)
+	if stagesRepo == "" {
+		return fmt.Errorf("--stages-repo option or publish.stagesRepo in werf.yaml required")
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
+	if err != nil {
+		return fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	dockerAuthorizer, err := docker_authorizer.GetPushDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, stagesRepo)
+	if err != nil {
+		return err
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+
+	c := build.NewConveyor(werfConfig, imagesToProcess, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
+	c.SetPlatforms(platforms)
+	if opts := common.GetAutoCreateRepoOptions(&CommonCmdData); opts != nil {
+		c.SetAutoCreateRepo(*opts)
+	}
+	if err = c.PublishStages(werf.GetContext(), stagesRepo, CmdData.SignaturesFile); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package pull
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+	SignaturesFile string
+	StagesRepo     string
+
+	RegistryUsername string
+	RegistryPassword string
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Pull stages listed in the signatures file from the stages repo into the local stages cache",
+		Long: common.GetLongCommandDescription(`Pull stages listed in the signatures file from the stages repo into the local stages cache.
+
+The signatures file is saved by werf stages publish command, so CI job can get exactly the same stages, which have been built by another job, without werf.yaml processing. Stages, which exist locally, are not pulled.
+
+Stages repo is taken from the signatures file by default.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfContainerRuntime, common.WerfDockerConfig, common.WerfIgnoreCIDockerAutologin, common.WerfInsecureRegistry, common.WerfHome, common.WerfTmp),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPull()
+			if err != nil {
+				return fmt.Errorf("stages pull failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&CmdData.SignaturesFile, "signatures", "", "", "Path to the signatures file saved by werf stages publish (required)")
+	cmd.Flags().StringVarP(&CmdData.StagesRepo, "stages-repo", "", "", "Docker repo to pull stages from (stages repo from the signatures file by default)")
+
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username to authorize pull from the stages repo")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password to authorize pull from the stages repo")
+
+	return cmd
+}
+
+func runPull() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return err
+	}
+
+	if CmdData.SignaturesFile == "" {
+		return fmt.Errorf("--signatures option required")
+	}
+
+	signatures, err := build.ReadStagesSignatures(CmdData.SignaturesFile)
+	if err != nil {
+		return err
+	}
+
+	stagesRepo := CmdData.StagesRepo
+	if stagesRepo == "" {
+		stagesRepo = signatures.StagesRepo
+	}
+
+	projectTmpDir, err := project_tmp_dir.Get()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer project_tmp_dir.Release(projectTmpDir)
+
+	dockerAuthorizer, err := docker_authorizer.GetStagesPullDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, stagesRepo)
+	if err != nil {
+		return err
+	}
+
+	return build.PullStages(werf.GetContext(), stagesRepo, signatures, dockerAuthorizer)
+}
@@ -0,0 +1,137 @@
+package status
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/cleanup"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var CmdData struct {
+}
+
+var CommonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print summary of werf data on the host",
+		Long: common.GetLongCommandDescription(`Print summary of werf data on the host.
+
+Command reports number and total size of project stages images, git worktrees, cached clones of remote git repos, active locks and werf tmp dirs with their disk usage. It is useful to check the host state before running cleanup commands.
+
+Stages images are reported only if command runs from the project directory, where werf.yaml file reside.`),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runStatus()
+			if err != nil {
+				return fmt.Errorf("status failed: %s", err)
+			}
+			return nil
+		},
+	}
+
+	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
+	common.SetupTmpDir(&CommonCmdData, cmd)
+	common.SetupHomeDir(&CommonCmdData, cmd)
+
+	return cmd
+}
+
+func runStatus() error {
+	if err := werf.Init(*CommonCmdData.TmpDir, *CommonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&CommonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	var projectName, stagesNamespace string
+	if common.GetConfigPath(&CommonCmdData) != "" || util.FileExists(filepath.Join(projectDir, "werf.yaml")) || util.FileExists(filepath.Join(projectDir, "werf.yml")) {
+		werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
+		if err != nil {
+			return fmt.Errorf("cannot parse werf config: %s", err)
+		}
+
+		projectName = werfConfig.Meta.Project
+		stagesNamespace = werfConfig.Meta.StagesNamespace
+
+		if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+			return err
+		}
+	}
+
+	status, err := cleanup.GetHostStatus(cleanup.CommonProjectOptions{ProjectName: projectName, StagesNamespace: stagesNamespace})
+	if err != nil {
+		return err
+	}
+
+	if projectName != "" {
+		fmt.Printf("Project %s stages: %d images, %s\n", projectName, status.StagesImages, units.HumanSize(float64(status.StagesImagesSize)))
+
+		var imageNames []string
+		for imageName := range status.StagesByImageName {
+			imageNames = append(imageNames, imageName)
+		}
+		sort.Strings(imageNames)
+
+		for _, imageName := range imageNames {
+			if imageName == "" {
+				fmt.Printf("  unknown image (built without provenance labels): %d\n", status.StagesByImageName[imageName])
+			} else {
+				fmt.Printf("  image %s: %d\n", imageName, status.StagesByImageName[imageName])
+			}
+		}
+	} else {
+		fmt.Printf("Project stages: werf.yaml not found in %s\n", projectDir)
+	}
+
+	printDirsStatus("Git worktrees", status.WorkTrees)
+	printDirsStatus("Remote git repos clones", status.RemoteGitRepos)
+	printDirsStatus("Tmp dirs", status.TmpDirs)
+
+	fmt.Printf("Locks: %d\n", len(status.Locks))
+	for _, info := range status.Locks {
+		if info.IsStale() {
+			fmt.Printf("  %s %s [stale]\n", info.Name, info.String())
+		} else {
+			fmt.Printf("  %s %s\n", info.Name, info.String())
+		}
+	}
+
+	return nil
+}
+
+func printDirsStatus(title string, dirs []cleanup.DirStatus) {
+	var totalSize int64
+	for _, dir := range dirs {
+		totalSize += dir.Size
+	}
+
+	fmt.Printf("%s: %d, %s\n", title, len(dirs), units.HumanSize(float64(totalSize)))
+	for _, dir := range dirs {
+		fmt.Printf("  %s %s\n", dir.Path, units.HumanSize(float64(dir.Size)))
+	}
+}
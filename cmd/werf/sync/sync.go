@@ -6,7 +6,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker_authorizer"
 	"github.com/flant/werf/pkg/cleanup"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/lock"
@@ -19,6 +20,8 @@ var CmdData struct {
 	RegistryUsername string
 	RegistryPassword string
 
+	PushMissingStages bool
+
 	DryRun bool
 }
 
@@ -32,6 +35,9 @@ func NewCmd() *cobra.Command {
 		Long: common.GetLongCommandDescription(`Remove local stages cache for the images, that doesn't exist in the Docker registry.
 
 Sync is a werf ability to automate periodical cleaning of build machine. Command should run after cleaning up Docker registry with the cleanup command.
+Local tags of images and stages cache, which have been removed from the Docker registry, are removed too.
+
+With option --push-missing-stages the reverse synchronization is performed: local stages cache, that doesn't exist in the Docker registry, is pushed and local images are not removed.
 See more info about sync: https://flant.github.io/werf/reference/registry/cleaning.html#local-storage-synchronization
 
 Command should run from the project directory, where werf.yaml file reside.`),
@@ -48,13 +54,17 @@ Command should run from the project directory, where werf.yaml file reside.`),
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name to get images information")
 	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username (granted read permission)")
 	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password (granted read permission)")
 
+	cmd.Flags().BoolVarP(&CmdData.PushMissingStages, "push-missing-stages", "", false, "Push local stages cache, that doesn't exist in the Docker registry, instead of removing local images (registry user should be granted write permission)")
+
 	cmd.Flags().BoolVarP(&CmdData.DryRun, "dry-run", "", false, "Indicate what the command would do without actually doing that")
 
 	return cmd
@@ -78,20 +88,28 @@ func runSync() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
 	projectTmpDir, err := project_tmp_dir.Get()
 	if err != nil {
 		return fmt.Errorf("getting project tmp dir failed: %s", err)
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	repoName, err := common.GetRequiredRepoName(projectName, CmdData.Repo)
+	repoName, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 	if err != nil {
 		return err
 	}
@@ -115,8 +133,9 @@ func runSync() error {
 	}
 
 	commonProjectOptions := cleanup.CommonProjectOptions{
-		ProjectName:   projectName,
-		CommonOptions: cleanup.CommonOptions{DryRun: CmdData.DryRun},
+		ProjectName:     projectName,
+		StagesNamespace: werfConfig.Meta.StagesNamespace,
+		CommonOptions:   cleanup.CommonOptions{DryRun: CmdData.DryRun},
 	}
 
 	commonRepoOptions := cleanup.CommonRepoOptions{
@@ -125,6 +144,14 @@ func runSync() error {
 		DryRun:      CmdData.DryRun,
 	}
 
+	if CmdData.PushMissingStages {
+		if err := cleanup.ProjectImageStagesPushMissing(commonProjectOptions, commonRepoOptions); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
 	if err := cleanup.ProjectImageStagesSync(commonProjectOptions, commonRepoOptions); err != nil {
 		return err
 	}
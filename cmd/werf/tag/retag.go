@@ -0,0 +1,83 @@
+package tag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+)
+
+// retagImages publishes already published images tag under the new tags without building stages.
+// Manifest is re-tagged right in the registry, image is pulled, tagged and pushed back if registry rejects manifest put.
+func retagImages(ctx context.Context, werfConfig *config.WerfConfig, imagesToProcess []string, repo, fromTag string, toTags []string) error {
+	for _, imageName := range imagesToProcess {
+		if !isImageInConfig(werfConfig, imageName) {
+			return fmt.Errorf("specified image '%s' isn't defined in werf.yaml", imageName)
+		}
+	}
+
+	for _, image := range werfConfig.Images {
+		if len(imagesToProcess) > 0 && !util.IsStringsContainValue(imagesToProcess, image.Name) {
+			continue
+		}
+
+		imageRepository := repo
+		if image.Name != "" {
+			imageRepository = fmt.Sprintf("%s/%s", repo, image.Name)
+		}
+
+		for _, toTag := range toTags {
+			if err := retagImage(ctx, imageRepository, fromTag, toTag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func retagImage(ctx context.Context, imageRepository, fromTag, toTag string) error {
+	fromImageName := fmt.Sprintf("%s:%s", imageRepository, fromTag)
+	toImageName := fmt.Sprintf("%s:%s", imageRepository, toTag)
+
+	logger.LogInfoF("# Retagging %s as %s\n", fromImageName, toImageName)
+
+	err := docker_registry.Retag(imageRepository, fromTag, toTag)
+	if err == nil {
+		return nil
+	}
+
+	logger.LogWarningF("WARNING: Unable to retag %s in the registry: %s\nFalling back to pull, tag and push of the image\n", fromImageName, err)
+
+	if err := docker.CliPull(ctx, fromImageName); err != nil {
+		return fmt.Errorf("error pulling %s: %s", fromImageName, err)
+	}
+
+	if err := docker.CliTag(fromImageName, toImageName); err != nil {
+		return fmt.Errorf("error tagging %s as %s: %s", fromImageName, toImageName, err)
+	}
+
+	if err := docker.CliPush(ctx, toImageName); err != nil {
+		return fmt.Errorf("error pushing %s: %s", toImageName, err)
+	}
+
+	if err := docker.CliRmi(fromImageName, toImageName); err != nil {
+		logger.LogWarningF("WARNING: Unable to remove local images %s, %s: %s\n", fromImageName, toImageName, err)
+	}
+
+	return nil
+}
+
+func isImageInConfig(werfConfig *config.WerfConfig, imageName string) bool {
+	for _, image := range werfConfig.Images {
+		if image.Name == imageName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,12 +6,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flant/werf/cmd/werf/common"
-	"github.com/flant/werf/cmd/werf/common/docker_authorizer"
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/lock"
 	"github.com/flant/werf/pkg/logger"
 	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/slug"
 	"github.com/flant/werf/pkg/ssh_agent"
 	"github.com/flant/werf/pkg/true_git"
 	"github.com/flant/werf/pkg/werf"
@@ -19,6 +22,11 @@ import (
 
 var CmdData struct {
 	Repo string
+
+	FromTag          string
+	ToTags           []string
+	RegistryUsername string
+	RegistryPassword string
 }
 
 var CommonCmdData common.CmdData
@@ -31,8 +39,13 @@ func NewCmd() *cobra.Command {
 
 Stages cache should exists for images to be tagged. I.e. images should be built with build command before tagging. Docker images names are constructed from parameters as REPO/IMAGE_NAME:TAG. See more info about images naming: https://flant.github.io/werf/reference/registry/image_naming.html.
 
-If one or more IMAGE_NAME parameters specified, werf will tag only these images from werf.yaml. `),
+If one or more IMAGE_NAME parameters specified, werf will tag only these images from werf.yaml.
+
+With --from-tag and --to-tag options werf publishes already published images REPO/IMAGE_NAME:FROM_TAG under the new tags (e.g. latest) without building stages: image manifest is re-tagged right in the docker registry, image is pulled, tagged and pushed back if registry does not allow that.`),
 		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfAllowCaseCollisions, common.WerfOffline, common.WerfSkipGitFetch),
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runPush(args)
 			if err != nil {
@@ -43,12 +56,25 @@ If one or more IMAGE_NAME parameters specified, werf will tag only these images
 	}
 
 	common.SetupDir(&CommonCmdData, cmd)
+	common.SetupConfigPath(&CommonCmdData, cmd)
 	common.SetupTmpDir(&CommonCmdData, cmd)
 	common.SetupHomeDir(&CommonCmdData, cmd)
+	common.SetupRegistriesOptions(&CommonCmdData, cmd)
 	common.SetupSSHKey(&CommonCmdData, cmd)
+	common.SetupAllowCaseCollisions(&CommonCmdData, cmd)
+	common.SetupOffline(&CommonCmdData, cmd)
+	common.SetupSkipGitFetch(&CommonCmdData, cmd)
+	common.SetupPlatform(&CommonCmdData, cmd)
+	common.SetupContainerRuntime(&CommonCmdData, cmd)
+	common.SetupAddLabels(&CommonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&CmdData.Repo, "repo", "", "", "Docker repository name to tag images for. CI_REGISTRY_IMAGE will be used by default if available.")
 
+	cmd.Flags().StringVarP(&CmdData.FromTag, "from-tag", "", "", "Publish existing REPO/IMAGE_NAME:FROM_TAG images under tags specified by --to-tag without building stages")
+	cmd.Flags().StringArrayVarP(&CmdData.ToTags, "to-tag", "", []string{}, "Alias tag for the images specified by --from-tag (can be used one or more times)")
+	cmd.Flags().StringVarP(&CmdData.RegistryUsername, "registry-username", "", "", "Docker registry username to authorize push to the docker repo")
+	cmd.Flags().StringVarP(&CmdData.RegistryPassword, "registry-password", "", "", "Docker registry password to authorize push to the docker repo")
+
 	common.SetupTag(&CommonCmdData, cmd)
 
 	return cmd
@@ -67,6 +93,10 @@ func runPush(imagesToProcess []string) error {
 		return err
 	}
 
+	if err := docker.SetContainerRuntime(common.GetContainerRuntime(&CommonCmdData)); err != nil {
+		return err
+	}
+
 	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
 		return err
 	}
@@ -76,14 +106,22 @@ func runPush(imagesToProcess []string) error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	werfConfig, err := common.GetWerfConfig(projectDir)
+	if err := common.InitProjectHome(projectDir); err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(projectDir, common.GetConfigPath(&CommonCmdData), common.GetConfigOverlays(&CommonCmdData)...)
 	if err != nil {
 		return fmt.Errorf("cannot parse werf config: %s", err)
 	}
 
+	if err := common.InitRegistriesOptions(&CommonCmdData, werfConfig, projectDir); err != nil {
+		return err
+	}
+
 	projectName := werfConfig.Meta.Project
 
-	projectBuildDir, err := common.GetProjectBuildDir(projectName)
+	projectBuildDir, err := werf.GetProjectBuildDir(projectName)
 	if err != nil {
 		return fmt.Errorf("getting project build dir failed: %s", err)
 	}
@@ -94,12 +132,16 @@ func runPush(imagesToProcess []string) error {
 	}
 	defer project_tmp_dir.Release(projectTmpDir)
 
-	repo, err := common.GetRequiredRepoName(projectName, CmdData.Repo)
+	repo, err := config.GetRequiredRepoName(werfConfig, CmdData.Repo)
 	if err != nil {
 		return err
 	}
 
-	if err := ssh_agent.Init(*CommonCmdData.SSHKeys); err != nil {
+	if CmdData.FromTag != "" || len(CmdData.ToTags) > 0 {
+		return runRetag(werfConfig, imagesToProcess, projectTmpDir, repo)
+	}
+
+	if err := ssh_agent.Init(*CommonCmdData.SSHKeys, common.GetSSHHostKeyCheckingOptions(&CommonCmdData)); err != nil {
 		return fmt.Errorf("cannot initialize ssh agent: %s", err)
 	}
 	defer func() {
@@ -109,15 +151,58 @@ func runPush(imagesToProcess []string) error {
 		}
 	}()
 
-	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir)
+	tagOpts, err := common.GetTagOptions(&CommonCmdData, projectDir, werfConfig)
 	if err != nil {
 		return err
 	}
 
+	platforms, err := common.GetPlatforms(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	labels, err := common.GetAddLabels(&CommonCmdData)
+	if err != nil {
+		return err
+	}
+
+	git_repo.AllowCaseCollisions = common.GetAllowCaseCollisions(&CommonCmdData)
+	werf.SetOffline(common.GetOffline(&CommonCmdData))
+	git_repo.SkipFetch = common.GetSkipGitFetch(&CommonCmdData)
+
 	c := build.NewConveyor(werfConfig, imagesToProcess, projectDir, projectBuildDir, projectTmpDir, ssh_agent.SSHAuthSock, nil)
-	if err = c.Tag(repo, tagOpts); err != nil {
+	c.SetPlatforms(platforms)
+	c.SetLabels(labels)
+	if err = c.Tag(werf.GetContext(), repo, tagOpts); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func runRetag(werfConfig *config.WerfConfig, imagesToProcess []string, projectTmpDir, repo string) error {
+	if CmdData.FromTag == "" {
+		return fmt.Errorf("--from-tag option required with --to-tag")
+	}
+
+	if len(CmdData.ToTags) == 0 {
+		return fmt.Errorf("--to-tag option required with --from-tag")
+	}
+
+	for _, tag := range append([]string{CmdData.FromTag}, CmdData.ToTags...) {
+		if err := slug.ValidateDockerTag(tag); err != nil {
+			return err
+		}
+	}
+
+	dockerAuthorizer, err := docker_authorizer.GetPushDockerAuthorizer(projectTmpDir, CmdData.RegistryUsername, CmdData.RegistryPassword, repo)
+	if err != nil {
+		return err
+	}
+
+	if err := dockerAuthorizer.LoginForPush(repo); err != nil {
+		return fmt.Errorf("login into '%s' for push failed: %s", repo, err)
+	}
+
+	return retagImages(werf.GetContext(), werfConfig, imagesToProcess, repo, CmdData.FromTag, CmdData.ToTags)
+}
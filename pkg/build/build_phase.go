@@ -2,9 +2,14 @@ package build
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/docker"
 	imagePkg "github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
 )
 
 func NewBuildPhase(opts BuildOptions) *BuildPhase {
@@ -13,6 +18,16 @@ func NewBuildPhase(opts BuildOptions) *BuildPhase {
 
 type BuildOptions struct {
 	ImageBuildOptions imagePkg.BuildOptions
+
+	// ReadOnlyStages forbids building: stages should exist locally or in the stages repo
+	ReadOnlyStages bool
+
+	// CacheFromRepo and CacheFromTag specify previously published images, which are imported as stages before building
+	CacheFromRepo string
+	CacheFromTag  string
+
+	// RefreshBaseImages resolves digests of base images again instead of using pinned ones
+	RefreshBaseImages bool
 }
 
 type BuildPhase struct {
@@ -24,6 +39,8 @@ func (p *BuildPhase) Run(c *Conveyor) error {
 		fmt.Printf("BuildPhase.Run\n")
 	}
 
+	var missingStages []string
+
 	for _, image := range c.imagesInOrder {
 		if debug() {
 			fmt.Printf("  image: '%s'\n", image.GetName())
@@ -48,11 +65,11 @@ func (p *BuildPhase) Run(c *Conveyor) error {
 		// lock
 		for _, stage := range image.GetStages() {
 			img := stage.GetImage()
-			if img.IsExists() {
+			if img.IsExists() || image.isCoveredByCacheFrom(stage) {
 				continue
 			}
 
-			imageLockName := fmt.Sprintf("%s.image.%s", c.projectName(), img.Name())
+			imageLockName := fmt.Sprintf("%s.image.%s", c.stagesNamespace(), img.Name())
 			err := lock.Lock(imageLockName, lock.LockOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to lock %s: %s", imageLockName, err)
@@ -67,23 +84,47 @@ func (p *BuildPhase) Run(c *Conveyor) error {
 
 		// build
 		for _, s := range image.GetStages() {
+			if err := c.GetContext().Err(); err != nil {
+				return err
+			}
+
+			if image.isCoveredByCacheFrom(s) {
+				continue
+			}
+
 			img := s.GetImage()
-			if img.IsExists() {
-				if image.GetName() == "" {
-					fmt.Printf("# Using cached image %s for image %s\n", img.Name(), fmt.Sprintf("stage/%s", s.Name()))
-				} else {
-					fmt.Printf("# Using cached image %s for image/%s %s\n", img.Name(), image.GetName(), fmt.Sprintf("stage/%s", s.Name()))
+
+			if !img.IsExists() && c.stagesRepo != nil {
+				pulled, err := c.stagesRepo.PullStage(c, image, s)
+				if err != nil {
+					return err
+				}
+
+				if pulled {
+					c.emitEvent(Event{Type: StageCacheHitEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
+
+					unlockLock()
+
+					continue
 				}
+			}
+
+			if img.IsExists() {
+				c.emitEvent(Event{Type: StageCacheHitEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
 
 				continue
 			}
 
-			if image.GetName() == "" {
-				fmt.Printf("# Building image %s for image %s\n", img.Name(), fmt.Sprintf("stage/%s", s.Name()))
-			} else {
-				fmt.Printf("# Building image %s for image/%s %s\n", img.Name(), image.GetName(), fmt.Sprintf("stage/%s", s.Name()))
+			if p.ReadOnlyStages {
+				missingStages = append(missingStages, fmt.Sprintf("image '%s' stage '%s' signature %s", image.GetName(), s.Name(), s.GetSignature()))
+
+				unlockLock()
+
+				continue
 			}
 
+			c.emitEvent(Event{Type: StageBuildStartedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
+
 			if debug() {
 				fmt.Printf("    %s\n", s.Name())
 			}
@@ -92,7 +133,27 @@ func (p *BuildPhase) Run(c *Conveyor) error {
 				return fmt.Errorf("stage '%s' preRunHook failed: %s", s.Name(), err)
 			}
 
-			if err := img.Build(p.ImageBuildOptions); err != nil {
+			imageBuildOptions := p.ImageBuildOptions
+			// buildah does not keep output of the run command
+			if docker.GetContainerRuntime() == docker.DockerContainerRuntime {
+				imageBuildOptions.LogFile = c.GetStageLogPath(image.GetName(), s.Name())
+			}
+
+			if timeout, ok := image.stagesTimeout[string(s.Name())]; ok {
+				imageBuildOptions.Timeout = timeout
+			}
+
+			if retries, ok := image.stagesRetries[string(s.Name())]; ok {
+				imageBuildOptions.Retries = retries
+			}
+
+			if err := lock.WithHostSlot(lock.BuildResource, c.projectName(), func() error {
+				return img.Build(imageBuildOptions)
+			}); err != nil {
+				if s.Name() == stage.Check {
+					return fmt.Errorf("image '%s' check failed: %s", image.GetName(), err)
+				}
+
 				return fmt.Errorf("failed to build %s: %s", img.Name(), err)
 			}
 
@@ -101,8 +162,36 @@ func (p *BuildPhase) Run(c *Conveyor) error {
 				return fmt.Errorf("failed to save in cache image %s: %s", img.Name(), err)
 			}
 
+			c.emitEvent(Event{Type: StageBuildFinishedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
+
+			if c.stagesRepo != nil {
+				if err := c.stagesRepo.PushStage(c, image, s); err != nil {
+					return err
+				}
+			}
+
+			if err := os.RemoveAll(c.GetImageStageTmpDir(image.GetName(), s.Name())); err != nil {
+				logger.LogWarningF("WARNING: unable to remove stage %s tmp dir: %s\n", s.Name(), err)
+			}
+
 			unlockLock()
 		}
+
+		if err := os.RemoveAll(c.GetImageTmpDir(image.GetName())); err != nil {
+			logger.LogWarningF("WARNING: unable to remove image %s tmp dir: %s\n", image.GetName(), err)
+		}
+	}
+
+	if c.stagesRepo != nil {
+		if err := c.stagesRepo.Reconcile(c, true); err != nil {
+			return err
+		}
+
+		c.stagesRepo.reportPendingPushes()
+	}
+
+	if len(missingStages) > 0 {
+		return fmt.Errorf("building is forbidden in read-only stages mode, missing stages:\n%s", strings.Join(missingStages, "\n"))
 	}
 
 	return nil
@@ -0,0 +1,131 @@
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BuildSecretsContainerDir is a tmpfs dir of stages containers, where build secrets are mounted
+const BuildSecretsContainerDir = "/run/secrets"
+
+// BuildSecret is a file or env variable value, which is available in stages containers as BuildSecretsContainerDir/ID file.
+// Secrets are not saved into stages images and do not affect stages signatures.
+type BuildSecret struct {
+	ID  string
+	Src string
+	Env string
+}
+
+// ParseBuildSecret parses secret specification in docker buildkit format: id=ID,src=PATH or id=ID,env=VAR
+// (type=file|env is optional, env defaults to ID for type=env)
+func ParseBuildSecret(spec string) (*BuildSecret, error) {
+	secret := &BuildSecret{}
+
+	var secretType string
+	for _, field := range strings.Split(spec, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad secret '%s': expected comma separated KEY=VALUE fields", spec)
+		}
+
+		switch key, value := strings.TrimSpace(parts[0]), parts[1]; key {
+		case "id":
+			secret.ID = value
+		case "src", "source":
+			secret.Src = value
+		case "env":
+			secret.Env = value
+		case "type":
+			if value != "file" && value != "env" {
+				return nil, fmt.Errorf("bad secret '%s': unsupported type '%s', expected file or env", spec, value)
+			}
+			secretType = value
+		default:
+			return nil, fmt.Errorf("bad secret '%s': unknown field '%s'", spec, key)
+		}
+	}
+
+	if secretType == "env" && secret.Env == "" && secret.Src == "" {
+		secret.Env = secret.ID
+	}
+
+	switch {
+	case secret.ID == "" || strings.Contains(secret.ID, "/") || secret.ID == "." || secret.ID == "..":
+		return nil, fmt.Errorf("bad secret '%s': id should be a valid file name", spec)
+	case secret.Src == "" && secret.Env == "":
+		return nil, fmt.Errorf("bad secret '%s': src or env required", spec)
+	case secret.Src != "" && secret.Env != "":
+		return nil, fmt.Errorf("bad secret '%s': src and env cannot be used together", spec)
+	case secretType == "file" && secret.Src == "":
+		return nil, fmt.Errorf("bad secret '%s': src required for type file", spec)
+	}
+
+	return secret, nil
+}
+
+// buildSecretsVolumes returns read-only bind mounts of secrets files, env secrets are written into the secrets tmp dir.
+// Secrets tmp dir is created in /dev/shm if possible, so secrets values are not written to the disk.
+func (c *Conveyor) buildSecretsVolumes() ([]string, error) {
+	var volumes []string
+	for _, secret := range c.buildSecrets {
+		hostPath := secret.Src
+		if secret.Env != "" {
+			value, ok := os.LookupEnv(secret.Env)
+			if !ok {
+				return nil, fmt.Errorf("env %s of secret %s is not set", secret.Env, secret.ID)
+			}
+
+			if c.buildSecretsDir == "" {
+				dir, err := ioutil.TempDir(buildSecretsTmpDir(c.baseTmpDir), "werf-secrets-")
+				if err != nil {
+					return nil, fmt.Errorf("unable to create secrets dir: %s", err)
+				}
+				c.buildSecretsDir = dir
+			}
+
+			// secrets dir is accessible only by the owner, file mode allows to read secret by any user of the container
+			hostPath = filepath.Join(c.buildSecretsDir, secret.ID)
+			if err := os.Remove(hostPath); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("unable to remove secret %s: %s", secret.ID, err)
+			}
+			if err := ioutil.WriteFile(hostPath, []byte(value), 0444); err != nil {
+				return nil, fmt.Errorf("unable to write secret %s: %s", secret.ID, err)
+			}
+		} else {
+			absPath, err := filepath.Abs(hostPath)
+			if err != nil {
+				return nil, fmt.Errorf("bad secret %s src %s: %s", secret.ID, hostPath, err)
+			}
+
+			if _, err := os.Stat(absPath); err != nil {
+				return nil, fmt.Errorf("bad secret %s src: %s", secret.ID, err)
+			}
+			hostPath = absPath
+		}
+
+		volumes = append(volumes, fmt.Sprintf("%s:%s:ro", hostPath, filepath.Join(BuildSecretsContainerDir, secret.ID)))
+	}
+
+	return volumes, nil
+}
+
+func (c *Conveyor) removeBuildSecretsDir() {
+	if c.buildSecretsDir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(c.buildSecretsDir); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: unable to remove secrets dir %s: %s\n", c.buildSecretsDir, err)
+	}
+	c.buildSecretsDir = ""
+}
+
+func buildSecretsTmpDir(defaultDir string) string {
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm"
+	}
+	return defaultDir
+}
@@ -88,6 +88,16 @@ func (b *Ansible) stage(userStageName string, container Container) error {
 	}
 	container.AddVolumeFrom(fmt.Sprintf("%s:ro", containerName))
 
+	if len(b.config.Requirements) != 0 {
+		rolesDir, err := b.prepareGalaxyRoles()
+		if err != nil {
+			return err
+		}
+
+		container.AddVolume(fmt.Sprintf("%s:%s:ro", rolesDir, b.containerGalaxyRolesDir()))
+		container.AddEnv(map[string]string{"ANSIBLE_ROLES_PATH": b.containerGalaxyRolesDir()})
+	}
+
 	commandParts := []string{
 		dappdeps.AnsibleBinPath("ansible-playbook"),
 		filepath.Join(b.containerWorkDir(), "playbook.yml"),
@@ -119,6 +129,13 @@ func (b *Ansible) stageChecksum(userStageName string) string {
 		checksumArgs = append(checksumArgs, string(jsonOutput))
 	}
 
+	// roles installed by requirements are used by the stage tasks
+	if len(checksumArgs) != 0 {
+		if requirementsChecksum := b.requirementsChecksum(); requirementsChecksum != "" {
+			checksumArgs = append(checksumArgs, requirementsChecksum)
+		}
+	}
+
 	if stageVersionChecksum := b.stageVersionChecksum(userStageName); stageVersionChecksum != "" {
 		checksumArgs = append(checksumArgs, stageVersionChecksum)
 	}
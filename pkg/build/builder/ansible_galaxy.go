@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	ghodssYaml "github.com/ghodss/yaml"
+	"gopkg.in/yaml.v1"
+
+	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/home_usage"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// ANSIBLE_GALAXY_ROLES_CACHE_VERSION should be bumped when layout of the galaxy roles cache is changed
+const ANSIBLE_GALAXY_ROLES_CACHE_VERSION = "1"
+
+// GetAnsibleGalaxyRolesDir is a dir of galaxy roles installed by requirements, roles are shared between projects and keyed by requirements checksum
+func GetAnsibleGalaxyRolesDir() string {
+	return filepath.Join(werf.GetHomeDir(), "ansible_galaxy_roles", ANSIBLE_GALAXY_ROLES_CACHE_VERSION)
+}
+
+// requirementsChecksum is a checksum of galaxy requirements and ansible version, which installs roles
+func (b *Ansible) requirementsChecksum() string {
+	if len(b.config.Requirements) == 0 {
+		return ""
+	}
+
+	checksumArgs := []string{dappdeps.AnsibleVersion()}
+	for _, requirement := range b.config.Requirements {
+		output, err := yaml.Marshal(requirement)
+		if err != nil {
+			panic(fmt.Sprintf("runtime err: %s", err))
+		}
+
+		jsonOutput, err := ghodssYaml.YAMLToJSON(output)
+		if err != nil {
+			panic(fmt.Sprintf("runtime err: %s", err))
+		}
+		checksumArgs = append(checksumArgs, string(jsonOutput))
+	}
+
+	return util.Sha256Hash(checksumArgs...)
+}
+
+func (b *Ansible) containerGalaxyRolesDir() string {
+	return filepath.Join(b.extra.ContainerWerfPath, "ansible-galaxy-roles")
+}
+
+// prepareGalaxyRoles installs roles by requirements into the cache once and returns the roles dir.
+// Roles are installed with ansible-galaxy of dappdeps ansible into the tmp dir, which is renamed to the roles dir on success,
+// so interrupted installation is never used. Only already installed roles can be used in offline mode.
+func (b *Ansible) prepareGalaxyRoles() (string, error) {
+	checksum := b.requirementsChecksum()
+	rolesDir := filepath.Join(GetAnsibleGalaxyRolesDir(), checksum)
+	lockName := fmt.Sprintf("ansible_galaxy_roles.%s", checksum)
+
+	err := lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		if _, err := os.Stat(rolesDir); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		if werf.IsOffline() {
+			return fmt.Errorf("ansible galaxy roles (requirements checksum %s) are not found in %s: %s", checksum, GetAnsibleGalaxyRolesDir(), werf.OfflineError("roles installation"))
+		}
+
+		tmpDir := rolesDir + ".tmp"
+		if err := os.RemoveAll(tmpDir); err != nil {
+			return fmt.Errorf("unable to remove %s: %s", tmpDir, err)
+		}
+		if err := mkdirP(filepath.Join(tmpDir, "roles")); err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(b.config.Requirements)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(tmpDir, "requirements.yml"), string(data)); err != nil {
+			return err
+		}
+
+		logger.LogInfoF("Installing ansible galaxy roles (requirements checksum %s) ...\n", checksum)
+
+		if err := installGalaxyRoles(tmpDir); err != nil {
+			return fmt.Errorf("unable to install ansible galaxy roles: %s", err)
+		}
+
+		if err := os.Rename(filepath.Join(tmpDir, "roles"), rolesDir); err != nil {
+			return err
+		}
+
+		return os.RemoveAll(tmpDir)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := home_usage.MarkUsed(home_usage.AnsibleGalaxyRolesKind, rolesDir, lockName); err != nil {
+		return "", err
+	}
+
+	return rolesDir, nil
+}
+
+// installGalaxyRoles runs ansible-galaxy of dappdeps ansible in the container by the current user,
+// so installed roles can be removed from werf home without root permissions
+func installGalaxyRoles(dir string) error {
+	toolchainContainerName, err := dappdeps.ToolchainContainer()
+	if err != nil {
+		return err
+	}
+
+	ansibleContainerName, err := dappdeps.AnsibleContainer()
+	if err != nil {
+		return err
+	}
+
+	gitArtifactContainerName, err := dappdeps.GitArtifactContainer()
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"--rm",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--volumes-from", toolchainContainerName,
+		"--volumes-from", ansibleContainerName,
+		"--volumes-from", gitArtifactContainerName,
+		"--volume", fmt.Sprintf("%s:%s", dir, dir),
+		"--env", fmt.Sprintf("HOME=%s", dir),
+		"--env", fmt.Sprintf("PATH=%s:%s", filepath.Dir(dappdeps.GitBin()), dappdeps.BasePath()),
+		dappdeps.BaseImageName(),
+		dappdeps.AnsibleBinPath("ansible-galaxy"), "install",
+		"--role-file", filepath.Join(dir, "requirements.yml"),
+		"--roles-path", filepath.Join(dir, "roles"),
+	}
+
+	return docker.CliRun(args...)
+}
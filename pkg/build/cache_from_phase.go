@@ -0,0 +1,169 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/docker_registry"
+	imagePkg "github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+)
+
+// WerfStagesSignaturesLabel lists signatures of the image stages in order as STAGE_NAME:SIGNATURE pairs separated by comma,
+// the pushed image is the latest stage of the image, which is the last pair
+const WerfStagesSignaturesLabel = "werf-stages-signatures"
+
+func stagesSignaturesLabelValue(image *Image) string {
+	var pairs []string
+	for _, s := range image.GetStages() {
+		pairs = append(pairs, fmt.Sprintf("%s:%s", s.Name(), s.GetSignature()))
+
+		if s == image.LatestStage() {
+			break
+		}
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// latestStageSignatureFromLabel returns name and signature of the stage, which the pushed image has been built from
+func latestStageSignatureFromLabel(value string) (stage.StageName, string) {
+	if value == "" {
+		return "", ""
+	}
+
+	pairs := strings.Split(value, ",")
+
+	parts := strings.SplitN(pairs[len(pairs)-1], ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+
+	return stage.StageName(parts[0]), parts[1]
+}
+
+func NewCacheFromPhase(repo, tag string) *CacheFromPhase {
+	return &CacheFromPhase{Repo: repo, Tag: tag}
+}
+
+// CacheFromPhase warms local stages cache of ephemeral hosts with previously published images:
+// if the stage, which the previous image has been built from, has the same signature, the previous image is imported as this stage
+type CacheFromPhase struct {
+	Repo string
+	Tag  string
+}
+
+func (p *CacheFromPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("CacheFromPhase.Run\n")
+	}
+
+	if err := c.GetDockerAuthorizer().LoginForPull(p.Repo); err != nil {
+		return fmt.Errorf("login into '%s' for pull failed: %s", p.Repo, err)
+	}
+
+	for _, image := range c.imagesInOrder {
+		if image.isArtifact || image.LatestStage().GetImage().IsExists() {
+			continue
+		}
+
+		if err := p.importPreviousImage(c, image); err != nil {
+			return fmt.Errorf("unable to import previous image of image %s: %s", imageOrderItemName(image.GetName()), err)
+		}
+	}
+
+	return nil
+}
+
+func (p *CacheFromPhase) importPreviousImage(c *Conveyor, image *Image) error {
+	var imageRepository string
+	if image.GetName() != "" {
+		imageRepository = fmt.Sprintf("%s/%s", p.Repo, image.GetName())
+	} else {
+		imageRepository = p.Repo
+	}
+
+	tag := p.Tag
+	if c.platform != "" {
+		tag = fmt.Sprintf("%s-%s", tag, platformTagSuffix(c.platform))
+	}
+
+	previousImageName := fmt.Sprintf("%s:%s", imageRepository, tag)
+
+	configFile, err := docker_registry.ImageConfigFile(previousImageName)
+	if err != nil {
+		logger.LogInfoF("# Previous image %s of image %s is not available: %s\n", previousImageName, imageOrderItemName(image.GetName()), err)
+		return nil
+	}
+
+	labels := configFile.Config.Labels
+
+	stageName, signature := latestStageSignatureFromLabel(labels[WerfStagesSignaturesLabel])
+	s := image.GetStage(stageName)
+	if s == nil || s.GetSignature() != signature {
+		logger.LogInfoF("# Previous image %s of image %s does not match any stage\n", previousImageName, imageOrderItemName(image.GetName()))
+		return nil
+	}
+
+	if reason := stageWerfVersionIncompatibility(labels); reason != "" {
+		logger.LogInfoF("# Previous image %s of image %s is incompatible: %s\n", previousImageName, imageOrderItemName(image.GetName()), reason)
+		return nil
+	}
+
+	img := s.GetImage()
+
+	imageLockName := fmt.Sprintf("%s.image.%s", c.stagesNamespace(), img.Name())
+	if err := lock.Lock(imageLockName, lock.LockOptions{}); err != nil {
+		return fmt.Errorf("failed to lock %s: %s", imageLockName, err)
+	}
+	defer lock.Unlock(imageLockName)
+
+	if err := img.SyncDockerState(); err != nil {
+		return err
+	}
+
+	if !img.IsExists() {
+		c.emitEvent(Event{Type: StagePullStartedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: previousImageName})
+
+		previousImage := imagePkg.NewStageImage(nil, previousImageName)
+		previousImage.SetPlatform(c.platform)
+
+		if err := previousImage.Pull(c.GetContext()); err != nil {
+			if ctxErr := c.GetContext().Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			logger.LogWarningF("WARNING: Unable to pull previous image %s of image %s: %s\n", previousImageName, imageOrderItemName(image.GetName()), err)
+			return nil
+		}
+
+		// previous image is marked as stage, so cleanup does not treat it as the final image
+		stageImage := imagePkg.NewStageImage(previousImage, img.Name())
+		stageImage.SetPlatform(c.platform)
+		stageImage.Container().ServiceCommitChangeOptions().AddLabel(map[string]string{"werf-image": "false"})
+
+		if err := stageImage.Build(imagePkg.BuildOptions{}); err != nil {
+			return fmt.Errorf("error building %s: %s", img.Name(), err)
+		}
+
+		if err := stageImage.SaveInCache(); err != nil {
+			return fmt.Errorf("failed to save in cache image %s: %s", img.Name(), err)
+		}
+
+		if err := previousImage.Untag(); err != nil {
+			return err
+		}
+
+		if err := img.SyncDockerState(); err != nil {
+			return err
+		}
+
+		logger.LogInfoF("# Stage %s of image %s is imported from previous image %s (signature %s)\n", s.Name(), imageOrderItemName(image.GetName()), previousImageName, s.GetSignature())
+	}
+
+	image.cacheFromStage = s.Name()
+
+	return nil
+}
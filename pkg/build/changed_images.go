@@ -0,0 +1,184 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/util"
+)
+
+const (
+	ImageChanged   = "changed"
+	ImageUnchanged = "unchanged"
+	ImageAdded     = "added"
+	ImageRemoved   = "removed"
+)
+
+// ChangedImagesReport describes how werf.yaml images differ between two commits of the project git repo
+type ChangedImagesReport struct {
+	FromCommit string `json:"fromCommit"`
+	ToCommit   string `json:"toCommit"`
+	// Changed are names of changed and added images
+	Changed []string                    `json:"changed"`
+	Images  []*ChangedImagesReportImage `json:"images"`
+}
+
+type ChangedImagesReportImage struct {
+	Name string `json:"name"`
+	// Status is changed, unchanged, added or removed
+	Status string `json:"status"`
+	// ConfigChanged is set if config doc of the image or of the images, which the image depends on, is changed
+	ConfigChanged bool `json:"configChanged,omitempty"`
+	// ChangedPaths are paths of the project git repo, which are changed between commits and match git mappings of the image
+	ChangedPaths []string `json:"changedPaths,omitempty"`
+}
+
+// NewChangedImagesReport compares images of werf configs of two commits without building and without docker:
+// image is changed if config docs of the image or its dependencies are changed
+// or if the project git repo has changes between commits in paths of the local git mappings (add, includePaths and excludePaths).
+// Images processing is limited by imagesToProcess, if specified.
+func NewChangedImagesReport(ctx context.Context, localGitRepo git_repo.GitRepo, fromCommit, toCommit string, fromConfig, toConfig *config.WerfConfig, imagesToProcess []string) (*ChangedImagesReport, error) {
+	report := &ChangedImagesReport{FromCommit: fromCommit, ToCommit: toCommit, Changed: []string{}}
+
+	fromImages := changedImagesReportImages(fromConfig, imagesToProcess)
+	toImages := changedImagesReportImages(toConfig, imagesToProcess)
+
+	for name, toImage := range toImages {
+		image := &ChangedImagesReportImage{Name: name}
+
+		if fromImage, ok := fromImages[name]; !ok {
+			image.Status = ImageAdded
+		} else {
+			image.ConfigChanged = imageTreeConfigChecksum(fromImage) != imageTreeConfigChecksum(toImage)
+
+			changedPaths, err := imageTreeChangedPaths(ctx, localGitRepo, fromCommit, toCommit, toImage)
+			if err != nil {
+				return nil, fmt.Errorf("image %s: %s", name, err)
+			}
+			image.ChangedPaths = changedPaths
+
+			if image.ConfigChanged || len(image.ChangedPaths) != 0 {
+				image.Status = ImageChanged
+			} else {
+				image.Status = ImageUnchanged
+			}
+		}
+
+		if image.Status != ImageUnchanged {
+			report.Changed = append(report.Changed, name)
+		}
+
+		report.Images = append(report.Images, image)
+	}
+
+	for name := range fromImages {
+		if _, ok := toImages[name]; !ok {
+			report.Images = append(report.Images, &ChangedImagesReportImage{Name: name, Status: ImageRemoved})
+		}
+	}
+
+	sort.Strings(report.Changed)
+	sort.Slice(report.Images, func(i, j int) bool { return report.Images[i].Name < report.Images[j].Name })
+
+	return report, nil
+}
+
+func changedImagesReportImages(werfConfig *config.WerfConfig, imagesToProcess []string) map[string]*config.Image {
+	res := map[string]*config.Image{}
+	for _, image := range werfConfig.Images {
+		if len(imagesToProcess) != 0 && !util.IsStringsContainValue(imagesToProcess, image.Name) {
+			continue
+		}
+
+		res[image.Name] = image
+	}
+
+	return res
+}
+
+// imageTreeConfigChecksum returns checksum of config docs of the image and of all images, which the image depends on
+func imageTreeConfigChecksum(image *config.Image) string {
+	var args []string
+	for _, imageConfig := range image.ImageTree() {
+		imageBase, imageName, _ := processImageConfig(imageConfig)
+		args = append(args, imageName, imageBase.ConfigChecksum())
+	}
+
+	return util.Sha256Hash(args...)
+}
+
+// imageTreeChangedPaths returns paths changed between commits in the local git mappings of the image and of all images, which the image depends on
+func imageTreeChangedPaths(ctx context.Context, localGitRepo git_repo.GitRepo, fromCommit, toCommit string, image *config.Image) ([]string, error) {
+	changedPaths := map[string]bool{}
+	for _, imageConfig := range image.ImageTree() {
+		imageBase, _, _ := processImageConfig(imageConfig)
+		if imageBase.Git == nil {
+			continue
+		}
+
+		for _, local := range imageBase.Git.Local {
+			patch, err := localGitRepo.CreatePatch(ctx, git_repo.PatchOptions{
+				FilterOptions: git_repo.FilterOptions{
+					BasePath:     path.Join("/", local.Add),
+					IncludePaths: local.IncludePaths,
+					ExcludePaths: local.ExcludePaths,
+				},
+				FromCommit: fromCommit,
+				ToCommit:   toCommit,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot diff git mapping `add: %s`: %s", local.Add, err)
+			}
+
+			for _, p := range patch.GetPaths() {
+				changedPaths[strings.TrimPrefix(path.Join(local.Add, p), "/")] = true
+			}
+
+			if err := os.RemoveAll(patch.GetFilePath()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var res []string
+	for p := range changedPaths {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// LogChangedImagesReport prints status of each image
+func LogChangedImagesReport(report *ChangedImagesReport) {
+	fmt.Printf("# Images changed between %s and %s\n", report.FromCommit, report.ToCommit)
+	for _, image := range report.Images {
+		fmt.Printf("%s: %s\n", imageOrderItemName(image.Name), image.Status)
+	}
+}
+
+func WriteChangedImagesReport(path string, report *ChangedImagesReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write changed images report %s: %s", path, err)
+	}
+
+	return nil
+}
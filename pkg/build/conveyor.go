@@ -1,34 +1,63 @@
 package build
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/flant/werf/pkg/build/stage"
 	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/home_usage"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/sign"
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/telemetry"
+	"github.com/flant/werf/pkg/true_git"
 	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
 )
 
 type Conveyor struct {
 	*conveyorPermanentFields
 
+	ctx context.Context
+
 	imagesInOrder []*Image
 
 	stageImages                     map[string]*image.StageImage
 	buildingGitStageNameByImageName map[string]stage.StageName
 	remoteGitRepos                  map[string]*git_repo.Remote
+	cachedGitRepos                  map[string]*git_repo.CachedGitRepo
 	imagesBySignature               map[string]image.ImageInterface
 
+	// divergedSubmodules are submodules of the project work tree, which differ from HEAD, checked once per run
+	divergedSubmodules        []*true_git.SubmoduleWorkTreeState
+	divergedSubmodulesChecked bool
+
+	// resetImages are images with stages untagged by RenewPhase, other images are reused when conveyor is restarted
+	resetImages   map[string]bool
+	imagesToReuse map[string]*Image
+
 	tmpDir string
+
+	platform               string
+	manifestListsToPublish map[string][]string
+	// publishedImages are images pushed or found up to date in the repo, which are signed after push
+	publishedImages []string
+	// imagesReportTags are published tags by image name and repository
+	imagesReportTags map[string]map[string][]string
 }
 
 type conveyorPermanentFields struct {
 	werfConfig          *config.WerfConfig
 	imageNamesToProcess []string
+	imagesOrder         []string
 
 	projectDir       string
 	projectBuildDir  string
@@ -38,6 +67,35 @@ type conveyorPermanentFields struct {
 	dockerAuthorizer DockerAuthorizer
 
 	sshAuthSock string
+
+	eventListeners []EventListener
+
+	ownGitRepo   *git_repo.Remote
+	ownGitCommit string
+
+	platforms []string
+
+	stagesRepo *stagesRepo
+
+	labels map[string]string
+
+	commitSignatureKeyrings []string
+
+	buildSecrets    []*BuildSecret
+	buildSecretsDir string
+
+	telemetry *telemetryRecorder
+
+	autoCreateRepo *docker_registry.AutoCreateRepoOptions
+
+	rebuildStages *rebuildStages
+
+	scanOptions *ScanOptions
+	scanReport  *ScanReport
+
+	takeSubmodulesFromWorkTree bool
+
+	signOptions *sign.Options
 }
 
 type DockerAuthorizer interface {
@@ -59,13 +117,70 @@ func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, pr
 			dockerAuthorizer: authorizer,
 
 			sshAuthSock: sshAuthSock,
+
+			eventListeners: []EventListener{PrintEventListener},
+
+			telemetry: newTelemetryRecorder(),
 		},
 	}
 	c.ReInitRuntimeFields()
 
+	telemetry.SetProject(c.projectName())
+
 	return c
 }
 
+// SetOwnGitRepo makes conveyor use specified commit of the remote repo instead of the project directory git repo
+func (c *Conveyor) SetOwnGitRepo(repo *git_repo.Remote, commit string) {
+	c.ownGitRepo = repo
+	c.ownGitCommit = commit
+}
+
+// SetPlatforms makes conveyor build separate stages chain for each platform (os/arch[/variant]).
+// Push publishes platform images with platform suffixed tags and manifest lists combining them by the requested tags.
+func (c *Conveyor) SetPlatforms(platforms []string) {
+	c.platforms = platforms
+}
+
+// SetStagesRepo enables registry-backed stages cache: missing stages are pulled from the repo and built stages are pushed into the repo
+func (c *Conveyor) SetStagesRepo(repo string) {
+	c.stagesRepo = newStagesRepo(repo)
+}
+
+// SetLabels adds labels to the tagged and pushed images in addition to the labels from werf.yaml
+func (c *Conveyor) SetLabels(labels map[string]string) {
+	c.labels = labels
+}
+
+// SetAutoCreateRepo makes conveyor create missing repositories before push in the registries, which require it (ECR, GCP Artifact Registry)
+func (c *Conveyor) SetAutoCreateRepo(opts docker_registry.AutoCreateRepoOptions) {
+	c.autoCreateRepo = &opts
+}
+
+// SetCommitSignatureKeyrings makes build fail if commits of git mappings are not signed by keys from specified keyrings files
+// in addition to the keyrings from werf.yaml
+func (c *Conveyor) SetCommitSignatureKeyrings(paths []string) {
+	c.commitSignatureKeyrings = paths
+}
+
+// SetBuildSecrets makes secrets available in stages containers during build
+func (c *Conveyor) SetBuildSecrets(secrets []*BuildSecret) {
+	c.buildSecrets = secrets
+}
+
+// userLabels merges werf.yaml labels and labels set by SetLabels, the latter take precedence
+func (c *Conveyor) userLabels() map[string]string {
+	res := map[string]string{}
+	for name, value := range c.werfConfig.Meta.Build.Labels {
+		res[name] = value
+	}
+	for name, value := range c.labels {
+		res[name] = value
+	}
+
+	return res
+}
+
 func (c *Conveyor) ReInitRuntimeFields() {
 	c.stageImages = make(map[string]*image.StageImage)
 	c.imagesBySignature = make(map[string]image.ImageInterface)
@@ -73,6 +188,13 @@ func (c *Conveyor) ReInitRuntimeFields() {
 	c.buildingGitStageNameByImageName = make(map[string]stage.StageName)
 
 	c.remoteGitRepos = make(map[string]*git_repo.Remote)
+	c.cachedGitRepos = make(map[string]*git_repo.CachedGitRepo)
+
+	c.divergedSubmodules = nil
+	c.divergedSubmodulesChecked = false
+
+	c.resetImages = make(map[string]bool)
+	c.imagesToReuse = nil
 
 	c.tmpDir = filepath.Join(c.baseTmpDir, string(util.GenerateConsistentRandomString(10)))
 }
@@ -81,11 +203,41 @@ type Phase interface {
 	Run(*Conveyor) error
 }
 
-func (c *Conveyor) Build(opts BuildOptions) error {
+func (c *Conveyor) Build(ctx context.Context, opts BuildOptions) error {
+	c.ctx = ctx
+	defer c.removeBuildSecretsDir()
+
+	if werf.IsOffline() && c.stagesRepo != nil {
+		return werf.OfflineError("stages repo")
+	}
+
+	if err := home_usage.EnforceQuota(); err != nil {
+		return fmt.Errorf("werf home quota enforcement failed: %s", err)
+	}
+
+	return c.forEachPlatform(func() error {
+		return c.buildWithRestart(opts)
+	})
+}
+
+func (c *Conveyor) buildWithRestart(opts BuildOptions) error {
+	// images are reused only between restarts of the same run
+	defer func() { c.imagesToReuse = nil }()
+
+	daemonReconnects := 0
+
 restart:
 	if err := c.build(opts); err != nil {
 		if isConveyorShouldBeResetError(err) {
-			c.ReInitRuntimeFields()
+			c.reInitRuntimeFieldsAfterReset()
+			goto restart
+		}
+
+		if daemonReconnects < maxDockerDaemonReconnects && docker.IsDaemonConnectionError(err) {
+			daemonReconnects++
+			if err := c.reInitRuntimeFieldsAfterDaemonReconnect(err); err != nil {
+				return err
+			}
 			goto restart
 		}
 
@@ -100,10 +252,13 @@ func (c *Conveyor) build(opts BuildOptions) error {
 
 	var phases []Phase
 	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewVerifyCommitsPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(opts.RefreshBaseImages))
 	phases = append(phases, NewSignaturesPhase())
-	phases = append(phases, NewRenewPhase())
-	phases = append(phases, NewPrepareImagesPhase())
-	phases = append(phases, NewBuildPhase(opts))
+	phases = append(phases, buildPhases(opts)...)
+	if c.scanOptions != nil {
+		phases = append(phases, NewScanPhase())
+	}
 
 	lockName, err := c.lockAllImagesReadOnly()
 	if err != nil {
@@ -127,11 +282,20 @@ type PushOptions struct {
 	WithStages bool
 }
 
-func (c *Conveyor) Tag(repo string, opts TagOptions) error {
+func (c *Conveyor) Tag(ctx context.Context, repo string, opts TagOptions) error {
+	c.ctx = ctx
+
+	return c.forEachPlatform(func() error {
+		return c.tag(repo, opts)
+	})
+}
+
+func (c *Conveyor) tag(repo string, opts TagOptions) error {
 	var err error
 
 	var phases []Phase
 	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(false))
 	phases = append(phases, NewSignaturesPhase())
 	phases = append(phases, NewShouldBeBuiltPhase())
 	phases = append(phases, NewTagPhase(repo, opts))
@@ -145,11 +309,73 @@ func (c *Conveyor) Tag(repo string, opts TagOptions) error {
 	return c.runPhases(phases)
 }
 
-func (c *Conveyor) Push(repo string, opts PushOptions) error {
+// Export saves the final image of the specified image from werf.yaml into the docker archive or OCI layout,
+// images for all platforms are saved into the same archive or layout
+func (c *Conveyor) Export(ctx context.Context, imageName, repo string, opts ExportOptions) error {
+	c.ctx = ctx
+
+	exportPhase := NewExportPhase(imageName, repo, opts.TagOptions)
+	if err := c.forEachPlatform(func() error {
+		return c.export(exportPhase)
+	}); err != nil {
+		return err
+	}
+
+	return c.saveExportedImages(imageName, exportPhase.TaggedImages, opts)
+}
+
+func (c *Conveyor) export(exportPhase *ExportPhase) error {
+	var err error
+
+	var phases []Phase
+	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(false))
+	phases = append(phases, NewSignaturesPhase())
+	phases = append(phases, NewShouldBeBuiltPhase())
+	phases = append(phases, exportPhase)
+
+	lockName, err := c.lockAllImagesReadOnly()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(lockName)
+
+	return c.runPhases(phases)
+}
+
+func (c *Conveyor) Push(ctx context.Context, repo string, opts PushOptions) error {
+	c.ctx = ctx
+	c.manifestListsToPublish = map[string][]string{}
+	c.publishedImages = nil
+	c.imagesReportTags = nil
+
+	if werf.IsOffline() {
+		return werf.OfflineError("push")
+	}
+
+	if err := home_usage.EnforceQuota(); err != nil {
+		return fmt.Errorf("werf home quota enforcement failed: %s", err)
+	}
+
+	if err := c.forEachPlatform(func() error {
+		return c.push(repo, opts)
+	}); err != nil {
+		return err
+	}
+
+	if err := c.publishManifestLists(repo); err != nil {
+		return err
+	}
+
+	return c.signPublishedImages()
+}
+
+func (c *Conveyor) push(repo string, opts PushOptions) error {
 	var err error
 
 	var phases []Phase
 	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(false))
 	phases = append(phases, NewSignaturesPhase())
 	phases = append(phases, NewShouldBeBuiltPhase())
 	phases = append(phases, NewPushPhase(repo, opts))
@@ -163,11 +389,48 @@ func (c *Conveyor) Push(repo string, opts PushOptions) error {
 	return c.runPhases(phases)
 }
 
-func (c *Conveyor) BP(repo string, buildOpts BuildOptions, pushOpts PushOptions) error {
+func (c *Conveyor) BP(ctx context.Context, repo string, buildOpts BuildOptions, pushOpts PushOptions) error {
+	c.ctx = ctx
+	defer c.removeBuildSecretsDir()
+	c.manifestListsToPublish = map[string][]string{}
+	c.publishedImages = nil
+	c.imagesReportTags = nil
+
+	if werf.IsOffline() {
+		return werf.OfflineError("push")
+	}
+
+	if err := c.forEachPlatform(func() error {
+		return c.bpWithRestart(repo, buildOpts, pushOpts)
+	}); err != nil {
+		return err
+	}
+
+	if err := c.publishManifestLists(repo); err != nil {
+		return err
+	}
+
+	return c.signPublishedImages()
+}
+
+func (c *Conveyor) bpWithRestart(repo string, buildOpts BuildOptions, pushOpts PushOptions) error {
+	// images are reused only between restarts of the same run
+	defer func() { c.imagesToReuse = nil }()
+
+	daemonReconnects := 0
+
 restart:
 	if err := c.bp(repo, buildOpts, pushOpts); err != nil {
 		if isConveyorShouldBeResetError(err) {
-			c.ReInitRuntimeFields()
+			c.reInitRuntimeFieldsAfterReset()
+			goto restart
+		}
+
+		if daemonReconnects < maxDockerDaemonReconnects && docker.IsDaemonConnectionError(err) {
+			daemonReconnects++
+			if err := c.reInitRuntimeFieldsAfterDaemonReconnect(err); err != nil {
+				return err
+			}
 			goto restart
 		}
 
@@ -182,10 +445,13 @@ func (c *Conveyor) bp(repo string, buildOpts BuildOptions, pushOpts PushOptions)
 
 	var phases []Phase
 	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewVerifyCommitsPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(buildOpts.RefreshBaseImages))
 	phases = append(phases, NewSignaturesPhase())
-	phases = append(phases, NewRenewPhase())
-	phases = append(phases, NewPrepareImagesPhase())
-	phases = append(phases, NewBuildPhase(buildOpts))
+	phases = append(phases, buildPhases(buildOpts)...)
+	if c.scanOptions != nil {
+		phases = append(phases, NewScanPhase())
+	}
 	phases = append(phases, NewPushPhase(repo, pushOpts))
 
 	lockName, err := c.lockAllImagesReadOnly()
@@ -197,9 +463,69 @@ func (c *Conveyor) bp(repo string, buildOpts BuildOptions, pushOpts PushOptions)
 	return c.runPhases(phases)
 }
 
-func (c *Conveyor) runPhases(phases []Phase) error {
+// buildPhases returns phases to build stages, stages are neither imported from previous images, renewed nor prepared in read-only stages mode
+func buildPhases(opts BuildOptions) []Phase {
+	if opts.ReadOnlyStages {
+		return []Phase{NewBuildPhase(opts)}
+	}
+
+	var phases []Phase
+	if opts.CacheFromTag != "" {
+		phases = append(phases, NewCacheFromPhase(opts.CacheFromRepo, opts.CacheFromTag))
+	}
+
+	return append(phases, NewRenewPhase(), NewPrepareImagesPhase(), NewBuildPhase(opts))
+}
+
+// forEachPlatform runs f once without platform or once for each platform with a fresh runtime state
+func (c *Conveyor) forEachPlatform(f func() error) error {
+	if len(c.platforms) == 0 {
+		return f()
+	}
+
+	defer func() { c.platform = "" }()
+
+	for _, platform := range c.platforms {
+		c.platform = platform
+		c.ReInitRuntimeFields()
+
+		if err := f(); err != nil {
+			return fmt.Errorf("platform %s: %s", platform, err)
+		}
+	}
+
+	return nil
+}
+
+// targetPlatform returns the platform, which stages are built for: the current platform or the docker daemon os/arch
+func (c *Conveyor) targetPlatform() (string, error) {
+	if c.platform != "" {
+		return c.platform, nil
+	}
+
+	v, err := docker.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("unable to get docker server platform: %s", err)
+	}
+
+	return fmt.Sprintf("%s/%s", v.Os, v.Arch), nil
+}
+
+func (c *Conveyor) runPhases(phases []Phase) (err error) {
+	runSpan := telemetry.StartSpan("conveyor run", nil, map[string]string{"werf.project": c.projectName()})
+	if c.platform != "" {
+		runSpan.SetAttribute("werf.platform", c.platform)
+	}
+	defer func() { runSpan.End(err) }()
+
 	for _, phase := range phases {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		c.telemetry.startPhase(runSpan, phase)
 		err := phase.Run(c)
+		c.telemetry.endPhase(err)
 		if err != nil {
 			return err
 		}
@@ -207,12 +533,86 @@ func (c *Conveyor) runPhases(phases []Phase) error {
 	return nil
 }
 
+func (c *Conveyor) GetContext() context.Context {
+	return c.ctx
+}
+
 func (c *Conveyor) projectName() string {
 	return c.werfConfig.Meta.Project
 }
 
+// stagesNamespace is a name of the local stages cache, which can be shared by several projects of one repo
+func (c *Conveyor) stagesNamespace() string {
+	if c.werfConfig.Meta.StagesNamespace != "" {
+		return c.werfConfig.Meta.StagesNamespace
+	}
+	return c.projectName()
+}
+
+// finalImageServiceLabels returns service labels of the tagged and pushed images: tag scheme, signatures of the image stages and commit of the project git repo, which image has been built from, with commit author, date and subject
+func (c *Conveyor) finalImageServiceLabels(image *Image, scheme TagScheme) (map[string]string, error) {
+	labels := map[string]string{
+		"werf-tag-scheme":         string(scheme),
+		"werf-image":              "true",
+		WerfStagesSignaturesLabel: stagesSignaturesLabelValue(image),
+	}
+
+	commitInfo, err := c.projectGitCommitInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if commitInfo != nil {
+		labels[WerfGitCommitLabel] = commitInfo.Hash
+		labels[WerfGitCommitAuthorLabel] = fmt.Sprintf("%s <%s>", commitInfo.Author.Name, commitInfo.Author.Email)
+		labels[WerfGitCommitDateLabel] = commitInfo.Committer.Time.UTC().Format(time.RFC3339)
+		labels[WerfGitCommitSubjectLabel] = commitInfo.Subject()
+	}
+
+	return labels, nil
+}
+
+// projectGitCommitInfo returns metadata of the project git repo commit or nil if project dir is not a git repo
+func (c *Conveyor) projectGitCommitInfo() (*git_repo.CommitInfo, error) {
+	commit, err := c.projectGitCommit()
+	if err != nil || commit == "" {
+		return nil, err
+	}
+
+	if c.ownGitRepo != nil {
+		return c.ownGitRepo.CommitInfo(commit)
+	}
+
+	localGitRepo := &git_repo.Local{Path: c.projectDir, GitDir: path.Join(c.projectDir, ".git")}
+
+	return localGitRepo.CommitInfo(commit)
+}
+
+// projectGitCommit returns commit of the project git repo or empty string if project dir is not a git repo
+func (c *Conveyor) projectGitCommit() (string, error) {
+	if c.ownGitRepo != nil {
+		if c.ownGitCommit != "" {
+			return c.ownGitCommit, nil
+		}
+
+		return c.ownGitRepo.HeadCommit()
+	}
+
+	gitDir := path.Join(c.projectDir, ".git")
+	exist, err := util.DirExists(gitDir)
+	if err != nil {
+		return "", err
+	} else if !exist {
+		return "", nil
+	}
+
+	localGitRepo := &git_repo.Local{Path: c.projectDir, GitDir: gitDir}
+
+	return localGitRepo.HeadCommit()
+}
+
 func (c *Conveyor) lockAllImagesReadOnly() (string, error) {
-	lockName := fmt.Sprintf("%s.images", c.projectName())
+	lockName := fmt.Sprintf("%s.images", c.stagesNamespace())
 	err := lock.Lock(lockName, lock.LockOptions{ReadOnly: true})
 	if err != nil {
 		return "", fmt.Errorf("error locking %s: %s", lockName, err)
@@ -230,6 +630,7 @@ func (c *Conveyor) GetOrCreateImage(fromImage *image.StageImage, name string) *i
 	}
 
 	img := image.NewStageImage(fromImage, name)
+	img.SetPlatform(c.platform)
 	c.stageImages[name] = img
 	return img
 }
@@ -277,6 +678,45 @@ func (c *Conveyor) GetBuildingGitStage(imageName string) stage.StageName {
 	return stageName
 }
 
+// getCachedGitRepo returns repo wrapper shared by all images of the conveyor,
+// so equal archives and patches are created only once
+func (c *Conveyor) getCachedGitRepo(repo git_repo.GitRepo) *git_repo.CachedGitRepo {
+	if cachedRepo, ok := c.cachedGitRepos[repo.GetName()]; ok {
+		return cachedRepo
+	}
+
+	cacheDir := path.Join(c.tmpDir, "git_repo_cache", slug.Slug(repo.GetName()))
+	cachedRepo := git_repo.NewCachedGitRepo(repo, cacheDir)
+	c.cachedGitRepos[repo.GetName()] = cachedRepo
+
+	return cachedRepo
+}
+
+// GetImageTmpDir returns tmp dir of the image, which is removed as soon as all image stages are built
 func (c *Conveyor) GetImageTmpDir(imageName string) string {
-	return path.Join(c.tmpDir, "image", imageName)
+	if imageName == "" {
+		return path.Join(c.tmpDir, "nameless_image")
+	}
+
+	return path.Join(c.tmpDir, "image", slug.Slug(imageName))
+}
+
+// GetStageLogPath returns path of the file with output of the latest build of the image stage
+func (c *Conveyor) GetStageLogPath(imageName string, stageName stage.StageName) string {
+	return GetStageLogPath(c.projectBuildDir, imageName, string(stageName))
+}
+
+// GetStageLogPath returns path of the file with output of the latest build of the image stage in the project build dir
+func GetStageLogPath(projectBuildDir, imageName, stageName string) string {
+	imageDir := "nameless_image"
+	if imageName != "" {
+		imageDir = path.Join("image", slug.Slug(imageName))
+	}
+
+	return path.Join(projectBuildDir, "stage_logs", imageDir, fmt.Sprintf("%s.log", stageName))
+}
+
+// GetImageStageTmpDir returns tmp dir of the image stage, which is removed as soon as the stage is built
+func (c *Conveyor) GetImageStageTmpDir(imageName string, stageName stage.StageName) string {
+	return stage.GetStageTmpDir(c.GetImageTmpDir(imageName), stageName)
 }
@@ -0,0 +1,100 @@
+package build
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logger"
+)
+
+// reInitRuntimeFieldsAfterReset prepares conveyor to restart after RenewPhase has untagged stages.
+// Images without untagged stages keep stages and signatures calculated by the previous run,
+// only reset images and images, which depend on them, are initialized and signed again.
+func (c *Conveyor) reInitRuntimeFieldsAfterReset() {
+	imagesToReuse := map[string]*Image{}
+	stageImages := map[string]*image.StageImage{}
+
+	var resetImageNames []string
+	for _, img := range c.imagesInOrder {
+		if c.resetImages[img.GetName()] {
+			resetImageNames = append(resetImageNames, imageOrderItemName(img.GetName()))
+			continue
+		}
+
+		imagesToReuse[img.GetName()] = img
+
+		if baseImage := img.GetBaseImage(); baseImage != nil {
+			stageImages[baseImage.Name()] = baseImage
+		}
+
+		for _, s := range img.GetStages() {
+			if stageImage, ok := s.GetImage().(*image.StageImage); ok && stageImage != nil {
+				stageImages[stageImage.Name()] = stageImage
+			}
+		}
+	}
+
+	sort.Strings(resetImageNames)
+	logger.LogInfoF("# Conveyor reset: recalculating signatures of %s and dependent images\n", strings.Join(resetImageNames, ", "))
+
+	c.imagesToReuse = imagesToReuse
+	c.stageImages = stageImages
+	c.imagesBySignature = make(map[string]image.ImageInterface)
+	c.resetImages = make(map[string]bool)
+}
+
+// maxDockerDaemonReconnects limits number of conveyor restarts after docker daemon has gone during a single run
+const maxDockerDaemonReconnects = 3
+
+// reInitRuntimeFieldsAfterDaemonReconnect waits for docker daemon and prepares conveyor to restart from the beginning.
+// Stages, which have been saved into the stages cache before the daemon has gone, are not built again,
+// so the build is resumed from the first stage, which is missing in the stages cache.
+func (c *Conveyor) reInitRuntimeFieldsAfterDaemonReconnect(err error) error {
+	logger.LogWarningF("WARNING: Lost connection to docker daemon: %s\n", err)
+
+	if err := docker.WaitForDaemon(c.GetContext()); err != nil {
+		return err
+	}
+
+	logger.LogInfoF("# Conveyor reset: docker daemon is available again, resuming from the stages cache\n")
+
+	c.ReInitRuntimeFields()
+
+	return nil
+}
+
+// getImageToReuse returns image of the previous run, if neither the image nor its dependencies have been reset
+func (c *Conveyor) getImageToReuse(imageConfig config.ImageInterface, initializedImages map[string]bool) *Image {
+	_, imageName, _ := processImageConfig(imageConfig)
+
+	img, ok := c.imagesToReuse[imageName]
+	if !ok {
+		return nil
+	}
+
+	fromImage, imports, dependsOn := config.ImageDependencies(imageConfig)
+
+	var dependencies []config.ImageInterface
+	if fromImage != nil {
+		dependencies = append(dependencies, fromImage)
+	}
+	dependencies = append(dependencies, imports...)
+	dependencies = append(dependencies, dependsOn...)
+
+	for _, dependency := range dependencies {
+		_, dependencyName, _ := processImageConfig(dependency)
+		if initializedImages[dependencyName] {
+			return nil
+		}
+	}
+
+	return img
+}
+
+// isImageReused reports whether the image has been taken from the previous run without recalculation of stages signatures
+func (c *Conveyor) isImageReused(img *Image) bool {
+	return c.imagesToReuse[img.GetName()] == img
+}
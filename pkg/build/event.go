@@ -0,0 +1,131 @@
+package build
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flant/werf/pkg/build/stage"
+)
+
+type EventType string
+
+const (
+	StageSignatureCalculatedEvent EventType = "stage_signature_calculated"
+	StageResetEvent               EventType = "stage_reset"
+	StagePreparedEvent            EventType = "stage_prepared"
+	StageCacheHitEvent            EventType = "stage_cache_hit"
+	StageBuildStartedEvent        EventType = "stage_build_started"
+	StageBuildFinishedEvent       EventType = "stage_build_finished"
+	BaseImagePullStartedEvent     EventType = "base_image_pull_started"
+	BaseImageResolvedEvent        EventType = "base_image_resolved"
+	ImageStagesPushStartedEvent   EventType = "image_stages_push_started"
+	StagePullStartedEvent         EventType = "stage_pull_started"
+	StagePushSkippedEvent         EventType = "stage_push_skipped"
+	StagePushStartedEvent         EventType = "stage_push_started"
+	StagePushFinishedEvent        EventType = "stage_push_finished"
+	ImagePushStartedEvent         EventType = "image_push_started"
+	ImageTagStartedEvent          EventType = "image_tag_started"
+	TagBuildStartedEvent          EventType = "tag_build_started"
+	TagPushSkippedEvent           EventType = "tag_push_skipped"
+	TagPushStartedEvent           EventType = "tag_push_started"
+	TagPushFinishedEvent          EventType = "tag_push_finished"
+	TagStartedEvent               EventType = "tag_started"
+	ImageExportStartedEvent       EventType = "image_export_started"
+	ManifestListPushStartedEvent  EventType = "manifest_list_push_started"
+	ManifestListPushFinishedEvent EventType = "manifest_list_push_finished"
+	ImageSignStartedEvent         EventType = "image_sign_started"
+	ImageSignFinishedEvent        EventType = "image_sign_finished"
+)
+
+// Event describes a single step of the conveyor work.
+// ImageName is empty for the nameless image, DockerImageName is a docker image the event relates to.
+type Event struct {
+	Type            EventType
+	Time            time.Time
+	ImageName       string
+	StageName       stage.StageName
+	Signature       string
+	DockerImageName string
+	TagScheme       TagScheme
+}
+
+type EventListener func(event Event)
+
+func PrintEventListener(event Event) {
+	if msg := event.String(); msg != "" {
+		fmt.Println(msg)
+	}
+}
+
+func (e Event) String() string {
+	image := "image"
+	if e.ImageName != "" {
+		image = fmt.Sprintf("image/%s", e.ImageName)
+	}
+
+	switch e.Type {
+	case StageSignatureCalculatedEvent:
+		return fmt.Sprintf("# Calculated signature %s for %s stage/%s", e.Signature, image, e.StageName)
+	case StageResetEvent:
+		return fmt.Sprintf("# Reseting image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case StagePreparedEvent:
+		return fmt.Sprintf("# Prepared for build image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case StageCacheHitEvent:
+		return fmt.Sprintf("# Using cached image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case StageBuildStartedEvent:
+		return fmt.Sprintf("# Building image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case BaseImagePullStartedEvent:
+		return fmt.Sprintf("# Pulling base image for %s", image)
+	case BaseImageResolvedEvent:
+		return fmt.Sprintf("# Using base image %s for %s", e.DockerImageName, image)
+	case ImageStagesPushStartedEvent:
+		return fmt.Sprintf("# Pushing %s stages cache", image)
+	case StagePullStartedEvent:
+		return fmt.Sprintf("# Pulling image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case StagePushSkippedEvent:
+		return fmt.Sprintf("# Ignore existing in repo image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case StagePushStartedEvent:
+		return fmt.Sprintf("# Pushing image %s for %s stage/%s", e.DockerImageName, image, e.StageName)
+	case ImagePushStartedEvent:
+		return fmt.Sprintf("# Pushing %s", image)
+	case ImageTagStartedEvent:
+		return fmt.Sprintf("# Tagging %s", image)
+	case TagBuildStartedEvent:
+		return fmt.Sprintf("# Build %s layer with tag scheme '%s'", e.DockerImageName, e.TagScheme)
+	case TagPushSkippedEvent:
+		return fmt.Sprintf("# Ignore existing in repo image %s for %s", e.DockerImageName, image)
+	case TagPushStartedEvent:
+		return fmt.Sprintf("# Pushing image %s for %s", e.DockerImageName, image)
+	case TagStartedEvent:
+		return fmt.Sprintf("# Tagging image %s for %s", e.DockerImageName, image)
+	case ImageExportStartedEvent:
+		return fmt.Sprintf("# Exporting image %s for %s", e.DockerImageName, image)
+	case ManifestListPushStartedEvent:
+		return fmt.Sprintf("# Pushing manifest list %s", e.DockerImageName)
+	case ImageSignStartedEvent:
+		return fmt.Sprintf("# Signing image %s", e.DockerImageName)
+	}
+
+	return ""
+}
+
+func (c *Conveyor) AddEventListener(listener EventListener) {
+	c.eventListeners = append(c.eventListeners, listener)
+}
+
+// SetEventListeners replaces all event listeners including default PrintEventListener.
+func (c *Conveyor) SetEventListeners(listeners ...EventListener) {
+	c.eventListeners = listeners
+}
+
+func (c *Conveyor) emitEvent(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	c.telemetry.recordEvent(event)
+
+	for _, listener := range c.eventListeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,101 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flant/werf/pkg/docker"
+	imagePkg "github.com/flant/werf/pkg/image"
+)
+
+const (
+	DockerArchiveExportFormat = "docker-archive"
+	OCIExportFormat           = "oci"
+)
+
+type ExportOptions struct {
+	TagOptions
+
+	// Format is docker-archive (docker save format) or oci (OCI image layout)
+	Format string
+	Path   string
+}
+
+// ParseExportDestination parses FORMAT:PATH, e.g. oci:/path or docker-archive:/file.tar
+func ParseExportDestination(destination string) (string, string, error) {
+	parts := strings.SplitN(destination, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("bad export destination '%s': expected oci:PATH or docker-archive:FILE", destination)
+	}
+
+	switch parts[0] {
+	case DockerArchiveExportFormat, OCIExportFormat:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("bad export destination '%s': unknown format '%s', expected %s or %s", destination, parts[0], OCIExportFormat, DockerArchiveExportFormat)
+	}
+}
+
+func NewExportPhase(imageName, repo string, opts TagOptions) *ExportPhase {
+	return &ExportPhase{TagPhase: NewTagPhase(repo, opts), ImageName: imageName}
+}
+
+// ExportPhase tags the final image of the specified werf.yaml image, the tagged images are saved by the conveyor after all platforms are processed
+type ExportPhase struct {
+	*TagPhase
+	ImageName string
+}
+
+func (p *ExportPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("ExportPhase.Run\n")
+	}
+
+	for _, image := range c.imagesInOrder {
+		if image.isArtifact || image.GetName() != p.ImageName {
+			continue
+		}
+
+		c.emitEvent(Event{Type: ImageTagStartedEvent, ImageName: image.GetName()})
+
+		if err := p.tagImage(c, image); err != nil {
+			return fmt.Errorf("unable to tag image %s: %s", image.GetName(), err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("image '%s' is not defined in werf.yaml", p.ImageName)
+}
+
+func (c *Conveyor) saveExportedImages(imageName string, names []string, opts ExportOptions) error {
+	for _, name := range names {
+		c.emitEvent(Event{Type: ImageExportStartedEvent, ImageName: imageName, DockerImageName: name})
+	}
+
+	switch opts.Format {
+	case DockerArchiveExportFormat:
+		return docker.CliSave(append([]string{"--output", opts.Path}, names...)...)
+	case OCIExportFormat:
+		if err := os.MkdirAll(c.tmpDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		archivePath := filepath.Join(c.tmpDir, "export.tar")
+		defer os.Remove(archivePath)
+
+		if err := docker.CliSave(append([]string{"--output", archivePath}, names...)...); err != nil {
+			return err
+		}
+
+		if err := imagePkg.DockerArchiveToOCILayout(archivePath, opts.Path); err != nil {
+			return fmt.Errorf("cannot convert docker archive into OCI layout %s: %s", opts.Path, err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown export format '%s'", opts.Format)
+	}
+}
@@ -2,12 +2,14 @@ package build
 
 import (
 	"fmt"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/ci_env"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/werf"
 )
 
 type Image struct {
@@ -15,10 +17,19 @@ type Image struct {
 
 	baseImageName      string
 	baseImageImageName string
+	baseImageDigest    string
 
 	stages     []stage.Interface
 	baseImage  *image.StageImage
 	isArtifact bool
+
+	cacheVersion       string
+	stagesCacheVersion map[string]string
+	stagesTimeout      map[string]time.Duration
+	stagesRetries      map[string]int
+
+	// cacheFromStage is the stage imported from the previously published image
+	cacheFromStage stage.StageName
 }
 
 func (d *Image) SetStages(stages []stage.Interface) {
@@ -39,16 +50,70 @@ func (d *Image) GetStage(name stage.StageName) stage.Interface {
 	return nil
 }
 
+// LatestStage returns the stage of the resulting image: check stage is not a part of the image
 func (d *Image) LatestStage() stage.Interface {
-	return d.stages[len(d.stages)-1]
+	latestStage := d.stages[len(d.stages)-1]
+	if latestStage.Name() == stage.Check {
+		return d.stages[len(d.stages)-2]
+	}
+
+	return latestStage
+}
+
+// isCoveredByCacheFrom reports whether stage precedes the stage imported from the previously published image.
+// Signature of the imported stage depends on signatures of all previous stages, so these stages are not needed to build the image.
+func (d *Image) isCoveredByCacheFrom(s stage.Interface) bool {
+	if d.cacheFromStage == "" {
+		return false
+	}
+
+	for _, st := range d.stages {
+		if st.Name() == d.cacheFromStage {
+			return false
+		}
+
+		if st.Name() == s.Name() {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (d *Image) GetName() string {
 	return d.name
 }
 
+// cacheVersionChecksumArgs returns user defined cache versions of the project, the image and the stage to be mixed into stage signature
+func (d *Image) cacheVersionChecksumArgs(c *Conveyor, stageName stage.StageName) []string {
+	var args []string
+
+	if c.werfConfig.Meta.CacheVersion != "" {
+		args = append(args, fmt.Sprintf("project-cache-version:%s", c.werfConfig.Meta.CacheVersion))
+	}
+
+	if d.cacheVersion != "" {
+		args = append(args, fmt.Sprintf("image-cache-version:%s", d.cacheVersion))
+	}
+
+	if stageCacheVersion, ok := d.stagesCacheVersion[string(stageName)]; ok && stageCacheVersion != "" {
+		args = append(args, fmt.Sprintf("stage-cache-version:%s", stageCacheVersion))
+	}
+
+	return args
+}
+
+// pinnedBaseImageName returns base image name with resolved digest (REPO@DIGEST) instead of tag
+func (d *Image) pinnedBaseImageName() string {
+	if d.baseImageDigest == "" {
+		return d.baseImageName
+	}
+
+	return fmt.Sprintf("%s@%s", imageRepository(d.baseImageName), d.baseImageDigest)
+}
+
 func (d *Image) SetupBaseImage(c *Conveyor) {
-	baseImageName := d.baseImageName
+	baseImageName := d.pinnedBaseImageName()
 	if d.baseImageImageName != "" {
 		baseImageName = c.GetImage(d.baseImageImageName).LatestStage().GetImage().Name()
 	}
@@ -71,33 +136,59 @@ func (d *Image) PrepareBaseImage(c *Conveyor) error {
 		return nil
 	}
 
-	ciRegistry := os.Getenv("CI_REGISTRY")
-	if ciRegistry != "" && strings.HasPrefix(d.baseImage.Name(), ciRegistry) {
-		err := c.GetDockerAuthorizer().LoginForPull(ciRegistry)
-		if err != nil {
-			return fmt.Errorf("login into repo %s for base image %s failed: %s", ciRegistry, d.baseImage.Name(), err)
+	if werf.IsOffline() {
+		if !d.baseImage.IsExists() {
+			return fmt.Errorf("base image %s not found locally: %s", d.baseImage.Name(), werf.OfflineError("pull"))
 		}
+
+		return d.checkBaseImagePlatform()
 	}
 
-	if d.GetName() == "" {
-		fmt.Printf("# Pulling base image for image\n")
-	} else {
-		fmt.Printf("# Pulling base image for image/%s\n", d.GetName())
+	if err := loginForBaseImagePull(c, d.baseImage.Name()); err != nil {
+		return err
 	}
 
+	c.emitEvent(Event{Type: BaseImagePullStartedEvent, ImageName: d.GetName(), DockerImageName: d.baseImage.Name()})
+
 	if d.baseImage.IsExists() {
-		err := d.baseImage.Pull()
+		err := d.baseImage.Pull(c.GetContext())
 		if err != nil {
 			logger.LogWarningF("WARNING: cannot pull base image %s: %s\n", d.baseImage.Name(), err)
 			logger.LogWarningF("WARNING: using existing image %s without pull\n", d.baseImage.Name())
 		}
-		return nil
+
+		return d.checkBaseImagePlatform()
 	}
 
-	err := d.baseImage.Pull()
+	err := d.baseImage.Pull(c.GetContext())
 	if err != nil {
 		return fmt.Errorf("image %s pull failed: %s", d.baseImage.Name(), err)
 	}
 
+	return d.checkBaseImagePlatform()
+}
+
+func loginForBaseImagePull(c *Conveyor, baseImageName string) error {
+	ciRegistry := ci_env.GetRegistry()
+	if ciRegistry != "" && strings.HasPrefix(baseImageName, ciRegistry) {
+		err := c.GetDockerAuthorizer().LoginForPull(ciRegistry)
+		if err != nil {
+			return fmt.Errorf("login into repo %s for base image %s failed: %s", ciRegistry, baseImageName, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Image) checkBaseImagePlatform() error {
+	matched, err := d.baseImage.IsPlatformMatched()
+	if err != nil {
+		return err
+	}
+
+	if !matched {
+		return fmt.Errorf("base image %s does not match target platform %s", d.baseImage.Name(), d.baseImage.GetPlatform())
+	}
+
 	return nil
 }
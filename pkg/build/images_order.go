@@ -0,0 +1,154 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/logger"
+)
+
+// ImageOrderItem describes position of the image in the build order and why the image is built
+type ImageOrderItem struct {
+	Name       string
+	IsArtifact bool
+	Reasons    []string
+
+	config config.ImageInterface
+}
+
+// SetImagesOrder makes conveyor process specified images first in the specified order, other images follow in werf.yaml order.
+// Images, which are used by the image (fromImage, import, dependsOn), are always built before the image.
+func (c *Conveyor) SetImagesOrder(imageNames []string) {
+	c.imagesOrder = imageNames
+}
+
+// ImagesOrder returns images in the build order with reasons, conveyor phases are not run
+func (c *Conveyor) ImagesOrder() ([]*ImageOrderItem, error) {
+	return getImagesOrder(c.werfConfig.Images, c)
+}
+
+func getImagesOrder(imageConfigs []*config.Image, c *Conveyor) ([]*ImageOrderItem, error) {
+	imageConfigsToProcess, reasons, err := getOrderedImageConfigsToProcess(imageConfigs, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*ImageOrderItem
+	itemsByConfig := map[config.ImageInterface]*ImageOrderItem{}
+
+	var addImage func(imageConfig config.ImageInterface, reason string)
+	addImage = func(imageConfig config.ImageInterface, reason string) {
+		// dependencies of the visited image have been already added, only the reason is added
+		if item, ok := itemsByConfig[imageConfig]; ok {
+			item.Reasons = appendReason(item.Reasons, reason)
+			return
+		}
+
+		_, name, isArtifact := processImageConfig(imageConfig)
+
+		item := &ImageOrderItem{
+			Name:       name,
+			IsArtifact: isArtifact,
+			Reasons:    []string{reason},
+			config:     imageConfig,
+		}
+		itemsByConfig[imageConfig] = item
+
+		fromImage, imports, dependsOn := config.ImageDependencies(imageConfig)
+		if fromImage != nil {
+			addImage(fromImage, fmt.Sprintf("base image of %s", imageOrderItemName(name)))
+		}
+		for _, importImage := range imports {
+			addImage(importImage, fmt.Sprintf("imported into %s", imageOrderItemName(name)))
+		}
+		for _, dependency := range dependsOn {
+			addImage(dependency, fmt.Sprintf("dependsOn of %s", imageOrderItemName(name)))
+		}
+
+		items = append(items, item)
+	}
+
+	for ind, imageConfig := range imageConfigsToProcess {
+		addImage(imageConfig, reasons[ind])
+	}
+
+	return items, nil
+}
+
+func getOrderedImageConfigsToProcess(imageConfigs []*config.Image, c *Conveyor) ([]*config.Image, []string, error) {
+	var res []*config.Image
+	var reasons []string
+
+	for ind, imageName := range c.imagesOrder {
+		imageConfig := getImageConfigByName(imageConfigs, imageName)
+		if imageConfig == nil {
+			return nil, nil, fmt.Errorf("image '%s' specified in images order isn't defined in werf.yaml", imageName)
+		}
+
+		if len(c.imageNamesToProcess) != 0 && !isImageNameInList(c.imageNamesToProcess, imageName) {
+			continue
+		}
+
+		if !isImageNameInList(c.imagesOrder[:ind], imageName) {
+			res = append(res, imageConfig)
+			reasons = append(reasons, fmt.Sprintf("position %d in images order", ind+1))
+		}
+	}
+
+	reason := "defined in werf.yaml"
+	if len(c.imageNamesToProcess) != 0 {
+		reason = "requested"
+	}
+
+	for _, imageConfig := range getImageConfigToProcess(imageConfigs, c) {
+		if isImageNameInList(c.imagesOrder, imageConfig.Name) {
+			continue
+		}
+
+		res = append(res, imageConfig)
+		reasons = append(reasons, reason)
+	}
+
+	return res, reasons, nil
+}
+
+func isImageNameInList(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendReason(reasons []string, reason string) []string {
+	for _, r := range reasons {
+		if r == reason {
+			return reasons
+		}
+	}
+
+	return append(reasons, reason)
+}
+
+func imageOrderItemName(name string) string {
+	if name == "" {
+		return "~"
+	}
+
+	return fmt.Sprintf("'%s'", name)
+}
+
+// LogImagesOrder prints images build order with reasons
+func LogImagesOrder(items []*ImageOrderItem) {
+	for ind, item := range items {
+		kind := "image"
+		if item.IsArtifact {
+			kind = "artifact"
+		}
+
+		logger.LogInfoF("%d. %s %s: %s\n", ind+1, kind, imageOrderItemName(item.Name), strings.Join(item.Reasons, ", "))
+	}
+}
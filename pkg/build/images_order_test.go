@@ -0,0 +1,114 @@
+package build
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flant/werf/pkg/config"
+)
+
+// testImagesConfigs returns images configs: app is built from base and imports assets artifact, worker depends on app and migrations
+func testImagesConfigs() []*config.Image {
+	assets := &config.ImageArtifact{ImageBase: &config.ImageBase{Name: "assets", From: "node"}}
+	base := &config.Image{ImageBase: &config.ImageBase{Name: "base", From: "alpine"}}
+	migrations := &config.Image{ImageBase: &config.ImageBase{Name: "migrations", From: "alpine"}}
+	app := &config.Image{ImageBase: &config.ImageBase{
+		Name:      "app",
+		FromImage: base,
+		Import:    []*config.ArtifactImport{{ImageArtifact: assets}},
+	}}
+	worker := &config.Image{ImageBase: &config.ImageBase{
+		Name:      "worker",
+		FromImage: base,
+		DependsOn: []config.ImageInterface{app, migrations},
+	}}
+
+	return []*config.Image{worker, app, base, migrations}
+}
+
+// newTestConveyor returns conveyor with only images order options set
+func newTestConveyor(imagesOrder, imageNamesToProcess []string) *Conveyor {
+	return &Conveyor{conveyorPermanentFields: &conveyorPermanentFields{
+		imagesOrder:         imagesOrder,
+		imageNamesToProcess: imageNamesToProcess,
+	}}
+}
+
+type testImageOrderItem struct {
+	Name       string
+	IsArtifact bool
+	Reasons    []string
+}
+
+func testImagesOrder(t *testing.T, c *Conveyor) []testImageOrderItem {
+	items, err := getImagesOrder(testImagesConfigs(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res []testImageOrderItem
+	for _, item := range items {
+		res = append(res, testImageOrderItem{Name: item.Name, IsArtifact: item.IsArtifact, Reasons: item.Reasons})
+	}
+
+	return res
+}
+
+func TestImagesOrder(t *testing.T) {
+	expected := []testImageOrderItem{
+		{Name: "base", Reasons: []string{"base image of 'worker'", "base image of 'app'", "defined in werf.yaml"}},
+		{Name: "assets", IsArtifact: true, Reasons: []string{"imported into 'app'"}},
+		{Name: "app", Reasons: []string{"dependsOn of 'worker'", "defined in werf.yaml"}},
+		{Name: "migrations", Reasons: []string{"dependsOn of 'worker'", "defined in werf.yaml"}},
+		{Name: "worker", Reasons: []string{"defined in werf.yaml"}},
+	}
+
+	if got := testImagesOrder(t, newTestConveyor(nil, nil)); !reflect.DeepEqual(expected, got) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, got)
+	}
+}
+
+func TestImagesOrder_imagesOrder(t *testing.T) {
+	expected := []testImageOrderItem{
+		{Name: "migrations", Reasons: []string{"position 1 in images order", "dependsOn of 'worker'"}},
+		{Name: "base", Reasons: []string{"base image of 'app'", "base image of 'worker'"}},
+		{Name: "assets", IsArtifact: true, Reasons: []string{"imported into 'app'"}},
+		{Name: "app", Reasons: []string{"position 2 in images order", "dependsOn of 'worker'"}},
+		{Name: "worker", Reasons: []string{"requested"}},
+	}
+
+	c := newTestConveyor([]string{"migrations", "app", "migrations"}, []string{"worker", "app", "migrations"})
+
+	if got := testImagesOrder(t, c); !reflect.DeepEqual(expected, got) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, got)
+	}
+}
+
+func TestImagesOrder_notDefinedImage(t *testing.T) {
+	_, err := getImagesOrder(testImagesConfigs(), newTestConveyor([]string{"nonexistent"}, nil))
+
+	expected := "image 'nonexistent' specified in images order isn't defined in werf.yaml"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", expected, err)
+	}
+}
+
+func TestImagesOrder_dependenciesCycle(t *testing.T) {
+	a := &config.Image{ImageBase: &config.ImageBase{Name: "a", From: "alpine"}}
+	b := &config.Image{ImageBase: &config.ImageBase{Name: "b", From: "alpine", DependsOn: []config.ImageInterface{a}}}
+	a.DependsOn = []config.ImageInterface{b}
+
+	items, err := getImagesOrder([]*config.Image{a, b}, newTestConveyor(nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+
+	if expected := []string{"b", "a"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, names)
+	}
+}
@@ -0,0 +1,101 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/sign"
+	"github.com/flant/werf/pkg/util"
+)
+
+// IMAGES_REPORT_VERSION should be bumped when images report format is changed
+const IMAGES_REPORT_VERSION = "1"
+
+// ImagesReport describes images published by the build-and-publish command,
+// it is passed to the deploy stage of the pipeline or to external CD tools
+type ImagesReport struct {
+	Version string `json:"version"`
+	// Images are published images by werf.yaml image name, nameless image is reported with empty name
+	Images map[string]*ImagesReportImage `json:"images"`
+}
+
+type ImagesReportImage struct {
+	Repository string `json:"repository"`
+	// Reference is REPOSITORY@DIGEST of the first tag, tags of the image differ only by tag scheme labels
+	Reference string             `json:"reference"`
+	Tags      []*ImagesReportTag `json:"tags"`
+}
+
+type ImagesReportTag struct {
+	Tag       string `json:"tag"`
+	Digest    string `json:"digest"`
+	Reference string `json:"reference"`
+}
+
+// addImagesReportTag records the published tag of the image, tags of the platform images are recorded as manifest list tags
+func (c *Conveyor) addImagesReportTag(imageName, repository, tag string) {
+	if c.imagesReportTags == nil {
+		c.imagesReportTags = map[string]map[string][]string{}
+	}
+
+	if c.imagesReportTags[imageName] == nil {
+		c.imagesReportTags[imageName] = map[string][]string{}
+	}
+
+	if !util.IsStringsContainValue(c.imagesReportTags[imageName][repository], tag) {
+		c.imagesReportTags[imageName][repository] = append(c.imagesReportTags[imageName][repository], tag)
+	}
+}
+
+// GetImagesReport resolves digests of the images published by the last push
+func (c *Conveyor) GetImagesReport() (*ImagesReport, error) {
+	report := &ImagesReport{Version: IMAGES_REPORT_VERSION, Images: map[string]*ImagesReportImage{}}
+
+	for imageName, tagsByRepository := range c.imagesReportTags {
+		for repository, tags := range tagsByRepository {
+			sortedTags := append([]string{}, tags...)
+			sort.Strings(sortedTags)
+
+			reportImage := &ImagesReportImage{Repository: repository}
+
+			for _, tag := range sortedTags {
+				imageTagName := fmt.Sprintf("%s:%s", repository, tag)
+
+				digest, err := docker_registry.ManifestDigest(imageTagName)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get image %s digest: %s", imageTagName, err)
+				}
+
+				reportImage.Tags = append(reportImage.Tags, &ImagesReportTag{
+					Tag:       tag,
+					Digest:    digest,
+					Reference: sign.DigestReference(imageTagName, digest),
+				})
+			}
+
+			if len(reportImage.Tags) > 0 {
+				reportImage.Reference = reportImage.Tags[0].Reference
+			}
+
+			report.Images[imageName] = reportImage
+		}
+	}
+
+	return report, nil
+}
+
+func WriteImagesReport(path string, report *ImagesReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write images report file %s: %s", path, err)
+	}
+
+	return nil
+}
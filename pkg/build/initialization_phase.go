@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/flant/werf/pkg/build/stage"
@@ -32,17 +33,40 @@ func (p *InitializationPhase) Run(c *Conveyor) error {
 }
 
 func generateImagesInOrder(imageConfigs []*config.Image, c *Conveyor) ([]*Image, error) {
+	imagesOrder, err := getImagesOrder(imageConfigs, c)
+	if err != nil {
+		return nil, err
+	}
+
 	var images []*Image
-	for _, imageConfig := range getImageConfigsInOrder(imageConfigs, c) {
+	initializedImages := map[string]bool{}
+	for _, item := range imagesOrder {
+		imageConfig := item.config
+
+		if image := c.getImageToReuse(imageConfig, initializedImages); image != nil {
+			images = append(images, image)
+			continue
+		}
+
 		image := &Image{}
 
 		imageBaseConfig, imageName, imageArtifact := processImageConfig(imageConfig)
-		from, fromImageName := getFromAndFromImageName(imageBaseConfig)
+		from, fromImageName, err := getFromAndFromImageName(imageBaseConfig, c)
+		if err != nil {
+			return nil, err
+		}
 
 		image.name = imageName
 		image.baseImageName = from
 		image.baseImageImageName = fromImageName
 		image.isArtifact = imageArtifact
+		image.cacheVersion = imageBaseConfig.CacheVersion
+		image.stagesCacheVersion = imageBaseConfig.StagesCacheVersion
+		image.stagesTimeout = imageBaseConfig.StagesTimeout
+		image.stagesRetries = imageBaseConfig.StagesRetries
+
+		initializedImages[imageName] = true
+		delete(c.buildingGitStageNameByImageName, imageName)
 
 		stages, err := generateStages(imageConfig, c)
 		if err != nil {
@@ -57,12 +81,28 @@ func generateImagesInOrder(imageConfigs []*config.Image, c *Conveyor) ([]*Image,
 	return images, nil
 }
 
-func getFromAndFromImageName(imageBaseConfig *config.ImageBase) (string, string) {
+func getFromAndFromImageName(imageBaseConfig *config.ImageBase, c *Conveyor) (string, string, error) {
 	var from string
 	var fromImageName string
 
 	if imageBaseConfig.From != "" {
 		from = imageBaseConfig.From
+	} else if len(imageBaseConfig.FromPlatforms) != 0 {
+		platform, err := c.targetPlatform()
+		if err != nil {
+			return "", "", err
+		}
+
+		from = getPlatformFrom(imageBaseConfig.FromPlatforms, platform)
+		if from == "" {
+			var platforms []string
+			for p := range imageBaseConfig.FromPlatforms {
+				platforms = append(platforms, p)
+			}
+			sort.Strings(platforms)
+
+			return "", "", fmt.Errorf("image %s has no base image for platform %s: from is specified only for %s", imageOrderItemName(imageBaseConfig.Name), platform, strings.Join(platforms, ", "))
+		}
 	} else {
 		fromImage := imageBaseConfig.FromImage
 		fromImageArtifact := imageBaseConfig.FromImageArtifact
@@ -74,21 +114,48 @@ func getFromAndFromImageName(imageBaseConfig *config.ImageBase) (string, string)
 		}
 	}
 
-	return from, fromImageName
+	return from, fromImageName, nil
 }
 
-func getImageConfigsInOrder(imageConfigs []*config.Image, c *Conveyor) []config.ImageInterface {
-	var images []config.ImageInterface
-	for _, image := range getImageConfigToProcess(imageConfigs, c) {
-		imagesInBuildOrder := image.ImageTree()
-		for i := 0; i < len(imagesInBuildOrder); i++ {
-			if isNotInArr(images, imagesInBuildOrder[i]) {
-				images = append(images, imagesInBuildOrder[i])
-			}
+// getPlatformFrom returns base image of the platform exactly or, if there is no such image, base image of the same os/arch
+func getPlatformFrom(fromPlatforms map[string]string, platform string) string {
+	if from, ok := fromPlatforms[platform]; ok {
+		return from
+	}
+
+	var platforms []string
+	for p := range fromPlatforms {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+
+	for _, p := range platforms {
+		if isPlatformMatched(p, platform) {
+			return fromPlatforms[p]
 		}
 	}
 
-	return images
+	return ""
+}
+
+// isPlatformMatched compares platforms os/arch and variants, variant is ignored if it is not specified in any of platforms
+func isPlatformMatched(platform, targetPlatform string) bool {
+	parts := strings.Split(platform, "/")
+	targetParts := strings.Split(targetPlatform, "/")
+
+	if len(parts) < 2 || len(targetParts) < 2 {
+		return platform == targetPlatform
+	}
+
+	if parts[0] != targetParts[0] || parts[1] != targetParts[1] {
+		return false
+	}
+
+	if len(parts) > 2 && len(targetParts) > 2 {
+		return parts[2] == targetParts[2]
+	}
+
+	return true
 }
 
 func getImageConfigToProcess(imageConfigs []*config.Image, c *Conveyor) []*config.Image {
@@ -99,7 +166,9 @@ func getImageConfigToProcess(imageConfigs []*config.Image, c *Conveyor) []*confi
 	} else {
 		for _, imageName := range c.imageNamesToProcess {
 			imageToProcess := getImageConfigByName(imageConfigs, imageName)
-			if imageToProcess == nil {
+			if imageToProcess == nil && isImageArtifactName(imageConfigs, imageName) {
+				logger.LogWarningF("WARNING: Specified image '%s' is an artifact: artifacts are built only as dependencies of images and are never tagged or pushed!\n", imageName)
+			} else if imageToProcess == nil {
 				logger.LogWarningF("WARNING: Specified image '%s' isn't defined in werf.yaml!\n", imageName)
 			} else {
 				imageConfigsToProcess = append(imageConfigsToProcess, imageToProcess)
@@ -110,24 +179,26 @@ func getImageConfigToProcess(imageConfigs []*config.Image, c *Conveyor) []*confi
 	return imageConfigsToProcess
 }
 
-func getImageConfigByName(imageConfigs []*config.Image, name string) *config.Image {
+func isImageArtifactName(imageConfigs []*config.Image, name string) bool {
 	for _, image := range imageConfigs {
-		if image.Name == name {
-			return image
+		for _, imageInterface := range image.ImageTree() {
+			if artifact, ok := imageInterface.(*config.ImageArtifact); ok && artifact.Name == name {
+				return true
+			}
 		}
 	}
 
-	return nil
+	return false
 }
 
-func isNotInArr(arr []config.ImageInterface, obj config.ImageInterface) bool {
-	for _, elm := range arr {
-		if reflect.DeepEqual(elm, obj) {
-			return false
+func getImageConfigByName(imageConfigs []*config.Image, name string) *config.Image {
+	for _, image := range imageConfigs {
+		if image.Name == name {
+			return image
 		}
 	}
 
-	return true
+	return nil
 }
 
 func generateStages(imageConfig config.ImageInterface, c *Conveyor) ([]stage.Interface, error) {
@@ -138,6 +209,7 @@ func generateStages(imageConfig config.ImageInterface, c *Conveyor) ([]stage.Int
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:        imageName,
 		ConfigMounts:     imageBaseConfig.Mount,
+		ConfigDownloads:  imageBaseConfig.Download,
 		ImageTmpDir:      c.GetImageTmpDir(imageBaseConfig.Name),
 		ContainerWerfDir: c.containerWerfDir,
 		ProjectBuildDir:  c.projectBuildDir,
@@ -149,8 +221,10 @@ func generateStages(imageConfig config.ImageInterface, c *Conveyor) ([]stage.Int
 	}
 
 	gitPatchStageOptions := &stage.NewGitPatchStageOptions{
-		PatchesDir:          getImagePatchesDir(imageName, c),
-		ContainerPatchesDir: getImagePatchesContainerDir(c),
+		PatchesDir:           getImagePatchesDir(imageName, c),
+		ContainerPatchesDir:  getImagePatchesContainerDir(c),
+		ArchivesDir:          getImageArchivesDir(imageName, c),
+		ContainerArchivesDir: getImageArchivesContainerDir(c),
 	}
 
 	gitPaths, err := generateGitPaths(imageBaseConfig, c)
@@ -208,6 +282,16 @@ func generateStages(imageConfig config.ImageInterface, c *Conveyor) ([]stage.Int
 		stages = appendIfExist(stages, stage.GenerateDockerInstructionsStage(imageConfig.(*config.Image), baseStageOptions))
 	}
 
+	// check
+	stages = appendIfExist(stages, stage.GenerateCheckStage(imageBaseConfig, baseStageOptions))
+
+	if len(imageBaseConfig.StagesPlatforms) != 0 {
+		stages, err = skipStagesByPlatform(stages, imageBaseConfig, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for _, s := range stages {
 		s.SetGitPaths(gitPaths)
 	}
@@ -215,48 +299,105 @@ func generateStages(imageConfig config.ImageInterface, c *Conveyor) ([]stage.Int
 	return stages, nil
 }
 
+// skipStagesByPlatform removes stages, which are not built for the target platform according to stagesPlatforms directive
+func skipStagesByPlatform(stages []stage.Interface, imageBaseConfig *config.ImageBase, c *Conveyor) ([]stage.Interface, error) {
+	platform, err := c.targetPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []stage.Interface
+	for _, s := range stages {
+		platforms, ok := imageBaseConfig.StagesPlatforms[string(s.Name())]
+		if !ok {
+			res = append(res, s)
+			continue
+		}
+
+		isMatched := false
+		for _, p := range platforms {
+			if isPlatformMatched(p, platform) {
+				isMatched = true
+				break
+			}
+		}
+
+		if isMatched {
+			res = append(res, s)
+		} else {
+			logger.LogInfoF("# Stage %s of image %s is skipped for platform %s\n", s.Name(), imageOrderItemName(imageBaseConfig.Name), platform)
+		}
+	}
+
+	return res, nil
+}
+
 func generateGitPaths(imageBaseConfig *config.ImageBase, c *Conveyor) ([]*stage.GitPath, error) {
 	var gitPaths, nonEmptyGitPaths []*stage.GitPath
 
-	var localGitRepo *git_repo.Local
+	var localGitRepo git_repo.GitRepo
 	if len(imageBaseConfig.Git.Local) != 0 {
-		localGitRepo = &git_repo.Local{
-			Base:   git_repo.Base{Name: "own"},
-			Path:   c.projectDir,
-			GitDir: path.Join(c.projectDir, ".git"),
+		if c.ownGitRepo != nil {
+			localGitRepo = c.ownGitRepo
+		} else {
+			localGitRepo = &git_repo.Local{
+				Base:   git_repo.Base{Name: "own"},
+				Path:   c.projectDir,
+				GitDir: path.Join(c.projectDir, ".git"),
+			}
 		}
 	}
 
 	for _, localGitPathConfig := range imageBaseConfig.Git.Local {
-		gitPaths = append(gitPaths, gitLocalPathInit(localGitPathConfig, localGitRepo, imageBaseConfig.Name, c))
+		gitPath, err := gitLocalPathInit(localGitPathConfig, localGitRepo, imageBaseConfig.Name, c)
+		if err != nil {
+			return nil, err
+		}
+
+		gitPaths = append(gitPaths, gitPath)
 	}
 
 	for _, remoteGitPathConfig := range imageBaseConfig.Git.Remote {
 		remoteGitRepo, exist := c.remoteGitRepos[remoteGitPathConfig.Name]
 		if !exist {
-			clonePath, err := getRemoteGitRepoClonePath(remoteGitPathConfig, c)
+			// narrow mapping does not require all objects of the repo
+			partialClone := len(remoteGitPathConfig.IncludePaths) > 0 || strings.Trim(remoteGitPathConfig.Add, "/") != ""
+
+			clonePath, err := git_repo.GetClonePath(remoteGitPathConfig.Url, partialClone)
+			if err != nil {
+				return nil, err
+			}
+
+			legacyClonePath, err := getLegacyRemoteGitRepoClonePath(remoteGitPathConfig, c)
 			if err != nil {
 				return nil, err
 			}
 
 			remoteGitRepo = &git_repo.Remote{
-				Base:      git_repo.Base{Name: remoteGitPathConfig.Name},
-				Url:       remoteGitPathConfig.Url,
-				ClonePath: clonePath,
+				Base:            git_repo.Base{Name: remoteGitPathConfig.Name},
+				Url:             remoteGitPathConfig.Url,
+				ClonePath:       clonePath,
+				LegacyClonePath: legacyClonePath,
+				PartialClone:    partialClone,
 			}
 
-			if err := remoteGitRepo.CloneAndFetch(); err != nil {
+			if err := remoteGitRepo.CloneAndFetch(c.GetContext()); err != nil {
 				return nil, err
 			}
 
 			c.remoteGitRepos[remoteGitPathConfig.Name] = remoteGitRepo
 		}
 
-		gitPaths = append(gitPaths, gitRemoteArtifactInit(remoteGitPathConfig, remoteGitRepo, imageBaseConfig.Name, c))
+		gitPath, err := gitRemoteArtifactInit(remoteGitPathConfig, remoteGitRepo, imageBaseConfig.Name, c)
+		if err != nil {
+			return nil, err
+		}
+
+		gitPaths = append(gitPaths, gitPath)
 	}
 
 	for _, gitPath := range gitPaths {
-		if empty, err := gitPath.IsEmpty(); err != nil {
+		if empty, err := gitPath.IsEmpty(c.GetContext()); err != nil {
 			return nil, err
 		} else if !empty {
 			nonEmptyGitPaths = append(nonEmptyGitPaths, gitPath)
@@ -266,7 +407,8 @@ func generateGitPaths(imageBaseConfig *config.ImageBase, c *Conveyor) ([]*stage.
 	return nonEmptyGitPaths, nil
 }
 
-func getRemoteGitRepoClonePath(remoteGitPathConfig *config.GitRemote, c *Conveyor) (string, error) {
+// getLegacyRemoteGitRepoClonePath returns path of the per-project clone of older werf versions
+func getLegacyRemoteGitRepoClonePath(remoteGitPathConfig *config.GitRemote, c *Conveyor) (string, error) {
 	scheme, err := urlScheme(remoteGitPathConfig.Url)
 	if err != nil {
 		return "", err
@@ -299,36 +441,58 @@ func urlScheme(urlString string) (string, error) {
 	return u.Scheme, nil
 }
 
-func gitRemoteArtifactInit(remoteGitPathConfig *config.GitRemote, remoteGitRepo *git_repo.Remote, imageName string, c *Conveyor) *stage.GitPath {
+func gitRemoteArtifactInit(remoteGitPathConfig *config.GitRemote, remoteGitRepo *git_repo.Remote, imageName string, c *Conveyor) (*stage.GitPath, error) {
 	gitPath := baseGitPathInit(remoteGitPathConfig.GitLocalExport, imageName, c)
 
 	gitPath.Tag = remoteGitPathConfig.Tag
-	gitPath.Commit = remoteGitPathConfig.Commit
 	gitPath.Branch = remoteGitPathConfig.Branch
 
 	gitPath.Name = remoteGitPathConfig.Name
 
-	gitPath.GitRepoInterface = remoteGitRepo
+	gitPath.GitRepoInterface = c.getCachedGitRepo(remoteGitRepo)
 
-	return gitPath
+	if remoteGitPathConfig.Commit != "" {
+		commit, err := remoteGitRepo.ResolveCommit(c.GetContext(), remoteGitPathConfig.Commit)
+		if err != nil {
+			return nil, err
+		}
+
+		gitPath.Commit = commit
+	}
+
+	return gitPath, nil
 }
 
-func gitLocalPathInit(localGitPathConfig *config.GitLocal, localGitRepo *git_repo.Local, imageName string, c *Conveyor) *stage.GitPath {
+func gitLocalPathInit(localGitPathConfig *config.GitLocal, localGitRepo git_repo.GitRepo, imageName string, c *Conveyor) (*stage.GitPath, error) {
 	gitPath := baseGitPathInit(localGitPathConfig.GitLocalExport, imageName, c)
 
 	gitPath.As = localGitPathConfig.As
 
+	if c.ownGitRepo != nil {
+		gitPath.Commit = c.ownGitCommit
+	}
+
 	gitPath.Name = "own"
 
-	gitPath.GitRepoInterface = localGitRepo
+	gitPath.GitRepoInterface = c.getCachedGitRepo(localGitRepo)
 
-	return gitPath
+	if repo, ok := localGitRepo.(*git_repo.Local); ok && !gitPath.SkipSubmodules {
+		if err := c.applyDivergedSubmodules(repo, gitPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return gitPath, nil
 }
 
 func baseGitPathInit(local *config.GitLocalExport, imageName string, c *Conveyor) *stage.GitPath {
 	var stageDependencies map[stage.StageName][]string
+	var stageDependenciesOptions map[stage.StageName]map[string]git_repo.ChecksumPathOptions
+	var stageDependenciesAnyChanges map[stage.StageName][]string
 	if local.StageDependencies != nil {
 		stageDependencies = stageDependenciesToMap(local.StageDependencies)
+		stageDependenciesOptions = stageDependenciesOptionsToMap(local.StageDependencies)
+		stageDependenciesAnyChanges = stageDependenciesAnyChangesToMap(local.StageDependencies)
 	}
 
 	gitPath := &stage.GitPath{
@@ -346,13 +510,34 @@ func baseGitPathInit(local *config.GitLocalExport, imageName string, c *Conveyor
 		Owner:              local.Owner,
 		Group:              local.Group,
 		StagesDependencies: stageDependencies,
+		DetectRenames:      local.DetectRenames,
+		DetectCopies:       local.DetectCopies,
+		ArchiveBaseBranch:  local.ArchiveBaseBranch,
+		FileMode:           local.FileMode,
+		DirMode:            local.DirMode,
+
+		DeterministicArchives: c.werfConfig.Meta.Build.DeterministicArchives,
+
+		StagesDependenciesOptions:    stageDependenciesOptions,
+		StagesDependenciesAnyChanges: stageDependenciesAnyChanges,
+	}
+
+	if local.PatchLimits != nil {
+		gitPath.PatchMaxSize = local.PatchLimits.MaxSize
+		gitPath.PatchMaxFiles = local.PatchLimits.MaxFiles
+	}
+
+	if local.Submodules != nil {
+		gitPath.SkipSubmodules = local.Submodules.Skip
+		gitPath.IncludeSubmodules = local.Submodules.Include
+		gitPath.SubmodulesRevisions = local.Submodules.Revisions
 	}
 
 	return gitPath
 }
 
 func getImagePatchesDir(imageName string, c *Conveyor) string {
-	return path.Join(c.tmpDir, imageName, "patch")
+	return path.Join(c.GetImageTmpDir(imageName), "patch")
 }
 
 func getImagePatchesContainerDir(c *Conveyor) string {
@@ -360,7 +545,7 @@ func getImagePatchesContainerDir(c *Conveyor) string {
 }
 
 func getImageArchivesDir(imageName string, c *Conveyor) string {
-	return path.Join(c.tmpDir, imageName, "archive")
+	return path.Join(c.GetImageTmpDir(imageName), "archive")
 }
 
 func getImageArchivesContainerDir(c *Conveyor) string {
@@ -369,9 +554,47 @@ func getImageArchivesContainerDir(c *Conveyor) string {
 
 func stageDependenciesToMap(sd *config.StageDependencies) map[stage.StageName][]string {
 	result := map[stage.StageName][]string{
-		stage.Install:     sd.Install,
-		stage.BeforeSetup: sd.BeforeSetup,
-		stage.Setup:       sd.Setup,
+		stage.BeforeInstall: sd.BeforeInstall,
+		stage.Install:       sd.Install,
+		stage.BeforeSetup:   sd.BeforeSetup,
+		stage.Setup:         sd.Setup,
+		stage.Check:         sd.Check,
+	}
+
+	return result
+}
+
+// stageDependenciesStageNames maps stageDependencies config keys to the stage names
+var stageDependenciesStageNames = map[string]stage.StageName{
+	"beforeInstall": stage.BeforeInstall,
+	"install":       stage.Install,
+	"beforeSetup":   stage.BeforeSetup,
+	"setup":         stage.Setup,
+	"check":         stage.Check,
+}
+
+func stageDependenciesAnyChangesToMap(sd *config.StageDependencies) map[stage.StageName][]string {
+	result := map[stage.StageName][]string{}
+	for configStageName, anyChanges := range sd.AnyChanges {
+		result[stageDependenciesStageNames[configStageName]] = anyChanges.ExcludeOnly
+	}
+
+	return result
+}
+
+func stageDependenciesOptionsToMap(sd *config.StageDependencies) map[stage.StageName]map[string]git_repo.ChecksumPathOptions {
+	result := map[stage.StageName]map[string]git_repo.ChecksumPathOptions{}
+	for configStageName, pathsOptions := range sd.PathsOptions {
+		name := stageDependenciesStageNames[configStageName]
+		result[name] = map[string]git_repo.ChecksumPathOptions{}
+
+		for path, options := range pathsOptions {
+			result[name][path] = git_repo.ChecksumPathOptions{
+				DereferenceSymlinks: options.DereferenceSymlinks,
+				Xattrs:              options.Xattrs,
+				IgnoreMode:          options.IgnoreMode,
+			}
+		}
 	}
 
 	return result
@@ -0,0 +1,59 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flant/werf/pkg/docker"
+)
+
+// platformTagSuffix converts platform os/arch[/variant] into the docker tag compatible form os-arch[-variant]
+func platformTagSuffix(platform string) string {
+	return strings.Replace(platform, "/", "-", -1)
+}
+
+func (c *Conveyor) addManifestListImage(listName, imageName string) {
+	if c.manifestListsToPublish == nil {
+		return
+	}
+
+	c.manifestListsToPublish[listName] = append(c.manifestListsToPublish[listName], imageName)
+}
+
+func (c *Conveyor) publishManifestLists(repo string) error {
+	var listNames []string
+	for listName := range c.manifestListsToPublish {
+		listNames = append(listNames, listName)
+	}
+	sort.Strings(listNames)
+
+	if len(listNames) == 0 {
+		return nil
+	}
+
+	if err := c.GetDockerAuthorizer().LoginForPush(repo); err != nil {
+		return fmt.Errorf("login into '%s' for push failed: %s", repo, err)
+	}
+
+	for _, listName := range listNames {
+		if err := c.GetContext().Err(); err != nil {
+			return err
+		}
+
+		c.emitEvent(Event{Type: ManifestListPushStartedEvent, DockerImageName: listName})
+
+		createArgs := append([]string{"--amend", listName}, c.manifestListsToPublish[listName]...)
+		if err := docker.CliManifestCreate(createArgs...); err != nil {
+			return fmt.Errorf("unable to create manifest list %s: %s", listName, err)
+		}
+
+		if err := docker.CliManifestPush(c.GetContext(), "--purge", listName); err != nil {
+			return fmt.Errorf("unable to push manifest list %s: %s", listName, err)
+		}
+
+		c.emitEvent(Event{Type: ManifestListPushFinishedEvent, DockerImageName: listName})
+	}
+
+	return nil
+}
@@ -13,13 +13,38 @@ func NewPrepareImagesPhase() *PrepareImagesPhase {
 
 type PrepareImagesPhase struct{}
 
-const WerfCacheVersionLabel = "werf-cache-version"
+const (
+	WerfCacheVersionLabel = "werf-cache-version"
+
+	// Provenance labels of stages images, which allow to find owners of the stages on the host
+	WerfProjectLabel         = "werf"
+	WerfVersionLabel         = "werf-version"
+	WerfStagesNamespaceLabel = "werf-stages-namespace"
+	WerfImageNameLabel       = "werf-image-name"
+	WerfStageNameLabel       = "werf-stage-name"
+	WerfGitCommitLabel       = "werf-git-commit"
+
+	// Commit metadata labels of the tagged and pushed images
+	WerfGitCommitAuthorLabel  = "werf-git-commit-author"
+	WerfGitCommitDateLabel    = "werf-git-commit-date"
+	WerfGitCommitSubjectLabel = "werf-git-commit-subject"
+)
 
 func (p *PrepareImagesPhase) Run(c *Conveyor) error {
 	if debug() {
 		fmt.Printf("PrepareImagesPhase.Run\n")
 	}
 
+	secretsVolumes, err := c.buildSecretsVolumes()
+	if err != nil {
+		return err
+	}
+
+	gitCommit, err := c.projectGitCommit()
+	if err != nil {
+		return fmt.Errorf("cannot get project git commit: %s", err)
+	}
+
 	for _, image := range c.imagesInOrder {
 		if debug() {
 			fmt.Printf("  image: '%s'\n", image.GetName())
@@ -40,7 +65,7 @@ func (p *PrepareImagesPhase) Run(c *Conveyor) error {
 
 			stageImage := s.GetImage()
 
-			if c.GetImageBySignature(s.GetSignature()) != nil || stageImage.IsExists() {
+			if c.GetImageBySignature(s.GetSignature()) != nil || stageImage.IsExists() || image.isCoveredByCacheFrom(s) {
 				prevImage = stageImage
 				continue
 			}
@@ -51,12 +76,25 @@ func (p *PrepareImagesPhase) Run(c *Conveyor) error {
 
 			imageServiceCommitChangeOptions := stageImage.Container().ServiceCommitChangeOptions()
 			imageServiceCommitChangeOptions.AddLabel(map[string]string{
-				"werf":                c.projectName(),
-				"werf-version":        werf.Version,
-				WerfCacheVersionLabel: BuildCacheVersion,
-				"werf-image":          "false",
-				"werf-dev-mode":       "false",
+				WerfProjectLabel:              c.projectName(),
+				WerfVersionLabel:              werf.Version,
+				WerfMinCompatibleVersionLabel: MinCompatibleWerfVersion,
+				WerfCacheVersionLabel:         BuildCacheVersion,
+				WerfStagesNamespaceLabel:      c.stagesNamespace(),
+				WerfImageNameLabel:            image.GetName(),
+				WerfStageNameLabel:            string(s.Name()),
+				"werf-image":                  "false",
+				"werf-dev-mode":               "false",
 			})
+			if gitCommit != "" {
+				imageServiceCommitChangeOptions.AddLabel(map[string]string{WerfGitCommitLabel: gitCommit})
+			}
+			if image.baseImageDigest != "" {
+				imageServiceCommitChangeOptions.AddLabel(map[string]string{
+					WerfBaseImageLabel:       image.baseImageName,
+					WerfBaseImageDigestLabel: image.baseImageDigest,
+				})
+			}
 
 			if c.sshAuthSock != "" {
 				imageRunOptions := stageImage.Container().RunOptions()
@@ -64,6 +102,12 @@ func (p *PrepareImagesPhase) Run(c *Conveyor) error {
 				imageRunOptions.AddEnv(map[string]string{"SSH_AUTH_SOCK": "/tmp/werf-ssh-agent"})
 			}
 
+			if len(secretsVolumes) > 0 {
+				imageRunOptions := stageImage.Container().RunOptions()
+				imageRunOptions.AddTmpfs(BuildSecretsContainerDir)
+				imageRunOptions.AddVolume(secretsVolumes...)
+			}
+
 			err := s.PrepareImage(c, prevBuiltImage, stageImage)
 			if err != nil {
 				return fmt.Errorf("error preparing stage %s: %s", s.Name(), err)
@@ -71,11 +115,7 @@ func (p *PrepareImagesPhase) Run(c *Conveyor) error {
 
 			c.SetImageBySignature(s.GetSignature(), stageImage)
 
-			if image.GetName() == "" {
-				fmt.Printf("# Prepared for build image %s for image %s\n", stageImage.Name(), fmt.Sprintf("stage/%s", s.Name()))
-			} else {
-				fmt.Printf("# Prepared for build image %s for image/%s %s\n", stageImage.Name(), image.GetName(), fmt.Sprintf("stage/%s", s.Name()))
-			}
+			c.emitEvent(Event{Type: StagePreparedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: stageImage.Name()})
 
 			prevImage = stageImage
 		}
@@ -6,6 +6,7 @@ import (
 	"github.com/flant/werf/pkg/docker_registry"
 	imagePkg "github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/slug"
 	"github.com/flant/werf/pkg/util"
 )
 
@@ -26,10 +27,13 @@ const (
 	GitBranchScheme TagScheme = "git_branch"
 	GitCommitScheme TagScheme = "git_commit"
 	CIScheme        TagScheme = "ci"
-
-	RepoImageStageTagFormat = "image-stage-%s"
 )
 
+// RepoImageStageTag returns tag of the stage image in the docker repo, prefix is defined by slug policy
+func RepoImageStageTag(signature string) string {
+	return fmt.Sprintf("%s-%s", slug.StageImagePrefix(), signature)
+}
+
 type TagScheme string
 
 type PushPhase struct {
@@ -50,11 +54,7 @@ func (p *PushPhase) Run(c *Conveyor) error {
 
 	for _, image := range c.imagesInOrder {
 		if p.WithStages {
-			if image.GetName() == "" {
-				fmt.Printf("# Pushing image stages cache\n")
-			} else {
-				fmt.Printf("# Pushing image/%s stages cache\n", image.GetName())
-			}
+			c.emitEvent(Event{Type: ImageStagesPushStartedEvent, ImageName: image.GetName()})
 
 			err := p.pushImageStages(c, image)
 			if err != nil {
@@ -63,11 +63,7 @@ func (p *PushPhase) Run(c *Conveyor) error {
 		}
 
 		if !image.isArtifact {
-			if image.GetName() == "" {
-				fmt.Printf("# Pushing image\n")
-			} else {
-				fmt.Printf("# Pushing image/%s\n", image.GetName())
-			}
+			c.emitEvent(Event{Type: ImagePushStartedEvent, ImageName: image.GetName()})
 
 			err := p.pushImage(c, image)
 			if err != nil {
@@ -79,24 +75,34 @@ func (p *PushPhase) Run(c *Conveyor) error {
 	return nil
 }
 
+// ensureRepo creates the repository before push if auto creation is enabled
+func (p *PushPhase) ensureRepo(c *Conveyor, repository string) error {
+	if c.autoCreateRepo == nil {
+		return nil
+	}
+
+	return docker_registry.EnsureRepo(repository, *c.autoCreateRepo)
+}
+
 func (p *PushPhase) pushImageStages(c *Conveyor, image *Image) error {
 	stages := image.GetStages()
 
+	if err := p.ensureRepo(c, p.Repo); err != nil {
+		return err
+	}
+
 	existingStagesTags, err := docker_registry.ImageStagesTags(p.Repo)
 	if err != nil {
 		return fmt.Errorf("error fetching existing stages cache list %s: %s", p.Repo, err)
 	}
 
 	for _, stage := range stages {
-		stageTagName := fmt.Sprintf(RepoImageStageTagFormat, stage.GetSignature())
+		stageTagName := RepoImageStageTag(stage.GetSignature())
 		stageImageName := fmt.Sprintf("%s:%s", p.Repo, stageTagName)
 
-		if util.IsStringsContainValue(existingStagesTags, stageTagName) {
-			if image.GetName() == "" {
-				fmt.Printf("# Ignore existing in repo image %s for image stage/%s\n", stageImageName, stage.Name())
-			} else {
-				fmt.Printf("# Ignore existing in repo image %s for image/%s stage/%s\n", stageImageName, image.GetName(), stage.Name())
-			}
+		// stages preceding the stage imported from the previously published image (--cache-from-tag) are neither built nor pulled
+		if util.IsStringsContainValue(existingStagesTags, stageTagName) || image.isCoveredByCacheFrom(stage) {
+			c.emitEvent(Event{Type: StagePushSkippedEvent, ImageName: image.GetName(), StageName: stage.Name(), Signature: stage.GetSignature(), DockerImageName: stageImageName})
 
 			continue
 		}
@@ -111,19 +117,19 @@ func (p *PushPhase) pushImageStages(c *Conveyor, image *Image) error {
 			}
 			defer lock.Unlock(imageLockName)
 
-			if image.GetName() == "" {
-				fmt.Printf("# Pushing image %s for image stage/%s\n", stageImageName, stage.Name())
-			} else {
-				fmt.Printf("# Pushing image %s for image/%s stage/%s\n", stageImageName, image.GetName(), stage.Name())
-			}
+			c.emitEvent(Event{Type: StagePushStartedEvent, ImageName: image.GetName(), StageName: stage.Name(), Signature: stage.GetSignature(), DockerImageName: stageImageName})
 
 			stageImage := c.GetStageImage(stage.GetImage().Name())
 
-			err = stageImage.Export(stageImageName)
+			err = lock.WithHostSlot(lock.PushResource, c.projectName(), func() error {
+				return stageImage.Export(c.GetContext(), stageImageName)
+			})
 			if err != nil {
 				return fmt.Errorf("error pushing %s: %s", stageImageName, err)
 			}
 
+			c.emitEvent(Event{Type: StagePushFinishedEvent, ImageName: image.GetName(), StageName: stage.Name(), Signature: stage.GetSignature(), DockerImageName: stageImageName})
+
 			return nil
 		}()
 
@@ -143,17 +149,27 @@ func (p *PushPhase) pushImage(c *Conveyor, image *Image) error {
 		imageRepository = p.Repo
 	}
 
+	if err := p.ensureRepo(c, imageRepository); err != nil {
+		return err
+	}
+
 	existingTags, err := docker_registry.ImageTags(imageRepository)
 	if err != nil {
 		return fmt.Errorf("error fetch existing tags of image %s: %s", imageRepository, err)
 	}
 
-	stages := image.GetStages()
-	lastStageImage := stages[len(stages)-1].GetImage()
+	lastStageImage := image.LatestStage().GetImage()
 
 	for scheme, tags := range p.TagsByScheme {
 	ProcessingTags:
 		for _, tag := range tags {
+			c.addImagesReportTag(image.GetName(), imageRepository, tag)
+
+			if c.platform != "" {
+				c.addManifestListImage(fmt.Sprintf("%s:%s", imageRepository, tag), fmt.Sprintf("%s:%s-%s", imageRepository, tag, platformTagSuffix(c.platform)))
+				tag = fmt.Sprintf("%s-%s", tag, platformTagSuffix(c.platform))
+			}
+
 			imageImageName := fmt.Sprintf("%s:%s", imageRepository, tag)
 
 			if util.IsStringsContainValue(existingTags, tag) {
@@ -163,11 +179,8 @@ func (p *PushPhase) pushImage(c *Conveyor, image *Image) error {
 				}
 
 				if lastStageImage.ID() == parentID {
-					if image.GetName() == "" {
-						fmt.Printf("# Ignore existing in repo image %s for image\n", imageImageName)
-					} else {
-						fmt.Printf("# Ignore existing in repo image %s for image/%s\n", imageImageName, image.GetName())
-					}
+					c.emitEvent(Event{Type: TagPushSkippedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
+					c.addPublishedImage(imageImageName)
 					continue ProcessingTags
 				}
 			}
@@ -182,31 +195,35 @@ func (p *PushPhase) pushImage(c *Conveyor, image *Image) error {
 				}
 				defer lock.Unlock(imageLockName)
 
-				fmt.Printf("# Build %s layer with tag scheme '%s'\n", imageImageName, scheme)
+				c.emitEvent(Event{Type: TagBuildStartedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
 
 				pushImage := imagePkg.NewImage(c.GetStageImage(lastStageImage.Name()), imageImageName)
+				pushImage.SetPlatform(c.platform)
 
-				pushImage.Container().ServiceCommitChangeOptions().AddLabel(map[string]string{
-					"werf-tag-scheme": string(scheme),
-					"werf-image":      "true",
-				})
+				labels, err := c.finalImageServiceLabels(image, scheme)
+				if err != nil {
+					return err
+				}
+				pushImage.Container().ServiceCommitChangeOptions().AddLabel(c.userLabels())
+				pushImage.Container().ServiceCommitChangeOptions().AddLabel(labels)
 
 				err = pushImage.Build(imagePkg.BuildOptions{})
 				if err != nil {
 					return fmt.Errorf("error building %s with tag scheme '%s': %s", imageImageName, scheme, err)
 				}
 
-				if image.GetName() == "" {
-					fmt.Printf("# Pushing image %s for image\n", imageImageName)
-				} else {
-					fmt.Printf("# Pushing image %s for image/%s\n", imageImageName, image.GetName())
-				}
+				c.emitEvent(Event{Type: TagPushStartedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
 
-				err = pushImage.Export()
+				err = lock.WithHostSlot(lock.PushResource, c.projectName(), func() error {
+					return pushImage.Export(c.GetContext())
+				})
 				if err != nil {
 					return fmt.Errorf("error pushing %s: %s", imageImageName, err)
 				}
 
+				c.emitEvent(Event{Type: TagPushFinishedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
+				c.addPublishedImage(imageImageName)
+
 				return nil
 			}()
 
@@ -0,0 +1,52 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/util"
+)
+
+// rebuildStages forces building of the stage and all following stages of the processed images without cache version bump:
+// random cache bust is mixed into signatures of these stages, so existing stages cache is not used
+type rebuildStages struct {
+	fromStage stage.StageName
+	cacheBust string
+}
+
+// SetRebuildStagesFrom makes conveyor build specified stage and all following stages of the processed images again.
+// Stages of other images are rebuilt only if they depend on the rebuilt images.
+func (c *Conveyor) SetRebuildStagesFrom(stageName stage.StageName) {
+	c.rebuildStages = &rebuildStages{
+		fromStage: stageName,
+		cacheBust: util.GenerateConsistentRandomString(16),
+	}
+}
+
+// cacheBustChecksumArgs returns cache bust to be mixed into stage signature if the stage should be rebuilt
+func (c *Conveyor) cacheBustChecksumArgs(imageName string, stageName stage.StageName) []string {
+	if c.rebuildStages == nil {
+		return nil
+	}
+
+	if len(c.imageNamesToProcess) != 0 && !isImageNameInList(c.imageNamesToProcess, imageName) {
+		return nil
+	}
+
+	if stageIndex(stageName) < stageIndex(c.rebuildStages.fromStage) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("cache-bust:%s", c.rebuildStages.cacheBust)}
+}
+
+func stageIndex(stageName stage.StageName) int {
+	for ind, name := range config.StageNames {
+		if name == string(stageName) {
+			return ind
+		}
+	}
+
+	return -1
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
 )
 
 func NewRenewPhase() *RenewPhase {
@@ -43,7 +44,7 @@ func (p *RenewPhase) Run(c *Conveyor) error {
 				continue
 			}
 
-			imageLockName := fmt.Sprintf("%s.image.%s", c.projectName(), img.Name())
+			imageLockName := fmt.Sprintf("%s.image.%s", c.stagesNamespace(), img.Name())
 			err := lock.Lock(imageLockName, lock.LockOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to lock %s: %s", imageLockName, err)
@@ -58,16 +59,27 @@ func (p *RenewPhase) Run(c *Conveyor) error {
 		for _, s := range image.GetStages() {
 			img := s.GetImage()
 			if img.IsExists() {
+				if reason := stageWerfVersionIncompatibility(img.Labels()); reason != "" {
+					conveyorShouldBeReset = true
+					c.resetImages[image.GetName()] = true
+
+					logger.LogWarningF("WARNING: Stage %s of image '%s' (%s) is incompatible and will be rebuilt: %s\n", s.Name(), image.GetName(), img.Name(), reason)
+					c.emitEvent(Event{Type: StageResetEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
+
+					if err := img.Untag(); err != nil {
+						return err
+					}
+
+					continue
+				}
+
 				if stageShouldBeReset, err := s.ShouldBeReset(img); err != nil {
 					return err
 				} else if stageShouldBeReset {
 					conveyorShouldBeReset = true
+					c.resetImages[image.GetName()] = true
 
-					if image.GetName() == "" {
-						fmt.Printf("# Reseting image %s for image %s\n", img.Name(), fmt.Sprintf("stage/%s", s.Name()))
-					} else {
-						fmt.Printf("# Reseting image %s for image/%s %s\n", img.Name(), image.GetName(), fmt.Sprintf("stage/%s", s.Name()))
-					}
+					c.emitEvent(Event{Type: StageResetEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: img.Name()})
 
 					if err := img.Untag(); err != nil {
 						return err
@@ -0,0 +1,247 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	imagePkg "github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/werf"
+)
+
+const (
+	WerfBaseImageLabel       = "werf-base-image"
+	WerfBaseImageDigestLabel = "werf-base-image-digest"
+
+	BaseImagesLockFile = "werf-base-images.lock"
+)
+
+func NewResolveBaseImagesPhase(refresh bool) *ResolveBaseImagesPhase {
+	return &ResolveBaseImagesPhase{Refresh: refresh}
+}
+
+// ResolveBaseImagesPhase pins base images specified by tag to digests, so upstream changes of the tag
+// do not affect stages signatures until digests are refreshed.
+// Digests are saved in the base images lock file in the project dir, which is committed and shared by all hosts,
+// and resolved again only when Refresh is set or pinned digest is unknown.
+type ResolveBaseImagesPhase struct {
+	Refresh bool
+}
+
+func (p *ResolveBaseImagesPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("ResolveBaseImagesPhase.Run\n")
+	}
+
+	pins, err := readBaseImagesPins(c)
+	if err != nil {
+		return err
+	}
+
+	resolved := map[string]bool{}
+	isPinsChanged := false
+
+	for _, image := range c.imagesInOrder {
+		if image.baseImageImageName != "" {
+			continue
+		}
+
+		if ind := strings.Index(image.baseImageName, "@"); ind != -1 {
+			image.baseImageDigest = image.baseImageName[ind+1:]
+			continue
+		}
+
+		digest, ok := pins[image.baseImageName]
+		if !ok || (p.Refresh && !resolved[image.baseImageName]) {
+			digest, err = p.resolveDigest(c, image.GetName(), image.baseImageName, !ok)
+			if err != nil {
+				return fmt.Errorf("cannot resolve digest of base image %s of image %s: %s", image.baseImageName, image.GetName(), err)
+			}
+
+			if digest == "" {
+				logger.LogWarningF("WARNING: cannot resolve digest of base image %s: image has no repo digest, using it by name\n", image.baseImageName)
+			} else if pins[image.baseImageName] != digest {
+				pins[image.baseImageName] = digest
+				isPinsChanged = true
+			}
+
+			resolved[image.baseImageName] = true
+		}
+
+		image.baseImageDigest = digest
+
+		if digest != "" {
+			c.emitEvent(Event{Type: BaseImageResolvedEvent, ImageName: image.GetName(), DockerImageName: image.pinnedBaseImageName()})
+		}
+	}
+
+	if isPinsChanged {
+		if err := writeBaseImagesPins(c, pins); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveDigest returns digest of the existing local image or pulls the image to get actual digest from the registry.
+// Only local image is used in offline mode.
+func (p *ResolveBaseImagesPhase) resolveDigest(c *Conveyor, imageName, name string, useLocalImage bool) (string, error) {
+	img := imagePkg.NewStageImage(nil, name)
+	img.SetPlatform(c.platform)
+
+	if useLocalImage || werf.IsOffline() {
+		if err := img.SyncDockerState(); err != nil {
+			return "", err
+		}
+
+		if img.IsExists() {
+			if digest, err := repoDigest(img); err != nil || digest != "" || werf.IsOffline() {
+				return digest, err
+			}
+		}
+
+		if werf.IsOffline() {
+			return "", fmt.Errorf("image %s not found locally: %s", name, werf.OfflineError("pull"))
+		}
+	}
+
+	if err := loginForBaseImagePull(c, name); err != nil {
+		return "", err
+	}
+
+	c.emitEvent(Event{Type: BaseImagePullStartedEvent, ImageName: imageName, DockerImageName: name})
+
+	if err := img.Pull(c.GetContext()); err != nil {
+		if err := img.SyncDockerState(); err != nil {
+			return "", err
+		}
+
+		if !img.IsExists() {
+			return "", fmt.Errorf("image %s pull failed: %s", name, err)
+		}
+
+		logger.LogWarningF("WARNING: cannot pull base image %s: %s\n", name, err)
+		logger.LogWarningF("WARNING: using digest of existing image %s without pull\n", name)
+	}
+
+	return repoDigest(img)
+}
+
+// repoDigest returns digest of the image in the repo it has been pulled from or empty string for images without repo digest
+func repoDigest(img *imagePkg.StageImage) (string, error) {
+	inspect, err := img.MustGetInspect()
+	if err != nil {
+		return "", err
+	}
+
+	repository := imageRepository(img.Name())
+	for _, repoDigest := range inspect.RepoDigests {
+		parts := strings.SplitN(repoDigest, "@", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if parts[0] == repository || parts[0] == fmt.Sprintf("docker.io/%s", repository) || parts[0] == fmt.Sprintf("docker.io/library/%s", repository) {
+			return parts[1], nil
+		}
+	}
+
+	return "", nil
+}
+
+// imageRepository returns image name without tag and digest
+func imageRepository(name string) string {
+	if ind := strings.Index(name, "@"); ind != -1 {
+		name = name[:ind]
+	}
+
+	if ind := strings.LastIndex(name, ":"); ind != -1 && !strings.Contains(name[ind:], "/") {
+		name = name[:ind]
+	}
+
+	return name
+}
+
+// baseImagesPins is the content of the base images lock file: digests of base images by name and by platform
+type baseImagesPins struct {
+	Images    map[string]string            `json:"images,omitempty"`
+	Platforms map[string]map[string]string `json:"platforms,omitempty"`
+}
+
+func getBaseImagesPinsPath(c *Conveyor) string {
+	return filepath.Join(c.projectDir, BaseImagesLockFile)
+}
+
+func readBaseImagesPinsFile(path string) (*baseImagesPins, error) {
+	pinsFile := &baseImagesPins{}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return pinsFile, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, pinsFile); err != nil {
+		logger.LogWarningF("WARNING: bad base images lock file %s: %s\n", path, err)
+		return &baseImagesPins{}, nil
+	}
+
+	return pinsFile, nil
+}
+
+// readBaseImagesPins returns pinned digests of base images for the current platform
+func readBaseImagesPins(c *Conveyor) (map[string]string, error) {
+	pinsFile, err := readBaseImagesPinsFile(getBaseImagesPinsPath(c))
+	if err != nil {
+		return nil, err
+	}
+
+	pins := pinsFile.Images
+	if c.platform != "" {
+		pins = pinsFile.Platforms[c.platform]
+	}
+
+	if pins == nil {
+		pins = map[string]string{}
+	}
+
+	return pins, nil
+}
+
+// writeBaseImagesPins saves pinned digests of base images for the current platform, pins of other platforms are kept
+func writeBaseImagesPins(c *Conveyor, pins map[string]string) error {
+	path := getBaseImagesPinsPath(c)
+
+	pinsFile, err := readBaseImagesPinsFile(path)
+	if err != nil {
+		return err
+	}
+
+	if c.platform != "" {
+		if pinsFile.Platforms == nil {
+			pinsFile.Platforms = map[string]map[string]string{}
+		}
+		pinsFile.Platforms[c.platform] = pins
+	} else {
+		pinsFile.Images = pins
+	}
+
+	data, err := json.MarshalIndent(pinsFile, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write base images lock file %s: %s", path, err)
+	}
+
+	logger.LogInfoF("Base images digests are pinned in %s: commit the file to use the same digests on all hosts\n", path)
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/scan"
+)
+
+// ScanOptions enables vulnerability scan of the built images
+type ScanOptions struct {
+	scan.Options
+
+	// SeverityThreshold fails build if there are findings with the same or higher severity
+	SeverityThreshold string
+
+	// ReportPath is a json file to write findings of all scanned images to
+	ReportPath string
+}
+
+// ScanReport contains findings of the images scanned during the conveyor run
+type ScanReport struct {
+	Scanner           string             `json:"scanner"`
+	SeverityThreshold string             `json:"severityThreshold"`
+	Images            []*ImageScanReport `json:"images"`
+}
+
+type ImageScanReport struct {
+	ImageName       string          `json:"imageName"`
+	Platform        string          `json:"platform,omitempty"`
+	DockerImageName string          `json:"dockerImageName"`
+	DockerImageID   string          `json:"dockerImageId"`
+	Cached          bool            `json:"cached"`
+	Findings        []*scan.Finding `json:"findings"`
+}
+
+// SetScanOptions makes conveyor scan built images for vulnerabilities after build
+func (c *Conveyor) SetScanOptions(opts ScanOptions) {
+	c.scanOptions = &opts
+	c.scanReport = &ScanReport{Scanner: opts.Scanner, SeverityThreshold: strings.ToUpper(opts.SeverityThreshold)}
+}
+
+func NewScanPhase() *ScanPhase {
+	return &ScanPhase{}
+}
+
+type ScanPhase struct{}
+
+func (p *ScanPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("ScanPhase.Run\n")
+	}
+
+	var failedImages []string
+
+	for _, image := range c.imagesInOrder {
+		if image.isArtifact {
+			continue
+		}
+
+		img := image.LatestStage().GetImage()
+
+		logger.LogInfoF("# Scanning image %s with %s\n", imageOrderItemName(image.GetName()), c.scanOptions.Scanner)
+
+		findings, cached, err := scan.ScanImage(img.Name(), img.ID(), c.tmpDir, c.scanOptions.Options)
+		if err != nil {
+			return fmt.Errorf("unable to scan image %s: %s", imageOrderItemName(image.GetName()), err)
+		}
+
+		c.scanReport.Images = append(c.scanReport.Images, &ImageScanReport{
+			ImageName:       image.GetName(),
+			Platform:        c.platform,
+			DockerImageName: img.Name(),
+			DockerImageID:   img.ID(),
+			Cached:          cached,
+			Findings:        findings,
+		})
+
+		countBySeverity := map[string]int{}
+		for _, finding := range findings {
+			if scan.IsSeverityAtLeast(finding.Severity, c.scanOptions.SeverityThreshold) {
+				countBySeverity[finding.Severity]++
+			}
+		}
+
+		if len(countBySeverity) == 0 {
+			logger.LogInfoF("# Image %s: %d finding(s), none of %s severity or above\n", imageOrderItemName(image.GetName()), len(findings), c.scanReport.SeverityThreshold)
+			continue
+		}
+
+		var counts []string
+		for severity, count := range countBySeverity {
+			counts = append(counts, fmt.Sprintf("%s: %d", severity, count))
+		}
+		sort.Strings(counts)
+
+		failedImages = append(failedImages, fmt.Sprintf("%s (%s)", imageOrderItemName(image.GetName()), strings.Join(counts, ", ")))
+	}
+
+	if c.scanOptions.ReportPath != "" {
+		if err := writeScanReport(c.scanOptions.ReportPath, c.scanReport); err != nil {
+			return err
+		}
+	}
+
+	if len(failedImages) > 0 {
+		return fmt.Errorf("vulnerabilities of %s severity or above found in images: %s", c.scanReport.SeverityThreshold, strings.Join(failedImages, "; "))
+	}
+
+	return nil
+}
+
+func writeScanReport(path string, report *ScanReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write scan report %s: %s", path, err)
+	}
+
+	return nil
+}
@@ -37,9 +37,9 @@ func (p *ShouldBeBuiltPhase) Run(c *Conveyor) error {
 
 		for _, s := range badStages {
 			if image.GetName() != "" {
-				fmt.Fprintf(os.Stderr, "Image '%s' stage '%s' is not built\n", image.GetName(), s.Name())
+				fmt.Fprintf(os.Stderr, "Image '%s' stage '%s' is not built (signature %s)\n", image.GetName(), s.Name(), s.GetSignature())
 			} else {
-				fmt.Fprintf(os.Stderr, "Image stage '%s' is not built\n", s.Name())
+				fmt.Fprintf(os.Stderr, "Image stage '%s' is not built (signature %s)\n", s.Name(), s.GetSignature())
 			}
 		}
 
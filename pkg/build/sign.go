@@ -0,0 +1,60 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/sign"
+)
+
+// SetSignOptions enables signing of the published images after push, manifest lists are signed as well as platform images
+func (c *Conveyor) SetSignOptions(opts sign.Options) {
+	c.signOptions = &opts
+}
+
+func (c *Conveyor) addPublishedImage(imageName string) {
+	c.publishedImages = append(c.publishedImages, imageName)
+}
+
+// signPublishedImages signs each published manifest once by digest, several tags can point to the same manifest
+func (c *Conveyor) signPublishedImages() error {
+	if c.signOptions == nil {
+		return nil
+	}
+
+	imageNames := append([]string{}, c.publishedImages...)
+	for listName := range c.manifestListsToPublish {
+		imageNames = append(imageNames, listName)
+	}
+	sort.Strings(imageNames)
+
+	signedReferences := map[string]bool{}
+	for _, imageName := range imageNames {
+		if err := c.GetContext().Err(); err != nil {
+			return err
+		}
+
+		digest, err := docker_registry.ManifestDigest(imageName)
+		if err != nil {
+			return fmt.Errorf("unable to get image %s digest: %s", imageName, err)
+		}
+
+		reference := sign.DigestReference(imageName, digest)
+		if signedReferences[reference] {
+			continue
+		}
+
+		c.emitEvent(Event{Type: ImageSignStartedEvent, DockerImageName: reference})
+
+		if err := sign.SignImage(c.GetContext(), reference, *c.signOptions); err != nil {
+			return fmt.Errorf("unable to sign image %s: %s", imageName, err)
+		}
+
+		c.emitEvent(Event{Type: ImageSignFinishedEvent, DockerImageName: reference})
+
+		signedReferences[reference] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+const SignaturesCacheVersion = "1"
+
+type signaturesCache struct {
+	Key    string                              `json:"key"`
+	Images map[string][]*signaturesCacheRecord `json:"images"`
+}
+
+type signaturesCacheRecord struct {
+	StageName   stage.StageName `json:"stageName"`
+	Signature   string          `json:"signature"`
+	ImageExists bool            `json:"imageExists"`
+}
+
+func getSignaturesCachePath(c *Conveyor) string {
+	if c.platform != "" {
+		return filepath.Join(c.projectBuildDir, fmt.Sprintf("signatures_cache_%s.json", platformTagSuffix(c.platform)))
+	}
+	return filepath.Join(c.projectBuildDir, "signatures_cache.json")
+}
+
+// getSignaturesCacheKey calculates key based on werf version, dappdeps versions, rendered werf.yaml, base images, latest commits of all git paths
+// and checksums of downloads, which are tracked without expected checksum and can change on --refresh-downloads
+func getSignaturesCacheKey(c *Conveyor) (string, error) {
+	args := []string{SignaturesCacheVersion, BuildCacheVersion, werf.Version, dappdeps.AnsibleVersion(), dappdeps.ToolchainVersion(), c.werfConfig.Checksum(), c.platform}
+
+	imageNamesToProcess := append([]string{}, c.imageNamesToProcess...)
+	sort.Strings(imageNamesToProcess)
+	args = append(args, imageNamesToProcess...)
+
+	if c.rebuildStages != nil {
+		args = append(args, string(c.rebuildStages.fromStage), c.rebuildStages.cacheBust)
+	}
+
+	for _, image := range c.imagesInOrder {
+		args = append(args, image.GetName())
+
+		if image.baseImageImageName == "" {
+			baseImage := c.GetOrCreateImage(nil, image.pinnedBaseImageName())
+			if err := baseImage.SyncDockerState(); err != nil {
+				return "", err
+			}
+
+			args = append(args, image.baseImageDigest, baseImage.ID())
+		}
+
+		for _, s := range image.GetStages() {
+			for _, gitPath := range s.GetGitPaths() {
+				commit, err := gitPath.LatestCommit()
+				if err != nil {
+					return "", err
+				}
+
+				args = append(args, string(s.Name()), gitPath.GetParamshash(), commit)
+			}
+
+			downloadsChecksum, err := s.GetDownloadsChecksum(c.GetContext())
+			if err != nil {
+				return "", err
+			}
+
+			if downloadsChecksum != "" {
+				args = append(args, string(s.Name()), downloadsChecksum)
+			}
+		}
+	}
+
+	return util.Sha256Hash(args...), nil
+}
+
+func readSignaturesCache(c *Conveyor, key string) (*signaturesCache, error) {
+	data, err := ioutil.ReadFile(getSignaturesCachePath(c))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cache := &signaturesCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, nil
+	}
+
+	if cache.Key != key {
+		return nil, nil
+	}
+
+	return cache, nil
+}
+
+func writeSignaturesCache(c *Conveyor, key string) error {
+	cache := &signaturesCache{Key: key, Images: map[string][]*signaturesCacheRecord{}}
+
+	for _, image := range c.imagesInOrder {
+		var records []*signaturesCacheRecord
+		for _, s := range image.GetStages() {
+			records = append(records, &signaturesCacheRecord{
+				StageName:   s.Name(),
+				Signature:   s.GetSignature(),
+				ImageExists: s.GetImage().IsExists(),
+			})
+		}
+
+		cache.Images[image.GetName()] = records
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	path := getSignaturesCachePath(c)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("cannot write signatures cache %s: %s", path, err)
+	}
+
+	return nil
+}
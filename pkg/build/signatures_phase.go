@@ -5,16 +5,24 @@ import (
 
 	"github.com/flant/werf/pkg/build/stage"
 	imagePkg "github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/slug"
 	"github.com/flant/werf/pkg/util"
 )
 
 const (
 	BuildCacheVersion = "33"
-
-	LocalImageStageImageNameFormat = "image-stage-%s"
-	LocalImageStageImageFormat     = "image-stage-%s:%s"
 )
 
+// LocalImageStageImageName returns docker repository name of the project local stages cache, prefix is defined by slug policy
+func LocalImageStageImageName(projectName string) string {
+	return fmt.Sprintf("%s-%s", slug.StageImagePrefix(), projectName)
+}
+
+func LocalImageStageImage(projectName, signature string) string {
+	return fmt.Sprintf("%s:%s", LocalImageStageImageName(projectName), signature)
+}
+
 func NewSignaturesPhase() *SignaturesPhase {
 	return &SignaturesPhase{}
 }
@@ -26,7 +34,115 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 		fmt.Printf("SignaturesPhase.Run\n")
 	}
 
+	cacheKey, err := getSignaturesCacheKey(c)
+	if err != nil {
+		return err
+	}
+
+	cache, err := readSignaturesCache(c, cacheKey)
+	if err != nil {
+		return err
+	}
+
+	if cache != nil {
+		if ok, err := p.runWithCache(c, cache); err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+	}
+
+	if err := p.calculateSignatures(c); err != nil {
+		return err
+	}
+
+	if err := writeSignaturesCache(c, cacheKey); err != nil {
+		logger.LogWarningF("WARNING: %s\n", err)
+	}
+
+	return nil
+}
+
+// runWithCache setups stages with signatures from cache.
+// Returns false when docker state of stages images has been changed since cache was saved.
+func (p *SignaturesPhase) runWithCache(c *Conveyor, cache *signaturesCache) (bool, error) {
+	for _, image := range c.imagesInOrder {
+		if c.isImageReused(image) {
+			continue
+		}
+
+		records, ok := cache.Images[image.GetName()]
+		if !ok {
+			return false, nil
+		}
+
+		image.SetupBaseImage(c)
+
+		prevImage := image.GetBaseImage()
+		if err := prevImage.SyncDockerState(); err != nil {
+			return false, err
+		}
+
+		stagesByName := map[stage.StageName]stage.Interface{}
+		for _, s := range image.GetStages() {
+			stagesByName[s.Name()] = s
+		}
+
+		var newStagesList []stage.Interface
+		var newStagesImages []*imagePkg.StageImage
+
+		for _, record := range records {
+			s, ok := stagesByName[record.StageName]
+			if !ok {
+				return false, nil
+			}
+
+			imageName := LocalImageStageImage(c.stagesNamespace(), record.Signature)
+			i := c.GetOrCreateImage(prevImage, imageName)
+
+			if err := i.SyncDockerState(); err != nil {
+				return false, fmt.Errorf("error synchronizing docker state of stage %s: %s", s.Name(), err)
+			}
+
+			if i.IsExists() != record.ImageExists {
+				return false, nil
+			}
+
+			newStagesList = append(newStagesList, s)
+			newStagesImages = append(newStagesImages, i)
+
+			prevImage = i
+		}
+
+		if len(newStagesList) == 0 {
+			return false, nil
+		}
+
+		for ind, s := range newStagesList {
+			i := newStagesImages[ind]
+
+			s.SetSignature(records[ind].Signature)
+			s.SetImage(i)
+
+			if err := s.AfterImageSyncDockerStateHook(c); err != nil {
+				return false, err
+			}
+
+			c.emitEvent(Event{Type: StageSignatureCalculatedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: i.Name()})
+		}
+
+		image.SetStages(newStagesList)
+	}
+
+	return true, nil
+}
+
+func (p *SignaturesPhase) calculateSignatures(c *Conveyor) error {
 	for _, image := range c.imagesInOrder {
+		if c.isImageReused(image) {
+			continue
+		}
+
 		if debug() {
 			fmt.Printf("  image: '%s'\n", image.GetName())
 		}
@@ -43,6 +159,7 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 		}
 
 		var newStagesList []stage.Interface
+		stagesDependencies := map[stage.StageName]string{}
 
 		for _, s := range image.GetStages() {
 			if prevImage.IsExists() {
@@ -66,9 +183,20 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 				return err
 			}
 
+			stagesDependencies[s.Name()] = stageDependencies
+
 			checksumArgs := []string{stageDependencies, BuildCacheVersion}
 
-			if prevStage != nil {
+			if c.platform != "" {
+				checksumArgs = append(checksumArgs, c.platform)
+			}
+
+			checksumArgs = append(checksumArgs, image.cacheVersionChecksumArgs(c, s.Name())...)
+			checksumArgs = append(checksumArgs, c.cacheBustChecksumArgs(image.GetName(), s.Name())...)
+
+			if s.Name() == stage.Check && isCheckStageNarrowed(s) {
+				checksumArgs = append(checksumArgs, checkStageChecksumArgs(newStagesList, stagesDependencies)...)
+			} else if prevStage != nil {
 				checksumArgs = append(checksumArgs, prevStage.GetSignature())
 			}
 
@@ -76,7 +204,7 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 
 			s.SetSignature(stageSig)
 
-			imageName := fmt.Sprintf(LocalImageStageImageFormat, c.projectName(), stageSig)
+			imageName := LocalImageStageImage(c.stagesNamespace(), stageSig)
 			i := c.GetOrCreateImage(prevImage, imageName)
 			s.SetImage(i)
 
@@ -88,11 +216,7 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 				return err
 			}
 
-			if image.GetName() == "" {
-				fmt.Printf("# Calculated signature %s for image %s\n", stageSig, fmt.Sprintf("stage/%s", s.Name()))
-			} else {
-				fmt.Printf("# Calculated signature %s for image/%s %s\n", stageSig, image.GetName(), fmt.Sprintf("stage/%s", s.Name()))
-			}
+			c.emitEvent(Event{Type: StageSignatureCalculatedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: stageSig, DockerImageName: i.Name()})
 
 			newStagesList = append(newStagesList, s)
 
@@ -105,3 +229,34 @@ func (p *SignaturesPhase) Run(c *Conveyor) error {
 
 	return nil
 }
+
+// isCheckStageNarrowed checks whether stageDependencies.check is set for any git path of the image.
+// Otherwise check stage depends on the previous stage signature as other stages do, so check is rerun on any change of the image
+func isCheckStageNarrowed(s stage.Interface) bool {
+	for _, gitPath := range s.GetGitPaths() {
+		if gitPath.HasStageDependencies(stage.Check) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkStageChecksumArgs returns signature of the last stage before git patches instead of the previous stage signature,
+// so check is not rerun on changes of git files, which are not in stageDependencies.check
+func checkStageChecksumArgs(stages []stage.Interface, stagesDependencies map[stage.StageName]string) []string {
+	var args []string
+	for ind := len(stages) - 1; ind >= 0; ind-- {
+		switch stages[ind].Name() {
+		case stage.GitCache, stage.GitLatestPatch:
+			continue
+		case stage.DockerInstructions:
+			args = append(args, stagesDependencies[stage.DockerInstructions])
+			continue
+		}
+
+		return append(args, stages[ind].GetSignature())
+	}
+
+	return args
+}
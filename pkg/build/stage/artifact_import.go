@@ -124,7 +124,7 @@ func (s *ArtifactImportStage) prepareImportData(c Conveyor, i *config.ArtifactIm
 func (s *ArtifactImportStage) generateImportPaths(i *config.ArtifactImport) (string, string) {
 	exportFolderName := util.Sha256Hash(fmt.Sprintf("%+v", i))
 	artifactNamePathPart := slug.Slug(i.ArtifactName)
-	importTmpPath := path.Join(s.imageTmpDir, "artifact", artifactNamePathPart, exportFolderName)
+	importTmpPath := path.Join(s.getStageTmpDir(), "artifact", artifactNamePathPart, exportFolderName)
 	importContainerTmpPath := path.Join(s.containerWerfDir, "artifact", artifactNamePathPart, exportFolderName)
 
 	return importTmpPath, importContainerTmpPath
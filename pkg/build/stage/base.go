@@ -28,6 +28,7 @@ const (
 	GitCache                    StageName = "git_cache"
 	GitLatestPatch              StageName = "git_latest_patch"
 	DockerInstructions          StageName = "docker_instructions"
+	Check                       StageName = "check"
 )
 
 const (
@@ -39,6 +40,7 @@ const (
 type NewBaseStageOptions struct {
 	ImageName        string
 	ConfigMounts     []*config.Mount
+	ConfigDownloads  []*config.Download
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectBuildDir  string
@@ -49,6 +51,7 @@ func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
 	s.name = name
 	s.imageName = options.ImageName
 	s.configMounts = options.ConfigMounts
+	s.configDownloads = options.ConfigDownloads
 	s.projectBuildDir = options.ProjectBuildDir
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
@@ -65,6 +68,16 @@ type BaseStage struct {
 	containerWerfDir string
 	projectBuildDir  string
 	configMounts     []*config.Mount
+	configDownloads  []*config.Download
+}
+
+// GetStageTmpDir returns tmp dir of the stage inside image tmp dir
+func GetStageTmpDir(imageTmpDir string, name StageName) string {
+	return filepath.Join(imageTmpDir, "stage", string(name))
+}
+
+func (s *BaseStage) getStageTmpDir() string {
+	return GetStageTmpDir(s.imageTmpDir, s.Name())
 }
 
 func (s *BaseStage) Name() StageName {
@@ -87,7 +100,7 @@ func (s *BaseStage) ShouldBeReset(_ image.ImageInterface) (bool, error) {
 	return false, nil
 }
 
-func (s *BaseStage) PrepareImage(_ Conveyor, prevBuiltImage, image image.ImageInterface) error {
+func (s *BaseStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
 	/*
 	 * NOTE: BaseStage.PrepareImage does not called in From.PrepareImage.
 	 * NOTE: Take into account when adding new base PrepareImage steps.
@@ -105,6 +118,10 @@ func (s *BaseStage) PrepareImage(_ Conveyor, prevBuiltImage, image image.ImageIn
 		return fmt.Errorf("error adding mounts volumes: %s", err)
 	}
 
+	if err := s.addDownloadsVolumes(c.GetContext(), image); err != nil {
+		return fmt.Errorf("error adding downloads volumes: %s", err)
+	}
+
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"github.com/flant/werf/pkg/build/builder"
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/util"
 )
 
 func GenerateBeforeInstallStage(imageBaseConfig *config.ImageBase, baseStageOptions *NewBaseStageOptions) *BeforeInstallStage {
@@ -25,8 +26,20 @@ type BeforeInstallStage struct {
 	*UserStage
 }
 
-func (s *BeforeInstallStage) GetDependencies(_ Conveyor, _ image.ImageInterface) (string, error) {
-	return s.builder.BeforeInstallChecksum(), nil
+func (s *BeforeInstallStage) GetDependencies(c Conveyor, _ image.ImageInterface) (string, error) {
+	checksum := s.builder.BeforeInstallChecksum()
+
+	// signature of the stage without stage dependencies is not changed
+	if s.hasStageDependencies(BeforeInstall) {
+		stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c.GetContext(), BeforeInstall)
+		if err != nil {
+			return "", err
+		}
+
+		checksum = util.Sha256Hash(checksum, stageDependenciesChecksum)
+	}
+
+	return s.withDownloadsChecksum(c.GetContext(), checksum)
 }
 
 func (s *BeforeInstallStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
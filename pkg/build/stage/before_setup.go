@@ -26,13 +26,13 @@ type BeforeSetupStage struct {
 	*UserWithGitPatchStage
 }
 
-func (s *BeforeSetupStage) GetDependencies(_ Conveyor, _ image.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(BeforeSetup)
+func (s *BeforeSetupStage) GetDependencies(c Conveyor, _ image.ImageInterface) (string, error) {
+	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c.GetContext(), BeforeSetup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.BeforeSetupChecksum(), stageDependenciesChecksum), nil
+	return s.withDownloadsChecksum(c.GetContext(), util.Sha256Hash(s.builder.BeforeSetupChecksum(), stageDependenciesChecksum))
 }
 
 func (s *BeforeSetupStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
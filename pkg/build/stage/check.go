@@ -0,0 +1,57 @@
+package stage
+
+import (
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/util"
+)
+
+func GenerateCheckStage(imageBaseConfig *config.ImageBase, baseStageOptions *NewBaseStageOptions) *CheckStage {
+	if imageBaseConfig.Check != nil {
+		return newCheckStage(imageBaseConfig.Check, baseStageOptions)
+	}
+
+	return nil
+}
+
+func newCheckStage(check *config.Check, baseStageOptions *NewBaseStageOptions) *CheckStage {
+	s := &CheckStage{}
+	s.check = check
+	s.BaseStage = newBaseStage(Check, baseStageOptions)
+	return s
+}
+
+// CheckStage runs check commands in the container of the built image.
+// Stage image is not used as the image result: it only marks that the check has been passed with the stage signature.
+type CheckStage struct {
+	*BaseStage
+
+	check *config.Check
+}
+
+func (s *CheckStage) GetDependencies(c Conveyor, _ image.ImageInterface) (string, error) {
+	var args []string
+	for _, gitPath := range s.gitPaths {
+		checksum, err := gitPath.StageDependenciesChecksum(c.GetContext(), Check)
+		if err != nil {
+			return "", err
+		}
+
+		args = append(args, checksum)
+	}
+
+	args = append(args, s.check.Run...)
+	args = append(args, s.check.CacheVersion)
+
+	return util.Sha256Hash(args...), nil
+}
+
+func (s *CheckStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
+	if err := s.BaseStage.PrepareImage(c, prevBuiltImage, image); err != nil {
+		return err
+	}
+
+	image.BuilderContainer().AddRunCommands(s.check.Run...)
+
+	return nil
+}
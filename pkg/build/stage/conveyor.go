@@ -1,6 +1,10 @@
 package stage
 
+import "context"
+
 type Conveyor interface {
+	GetContext() context.Context
+
 	GetImageLatestStageSignature(imageName string) string
 	GetImageLatestStageImageName(imageName string) string
 	SetBuildingGitStage(imageName string, stageName StageName)
@@ -0,0 +1,77 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/download"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/util"
+)
+
+// downloadStageNames maps stage names of download directive to the user stages
+var downloadStageNames = map[string]StageName{
+	"beforeInstall": BeforeInstall,
+	"install":       Install,
+	"beforeSetup":   BeforeSetup,
+	"setup":         Setup,
+}
+
+func (s *BaseStage) getStageDownloads() []*config.Download {
+	var res []*config.Download
+	for _, d := range s.configDownloads {
+		if downloadStageNames[d.Stage] == s.Name() {
+			res = append(res, d)
+		}
+	}
+
+	return res
+}
+
+// GetDownloadsChecksum returns empty string if stage has no downloads, so signatures of other stages are not changed
+func (s *BaseStage) GetDownloadsChecksum(ctx context.Context) (string, error) {
+	downloads := s.getStageDownloads()
+	if len(downloads) == 0 {
+		return "", nil
+	}
+
+	var args []string
+	for _, d := range downloads {
+		_, checksum, err := download.Get(ctx, d.Url, d.Checksum)
+		if err != nil {
+			return "", err
+		}
+
+		args = append(args, d.To, checksum)
+	}
+
+	return util.Sha256Hash(args...), nil
+}
+
+func (s *BaseStage) addDownloadsVolumes(ctx context.Context, image image.ImageInterface) error {
+	for _, d := range s.getStageDownloads() {
+		path, _, err := download.Get(ctx, d.Url, d.Checksum)
+		if err != nil {
+			return err
+		}
+
+		image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", path, d.To))
+	}
+
+	return nil
+}
+
+// withDownloadsChecksum mixes downloads checksum into the stage dependencies
+func (s *BaseStage) withDownloadsChecksum(ctx context.Context, dependencies string) (string, error) {
+	downloadsChecksum, err := s.GetDownloadsChecksum(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if downloadsChecksum == "" {
+		return dependencies, nil
+	}
+
+	return util.Sha256Hash(dependencies, downloadsChecksum), nil
+}
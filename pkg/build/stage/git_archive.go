@@ -55,7 +55,7 @@ func (s *GitArchiveStage) PrepareImage(c Conveyor, prevBuiltImage, image image.I
 	}
 
 	for _, gitPath := range s.gitPaths {
-		if err := gitPath.ApplyArchiveCommand(image); err != nil {
+		if err := gitPath.ApplyArchiveCommand(c.GetContext(), image); err != nil {
 			return err
 		}
 	}
@@ -1,6 +1,10 @@
 package stage
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/util"
 )
@@ -17,8 +21,9 @@ type GitCacheStage struct {
 	*GitPatchStage
 }
 
-func (s *GitCacheStage) GetDependencies(_ Conveyor, prevImage image.ImageInterface) (string, error) {
-	var size int64
+func (s *GitCacheStage) GetDependencies(c Conveyor, prevImage image.ImageInterface) (string, error) {
+	var gitPaths []*GitPath
+	var patchesOpts []git_repo.PatchOptions
 	for _, gitPath := range s.gitPaths {
 		commit := gitPath.GetGitCommitFromImageLabels(prevImage)
 		if commit != "" {
@@ -28,15 +33,37 @@ func (s *GitCacheStage) GetDependencies(_ Conveyor, prevImage image.ImageInterfa
 			}
 
 			if exist {
-				patchSize, err := gitPath.PatchSize(commit)
+				patchOpts, err := gitPath.GetPatchSizeOptions(commit)
 				if err != nil {
 					return "", err
 				}
 
-				size += patchSize
+				gitPaths = append(gitPaths, gitPath)
+				patchesOpts = append(patchesOpts, patchOpts)
 			}
 		}
 	}
 
-	return util.Sha256Hash(string(size / patchSizeStep)), nil
+	patches, err := createGitPathsPatches(c.GetContext(), gitPaths, patchesOpts)
+	if err != nil {
+		return "", err
+	}
+	defer removePatchesFiles(patches)
+
+	var size int64
+	for _, patch := range patches {
+		fileInfo, err := os.Stat(patch.GetFilePath())
+		if err != nil {
+			return "", fmt.Errorf("unable to stat temporary patch file `%s`: %s", patch.GetFilePath(), err)
+		}
+
+		size += fileInfo.Size()
+	}
+
+	patchLimitsArgs, err := s.patchLimitsDependencies(c, prevImage)
+	if err != nil {
+		return "", err
+	}
+
+	return util.Sha256Hash(append([]string{string(size / patchSizeStep)}, patchLimitsArgs...)...), nil
 }
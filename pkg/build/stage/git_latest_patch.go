@@ -1,6 +1,7 @@
 package stage
 
 import (
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/util"
 )
@@ -22,7 +23,7 @@ func (s *GitLatestPatchStage) IsEmpty(c Conveyor, prevBuiltImage image.ImageInte
 		return true, nil
 	}
 
-	isEmpty := true
+	var patchesOpts []git_repo.PatchOptions
 	for _, gitPath := range s.gitPaths {
 		commit := gitPath.GetGitCommitFromImageLabels(prevBuiltImage)
 		if exist, err := gitPath.GitRepo().IsCommitExists(commit); err != nil {
@@ -31,18 +32,29 @@ func (s *GitLatestPatchStage) IsEmpty(c Conveyor, prevBuiltImage image.ImageInte
 			return true, nil
 		}
 
-		if empty, err := gitPath.IsPatchEmpty(prevBuiltImage); err != nil {
+		patchOpts, err := gitPath.GetLatestPatchOptions(prevBuiltImage)
+		if err != nil {
 			return false, err
-		} else if !empty {
-			isEmpty = false
-			break
 		}
+		patchesOpts = append(patchesOpts, patchOpts)
 	}
 
-	return isEmpty, nil
+	patches, err := createGitPathsPatches(c.GetContext(), s.gitPaths, patchesOpts)
+	if err != nil {
+		return false, err
+	}
+	defer removePatchesFiles(patches)
+
+	for _, patch := range patches {
+		if !patch.IsEmpty() {
+			return false, nil
+		}
+	}
+
+	return true, nil
 }
 
-func (s *GitLatestPatchStage) GetDependencies(_ Conveyor, prevImage image.ImageInterface) (string, error) {
+func (s *GitLatestPatchStage) GetDependencies(c Conveyor, prevImage image.ImageInterface) (string, error) {
 	var args []string
 
 	for _, gitPath := range s.gitPaths {
@@ -54,5 +66,11 @@ func (s *GitLatestPatchStage) GetDependencies(_ Conveyor, prevImage image.ImageI
 		args = append(args, commit)
 	}
 
+	patchLimitsArgs, err := s.patchLimitsDependencies(c, prevImage)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, patchLimitsArgs...)
+
 	return util.Sha256Hash(args...), nil
 }
@@ -1,21 +1,28 @@
 package stage
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/flant/werf/pkg/dappdeps"
+	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 )
 
 type NewGitPatchStageOptions struct {
-	PatchesDir          string
-	ContainerPatchesDir string
+	PatchesDir           string
+	ContainerPatchesDir  string
+	ArchivesDir          string
+	ContainerArchivesDir string
 }
 
 func newGitPatchStage(name StageName, gitPatchStageOptions *NewGitPatchStageOptions, baseStageOptions *NewBaseStageOptions) *GitPatchStage {
 	s := &GitPatchStage{
-		PatchesDir:          gitPatchStageOptions.PatchesDir,
-		ContainerPatchesDir: gitPatchStageOptions.ContainerPatchesDir,
+		PatchesDir:           gitPatchStageOptions.PatchesDir,
+		ContainerPatchesDir:  gitPatchStageOptions.ContainerPatchesDir,
+		ArchivesDir:          gitPatchStageOptions.ArchivesDir,
+		ContainerArchivesDir: gitPatchStageOptions.ContainerArchivesDir,
 	}
 	s.GitStage = newGitStage(name, baseStageOptions)
 	return s
@@ -24,8 +31,10 @@ func newGitPatchStage(name StageName, gitPatchStageOptions *NewGitPatchStageOpti
 type GitPatchStage struct {
 	*GitStage
 
-	PatchesDir          string
-	ContainerPatchesDir string
+	PatchesDir           string
+	ContainerPatchesDir  string
+	ArchivesDir          string
+	ContainerArchivesDir string
 }
 
 func (s *GitPatchStage) IsEmpty(c Conveyor, prevBuiltImage image.ImageInterface) (bool, error) {
@@ -89,7 +98,7 @@ func (s *GitPatchStage) PrepareImage(c Conveyor, prevBuiltImage, image image.Ima
 
 func (s *GitPatchStage) prepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
 	for _, gitPath := range s.gitPaths {
-		if err := gitPath.ApplyPatchCommand(prevBuiltImage, image); err != nil {
+		if err := gitPath.ApplyPatchCommand(c.GetContext(), prevBuiltImage, image); err != nil {
 			return err
 		}
 	}
@@ -101,6 +110,80 @@ func (s *GitPatchStage) prepareImage(c Conveyor, prevBuiltImage, image image.Ima
 
 	image.Container().RunOptions().AddVolumeFrom(gitArtifactContainerName)
 	image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", s.PatchesDir, s.ContainerPatchesDir))
+	// patches with binary files and patches, which exceed patch limits, are replaced with archives
+	image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", s.ArchivesDir, s.ContainerArchivesDir))
 
 	return nil
 }
+
+// createGitPathsPatches creates patches of the git paths with the corresponding options,
+// patches of the git paths with the same repo and commits range are created with a single git diff invocation.
+// Patches are returned in the git paths order.
+func createGitPathsPatches(ctx context.Context, gitPaths []*GitPath, patchesOpts []git_repo.PatchOptions) ([]git_repo.Patch, error) {
+	type patchesGroupKey struct {
+		repo                 git_repo.GitRepo
+		fromCommit, toCommit string
+	}
+
+	var keys []patchesGroupKey
+	groupsIndexes := map[patchesGroupKey][]int{}
+	for ind, gitPath := range gitPaths {
+		key := patchesGroupKey{repo: gitPath.GitRepo(), fromCommit: patchesOpts[ind].FromCommit, toCommit: patchesOpts[ind].ToCommit}
+		if _, ok := groupsIndexes[key]; !ok {
+			keys = append(keys, key)
+		}
+		groupsIndexes[key] = append(groupsIndexes[key], ind)
+	}
+
+	patches := make([]git_repo.Patch, len(gitPaths))
+	for _, key := range keys {
+		var groupOpts []git_repo.PatchOptions
+		for _, ind := range groupsIndexes[key] {
+			groupOpts = append(groupOpts, patchesOpts[ind])
+		}
+
+		groupPatches, err := key.repo.CreatePatches(ctx, groupOpts)
+		if err != nil {
+			removePatchesFiles(patches)
+			return nil, err
+		}
+
+		for groupInd, ind := range groupsIndexes[key] {
+			patches[ind] = groupPatches[groupInd]
+		}
+	}
+
+	return patches, nil
+}
+
+func removePatchesFiles(patches []git_repo.Patch) {
+	for _, patch := range patches {
+		if patch != nil {
+			os.RemoveAll(patch.GetFilePath())
+		}
+	}
+}
+
+// patchLimitsDependencies marks git paths, which patches exceed patch limits, so stage applied with archive
+// instead of patch has its own signature
+func (s *GitPatchStage) patchLimitsDependencies(c Conveyor, prevImage image.ImageInterface) ([]string, error) {
+	var args []string
+
+	for _, gitPath := range s.gitPaths {
+		if !gitPath.HasPatchLimits() {
+			continue
+		}
+
+		exceeded, err := gitPath.IsPatchLimitsExceeded(c.GetContext(), prevImage)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, gitPath.GetParamshash(), fmt.Sprintf("%d:%d", gitPath.PatchMaxSize, gitPath.PatchMaxFiles))
+		if exceeded {
+			args = append(args, "archive")
+		}
+	}
+
+	return args, nil
+}
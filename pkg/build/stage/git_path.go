@@ -1,16 +1,22 @@
 package stage
 
 import (
+	"archive/tar"
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/flant/werf/pkg/dappdeps"
 	"github.com/flant/werf/pkg/git_repo"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
 )
 
 type GitPath struct {
@@ -31,6 +37,37 @@ type GitPath struct {
 	IncludePaths       []string
 	ExcludePaths       []string
 	StagesDependencies map[StageName][]string
+	// checksum options of the stage dependencies paths, paths without options are hashed with default options
+	StagesDependenciesOptions map[StageName]map[string]git_repo.ChecksumPathOptions
+	// stages, which depend on all files of the git path, except files matching the exclude patterns
+	StagesDependenciesAnyChanges map[StageName][]string
+
+	SkipSubmodules      bool
+	IncludeSubmodules   []string
+	SubmodulesRevisions map[string]string
+	// repos to fetch SubmodulesRevisions from, it is not a part of the paramshash: revisions define the result
+	SubmodulesRevisionsSources map[string]string
+
+	// rename detection makes patches smaller, but does not affect the result,
+	// so it is not a part of the paramshash and is not used to calculate patch size for the git cache stage
+	DetectRenames bool
+	DetectCopies  bool
+
+	// archive is created from the merge-base of the latest commit and the branch,
+	// so images of feature branches share archive layer with the base branch and differ only in patches
+	ArchiveBaseBranch string
+
+	// patch, which exceeds limits, is replaced with archive of the latest commit, zero value means no limit
+	PatchMaxSize  int64
+	PatchMaxFiles int
+
+	// permissions of files and directories of the mapping in the image (octal, e.g. 0644), empty value keeps permissions from archive or patch.
+	// Executable files get execute permission for each read permission of FileMode.
+	FileMode string
+	DirMode  string
+
+	// DeterministicArchives makes archives of the same tree byte-identical regardless of the work tree files times
+	DeterministicArchives bool
 
 	PatchesDir           string
 	ContainerPatchesDir  string
@@ -77,6 +114,16 @@ func (gp *GitPath) getRepoFilterOptions() git_repo.FilterOptions {
 	}
 }
 
+func (gp *GitPath) getRepoSubmodulesOptions() git_repo.SubmodulesOptions {
+	return git_repo.SubmodulesOptions{
+		SkipSubmodules:      gp.SkipSubmodules,
+		IncludeSubmodules:   gp.IncludeSubmodules,
+		SubmodulesRevisions: gp.SubmodulesRevisions,
+
+		SubmodulesRevisionsSources: gp.SubmodulesRevisionsSources,
+	}
+}
+
 func (gp *GitPath) IsLocal() bool {
 	if gp.LocalGitRepo != nil {
 		return true
@@ -107,7 +154,7 @@ func (gp *GitPath) LatestCommit() (string, error) {
 	return commit, nil
 }
 
-func (gp *GitPath) applyPatchCommand(patchFile *ContainerFileDescriptor, archiveType git_repo.ArchiveType) ([]string, error) {
+func (gp *GitPath) applyPatchCommand(patchFile *ContainerFileDescriptor, patchPaths []string, fromCommit, toCommit string, archiveType git_repo.ArchiveType) ([]string, error) {
 	commands := make([]string, 0)
 
 	var applyPatchDirectory string
@@ -136,16 +183,31 @@ func (gp *GitPath) applyPatchCommand(patchFile *ContainerFileDescriptor, archive
 		patchFile.ContainerFilePath,
 	))
 
+	if gp.HasModes() {
+		var paths []string
+		for _, path := range patchPaths {
+			paths = append(paths, filepath.Join(gp.To, path))
+		}
+
+		modesListFile := gp.getPatchModesListFileDescriptor(fromCommit, toCommit)
+
+		modesCommands, err := gp.applyModesCommands(modesListFile, paths, archiveType)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, modesCommands...)
+	}
+
 	return commands, nil
 }
 
-func (gp *GitPath) ApplyPatchCommand(prevBuiltImage, image image.ImageInterface) error {
+func (gp *GitPath) ApplyPatchCommand(ctx context.Context, prevBuiltImage, image image.ImageInterface) error {
 	fromCommit, toCommit, err := gp.GetCommitsToPatch(prevBuiltImage)
 	if err != nil {
 		return err
 	}
 
-	commands, err := gp.baseApplyPatchCommand(fromCommit, toCommit, prevBuiltImage)
+	commands, err := gp.baseApplyPatchCommand(ctx, fromCommit, toCommit, prevBuiltImage)
 	if err != nil {
 		return err
 	}
@@ -190,15 +252,76 @@ func (gp *GitPath) ImageGitCommitLabel() string {
 	return fmt.Sprintf("werf-git-%s-commit", gp.GetParamshash())
 }
 
-func (gp *GitPath) baseApplyPatchCommand(fromCommit, toCommit string, prevBuiltImage image.ImageInterface) ([]string, error) {
-	archiveType := git_repo.ArchiveType(prevBuiltImage.Labels()[gp.getArchiveTypeLabelName()])
+func (gp *GitPath) getPatchOptions(fromCommit, toCommit string) git_repo.PatchOptions {
+	return git_repo.PatchOptions{
+		FilterOptions:     gp.getRepoFilterOptions(),
+		SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+		FromCommit:        fromCommit,
+		ToCommit:          toCommit,
+		DetectRenames:     gp.DetectRenames,
+		DetectCopies:      gp.DetectCopies,
+	}
+}
+
+func (gp *GitPath) HasPatchLimits() bool {
+	return gp.PatchMaxSize != 0 || gp.PatchMaxFiles != 0
+}
+
+// IsPatchLimitsExceeded checks whether patch from the prev built image commit to the latest commit exceeds limits,
+// so archive of the latest commit is applied instead of the patch
+func (gp *GitPath) IsPatchLimitsExceeded(ctx context.Context, prevBuiltImage image.ImageInterface) (bool, error) {
+	if !gp.HasPatchLimits() {
+		return false, nil
+	}
+
+	fromCommit := gp.GetGitCommitFromImageLabels(prevBuiltImage)
+	if fromCommit == "" {
+		return false, nil
+	}
+
+	if exist, err := gp.GitRepo().IsCommitExists(fromCommit); err != nil {
+		return false, err
+	} else if !exist {
+		return false, nil
+	}
 
-	patchOpts := git_repo.PatchOptions{
-		FilterOptions: gp.getRepoFilterOptions(),
-		FromCommit:    fromCommit,
-		ToCommit:      toCommit,
+	toCommit, err := gp.LatestCommit()
+	if err != nil {
+		return false, fmt.Errorf("unable to get latest commit: %s", err)
 	}
-	patch, err := gp.GitRepo().CreatePatch(patchOpts)
+
+	patch, err := gp.GitRepo().CreatePatch(ctx, gp.getPatchOptions(fromCommit, toCommit))
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(patch.GetFilePath())
+
+	return gp.isPatchLimitsExceeded(patch)
+}
+
+func (gp *GitPath) isPatchLimitsExceeded(patch git_repo.Patch) (bool, error) {
+	if gp.PatchMaxFiles != 0 && len(patch.GetPaths()) > gp.PatchMaxFiles {
+		return true, nil
+	}
+
+	if gp.PatchMaxSize != 0 {
+		fileInfo, err := os.Stat(patch.GetFilePath())
+		if err != nil {
+			return false, fmt.Errorf("unable to stat temporary patch file `%s`: %s", patch.GetFilePath(), err)
+		}
+
+		if fileInfo.Size() > gp.PatchMaxSize {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (gp *GitPath) baseApplyPatchCommand(ctx context.Context, fromCommit, toCommit string, prevBuiltImage image.ImageInterface) ([]string, error) {
+	archiveType := git_repo.ArchiveType(prevBuiltImage.Labels()[gp.getArchiveTypeLabelName()])
+
+	patch, err := gp.GitRepo().CreatePatch(ctx, gp.getPatchOptions(fromCommit, toCommit))
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +331,16 @@ func (gp *GitPath) baseApplyPatchCommand(fromCommit, toCommit string, prevBuiltI
 		return nil, nil
 	}
 
-	if patch.HasBinary() {
+	isPatchLimitsExceeded, err := gp.isPatchLimitsExceeded(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPatchLimitsExceeded {
+		fmt.Printf("Patch `%s..%s` of repository `%s` exceeds patch limits: archive of commit `%s` is applied instead\n", fromCommit, toCommit, gp.GitRepo().String(), toCommit)
+	}
+
+	if patch.HasBinary() || isPatchLimitsExceeded {
 		patchPaths := patch.GetPaths()
 
 		pathsListFile, err := gp.createPatchPathsListFile(patchPaths, fromCommit, toCommit)
@@ -232,10 +364,12 @@ func (gp *GitPath) baseApplyPatchCommand(fromCommit, toCommit string, prevBuiltI
 		))
 
 		archiveOpts := git_repo.ArchiveOptions{
-			FilterOptions: gp.getRepoFilterOptions(),
-			Commit:        toCommit,
+			FilterOptions:     gp.getRepoFilterOptions(),
+			SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+			Commit:            toCommit,
+			Deterministic:     gp.DeterministicArchives,
 		}
-		archive, err := gp.GitRepo().CreateArchive(archiveOpts)
+		archive, err := gp.GitRepo().CreateArchive(ctx, archiveOpts)
 		if err != nil {
 			return nil, err
 		}
@@ -266,7 +400,7 @@ func (gp *GitPath) baseApplyPatchCommand(fromCommit, toCommit string, prevBuiltI
 		return nil, fmt.Errorf("cannot create patch file: %s", err)
 	}
 
-	return gp.applyPatchCommand(patchFile, archiveType)
+	return gp.applyPatchCommand(patchFile, patch.GetPaths(), fromCommit, toCommit, archiveType)
 }
 
 func (gp *GitPath) applyArchiveCommand(archiveFile *ContainerFileDescriptor, archiveType git_repo.ArchiveType) ([]string, error) {
@@ -297,16 +431,39 @@ func (gp *GitPath) applyArchiveCommand(archiveFile *ContainerFileDescriptor, arc
 		unpackArchiveDirectory,
 	))
 
+	if gp.HasModes() {
+		archivePaths, err := archiveFilesPaths(archiveFile.FilePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var paths []string
+		for _, path := range archivePaths {
+			paths = append(paths, filepath.Join(unpackArchiveDirectory, path))
+		}
+
+		modesListFile := &ContainerFileDescriptor{
+			FilePath:          archiveFile.FilePath + "-modes-list",
+			ContainerFilePath: archiveFile.ContainerFilePath + "-modes-list",
+		}
+
+		modesCommands, err := gp.applyModesCommands(modesListFile, paths, archiveType)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, modesCommands...)
+	}
+
 	return commands, nil
 }
 
-func (gp *GitPath) ApplyArchiveCommand(image image.ImageInterface) error {
-	commit, err := gp.LatestCommit()
+func (gp *GitPath) ApplyArchiveCommand(ctx context.Context, image image.ImageInterface) error {
+	commit, err := gp.archiveCommit(ctx)
 	if err != nil {
 		return err
 	}
 
-	commands, err := gp.baseApplyArchiveCommand(commit, image)
+	commands, err := gp.baseApplyArchiveCommand(ctx, commit, image)
 	if err != nil {
 		return err
 	}
@@ -318,12 +475,41 @@ func (gp *GitPath) ApplyArchiveCommand(image image.ImageInterface) error {
 	return nil
 }
 
-func (gp *GitPath) baseApplyArchiveCommand(commit string, image image.ImageInterface) ([]string, error) {
+func (gp *GitPath) archiveCommit(ctx context.Context) (string, error) {
+	commit, err := gp.LatestCommit()
+	if err != nil {
+		return "", err
+	}
+
+	if gp.ArchiveBaseBranch == "" {
+		return commit, nil
+	}
+
+	baseBranchCommit, err := gp.GitRepo().LatestBranchCommit(gp.ArchiveBaseBranch)
+	if err != nil {
+		return "", err
+	}
+
+	mergeBase, err := gp.GitRepo().MergeBase(ctx, commit, baseBranchCommit)
+	if err != nil {
+		return "", err
+	}
+
+	if mergeBase != commit {
+		fmt.Printf("Using merge-base commit `%s` of branch `%s` for archive of repository `%s`\n", mergeBase, gp.ArchiveBaseBranch, gp.GitRepo().String())
+	}
+
+	return mergeBase, nil
+}
+
+func (gp *GitPath) baseApplyArchiveCommand(ctx context.Context, commit string, image image.ImageInterface) ([]string, error) {
 	archiveOpts := git_repo.ArchiveOptions{
-		FilterOptions: gp.getRepoFilterOptions(),
-		Commit:        commit,
+		FilterOptions:     gp.getRepoFilterOptions(),
+		SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+		Commit:            commit,
+		Deterministic:     gp.DeterministicArchives,
 	}
-	archive, err := gp.GitRepo().CreateArchive(archiveOpts)
+	archive, err := gp.GitRepo().CreateArchive(ctx, archiveOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -350,7 +536,17 @@ func (gp *GitPath) baseApplyArchiveCommand(commit string, image image.ImageInter
 	return commands, err
 }
 
-func (gp *GitPath) StageDependenciesChecksum(stageName StageName) (string, error) {
+// HasStageDependencies checks whether the stage depends on files of the git path
+func (gp *GitPath) HasStageDependencies(stageName StageName) bool {
+	_, anyChanges := gp.StagesDependenciesAnyChanges[stageName]
+	return anyChanges || len(gp.StagesDependencies[stageName]) > 0
+}
+
+func (gp *GitPath) StageDependenciesChecksum(ctx context.Context, stageName StageName) (string, error) {
+	if excludeOnly, anyChanges := gp.StagesDependenciesAnyChanges[stageName]; anyChanges {
+		return gp.anyChangesChecksum(ctx, excludeOnly)
+	}
+
 	depsPaths := gp.StagesDependencies[stageName]
 	if len(depsPaths) == 0 {
 		return "", nil
@@ -361,49 +557,90 @@ func (gp *GitPath) StageDependenciesChecksum(stageName StageName) (string, error
 		return "", fmt.Errorf("unable to get latest commit: %s", err)
 	}
 
-	opts := git_repo.ChecksumOptions{
-		FilterOptions: gp.getRepoFilterOptions(),
-		Paths:         depsPaths,
-		Commit:        commit,
+	// paths are grouped by checksum options, paths with default options are hashed first
+	// and the checksum is used as is if all paths have default options, so signatures are not changed
+	var pathsOptions []git_repo.ChecksumPathOptions
+	pathsByOptions := map[git_repo.ChecksumPathOptions][]string{}
+	for _, path := range depsPaths {
+		pathOptions := gp.StagesDependenciesOptions[stageName][path]
+		if _, ok := pathsByOptions[pathOptions]; !ok {
+			pathsOptions = append(pathsOptions, pathOptions)
+		}
+		pathsByOptions[pathOptions] = append(pathsByOptions[pathOptions], path)
 	}
 
-	checksum, err := gp.GitRepo().Checksum(opts)
+	sort.SliceStable(pathsOptions, func(i, j int) bool {
+		return pathsOptions[i] == git_repo.ChecksumPathOptions{} && pathsOptions[j] != git_repo.ChecksumPathOptions{}
+	})
+
+	var checksums []string
+	for _, pathOptions := range pathsOptions {
+		opts := git_repo.ChecksumOptions{
+			FilterOptions:       gp.getRepoFilterOptions(),
+			SubmodulesOptions:   gp.getRepoSubmodulesOptions(),
+			ChecksumPathOptions: pathOptions,
+			Paths:               pathsByOptions[pathOptions],
+			Commit:              commit,
+		}
+
+		checksum, err := gp.GitRepo().Checksum(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+
+		for _, path := range checksum.GetNoMatchPaths() {
+			logger.LogWarningF("WARNING: stage `%s` dependency path `%s` have not been found in repo `%s`\n", stageName, path, gp.GitRepo().String())
+		}
+
+		checksums = append(checksums, checksum.String())
+	}
+
+	if len(checksums) == 1 {
+		return checksums[0], nil
+	}
+
+	return util.Sha256Hash(checksums...), nil
+}
+
+// anyChangesChecksum is a checksum of all files of the git path, except files matching exclude patterns
+func (gp *GitPath) anyChangesChecksum(ctx context.Context, excludePaths []string) (string, error) {
+	commit, err := gp.LatestCommit()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("unable to get latest commit: %s", err)
 	}
 
-	for _, path := range checksum.GetNoMatchPaths() {
-		logger.LogWarningF("WARNING: stage `%s` dependency path `%s` have not been found in repo `%s`\n", stageName, path, gp.GitRepo().String())
+	filterOptions := gp.getRepoFilterOptions()
+	filterOptions.ExcludePaths = append(append([]string{}, filterOptions.ExcludePaths...), excludePaths...)
+
+	// empty path pattern matches base path of the git path
+	checksum, err := gp.GitRepo().Checksum(ctx, git_repo.ChecksumOptions{
+		FilterOptions:     filterOptions,
+		SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+		Paths:             []string{""},
+		Commit:            commit,
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return checksum.String(), nil
 }
 
-func (gp *GitPath) PatchSize(fromCommit string) (int64, error) {
+// GetPatchSizeOptions returns options of the patch from the commit to the latest commit, which size is used by git cache stage
+func (gp *GitPath) GetPatchSizeOptions(fromCommit string) (git_repo.PatchOptions, error) {
 	toCommit, err := gp.LatestCommit()
 	if err != nil {
-		return 0, fmt.Errorf("unable to get latest commit: %s", err)
+		return git_repo.PatchOptions{}, fmt.Errorf("unable to get latest commit: %s", err)
 	}
 
-	patchOpts := git_repo.PatchOptions{
+	return git_repo.PatchOptions{
 		FilterOptions:         gp.getRepoFilterOptions(),
+		SubmodulesOptions:     gp.getRepoSubmodulesOptions(),
 		FromCommit:            fromCommit,
 		ToCommit:              toCommit,
 		WithEntireFileContext: true,
 		WithBinary:            true,
-	}
-	patch, err := gp.GitRepo().CreatePatch(patchOpts)
-	if err != nil {
-		return 0, err
-	}
-	defer os.RemoveAll(patch.GetFilePath())
-
-	fileInfo, err := os.Stat(patch.GetFilePath())
-	if err != nil {
-		return 0, fmt.Errorf("unable to stat temporary patch file `%s`: %s", patch.GetFilePath(), err)
-	}
-
-	return fileInfo.Size(), nil
+	}, nil
 }
 
 func (gp *GitPath) GetFullName() string {
@@ -434,6 +671,31 @@ func (gp *GitPath) GetParamshash() string {
 	parts = append(parts, ":::")
 	parts = append(parts, gp.Commit)
 
+	if gp.HasModes() {
+		parts = append(parts, ":::")
+		parts = append(parts, gp.FileMode)
+		parts = append(parts, ":::")
+		parts = append(parts, gp.DirMode)
+	}
+
+	if gp.SkipSubmodules || len(gp.IncludeSubmodules) > 0 || len(gp.SubmodulesRevisions) > 0 {
+		parts = append(parts, ":::")
+		parts = append(parts, fmt.Sprintf("%v", gp.SkipSubmodules))
+		parts = append(parts, ":::")
+		parts = append(parts, gp.IncludeSubmodules...)
+		parts = append(parts, ":::")
+
+		var revisionsPaths []string
+		for path := range gp.SubmodulesRevisions {
+			revisionsPaths = append(revisionsPaths, path)
+		}
+		sort.Strings(revisionsPaths)
+
+		for _, path := range revisionsPaths {
+			parts = append(parts, path, gp.SubmodulesRevisions[path])
+		}
+	}
+
 	for _, part := range parts {
 		_, err = hash.Write([]byte(part))
 		if err != nil {
@@ -444,41 +706,34 @@ func (gp *GitPath) GetParamshash() string {
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
-func (gp *GitPath) IsPatchEmpty(prevBuiltImage image.ImageInterface) (bool, error) {
+// GetLatestPatchOptions returns options of the patch from the prev built image commit to the latest commit, which emptiness is checked by git latest patch stage
+func (gp *GitPath) GetLatestPatchOptions(prevBuiltImage image.ImageInterface) (git_repo.PatchOptions, error) {
 	fromCommit, toCommit, err := gp.GetCommitsToPatch(prevBuiltImage)
 	if err != nil {
-		return false, err
+		return git_repo.PatchOptions{}, err
 	}
 
-	return gp.baseIsPatchEmpty(fromCommit, toCommit)
+	return git_repo.PatchOptions{
+		FilterOptions:     gp.getRepoFilterOptions(),
+		SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+		FromCommit:        fromCommit,
+		ToCommit:          toCommit,
+	}, nil
 }
 
-func (gp *GitPath) baseIsPatchEmpty(fromCommit, toCommit string) (bool, error) {
-	patchOpts := git_repo.PatchOptions{
-		FilterOptions: gp.getRepoFilterOptions(),
-		FromCommit:    fromCommit,
-		ToCommit:      toCommit,
-	}
-	patch, err := gp.GitRepo().CreatePatch(patchOpts)
-	if err != nil {
-		return false, err
-	}
-	defer os.RemoveAll(patch.GetFilePath())
-
-	return patch.IsEmpty(), nil
-}
-
-func (gp *GitPath) IsEmpty() (bool, error) {
+func (gp *GitPath) IsEmpty(ctx context.Context) (bool, error) {
 	commit, err := gp.LatestCommit()
 	if err != nil {
 		return false, fmt.Errorf("unable to get latest commit: %s", err)
 	}
 
 	archiveOpts := git_repo.ArchiveOptions{
-		FilterOptions: gp.getRepoFilterOptions(),
-		Commit:        commit,
+		FilterOptions:     gp.getRepoFilterOptions(),
+		SubmodulesOptions: gp.getRepoSubmodulesOptions(),
+		Commit:            commit,
+		Deterministic:     gp.DeterministicArchives,
 	}
-	archive, err := gp.GitRepo().CreateArchive(archiveOpts)
+	archive, err := gp.GitRepo().CreateArchive(ctx, archiveOpts)
 	if err != nil {
 		return false, err
 	}
@@ -554,6 +809,15 @@ func (gp *GitPath) getPatchPathsListFileDescriptor(fromCommit, toCommit string)
 	}
 }
 
+func (gp *GitPath) getPatchModesListFileDescriptor(fromCommit, toCommit string) *ContainerFileDescriptor {
+	fileName := fmt.Sprintf("%s_%s_%s-modes-list", gp.GetParamshash(), fromCommit, toCommit)
+
+	return &ContainerFileDescriptor{
+		FilePath:          filepath.Join(gp.PatchesDir, fileName),
+		ContainerFilePath: filepath.Join(gp.ContainerPatchesDir, fileName),
+	}
+}
+
 func (gp *GitPath) getPatchFileDescriptor(fromCommit, toCommit string) *ContainerFileDescriptor {
 	fileName := fmt.Sprintf("%s_%s_%s.patch", gp.GetParamshash(), fromCommit, toCommit)
 
@@ -576,6 +840,120 @@ func (gp *GitPath) makeCredentialsOpts() string {
 	return strings.Join(opts, " ")
 }
 
+func (gp *GitPath) HasModes() bool {
+	return gp.FileMode != "" || gp.DirMode != ""
+}
+
+// applyModesCommands sets FileMode and DirMode for the applied files and directories between the files and the mapping directory,
+// paths are passed to the container in the list file, paths of deleted files and symlinks are skipped
+func (gp *GitPath) applyModesCommands(listFile *ContainerFileDescriptor, paths []string, archiveType git_repo.ArchiveType) ([]string, error) {
+	var entries []string
+
+	if gp.FileMode != "" {
+		entries = append(entries, paths...)
+	}
+
+	if gp.DirMode != "" && archiveType == git_repo.DirectoryArchive {
+		toPrefix := strings.TrimSuffix(gp.To, "/") + "/"
+
+		dirs := map[string]bool{}
+		for _, path := range paths {
+			for dir := filepath.Dir(path); strings.HasPrefix(dir, toPrefix) && !dirs[dir]; dir = filepath.Dir(dir) {
+				dirs[dir] = true
+			}
+		}
+
+		if len(paths) > 0 {
+			dirs[gp.To] = true
+		}
+
+		for dir := range dirs {
+			entries = append(entries, dir)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	sort.Strings(entries)
+
+	f, err := listFile.Open(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file `%s`: %s", listFile.FilePath, err)
+	}
+
+	if _, err := f.Write([]byte(strings.Join(entries, "\000") + "\000")); err != nil {
+		return nil, fmt.Errorf("unable to write file `%s`: %s", listFile.FilePath, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("unable to close file `%s`: %s", listFile.FilePath, err)
+	}
+
+	chmod := dappdeps.BaseBinPath("chmod")
+
+	var branches []string
+	branches = append(branches, `if [ -L "$path" ]; then :`)
+	if gp.DirMode != "" {
+		branches = append(branches, fmt.Sprintf(`elif [ -d "$path" ]; then %s %s "$path"`, chmod, gp.DirMode))
+	} else {
+		branches = append(branches, `elif [ -d "$path" ]; then :`)
+	}
+	if gp.FileMode != "" {
+		executableFileMode, err := executableMode(gp.FileMode)
+		if err != nil {
+			return nil, err
+		}
+
+		branches = append(branches, fmt.Sprintf(`elif [ -x "$path" ]; then %s %s "$path"`, chmod, executableFileMode))
+		branches = append(branches, fmt.Sprintf(`elif [ -f "$path" ]; then %s %s "$path"`, chmod, gp.FileMode))
+	}
+
+	return []string{fmt.Sprintf(
+		`while IFS= read -r -d '' path; do %s; fi; done < %s`,
+		strings.Join(branches, "; "),
+		listFile.ContainerFilePath,
+	)}, nil
+}
+
+// executableMode adds execute permission for each read permission of the file mode (0644 -> 0755, 0640 -> 0750)
+func executableMode(fileMode string) (string, error) {
+	mode, err := strconv.ParseUint(fileMode, 8, 32)
+	if err != nil {
+		return "", fmt.Errorf("bad file mode `%s`: %s", fileMode, err)
+	}
+
+	return fmt.Sprintf("%04o", mode|(mode&0444)>>2), nil
+}
+
+// archiveFilesPaths lists files and symlinks of the archive
+func archiveFilesPaths(archivePath string) ([]string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open archive `%s`: %s", archivePath, err)
+	}
+	defer f.Close()
+
+	var paths []string
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read archive `%s`: %s", archivePath, err)
+		}
+
+		if header.Typeflag != tar.TypeDir {
+			paths = append(paths, header.Name)
+		}
+	}
+
+	return paths, nil
+}
+
 func (gp *GitPath) getArchiveTypeLabelName() string {
 	return fmt.Sprintf("werf-git-%s-type", gp.GetParamshash())
 }
@@ -26,13 +26,13 @@ type InstallStage struct {
 	*UserWithGitPatchStage
 }
 
-func (s *InstallStage) GetDependencies(_ Conveyor, _ image.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(Install)
+func (s *InstallStage) GetDependencies(c Conveyor, _ image.ImageInterface) (string, error) {
+	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c.GetContext(), Install)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.InstallChecksum(), stageDependenciesChecksum), nil
+	return s.withDownloadsChecksum(c.GetContext(), util.Sha256Hash(s.builder.InstallChecksum(), stageDependenciesChecksum))
 }
 
 func (s *InstallStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
@@ -1,6 +1,10 @@
 package stage
 
-import "github.com/flant/werf/pkg/image"
+import (
+	"context"
+
+	"github.com/flant/werf/pkg/image"
+)
 
 type Interface interface {
 	Name() StageName
@@ -23,4 +27,6 @@ type Interface interface {
 
 	SetGitPaths([]*GitPath)
 	GetGitPaths() []*GitPath
+
+	GetDownloadsChecksum(ctx context.Context) (string, error)
 }
@@ -26,13 +26,13 @@ type SetupStage struct {
 	*UserWithGitPatchStage
 }
 
-func (s *SetupStage) GetDependencies(_ Conveyor, _ image.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(Setup)
+func (s *SetupStage) GetDependencies(c Conveyor, _ image.ImageInterface) (string, error) {
+	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c.GetContext(), Setup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.SetupChecksum(), stageDependenciesChecksum), nil
+	return s.withDownloadsChecksum(c.GetContext(), util.Sha256Hash(s.builder.SetupChecksum(), stageDependenciesChecksum))
 }
 
 func (s *SetupStage) PrepareImage(c Conveyor, prevBuiltImage, image image.ImageInterface) error {
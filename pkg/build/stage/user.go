@@ -1,6 +1,8 @@
 package stage
 
 import (
+	"context"
+
 	"github.com/flant/werf/pkg/build/builder"
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/util"
@@ -31,10 +33,10 @@ type UserStage struct {
 	builder builder.Builder
 }
 
-func (s *UserStage) getStageDependenciesChecksum(name StageName) (string, error) {
+func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, name StageName) (string, error) {
 	var args []string
 	for _, gitPath := range s.gitPaths {
-		checksum, err := gitPath.StageDependenciesChecksum(name)
+		checksum, err := gitPath.StageDependenciesChecksum(ctx, name)
 		if err != nil {
 			return "", err
 		}
@@ -44,3 +46,13 @@ func (s *UserStage) getStageDependenciesChecksum(name StageName) (string, error)
 
 	return util.Sha256Hash(args...), nil
 }
+
+func (s *UserStage) hasStageDependencies(name StageName) bool {
+	for _, gitPath := range s.gitPaths {
+		if gitPath.HasStageDependencies(name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,183 @@
+package build
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/docker_registry"
+	imagePkg "github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+)
+
+// StagesRepoRecheckPeriod is a minimal period between checks of unreachable stages repo
+var StagesRepoRecheckPeriod = 30 * time.Second
+
+// stagesRepo is a registry-backed stages cache: missing stages are pulled from the repo and built stages are pushed into the repo.
+// Repo errors are not fatal, conveyor falls back to the local stages cache and pushes postponed stages as soon as repo becomes reachable.
+type stagesRepo struct {
+	Repo string
+
+	available     bool
+	lastCheckTime time.Time
+	tags          []string
+	pendingPushes []*pendingStagePush
+}
+
+type pendingStagePush struct {
+	ImageName       string
+	StageName       stage.StageName
+	Signature       string
+	DockerImageName string
+}
+
+func newStagesRepo(repo string) *stagesRepo {
+	return &stagesRepo{Repo: repo}
+}
+
+func (r *stagesRepo) repoImageName(signature string) string {
+	return fmt.Sprintf("%s:%s", r.Repo, RepoImageStageTag(signature))
+}
+
+func (r *stagesRepo) hasStage(signature string) bool {
+	return util.IsStringsContainValue(r.tags, RepoImageStageTag(signature))
+}
+
+// isAvailable fetches stages list from the repo on the first call and rechecks unreachable repo not more often than StagesRepoRecheckPeriod
+func (r *stagesRepo) isAvailable(c *Conveyor, force bool) bool {
+	if r.available {
+		return true
+	}
+
+	if !r.lastCheckTime.IsZero() && !force && time.Since(r.lastCheckTime) < StagesRepoRecheckPeriod {
+		return false
+	}
+
+	r.lastCheckTime = time.Now()
+
+	err := c.GetDockerAuthorizer().LoginForPush(r.Repo)
+	if err == nil {
+		r.tags, err = docker_registry.ImageStagesTags(r.Repo)
+	}
+
+	if err != nil {
+		logger.LogWarningF("WARNING: stages repo %s is not available, using local stages cache: %s\n", r.Repo, err)
+		return false
+	}
+
+	r.available = true
+
+	return true
+}
+
+func (r *stagesRepo) setUnavailable(err error) {
+	logger.LogWarningF("WARNING: stages repo %s is not available, using local stages cache: %s\n", r.Repo, err)
+
+	r.available = false
+	r.lastCheckTime = time.Now()
+}
+
+// PullStage imports stage image from the repo, returns false if stage cannot be pulled
+func (r *stagesRepo) PullStage(c *Conveyor, image *Image, s stage.Interface) (bool, error) {
+	if !r.isAvailable(c, false) || !r.hasStage(s.GetSignature()) {
+		return false, nil
+	}
+
+	img := c.GetStageImage(s.GetImage().Name())
+	repoImageName := r.repoImageName(s.GetSignature())
+
+	c.emitEvent(Event{Type: StagePullStartedEvent, ImageName: image.GetName(), StageName: s.Name(), Signature: s.GetSignature(), DockerImageName: repoImageName})
+
+	if err := img.Import(c.GetContext(), repoImageName); err != nil {
+		if ctxErr := c.GetContext().Err(); ctxErr != nil {
+			return false, ctxErr
+		}
+
+		r.setUnavailable(fmt.Errorf("error pulling %s: %s", repoImageName, err))
+
+		return false, nil
+	}
+
+	if err := img.SyncDockerState(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// PushStage pushes built stage into the repo or postpones push until repo becomes reachable
+func (r *stagesRepo) PushStage(c *Conveyor, image *Image, s stage.Interface) error {
+	if r.available && r.hasStage(s.GetSignature()) {
+		return nil
+	}
+
+	r.pendingPushes = append(r.pendingPushes, &pendingStagePush{
+		ImageName:       image.GetName(),
+		StageName:       s.Name(),
+		Signature:       s.GetSignature(),
+		DockerImageName: s.GetImage().Name(),
+	})
+
+	return r.Reconcile(c, false)
+}
+
+// Reconcile pushes postponed stages if repo is reachable
+func (r *stagesRepo) Reconcile(c *Conveyor, force bool) error {
+	if len(r.pendingPushes) == 0 || !r.isAvailable(c, force) {
+		return nil
+	}
+
+	for len(r.pendingPushes) > 0 {
+		p := r.pendingPushes[0]
+
+		if !r.hasStage(p.Signature) {
+			if err := r.pushStage(c, p); err != nil {
+				if ctxErr := c.GetContext().Err(); ctxErr != nil {
+					return ctxErr
+				}
+
+				r.setUnavailable(err)
+
+				return nil
+			}
+
+			r.tags = append(r.tags, RepoImageStageTag(p.Signature))
+		}
+
+		r.pendingPushes = r.pendingPushes[1:]
+	}
+
+	return nil
+}
+
+func (r *stagesRepo) pushStage(c *Conveyor, p *pendingStagePush) error {
+	repoImageName := r.repoImageName(p.Signature)
+
+	imageLockName := fmt.Sprintf("image.%s", util.Sha256Hash(repoImageName))
+	if err := lock.Lock(imageLockName, lock.LockOptions{}); err != nil {
+		return fmt.Errorf("failed to lock %s: %s", imageLockName, err)
+	}
+	defer lock.Unlock(imageLockName)
+
+	c.emitEvent(Event{Type: StagePushStartedEvent, ImageName: p.ImageName, StageName: p.StageName, Signature: p.Signature, DockerImageName: repoImageName})
+
+	// stage image is created anew, because conveyor runtime state could be reset since stage has been built
+	if err := lock.WithHostSlot(lock.PushResource, c.projectName(), func() error {
+		return imagePkg.NewStageImage(nil, p.DockerImageName).Export(c.GetContext(), repoImageName)
+	}); err != nil {
+		return fmt.Errorf("error pushing %s: %s", repoImageName, err)
+	}
+
+	c.emitEvent(Event{Type: StagePushFinishedEvent, ImageName: p.ImageName, StageName: p.StageName, Signature: p.Signature, DockerImageName: repoImageName})
+
+	return nil
+}
+
+// reportPendingPushes warns about stages, which have not been pushed into unreachable repo
+func (r *stagesRepo) reportPendingPushes() {
+	if len(r.pendingPushes) > 0 {
+		logger.LogWarningF("WARNING: %d built stages have not been pushed into unavailable stages repo %s\n", len(r.pendingPushes), r.Repo)
+	}
+}
@@ -0,0 +1,211 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/flant/werf/pkg/docker_registry"
+	imagePkg "github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// STAGES_SIGNATURES_VERSION should be bumped when signatures file format is changed
+const STAGES_SIGNATURES_VERSION = "1"
+
+// StagesSignatures is a list of the project stages published into the stages repo,
+// which is passed between CI jobs to pull exactly the same stages without werf.yaml processing
+type StagesSignatures struct {
+	Version         string                   `json:"version"`
+	Project         string                   `json:"project"`
+	StagesNamespace string                   `json:"stagesNamespace"`
+	StagesRepo      string                   `json:"stagesRepo"`
+	Stages          []*StagesSignaturesStage `json:"stages"`
+}
+
+type StagesSignaturesStage struct {
+	ImageName string `json:"imageName"`
+	StageName string `json:"stageName"`
+	Signature string `json:"signature"`
+	Platform  string `json:"platform,omitempty"`
+}
+
+func ReadStagesSignatures(path string) (*StagesSignatures, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read signatures file %s: %s", path, err)
+	}
+
+	signatures := &StagesSignatures{}
+	if err := json.Unmarshal(data, signatures); err != nil {
+		return nil, fmt.Errorf("bad signatures file %s: %s", path, err)
+	}
+
+	if signatures.Version != STAGES_SIGNATURES_VERSION {
+		return nil, fmt.Errorf("signatures file %s version '%s' is not supported: expected version '%s'", path, signatures.Version, STAGES_SIGNATURES_VERSION)
+	}
+
+	return signatures, nil
+}
+
+func WriteStagesSignatures(path string, signatures *StagesSignatures) error {
+	data, err := json.MarshalIndent(signatures, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write signatures file %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// PublishStages pushes built stages of the images into the stages repo and writes list of these stages into the signatures file.
+// Stages should be built before publishing.
+func (c *Conveyor) PublishStages(ctx context.Context, repo, signaturesFile string) error {
+	c.ctx = ctx
+
+	if werf.IsOffline() {
+		return werf.OfflineError("stages publish")
+	}
+
+	signatures := &StagesSignatures{
+		Version:         STAGES_SIGNATURES_VERSION,
+		Project:         c.projectName(),
+		StagesNamespace: c.stagesNamespace(),
+		StagesRepo:      repo,
+	}
+
+	if err := c.forEachPlatform(func() error {
+		return c.publishStages(repo, signatures)
+	}); err != nil {
+		return err
+	}
+
+	return WriteStagesSignatures(signaturesFile, signatures)
+}
+
+func (c *Conveyor) publishStages(repo string, signatures *StagesSignatures) error {
+	var err error
+
+	var phases []Phase
+	phases = append(phases, NewInitializationPhase())
+	phases = append(phases, NewResolveBaseImagesPhase(false))
+	phases = append(phases, NewSignaturesPhase())
+	phases = append(phases, NewShouldBeBuiltPhase())
+	phases = append(phases, NewPublishStagesPhase(repo, signatures))
+
+	lockName, err := c.lockAllImagesReadOnly()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(lockName)
+
+	return c.runPhases(phases)
+}
+
+func NewPublishStagesPhase(repo string, signatures *StagesSignatures) *PublishStagesPhase {
+	return &PublishStagesPhase{Repo: repo, Signatures: signatures}
+}
+
+type PublishStagesPhase struct {
+	Repo       string
+	Signatures *StagesSignatures
+}
+
+func (p *PublishStagesPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("PublishStagesPhase.Run\n")
+	}
+
+	if err := c.GetDockerAuthorizer().LoginForPush(p.Repo); err != nil {
+		return fmt.Errorf("login into '%s' for push failed: %s", p.Repo, err)
+	}
+
+	pushPhase := &PushPhase{Repo: p.Repo}
+
+	for _, image := range c.imagesInOrder {
+		c.emitEvent(Event{Type: ImageStagesPushStartedEvent, ImageName: image.GetName()})
+
+		if err := pushPhase.pushImageStages(c, image); err != nil {
+			return fmt.Errorf("unable to push image %s stages: %s", image.GetName(), err)
+		}
+
+		for _, s := range image.GetStages() {
+			p.Signatures.Stages = append(p.Signatures.Stages, &StagesSignaturesStage{
+				ImageName: image.GetName(),
+				StageName: string(s.Name()),
+				Signature: s.GetSignature(),
+				Platform:  c.platform,
+			})
+		}
+	}
+
+	return nil
+}
+
+// PullStages imports stages listed in the signatures file from the stages repo into the local stages cache,
+// stages, which exist locally, are skipped
+func PullStages(ctx context.Context, repo string, signatures *StagesSignatures, authorizer DockerAuthorizer) error {
+	if err := authorizer.LoginForPull(repo); err != nil {
+		return fmt.Errorf("login into '%s' for pull failed: %s", repo, err)
+	}
+
+	existingStagesTags, err := docker_registry.ImageStagesTags(repo)
+	if err != nil {
+		return fmt.Errorf("error fetching existing stages cache list %s: %s", repo, err)
+	}
+
+	for _, record := range signatures.Stages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		localImageName := LocalImageStageImage(signatures.StagesNamespace, record.Signature)
+		stageTagName := RepoImageStageTag(record.Signature)
+		repoImageName := fmt.Sprintf("%s:%s", repo, stageTagName)
+
+		err := func() error {
+			imageLockName := fmt.Sprintf("%s.image.%s", signatures.StagesNamespace, localImageName)
+			if err := lock.Lock(imageLockName, lock.LockOptions{}); err != nil {
+				return fmt.Errorf("failed to lock %s: %s", imageLockName, err)
+			}
+			defer lock.Unlock(imageLockName)
+
+			stageImage := imagePkg.NewStageImage(nil, localImageName)
+			stageImage.SetPlatform(record.Platform)
+
+			if err := stageImage.SyncDockerState(); err != nil {
+				return err
+			}
+
+			if stageImage.IsExists() {
+				logger.LogInfoF("# Stage %s of image %s exists locally (signature %s)\n", record.StageName, imageOrderItemName(record.ImageName), record.Signature)
+				return nil
+			}
+
+			if !util.IsStringsContainValue(existingStagesTags, stageTagName) {
+				return fmt.Errorf("stage %s of image %s (signature %s) not found in stages repo %s", record.StageName, imageOrderItemName(record.ImageName), record.Signature, repo)
+			}
+
+			logger.LogInfoF("# Pulling stage %s of image %s from %s\n", record.StageName, imageOrderItemName(record.ImageName), repoImageName)
+
+			if err := stageImage.Import(ctx, repoImageName); err != nil {
+				return fmt.Errorf("error pulling %s: %s", repoImageName, err)
+			}
+
+			return nil
+		}()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+)
+
+// SetTakeSubmodulesFromWorkTree makes werf build not committed commits of the project work tree submodules
+// instead of the commits recorded in the HEAD commit of the project repo
+func (c *Conveyor) SetTakeSubmodulesFromWorkTree(take bool) {
+	c.takeSubmodulesFromWorkTree = take
+}
+
+func (c *Conveyor) getDivergedSubmodules(repo *git_repo.Local) ([]*true_git.SubmoduleWorkTreeState, error) {
+	if c.divergedSubmodulesChecked {
+		return c.divergedSubmodules, nil
+	}
+
+	commit, err := repo.HeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get project repo HEAD commit: %s", err)
+	}
+
+	submodules, err := repo.DivergedSubmodules(c.GetContext(), commit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check project work tree submodules: %s", err)
+	}
+
+	for _, submodule := range submodules {
+		if submodule.IsDiverged() {
+			if c.takeSubmodulesFromWorkTree {
+				logger.LogWarningF("WARNING: Submodule `%s` work tree commit %s is used instead of commit %s recorded in the project repo HEAD\n", submodule.Path, submodule.WorkTreeCommit, submodule.Commit)
+			} else {
+				logger.LogWarningF("WARNING: Submodule `%s` is checked out at commit %s, but commit %s recorded in the project repo HEAD is built: commit submodule update or use --take-submodules-from-worktree option\n", submodule.Path, submodule.WorkTreeCommit, submodule.Commit)
+			}
+		}
+
+		if submodule.IsDirty {
+			logger.LogWarningF("WARNING: Submodule `%s` has uncommitted changes, which are not built\n", submodule.Path)
+		}
+	}
+
+	c.divergedSubmodules = submodules
+	c.divergedSubmodulesChecked = true
+
+	return submodules, nil
+}
+
+// applyDivergedSubmodules warns about the project work tree submodules, which differ from the HEAD commit,
+// and pins work tree commits of these submodules in the take submodules from work tree mode.
+// Submodules revisions explicitly set in the werf.yaml are not changed.
+func (c *Conveyor) applyDivergedSubmodules(repo *git_repo.Local, gitPath *stage.GitPath) error {
+	submodules, err := c.getDivergedSubmodules(repo)
+	if err != nil {
+		return err
+	}
+
+	if !c.takeSubmodulesFromWorkTree {
+		return nil
+	}
+
+	revisions := map[string]string{}
+	for path, revision := range gitPath.SubmodulesRevisions {
+		revisions[path] = revision
+	}
+
+	sources := map[string]string{}
+	for path, source := range gitPath.SubmodulesRevisionsSources {
+		sources[path] = source
+	}
+
+	for _, submodule := range submodules {
+		if !submodule.IsDiverged() || !isSubmoduleIncluded(gitPath.IncludeSubmodules, submodule.Path) || isSubmodulePinned(gitPath.SubmodulesRevisions, submodule.Path) {
+			continue
+		}
+
+		revisions[submodule.Path] = submodule.WorkTreeCommit
+		sources[submodule.Path] = filepath.Join(repo.Path, submodule.Path)
+	}
+
+	gitPath.SubmodulesRevisions = revisions
+	gitPath.SubmodulesRevisionsSources = sources
+
+	return nil
+}
+
+func isSubmoduleIncluded(include []string, path string) bool {
+	if len(include) == 0 {
+		return true
+	}
+
+	for _, includePath := range include {
+		if filepath.Clean(includePath) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSubmodulePinned(revisions map[string]string, path string) bool {
+	for revisionPath := range revisions {
+		if filepath.Clean(revisionPath) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
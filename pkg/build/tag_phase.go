@@ -22,6 +22,9 @@ func NewTagPhase(repo string, opts TagOptions) *TagPhase {
 type TagPhase struct {
 	Repo         string
 	TagsByScheme map[TagScheme][]string
+
+	// TaggedImages are names of all tagged docker images
+	TaggedImages []string
 }
 
 func (p *TagPhase) Run(c *Conveyor) error {
@@ -31,11 +34,7 @@ func (p *TagPhase) Run(c *Conveyor) error {
 
 	for _, image := range c.imagesInOrder {
 		if !image.isArtifact {
-			if image.GetName() == "" {
-				fmt.Printf("# Tagging image\n")
-			} else {
-				fmt.Printf("# Tagging image/%s\n", image.GetName())
-			}
+			c.emitEvent(Event{Type: ImageTagStartedEvent, ImageName: image.GetName()})
 
 			err := p.tagImage(c, image)
 			if err != nil {
@@ -55,11 +54,14 @@ func (p *TagPhase) tagImage(c *Conveyor, image *Image) error {
 		imageRepository = p.Repo
 	}
 
-	stages := image.GetStages()
-	lastStageImage := stages[len(stages)-1].GetImage()
+	lastStageImage := image.LatestStage().GetImage()
 
 	for scheme, tags := range p.TagsByScheme {
 		for _, tag := range tags {
+			if c.platform != "" {
+				tag = fmt.Sprintf("%s-%s", tag, platformTagSuffix(c.platform))
+			}
+
 			imageImageName := fmt.Sprintf("%s:%s", imageRepository, tag)
 
 			err := func() error {
@@ -72,31 +74,31 @@ func (p *TagPhase) tagImage(c *Conveyor, image *Image) error {
 				}
 				defer lock.Unlock(imageLockName)
 
-				fmt.Printf("# Build %s layer with tag scheme '%s'\n", imageImageName, scheme)
+				c.emitEvent(Event{Type: TagBuildStartedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
 
 				tagImage := imagePkg.NewImage(c.GetStageImage(lastStageImage.Name()), imageImageName)
 
-				tagImage.Container().ServiceCommitChangeOptions().AddLabel(map[string]string{
-					"werf-tag-scheme": string(scheme),
-					"werf-image":      "true",
-				})
+				labels, err := c.finalImageServiceLabels(image, scheme)
+				if err != nil {
+					return err
+				}
+				tagImage.Container().ServiceCommitChangeOptions().AddLabel(c.userLabels())
+				tagImage.Container().ServiceCommitChangeOptions().AddLabel(labels)
 
 				err = tagImage.Build(imagePkg.BuildOptions{})
 				if err != nil {
 					return fmt.Errorf("error building %s with tag scheme '%s': %s", imageImageName, scheme, err)
 				}
 
-				if image.GetName() == "" {
-					fmt.Printf("# Tagging image %s for image\n", imageImageName)
-				} else {
-					fmt.Printf("# Tagging image %s for image/%s\n", imageImageName, image.GetName())
-				}
+				c.emitEvent(Event{Type: TagStartedEvent, ImageName: image.GetName(), DockerImageName: imageImageName, TagScheme: scheme})
 
 				err = tagImage.Tag()
 				if err != nil {
 					return fmt.Errorf("error tagging %s: %s", imageImageName, err)
 				}
 
+				p.TaggedImages = append(p.TaggedImages, imageImageName)
+
 				return nil
 			}()
 
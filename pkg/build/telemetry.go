@@ -0,0 +1,72 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flant/werf/pkg/telemetry"
+)
+
+// telemetryRecorder reports conveyor runs into the telemetry: spans of the run, phases and built stages,
+// stages build durations and cache hits metrics
+type telemetryRecorder struct {
+	phaseSpan  *telemetry.Span
+	stageSpans map[string]*telemetry.Span
+	stageStart map[string]time.Time
+}
+
+func newTelemetryRecorder() *telemetryRecorder {
+	return &telemetryRecorder{stageSpans: map[string]*telemetry.Span{}, stageStart: map[string]time.Time{}}
+}
+
+func (r *telemetryRecorder) startPhase(runSpan *telemetry.Span, phase Phase) {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", phase), "*build.")
+	r.phaseSpan = telemetry.StartSpan(name, runSpan, nil)
+}
+
+// endPhase also finishes spans of the stages, which build has been interrupted by the phase error
+func (r *telemetryRecorder) endPhase(err error) {
+	for key, stageSpan := range r.stageSpans {
+		stageSpan.End(err)
+		delete(r.stageSpans, key)
+		delete(r.stageStart, key)
+	}
+
+	r.phaseSpan.End(err)
+	r.phaseSpan = nil
+}
+
+func (r *telemetryRecorder) recordEvent(event Event) {
+	key := fmt.Sprintf("%s/%s/%s", event.ImageName, event.StageName, event.Signature)
+
+	switch event.Type {
+	case StageCacheHitEvent:
+		telemetry.IncStageCacheHits(telemetryImageName(event.ImageName), string(event.StageName))
+	case StageBuildStartedEvent:
+		telemetry.IncStageCacheMisses(telemetryImageName(event.ImageName), string(event.StageName))
+
+		r.stageStart[key] = event.Time
+		r.stageSpans[key] = telemetry.StartSpan(fmt.Sprintf("stage %s", event.StageName), r.phaseSpan, map[string]string{
+			"werf.image":     telemetryImageName(event.ImageName),
+			"werf.stage":     string(event.StageName),
+			"werf.signature": event.Signature,
+		})
+	case StageBuildFinishedEvent:
+		if start, hasKey := r.stageStart[key]; hasKey {
+			telemetry.ObserveStageBuild(telemetryImageName(event.ImageName), string(event.StageName), event.Time.Sub(start))
+			delete(r.stageStart, key)
+		}
+
+		r.stageSpans[key].End(nil)
+		delete(r.stageSpans, key)
+	}
+}
+
+func telemetryImageName(name string) string {
+	if name == "" {
+		return "~"
+	}
+
+	return name
+}
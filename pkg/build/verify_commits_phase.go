@@ -0,0 +1,87 @@
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func NewVerifyCommitsPhase() *VerifyCommitsPhase {
+	return &VerifyCommitsPhase{}
+}
+
+// VerifyCommitsPhase checks that commits of all git mappings are signed by keys from trusted keyrings.
+// Keyrings are specified in werf.yaml (build.commitSignatureKeyrings) and with SetCommitSignatureKeyrings,
+// phase does nothing if there are no keyrings.
+type VerifyCommitsPhase struct{}
+
+func (p *VerifyCommitsPhase) Run(c *Conveyor) error {
+	if debug() {
+		fmt.Printf("VerifyCommitsPhase.Run\n")
+	}
+
+	keyringsPaths := c.getCommitSignatureKeyringsPaths()
+	if len(keyringsPaths) == 0 {
+		return nil
+	}
+
+	var armoredKeyRings []string
+	for _, path := range keyringsPaths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read commit signature keyring %s: %s", path, err)
+		}
+		armoredKeyRings = append(armoredKeyRings, string(data))
+	}
+
+	verified := map[string]bool{}
+	var errs []string
+
+	for _, image := range c.imagesInOrder {
+		for _, s := range image.GetStages() {
+			for _, gitPath := range s.GetGitPaths() {
+				commit, err := gitPath.LatestCommit()
+				if err != nil {
+					return err
+				}
+
+				repo := gitPath.GitRepo()
+
+				key := fmt.Sprintf("%s:%s", repo.GetName(), commit)
+				if verified[key] {
+					continue
+				}
+				verified[key] = true
+
+				signer, err := repo.VerifyCommitSignature(commit, armoredKeyRings)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("git repo %s: %s", repo.String(), err))
+					continue
+				}
+
+				fmt.Printf("# Verified commit %s of git repo %s signed by %s\n", commit, repo.String(), signer)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("commit signature verification failed:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// getCommitSignatureKeyringsPaths returns keyrings from werf.yaml relative to the project dir and keyrings set by SetCommitSignatureKeyrings
+func (c *Conveyor) getCommitSignatureKeyringsPaths() []string {
+	var paths []string
+
+	for _, path := range c.werfConfig.Meta.Build.CommitSignatureKeyrings {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.projectDir, path)
+		}
+		paths = append(paths, path)
+	}
+
+	return append(paths, c.commitSignatureKeyrings...)
+}
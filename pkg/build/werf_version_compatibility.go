@@ -0,0 +1,44 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/flant/werf/pkg/werf"
+)
+
+const (
+	// MinCompatibleWerfVersion is the oldest werf version, which stages can be used by the current werf version and
+	// which can use stages built by the current werf version. Should be increased with incompatible changes of stages,
+	// which do not change signatures (BuildCacheVersion).
+	MinCompatibleWerfVersion = "v1.0.0-alpha.1"
+
+	WerfMinCompatibleVersionLabel = "werf-min-compatible-version"
+)
+
+// stageWerfVersionIncompatibility returns reason why the stage built by another werf version cannot be used or empty string.
+// Stages of development builds and stages without version labels are considered compatible.
+func stageWerfVersionIncompatibility(labels map[string]string) string {
+	currentVersion, err := semver.NewVersion(werf.Version)
+	if err != nil {
+		return ""
+	}
+
+	minCompatibleVersion, err := semver.NewVersion(MinCompatibleWerfVersion)
+	if err != nil {
+		panic(err)
+	}
+
+	stageVersion, err := semver.NewVersion(labels[WerfVersionLabel])
+	if err == nil && stageVersion.LessThan(minCompatibleVersion) {
+		return fmt.Sprintf("stage has been built by werf %s, werf %s requires stages built by werf >= %s", labels[WerfVersionLabel], werf.Version, MinCompatibleWerfVersion)
+	}
+
+	stageMinCompatibleVersion, err := semver.NewVersion(labels[WerfMinCompatibleVersionLabel])
+	if err == nil && currentVersion.LessThan(stageMinCompatibleVersion) {
+		return fmt.Sprintf("stage has been built by werf %s and requires werf >= %s, current werf version is %s", labels[WerfVersionLabel], labels[WerfMinCompatibleVersionLabel], werf.Version)
+	}
+
+	return ""
+}
@@ -0,0 +1,74 @@
+package ci_env
+
+import (
+	"os"
+	"strings"
+)
+
+// RegistryCredentials are docker registry credentials provided by CI system
+type RegistryCredentials struct {
+	Username, Password string
+}
+
+// System describes CI system, which werf is running in
+type System interface {
+	Name() string
+	IsDetected() bool
+
+	// Registry returns address of the CI system docker registry or empty string
+	Registry() string
+	// RegistryCredentials returns credentials to login into the repo or nil if CI system does not provide them
+	RegistryCredentials(repo string) *RegistryCredentials
+	// Envs returns recommended werf envs, which are derived from the CI system envs
+	Envs() map[string]string
+}
+
+var systems = []System{&githubActions{}, &circleCI{}, &gitlabCI{}}
+
+// Register adds CI system, registered systems are detected before the builtin ones
+func Register(s System) {
+	systems = append([]System{s}, systems...)
+}
+
+// Detect returns the first detected CI system or nil
+func Detect() System {
+	for _, s := range systems {
+		if s.IsDetected() {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// GetRegistry returns registry of the detected CI system or empty string
+func GetRegistry() string {
+	if s := Detect(); s != nil {
+		return s.Registry()
+	}
+
+	return ""
+}
+
+// GetRegistryCredentials returns generic WERF_REGISTRY_USERNAME and WERF_REGISTRY_PASSWORD credentials if specified,
+// otherwise credentials of the detected CI system (can be disabled with WERF_IGNORE_CI_DOCKER_AUTOLOGIN)
+func GetRegistryCredentials(repo string) *RegistryCredentials {
+	username, password := os.Getenv("WERF_REGISTRY_USERNAME"), os.Getenv("WERF_REGISTRY_PASSWORD")
+	if username != "" && password != "" {
+		return &RegistryCredentials{Username: username, Password: password}
+	}
+
+	if os.Getenv("WERF_IGNORE_CI_DOCKER_AUTOLOGIN") != "" {
+		return nil
+	}
+
+	if s := Detect(); s != nil {
+		return s.RegistryCredentials(repo)
+	}
+
+	return nil
+}
+
+func isRepoInRegistry(repo, registry string) bool {
+	return repo == registry || strings.HasPrefix(repo, registry+"/")
+}
@@ -0,0 +1,120 @@
+package ci_env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type gitlabCI struct{}
+
+func (s *gitlabCI) Name() string {
+	return "GitLab CI"
+}
+
+func (s *gitlabCI) IsDetected() bool {
+	return os.Getenv("GITLAB_CI") != "" || (os.Getenv("CI_REGISTRY") != "" && os.Getenv("CI_JOB_TOKEN") != "")
+}
+
+func (s *gitlabCI) Registry() string {
+	return os.Getenv("CI_REGISTRY")
+}
+
+func (s *gitlabCI) RegistryCredentials(_ string) *RegistryCredentials {
+	ciRegistryEnv := os.Getenv("CI_REGISTRY")
+	ciJobTokenEnv := os.Getenv("CI_JOB_TOKEN")
+	if ciRegistryEnv != "" && ciJobTokenEnv != "" {
+		return &RegistryCredentials{Username: "gitlab-ci-token", Password: ciJobTokenEnv}
+	}
+
+	return nil
+}
+
+// Envs is empty, because werf uses GitLab CI envs natively
+func (s *gitlabCI) Envs() map[string]string {
+	return map[string]string{}
+}
+
+const githubRegistry = "ghcr.io"
+
+type githubActions struct{}
+
+func (s *githubActions) Name() string {
+	return "GitHub Actions"
+}
+
+func (s *githubActions) IsDetected() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+func (s *githubActions) Registry() string {
+	return githubRegistry
+}
+
+// RegistryCredentials returns GITHUB_TOKEN credentials only for GitHub container registry,
+// the token should be passed to the job env explicitly
+func (s *githubActions) RegistryCredentials(repo string) *RegistryCredentials {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" || !isRepoInRegistry(repo, githubRegistry) {
+		return nil
+	}
+
+	return &RegistryCredentials{Username: os.Getenv("GITHUB_ACTOR"), Password: token}
+}
+
+func (s *githubActions) Envs() map[string]string {
+	envs := map[string]string{}
+
+	if repository := os.Getenv("GITHUB_REPOSITORY"); repository != "" {
+		envs["CI_REGISTRY_IMAGE"] = fmt.Sprintf("%s/%s", githubRegistry, strings.ToLower(repository))
+	}
+
+	ref := os.Getenv("GITHUB_REF")
+	switch {
+	case strings.HasPrefix(ref, "refs/tags/"):
+		envs["CI_COMMIT_TAG"] = strings.TrimPrefix(ref, "refs/tags/")
+	case strings.HasPrefix(ref, "refs/heads/"):
+		envs["CI_COMMIT_REF_NAME"] = strings.TrimPrefix(ref, "refs/heads/")
+	}
+
+	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+		envs["CI_BUILD_ID"] = runID
+	}
+
+	return envs
+}
+
+type circleCI struct{}
+
+func (s *circleCI) Name() string {
+	return "CircleCI"
+}
+
+func (s *circleCI) IsDetected() bool {
+	return os.Getenv("CIRCLECI") == "true"
+}
+
+// Registry is empty, because CircleCI does not provide docker registry
+func (s *circleCI) Registry() string {
+	return ""
+}
+
+func (s *circleCI) RegistryCredentials(_ string) *RegistryCredentials {
+	return nil
+}
+
+func (s *circleCI) Envs() map[string]string {
+	envs := map[string]string{}
+
+	if tag := os.Getenv("CIRCLE_TAG"); tag != "" {
+		envs["CI_COMMIT_TAG"] = tag
+	} else if branch := os.Getenv("CIRCLE_BRANCH"); branch != "" {
+		envs["CI_COMMIT_REF_NAME"] = branch
+	}
+
+	if buildNum := os.Getenv("CIRCLE_BUILD_NUM"); buildNum != "" {
+		envs["CI_BUILD_ID"] = buildNum
+	}
+
+	return envs
+}
@@ -6,9 +6,11 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/werf/pkg/docker_registry"
@@ -20,7 +22,22 @@ import (
 type CleanupOptions struct {
 	CommonRepoOptions CommonRepoOptions
 	LocalRepo         GitRepo
-	WithoutKube       bool
+	Policies          CleanupPolicies
+
+	WithoutKube bool
+	// KubeContexts are kubeconfig contexts of clusters, which are queried for deployed images, the current context is queried if empty
+	KubeContexts []string
+	// AllKubeContexts queries clusters of all kubeconfig contexts for deployed images
+	AllKubeContexts bool
+}
+
+// CleanupPolicies overrides default policies values, nil value means default.
+// Policies environment variables take precedence over these values.
+type CleanupPolicies struct {
+	GitTagsExpiryDatePeriod    *int64
+	GitTagsLimit               *int64
+	GitCommitsExpiryDatePeriod *int64
+	GitCommitsLimit            *int64
 }
 
 const (
@@ -38,8 +55,9 @@ func Cleanup(options CleanupOptions) error {
 		}
 
 		if options.LocalRepo != nil {
+			var deployedRepoImages []docker_registry.RepoImage
 			if !options.WithoutKube {
-				repoImages, err = exceptRepoImagesByWhitelist(repoImages)
+				repoImages, deployedRepoImages, err = exceptRepoImagesByWhitelist(repoImages, options.KubeContexts, options.AllKubeContexts)
 				if err != nil {
 					return err
 				}
@@ -54,6 +72,9 @@ func Cleanup(options CleanupOptions) error {
 			if err != nil {
 				return err
 			}
+
+			// stages of deployed images should be kept too
+			repoImages = append(repoImages, deployedRepoImages...)
 		}
 
 		if err := repoImageStagesSyncByRepoImages(repoImages, options.CommonRepoOptions); err != nil {
@@ -70,25 +91,26 @@ func Cleanup(options CleanupOptions) error {
 	return nil
 }
 
-func exceptRepoImagesByWhitelist(repoImages []docker_registry.RepoImage) ([]docker_registry.RepoImage, error) {
+// exceptRepoImagesByWhitelist splits repo images into images to process and images, which are used in kubernetes clusters by tag or by digest
+func exceptRepoImagesByWhitelist(repoImages []docker_registry.RepoImage, kubeContexts []string, allKubeContexts bool) ([]docker_registry.RepoImage, []docker_registry.RepoImage, error) {
 	var newRepoImages, exceptedRepoImages []docker_registry.RepoImage
 
-	deployedDockerImages, err := deployedDockerImages()
+	deployedDockerImages, err := deployedDockerImagesInContexts(kubeContexts, allKubeContexts)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get deployed images: %s", err)
+		return nil, nil, fmt.Errorf("cannot get deployed images: %s", err)
 	}
 
-Loop:
 	for _, repoImage := range repoImages {
-		imageName := fmt.Sprintf("%s:%s", repoImage.Repository, repoImage.Tag)
-		for _, deployedDockerImage := range deployedDockerImages {
-			if deployedDockerImage == imageName {
-				exceptedRepoImages = append(exceptedRepoImages, repoImage)
-				continue Loop
-			}
+		isDeployed, err := isRepoImageDeployed(repoImage, deployedDockerImages)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		newRepoImages = append(newRepoImages, repoImage)
+		if isDeployed {
+			exceptedRepoImages = append(exceptedRepoImages, repoImage)
+		} else {
+			newRepoImages = append(newRepoImages, repoImage)
+		}
 	}
 
 	if len(exceptedRepoImages) != 0 {
@@ -100,7 +122,49 @@ Loop:
 		fmt.Println()
 	}
 
-	return newRepoImages, nil
+	return newRepoImages, exceptedRepoImages, nil
+}
+
+// isRepoImageDeployed checks deployed images references REPOSITORY:TAG, REPOSITORY@DIGEST and REPOSITORY:TAG@DIGEST,
+// digest of the repo image is requested only if the repository is deployed by digest
+func isRepoImageDeployed(repoImage docker_registry.RepoImage, deployedDockerImages []string) (bool, error) {
+	imageName := fmt.Sprintf("%s:%s", repoImage.Repository, repoImage.Tag)
+
+	var repoImageDigest string
+	for _, deployedDockerImage := range deployedDockerImages {
+		parts := strings.SplitN(deployedDockerImage, "@", 2)
+		if parts[0] == imageName {
+			return true, nil
+		}
+
+		if len(parts) != 2 || deployedImageRepository(parts[0]) != repoImage.Repository {
+			continue
+		}
+
+		if repoImageDigest == "" {
+			digest, err := repoImage.Digest()
+			if err != nil {
+				return false, fmt.Errorf("cannot get image %s digest: %s", imageName, err)
+			}
+
+			repoImageDigest = digest.String()
+		}
+
+		if parts[1] == repoImageDigest {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// deployedImageRepository trims tag of the image reference, registry host can contain port
+func deployedImageRepository(reference string) string {
+	if ind := strings.LastIndex(reference, ":"); ind > strings.LastIndex(reference, "/") {
+		return reference[:ind]
+	}
+
+	return reference
 }
 
 func repoImagesCleanupByNonexistentGitPrimitive(repoImages []docker_registry.RepoImage, options CleanupOptions) ([]docker_registry.RepoImage, error) {
@@ -216,8 +280,8 @@ func repoImagesCleanupByPolicies(repoImages []docker_registry.RepoImage, options
 	}
 
 	cleanupByPolicyOptions := repoImagesCleanupByPolicyOptions{
-		expiryDatePeriod:  gitTagsExpiryDatePeriodPolicyValue(),
-		expiryLimit:       gitTagsLimitPolicyValue(),
+		expiryDatePeriod:  gitTagsExpiryDatePeriodPolicyValue(options.Policies),
+		expiryLimit:       gitTagsLimitPolicyValue(options.Policies),
 		gitPrimitive:      "tag",
 		commonRepoOptions: options.CommonRepoOptions,
 	}
@@ -229,8 +293,8 @@ func repoImagesCleanupByPolicies(repoImages []docker_registry.RepoImage, options
 	}
 
 	cleanupByPolicyOptions = repoImagesCleanupByPolicyOptions{
-		expiryDatePeriod:  gitCommitsExpiryDatePeriodPolicyValue(),
-		expiryLimit:       gitCommitsLimitPolicyValue(),
+		expiryDatePeriod:  gitCommitsExpiryDatePeriodPolicyValue(options.Policies),
+		expiryLimit:       gitCommitsLimitPolicyValue(options.Policies),
 		gitPrimitive:      "commit",
 		commonRepoOptions: options.CommonRepoOptions,
 	}
@@ -243,33 +307,37 @@ func repoImagesCleanupByPolicies(repoImages []docker_registry.RepoImage, options
 	return repoImages, nil
 }
 
-func gitTagsExpiryDatePeriodPolicyValue() int64 {
-	return policyValue("WERF_GIT_TAGS_EXPIRY_DATE_PERIOD_POLICY", gitTagsExpiryDatePeriodPolicy)
+func gitTagsExpiryDatePeriodPolicyValue(policies CleanupPolicies) int64 {
+	return policyValue("WERF_GIT_TAGS_EXPIRY_DATE_PERIOD_POLICY", policies.GitTagsExpiryDatePeriod, gitTagsExpiryDatePeriodPolicy)
 }
 
-func gitTagsLimitPolicyValue() int64 {
-	return policyValue("WERF_GIT_TAGS_LIMIT_POLICY", gitTagsLimitPolicy)
+func gitTagsLimitPolicyValue(policies CleanupPolicies) int64 {
+	return policyValue("WERF_GIT_TAGS_LIMIT_POLICY", policies.GitTagsLimit, gitTagsLimitPolicy)
 }
 
-func gitCommitsExpiryDatePeriodPolicyValue() int64 {
-	return policyValue("WERF_GIT_COMMITS_EXPIRY_DATE_PERIOD_POLICY", gitCommitsExpiryDatePeriodPolicy)
+func gitCommitsExpiryDatePeriodPolicyValue(policies CleanupPolicies) int64 {
+	return policyValue("WERF_GIT_COMMITS_EXPIRY_DATE_PERIOD_POLICY", policies.GitCommitsExpiryDatePeriod, gitCommitsExpiryDatePeriodPolicy)
 }
 
-func gitCommitsLimitPolicyValue() int64 {
-	return policyValue("WERF_GIT_COMMITS_LIMIT_POLICY", gitCommitsLimitPolicy)
+func gitCommitsLimitPolicyValue(policies CleanupPolicies) int64 {
+	return policyValue("WERF_GIT_COMMITS_LIMIT_POLICY", policies.GitCommitsLimit, gitCommitsLimitPolicy)
 }
 
-func policyValue(envKey string, defaultValue int64) int64 {
+func policyValue(envKey string, value *int64, defaultValue int64) int64 {
 	envValue := os.Getenv(envKey)
 	if envValue != "" {
-		value, err := strconv.ParseInt(envValue, 10, 64)
+		envIntValue, err := strconv.ParseInt(envValue, 10, 64)
 		if err != nil {
 			logger.LogWarningF("WARNING: '%s' value '%s' is ignored (using default value '%s'\n", envKey, envValue, defaultValue)
 		} else {
-			return value
+			return envIntValue
 		}
 	}
 
+	if value != nil {
+		return *value
+	}
+
 	return defaultValue
 }
 
@@ -341,6 +409,45 @@ func repoImagesCleanupByPolicy(repoImages, repoImagesWithScheme []docker_registr
 	return repoImages, nil
 }
 
+// deployedDockerImagesInContexts gathers images used in the clusters of specified kubeconfig contexts, all contexts of kubeconfig or the current context.
+// Cleanup fails if some cluster is not available, because images used in this cluster could be deleted.
+func deployedDockerImagesInContexts(kubeContexts []string, allKubeContexts bool) ([]string, error) {
+	if allKubeContexts {
+		kubeContexts = nil
+
+		kubeConfig, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load kubeconfig: %s", err)
+		}
+
+		for kubeContext := range kubeConfig.Contexts {
+			kubeContexts = append(kubeContexts, kubeContext)
+		}
+		sort.Strings(kubeContexts)
+	}
+
+	// current context, in-cluster config or kubeconfig without contexts
+	if len(kubeContexts) == 0 {
+		kubeContexts = []string{""}
+	}
+
+	var images []string
+	for _, kubeContext := range kubeContexts {
+		if err := kube.Init(kube.InitOptions{KubeContext: kubeContext}); err != nil {
+			return nil, fmt.Errorf("cannot initialize kubernetes context '%s': %s", kubeContext, err)
+		}
+
+		contextImages, err := deployedDockerImages()
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes context '%s': %s", kubeContext, err)
+		}
+
+		images = append(images, contextImages...)
+	}
+
+	return images, nil
+}
+
 func deployedDockerImages() ([]string, error) {
 	var deployedDockerImages []string
 
@@ -411,9 +518,17 @@ func getPodsImages() ([]string, error) {
 	}
 
 	for _, pod := range list.Items {
-		for _, container := range pod.Spec.Containers {
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
 			images = append(images, container.Image)
 		}
+
+		// running containers could use image, which tag has been moved since pod creation
+		for _, containerStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			imageID := strings.TrimPrefix(containerStatus.ImageID, "docker-pullable://")
+			if strings.Contains(imageID, "@") {
+				images = append(images, imageID)
+			}
+		}
 	}
 
 	return images, nil
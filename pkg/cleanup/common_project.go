@@ -9,8 +9,10 @@ import (
 )
 
 type CommonProjectOptions struct {
-	ProjectName   string
-	CommonOptions CommonOptions
+	ProjectName string
+	// StagesNamespace is a name of the local stages cache, project name is used if not specified
+	StagesNamespace string
+	CommonOptions   CommonOptions
 }
 
 func projectCleanup(options CommonProjectOptions) error {
@@ -27,8 +29,13 @@ func projectCleanup(options CommonProjectOptions) error {
 	return nil
 }
 
+// projectImageStageFilterSet selects stages of the project in the project stages namespace,
+// stages built by other projects, which share the namespace, are not selected
 func projectImageStageFilterSet(options CommonProjectOptions) filters.Args {
 	filterSet := projectFilterSet(options)
+	if options.StagesNamespace != "" {
+		filterSet.Add("label", stagesNamespaceLabel(options))
+	}
 	filterSet.Add("reference", stageCacheReference(options))
 	return filterSet
 }
@@ -40,9 +47,25 @@ func projectFilterSet(options CommonProjectOptions) filters.Args {
 }
 
 func werfLabel(options CommonProjectOptions) string {
-	return fmt.Sprintf("werf=%s", options.ProjectName)
+	return fmt.Sprintf("%s=%s", build.WerfProjectLabel, options.ProjectName)
+}
+
+func stagesNamespaceLabel(options CommonProjectOptions) string {
+	return fmt.Sprintf("%s=%s", build.WerfStagesNamespaceLabel, stagesNamespace(options))
 }
 
 func stageCacheReference(options CommonProjectOptions) string {
-	return fmt.Sprintf(build.LocalImageStageImageNameFormat, options.ProjectName)
+	return build.LocalImageStageImageName(stagesNamespace(options))
+}
+
+func stagesNamespace(options CommonProjectOptions) string {
+	if options.StagesNamespace != "" {
+		return options.StagesNamespace
+	}
+	return options.ProjectName
+}
+
+// stagesLockName is a lock of the stages cache, which is held by builds in read-only mode
+func stagesLockName(options CommonProjectOptions) string {
+	return fmt.Sprintf("%s.images", stagesNamespace(options))
 }
@@ -5,38 +5,64 @@ import (
 	"strings"
 
 	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/util"
 )
 
 type CommonRepoOptions struct {
 	Repository  string
 	ImagesNames []string
 	DryRun      bool
+	// DiscoverImagesRepos adds repositories REPO/IMAGE_NAME found in the registry catalog,
+	// so images, which are not described in werf.yaml anymore, are processed as well
+	DiscoverImagesRepos bool
 }
 
 func repoImages(options CommonRepoOptions) ([]docker_registry.RepoImage, error) {
 	var repoImages []docker_registry.RepoImage
 
-	isNamelessImage := len(options.ImagesNames) == 0
-	if isNamelessImage {
-		namelessImages, err := docker_registry.ImagesByWerfImageLabel(options.Repository, "true")
+	repositories, err := imagesRepositories(options)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repository := range repositories {
+		images, err := docker_registry.ImagesByWerfImageLabel(repository, "true")
 		if err != nil {
 			return nil, err
 		}
 
-		repoImages = append(repoImages, namelessImages...)
+		repoImages = append(repoImages, images...)
+	}
+
+	return repoImages, nil
+}
+
+func imagesRepositories(options CommonRepoOptions) ([]string, error) {
+	var repositories []string
+
+	isNamelessImage := len(options.ImagesNames) == 0
+	if isNamelessImage {
+		repositories = append(repositories, options.Repository)
 	} else {
 		for _, imageName := range options.ImagesNames {
-			repository := fmt.Sprintf("%s/%s", options.Repository, imageName)
-			images, err := docker_registry.ImagesByWerfImageLabel(repository, "true")
-			if err != nil {
-				return nil, err
-			}
+			repositories = append(repositories, fmt.Sprintf("%s/%s", options.Repository, imageName))
+		}
+	}
 
-			repoImages = append(repoImages, images...)
+	if options.DiscoverImagesRepos {
+		discoveredRepositories, err := docker_registry.ImagesRepositories(options.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("cannot discover images repositories of %s: %s", options.Repository, err)
+		}
+
+		for _, repository := range discoveredRepositories {
+			if !util.IsStringsContainValue(repositories, repository) {
+				repositories = append(repositories, repository)
+			}
 		}
 	}
 
-	return repoImages, nil
+	return repositories, nil
 }
 
 func repoImageStagesImages(options CommonRepoOptions) ([]docker_registry.RepoImage, error) {
@@ -1,7 +1,6 @@
 package cleanup
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/flant/werf/pkg/lock"
@@ -30,7 +29,7 @@ func RepoImagesFlush(withImages bool, options CommonRepoOptions) error {
 }
 
 func ProjectImagesFlush(withImages bool, options CommonProjectOptions) error {
-	projectImagesLockName := fmt.Sprintf("%s.images", options.ProjectName)
+	projectImagesLockName := stagesLockName(options)
 	err := lock.WithLock(projectImagesLockName, lock.LockOptions{Timeout: time.Second * 600}, func() error {
 		if withImages {
 			if err := projectImagesFlush(options); err != nil {
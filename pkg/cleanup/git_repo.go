@@ -1,7 +1,10 @@
 package cleanup
 
+import "time"
+
 type GitRepo interface {
 	IsCommitExists(commit string) (bool, error)
 	TagsList() ([]string, error)
 	RemoteBranchesList() ([]string, error)
+	RecentCommitsList(since time.Time) ([]string, error)
 }
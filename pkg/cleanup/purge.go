@@ -0,0 +1,81 @@
+package cleanup
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+)
+
+type ProjectPurgeOptions struct {
+	// CommonRepoOptions.Repository is optional: images are not deleted from the registry if it is not specified
+	CommonRepoOptions    CommonRepoOptions
+	CommonProjectOptions CommonProjectOptions
+	// ProjectBuildDir contains remote git repos clones of the project made by older werf versions
+	ProjectBuildDir string
+	// WorkTreeDirs are git work trees of the project repos in werf home
+	WorkTreeDirs []string
+}
+
+// ProjectPurge deletes all project images and stages from the registry and local Docker storage,
+// project files from werf home and released tmp dirs
+func ProjectPurge(options ProjectPurgeOptions) error {
+	if options.CommonRepoOptions.Repository != "" {
+		if err := RepoImagesFlush(true, options.CommonRepoOptions); err != nil {
+			return err
+		}
+	}
+
+	if err := ProjectImagesFlush(true, options.CommonProjectOptions); err != nil {
+		return err
+	}
+
+	dryRun := options.CommonProjectOptions.CommonOptions.DryRun
+
+	if err := git_repo.RemoveProjectLegacyClones(options.ProjectBuildDir, dryRun); err != nil {
+		return err
+	}
+
+	if err := removeProjectDir(options.ProjectBuildDir, dryRun); err != nil {
+		return err
+	}
+
+	for _, workTreeDir := range options.WorkTreeDirs {
+		err := lock.WithLock(git_repo.WorkTreeLockName(workTreeDir), lock.LockOptions{Timeout: time.Second * 600}, func() error {
+			return removeProjectDir(workTreeDir, dryRun)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !dryRun {
+		if err := project_tmp_dir.GC(); err != nil {
+			return fmt.Errorf("project tmp dir gc failed: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func removeProjectDir(dir string, dryRun bool) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println(dir)
+		return nil
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("unable to remove %s: %s", dir, err)
+	}
+
+	return nil
+}
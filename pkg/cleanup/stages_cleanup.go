@@ -0,0 +1,184 @@
+package cleanup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/util"
+)
+
+type StagesCleanupOptions struct {
+	// CommonRepoOptions describes repo of final images, stages of these images are kept (Repository is optional)
+	CommonRepoOptions CommonRepoOptions
+	// StagesRepo is a stages repo to cleanup besides local stages (optional)
+	StagesRepo string
+	// LocalRepo is used to keep stages, which have been built for recent commits (optional)
+	LocalRepo GitRepo
+	// GitCommitsPeriod is a period of recent commits, which stages are kept
+	GitCommitsPeriod time.Duration
+	// KeepPeriod is a period, in which stages are kept after creation regardless of final images and commits
+	KeepPeriod time.Duration
+}
+
+// StagesCleanup removes local stages and stages from the stages repo, which are not used by final images
+// (local or published into the images repo) and have not been built for recent commits.
+// Project images lock is held to prevent concurrent builds, which could use removed stages.
+func StagesCleanup(commonProjectOptions CommonProjectOptions, options StagesCleanupOptions) error {
+	projectImagesLockName := stagesLockName(commonProjectOptions)
+	return lock.WithLock(projectImagesLockName, lock.LockOptions{Timeout: time.Second * 600}, func() error {
+		if options.CommonRepoOptions.Repository == "" {
+			return stagesCleanup(commonProjectOptions, options)
+		}
+
+		return lock.WithLock(options.CommonRepoOptions.Repository, lock.LockOptions{ReadOnly: true, Timeout: time.Second * 600}, func() error {
+			return stagesCleanup(commonProjectOptions, options)
+		})
+	})
+}
+
+func stagesCleanup(commonProjectOptions CommonProjectOptions, options StagesCleanupOptions) error {
+	var finalImagesParentIds []string
+
+	if options.CommonRepoOptions.Repository != "" {
+		repoImages, err := repoImages(options.CommonRepoOptions)
+		if err != nil {
+			return err
+		}
+
+		for _, repoImage := range repoImages {
+			parentId, err := repoImageParentId(repoImage)
+			if err != nil {
+				return err
+			}
+
+			finalImagesParentIds = append(finalImagesParentIds, parentId)
+		}
+	}
+
+	filterSet := projectFilterSet(commonProjectOptions)
+	filterSet.Add("label", "werf-image=true")
+	images, err := werfImagesByFilterSet(filterSet)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		finalImagesParentIds = append(finalImagesParentIds, image.ParentID)
+	}
+
+	var recentCommits []string
+	if options.LocalRepo != nil && options.GitCommitsPeriod > 0 {
+		recentCommits, err = options.LocalRepo.RecentCommitsList(time.Now().Add(-options.GitCommitsPeriod))
+		if err != nil {
+			return fmt.Errorf("unable to get recent commits: %s", err)
+		}
+	}
+
+	if err := projectImageStagesCleanup(commonProjectOptions, finalImagesParentIds, recentCommits, options.KeepPeriod); err != nil {
+		return err
+	}
+
+	if options.StagesRepo != "" {
+		stagesRepoOptions := CommonRepoOptions{
+			Repository: options.StagesRepo,
+			DryRun:     commonProjectOptions.CommonOptions.DryRun,
+		}
+
+		err := lock.WithLock(options.StagesRepo, lock.LockOptions{Timeout: time.Second * 600}, func() error {
+			return repoImageStagesCleanup(stagesRepoOptions, finalImagesParentIds, recentCommits, options.KeepPeriod)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func projectImageStagesCleanup(commonProjectOptions CommonProjectOptions, finalImagesParentIds, recentCommits []string, keepPeriod time.Duration) error {
+	imageStages, err := projectImageStages(commonProjectOptions)
+	if err != nil {
+		return err
+	}
+
+	for _, parentId := range finalImagesParentIds {
+		imageStages, err = exceptImageStagesByImageId(imageStages, parentId, commonProjectOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, imageStage := range append([]types.ImageSummary{}, imageStages...) {
+		if isStageOfCommits(imageStage.Labels, recentCommits) {
+			imageStages, err = exceptImageStagesByImageStage(imageStages, imageStage, commonProjectOptions)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, imageStage := range imageStages {
+		if time.Since(time.Unix(imageStage.Created, 0)) < keepPeriod {
+			imageStages = exceptImage(imageStages, imageStage)
+		}
+	}
+
+	return imagesRemove(imageStages, commonProjectOptions.CommonOptions)
+}
+
+func repoImageStagesCleanup(options CommonRepoOptions, finalImagesParentIds, recentCommits []string, keepPeriod time.Duration) error {
+	repoImageStages, err := repoImageStagesImages(options)
+	if err != nil {
+		return err
+	}
+
+	for _, parentId := range finalImagesParentIds {
+		repoImageStages, err = exceptRepoImageStagesByImageId(repoImageStages, parentId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, repoImageStage := range append([]docker_registry.RepoImage{}, repoImageStages...) {
+		labels, err := repoImageLabels(repoImageStage)
+		if err != nil {
+			return err
+		}
+
+		if isStageOfCommits(labels, recentCommits) {
+			repoImageStages, err = exceptRepoImageStagesByRepoImageStage(repoImageStages, repoImageStage)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, repoImageStage := range append([]docker_registry.RepoImage{}, repoImageStages...) {
+		created, err := repoImageCreated(repoImageStage)
+		if err != nil {
+			return err
+		}
+
+		if time.Since(created) < keepPeriod {
+			repoImageStages = exceptRepoImages(repoImageStages, repoImageStage)
+		}
+	}
+
+	return repoImagesRemove(repoImageStages, options)
+}
+
+// isStageOfCommits checks git commits labels of the stage, which are inherited from the previous stages
+func isStageOfCommits(labels map[string]string, commits []string) bool {
+	for label, commit := range labels {
+		if strings.HasPrefix(label, "werf-git-") && strings.HasSuffix(label, "-commit") && util.IsStringsContainValue(commits, commit) {
+			return true
+		}
+	}
+
+	return false
+}
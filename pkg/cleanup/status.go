@@ -0,0 +1,129 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// DirStatus describes directory of werf home or tmp dir and its disk usage
+type DirStatus struct {
+	Path string
+	Size int64
+}
+
+// HostStatus summarizes werf data of the host, which can be removed by cleanup commands
+type HostStatus struct {
+	StagesImages     int
+	StagesImagesSize int64
+	// StagesByImageName counts stages by werf-image-name label, stages built by werf versions without the label are counted by empty name
+	StagesByImageName map[string]int
+
+	WorkTrees      []DirStatus
+	RemoteGitRepos []DirStatus
+	TmpDirs        []DirStatus
+
+	Locks []*lock.LockInfo
+}
+
+// GetHostStatus collects host status, project stages images are counted only if options.ProjectName is specified
+func GetHostStatus(options CommonProjectOptions) (*HostStatus, error) {
+	status := &HostStatus{}
+
+	if options.ProjectName != "" {
+		images, err := projectImageStages(options)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list project stages images: %s", err)
+		}
+
+		status.StagesImages = len(images)
+		status.StagesByImageName = map[string]int{}
+		for _, image := range images {
+			status.StagesImagesSize += image.Size
+
+			imageName, ok := image.Labels[build.WerfImageNameLabel]
+			if ok && imageName == "" {
+				imageName = "~"
+			}
+			status.StagesByImageName[imageName]++
+		}
+	}
+
+	var err error
+
+	status.WorkTrees, err = dirsStatus(filepath.Join(git_repo.GetBaseWorkTreeDir(), "*"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get worktrees status: %s", err)
+	}
+
+	status.RemoteGitRepos, err = dirsStatus(filepath.Join(git_repo.GetClonesDir(), "*"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get remote git repos status: %s", err)
+	}
+
+	status.TmpDirs, err = tmpDirsStatus()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tmp dirs status: %s", err)
+	}
+
+	status.Locks, err = lock.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list locks: %s", err)
+	}
+
+	return status, nil
+}
+
+func dirsStatus(pattern string) ([]DirStatus, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return pathsStatus(paths)
+}
+
+// tmpDirsStatus returns werf files of tmp dir, the same files are removed by gc
+func tmpDirsStatus() ([]DirStatus, error) {
+	tmpFiles, err := ioutil.ReadDir(werf.GetTmpDir())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tmp files in %s: %s", werf.GetTmpDir(), err)
+	}
+
+	var paths []string
+	for _, finfo := range tmpFiles {
+		if strings.HasPrefix(finfo.Name(), "werf") {
+			paths = append(paths, filepath.Join(werf.GetTmpDir(), finfo.Name()))
+		}
+	}
+
+	return pathsStatus(paths)
+}
+
+func pathsStatus(paths []string) ([]DirStatus, error) {
+	var res []DirStatus
+	for _, path := range paths {
+		size, err := util.DirSize(path)
+		if err != nil {
+			if os.IsPermission(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to calculate size of %s: %s", path, err)
+		}
+
+		res = append(res, DirStatus{Path: path, Size: size})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+
+	return res, nil
+}
@@ -9,17 +9,25 @@ import (
 	"github.com/docker/docker/api/types"
 
 	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
 )
 
 const syncIgnoreProjectImageStagePeriod = 2 * 60 * 60
 
 func ProjectImageStagesSync(commonProjectOptions CommonProjectOptions, commonRepoOptions CommonRepoOptions) error {
-	projectImagesLockName := fmt.Sprintf("%s.images", commonProjectOptions.ProjectName)
+	projectImagesLockName := stagesLockName(commonProjectOptions)
 	err := lock.WithLock(projectImagesLockName, lock.LockOptions{Timeout: time.Second * 600}, func() error {
 		if commonRepoOptions.Repository != "" {
 			err := lock.WithLock(commonRepoOptions.Repository, lock.LockOptions{ReadOnly: true, Timeout: time.Second * 600}, func() error {
+				if err := projectImagesSyncByRepoImages(commonProjectOptions, commonRepoOptions); err != nil {
+					return err
+				}
+
 				if err := projectImageStagesSyncByRepoImages(commonProjectOptions, commonRepoOptions); err != nil {
 					return err
 				}
@@ -50,6 +58,78 @@ func ProjectImageStagesSync(commonProjectOptions CommonProjectOptions, commonRep
 	return nil
 }
 
+// ProjectImageStagesPushMissing pushes local stages cache, that doesn't exist in the Docker registry
+func ProjectImageStagesPushMissing(commonProjectOptions CommonProjectOptions, commonRepoOptions CommonRepoOptions) error {
+	projectImagesLockName := stagesLockName(commonProjectOptions)
+	return lock.WithLock(projectImagesLockName, lock.LockOptions{ReadOnly: true, Timeout: time.Second * 600}, func() error {
+		return lock.WithLock(commonRepoOptions.Repository, lock.LockOptions{Timeout: time.Second * 600}, func() error {
+			return projectImageStagesPushMissing(commonProjectOptions, commonRepoOptions)
+		})
+	})
+}
+
+func projectImageStagesPushMissing(commonProjectOptions CommonProjectOptions, commonRepoOptions CommonRepoOptions) error {
+	existingStagesTags, err := docker_registry.ImageStagesTags(commonRepoOptions.Repository)
+	if err != nil {
+		return err
+	}
+
+	imageStages, err := projectImageStages(commonProjectOptions)
+	if err != nil {
+		return err
+	}
+
+	stageCacheImagePrefix := fmt.Sprintf("%s:", stageCacheReference(commonProjectOptions))
+	for _, imageStage := range imageStages {
+		if imageStage.Labels[build.WerfCacheVersionLabel] != build.BuildCacheVersion {
+			continue
+		}
+
+		for _, imageStageName := range imageStage.RepoTags {
+			if !strings.HasPrefix(imageStageName, stageCacheImagePrefix) {
+				continue
+			}
+
+			signature := strings.TrimPrefix(imageStageName, stageCacheImagePrefix)
+			stageTagName := build.RepoImageStageTag(signature)
+			if util.IsStringsContainValue(existingStagesTags, stageTagName) {
+				continue
+			}
+
+			repoImageStageName := strings.Join([]string{commonRepoOptions.Repository, stageTagName}, ":")
+			fmt.Println(repoImageStageName)
+
+			if commonRepoOptions.DryRun {
+				continue
+			}
+
+			if err := pushImageAs(imageStageName, repoImageStageName); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func pushImageAs(localImageName, repoImageName string) error {
+	if err := docker.CliTag(localImageName, repoImageName); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := docker.CliRmi(repoImageName); err != nil {
+			logger.LogWarningF("WARNING: cannot remove temporary tag %s: %s\n", repoImageName, err)
+		}
+	}()
+
+	if err := docker.CliPush(werf.GetContext(), repoImageName); err != nil {
+		return fmt.Errorf("error pushing %s: %s", repoImageName, err)
+	}
+
+	return nil
+}
+
 func repoImageStagesSyncByRepoImages(repoImages []docker_registry.RepoImage, options CommonRepoOptions) error {
 	repoImageStages, err := repoImageStagesImages(options)
 	if err != nil {
@@ -190,7 +270,7 @@ func exceptRepoImageStagesBySignature(repoImageStages []docker_registry.RepoImag
 
 func findRepoImageStageBySignature(repoImageStages []docker_registry.RepoImage, signature string) (*docker_registry.RepoImage, error) {
 	for _, repoImageStage := range repoImageStages {
-		if repoImageStage.Tag == fmt.Sprintf(build.RepoImageStageTagFormat, signature) {
+		if repoImageStage.Tag == build.RepoImageStageTag(signature) {
 			return &repoImageStage, nil
 		}
 	}
@@ -225,6 +305,57 @@ func repoImageCreated(repoImage docker_registry.RepoImage) (time.Time, error) {
 	return configFile.Created.Time, nil
 }
 
+// projectImagesSyncByRepoImages removes local images tags of the repository, that have been deleted from the Docker registry
+func projectImagesSyncByRepoImages(commonProjectOptions CommonProjectOptions, commonRepoOptions CommonRepoOptions) error {
+	repoImages, err := repoImages(commonRepoOptions)
+	if err != nil {
+		return err
+	}
+
+	existingReferences := map[string]bool{}
+	for _, repoImage := range repoImages {
+		existingReferences[strings.Join([]string{repoImage.Repository, repoImage.Tag}, ":")] = true
+	}
+
+	var imagesRepositories []string
+	if len(commonRepoOptions.ImagesNames) == 0 {
+		imagesRepositories = append(imagesRepositories, commonRepoOptions.Repository)
+	} else {
+		for _, imageName := range commonRepoOptions.ImagesNames {
+			imagesRepositories = append(imagesRepositories, fmt.Sprintf("%s/%s", commonRepoOptions.Repository, imageName))
+		}
+	}
+
+	filterSet := projectFilterSet(commonProjectOptions)
+	filterSet.Add("label", "werf-image=true")
+	images, err := werfImagesByFilterSet(filterSet)
+	if err != nil {
+		return err
+	}
+
+	images, err = ignoreUsedImages(images)
+	if err != nil {
+		return err
+	}
+
+	var referencesToRemove []string
+	for _, image := range images {
+		for _, repoTag := range image.RepoTags {
+			for _, imagesRepository := range imagesRepositories {
+				if strings.HasPrefix(repoTag, fmt.Sprintf("%s:", imagesRepository)) && !existingReferences[repoTag] {
+					referencesToRemove = append(referencesToRemove, repoTag)
+				}
+			}
+		}
+	}
+
+	if err := imageReferencesRemove(referencesToRemove, commonProjectOptions.CommonOptions); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func projectImageStagesSyncByRepoImages(commonProjectOptions CommonProjectOptions, commonRepoOptions CommonRepoOptions) error {
 	repoImages, err := repoImages(commonRepoOptions)
 	if err != nil {
@@ -330,7 +461,7 @@ func findImageStageBySignature(imageStages []types.ImageSummary, signature strin
 }
 
 func stageCacheImage(signature string, options CommonProjectOptions) string {
-	return fmt.Sprintf(build.LocalImageStageImageFormat, options.ProjectName, signature)
+	return build.LocalImageStageImage(stagesNamespace(options), signature)
 }
 
 func findImageStageByImageId(imageStages []types.ImageSummary, imageId string) *types.ImageSummary {
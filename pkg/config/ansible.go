@@ -11,6 +11,9 @@ type Ansible struct {
 	BeforeSetupCacheVersion   string
 	SetupCacheVersion         string
 
+	// Requirements are ansible galaxy roles in requirements.yml format, which can be used by tasks with include_role and import_role modules
+	Requirements []map[string]interface{}
+
 	raw *rawAnsible
 }
 
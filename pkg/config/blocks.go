@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/flant/yaml.v2"
+)
+
+// defaultBlockName is a name of the block, which is merged into images and artifacts without blocks directive
+const defaultBlockName = "_default"
+
+// block is a named part of image config defined in the separate doc (block: NAME), which is merged into images and artifacts listing it in blocks directive
+type block struct {
+	Name    string
+	Content yaml.MapSlice
+	doc     *doc
+}
+
+func isBlockDoc(h map[string]interface{}) bool {
+	_, ok := h["block"]
+	return ok
+}
+
+func parseBlocks(docs []*doc) (map[string]*block, error) {
+	blocks := map[string]*block{}
+
+	for _, doc := range docs {
+		var raw yaml.MapSlice
+		if err := yaml.Unmarshal(doc.Content, &raw); err != nil {
+			return nil, newYamlUnmarshalError(err, doc)
+		}
+
+		var isBlock bool
+		for _, item := range raw {
+			if item.Key == "block" {
+				isBlock = true
+			}
+		}
+
+		if !isBlock {
+			continue
+		}
+
+		b := &block{doc: doc}
+		for _, item := range raw {
+			switch item.Key {
+			case "block":
+				name, ok := item.Value.(string)
+				if !ok || name == "" {
+					return nil, newDetailedConfigError("block name should be a non-empty string!", nil, doc)
+				}
+				b.Name = name
+			case "image", "artifact", "project", "blocks":
+				return nil, newDetailedConfigError(fmt.Sprintf("block cannot contain `%s` directive!", item.Key), nil, doc)
+			default:
+				b.Content = append(b.Content, item)
+			}
+		}
+
+		if _, exist := blocks[b.Name]; exist {
+			return nil, newDetailedConfigError(fmt.Sprintf("duplicate block `%s`!", b.Name), nil, doc)
+		}
+
+		blocks[b.Name] = b
+	}
+
+	return blocks, nil
+}
+
+// applyBlocks merges blocks listed in blocks directive (or _default block) into the image doc: maps are merged,
+// lists of the blocks and the image are concatenated, other values of the image replace values of the blocks
+func applyBlocks(imageDoc *doc, blocks map[string]*block) (*doc, error) {
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(imageDoc.Content, &raw); err != nil {
+		return nil, newYamlUnmarshalError(err, imageDoc)
+	}
+
+	var names []string
+	var isBlocksDefined bool
+	for _, item := range raw {
+		if item.Key != "blocks" {
+			continue
+		}
+
+		isBlocksDefined = true
+
+		rawNames, ok := item.Value.([]interface{})
+		if !ok && item.Value != nil {
+			return nil, newDetailedConfigError("blocks directive should be an array of block names!", nil, imageDoc)
+		}
+
+		for _, rawName := range rawNames {
+			name, ok := rawName.(string)
+			if !ok {
+				return nil, newDetailedConfigError("blocks directive should be an array of block names!", nil, imageDoc)
+			}
+			names = append(names, name)
+		}
+	}
+
+	if !isBlocksDefined {
+		if _, exist := blocks[defaultBlockName]; exist {
+			names = []string{defaultBlockName}
+		}
+	}
+
+	if len(names) == 0 {
+		return imageDoc, nil
+	}
+
+	// lines tree is merged the same way as the content to keep locations of the merged content lines
+	var merged, mergedLines yaml.MapSlice
+	for _, name := range names {
+		b, exist := blocks[name]
+		if !exist {
+			return nil, newDetailedConfigError(fmt.Sprintf("unknown block `%s`!", name), nil, imageDoc)
+		}
+
+		merged = mergeYamlMaps(merged, b.Content)
+		mergedLines = mergeYamlMaps(mergedLines, newLinesTree(b.Content, nil, yamlLinesLocations(b.doc.Content, b.doc.lineLocation)))
+	}
+	merged = mergeYamlMaps(merged, raw)
+	mergedLines = mergeYamlMaps(mergedLines, newLinesTree(raw, nil, yamlLinesLocations(imageDoc.Content, imageDoc.lineLocation)))
+
+	// directives of the image go first in the merged doc
+	var ordered yaml.MapSlice
+	for _, item := range raw {
+		for _, mergedItem := range merged {
+			if mergedItem.Key == item.Key {
+				ordered = append(ordered, mergedItem)
+			}
+		}
+	}
+	for _, mergedItem := range merged {
+		if !isYamlMapKeyExist(raw, mergedItem.Key) {
+			ordered = append(ordered, mergedItem)
+		}
+	}
+
+	content, err := yaml.Marshal(ordered)
+	if err != nil {
+		return nil, newDetailedConfigError(fmt.Sprintf("cannot merge blocks: %s", err), nil, imageDoc)
+	}
+
+	return &doc{
+		Content:        content,
+		Line:           imageDoc.Line,
+		RenderFilePath: imageDoc.RenderFilePath,
+		SourceFilePath: imageDoc.SourceFilePath,
+		SourceLine:     imageDoc.SourceLine,
+		Blocks:         names,
+		LineMap:        newLineMap(content, mergedLines),
+	}, nil
+}
+
+func isYamlMapKeyExist(m yaml.MapSlice, key interface{}) bool {
+	for _, item := range m {
+		if item.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mergeYamlMaps(base, override yaml.MapSlice) yaml.MapSlice {
+	res := make(yaml.MapSlice, len(base), len(base)+len(override))
+	copy(res, base)
+
+	for _, item := range override {
+		index := -1
+		for i := range res {
+			if res[i].Key == item.Key {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			res = append(res, item)
+			continue
+		}
+
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			if baseValue, ok := res[index].Value.(yaml.MapSlice); ok {
+				res[index].Value = mergeYamlMaps(baseValue, value)
+				continue
+			}
+		case []interface{}:
+			if baseValue, ok := res[index].Value.([]interface{}); ok {
+				res[index].Value = append(append([]interface{}{}, baseValue...), value...)
+				continue
+			}
+		}
+
+		res[index].Value = item.Value
+	}
+
+	return res
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/flant/yaml.v2"
+)
+
+func TestMergeYamlMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		override string
+		expected string
+	}{
+		{
+			name:     "new keys are appended",
+			base:     "a: 1",
+			override: "b: 2",
+			expected: "a: 1\nb: 2\n",
+		},
+		{
+			name:     "scalars are replaced",
+			base:     "a: 1\nb: 2",
+			override: "a: 3",
+			expected: "a: 3\nb: 2\n",
+		},
+		{
+			name:     "maps are merged recursively",
+			base:     "docker:\n  LABEL:\n    a: 1\n    b: 2\n  WORKDIR: /app",
+			override: "docker:\n  LABEL:\n    b: 3\n    c: 4",
+			expected: "docker:\n  LABEL:\n    a: 1\n    b: 3\n    c: 4\n  WORKDIR: /app\n",
+		},
+		{
+			name:     "lists are concatenated",
+			base:     "mount:\n- a\n- b",
+			override: "mount:\n- c",
+			expected: "mount:\n- a\n- b\n- c\n",
+		},
+		{
+			name:     "map is replaced by scalar",
+			base:     "docker:\n  WORKDIR: /app",
+			override: "docker: null",
+			expected: "docker: null\n",
+		},
+		{
+			name:     "list is replaced by map",
+			base:     "mount:\n- a",
+			override: "mount:\n  a: b",
+			expected: "mount:\n  a: b\n",
+		},
+		{
+			name:     "empty value is replaced by map",
+			base:     "docker:",
+			override: "docker:\n  WORKDIR: /app",
+			expected: "docker:\n  WORKDIR: /app\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var base, override yaml.MapSlice
+			if err := yaml.Unmarshal([]byte(test.base), &base); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(test.override), &override); err != nil {
+				t.Fatal(err)
+			}
+
+			baseContent, err := yaml.Marshal(base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			merged, err := yaml.Marshal(mergeYamlMaps(base, override))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(merged) != test.expected {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.expected, string(merged))
+			}
+
+			if content, err := yaml.Marshal(base); err != nil {
+				t.Fatal(err)
+			} else if string(content) != string(baseContent) {
+				t.Errorf("base map is modified by merge\n[EXPECTED]: %s\n[GOT]: %s", string(baseContent), string(content))
+			}
+		})
+	}
+}
+
+func TestParseWerfConfig_blocks(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+block: _default
+docker:
+  LABEL:
+    team: platform
+---
+block: apt
+mount:
+- from: build_dir
+  to: /var/cache/apt
+docker:
+  LABEL:
+    cache: apt
+---
+image: default
+from: alpine
+---
+image: app
+blocks: [apt]
+from: ubuntu
+mount:
+- from: tmp_dir
+  to: /tmp
+docker:
+  LABEL:
+    app: test
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images := map[string]*Image{}
+	for _, image := range werfConfig.Images {
+		images[image.Name] = image
+	}
+
+	if labels := images["default"].Docker.Label; !reflect.DeepEqual(labels, map[string]string{"team": "platform"}) {
+		t.Errorf("\n[EXPECTED]: _default block is merged into image without blocks directive\n[GOT]: %#v", labels)
+	}
+
+	if labels := images["app"].Docker.Label; !reflect.DeepEqual(labels, map[string]string{"cache": "apt", "app": "test"}) {
+		t.Errorf("\n[EXPECTED]: only listed blocks are merged\n[GOT]: %#v", labels)
+	}
+
+	var mountsTo []string
+	for _, mount := range images["app"].Mount {
+		mountsTo = append(mountsTo, mount.To)
+	}
+	if expected := []string{"/var/cache/apt", "/tmp"}; !reflect.DeepEqual(mountsTo, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, mountsTo)
+	}
+}
+
+func TestParseWerfConfig_blocks_negative(t *testing.T) {
+	tests := []struct {
+		name                   string
+		content                string
+		expectedErrorSubstring string
+	}{
+		{
+			name:                   "unknown block",
+			content:                "image: app\nfrom: alpine\nblocks: [unknown]\n",
+			expectedErrorSubstring: "unknown block `unknown`!",
+		},
+		{
+			name:                   "duplicate block",
+			content:                "block: a\nfrom: alpine\n---\nblock: a\nfrom: ubuntu\n",
+			expectedErrorSubstring: "duplicate block `a`!",
+		},
+		{
+			name:                   "block with image directive",
+			content:                "block: a\nimage: app\n",
+			expectedErrorSubstring: "block cannot contain `image` directive!",
+		},
+		{
+			name:                   "blocks directive is not a list",
+			content:                "image: app\nfrom: alpine\nblocks: a\n",
+			expectedErrorSubstring: "blocks directive should be an array of block names!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTestWerfConfig(t, "project: test\n---\n"+test.content)
+			expectConfigError(t, err, test.expectedErrorSubstring)
+		})
+	}
+}
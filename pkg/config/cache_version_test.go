@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheVersion(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+cacheVersion: project-v1
+---
+image: app
+from: alpine:3.9
+fromCacheVersion: from-v1
+cacheVersion: image-v1
+stagesCacheVersion:
+  install: install-v1
+  git_archive: git-archive-v1
+---
+image: worker
+from: alpine:3.9
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if werfConfig.Meta.CacheVersion != "project-v1" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "project-v1", werfConfig.Meta.CacheVersion)
+	}
+
+	app := werfConfig.Images[0]
+	if app.FromCacheVersion != "from-v1" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "from-v1", app.FromCacheVersion)
+	}
+
+	if app.CacheVersion != "image-v1" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "image-v1", app.CacheVersion)
+	}
+
+	expectedStagesCacheVersion := map[string]string{"install": "install-v1", "git_archive": "git-archive-v1"}
+	if !reflect.DeepEqual(app.StagesCacheVersion, expectedStagesCacheVersion) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedStagesCacheVersion, app.StagesCacheVersion)
+	}
+
+	worker := werfConfig.Images[1]
+	if worker.FromCacheVersion != "" || worker.CacheVersion != "" || worker.StagesCacheVersion != nil {
+		t.Errorf("\n[EXPECTED]: empty cache versions\n[GOT]: %#v, %#v, %#v", worker.FromCacheVersion, worker.CacheVersion, worker.StagesCacheVersion)
+	}
+}
+
+func TestCacheVersion_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		directives    string
+		errorContains string
+	}{
+		{
+			"stagesCacheVersion:\n  compile: v1\n",
+			"unknown stage `compile` in stagesCacheVersion: expected one of from, before_install, ",
+		},
+		{
+			"stagesCacheVersion:\n  beforeInstall: v1\n",
+			"unknown stage `beforeInstall` in stagesCacheVersion",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestStagesDirectives(t, expectation.directives)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
@@ -0,0 +1,18 @@
+package config
+
+// Check is a set of commands, which are run in the built image to test it.
+// Changes made by the commands are not saved into the image, the build fails if any command exits with non-zero code.
+type Check struct {
+	Run          []string
+	CacheVersion string
+
+	raw *rawCheck
+}
+
+func (c *Check) validate() error {
+	if len(c.Run) == 0 {
+		return newDetailedConfigError("`run: [COMMAND, ...]|COMMAND` required for check!", c.raw, c.raw.rawImage.doc)
+	}
+
+	return nil
+}
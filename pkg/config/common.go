@@ -16,6 +16,15 @@ type doc struct {
 	Content        []byte
 	Line           int
 	RenderFilePath string
+	// SourceFilePath is werf.yaml or the included config file, which the doc is rendered from, SourceLine is the doc line in the rendered file
+	SourceFilePath string
+	SourceLine     int
+	// Blocks are names of the blocks merged into the image doc
+	Blocks []string
+	// Overlays are paths of the config overlays merged into the doc
+	Overlays []string
+	// LineMap is a location of the each line of the content merged with blocks or config overlays, nil for the content of the render file
+	LineMap []*docLine
 }
 
 func checkOverflow(m map[string]interface{}, configSection interface{}, doc *doc) error {
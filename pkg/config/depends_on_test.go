@@ -0,0 +1,125 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependsOn(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+artifact: assets
+from: alpine:3.9
+---
+image: migrations
+from: alpine:3.9
+---
+image: app
+from: alpine:3.9
+dependsOn:
+- migrations
+- assets
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := werfConfig.Images[1]
+	_, _, dependsOn := ImageDependencies(app)
+
+	var names []string
+	for _, dependency := range dependsOn {
+		names = append(names, imageInterfaceName(dependency))
+	}
+
+	if expected := []string{"migrations", "assets"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, names)
+	}
+
+	if dependsOn[0] != werfConfig.Images[0] {
+		t.Errorf("\n[EXPECTED]: %p\n[GOT]: %p", werfConfig.Images[0], dependsOn[0])
+	}
+}
+
+func TestDependsOn_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		werfConfig    string
+		errorContains string
+	}{
+		{
+			`
+project: test
+---
+image: app
+from: alpine:3.9
+dependsOn: [app]
+`,
+			"cannot use own image name as `dependsOn` directive value!",
+		},
+		{
+			`
+project: test
+---
+image: app
+from: alpine:3.9
+dependsOn: [nonexistent]
+`,
+			"no such image or artifact `nonexistent` in `dependsOn` directive!",
+		},
+		{
+			`
+project: test
+---
+image: a
+from: alpine:3.9
+dependsOn: [b]
+---
+image: b
+from: alpine:3.9
+dependsOn: [a]
+`,
+			"images dependencies cycle detected: a -> b -> a!",
+		},
+		{
+			`
+project: test
+---
+image: a
+fromImage: b
+---
+image: b
+from: alpine:3.9
+dependsOn: [c]
+---
+image: c
+from: alpine:3.9
+dependsOn: [a]
+`,
+			"images dependencies cycle detected: a -> b -> c -> a!",
+		},
+		{
+			`
+project: test
+---
+artifact: assets
+from: alpine:3.9
+dependsOn: [app]
+---
+image: app
+from: alpine:3.9
+import:
+- artifact: assets
+  add: /app
+  to: /app
+  before: install
+`,
+			"images dependencies cycle detected: ",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestWerfConfig(t, expectation.werfConfig)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
@@ -5,4 +5,5 @@ type DeployTemplates struct {
 	HelmReleaseSlug         bool
 	KubernetesNamespace     string
 	KubernetesNamespaceSlug bool
+	Annotations             map[string]string
 }
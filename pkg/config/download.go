@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DownloadStages are the user stages, which can depend on the downloaded files
+var DownloadStages = []string{"beforeInstall", "install", "beforeSetup", "setup"}
+
+// Download is a remote file, which is downloaded once by werf and mounted read-only into the stage container.
+// Checksum of the file content is a part of the stage signature.
+type Download struct {
+	Url string
+	// Checksum is expected sha256:HEX checksum, checksum of the first download is tracked if not specified
+	Checksum string
+	To       string
+	Stage    string
+
+	raw *rawDownload
+}
+
+func (c *Download) validate() error {
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newDetailedConfigError(fmt.Sprintf("`url: %s` should be http or https url for download!", c.Url), c.raw, c.raw.rawImage.doc)
+	}
+
+	if c.To == "" || !isAbsolutePath(c.To) {
+		return newDetailedConfigError("`to: PATH` absolute path required for download!", c.raw, c.raw.rawImage.doc)
+	}
+
+	var stageFound bool
+	for _, stage := range DownloadStages {
+		if stage == c.Stage {
+			stageFound = true
+		}
+	}
+
+	if !stageFound {
+		return newDetailedConfigError(fmt.Sprintf("invalid `stage: %s` for download: expected `beforeInstall`, `install`, `beforeSetup` or `setup`!", c.Stage), c.raw, c.raw.rawImage.doc)
+	}
+
+	return nil
+}
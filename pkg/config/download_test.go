@@ -0,0 +1,101 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+download:
+- url: https://example.com/archive.tar.gz
+  checksum: sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+  to: /opt/archive.tar.gz
+  stage: install
+- url: http://example.com/tool
+  to: /usr/local/bin/tool
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloads := werfConfig.Images[0].Download
+	if len(downloads) != 2 {
+		t.Fatalf("\n[EXPECTED]: 2 downloads\n[GOT]: %d", len(downloads))
+	}
+
+	var expectations = []struct {
+		url      string
+		checksum string
+		to       string
+		stage    string
+	}{
+		{"https://example.com/archive.tar.gz", "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "/opt/archive.tar.gz", "install"},
+		{"http://example.com/tool", "", "/usr/local/bin/tool", "beforeInstall"},
+	}
+
+	for i, expectation := range expectations {
+		d := downloads[i]
+		if d.Url != expectation.url || d.Checksum != expectation.checksum || d.To != expectation.to || d.Stage != expectation.stage {
+			t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectation, *d)
+		}
+	}
+}
+
+func TestDownload_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		download      string
+		errorContains string
+	}{
+		{
+			"- url: ftp://example.com/file\n  to: /file",
+			"`url: ftp://example.com/file` should be http or https url for download!",
+		},
+		{
+			"- url: https://example.com/file",
+			"`to: PATH` absolute path required for download!",
+		},
+		{
+			"- url: https://example.com/file\n  to: file",
+			"`to: PATH` absolute path required for download!",
+		},
+		{
+			"- url: https://example.com/file\n  to: /file\n  stage: gitArchive",
+			"invalid `stage: gitArchive` for download",
+		},
+		{
+			"- url: https://example.com/file\n  to: /file\n  checksum: md5:d41d8cd98f00b204e9800998ecf8427e",
+			"bad `checksum` for download: checksum `md5:d41d8cd98f00b204e9800998ecf8427e` should have sha256: prefix!",
+		},
+		{
+			"- url: https://example.com/file\n  to: /file\n  checksum: sha256:abc",
+			"should contain 64 hex characters after sha256: prefix!",
+		},
+		{
+			"- url: https://example.com/file\n  to: /file\n  sha: abc",
+			"sha",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestWerfConfig(t, "project: test\n---\nimage: app\nfrom: alpine:3.9\ndownload:\n"+expectation.download+"\n")
+		expectConfigError(t, err, expectation.errorContains)
+	}
+
+	_, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+mount:
+- from: tmp_dir
+  to: /file
+download:
+- url: https://example.com/file
+  to: /file
+`)
+	expectConfigError(t, err, "conflict between mounts and download to `/file`!")
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/flant/yaml.v2"
+)
+
+// StrictConfigVersion is the config version, which forbids keys defined more than once in one mapping of the doc
+const StrictConfigVersion = 2
+
+var (
+	mergeKeyRegexp     = regexp.MustCompile(`<<\s*:`)
+	duplicateKeyRegexp = regexp.MustCompile(`^line ([0-9]+): key (.*) already set in map$`)
+)
+
+// checkDuplicateKeys fails if the doc contains keys, which are defined more than once in one mapping: yaml parser silently takes the last value.
+// Keys merged from anchors with `<<` are not checked, so merged keys can be overridden.
+func checkDuplicateKeys(doc *doc) error {
+	// strict decoder reports keys merged with `<<` and then overridden as duplicates,
+	// so merge keys are renamed into unique regular keys, which values are not merged
+	mergeKeyInd := 0
+	content := mergeKeyRegexp.ReplaceAllStringFunc(string(doc.Content), func(mergeKey string) string {
+		mergeKeyInd++
+		return fmt.Sprintf("<<werf-merge-key-%d%s", mergeKeyInd, strings.TrimPrefix(mergeKey, "<<"))
+	})
+
+	var raw map[string]interface{}
+	err := yaml.UnmarshalStrict([]byte(content), &raw)
+	if err == nil {
+		return nil
+	}
+
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return newYamlUnmarshalError(err, doc)
+	}
+
+	var lines []string
+	for _, msg := range typeErr.Errors {
+		res := duplicateKeyRegexp.FindStringSubmatch(msg)
+		if len(res) != 3 {
+			return newYamlUnmarshalError(err, doc)
+		}
+
+		line, err := strconv.Atoi(res[1])
+		if err != nil {
+			return err
+		}
+
+		key := res[2]
+		if unquotedKey, err := strconv.Unquote(key); err == nil {
+			key = unquotedKey
+		}
+
+		lines = append(lines, fmt.Sprintf("key `%s` at line %d is already defined in the same mapping", key, doc.Line+line))
+	}
+
+	return newDetailedConfigError(fmt.Sprintf("duplicate keys in doc:\n%s", strings.Join(lines, "\n")), nil, doc)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCheckDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no duplicates", `
+image: app
+from: alpine
+shell:
+  install: echo install
+  setup: echo setup
+`},
+		{"same keys in different mappings", `
+import:
+- artifact: a
+  add: /a
+- artifact: b
+  add: /b
+`},
+		{"merged keys are overridden", `
+ansible:
+  install:
+  - apt: &apt
+      name: curl
+      state: present
+  - apt:
+      <<: *apt
+      name: build-essential
+  - apt:
+      name: git
+      <<  : *apt
+`},
+		{"duplicate-like content of block scalar", `
+shell:
+  install: |
+    name: a
+    name: b
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := checkDuplicateKeys(&doc{Content: []byte(test.content)}); err != nil {
+				t.Errorf("\n[EXPECTED]: <nil>\n[GOT]: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateKeys_negative(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedMessages []string
+	}{
+		{"top level key", `
+image: app
+from: alpine
+from: ubuntu
+`, []string{"key `from` at line 14 is already defined in the same mapping"}},
+		{"nested key", `
+image: app
+shell:
+  install: echo a
+  setup: echo b
+  install: echo c
+`, []string{"key `install` at line 16 is already defined in the same mapping"}},
+		{"key of sequence item", `
+import:
+- artifact: a
+  add: /a
+  add: /b
+`, []string{"key `add` at line 15 is already defined in the same mapping"}},
+		{"flow mapping key", `
+mount:
+- {from: tmp_dir, from: build_dir, to: /tmp}
+`, []string{"key `from` at line 13 is already defined in the same mapping"}},
+		{"several keys", `
+image: app
+from: alpine
+image: app2
+from: ubuntu
+`, []string{
+			"key `image` at line 14 is already defined in the same mapping",
+			"key `from` at line 15 is already defined in the same mapping",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkDuplicateKeys(&doc{Content: []byte(test.content), Line: 10})
+			for _, expectedMessage := range test.expectedMessages {
+				expectConfigError(t, err, expectedMessage)
+			}
+		})
+	}
+}
+
+func TestParseWerfConfig_duplicateKeys(t *testing.T) {
+	content := `
+project: test
+configVersion: %d
+---
+image: app
+from: alpine:3.9
+from: ubuntu:18.04
+`
+
+	if _, err := parseTestWerfConfig(t, fmt.Sprintf(content, 1)); err != nil {
+		t.Errorf("\n[EXPECTED]: <nil>\n[GOT]: %s", err)
+	}
+
+	_, err := parseTestWerfConfig(t, fmt.Sprintf(content, StrictConfigVersion))
+	expectConfigError(t, err, "key `from` at line 7 is already defined in the same mapping")
+}
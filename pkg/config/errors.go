@@ -3,6 +3,7 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	yaml "gopkg.in/flant/yaml.v2"
 )
@@ -56,9 +57,30 @@ func dumpConfigSection(config interface{}) string {
 func dumpConfigDoc(doc *doc) string {
 	contentLines := getLines(doc.Content)
 
-	res := fmt.Sprintf("%s\n\n", doc.RenderFilePath)
+	header := doc.RenderFilePath
+	if doc.SourceFilePath != "" {
+		header += fmt.Sprintf(" (%s, line %d)", doc.SourceFilePath, doc.SourceLine+1)
+	}
+
+	if len(doc.Blocks) != 0 {
+		header += fmt.Sprintf(" merged with blocks `%s`", strings.Join(doc.Blocks, "`, `"))
+	}
+	if len(doc.Overlays) != 0 {
+		header += fmt.Sprintf(" merged with config overlays %s", strings.Join(doc.Overlays, ", "))
+	}
+
+	// lines of the merged content are numbered by the lines of the render file or the config overlay file, which they are merged from
+	res := fmt.Sprintf("%s\n\n", header)
 	for lineNum, lineBytes := range contentLines {
-		res += fmt.Sprintf("%6d  %s\n", doc.Line+lineNum+1, string(lineBytes))
+		location := doc.lineLocation(lineNum)
+		switch {
+		case location == nil:
+			res += fmt.Sprintf("%6s  %s\n", "", string(lineBytes))
+		case location.FilePath != doc.RenderFilePath:
+			res += fmt.Sprintf("%6d  %s  # %s\n", location.Line, string(lineBytes), location.FilePath)
+		default:
+			res += fmt.Sprintf("%6d  %s\n", location.Line, string(lineBytes))
+		}
 	}
 	res += "\n"
 
@@ -3,6 +3,13 @@ package config
 type GitExportBase struct {
 	*GitExport
 	StageDependencies *StageDependencies
+	Submodules        *Submodules
+	DetectRenames     bool
+	DetectCopies      bool
+	ArchiveBaseBranch string
+	PatchLimits       *PatchLimits
+	FileMode          string
+	DirMode           string
 
 	raw *rawGit
 }
@@ -17,6 +17,10 @@ func (c *Image) ImageTree() (tree []ImageInterface) {
 		tree = append(tree, importElm.ImageArtifact.ImageTree()...)
 	}
 
+	for _, dependency := range c.DependsOn {
+		tree = append(tree, imageTree(dependency)...)
+	}
+
 	tree = append(tree, c)
 
 	return
@@ -2,23 +2,93 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flant/werf/pkg/util"
 )
 
 type ImageInterface interface{}
 
+// StageNames are the names of the image stages, which can be specified in stagesCacheVersion, stagesTimeout, stagesRetries and stagesPlatforms directives
+var StageNames = []string{
+	"from",
+	"before_install",
+	"imports_before_install",
+	"git_archive",
+	"install",
+	"imports_after_install",
+	"before_setup",
+	"imports_before_setup",
+	"setup",
+	"imports_after_setup",
+	"git_cache",
+	"git_latest_patch",
+	"docker_instructions",
+	"check",
+}
+
+func isStageName(name string) bool {
+	for _, stageName := range StageNames {
+		if stageName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSkippableStageName reports whether the stage can be skipped on some platforms by stagesPlatforms directive,
+// base image and git stages are required to build the image on any platform
+func isSkippableStageName(name string) bool {
+	switch name {
+	case "from", "git_archive", "git_cache", "git_latest_patch":
+		return false
+	}
+
+	return isStageName(name)
+}
+
+// isPlatform checks platform format OS/ARCH[/VARIANT]
+func isPlatform(platform string) bool {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return false
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
 type ImageBase struct {
 	ImageInterface
 
-	Name              string
-	From              string
-	FromImage         *Image
-	FromImageArtifact *ImageArtifact
-	FromCacheVersion  string
-	Git               *GitManager
-	Shell             *Shell
-	Ansible           *Ansible
-	Mount             []*Mount
-	Import            []*ArtifactImport
+	Name               string
+	From               string
+	FromPlatforms      map[string]string
+	FromImage          *Image
+	FromImageArtifact  *ImageArtifact
+	FromCacheVersion   string
+	CacheVersion       string
+	StagesCacheVersion map[string]string
+	StagesTimeout      map[string]time.Duration
+	StagesRetries      map[string]int
+	StagesPlatforms    map[string][]string
+	Git                *GitManager
+	Shell              *Shell
+	Ansible            *Ansible
+	Mount              []*Mount
+	Download           []*Download
+	Import             []*ArtifactImport
+	// Check is run in the built image and is not saved into the image
+	Check *Check
+	// DependsOn are images and artifacts, which are built before the image without fromImage or import relation
+	DependsOn []ImageInterface
 
 	raw *rawImage
 }
@@ -93,6 +163,66 @@ func (c *ImageBase) associateFrom(images []*Image, artifacts []*ImageArtifact) e
 	return nil
 }
 
+func (c *ImageBase) associateDependsOn(images []*Image, artifacts []*ImageArtifact) error {
+	for _, name := range c.raw.DependsOn {
+		if name == c.Name {
+			return newDetailedConfigError("cannot use own image name as `dependsOn` directive value!", nil, c.raw.doc)
+		}
+
+		if image := imageByName(images, name); image != nil {
+			c.DependsOn = append(c.DependsOn, image)
+		} else if imageArtifact := imageArtifactByName(artifacts, name); imageArtifact != nil {
+			c.DependsOn = append(c.DependsOn, imageArtifact)
+		} else {
+			return newDetailedConfigError(fmt.Sprintf("no such image or artifact `%s` in `dependsOn` directive!", name), nil, c.raw.doc)
+		}
+	}
+
+	return nil
+}
+
+// imageTree returns images, which should be built before the image, and the image itself
+func imageTree(image ImageInterface) []ImageInterface {
+	switch i := image.(type) {
+	case *Image:
+		return i.ImageTree()
+	case *ImageArtifact:
+		return i.ImageTree()
+	default:
+		panic("runtime error")
+	}
+}
+
+// ConfigChecksum returns checksum of the rendered config doc of the image, includes and config overlays are merged into the doc
+func (c *ImageBase) ConfigChecksum() string {
+	return util.Sha256Hash(string(c.raw.doc.Content))
+}
+
+// ImageDependencies returns images, which are used by the image directly: base image, imported artifacts and dependsOn images
+func ImageDependencies(image ImageInterface) (fromImage ImageInterface, imports []ImageInterface, dependsOn []ImageInterface) {
+	var imageBase *ImageBase
+	switch i := image.(type) {
+	case *Image:
+		imageBase = i.ImageBase
+	case *ImageArtifact:
+		imageBase = i.ImageBase
+	default:
+		panic("runtime error")
+	}
+
+	if imageBase.FromImage != nil {
+		fromImage = imageBase.FromImage
+	} else if imageBase.FromImageArtifact != nil {
+		fromImage = imageBase.FromImageArtifact
+	}
+
+	for _, importElm := range imageBase.Import {
+		imports = append(imports, importElm.ImageArtifact)
+	}
+
+	return fromImage, imports, imageBase.DependsOn
+}
+
 func imageByName(images []*Image, name string) *Image {
 	for _, image := range images {
 		if image.Name == name {
@@ -112,7 +242,7 @@ func imageArtifactByName(images []*ImageArtifact, name string) *ImageArtifact {
 }
 
 func (c *ImageBase) validate() error {
-	if c.From == "" && c.raw.FromImage == "" && c.raw.FromImageArtifact == "" && c.FromImage == nil && c.FromImageArtifact == nil {
+	if c.From == "" && len(c.FromPlatforms) == 0 && c.raw.FromImage == "" && c.raw.FromImageArtifact == "" && c.FromImage == nil && c.FromImageArtifact == nil {
 		return newDetailedConfigError("`from: DOCKER_IMAGE`, `fromImage: IMAGE_NAME`, `fromImageArtifact: IMAGE_ARTIFACT_NAME` required!", nil, c.raw.doc)
 	}
 
@@ -126,7 +256,15 @@ func (c *ImageBase) validate() error {
 		mountByTo[mount.To] = true
 	}
 
-	if !oneOrNone([]bool{c.From != "", c.raw.FromImage != "", c.raw.FromImageArtifact != ""}) {
+	for _, d := range c.Download {
+		if mountByTo[d.To] {
+			return newDetailedConfigError(fmt.Sprintf("conflict between mounts and download to `%s`!", d.To), nil, c.raw.doc)
+		}
+
+		mountByTo[d.To] = true
+	}
+
+	if !oneOrNone([]bool{c.From != "" || len(c.FromPlatforms) != 0, c.raw.FromImage != "", c.raw.FromImageArtifact != ""}) {
 		return newDetailedConfigError("conflict between `from`, `fromImage` and `fromImageArtifact` directives!", nil, c.raw.doc)
 	}
 
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bmatcuk/doublestar"
+	yaml "gopkg.in/flant/yaml.v2"
+)
+
+// includedConfigsDir contains config files, which are included into werf.yaml automatically
+const includedConfigsDir = "werf.d"
+
+// configPart is rendered werf.yaml or included config file
+type configPart struct {
+	FilePath string
+	Content  string
+}
+
+// joinConfigParts joins rendered config files into the render file content, included files are preceded by the source comment
+func joinConfigParts(configParts []*configPart) string {
+	var res string
+	for i, part := range configParts {
+		if i > 0 {
+			if res != "" && !strings.HasSuffix(res, "\n") {
+				res += "\n"
+			}
+			res += fmt.Sprintf("--- # Source: %s\n", part.FilePath)
+		}
+
+		res += part.Content
+	}
+
+	return res
+}
+
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+
+	lines := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+
+	return lines
+}
+
+// getIncludedConfigsPaths returns *.yaml and *.yml files of werf.d directory and files matching includes of werf.yaml meta doc.
+// Each file is included once, files of werf.d directory and files matching each include pattern are sorted by path.
+func getIncludedConfigsPaths(werfConfigPath string, werfConfigContent string, projectFiles projectFiles) ([]string, error) {
+	var res []string
+	added := map[string]bool{path.Clean(werfConfigPath): true}
+	add := func(paths []string) {
+		for _, p := range paths {
+			p = path.Clean(p)
+			if !added[p] {
+				added[p] = true
+				res = append(res, p)
+			}
+		}
+	}
+
+	filesList, err := projectFiles.FilesList(includedConfigsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirConfigs []string
+	for _, fp := range filesList {
+		fp = filepath.ToSlash(fp)
+		if ext := path.Ext(fp); ext == ".yaml" || ext == ".yml" {
+			dirConfigs = append(dirConfigs, fp)
+		}
+	}
+	sort.Strings(dirConfigs)
+	add(dirConfigs)
+
+	for _, pattern := range metaIncludes(werfConfigContent) {
+		paths, err := globProjectFiles(pattern, projectFiles)
+		if err != nil {
+			return nil, fmt.Errorf("bad include `%s` of %s: %s", pattern, werfConfigPath, err)
+		}
+		add(paths)
+	}
+
+	return res, nil
+}
+
+// metaIncludes returns includes of the meta doc, bad docs are ignored here and reported by the config parsing
+func metaIncludes(werfConfigContent string) []string {
+	for _, docContent := range splitContent([]byte(werfConfigContent)) {
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(docContent, &raw); err != nil || !isMetaDoc(raw) {
+			continue
+		}
+
+		var includes []string
+		if rawIncludes, ok := raw["includes"].([]interface{}); ok {
+			for _, rawInclude := range rawIncludes {
+				if include, ok := rawInclude.(string); ok {
+					includes = append(includes, include)
+				}
+			}
+		}
+
+		return includes
+	}
+
+	return nil
+}
+
+// globProjectFiles returns files matching the glob pattern (** is supported), pattern without wildcards should be an existing file
+func globProjectFiles(pattern string, projectFiles projectFiles) ([]string, error) {
+	if isAbsolutePath(pattern) {
+		return nil, fmt.Errorf("relative path expected")
+	}
+
+	var dirParts []string
+	parts := strings.Split(path.Clean(pattern), "/")
+	for _, part := range parts {
+		if strings.ContainsAny(part, "*?[{") {
+			break
+		}
+		dirParts = append(dirParts, part)
+	}
+
+	if len(dirParts) == len(parts) {
+		if exist, err := projectFiles.IsFileExists(pattern); err != nil {
+			return nil, err
+		} else if !exist {
+			return nil, fmt.Errorf("file not found")
+		}
+
+		return []string{pattern}, nil
+	}
+
+	filesList, err := projectFiles.FilesList(path.Join(dirParts...))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, fp := range filesList {
+		fp = filepath.ToSlash(fp)
+
+		matched, err := doublestar.Match(path.Clean(pattern), fp)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			res = append(res, fp)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no files match the pattern")
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// renderIncludedConfig renders included config file with werf.yaml templates, so templates of .werf directory and defines of werf.yaml can be used
+func renderIncludedConfig(tmpl *template.Template, includedConfigPath string, data interface{}, projectFiles projectFiles) (string, error) {
+	content, err := projectFiles.ReadFile(includedConfigPath)
+	if err != nil {
+		return "", err
+	}
+
+	templateName := fmt.Sprintf("include:%s", includedConfigPath)
+	if _, err := tmpl.New(templateName).Parse(string(content)); err != nil {
+		return "", err
+	}
+
+	return executeTemplate(tmpl, templateName, data)
+}
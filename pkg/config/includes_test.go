@@ -0,0 +1,116 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseWerfConfig_includes(t *testing.T) {
+	werfConfig, err := parseTestWerfConfigWithFiles(t, map[string]string{
+		"werf.yaml": `
+project: test
+includes:
+- images/*.yaml
+- images/backend.yaml
+---
+image: app
+from: alpine
+`,
+		"werf.d/10-base.yaml": `
+image: base
+from: {{ include "base-image" . }}
+`,
+		"images/frontend.yaml": "image: frontend\nfrom: node\n",
+		"images/backend.yaml":  "image: backend\nfrom: golang\n",
+		"images/readme.md":     "not a config",
+		".werf/base.tmpl":      `{{ define "base-image" }}ubuntu{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	froms := map[string]string{}
+	for _, image := range werfConfig.Images {
+		names = append(names, image.Name)
+		froms[image.Name] = image.From
+	}
+	sort.Strings(names)
+
+	if expected := []string{"app", "backend", "base", "frontend"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, names)
+	}
+
+	if froms["base"] != "ubuntu" {
+		t.Errorf("\n[EXPECTED]: included config is rendered with .werf templates\n[GOT]: %#v", froms["base"])
+	}
+}
+
+func TestParseWerfConfig_includes_negative(t *testing.T) {
+	tests := []struct {
+		name                   string
+		files                  map[string]string
+		expectedErrorSubstring string
+	}{
+		{
+			name: "include of not existing file",
+			files: map[string]string{
+				"werf.yaml": "project: test\nincludes: [images/app.yaml]\n",
+			},
+			expectedErrorSubstring: "bad include `images/app.yaml` of werf.yaml: file not found",
+		},
+		{
+			name: "absolute include",
+			files: map[string]string{
+				"werf.yaml": "project: test\nincludes: [/etc/app.yaml]\n",
+			},
+			expectedErrorSubstring: "bad include `/etc/app.yaml` of werf.yaml: relative path expected",
+		},
+		{
+			name: "includes directive in the included file",
+			files: map[string]string{
+				"werf.yaml":        "image: app\nfrom: alpine\n",
+				"werf.d/meta.yaml": "project: test\nincludes: [other.yaml]\n",
+			},
+			expectedErrorSubstring: "includes directive should be defined in werf.yaml!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTestWerfConfigWithFiles(t, test.files)
+			expectConfigError(t, err, test.expectedErrorSubstring)
+		})
+	}
+}
+
+func TestParseWerfConfig_includedDocLines(t *testing.T) {
+	_, err := parseTestWerfConfigWithFiles(t, map[string]string{
+		"werf.yaml":          "project: test\n---\nimage: app\nfrom: alpine\n",
+		"werf.d/images.yaml": "image: other\nfrom: alpine\n---\nimage: bad\nfrom: alpine\nunknownDirective: value\n",
+	})
+	if err == nil {
+		t.Fatal("\n[EXPECTED]: unknown field error")
+	}
+
+	// included file is rendered after werf.yaml and the source comment in the render file
+	for _, expectedLine := range []string{"(werf.d/images.yaml, line 4)", "     9  image: bad\n", "    11  unknownDirective: value\n"} {
+		if !strings.Contains(err.Error(), expectedLine) {
+			t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %s", expectedLine, err.Error())
+		}
+	}
+}
+
+func TestJoinConfigParts(t *testing.T) {
+	content := joinConfigParts([]*configPart{
+		{FilePath: "werf.yaml", Content: "project: test"},
+		{FilePath: "werf.d/app.yaml", Content: "image: app\nfrom: alpine\n"},
+	})
+
+	expected := "project: test\n--- # Source: werf.d/app.yaml\nimage: app\nfrom: alpine\n"
+	if content != expected {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, content)
+	}
+}
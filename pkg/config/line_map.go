@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/flant/yaml.v2"
+)
+
+// docLine is a location of the doc content line: the line of the render file or the line of the config overlay file
+type docLine struct {
+	FilePath string
+	Line     int
+}
+
+// lineLocation returns location of the content line (counted from 0), nil if the location of the merged content line is unknown
+func (d *doc) lineLocation(contentLine int) *docLine {
+	if d.LineMap == nil {
+		return &docLine{FilePath: d.RenderFilePath, Line: d.Line + contentLine + 1}
+	}
+
+	if contentLine < 0 || contentLine >= len(d.LineMap) {
+		return nil
+	}
+
+	return d.LineMap[contentLine]
+}
+
+// keyLine is a key of the lines tree map item, which value is the location of the map key line
+type keyLine struct {
+	Key string
+}
+
+// newLinesTree returns lines tree of the yaml map: the tree has the same structure as the map, values are replaced by the lines locations
+// and map keys lines are kept in keyLine items, so lines trees are merged by mergeYamlMaps the same way as the maps are.
+// Path is the path of the map in the content, which locations of the keys and list items are taken from
+func newLinesTree(m yaml.MapSlice, path []string, locations map[string]*docLine) yaml.MapSlice {
+	tree, _ := linesTree(m, path, locations, locations[yamlPathKey(path)]).(yaml.MapSlice)
+	return tree
+}
+
+func linesTree(value interface{}, path []string, locations map[string]*docLine, line *docLine) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		tree := yaml.MapSlice{}
+		for _, item := range v {
+			itemPath := appendYamlPath(path, fmt.Sprintf("%v", item.Key))
+
+			itemLine := locations[yamlPathKey(itemPath)]
+			if itemLine == nil {
+				itemLine = line
+			}
+
+			tree = append(tree, yaml.MapItem{Key: keyLine{Key: fmt.Sprintf("%v", item.Key)}, Value: itemLine})
+			tree = append(tree, yaml.MapItem{Key: item.Key, Value: linesTree(item.Value, itemPath, locations, itemLine)})
+		}
+		return tree
+	case []interface{}:
+		tree := make([]interface{}, len(v))
+		for i, item := range v {
+			itemPath := appendYamlPath(path, strconv.Itoa(i))
+
+			itemLine := locations[yamlPathKey(itemPath)]
+			if itemLine == nil {
+				itemLine = line
+			}
+
+			tree[i] = linesTree(item, itemPath, locations, itemLine)
+		}
+		return tree
+	default:
+		return line
+	}
+}
+
+// lookupLinesTree returns location of the key or list item by path, location of the nearest parent is returned for unknown path
+func lookupLinesTree(tree interface{}, path []string) *docLine {
+	var line *docLine
+
+	node := tree
+	for _, part := range path {
+		switch v := node.(type) {
+		case yaml.MapSlice:
+			var next interface{}
+			var found bool
+			for _, item := range v {
+				if k, ok := item.Key.(keyLine); ok {
+					if k.Key == part {
+						if l, ok := item.Value.(*docLine); ok && l != nil {
+							line = l
+						}
+					}
+				} else if fmt.Sprintf("%v", item.Key) == part {
+					next, found = item.Value, true
+				}
+			}
+
+			if !found {
+				return line
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return line
+			}
+			node = v[i]
+		default:
+			return line
+		}
+
+		if l, ok := node.(*docLine); ok && l != nil {
+			line = l
+		}
+	}
+
+	return line
+}
+
+// newLineMap returns locations of the merged content lines by the lines tree of the merged map
+func newLineMap(content []byte, tree yaml.MapSlice) []*docLine {
+	linesPaths := yamlLinesPaths(content)
+
+	lineMap := make([]*docLine, len(linesPaths))
+	for i, path := range linesPaths {
+		if path != nil {
+			lineMap[i] = lookupLinesTree(tree, path)
+		}
+	}
+
+	return lineMap
+}
+
+// yamlLinesLocations returns locations of the keys and list items of the content by paths
+func yamlLinesLocations(content []byte, location func(contentLine int) *docLine) map[string]*docLine {
+	locations := map[string]*docLine{}
+	for i, path := range yamlLinesPaths(content) {
+		if path != nil {
+			locations[yamlPathKey(path)] = location(i)
+		}
+	}
+
+	return locations
+}
+
+func yamlPathKey(path []string) string {
+	return strings.Join(path, "\n")
+}
+
+func appendYamlPath(path []string, part string) []string {
+	return append(append([]string{}, path...), part)
+}
+
+// yamlLinesPaths returns paths of the keys and list items defined on the lines of the block style yaml content,
+// path is nil for the other lines (comments, continuation of multiline values and flow collections)
+func yamlLinesPaths(content []byte) [][]string {
+	type level struct {
+		indent int
+		path   []string
+		isList bool
+		index  int
+	}
+
+	contentLines := getLines(content)
+	res := make([][]string, len(contentLines))
+
+	var levels []*level
+	pendingPath := []string{}
+	continuationIndent := -1
+
+	// setValue prepares parsing of the next lines by the value of the key or list item
+	setValue := func(path []string, value string, indent int) {
+		if value == "" || strings.HasPrefix(value, "#") || (strings.HasPrefix(value, "&") && !strings.Contains(value, " ")) {
+			pendingPath = path
+		} else {
+			continuationIndent = indent
+		}
+	}
+
+	for n, lineBytes := range contentLines {
+		line := strings.TrimRight(string(lineBytes), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || line == "---" || line == "..." {
+			continue
+		}
+
+		if continuationIndent >= 0 && indent > continuationIndent {
+			continue
+		}
+		continuationIndent = -1
+
+		isListItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+
+		for len(levels) > 0 {
+			top := levels[len(levels)-1]
+			if top.indent > indent || (top.indent == indent && top.isList && !isListItem) {
+				levels = levels[:len(levels)-1]
+				continue
+			}
+			break
+		}
+
+		var top *level
+		if len(levels) > 0 {
+			top = levels[len(levels)-1]
+		}
+
+		if isListItem {
+			var list *level
+			if top != nil && top.isList && top.indent == indent {
+				top.index++
+				list = top
+			} else if pendingPath != nil {
+				list = &level{indent: indent, path: pendingPath, isList: true}
+				levels = append(levels, list)
+			} else {
+				continue
+			}
+			pendingPath = nil
+
+			itemPath := appendYamlPath(list.path, strconv.Itoa(list.index))
+			rest := strings.TrimLeft(strings.TrimPrefix(trimmed, "-"), " ")
+			restIndent := indent + len(trimmed) - len(rest)
+
+			if key, value, ok := splitYamlKey(rest); ok {
+				levels = append(levels, &level{indent: restIndent, path: itemPath})
+				res[n] = appendYamlPath(itemPath, key)
+				setValue(res[n], value, restIndent)
+			} else {
+				res[n] = itemPath
+				setValue(itemPath, rest, indent)
+			}
+
+			continue
+		}
+
+		key, value, ok := splitYamlKey(trimmed)
+		if !ok {
+			continue
+		}
+
+		var m *level
+		if top != nil && !top.isList && top.indent == indent {
+			m = top
+		} else if pendingPath != nil && (top == nil || indent > top.indent) {
+			m = &level{indent: indent, path: pendingPath}
+			levels = append(levels, m)
+		} else {
+			continue
+		}
+		pendingPath = nil
+
+		res[n] = appendYamlPath(m.path, key)
+		setValue(res[n], value, indent)
+	}
+
+	return res
+}
+
+// splitYamlKey splits the line of the block style mapping by the key and the value
+func splitYamlKey(line string) (string, string, bool) {
+	if strings.HasPrefix(line, `"`) || strings.HasPrefix(line, "'") {
+		quote := line[:1]
+		end := strings.Index(line[1:], quote)
+		if end == -1 {
+			return "", "", false
+		}
+
+		rest := strings.TrimLeft(line[end+2:], " ")
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false
+		}
+
+		return line[1 : end+1], strings.TrimSpace(rest[1:]), true
+	}
+
+	if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "{") {
+		return "", "", false
+	}
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == ':' && (i+1 == len(line) || line[i+1] == ' '):
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		case line[i] == '#' && i > 0 && line[i-1] == ' ':
+			return "", "", false
+		}
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlLinesPaths(t *testing.T) {
+	content := `image: app
+from: alpine
+# comment
+docker:
+  LABEL:
+    "com.example/team": platform
+mount:
+- from: tmp_dir
+  to: /tmp
+-   from: build_dir
+    to: /var/cache
+shell:
+  install:
+  - echo one
+  - |
+    echo two
+    echo three
+  setup: [echo four,
+    echo five]
+ansible: &ansible
+  install:
+    - name: task
+`
+
+	expected := [][]string{
+		{"image"},
+		{"from"},
+		nil,
+		{"docker"},
+		{"docker", "LABEL"},
+		{"docker", "LABEL", "com.example/team"},
+		{"mount"},
+		{"mount", "0", "from"},
+		{"mount", "0", "to"},
+		{"mount", "1", "from"},
+		{"mount", "1", "to"},
+		{"shell"},
+		{"shell", "install"},
+		{"shell", "install", "0"},
+		{"shell", "install", "1"},
+		nil,
+		nil,
+		{"shell", "setup"},
+		nil,
+		{"ansible"},
+		{"ansible", "install"},
+		{"ansible", "install", "0", "name"},
+	}
+
+	paths := yamlLinesPaths([]byte(content))
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, paths)
+	}
+}
+
+func TestSplitYamlKey(t *testing.T) {
+	tests := []struct {
+		line          string
+		expectedKey   string
+		expectedValue string
+		expectedOk    bool
+	}{
+		{line: "from: alpine", expectedKey: "from", expectedValue: "alpine", expectedOk: true},
+		{line: "docker:", expectedKey: "docker", expectedOk: true},
+		{line: "docker:  # comment", expectedKey: "docker", expectedValue: "# comment", expectedOk: true},
+		{line: "url: http://example.com", expectedKey: "url", expectedValue: "http://example.com", expectedOk: true},
+		{line: `"a: b": c`, expectedKey: "a: b", expectedValue: "c", expectedOk: true},
+		{line: "'key':", expectedKey: "key", expectedOk: true},
+		{line: "http://example.com"},
+		{line: "echo # not: key"},
+		{line: "{a: b}"},
+		{line: `"quoted scalar"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			key, value, ok := splitYamlKey(test.line)
+			if key != test.expectedKey || value != test.expectedValue || ok != test.expectedOk {
+				t.Errorf("\n[EXPECTED]: %#v %#v %#v\n[GOT]: %#v %#v %#v", test.expectedKey, test.expectedValue, test.expectedOk, key, value, ok)
+			}
+		})
+	}
+}
+
+func TestParseWerfConfig_mergedDocLines(t *testing.T) {
+	_, err := parseTestWerfConfigWithFiles(t, map[string]string{
+		"werf.yaml": `project: test
+configVersion: 1
+---
+block: base
+from: alpine
+mount:
+- from: tmp_dir
+  to: /tmp
+---
+image: app
+blocks: [base]
+unknownDirective: value
+mount:
+- from: build_dir
+  to: /var/cache
+`,
+		"overlay.yaml": `images:
+  mount:
+  - from: tmp_dir
+    to: /overlay
+`,
+	}, "overlay.yaml")
+	if err == nil {
+		t.Fatal("\n[EXPECTED]: unknown field error")
+	}
+
+	for _, expectedLine := range []string{
+		" merged with blocks `base` merged with config overlays overlay.yaml",
+		"    10  image: app\n",
+		"    12  unknownDirective: value\n",
+		"     5  from: alpine\n",
+		"     2  mount:  # overlay.yaml\n",
+		"     7  - from: tmp_dir\n     8    to: /tmp\n",
+		"    14  - from: build_dir\n    15    to: /var/cache\n",
+		"     3  - from: tmp_dir  # overlay.yaml\n     4    to: /overlay  # overlay.yaml\n",
+	} {
+		if !strings.Contains(err.Error(), expectedLine) {
+			t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %s", expectedLine, err.Error())
+		}
+	}
+}
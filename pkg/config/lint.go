@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/flant/werf/pkg/true_git"
+)
+
+// LintWarning describes a config mistake, which does not break config parsing, but most likely leads to unexpected build result
+type LintWarning struct {
+	ImageName string
+	Message   string
+
+	section interface{}
+}
+
+func (w *LintWarning) String() string {
+	imageName := w.ImageName
+	if imageName == "" {
+		imageName = "~"
+	}
+
+	res := fmt.Sprintf("image %s: %s", imageName, w.Message)
+	if w.section != nil {
+		res = fmt.Sprintf("%s\n\n%s", res, dumpConfigSection(w.section))
+	}
+
+	return res
+}
+
+// Lint checks images and artifacts of the config for common mistakes.
+// headFiles are paths of the project git repo files at HEAD, local git mappings are not checked if headFiles is nil.
+func Lint(werfConfig *WerfConfig, headFiles []string) []*LintWarning {
+	var warnings []*LintWarning
+
+	for _, image := range lintImages(werfConfig) {
+		warnings = append(warnings, lintExportsDestinations(image)...)
+
+		if headFiles == nil {
+			continue
+		}
+
+		if image.Git != nil {
+			for _, git := range image.Git.Local {
+				warnings = append(warnings, lintGitLocalPaths(image, git, headFiles)...)
+			}
+		}
+
+		for _, importElm := range image.Import {
+			warnings = append(warnings, lintArtifactImportPath(image, importElm, headFiles)...)
+		}
+	}
+
+	return warnings
+}
+
+func lintImages(werfConfig *WerfConfig) []*ImageBase {
+	var images []*ImageBase
+
+	isImageAdded := func(image *ImageBase) bool {
+		for _, img := range images {
+			if img == image {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, image := range werfConfig.Images {
+		for _, elm := range image.ImageTree() {
+			var imageBase *ImageBase
+			switch img := elm.(type) {
+			case *Image:
+				imageBase = img.ImageBase
+			case *ImageArtifact:
+				imageBase = img.ImageBase
+			default:
+				continue
+			}
+
+			if !isImageAdded(imageBase) {
+				images = append(images, imageBase)
+			}
+		}
+	}
+
+	return images
+}
+
+func lintExportsDestinations(image *ImageBase) []*LintWarning {
+	var warnings []*LintWarning
+
+	exports := image.exports()
+	for i, exp1 := range exports {
+		for _, exp2 := range exports[i+1:] {
+			if !isSubPath(exp1.GetTo(), exp2.GetTo()) && !isSubPath(exp2.GetTo(), exp1.GetTo()) {
+				continue
+			}
+
+			warnings = append(warnings, &LintWarning{
+				ImageName: image.Name,
+				Message:   fmt.Sprintf("destinations `%s` and `%s` of git mappings or imports overlap: files of one export are excluded from another implicitly\n\n%s", exp1.GetTo(), exp2.GetTo(), dumpConfigSection(exp1.GetRaw())),
+				section:   exp2.GetRaw(),
+			})
+		}
+	}
+
+	return warnings
+}
+
+func lintGitLocalPaths(image *ImageBase, git *GitLocal, headFiles []string) []*LintWarning {
+	var warnings []*LintWarning
+
+	if !isAnyFileMatches(headFiles, func(path string) bool { return true_git.IsFileInBasePath(path, git.Add) }) {
+		warnings = append(warnings, &LintWarning{
+			ImageName: image.Name,
+			Message:   fmt.Sprintf("git mapping `add: %s` matches no files at HEAD", git.Add),
+			section:   git.GetRaw(),
+		})
+		return warnings
+	}
+
+	for _, includePath := range git.IncludePaths {
+		if !isAnyFileMatches(headFiles, func(path string) bool {
+			return true_git.IsFilePathValid(path, git.Add, []string{includePath}, nil)
+		}) {
+			warnings = append(warnings, &LintWarning{
+				ImageName: image.Name,
+				Message:   fmt.Sprintf("git mapping `add: %s` includePaths pattern `%s` matches no files at HEAD", git.Add, includePath),
+				section:   git.GetRaw(),
+			})
+		}
+	}
+
+	if git.StageDependencies == nil {
+		return warnings
+	}
+
+	for _, stage := range []struct {
+		Name     string
+		Patterns []string
+	}{
+		{"beforeInstall", git.StageDependencies.BeforeInstall},
+		{"install", git.StageDependencies.Install},
+		{"beforeSetup", git.StageDependencies.BeforeSetup},
+		{"setup", git.StageDependencies.Setup},
+		{"check", git.StageDependencies.Check},
+	} {
+		for _, pattern := range stage.Patterns {
+			if !isAnyFileMatches(headFiles, func(path string) bool {
+				if !true_git.IsFilePathValid(path, git.Add, git.IncludePaths, git.ExcludePaths) {
+					return false
+				}
+				return true_git.IsFilePathMatchesOneOfPatterns(true_git.TrimFileBasePath(path, git.Add), []string{pattern})
+			}) {
+				warnings = append(warnings, &LintWarning{
+					ImageName: image.Name,
+					Message:   fmt.Sprintf("git mapping `add: %s` stageDependencies.%s pattern `%s` matches no files of the mapping at HEAD: stage will never be rebuilt on changes", git.Add, stage.Name, pattern),
+					section:   git.GetRaw(),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// lintArtifactImportPath checks imports of the files, which are added into the artifact by local git mappings.
+// Other paths are created by the artifact assembly instructions and cannot be checked.
+func lintArtifactImportPath(image *ImageBase, importElm *ArtifactImport, headFiles []string) []*LintWarning {
+	artifact := importElm.ImageArtifact
+	if artifact == nil || artifact.Git == nil {
+		return nil
+	}
+
+	for _, git := range artifact.Git.Local {
+		if !isSubPath(git.To, importElm.Add) {
+			continue
+		}
+
+		relPath, err := filepath.Rel(git.To, importElm.Add)
+		if err != nil {
+			continue
+		}
+		repoPath := filepath.Join(git.Add, relPath)
+
+		if isAnyFileMatches(headFiles, func(path string) bool {
+			return true_git.IsFileInBasePath(path, repoPath) && true_git.IsFilePathValid(path, git.Add, git.IncludePaths, git.ExcludePaths)
+		}) {
+			return nil
+		}
+
+		return []*LintWarning{{
+			ImageName: image.Name,
+			Message:   fmt.Sprintf("import `add: %s` from artifact %s is added by git mapping `add: %s`, but path `%s` does not exist in the mapping at HEAD", importElm.Add, importElm.ArtifactName, git.Add, strings.TrimPrefix(repoPath, "/")),
+			section:   importElm.GetRaw(),
+		}}
+	}
+
+	return nil
+}
+
+func isAnyFileMatches(files []string, matchFunc func(path string) bool) bool {
+	for _, path := range files {
+		if matchFunc(path) {
+			return true
+		}
+	}
+	return false
+}
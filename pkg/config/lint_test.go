@@ -0,0 +1,117 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const lintTestWerfConfig = `
+project: test
+---
+artifact: assets
+from: alpine:3.9
+git:
+- add: /frontend
+  to: /assets
+---
+image: app
+from: alpine:3.9
+git:
+- add: /src
+  to: /app
+  includePaths: ["*.go", go.mod, "*.py"]
+  stageDependencies:
+    install: [go.mod, requirements.txt]
+- add: /config
+  to: /app/config
+- add: /missing
+  to: /missing
+import:
+- artifact: assets
+  add: /assets/dist
+  to: /static
+  before: setup
+`
+
+var lintTestHeadFiles = []string{"src/main.go", "src/go.mod", "config/app.yaml", "frontend/package.json"}
+
+func lintTestWarnings(t *testing.T, headFiles []string) []*LintWarning {
+	werfConfig, err := parseTestWerfConfig(t, lintTestWerfConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Lint(werfConfig, headFiles)
+}
+
+func expectLintWarnings(t *testing.T, warnings []*LintWarning, expectedMessagePrefixes []string) {
+	if len(warnings) != len(expectedMessagePrefixes) {
+		var messages []string
+		for _, warning := range warnings {
+			messages = append(messages, warning.Message)
+		}
+		t.Fatalf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedMessagePrefixes, messages)
+	}
+
+	for i, warning := range warnings {
+		if warning.ImageName != "app" || !strings.HasPrefix(warning.Message, expectedMessagePrefixes[i]) {
+			t.Errorf("\n[EXPECTED]: image app: %s\n[GOT]: image %s: %s", expectedMessagePrefixes[i], warning.ImageName, warning.Message)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	expectLintWarnings(t, lintTestWarnings(t, lintTestHeadFiles), []string{
+		"destinations `/app` and `/app/config` of git mappings or imports overlap: files of one export are excluded from another implicitly",
+		"git mapping `add: /src` includePaths pattern `*.py` matches no files at HEAD",
+		"git mapping `add: /src` stageDependencies.install pattern `requirements.txt` matches no files of the mapping at HEAD: stage will never be rebuilt on changes",
+		"git mapping `add: /missing` matches no files at HEAD",
+		"import `add: /assets/dist` from artifact assets is added by git mapping `add: /frontend`, but path `frontend/dist` does not exist in the mapping at HEAD",
+	})
+}
+
+func TestLint_withoutHeadFiles(t *testing.T) {
+	expectLintWarnings(t, lintTestWarnings(t, nil), []string{
+		"destinations `/app` and `/app/config` of git mappings or imports overlap: files of one export are excluded from another implicitly",
+	})
+}
+
+func TestLint_noWarnings(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+artifact: assets
+from: alpine:3.9
+git:
+- add: /frontend
+  to: /assets
+---
+image: app
+from: alpine:3.9
+git:
+- add: /src
+  to: /app
+  includePaths: ["*.go"]
+  stageDependencies:
+    install: "*.go"
+import:
+- artifact: assets
+  add: /assets
+  to: /static
+  before: setup
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if warnings := Lint(werfConfig, lintTestHeadFiles); len(warnings) != 0 {
+		t.Errorf("\n[EXPECTED]: no warnings\n[GOT]: %s", warnings[0])
+	}
+}
+
+func TestLintWarning_String(t *testing.T) {
+	warning := &LintWarning{Message: "message"}
+	if expected := "image ~: message"; warning.String() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, warning.String())
+	}
+}
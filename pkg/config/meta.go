@@ -2,5 +2,12 @@ package config
 
 type Meta struct {
 	Project         string
+	ConfigVersion   int
+	StagesNamespace string
+	CacheVersion    string
+	Build           MetaBuild
 	DeployTemplates DeployTemplates
+	Publish         MetaPublish
+	Registries      []MetaRegistry
+	Secrets         MetaSecrets
 }
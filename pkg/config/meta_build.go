@@ -0,0 +1,7 @@
+package config
+
+type MetaBuild struct {
+	Labels                  map[string]string
+	CommitSignatureKeyrings []string
+	DeterministicArchives   bool
+}
@@ -0,0 +1,38 @@
+package config
+
+type MetaPublish struct {
+	Repo       string
+	StagesRepo string
+	Tag        MetaPublishTag
+	Cleanup    MetaPublishCleanup
+	Sign       MetaPublishSign
+}
+
+type MetaPublishTag struct {
+	Tags    []string
+	Branch  bool
+	Commit  bool
+	BuildID bool
+	CI      bool
+	// Custom contains tag templates, see tag_template package
+	Custom []string
+}
+
+// IsEmpty returns true if no tag strategy is specified
+func (c MetaPublishTag) IsEmpty() bool {
+	return len(c.Tags) == 0 && !c.Branch && !c.Commit && !c.BuildID && !c.CI && len(c.Custom) == 0
+}
+
+// MetaPublishSign configures signing of the published images, images are signed when --sign-images option is specified
+type MetaPublishSign struct {
+	Signer string
+	Key    string
+}
+
+// MetaPublishCleanup contains cleanup policies, nil value means that policy is not specified
+type MetaPublishCleanup struct {
+	GitTagsExpiryDatePeriod    *int64
+	GitTagsLimit               *int64
+	GitCommitsExpiryDatePeriod *int64
+	GitCommitsLimit            *int64
+}
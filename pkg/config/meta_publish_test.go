@@ -0,0 +1,117 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseTestMeta(t *testing.T, metaDirectives string) (*Meta, error) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+`+metaDirectives+`---
+image: app
+from: alpine:3.9
+`)
+	if err != nil {
+		return nil, err
+	}
+
+	return werfConfig.Meta, nil
+}
+
+func TestMetaPublish(t *testing.T) {
+	meta, err := parseTestMeta(t, `publish:
+  repo: registry.example.com/app
+  stagesRepo: registry.example.com/app/stages
+  tag:
+    tags: [latest, v1.0.0]
+    branch: true
+    custom: ['{{ "{{ branch }}-{{ commit_short }}" }}']
+  cleanup:
+    gitTagsLimit: 10
+    gitCommitsExpiryDatePeriod: 0
+  sign:
+    signer: cosign
+    key: cosign.key
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gitTagsLimit := int64(10)
+	gitCommitsExpiryDatePeriod := int64(0)
+	expected := MetaPublish{
+		Repo:       "registry.example.com/app",
+		StagesRepo: "registry.example.com/app/stages",
+		Tag: MetaPublishTag{
+			Tags:   []string{"latest", "v1.0.0"},
+			Branch: true,
+			Custom: []string{"{{ branch }}-{{ commit_short }}"},
+		},
+		Cleanup: MetaPublishCleanup{
+			GitTagsLimit:               &gitTagsLimit,
+			GitCommitsExpiryDatePeriod: &gitCommitsExpiryDatePeriod,
+		},
+		Sign: MetaPublishSign{Signer: "cosign", Key: "cosign.key"},
+	}
+
+	if !reflect.DeepEqual(meta.Publish, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, meta.Publish)
+	}
+}
+
+func TestMetaPublish_notSpecified(t *testing.T) {
+	meta, err := parseTestMeta(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(meta.Publish, MetaPublish{}) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", MetaPublish{}, meta.Publish)
+	}
+
+	if !meta.Publish.Tag.IsEmpty() {
+		t.Errorf("\n[EXPECTED]: empty publish tag\n[GOT]: %#v", meta.Publish.Tag)
+	}
+}
+
+func TestMetaPublish_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		metaDirectives string
+		errorContains  string
+	}{
+		{
+			"publish:\n  cleanup:\n    gitTagsLimit: -1\n",
+			"publish.cleanup.gitTagsLimit cannot be negative!",
+		},
+		{
+			"publish:\n  cleanup:\n    gitCommitsExpiryDatePeriod: -3600\n",
+			"publish.cleanup.gitCommitsExpiryDatePeriod cannot be negative!",
+		},
+		{
+			"publish:\n  tag:\n    tags: [\"bad tag\"]\n",
+			"bad tag 'bad tag' specified in publish.tag.tags",
+		},
+		{
+			"publish:\n  tag:\n    custom: ['{{ \"{{ branch\" }}']\n",
+			"bad tag template specified in publish.tag.custom",
+		},
+		{
+			"publish:\n  tag:\n    custom: ['{{ \"{{ unknown }}\" }}']\n",
+			"bad tag template specified in publish.tag.custom",
+		},
+		{
+			"publish:\n  sign:\n    signer: gpg\n",
+			"bad publish.sign.signer: unknown signer 'gpg': expected cosign or notation",
+		},
+		{
+			"publish:\n  registry: registry.example.com\n",
+			"registry",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestMeta(t, expectation.metaDirectives)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
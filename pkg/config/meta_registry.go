@@ -0,0 +1,8 @@
+package config
+
+type MetaRegistry struct {
+	Address       string
+	Insecure      bool
+	SkipTLSVerify bool
+	CAFile        string
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetaRegistries(t *testing.T) {
+	meta, err := parseTestMeta(t, `registries:
+- address: registry.example.com:5000
+  caFile: certs/ca.crt
+- address: localhost:5000
+  insecure: true
+- address: registry.local
+  skipTLSVerify: true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MetaRegistry{
+		{Address: "registry.example.com:5000", CAFile: "certs/ca.crt"},
+		{Address: "localhost:5000", Insecure: true},
+		{Address: "registry.local", SkipTLSVerify: true},
+	}
+
+	if !reflect.DeepEqual(meta.Registries, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, meta.Registries)
+	}
+}
+
+func TestMetaRegistries_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		metaDirectives string
+		errorContains  string
+	}{
+		{
+			"registries:\n- insecure: true\n",
+			"registry address cannot be empty!",
+		},
+		{
+			"registries:\n- address: https://registry.example.com\n",
+			"bad registry address 'https://registry.example.com': expected HOST[:PORT] without scheme and path",
+		},
+		{
+			"registries:\n- address: registry.example.com/app\n",
+			"bad registry address 'registry.example.com/app': expected HOST[:PORT] without scheme and path",
+		},
+		{
+			"registries:\n- address: localhost:5000\n  insecure: true\n  caFile: ca.crt\n",
+			"registry 'localhost:5000': caFile cannot be used with insecure registry!",
+		},
+		{
+			"registries:\n- address: localhost:5000\n  username: admin\n",
+			"username",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestMeta(t, expectation.metaDirectives)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
@@ -0,0 +1,18 @@
+package config
+
+const (
+	AesSecretsBackend   = "aes"
+	AgeSecretsBackend   = "age"
+	GPGSecretsBackend   = "gpg"
+	VaultSecretsBackend = "vault"
+)
+
+// MetaSecrets selects backend to encrypt project secrets, values encrypted by other backends are still decrypted
+type MetaSecrets struct {
+	Backend              string
+	AgeRecipients        []string
+	GPGRecipientsKeyring string
+	VaultAddress         string
+	VaultTransitMount    string
+	VaultTransitKey      string
+}
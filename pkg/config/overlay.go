@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/flant/yaml.v2"
+
+	"github.com/flant/werf/pkg/werf"
+)
+
+const configOverlayDownloadTimeout = 30 * time.Second
+
+// configOverlay is a config file, which is merged over werf.yaml docs: meta section is merged over the meta doc,
+// images and artifacts sections are merged over every image and artifact doc
+type configOverlay struct {
+	Path      string
+	Meta      yaml.MapSlice
+	Images    yaml.MapSlice
+	Artifacts yaml.MapSlice
+
+	content   []byte
+	locations map[string]*docLine
+}
+
+// section returns meta, images or artifacts section of the overlay
+func (o *configOverlay) section(name string) yaml.MapSlice {
+	switch name {
+	case "meta":
+		return o.Meta
+	case "images":
+		return o.Images
+	case "artifacts":
+		return o.Artifacts
+	default:
+		panic(fmt.Sprintf("unknown config overlay section %s", name))
+	}
+}
+
+func isConfigOverlayUrl(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// ResolveConfigOverlayPaths returns overlay paths, where relative local paths are resolved against the project dir, urls are kept as is
+func ResolveConfigOverlayPaths(projectDir string, overlayPaths []string) []string {
+	var res []string
+	for _, path := range overlayPaths {
+		if !isConfigOverlayUrl(path) && !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+
+		res = append(res, path)
+	}
+
+	return res
+}
+
+func readConfigOverlays(paths []string) ([]*configOverlay, error) {
+	var overlays []*configOverlay
+	for _, path := range paths {
+		content, err := readConfigOverlayContent(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read config overlay %s: %s", path, err)
+		}
+
+		overlay, err := parseConfigOverlay(path, content)
+		if err != nil {
+			return nil, err
+		}
+
+		overlays = append(overlays, overlay)
+	}
+
+	return overlays, nil
+}
+
+// readConfigOverlayContent reads local overlay file or downloads overlay by https url.
+// Overlay changes the build, so downloading overlay by plain http is not allowed
+func readConfigOverlayContent(path string) ([]byte, error) {
+	if !isConfigOverlayUrl(path) {
+		return ioutil.ReadFile(path)
+	}
+
+	if !strings.HasPrefix(path, "https://") {
+		return nil, fmt.Errorf("plain http url is not allowed, use https")
+	}
+
+	if werf.IsOffline() {
+		return nil, werf.OfflineError("remote config overlay")
+	}
+
+	client := &http.Client{Timeout: configOverlayDownloadTimeout}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func parseConfigOverlay(path string, content []byte) (*configOverlay, error) {
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("bad config overlay %s: %s", path, err)
+	}
+
+	overlay := &configOverlay{Path: path, content: content}
+	overlay.locations = yamlLinesLocations(content, func(contentLine int) *docLine {
+		return &docLine{FilePath: path, Line: contentLine + 1}
+	})
+	for _, item := range raw {
+		section, ok := item.Value.(yaml.MapSlice)
+		if !ok && item.Value != nil {
+			return nil, fmt.Errorf("bad config overlay %s: `%v` section should be a map", path, item.Key)
+		}
+
+		var forbiddenKeys []string
+		switch item.Key {
+		case "meta":
+			overlay.Meta = section
+			forbiddenKeys = []string{"project", "includes"}
+		case "images":
+			overlay.Images = section
+			forbiddenKeys = []string{"image", "artifact", "blocks"}
+		case "artifacts":
+			overlay.Artifacts = section
+			forbiddenKeys = []string{"image", "artifact", "blocks"}
+		default:
+			return nil, fmt.Errorf("bad config overlay %s: unknown section `%v`, expected meta, images or artifacts", path, item.Key)
+		}
+
+		for _, key := range forbiddenKeys {
+			if isYamlMapKeyExist(section, key) {
+				return nil, fmt.Errorf("bad config overlay %s: `%s` directive cannot be overridden in `%v` section", path, key, item.Key)
+			}
+		}
+	}
+
+	return overlay, nil
+}
+
+// configOverlaysChecksumContent returns overlays contents, which are a part of werf config checksum
+func configOverlaysChecksumContent(overlays []*configOverlay) string {
+	var parts []string
+	for _, overlay := range overlays {
+		parts = append(parts, string(overlay.content))
+	}
+
+	return strings.Join(parts, "\n---\n")
+}
+
+// applyConfigOverlays merges overlays sections over the doc in the order of overlays specification:
+// maps are merged, lists of the doc and the overlay are concatenated, other values of the overlay replace values of the doc
+func applyConfigOverlays(d *doc, overlays []*configOverlay, sectionName string) (*doc, error) {
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(d.Content, &raw); err != nil {
+		return nil, newYamlUnmarshalError(err, d)
+	}
+
+	// lines tree is merged the same way as the content to keep locations of the merged content lines
+	rawLines := newLinesTree(raw, nil, yamlLinesLocations(d.Content, d.lineLocation))
+
+	var appliedOverlays []string
+	for _, overlay := range overlays {
+		if content := overlay.section(sectionName); len(content) != 0 {
+			raw = mergeYamlMaps(raw, content)
+			rawLines = mergeYamlMaps(rawLines, newLinesTree(content, []string{sectionName}, overlay.locations))
+			appliedOverlays = append(appliedOverlays, overlay.Path)
+		}
+	}
+
+	if len(appliedOverlays) == 0 {
+		return d, nil
+	}
+
+	content, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, newDetailedConfigError(fmt.Sprintf("cannot apply config overlays: %s", err), nil, d)
+	}
+
+	return &doc{
+		Content:        content,
+		Line:           d.Line,
+		RenderFilePath: d.RenderFilePath,
+		SourceFilePath: d.SourceFilePath,
+		SourceLine:     d.SourceLine,
+		Blocks:         d.Blocks,
+		Overlays:       append(append([]string{}, d.Overlays...), appliedOverlays...),
+		LineMap:        newLineMap(content, rawLines),
+	}, nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const overlayTestWerfConfig = `
+project: test
+configVersion: 1
+publish:
+  repo: registry.example.com/test
+  cleanup:
+    gitCommitsLimit: 10
+---
+image: app
+from: alpine:3.9
+docker:
+  LABEL:
+    app: test
+mount:
+- from: tmp_dir
+  to: /tmp
+`
+
+func TestParseWerfConfig_configOverlays(t *testing.T) {
+	werfConfig, err := parseTestWerfConfigWithFiles(t, map[string]string{
+		"werf.yaml": overlayTestWerfConfig,
+		"common.yaml": `
+meta:
+  publish:
+    cleanup:
+      gitCommitsExpiryDatePeriod: 2592000
+      gitCommitsLimit: 50
+images:
+  from: alpine:3.10
+  docker:
+    LABEL:
+      team: platform
+  mount:
+  - from: build_dir
+    to: /var/cache/apt
+`,
+		"env.yaml": `
+images:
+  docker:
+    LABEL:
+      team: env
+`,
+	}, "common.yaml", "env.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := werfConfig.Meta.Publish.Cleanup
+	if cleanup.GitCommitsLimit == nil || *cleanup.GitCommitsLimit != 50 {
+		t.Errorf("\n[EXPECTED]: gitCommitsLimit replaced by the overlay value 50\n[GOT]: %#v", cleanup.GitCommitsLimit)
+	}
+	if cleanup.GitCommitsExpiryDatePeriod == nil || *cleanup.GitCommitsExpiryDatePeriod != 2592000 {
+		t.Errorf("\n[EXPECTED]: gitCommitsExpiryDatePeriod added by the overlay\n[GOT]: %#v", cleanup.GitCommitsExpiryDatePeriod)
+	}
+	if werfConfig.Meta.Publish.Repo != "registry.example.com/test" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "registry.example.com/test", werfConfig.Meta.Publish.Repo)
+	}
+
+	image := werfConfig.Images[0]
+	if image.From != "alpine:3.10" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "alpine:3.10", image.From)
+	}
+
+	expectedLabels := map[string]string{"app": "test", "team": "env"}
+	if !reflect.DeepEqual(image.Docker.Label, expectedLabels) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedLabels, image.Docker.Label)
+	}
+
+	var mounts [][]string
+	for _, mount := range image.Mount {
+		mounts = append(mounts, []string{mount.Type, mount.To})
+	}
+	expectedMounts := [][]string{{"tmp_dir", "/tmp"}, {"build_dir", "/var/cache/apt"}}
+	if !reflect.DeepEqual(mounts, expectedMounts) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedMounts, mounts)
+	}
+}
+
+func TestParseWerfConfig_configOverlaysChecksum(t *testing.T) {
+	files := map[string]string{"werf.yaml": overlayTestWerfConfig, "overlay.yaml": "images:\n  from: alpine:3.10\n"}
+
+	werfConfig, err := parseTestWerfConfigWithFiles(t, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlaidWerfConfig, err := parseTestWerfConfigWithFiles(t, files, "overlay.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if werfConfig.Checksum() == overlaidWerfConfig.Checksum() {
+		t.Errorf("config checksum is not changed by the config overlay")
+	}
+}
+
+func TestParseWerfConfig_configOverlays_negative(t *testing.T) {
+	tests := []struct {
+		name                   string
+		overlay                string
+		expectedErrorSubstring string
+	}{
+		{
+			name:                   "project cannot be overridden",
+			overlay:                "meta:\n  project: other\n",
+			expectedErrorSubstring: "`project` directive cannot be overridden in `meta` section",
+		},
+		{
+			name:                   "image cannot be overridden",
+			overlay:                "images:\n  image: other\n",
+			expectedErrorSubstring: "`image` directive cannot be overridden in `images` section",
+		},
+		{
+			name:                   "blocks cannot be overridden",
+			overlay:                "artifacts:\n  blocks: [other]\n",
+			expectedErrorSubstring: "`blocks` directive cannot be overridden in `artifacts` section",
+		},
+		{
+			name:                   "unknown section",
+			overlay:                "image:\n  from: alpine\n",
+			expectedErrorSubstring: "unknown section `image`, expected meta, images or artifacts",
+		},
+		{
+			name:                   "section is not a map",
+			overlay:                "images: [alpine]\n",
+			expectedErrorSubstring: "`images` section should be a map",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTestWerfConfigWithFiles(t, map[string]string{"werf.yaml": overlayTestWerfConfig, "overlay.yaml": test.overlay}, "overlay.yaml")
+			expectConfigError(t, err, test.expectedErrorSubstring)
+		})
+	}
+}
+
+func TestParseWerfConfig_configOverlayPlainHttpUrl(t *testing.T) {
+	_, err := parseTestWerfConfigWithFiles(t, map[string]string{"werf.yaml": overlayTestWerfConfig}, "http://example.com/overlay.yaml")
+	expectConfigError(t, err, "plain http url is not allowed, use https")
+}
+
+func TestGetWerfConfig_relativeConfigOverlayPath(t *testing.T) {
+	projectDir, err := ioutil.TempDir("", "werf-config-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	for name, content := range map[string]string{"werf.yaml": overlayTestWerfConfig, "overlay.yaml": "images:\n  from: alpine:3.10\n"} {
+		if err := ioutil.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// working directory is not the project dir
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Chdir(os.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	werfConfig, err := GetWerfConfig(projectDir, "", "overlay.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if werfConfig.Images[0].From != "alpine:3.10" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "alpine:3.10", werfConfig.Images[0].From)
+	}
+}
+
+func TestResolveConfigOverlayPaths(t *testing.T) {
+	paths := ResolveConfigOverlayPaths("/project", []string{"overlay.yaml", "../common/overlay.yaml", "/etc/werf/overlay.yaml", "https://example.com/overlay.yaml"})
+	expectedPaths := []string{"/project/overlay.yaml", "/common/overlay.yaml", "/etc/werf/overlay.yaml", "https://example.com/overlay.yaml"}
+
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedPaths, paths)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -23,29 +22,128 @@ import (
 	yaml "gopkg.in/flant/yaml.v2"
 )
 
-func ParseWerfConfig(werfConfigPath string) (*WerfConfig, error) {
-	werfConfigRenderContent, err := parseWerfConfigYaml(werfConfigPath)
+// GetWerfConfig parses specified config (path is relative to the project directory) or werf.yaml from the project directory.
+// Config templates are read from the .werf directory near the config, config overlays are merged over the config docs
+// (local overlay path is relative to the project directory too)
+func GetWerfConfig(projectDir, configPath string, configOverlays ...string) (*WerfConfig, error) {
+	configOverlays = ResolveConfigOverlayPaths(projectDir, configOverlays)
+
+	if configPath != "" {
+		werfConfigPath := configPath
+		if !filepath.IsAbs(werfConfigPath) {
+			werfConfigPath = filepath.Join(projectDir, werfConfigPath)
+		}
+
+		if !util.FileExists(werfConfigPath) {
+			return nil, fmt.Errorf("config %s not found", werfConfigPath)
+		}
+
+		return ParseWerfConfig(werfConfigPath, configOverlays...)
+	}
+
+	for _, werfConfigName := range []string{"werf.yml", "werf.yaml"} {
+		werfConfigPath := path.Join(projectDir, werfConfigName)
+		if util.FileExists(werfConfigPath) {
+			return ParseWerfConfig(werfConfigPath, configOverlays...)
+		}
+	}
+
+	return nil, errors.New("werf.yaml not found")
+}
+
+// ParseWerfConfig parses werf config from the local file, config overlays (local files or https urls) are merged over the config docs
+func ParseWerfConfig(werfConfigPath string, overlayPaths ...string) (*WerfConfig, error) {
+	return parseWerfConfig(path.Base(werfConfigPath), &localProjectFiles{Dir: path.Dir(werfConfigPath)}, overlayPaths)
+}
+
+// ParseWerfConfigFromGitCommit reads werf.yaml and config templates directly from the commit of the repo.
+// Custom config path is relative to the repo root, config templates are read from the .werf directory near the config
+func ParseWerfConfigFromGitCommit(repo *git_repo.Remote, commit, werfConfigPath string, overlayPaths ...string) (*WerfConfig, error) {
+	if werfConfigPath != "" {
+		files := &gitCommitProjectFiles{Repo: repo, Commit: commit, Dir: path.Dir(path.Clean(werfConfigPath))}
+		werfConfigName := path.Base(werfConfigPath)
+
+		if exist, err := files.IsFileExists(werfConfigName); err != nil {
+			return nil, err
+		} else if !exist {
+			return nil, fmt.Errorf("%s not found in commit `%s` of repo `%s`", werfConfigPath, commit, repo.Url)
+		}
+
+		return parseWerfConfig(werfConfigName, files, overlayPaths)
+	}
+
+	files := &gitCommitProjectFiles{Repo: repo, Commit: commit}
+
+	for _, werfConfigName := range []string{"werf.yml", "werf.yaml"} {
+		if exist, err := files.IsFileExists(werfConfigName); err != nil {
+			return nil, err
+		} else if exist {
+			return parseWerfConfig(werfConfigName, files, overlayPaths)
+		}
+	}
+
+	return nil, fmt.Errorf("werf.yaml not found in commit `%s` of repo `%s`", commit, repo.Url)
+}
+
+// ParseWerfConfigFromLocalGitCommit parses werf config from the commit of the project git repo instead of the project dir,
+// config path is relative to the repo root
+func ParseWerfConfigFromLocalGitCommit(repo *git_repo.Local, commit, werfConfigPath string, overlayPaths ...string) (*WerfConfig, error) {
+	if werfConfigPath != "" {
+		files := &localGitCommitProjectFiles{Repo: repo, Commit: commit, Dir: path.Dir(path.Clean(werfConfigPath))}
+		werfConfigName := path.Base(werfConfigPath)
+
+		if exist, err := files.IsFileExists(werfConfigName); err != nil {
+			return nil, err
+		} else if !exist {
+			return nil, fmt.Errorf("%s not found in commit `%s` of repo %s", werfConfigPath, commit, repo.Path)
+		}
+
+		return parseWerfConfig(werfConfigName, files, overlayPaths)
+	}
+
+	files := &localGitCommitProjectFiles{Repo: repo, Commit: commit}
+
+	for _, werfConfigName := range []string{"werf.yml", "werf.yaml"} {
+		if exist, err := files.IsFileExists(werfConfigName); err != nil {
+			return nil, err
+		} else if exist {
+			return parseWerfConfig(werfConfigName, files, overlayPaths)
+		}
+	}
+
+	return nil, fmt.Errorf("werf.yaml not found in commit `%s` of repo %s", commit, repo.Path)
+}
+
+func parseWerfConfig(werfConfigPath string, projectFiles projectFiles, overlayPaths []string) (*WerfConfig, error) {
+	overlays, err := readConfigOverlays(overlayPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	configParts, err := parseWerfConfigYaml(werfConfigPath, projectFiles)
 	if err != nil {
 		return nil, err
 	}
 
+	werfConfigRenderContent := joinConfigParts(configParts)
+
 	werfConfigRenderPath, err := dumpWerfConfigRender(werfConfigPath, werfConfigRenderContent)
 	if err != nil {
 		return nil, err
 	}
 
-	docs, err := splitByDocs(werfConfigRenderContent, werfConfigRenderPath)
+	docs, err := splitByDocs(configParts, werfConfigRenderPath)
 	if err != nil {
 		return nil, err
 	}
 
-	meta, rawImages, err := splitByMetaAndRawImages(docs)
+	meta, rawImages, err := splitByMetaAndRawImages(docs, werfConfigPath, overlays)
 	if err != nil {
 		return nil, err
 	}
 
 	if meta == nil {
-		defaultProjectName, err := GetProjectName(path.Dir(werfConfigPath))
+		defaultProjectName, err := projectFiles.DefaultProjectName()
 		if err != nil {
 			return nil, err
 		}
@@ -68,9 +166,15 @@ func ParseWerfConfig(werfConfigPath string) (*WerfConfig, error) {
 		return nil, err
 	}
 
+	checksumContent := werfConfigRenderContent
+	if len(overlays) != 0 {
+		checksumContent += "\n---\n" + configOverlaysChecksumContent(overlays)
+	}
+
 	werfConfig := &WerfConfig{
-		Meta:   meta,
-		Images: images,
+		Meta:     meta,
+		Images:   images,
+		checksum: util.Sha256Hash(checksumContent),
 	}
 
 	return werfConfig, nil
@@ -88,20 +192,24 @@ func GetProjectName(projectDir string) (string, error) {
 		}
 
 		if remoteOriginUrl != "" {
-			ep, err := transport.NewEndpoint(remoteOriginUrl)
-			if err != nil {
-				return "", fmt.Errorf("bad url '%s': %s", remoteOriginUrl, err)
-			}
-
-			gitName := strings.TrimSuffix(ep.Path, ".git")
-
-			return slug.Project(gitName), nil
+			return projectNameByGitUrl(remoteOriginUrl)
 		}
 	}
 
 	return slug.Project(name), nil
 }
 
+func projectNameByGitUrl(url string) (string, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return "", fmt.Errorf("bad url '%s': %s", url, err)
+	}
+
+	gitName := strings.TrimSuffix(ep.Path, ".git")
+
+	return slug.Project(gitName), nil
+}
+
 func gitOwnRepoOriginUrl(projectDir string) (string, error) {
 	localGitRepo := &git_repo.Local{
 		Path:   projectDir,
@@ -138,103 +246,125 @@ func dumpWerfConfigRender(werfConfigPath string, werfConfigRenderContent string)
 	return werfConfigRenderPath, nil
 }
 
-func splitByDocs(werfConfigRenderContent string, werfConfigRenderPath string) ([]*doc, error) {
+// splitByDocs splits rendered werf.yaml and included config files by docs, doc lines are counted in the render file,
+// which contains all files separated by the source comments
+func splitByDocs(configParts []*configPart, werfConfigRenderPath string) ([]*doc, error) {
 	var docs []*doc
-	var line int
-	for _, docContent := range splitContent([]byte(werfConfigRenderContent)) {
-		if !emptyDocContent(docContent) {
-			docs = append(docs, &doc{
-				Line:           line,
-				Content:        docContent,
-				RenderFilePath: werfConfigRenderPath,
-			})
+	var partLine int
+	for i, part := range configParts {
+		if i > 0 {
+			partLine++
 		}
 
-		contentLines := bytes.Split(docContent, []byte("\n"))
-		if string(contentLines[len(contentLines)-1]) == "" {
-			contentLines = contentLines[0 : len(contentLines)-1]
+		var line int
+		for _, docContent := range splitContent([]byte(part.Content)) {
+			if !emptyDocContent(docContent) {
+				docs = append(docs, &doc{
+					Line:           partLine + line,
+					Content:        docContent,
+					RenderFilePath: werfConfigRenderPath,
+					SourceFilePath: part.FilePath,
+					SourceLine:     line,
+				})
+			}
+
+			contentLines := bytes.Split(docContent, []byte("\n"))
+			if string(contentLines[len(contentLines)-1]) == "" {
+				contentLines = contentLines[0 : len(contentLines)-1]
+			}
+			line += len(contentLines) + 1
 		}
-		line += len(contentLines) + 1
+
+		partLine += countLines(part.Content)
 	}
 
 	return docs, nil
 }
 
-func parseWerfConfigYaml(werfConfigPath string) (string, error) {
-	data, err := ioutil.ReadFile(werfConfigPath)
+// parseWerfConfigYaml renders werf.yaml and included config files with the same templates from .werf directory
+func parseWerfConfigYaml(werfConfigPath string, projectFiles projectFiles) ([]*configPart, error) {
+	data, err := projectFiles.ReadFile(werfConfigPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	tmpl := template.New("werfConfig")
 	tmpl.Funcs(funcMap(tmpl))
 
-	projectDir := filepath.Dir(werfConfigPath)
-	werfConfigsDir := filepath.Join(projectDir, ".werf")
-	werfConfigsTemplates, err := getWerfConfigsTemplates(werfConfigsDir)
+	werfConfigsDir := ".werf"
+	werfConfigsTemplates, err := getWerfConfigsTemplates(werfConfigsDir, projectFiles)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(werfConfigsTemplates) != 0 {
 		for _, templatePath := range werfConfigsTemplates {
 			templateName, err := filepath.Rel(werfConfigsDir, templatePath)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 
 			extraTemplate := tmpl.New(templateName)
 
 			var filePathData []byte
-			if filePathData, err = ioutil.ReadFile(templatePath); err != nil {
-				return "", err
+			if filePathData, err = projectFiles.ReadFile(templatePath); err != nil {
+				return nil, err
 			}
 
 			if _, err := extraTemplate.Parse(string(filePathData)); err != nil {
-				return "", err
+				return nil, err
 			}
 		}
 	}
 
 	if _, err := tmpl.Parse(string(data)); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	files := files{filepath.Dir(werfConfigPath)}
-	config, err := executeTemplate(tmpl, "werfConfig", map[string]interface{}{"Files": files})
+	files := files{projectFiles}
+	git := gitInfo{projectFiles}
+	templateData := map[string]interface{}{"Files": files, "Git": git}
 
-	return config, err
-}
+	config, err := executeTemplate(tmpl, "werfConfig", templateData)
+	if err != nil {
+		return nil, err
+	}
+
+	configParts := []*configPart{{FilePath: werfConfigPath, Content: config}}
 
-func getWerfConfigsTemplates(path string) ([]string, error) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, nil
+	includedConfigsPaths, err := getIncludedConfigsPaths(werfConfigPath, config, projectFiles)
+	if err != nil {
+		return nil, err
 	}
 
-	var templates []string
-	err := filepath.Walk(path, func(fp string, fi os.FileInfo, err error) error {
+	for _, includedConfigPath := range includedConfigsPaths {
+		includedConfig, err := renderIncludedConfig(tmpl, includedConfigPath, templateData, projectFiles)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("cannot render included config %s: %s", includedConfigPath, err)
 		}
 
-		if fi.IsDir() {
-			return nil
-		}
+		configParts = append(configParts, &configPart{FilePath: includedConfigPath, Content: includedConfig})
+	}
+
+	return configParts, nil
+}
+
+func getWerfConfigsTemplates(path string, projectFiles projectFiles) ([]string, error) {
+	filesList, err := projectFiles.FilesList(path)
+	if err != nil {
+		return nil, err
+	}
 
-		matched, err := filepath.Match("*.tmpl", fi.Name())
+	var templates []string
+	for _, fp := range filesList {
+		matched, err := filepath.Match("*.tmpl", filepath.Base(fp))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if matched {
 			templates = append(templates, fp)
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
 	return templates, nil
@@ -257,24 +387,32 @@ func executeTemplate(tmpl *template.Template, name string, data interface{}) (st
 }
 
 type files struct {
-	HomePath string
+	projectFiles projectFiles
 }
 
 func (f files) Get(path string) string {
-	filePath := filepath.Join(f.HomePath, path)
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		logger.LogWarningF("WARNING: Config: {{ .Files.Get '%s' }}: file '%s' not exist!\n", path, filePath)
+	if exist, err := f.projectFiles.IsFileExists(path); err != nil || !exist {
+		logger.LogWarningF("WARNING: Config: {{ .Files.Get '%s' }}: file not exist!\n", path)
 		return ""
 	}
 
-	b, err := ioutil.ReadFile(filePath)
+	b, err := f.projectFiles.ReadFile(path)
 	if err != nil {
 		return ""
 	}
 	return string(b)
 }
 
+// gitInfo provides metadata of the project git repo commit to the config templates: {{ .Git.Commit.Author.Name }}.
+// Commit is read on demand, so projects without git repo can be used if templates do not use it.
+type gitInfo struct {
+	projectFiles projectFiles
+}
+
+func (g gitInfo) Commit() (*git_repo.CommitInfo, error) {
+	return g.projectFiles.HeadCommitInfo()
+}
+
 func splitContent(content []byte) (docsContents [][]byte) {
 	const (
 		stateLineBegin   = "stateLineBegin"
@@ -432,6 +570,14 @@ func splitByImages(rawImages []*rawImage, werfConfigRenderContent string, werfCo
 		return nil, err
 	}
 
+	if err := associateImagesDependsOn(images, artifacts); err != nil {
+		return nil, err
+	}
+
+	if err := validateImagesDependenciesCycles(images, artifacts); err != nil {
+		return nil, err
+	}
+
 	return images, nil
 }
 
@@ -538,6 +684,86 @@ func associateImagesFrom(images []*Image, artifacts []*ImageArtifact) error {
 	return nil
 }
 
+func associateImagesDependsOn(images []*Image, artifacts []*ImageArtifact) error {
+	for _, image := range images {
+		if err := image.associateDependsOn(images, artifacts); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range artifacts {
+		if err := image.associateDependsOn(images, artifacts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateImagesDependenciesCycles checks that images do not depend on themselves through fromImage, import and dependsOn directives
+func validateImagesDependenciesCycles(images []*Image, artifacts []*ImageArtifact) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[ImageInterface]int{}
+
+	var visit func(image ImageInterface, path []string) error
+	visit = func(image ImageInterface, path []string) error {
+		name := imageInterfaceName(image)
+
+		switch state[image] {
+		case visiting:
+			return newConfigError(fmt.Sprintf("images dependencies cycle detected: %s!", strings.Join(append(path, name), " -> ")))
+		case visited:
+			return nil
+		}
+
+		state[image] = visiting
+
+		fromImage, imports, dependsOn := ImageDependencies(image)
+		dependencies := append(imports, dependsOn...)
+		if fromImage != nil {
+			dependencies = append([]ImageInterface{fromImage}, dependencies...)
+		}
+
+		for _, dependency := range dependencies {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		state[image] = visited
+
+		return nil
+	}
+
+	for _, image := range images {
+		if err := visit(image, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range artifacts {
+		if err := visit(image, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func imageInterfaceName(image ImageInterface) string {
+	switch i := image.(type) {
+	case *Image:
+		return i.Name
+	case *ImageArtifact:
+		return i.Name
+	default:
+		panic("runtime error")
+	}
+}
+
 func associateImageFrom(image ImageInterface, images []*Image, artifacts []*ImageArtifact) error {
 	switch image.(type) {
 	case *Image:
@@ -549,10 +775,19 @@ func associateImageFrom(image ImageInterface, images []*Image, artifacts []*Imag
 	}
 }
 
-func splitByMetaAndRawImages(docs []*doc) (*Meta, []*rawImage, error) {
+func splitByMetaAndRawImages(docs []*doc, werfConfigPath string, overlays []*configOverlay) (*Meta, []*rawImage, error) {
 	var rawImages []*rawImage
 	var resultMeta *Meta
 
+	if err := checkDocsDuplicateKeys(docs); err != nil {
+		return nil, nil, err
+	}
+
+	blocks, err := parseBlocks(docs)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	parentStack = util.NewStack()
 	for _, doc := range docs {
 		var raw map[string]interface{}
@@ -561,23 +796,49 @@ func splitByMetaAndRawImages(docs []*doc) (*Meta, []*rawImage, error) {
 			return nil, nil, newYamlUnmarshalError(err, doc)
 		}
 
-		if isMetaDoc(raw) {
+		if isBlockDoc(raw) {
+			continue
+		} else if isMetaDoc(raw) {
 			if resultMeta != nil {
 				return nil, nil, newYamlUnmarshalError(errors.New("duplicate meta definition"), doc)
 			}
 
-			rawMeta := &rawMeta{doc: doc}
-			err := yaml.Unmarshal(doc.Content, &rawMeta)
+			metaDoc, err := applyConfigOverlays(doc, overlays, "meta")
+			if err != nil {
+				return nil, nil, err
+			}
+
+			rawMeta := &rawMeta{doc: metaDoc}
+			err = yaml.Unmarshal(metaDoc.Content, &rawMeta)
 			if err != nil {
-				return nil, nil, newYamlUnmarshalError(err, doc)
+				return nil, nil, newYamlUnmarshalError(err, metaDoc)
+			}
+
+			if len(rawMeta.Includes) != 0 && doc.SourceFilePath != werfConfigPath {
+				return nil, nil, newDetailedConfigError(fmt.Sprintf("includes directive should be defined in %s!", werfConfigPath), nil, doc)
 			}
 
 			resultMeta = rawMeta.toMeta()
 		} else if isImageDoc(raw) {
-			image := &rawImage{doc: doc}
-			err := yaml.Unmarshal(doc.Content, &image)
+			imageDoc, err := applyBlocks(doc, blocks)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			overlaySection := "images"
+			if _, ok := raw["artifact"]; ok {
+				overlaySection = "artifacts"
+			}
+
+			imageDoc, err = applyConfigOverlays(imageDoc, overlays, overlaySection)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			image := &rawImage{doc: imageDoc}
+			err = yaml.Unmarshal(imageDoc.Content, &image)
 			if err != nil {
-				return nil, nil, newYamlUnmarshalError(err, doc)
+				return nil, nil, newYamlUnmarshalError(err, imageDoc)
 			}
 
 			rawImages = append(rawImages, image)
@@ -589,6 +850,36 @@ func splitByMetaAndRawImages(docs []*doc) (*Meta, []*rawImage, error) {
 	return resultMeta, rawImages, nil
 }
 
+// checkDocsDuplicateKeys checks duplicate keys in all docs if strict config version is specified in the meta doc
+func checkDocsDuplicateKeys(docs []*doc) error {
+	var configVersion int
+	for _, doc := range docs {
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(doc.Content, &raw); err != nil {
+			return newYamlUnmarshalError(err, doc)
+		}
+
+		if isMetaDoc(raw) {
+			if version, ok := raw["configVersion"].(int); ok {
+				configVersion = version
+			}
+			break
+		}
+	}
+
+	if configVersion < StrictConfigVersion {
+		return nil
+	}
+
+	for _, doc := range docs {
+		if err := checkDuplicateKeys(doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func isMetaDoc(h map[string]interface{}) bool {
 	if _, ok := h["project"]; ok {
 		return true
@@ -626,7 +917,13 @@ func newYamlUnmarshalError(err error, doc *doc) error {
 				return err
 			}
 
-			message = reg.ReplaceAllString(message, fmt.Sprintf("line %d", line+doc.Line))
+			if location := doc.lineLocation(line - 1); location != nil {
+				if location.FilePath != doc.RenderFilePath {
+					message = reg.ReplaceAllString(message, fmt.Sprintf("line %d of %s", location.Line, location.FilePath))
+				} else {
+					message = reg.ReplaceAllString(message, fmt.Sprintf("line %d", location.Line))
+				}
+			}
 		}
 		return newDetailedConfigError(message, nil, doc)
 	}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseTestWerfConfig parses werf.yaml with specified content from the temporary project dir
+func parseTestWerfConfig(t *testing.T, werfConfigContent string) (*WerfConfig, error) {
+	return parseTestWerfConfigWithFiles(t, map[string]string{"werf.yaml": werfConfigContent})
+}
+
+// parseTestWerfConfigWithFiles parses werf.yaml from the temporary project dir with specified files (relative path => content)
+func parseTestWerfConfigWithFiles(t *testing.T, files map[string]string, overlayPaths ...string) (*WerfConfig, error) {
+	projectDir, err := ioutil.TempDir("", "werf-config-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	for relPath, content := range files {
+		path := filepath.Join(projectDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rendered config is dumped into the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatal(err)
+	}
+
+	return ParseWerfConfig(filepath.Join(projectDir, "werf.yaml"), overlayPaths...)
+}
+
+// parseTestStagesDirectives parses image from alpine:3.9 with specified directives
+func parseTestStagesDirectives(t *testing.T, directives string) (*Image, error) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+`+directives)
+	if err != nil {
+		return nil, err
+	}
+
+	return werfConfig.Images[0], nil
+}
+
+func expectConfigError(t *testing.T, err error, expectedErrorSubstring string) {
+	if err == nil {
+		t.Errorf("\n[EXPECTED]: error containing %q", expectedErrorSubstring)
+	} else if !strings.Contains(err.Error(), expectedErrorSubstring) {
+		t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %s", expectedErrorSubstring, err.Error())
+	}
+}
+
+func TestParseWerfConfig(t *testing.T) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+shell:
+  install: echo install
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if werfConfig.Meta.Project != "test" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "test", werfConfig.Meta.Project)
+	}
+
+	if len(werfConfig.Images) != 1 || werfConfig.Images[0].Name != "app" || werfConfig.Images[0].From != "alpine:3.9" {
+		t.Errorf("\n[EXPECTED]: image app from alpine:3.9\n[GOT]: %#v", werfConfig.Images)
+	}
+}
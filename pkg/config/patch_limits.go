@@ -0,0 +1,25 @@
+package config
+
+// PatchLimits are thresholds of the git patch: when patch exceeds any of them, archive of the latest commit is applied instead of the patch
+type PatchLimits struct {
+	MaxSize  int64
+	MaxFiles int
+
+	raw *rawPatchLimits
+}
+
+func (c *PatchLimits) validate() error {
+	if c.MaxSize < 0 {
+		return newDetailedConfigError("`maxSize: SIZE` should be positive size (e.g. 10MiB)!", c.raw, c.raw.rawGit.rawImage.doc)
+	}
+
+	if c.MaxFiles < 0 {
+		return newDetailedConfigError("`maxFiles: NUMBER` should be positive number!", c.raw, c.raw.rawGit.rawImage.doc)
+	}
+
+	if c.MaxSize == 0 && c.MaxFiles == 0 {
+		return newDetailedConfigError("`maxSize: SIZE` or `maxFiles: NUMBER` required in `patchLimits`!", c.raw, c.raw.rawGit.rawImage.doc)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestPatchLimits(t *testing.T) {
+	var positiveExpectations = []struct {
+		gitOptions string
+		maxSize    int64
+		maxFiles   int
+	}{
+		{
+			"  patchLimits:\n    maxSize: 10MiB\n",
+			10 * 1024 * 1024,
+			0,
+		},
+		{
+			"  patchLimits:\n    maxFiles: 1000\n",
+			0,
+			1000,
+		},
+		{
+			"  patchLimits:\n    maxSize: 512KiB\n    maxFiles: 10\n",
+			512 * 1024,
+			10,
+		},
+	}
+
+	for _, expectation := range positiveExpectations {
+		git, err := parseTestGitLocal(t, expectation.gitOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if git.PatchLimits.MaxSize != expectation.maxSize || git.PatchLimits.MaxFiles != expectation.maxFiles {
+			t.Errorf("\n[EXPECTED]: %d, %d\n[GOT]: %d, %d", expectation.maxSize, expectation.maxFiles, git.PatchLimits.MaxSize, git.PatchLimits.MaxFiles)
+		}
+	}
+
+	git, err := parseTestGitLocal(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if git.PatchLimits != nil {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", nil, git.PatchLimits)
+	}
+}
+
+func TestPatchLimits_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		gitOptions    string
+		errorContains string
+	}{
+		{
+			"  patchLimits:\n    maxSize: a lot\n",
+			"invalid `maxSize: a lot`",
+		},
+		{
+			"  patchLimits:\n    maxSize: -1MiB\n",
+			"invalid `maxSize: -1MiB`",
+		},
+		{
+			"  patchLimits:\n    maxFiles: -1\n",
+			"`maxFiles: NUMBER` should be positive number!",
+		},
+		{
+			"  patchLimits: {}\n",
+			"`maxSize: SIZE` or `maxFiles: NUMBER` required in `patchLimits`!",
+		},
+		{
+			"  patchLimits:\n    maxLines: 10\n",
+			"maxLines",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestGitLocal(t, expectation.gitOptions)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
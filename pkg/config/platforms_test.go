@@ -0,0 +1,120 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseTestImageWithoutFrom(t *testing.T, directives string) (*Image, error) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+configVersion: 1
+---
+image: app
+`+directives)
+	if err != nil {
+		return nil, err
+	}
+
+	return werfConfig.Images[0], nil
+}
+
+func TestFromPlatforms(t *testing.T) {
+	image, err := parseTestImageWithoutFrom(t, `
+from:
+  linux/amd64: ubuntu:18.04
+  linux/arm64/v8: arm64v8/ubuntu:18.04
+stagesPlatforms:
+  install: [linux/amd64]
+  setup: [linux/amd64, linux/arm64/v8]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if image.From != "" {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", "", image.From)
+	}
+
+	expectedFromPlatforms := map[string]string{"linux/amd64": "ubuntu:18.04", "linux/arm64/v8": "arm64v8/ubuntu:18.04"}
+	if !reflect.DeepEqual(image.FromPlatforms, expectedFromPlatforms) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedFromPlatforms, image.FromPlatforms)
+	}
+
+	expectedStagesPlatforms := map[string][]string{"install": {"linux/amd64"}, "setup": {"linux/amd64", "linux/arm64/v8"}}
+	if !reflect.DeepEqual(image.StagesPlatforms, expectedStagesPlatforms) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedStagesPlatforms, image.StagesPlatforms)
+	}
+}
+
+func TestFromPlatforms_singleFrom(t *testing.T) {
+	image, err := parseTestImageWithoutFrom(t, "from: alpine:3.9\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if image.From != "alpine:3.9" || image.FromPlatforms != nil || image.StagesPlatforms != nil {
+		t.Errorf("\n[EXPECTED]: %#v, nil, nil\n[GOT]: %#v, %#v, %#v", "alpine:3.9", image.From, image.FromPlatforms, image.StagesPlatforms)
+	}
+}
+
+func TestFromPlatforms_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		directives    string
+		errorContains string
+	}{
+		{
+			"from:\n  amd64: ubuntu:18.04\n",
+			"invalid platform `amd64` in from: OS/ARCH[/VARIANT] expected (e.g. linux/amd64 or linux/arm64/v8)!",
+		},
+		{
+			"from:\n  linux/arm64/v8/extra: ubuntu:18.04\n",
+			"invalid platform `linux/arm64/v8/extra` in from: OS/ARCH[/VARIANT] expected",
+		},
+		{
+			"from:\n  linux//v8: ubuntu:18.04\n",
+			"invalid platform `linux//v8` in from: OS/ARCH[/VARIANT] expected",
+		},
+		{
+			"from:\n  linux/amd64: \"\"\n",
+			"invalid `linux/amd64: ` in from: docker image name expected!",
+		},
+		{
+			"from:\n  linux/amd64: [ubuntu:18.04]\n",
+			"invalid `linux/amd64: [ubuntu:18.04]` in from: docker image name expected!",
+		},
+		{
+			"from: [ubuntu:18.04]\n",
+			"invalid from `[ubuntu:18.04]`: docker image name or map of docker images by platform expected!",
+		},
+		{
+			"from:\n  linux/amd64: ubuntu:18.04\nfromImage: base\n---\nimage: base\nfrom: alpine:3.9\n",
+			"conflict between `from`, `fromImage` and `fromImageArtifact` directives!",
+		},
+		{
+			"from: alpine:3.9\nstagesPlatforms:\n  compile: [linux/amd64]\n",
+			"unknown stage `compile` in stagesPlatforms: expected one of from, ",
+		},
+		{
+			"from: alpine:3.9\nstagesPlatforms:\n  git_archive: [linux/amd64]\n",
+			"stage `git_archive` cannot be specified in stagesPlatforms: this stage is required for every platform!",
+		},
+		{
+			"from: alpine:3.9\nstagesPlatforms:\n  from: [linux/amd64]\n",
+			"stage `from` cannot be specified in stagesPlatforms: this stage is required for every platform!",
+		},
+		{
+			"from: alpine:3.9\nstagesPlatforms:\n  install: []\n",
+			"invalid `install` in stagesPlatforms: non-empty list of platforms expected!",
+		},
+		{
+			"from: alpine:3.9\nstagesPlatforms:\n  install: [amd64]\n",
+			"invalid platform `amd64` of stage `install` in stagesPlatforms: OS/ARCH[/VARIANT] expected (e.g. linux/amd64 or linux/arm64/v8)!",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestImageWithoutFrom(t, expectation.directives)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
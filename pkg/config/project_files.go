@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/flant/werf/pkg/git_repo"
+)
+
+// projectFiles provides access to the project files used while werf config rendering
+type projectFiles interface {
+	IsFileExists(relPath string) (bool, error)
+	ReadFile(relPath string) ([]byte, error)
+	FilesList(relDir string) ([]string, error)
+	DefaultProjectName() (string, error)
+	// HeadCommitInfo returns metadata of the commit, which config is read from (HEAD commit of the local repo)
+	HeadCommitInfo() (*git_repo.CommitInfo, error)
+}
+
+type localProjectFiles struct {
+	Dir string
+}
+
+func (f *localProjectFiles) IsFileExists(relPath string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(f.Dir, relPath)); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (f *localProjectFiles) ReadFile(relPath string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(f.Dir, relPath))
+}
+
+func (f *localProjectFiles) FilesList(relDir string) ([]string, error) {
+	dir := filepath.Join(f.Dir, relDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var res []string
+	err := filepath.Walk(dir, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(f.Dir, fp)
+		if err != nil {
+			return err
+		}
+
+		res = append(res, relPath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (f *localProjectFiles) DefaultProjectName() (string, error) {
+	return GetProjectName(f.Dir)
+}
+
+// HeadCommitInfo looks for the git repo in the config dir and its parents, because config can reside in the subdirectory of the repo
+func (f *localProjectFiles) HeadCommitInfo() (*git_repo.CommitInfo, error) {
+	dir, err := filepath.Abs(f.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if _, err := os.Stat(gitDir); err == nil {
+			localGitRepo := &git_repo.Local{Path: dir, GitDir: gitDir}
+
+			commit, err := localGitRepo.HeadCommit()
+			if err != nil {
+				return nil, fmt.Errorf("cannot get HEAD commit of git repo %s: %s", dir, err)
+			}
+
+			return localGitRepo.CommitInfo(commit)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil, fmt.Errorf("project directory %s is not a git repo", f.Dir)
+		}
+		dir = parentDir
+	}
+}
+
+type gitCommitProjectFiles struct {
+	Repo   *git_repo.Remote
+	Commit string
+	// Dir is a directory of the config in the repo, paths are relative to this directory
+	Dir string
+}
+
+func (f *gitCommitProjectFiles) repoPath(relPath string) string {
+	return path.Join(f.Dir, relPath)
+}
+
+func (f *gitCommitProjectFiles) IsFileExists(relPath string) (bool, error) {
+	return f.Repo.IsCommitFileExists(f.Commit, f.repoPath(relPath))
+}
+
+func (f *gitCommitProjectFiles) ReadFile(relPath string) ([]byte, error) {
+	return f.Repo.ReadCommitFile(f.Commit, f.repoPath(relPath))
+}
+
+func (f *gitCommitProjectFiles) FilesList(relDir string) ([]string, error) {
+	filesList, err := f.Repo.CommitFilesList(f.Commit, f.repoPath(relDir))
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Dir == "" || f.Dir == "." {
+		return filesList, nil
+	}
+
+	var res []string
+	for _, fp := range filesList {
+		res = append(res, strings.TrimPrefix(fp, strings.TrimSuffix(f.Dir, "/")+"/"))
+	}
+
+	return res, nil
+}
+
+func (f *gitCommitProjectFiles) HeadCommitInfo() (*git_repo.CommitInfo, error) {
+	return f.Repo.CommitInfo(f.Commit)
+}
+
+func (f *gitCommitProjectFiles) DefaultProjectName() (string, error) {
+	name, err := projectNameByGitUrl(f.Repo.Url)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(f.Repo.Url), ".git")
+	}
+
+	return name, nil
+}
+
+// localGitCommitProjectFiles reads files from the commit of the project git repo, project name is the same as for the project dir
+type localGitCommitProjectFiles struct {
+	Repo   *git_repo.Local
+	Commit string
+	// Dir is a directory of the config in the repo, paths are relative to this directory
+	Dir string
+}
+
+func (f *localGitCommitProjectFiles) repoPath(relPath string) string {
+	return path.Join(f.Dir, relPath)
+}
+
+func (f *localGitCommitProjectFiles) IsFileExists(relPath string) (bool, error) {
+	return f.Repo.IsCommitFileExists(f.Commit, f.repoPath(relPath))
+}
+
+func (f *localGitCommitProjectFiles) ReadFile(relPath string) ([]byte, error) {
+	return f.Repo.ReadCommitFile(f.Commit, f.repoPath(relPath))
+}
+
+func (f *localGitCommitProjectFiles) FilesList(relDir string) ([]string, error) {
+	filesList, err := f.Repo.CommitFilesList(f.Commit, f.repoPath(relDir))
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Dir == "" || f.Dir == "." {
+		return filesList, nil
+	}
+
+	var res []string
+	for _, fp := range filesList {
+		res = append(res, strings.TrimPrefix(fp, strings.TrimSuffix(f.Dir, "/")+"/"))
+	}
+
+	return res, nil
+}
+
+func (f *localGitCommitProjectFiles) HeadCommitInfo() (*git_repo.CommitInfo, error) {
+	return f.Repo.CommitInfo(f.Commit)
+}
+
+func (f *localGitCommitProjectFiles) DefaultProjectName() (string, error) {
+	return GetProjectName(f.Repo.Path)
+}
@@ -11,6 +11,8 @@ type rawAnsible struct {
 	BeforeSetupCacheVersion   string           `yaml:"beforeSetupCacheVersion,omitempty"`
 	SetupCacheVersion         string           `yaml:"setupCacheVersion,omitempty"`
 
+	Requirements []map[string]interface{} `yaml:"requirements,omitempty"`
+
 	rawImage *rawImage `yaml:"-"` // parent
 
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -45,6 +47,16 @@ func (c *rawAnsible) toDirective() (ansible *Ansible, err error) {
 	ansible.BeforeSetupCacheVersion = c.BeforeSetupCacheVersion
 	ansible.SetupCacheVersion = c.SetupCacheVersion
 
+	for _, requirement := range c.Requirements {
+		src, _ := requirement["src"].(string)
+		name, _ := requirement["name"].(string)
+		if src == "" && name == "" {
+			return nil, newDetailedConfigError("ansible galaxy requirement should have `src` or `name` field!", c, c.rawImage.doc)
+		}
+
+		ansible.Requirements = append(ansible.Requirements, requirement)
+	}
+
 	for ind := range c.BeforeInstall {
 		if ansibleTask, err := c.BeforeInstall[ind].toDirective(); err != nil {
 			return nil, err
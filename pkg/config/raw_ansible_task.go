@@ -121,6 +121,8 @@ func supportedModules() []string {
 	modules = append(modules, []string{"openssl_certificate", "openssl_csr", "openssl_privatekey", "openssl_publickey"}...)
 	// Other modules
 	modules = append(modules, []string{"timezone", "cron"}...)
+	// Roles from ansible galaxy requirements
+	modules = append(modules, []string{"include_role", "import_role"}...)
 
 	return modules
 }
@@ -0,0 +1,54 @@
+package config
+
+type rawCheck struct {
+	Run          interface{} `yaml:"run,omitempty"`
+	CacheVersion string      `yaml:"cacheVersion,omitempty"`
+
+	rawImage *rawImage `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawCheck) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawImage); ok {
+		c.rawImage = parent
+	}
+
+	type plain rawCheck
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawCheck) toDirective() (check *Check, err error) {
+	check = &Check{}
+	check.CacheVersion = c.CacheVersion
+
+	if run, err := InterfaceToStringArray(c.Run, c, c.rawImage.doc); err != nil {
+		return nil, err
+	} else {
+		check.Run = run
+	}
+
+	check.raw = c
+
+	if err := c.validateDirective(check); err != nil {
+		return nil, err
+	}
+
+	return check, nil
+}
+
+func (c *rawCheck) validateDirective(check *Check) error {
+	if err := check.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
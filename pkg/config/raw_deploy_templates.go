@@ -6,6 +6,8 @@ type rawDeployTemplates struct {
 	KubernetesNamespace     *string `yaml:"kubernetesNamespace,omitempty"`
 	KubernetesNamespaceSlug *bool   `yaml:"kubernetesNamespaceSlug,omitempty"`
 
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+
 	rawMeta *rawMeta
 
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -36,6 +38,12 @@ func (c *rawDeployTemplates) UnmarshalYAML(unmarshal func(interface{}) error) er
 		return newDetailedConfigError("kubernetesNamespace field cannot be empty!", nil, c.rawMeta.doc)
 	}
 
+	for name := range c.Annotations {
+		if name == "" {
+			return newDetailedConfigError("annotation name cannot be empty!", nil, c.rawMeta.doc)
+		}
+	}
+
 	return nil
 }
 
@@ -60,5 +68,7 @@ func (c *rawDeployTemplates) toDeployTemplates() DeployTemplates {
 		deployTemplates.KubernetesNamespaceSlug = *c.KubernetesNamespaceSlug
 	}
 
+	deployTemplates.Annotations = c.Annotations
+
 	return deployTemplates
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/flant/werf/pkg/download"
+)
+
+type rawDownload struct {
+	Url      string `yaml:"url,omitempty"`
+	Checksum string `yaml:"checksum,omitempty"`
+	To       string `yaml:"to,omitempty"`
+	Stage    string `yaml:"stage,omitempty"`
+
+	rawImage *rawImage `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawDownload) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawImage); ok {
+		c.rawImage = parent
+	}
+
+	type plain rawDownload
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawDownload) toDirective() (d *Download, err error) {
+	d = &Download{}
+	d.Url = c.Url
+	d.Checksum = c.Checksum
+	d.To = c.To
+	d.Stage = c.Stage
+
+	if d.Stage == "" {
+		d.Stage = "beforeInstall"
+	}
+
+	d.raw = c
+
+	if err := c.validateDirective(d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (c *rawDownload) validateDirective(d *Download) (err error) {
+	if c.Checksum != "" {
+		if err := download.ValidateChecksum(c.Checksum); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("bad `checksum` for download: %s!", err), c, c.rawImage.doc)
+		}
+	}
+
+	if err := d.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
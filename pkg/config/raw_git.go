@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 )
 
 type rawGit struct {
@@ -13,6 +14,13 @@ type rawGit struct {
 	Tag                  string                `yaml:"tag,omitempty"`
 	Commit               string                `yaml:"commit,omitempty"`
 	RawStageDependencies *rawStageDependencies `yaml:"stageDependencies,omitempty"`
+	RawSubmodules        *rawSubmodules        `yaml:"submodules,omitempty"`
+	DetectRenames        bool                  `yaml:"detectRenames,omitempty"`
+	DetectCopies         bool                  `yaml:"detectCopies,omitempty"`
+	ArchiveBaseBranch    string                `yaml:"archiveBaseBranch,omitempty"`
+	RawPatchLimits       *rawPatchLimits       `yaml:"patchLimits,omitempty"`
+	FileMode             string                `yaml:"fileMode,omitempty"`
+	DirMode              string                `yaml:"dirMode,omitempty"`
 
 	rawImage *rawImage `yaml:"-"` // parent
 
@@ -107,6 +115,38 @@ func (c *rawGit) toGitLocalExportDirective() (gitLocalExport *GitLocalExport, er
 		}
 	}
 
+	if c.RawSubmodules != nil {
+		if submodules, err := c.RawSubmodules.toDirective(); err != nil {
+			return nil, err
+		} else {
+			gitLocalExport.Submodules = submodules
+		}
+	}
+
+	if c.RawPatchLimits != nil {
+		if patchLimits, err := c.RawPatchLimits.toDirective(); err != nil {
+			return nil, err
+		} else {
+			gitLocalExport.PatchLimits = patchLimits
+		}
+	}
+
+	if fileMode, err := c.parseMode("fileMode", c.FileMode); err != nil {
+		return nil, err
+	} else {
+		gitLocalExport.FileMode = fileMode
+	}
+
+	if dirMode, err := c.parseMode("dirMode", c.DirMode); err != nil {
+		return nil, err
+	} else {
+		gitLocalExport.DirMode = dirMode
+	}
+
+	gitLocalExport.DetectRenames = c.DetectRenames || c.DetectCopies
+	gitLocalExport.DetectCopies = c.DetectCopies
+	gitLocalExport.ArchiveBaseBranch = c.ArchiveBaseBranch
+
 	gitLocalExport.raw = c
 
 	if err := c.validateGitLocalExportDirective(gitLocalExport); err != nil {
@@ -116,6 +156,20 @@ func (c *rawGit) toGitLocalExportDirective() (gitLocalExport *GitLocalExport, er
 	return gitLocalExport, nil
 }
 
+// parseMode normalizes octal permissions (e.g. 644 or 0644) into 4-digit form
+func (c *rawGit) parseMode(name, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return "", newDetailedConfigError(fmt.Sprintf("invalid `%s: %s`: octal permissions expected (e.g. 0644)!", name, value), c, c.rawImage.doc)
+	}
+
+	return fmt.Sprintf("%04o", mode), nil
+}
+
 func (c *rawGit) validateGitLocalExportDirective(gitLocalExport *GitLocalExport) (err error) {
 	if err := gitLocalExport.validate(); err != nil {
 		return err
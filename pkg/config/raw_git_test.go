@@ -48,3 +48,68 @@ func TestRawGit_getNameFromUrl(t *testing.T) {
 		}
 	}
 }
+
+func TestRawGit_modes(t *testing.T) {
+	var positiveExpectations = []struct {
+		gitOptions string
+		fileMode   string
+		dirMode    string
+	}{
+		{
+			"",
+			"",
+			"",
+		},
+		{
+			"  fileMode: 644\n  dirMode: 755\n",
+			"0644",
+			"0755",
+		},
+		{
+			"  fileMode: \"0600\"\n",
+			"0600",
+			"",
+		},
+		{
+			"  dirMode: '0700'\n",
+			"",
+			"0700",
+		},
+	}
+
+	for _, expectation := range positiveExpectations {
+		git, err := parseTestGitLocal(t, expectation.gitOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if git.FileMode != expectation.fileMode || git.DirMode != expectation.dirMode {
+			t.Errorf("\n[EXPECTED]: %#v, %#v\n[GOT]: %#v, %#v", expectation.fileMode, expectation.dirMode, git.FileMode, git.DirMode)
+		}
+	}
+}
+
+func TestRawGit_modes_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		gitOptions    string
+		errorContains string
+	}{
+		{
+			"  fileMode: rw-r--r--\n",
+			"invalid `fileMode: rw-r--r--`: octal permissions expected (e.g. 0644)!",
+		},
+		{
+			"  fileMode: 0688\n",
+			"invalid `fileMode: 0688`: octal permissions expected (e.g. 0644)!",
+		},
+		{
+			"  dirMode: 1777\n",
+			"invalid `dirMode: 1777`: octal permissions expected (e.g. 0644)!",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestGitLocal(t, expectation.gitOptions)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
@@ -3,22 +3,34 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type rawImage struct {
-	Images            []string             `yaml:"-"`
-	Artifact          string               `yaml:"artifact,omitempty"`
-	From              string               `yaml:"from,omitempty"`
-	FromCacheVersion  string               `yaml:"fromCacheVersion,omitempty"`
-	FromImage         string               `yaml:"fromImage,omitempty"`
-	FromImageArtifact string               `yaml:"fromImageArtifact,omitempty"`
-	RawGit            []*rawGit            `yaml:"git,omitempty"`
-	RawShell          *rawShell            `yaml:"shell,omitempty"`
-	RawAnsible        *rawAnsible          `yaml:"ansible,omitempty"`
-	RawMount          []*rawMount          `yaml:"mount,omitempty"`
-	RawDocker         *rawDocker           `yaml:"docker,omitempty"`
-	RawImport         []*rawArtifactImport `yaml:"import,omitempty"`
-	AsLayers          bool                 `yaml:"asLayers,omitempty"`
+	Images             []string             `yaml:"-"`
+	Artifact           string               `yaml:"artifact,omitempty"`
+	From               string               `yaml:"-"`
+	FromPlatforms      map[string]string    `yaml:"-"`
+	FromCacheVersion   string               `yaml:"fromCacheVersion,omitempty"`
+	CacheVersion       string               `yaml:"cacheVersion,omitempty"`
+	StagesCacheVersion map[string]string    `yaml:"stagesCacheVersion,omitempty"`
+	StagesTimeout      map[string]string    `yaml:"stagesTimeout,omitempty"`
+	StagesRetries      map[string]int       `yaml:"stagesRetries,omitempty"`
+	StagesPlatforms    map[string][]string  `yaml:"stagesPlatforms,omitempty"`
+	FromImage          string               `yaml:"fromImage,omitempty"`
+	FromImageArtifact  string               `yaml:"fromImageArtifact,omitempty"`
+	RawGit             []*rawGit            `yaml:"git,omitempty"`
+	RawShell           *rawShell            `yaml:"shell,omitempty"`
+	RawAnsible         *rawAnsible          `yaml:"ansible,omitempty"`
+	RawMount           []*rawMount          `yaml:"mount,omitempty"`
+	RawDownload        []*rawDownload       `yaml:"download,omitempty"`
+	RawDocker          *rawDocker           `yaml:"docker,omitempty"`
+	RawCheck           *rawCheck            `yaml:"check,omitempty"`
+	RawImport          []*rawArtifactImport `yaml:"import,omitempty"`
+	DependsOn          []string             `yaml:"dependsOn,omitempty"`
+	AsLayers           bool                 `yaml:"asLayers,omitempty"`
+	// Blocks are merged into the doc before parsing
+	Blocks []string `yaml:"blocks,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -49,6 +61,40 @@ func (c *rawImage) setAndValidateImage() error {
 	return nil
 }
 
+// setAndValidateFrom sets `from` base image, which is either a single docker image or a map of docker images by platform
+func (c *rawImage) setAndValidateFrom() error {
+	value, ok := c.UnsupportedAttributes["from"]
+	if !ok {
+		return nil
+	}
+	delete(c.UnsupportedAttributes, "from")
+
+	switch t := value.(type) {
+	case string:
+		c.From = t
+	case nil:
+	case map[interface{}]interface{}:
+		c.FromPlatforms = map[string]string{}
+		for platform, from := range t {
+			platformStr, ok := platform.(string)
+			if !ok || !isPlatform(platformStr) {
+				return newDetailedConfigError(fmt.Sprintf("invalid platform `%v` in from: OS/ARCH[/VARIANT] expected (e.g. linux/amd64 or linux/arm64/v8)!", platform), nil, c.doc)
+			}
+
+			fromStr, ok := from.(string)
+			if !ok || fromStr == "" {
+				return newDetailedConfigError(fmt.Sprintf("invalid `%s: %v` in from: docker image name expected!", platformStr, from), nil, c.doc)
+			}
+
+			c.FromPlatforms[platformStr] = fromStr
+		}
+	default:
+		return newDetailedConfigError(fmt.Sprintf("invalid from `%v`: docker image name or map of docker images by platform expected!", t), nil, c.doc)
+	}
+
+	return nil
+}
+
 func (c *rawImage) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	parentStack.Push(c)
 	type plain rawImage
@@ -62,6 +108,10 @@ func (c *rawImage) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	if err := c.setAndValidateFrom(); err != nil {
+		return err
+	}
+
 	if err := checkOverflow(c.UnsupportedAttributes, nil, c.doc); err != nil {
 		return err
 	}
@@ -70,6 +120,52 @@ func (c *rawImage) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	for stageName := range c.StagesCacheVersion {
+		if !isStageName(stageName) {
+			return newDetailedConfigError(fmt.Sprintf("unknown stage `%s` in stagesCacheVersion: expected one of %s!", stageName, strings.Join(StageNames, ", ")), nil, c.doc)
+		}
+	}
+
+	for stageName, timeout := range c.StagesTimeout {
+		if !isStageName(stageName) {
+			return newDetailedConfigError(fmt.Sprintf("unknown stage `%s` in stagesTimeout: expected one of %s!", stageName, strings.Join(StageNames, ", ")), nil, c.doc)
+		}
+
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return newDetailedConfigError(fmt.Sprintf("invalid `%s: %s` in stagesTimeout: positive duration expected (e.g. 30m or 1h)!", stageName, timeout), nil, c.doc)
+		}
+	}
+
+	for stageName, retries := range c.StagesRetries {
+		if !isStageName(stageName) {
+			return newDetailedConfigError(fmt.Sprintf("unknown stage `%s` in stagesRetries: expected one of %s!", stageName, strings.Join(StageNames, ", ")), nil, c.doc)
+		}
+
+		if retries < 0 {
+			return newDetailedConfigError(fmt.Sprintf("invalid `%s: %d` in stagesRetries: non-negative number expected!", stageName, retries), nil, c.doc)
+		}
+	}
+
+	for stageName, platforms := range c.StagesPlatforms {
+		if !isStageName(stageName) {
+			return newDetailedConfigError(fmt.Sprintf("unknown stage `%s` in stagesPlatforms: expected one of %s!", stageName, strings.Join(StageNames, ", ")), nil, c.doc)
+		}
+
+		if !isSkippableStageName(stageName) {
+			return newDetailedConfigError(fmt.Sprintf("stage `%s` cannot be specified in stagesPlatforms: this stage is required for every platform!", stageName), nil, c.doc)
+		}
+
+		if len(platforms) == 0 {
+			return newDetailedConfigError(fmt.Sprintf("invalid `%s` in stagesPlatforms: non-empty list of platforms expected!", stageName), nil, c.doc)
+		}
+
+		for _, platform := range platforms {
+			if !isPlatform(platform) {
+				return newDetailedConfigError(fmt.Sprintf("invalid platform `%s` of stage `%s` in stagesPlatforms: OS/ARCH[/VARIANT] expected (e.g. linux/amd64 or linux/arm64/v8)!", platform, stageName), nil, c.doc)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -180,6 +276,7 @@ func (c *rawImage) toImageAsLayersDirective(name string) (image *Image, err erro
 	for _, imageLayer := range layers {
 		if prevImageLayer == nil {
 			imageLayer.From = c.From
+			imageLayer.FromPlatforms = c.FromPlatforms
 			imageLayer.FromCacheVersion = c.FromCacheVersion
 		} else {
 			imageLayer.FromImage = prevImageLayer
@@ -346,6 +443,10 @@ func (c *rawImage) toImageTopLayerDirective(name string) (mainImageLayer *Image,
 		return nil, err
 	}
 
+	if mainImageLayer.Check, err = c.toCheckDirective(); err != nil {
+		return nil, err
+	}
+
 	if c.RawDocker != nil {
 		if docker, err := c.RawDocker.toDirective(); err != nil {
 			return nil, err
@@ -388,6 +489,7 @@ func (c *rawImage) toImageArtifactAsLayersDirective() (imageArtifactLayer *Image
 	for _, layer := range layers {
 		if prevImageLayer == nil {
 			layer.From = c.From
+			layer.FromPlatforms = c.FromPlatforms
 			layer.FromCacheVersion = c.FromCacheVersion
 		} else {
 			layer.FromImageArtifact = prevImageLayer
@@ -493,6 +595,10 @@ func (c *rawImage) toImageArtifactTopLayerDirective() (mainImageArtifactLayer *I
 		return nil, err
 	}
 
+	if mainImageArtifactLayer.Check, err = c.toCheckDirective(); err != nil {
+		return nil, err
+	}
+
 	return mainImageArtifactLayer, nil
 }
 
@@ -585,6 +691,7 @@ func (c *rawImage) toImageBaseDirective(name string) (imageBase *ImageBase, err
 	}
 
 	imageBase.From = c.From
+	imageBase.FromPlatforms = c.FromPlatforms
 	imageBase.FromCacheVersion = c.FromCacheVersion
 
 	for _, git := range c.RawGit {
@@ -627,6 +734,10 @@ func (c *rawImage) toImageBaseDirective(name string) (imageBase *ImageBase, err
 		}
 	}
 
+	if imageBase.Check, err = c.toCheckDirective(); err != nil {
+		return nil, err
+	}
+
 	if err := c.validateImageBaseDirective(imageBase); err != nil {
 		return nil, err
 	}
@@ -634,6 +745,15 @@ func (c *rawImage) toImageBaseDirective(name string) (imageBase *ImageBase, err
 	return imageBase, nil
 }
 
+// toCheckDirective returns nil if check is not specified, check is added only to the main layer of asLayers image
+func (c *rawImage) toCheckDirective() (*Check, error) {
+	if c.RawCheck == nil {
+		return nil, nil
+	}
+
+	return c.RawCheck.toDirective()
+}
+
 func (c *rawImage) validateImageBaseDirective(imageBase *ImageBase) (err error) {
 	if err := imageBase.validate(); err != nil {
 		return err
@@ -654,8 +774,28 @@ func (c *rawImage) toBaseImageBaseDirective(name string) (imageBase *ImageBase,
 		}
 	}
 
+	for _, rawDownload := range c.RawDownload {
+		if d, err := rawDownload.toDirective(); err != nil {
+			return nil, err
+		} else {
+			imageBase.Download = append(imageBase.Download, d)
+		}
+	}
+
 	imageBase.Git = &GitManager{}
 
+	imageBase.CacheVersion = c.CacheVersion
+	imageBase.StagesCacheVersion = c.StagesCacheVersion
+	imageBase.StagesRetries = c.StagesRetries
+	imageBase.StagesPlatforms = c.StagesPlatforms
+
+	if len(c.StagesTimeout) > 0 {
+		imageBase.StagesTimeout = map[string]time.Duration{}
+		for stageName, timeout := range c.StagesTimeout {
+			imageBase.StagesTimeout[stageName], _ = time.ParseDuration(timeout)
+		}
+	}
+
 	imageBase.raw = c
 
 	return imageBase, nil
@@ -8,7 +8,16 @@ import (
 
 type rawMeta struct {
 	Project         *string            `yaml:"project,omitempty"`
+	ConfigVersion   int                `yaml:"configVersion,omitempty"`
+	StagesNamespace string             `yaml:"stagesNamespace,omitempty"`
+	CacheVersion    string             `yaml:"cacheVersion,omitempty"`
+	Build           rawMetaBuild       `yaml:"build,omitempty"`
 	DeployTemplates rawDeployTemplates `yaml:"deploy,omitempty"`
+	Publish         rawMetaPublish     `yaml:"publish,omitempty"`
+	Registries      []*rawMetaRegistry `yaml:"registries,omitempty"`
+	Secrets         rawMetaSecrets     `yaml:"secrets,omitempty"`
+	// Includes are config files patterns, which are rendered and parsed with werf.yaml
+	Includes []string `yaml:"includes,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -36,6 +45,16 @@ func (c *rawMeta) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return newDetailedConfigError(fmt.Sprintf("bad project name '%s' specified in config: %s", *c.Project, err), nil, c.doc)
 	}
 
+	if c.ConfigVersion < 0 || c.ConfigVersion > StrictConfigVersion {
+		return newDetailedConfigError(fmt.Sprintf("unsupported configVersion %d: supported versions are 1 and %d", c.ConfigVersion, StrictConfigVersion), nil, c.doc)
+	}
+
+	if c.StagesNamespace != "" {
+		if err := slug.ValidateProject(c.StagesNamespace); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("bad stages namespace '%s' specified in config: %s", c.StagesNamespace, err), nil, c.doc)
+		}
+	}
+
 	return nil
 }
 
@@ -45,7 +64,29 @@ func (c *rawMeta) toMeta() *Meta {
 		meta.Project = *c.Project
 	}
 
+	meta.ConfigVersion = c.ConfigVersion
+	if meta.ConfigVersion == 0 {
+		meta.ConfigVersion = 1
+	}
+
+	meta.StagesNamespace = c.StagesNamespace
+	if meta.StagesNamespace == "" {
+		meta.StagesNamespace = meta.Project
+	}
+
+	meta.CacheVersion = c.CacheVersion
+
+	meta.Build = c.Build.toMetaBuild()
+
 	meta.DeployTemplates = c.DeployTemplates.toDeployTemplates()
 
+	meta.Publish = c.Publish.toMetaPublish()
+
+	for _, registry := range c.Registries {
+		meta.Registries = append(meta.Registries, registry.toMetaRegistry())
+	}
+
+	meta.Secrets = c.Secrets.toMetaSecrets()
+
 	return meta
 }
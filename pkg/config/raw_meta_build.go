@@ -0,0 +1,53 @@
+package config
+
+type rawMetaBuild struct {
+	Labels                  map[string]string `yaml:"labels,omitempty"`
+	CommitSignatureKeyrings []string          `yaml:"commitSignatureKeyrings,omitempty"`
+	DeterministicArchives   bool              `yaml:"deterministicArchives,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaBuild) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaBuild
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	for name := range c.Labels {
+		if name == "" {
+			return newDetailedConfigError("label name cannot be empty!", nil, c.rawMeta.doc)
+		}
+	}
+
+	for _, keyring := range c.CommitSignatureKeyrings {
+		if keyring == "" {
+			return newDetailedConfigError("commit signature keyring path cannot be empty!", nil, c.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaBuild) toMetaBuild() MetaBuild {
+	metaBuild := MetaBuild{}
+
+	metaBuild.Labels = c.Labels
+	metaBuild.CommitSignatureKeyrings = c.CommitSignatureKeyrings
+	metaBuild.DeterministicArchives = c.DeterministicArchives
+
+	return metaBuild
+}
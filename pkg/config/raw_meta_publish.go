@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/flant/werf/pkg/sign"
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/tag_template"
+)
+
+type rawMetaPublish struct {
+	Repo       string                `yaml:"repo,omitempty"`
+	StagesRepo string                `yaml:"stagesRepo,omitempty"`
+	Tag        rawMetaPublishTag     `yaml:"tag,omitempty"`
+	Cleanup    rawMetaPublishCleanup `yaml:"cleanup,omitempty"`
+	Sign       rawMetaPublishSign    `yaml:"sign,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+type rawMetaPublishTag struct {
+	Tags    []string `yaml:"tags,omitempty"`
+	Branch  bool     `yaml:"branch,omitempty"`
+	Commit  bool     `yaml:"commit,omitempty"`
+	BuildID bool     `yaml:"buildID,omitempty"`
+	CI      bool     `yaml:"ci,omitempty"`
+	Custom  []string `yaml:"custom,omitempty"`
+
+	rawMetaPublish *rawMetaPublish
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+type rawMetaPublishCleanup struct {
+	GitTagsExpiryDatePeriod    *int64 `yaml:"gitTagsExpiryDatePeriod,omitempty"`
+	GitTagsLimit               *int64 `yaml:"gitTagsLimit,omitempty"`
+	GitCommitsExpiryDatePeriod *int64 `yaml:"gitCommitsExpiryDatePeriod,omitempty"`
+	GitCommitsLimit            *int64 `yaml:"gitCommitsLimit,omitempty"`
+
+	rawMetaPublish *rawMetaPublish
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+type rawMetaPublishSign struct {
+	Signer string `yaml:"signer,omitempty"`
+	Key    string `yaml:"key,omitempty"`
+
+	rawMetaPublish *rawMetaPublish
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaPublish) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaPublish
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawMetaPublishTag) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaPublish); ok {
+		c.rawMetaPublish = parent
+	}
+
+	type plain rawMetaPublishTag
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMetaPublish.rawMeta.doc); err != nil {
+		return err
+	}
+
+	for _, tag := range c.Tags {
+		if err := slug.ValidateDockerTag(tag); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("bad tag '%s' specified in publish.tag.tags: %s", tag, err), nil, c.rawMetaPublish.rawMeta.doc)
+		}
+	}
+
+	for _, tagTemplate := range c.Custom {
+		if err := tag_template.Validate(tagTemplate); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("bad tag template specified in publish.tag.custom: %s", err), nil, c.rawMetaPublish.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaPublishCleanup) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaPublish); ok {
+		c.rawMetaPublish = parent
+	}
+
+	type plain rawMetaPublishCleanup
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMetaPublish.rawMeta.doc); err != nil {
+		return err
+	}
+
+	for name, value := range map[string]*int64{
+		"gitTagsExpiryDatePeriod":    c.GitTagsExpiryDatePeriod,
+		"gitTagsLimit":               c.GitTagsLimit,
+		"gitCommitsExpiryDatePeriod": c.GitCommitsExpiryDatePeriod,
+		"gitCommitsLimit":            c.GitCommitsLimit,
+	} {
+		if value != nil && *value < 0 {
+			return newDetailedConfigError(fmt.Sprintf("publish.cleanup.%s cannot be negative!", name), nil, c.rawMetaPublish.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaPublishSign) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaPublish); ok {
+		c.rawMetaPublish = parent
+	}
+
+	type plain rawMetaPublishSign
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMetaPublish.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.Signer != "" {
+		if err := sign.ValidateSigner(c.Signer); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("bad publish.sign.signer: %s", err), nil, c.rawMetaPublish.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaPublish) toMetaPublish() MetaPublish {
+	metaPublish := MetaPublish{}
+
+	metaPublish.Repo = c.Repo
+	metaPublish.StagesRepo = c.StagesRepo
+
+	metaPublish.Tag = MetaPublishTag{
+		Tags:    c.Tag.Tags,
+		Branch:  c.Tag.Branch,
+		Commit:  c.Tag.Commit,
+		BuildID: c.Tag.BuildID,
+		CI:      c.Tag.CI,
+		Custom:  c.Tag.Custom,
+	}
+
+	metaPublish.Cleanup = MetaPublishCleanup{
+		GitTagsExpiryDatePeriod:    c.Cleanup.GitTagsExpiryDatePeriod,
+		GitTagsLimit:               c.Cleanup.GitTagsLimit,
+		GitCommitsExpiryDatePeriod: c.Cleanup.GitCommitsExpiryDatePeriod,
+		GitCommitsLimit:            c.Cleanup.GitCommitsLimit,
+	}
+
+	metaPublish.Sign = MetaPublishSign{
+		Signer: c.Sign.Signer,
+		Key:    c.Sign.Key,
+	}
+
+	return metaPublish
+}
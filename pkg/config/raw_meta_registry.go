@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type rawMetaRegistry struct {
+	Address       string `yaml:"address,omitempty"`
+	Insecure      bool   `yaml:"insecure,omitempty"`
+	SkipTLSVerify bool   `yaml:"skipTLSVerify,omitempty"`
+	CAFile        string `yaml:"caFile,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaRegistry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaRegistry
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.Address == "" {
+		return newDetailedConfigError("registry address cannot be empty!", nil, c.rawMeta.doc)
+	}
+
+	if strings.Contains(c.Address, "://") || strings.Contains(c.Address, "/") {
+		return newDetailedConfigError(fmt.Sprintf("bad registry address '%s': expected HOST[:PORT] without scheme and path", c.Address), nil, c.rawMeta.doc)
+	}
+
+	if c.Insecure && c.CAFile != "" {
+		return newDetailedConfigError(fmt.Sprintf("registry '%s': caFile cannot be used with insecure registry!", c.Address), nil, c.rawMeta.doc)
+	}
+
+	return nil
+}
+
+func (c *rawMetaRegistry) toMetaRegistry() MetaRegistry {
+	return MetaRegistry{
+		Address:       c.Address,
+		Insecure:      c.Insecure,
+		SkipTLSVerify: c.SkipTLSVerify,
+		CAFile:        c.CAFile,
+	}
+}
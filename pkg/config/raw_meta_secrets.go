@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+)
+
+type rawMetaSecrets struct {
+	Backend              string   `yaml:"backend,omitempty"`
+	AgeRecipients        []string `yaml:"ageRecipients,omitempty"`
+	GPGRecipientsKeyring string   `yaml:"gpgRecipientsKeyring,omitempty"`
+	VaultAddress         string   `yaml:"vaultAddress,omitempty"`
+	VaultTransitMount    string   `yaml:"vaultTransitMount,omitempty"`
+	VaultTransitKey      string   `yaml:"vaultTransitKey,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaSecrets) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaSecrets
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	switch c.Backend {
+	case "", AesSecretsBackend:
+	case AgeSecretsBackend:
+		if len(c.AgeRecipients) == 0 {
+			return newDetailedConfigError("secrets: `ageRecipients: [RECIPIENT, ...]` should be specified for age backend!", nil, c.rawMeta.doc)
+		}
+	case GPGSecretsBackend:
+		if c.GPGRecipientsKeyring == "" {
+			return newDetailedConfigError("secrets: `gpgRecipientsKeyring: PATH` should be specified for gpg backend!", nil, c.rawMeta.doc)
+		}
+	case VaultSecretsBackend:
+		if c.VaultTransitKey == "" {
+			return newDetailedConfigError("secrets: `vaultTransitKey: NAME` should be specified for vault backend!", nil, c.rawMeta.doc)
+		}
+	default:
+		return newDetailedConfigError(fmt.Sprintf("secrets: unknown backend '%s': expected %s, %s, %s or %s", c.Backend, AesSecretsBackend, AgeSecretsBackend, GPGSecretsBackend, VaultSecretsBackend), nil, c.rawMeta.doc)
+	}
+
+	return nil
+}
+
+func (c *rawMetaSecrets) toMetaSecrets() MetaSecrets {
+	metaSecrets := MetaSecrets{}
+
+	metaSecrets.Backend = c.Backend
+	if metaSecrets.Backend == "" {
+		metaSecrets.Backend = AesSecretsBackend
+	}
+
+	metaSecrets.AgeRecipients = c.AgeRecipients
+	metaSecrets.GPGRecipientsKeyring = c.GPGRecipientsKeyring
+	metaSecrets.VaultAddress = c.VaultAddress
+	metaSecrets.VaultTransitMount = c.VaultTransitMount
+	metaSecrets.VaultTransitKey = c.VaultTransitKey
+
+	return metaSecrets
+}
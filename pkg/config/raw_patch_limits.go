@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+)
+
+type rawPatchLimits struct {
+	MaxSize  string `yaml:"maxSize,omitempty"`
+	MaxFiles int    `yaml:"maxFiles,omitempty"`
+
+	rawGit *rawGit `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawPatchLimits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawGit); ok {
+		c.rawGit = parent
+	}
+
+	type plain rawPatchLimits
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawGit.rawImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawPatchLimits) toDirective() (patchLimits *PatchLimits, err error) {
+	patchLimits = &PatchLimits{}
+
+	if c.MaxSize != "" {
+		maxSize, err := units.RAMInBytes(c.MaxSize)
+		if err != nil {
+			return nil, newDetailedConfigError(fmt.Sprintf("invalid `maxSize: %s`: %s", c.MaxSize, err), c, c.rawGit.rawImage.doc)
+		}
+		patchLimits.MaxSize = maxSize
+	}
+
+	patchLimits.MaxFiles = c.MaxFiles
+
+	patchLimits.raw = c
+
+	if err := c.validateDirective(patchLimits); err != nil {
+		return nil, err
+	}
+
+	return patchLimits, nil
+}
+
+func (c *rawPatchLimits) validateDirective(patchLimits *PatchLimits) error {
+	if err := patchLimits.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
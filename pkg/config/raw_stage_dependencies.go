@@ -1,9 +1,13 @@
 package config
 
+import "fmt"
+
 type rawStageDependencies struct {
-	Install     interface{} `yaml:"install,omitempty"`
-	Setup       interface{} `yaml:"setup,omitempty"`
-	BeforeSetup interface{} `yaml:"beforeSetup,omitempty"`
+	BeforeInstall interface{} `yaml:"beforeInstall,omitempty"`
+	Install       interface{} `yaml:"install,omitempty"`
+	Setup         interface{} `yaml:"setup,omitempty"`
+	BeforeSetup   interface{} `yaml:"beforeSetup,omitempty"`
+	Check         interface{} `yaml:"check,omitempty"`
 
 	rawGit *rawGit `yaml:"-"` // parent
 
@@ -28,26 +32,41 @@ func (c *rawStageDependencies) UnmarshalYAML(unmarshal func(interface{}) error)
 }
 
 func (c *rawStageDependencies) toDirective() (stageDependencies *StageDependencies, err error) {
-	stageDependencies = &StageDependencies{}
+	stageDependencies = &StageDependencies{
+		PathsOptions: map[string]map[string]StageDependencyOptions{},
+		AnyChanges:   map[string]*StageAnyChanges{},
+	}
+
+	if beforeInstall, err := c.toPaths(c.BeforeInstall, "beforeInstall", stageDependencies); err != nil {
+		return nil, err
+	} else {
+		stageDependencies.BeforeInstall = beforeInstall
+	}
 
-	if install, err := InterfaceToStringArray(c.Install, c, c.rawGit.rawImage.doc); err != nil {
+	if install, err := c.toPaths(c.Install, "install", stageDependencies); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.Install = install
 	}
 
-	if beforeSetup, err := InterfaceToStringArray(c.BeforeSetup, c, c.rawGit.rawImage.doc); err != nil {
+	if beforeSetup, err := c.toPaths(c.BeforeSetup, "beforeSetup", stageDependencies); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.BeforeSetup = beforeSetup
 	}
 
-	if setup, err := InterfaceToStringArray(c.Setup, c, c.rawGit.rawImage.doc); err != nil {
+	if setup, err := c.toPaths(c.Setup, "setup", stageDependencies); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.Setup = setup
 	}
 
+	if check, err := c.toPaths(c.Check, "check", stageDependencies); err != nil {
+		return nil, err
+	} else {
+		stageDependencies.Check = check
+	}
+
 	stageDependencies.raw = c
 
 	if err := c.validateDirective(stageDependencies); err != nil {
@@ -57,6 +76,120 @@ func (c *rawStageDependencies) toDirective() (stageDependencies *StageDependenci
 	return stageDependencies, nil
 }
 
+// toPaths parses PATH, [PATH, ...] or array with `{path: PATH, dereferenceSymlinks: BOOL, xattrs: BOOL, ignoreMode: BOOL}` elements,
+// options of the elements are saved into stageDependencies.PathsOptions.
+// `{anyChanges: true}` and `{excludeOnly: [PATH, ...]|PATH}` are saved into stageDependencies.AnyChanges.
+func (c *rawStageDependencies) toPaths(value interface{}, stage string, stageDependencies *StageDependencies) ([]string, error) {
+	if m, ok := value.(map[interface{}]interface{}); ok {
+		anyChanges, err := c.toAnyChanges(m, stage)
+		if err != nil {
+			return nil, err
+		}
+
+		stageDependencies.AnyChanges[stage] = anyChanges
+
+		return nil, nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		return InterfaceToStringArray(value, c, c.rawGit.rawImage.doc)
+	}
+
+	var paths []string
+	for _, elm := range values {
+		switch v := elm.(type) {
+		case string:
+			paths = append(paths, v)
+		case map[interface{}]interface{}:
+			path, options, err := c.toPathWithOptions(v, stage)
+			if err != nil {
+				return nil, err
+			}
+
+			paths = append(paths, path)
+
+			if stageDependencies.PathsOptions[stage] == nil {
+				stageDependencies.PathsOptions[stage] = map[string]StageDependencyOptions{}
+			}
+			stageDependencies.PathsOptions[stage][path] = options
+		default:
+			return nil, newDetailedConfigError(fmt.Sprintf("`%s` element should be PATH or {path: PATH, ...}, got `%v`!", stage, elm), c, c.rawGit.rawImage.doc)
+		}
+	}
+
+	return paths, nil
+}
+
+func (c *rawStageDependencies) toAnyChanges(value map[interface{}]interface{}, stage string) (*StageAnyChanges, error) {
+	anyChanges := &StageAnyChanges{}
+
+	var isAnyChanges, hasExcludeOnly bool
+	for k, v := range value {
+		switch k {
+		case "anyChanges":
+			b, ok := v.(bool)
+			if !ok {
+				return nil, newDetailedConfigError(fmt.Sprintf("`%s: {anyChanges: BOOL}` should be boolean!", stage), c, c.rawGit.rawImage.doc)
+			}
+			isAnyChanges = b
+		case "excludeOnly":
+			excludeOnly, err := InterfaceToStringArray(v, c, c.rawGit.rawImage.doc)
+			if err != nil {
+				return nil, err
+			}
+			anyChanges.ExcludeOnly = excludeOnly
+			hasExcludeOnly = true
+		default:
+			return nil, newDetailedConfigError(fmt.Sprintf("unknown `%s` option `%v`: expected `anyChanges` or `excludeOnly`!", stage, k), c, c.rawGit.rawImage.doc)
+		}
+	}
+
+	if !isAnyChanges && !hasExcludeOnly {
+		return nil, newDetailedConfigError(fmt.Sprintf("`%s` should be PATH, [PATH, ...], {anyChanges: true} or {excludeOnly: [PATH, ...]|PATH}!", stage), c, c.rawGit.rawImage.doc)
+	}
+
+	return anyChanges, nil
+}
+
+func (c *rawStageDependencies) toPathWithOptions(value map[interface{}]interface{}, stage string) (string, StageDependencyOptions, error) {
+	var path string
+	var options StageDependencyOptions
+
+	for k, v := range value {
+		key, _ := k.(string)
+
+		var boolOption *bool
+		switch key {
+		case "path":
+			if path, _ = v.(string); path == "" {
+				return "", options, newDetailedConfigError(fmt.Sprintf("`%s` element `path: PATH` should be non-empty string!", stage), c, c.rawGit.rawImage.doc)
+			}
+			continue
+		case "dereferenceSymlinks":
+			boolOption = &options.DereferenceSymlinks
+		case "xattrs":
+			boolOption = &options.Xattrs
+		case "ignoreMode":
+			boolOption = &options.IgnoreMode
+		default:
+			return "", options, newDetailedConfigError(fmt.Sprintf("unknown `%s` element option `%v`: expected `path`, `dereferenceSymlinks`, `xattrs` or `ignoreMode`!", stage, k), c, c.rawGit.rawImage.doc)
+		}
+
+		b, ok := v.(bool)
+		if !ok {
+			return "", options, newDetailedConfigError(fmt.Sprintf("`%s` element option `%s` should be boolean!", stage, key), c, c.rawGit.rawImage.doc)
+		}
+		*boolOption = b
+	}
+
+	if path == "" {
+		return "", options, newDetailedConfigError(fmt.Sprintf("`%s` element `path: PATH` required!", stage), c, c.rawGit.rawImage.doc)
+	}
+
+	return path, options, nil
+}
+
 func (c *rawStageDependencies) validateDirective(stageDependencies *StageDependencies) error {
 	if err := stageDependencies.validate(); err != nil {
 		return err
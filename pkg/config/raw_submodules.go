@@ -0,0 +1,58 @@
+package config
+
+type rawSubmodules struct {
+	Skip      bool              `yaml:"skip,omitempty"`
+	Include   interface{}       `yaml:"include,omitempty"`
+	Revisions map[string]string `yaml:"revisions,omitempty"`
+
+	rawGit *rawGit `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawSubmodules) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawGit); ok {
+		c.rawGit = parent
+	}
+
+	type plain rawSubmodules
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawGit.rawImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawSubmodules) toDirective() (submodules *Submodules, err error) {
+	submodules = &Submodules{}
+
+	submodules.Skip = c.Skip
+
+	if include, err := InterfaceToStringArray(c.Include, c, c.rawGit.rawImage.doc); err != nil {
+		return nil, err
+	} else {
+		submodules.Include = include
+	}
+
+	submodules.Revisions = c.Revisions
+
+	submodules.raw = c
+
+	if err := c.validateDirective(submodules); err != nil {
+		return nil, err
+	}
+
+	return submodules, nil
+}
+
+func (c *rawSubmodules) validateDirective(submodules *Submodules) error {
+	if err := submodules.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
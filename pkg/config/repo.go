@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// GetRequiredRepoName returns repo by precedence of GetOptionalRepoName, repo is required
+func GetRequiredRepoName(werfConfig *WerfConfig, repoOption string) (string, error) {
+	res := GetOptionalRepoName(werfConfig, repoOption)
+	if res == "" {
+		return "", fmt.Errorf("--repo option, publish.repo in werf.yaml or CI_REGISTRY_IMAGE variable required!")
+	}
+	return res, nil
+}
+
+// GetOptionalRepoName returns repo by precedence: --repo option, publish.repo from werf.yaml, CI_REGISTRY_IMAGE variable
+func GetOptionalRepoName(werfConfig *WerfConfig, repoOption string) string {
+	if repoOption == ":minikube" {
+		return fmt.Sprintf("werf-registry.kube-system.svc.cluster.local:5000/%s", werfConfig.Meta.Project)
+	} else if repoOption != "" {
+		return repoOption
+	}
+
+	if werfConfig.Meta.Publish.Repo != "" {
+		return werfConfig.Meta.Publish.Repo
+	}
+
+	ciRegistryImage := os.Getenv("CI_REGISTRY_IMAGE")
+	if ciRegistryImage != "" {
+		return ciRegistryImage
+	}
+
+	return ""
+}
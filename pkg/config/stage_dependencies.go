@@ -1,20 +1,54 @@
 package config
 
+import "fmt"
+
+// StageDependencyOptions change how files of the stage dependency path are hashed into the stage signature
+type StageDependencyOptions struct {
+	// DereferenceSymlinks hashes content of the symlink target file instead of the link path
+	DereferenceSymlinks bool
+	// Xattrs hashes extended attributes of files (e.g. file capabilities)
+	Xattrs bool
+	// IgnoreMode excludes file mode bits from the hash
+	IgnoreMode bool
+}
+
+// StageAnyChanges makes the stage depend on all files of the git mapping, except files matching ExcludeOnly patterns
+type StageAnyChanges struct {
+	ExcludeOnly []string
+}
+
 type StageDependencies struct {
-	Install     []string
-	Setup       []string
-	BeforeSetup []string
+	BeforeInstall []string
+	Install       []string
+	Setup         []string
+	BeforeSetup   []string
+	Check         []string
+
+	// PathsOptions are options of the paths specified with `{path: PATH, ...}` syntax by stage and path
+	PathsOptions map[string]map[string]StageDependencyOptions
+	// AnyChanges are stages specified with `{anyChanges: true}` or `{excludeOnly: [PATH, ...]}` syntax
+	AnyChanges map[string]*StageAnyChanges
 
 	raw *rawStageDependencies
 }
 
 func (c *StageDependencies) validate() error {
-	if !allRelativePaths(c.Install) {
+	for stage, anyChanges := range c.AnyChanges {
+		if !allRelativePaths(anyChanges.ExcludeOnly) {
+			return newDetailedConfigError(fmt.Sprintf("`%s: {excludeOnly: [PATH, ...]|PATH}` should be relative paths!", stage), c.raw, c.raw.rawGit.rawImage.doc)
+		}
+	}
+
+	if !allRelativePaths(c.BeforeInstall) {
+		return newDetailedConfigError("`beforeInstall: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
+	} else if !allRelativePaths(c.Install) {
 		return newDetailedConfigError("`install: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
 	} else if !allRelativePaths(c.Setup) {
 		return newDetailedConfigError("`setup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
 	} else if !allRelativePaths(c.BeforeSetup) {
 		return newDetailedConfigError("`beforeSetup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
+	} else if !allRelativePaths(c.Check) {
+		return newDetailedConfigError("`check: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
 	}
 	return nil
 }
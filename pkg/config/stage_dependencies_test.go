@@ -0,0 +1,160 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseTestStageDependencies(t *testing.T, stageDependencies string) (*StageDependencies, error) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+git:
+- add: /
+  to: /app
+  stageDependencies:
+`+stageDependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	return werfConfig.Images[0].Git.Local[0].StageDependencies, nil
+}
+
+func TestStageDependencies_pathsOptions(t *testing.T) {
+	stageDependencies, err := parseTestStageDependencies(t, `
+    install:
+    - package.json
+    - path: bin/**
+      dereferenceSymlinks: true
+      ignoreMode: true
+    - path: caps/**
+      xattrs: true
+    - path: lib/**
+      ignoreMode: false
+    setup: src/**
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"package.json", "bin/**", "caps/**", "lib/**"}; !reflect.DeepEqual(stageDependencies.Install, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, stageDependencies.Install)
+	}
+
+	if expected := []string{"src/**"}; !reflect.DeepEqual(stageDependencies.Setup, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, stageDependencies.Setup)
+	}
+
+	expectedPathsOptions := map[string]map[string]StageDependencyOptions{
+		"install": {
+			"bin/**":  {DereferenceSymlinks: true, IgnoreMode: true},
+			"caps/**": {Xattrs: true},
+			"lib/**":  {},
+		},
+	}
+	if !reflect.DeepEqual(stageDependencies.PathsOptions, expectedPathsOptions) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedPathsOptions, stageDependencies.PathsOptions)
+	}
+}
+
+func TestStageDependencies_pathsOptions_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		stageDependencies string
+		errorContains     string
+	}{
+		{
+			"    install:\n    - dereferenceSymlinks: true\n",
+			"`install` element `path: PATH` required!",
+		},
+		{
+			"    install:\n    - path: bin/**\n      recursive: true\n",
+			"unknown `install` element option `recursive`: expected `path`, `dereferenceSymlinks`, `xattrs` or `ignoreMode`!",
+		},
+		{
+			"    install:\n    - path: bin/**\n      ignoreMode: yes please\n",
+			"`install` element option `ignoreMode` should be boolean!",
+		},
+		{
+			"    install:\n    - [bin]\n",
+			"`install` element should be PATH or {path: PATH, ...}",
+		},
+		{
+			"    setup:\n    - path: /bin/**\n",
+			"`setup: [PATH, ...]|PATH` should be relative paths!",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestStageDependencies(t, expectation.stageDependencies)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
+
+func TestStageDependencies_anyChanges(t *testing.T) {
+	stageDependencies, err := parseTestStageDependencies(t, `
+    beforeInstall: Gemfile.lock
+    install:
+      anyChanges: true
+    beforeSetup:
+      excludeOnly: docs/**
+    setup:
+      anyChanges: true
+      excludeOnly: ["*.md", tests/**]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"Gemfile.lock"}; !reflect.DeepEqual(stageDependencies.BeforeInstall, expected) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, stageDependencies.BeforeInstall)
+	}
+
+	if stageDependencies.Install != nil || stageDependencies.BeforeSetup != nil || stageDependencies.Setup != nil {
+		t.Errorf("\n[EXPECTED]: no paths for anyChanges stages\n[GOT]: %#v, %#v, %#v", stageDependencies.Install, stageDependencies.BeforeSetup, stageDependencies.Setup)
+	}
+
+	expectedAnyChanges := map[string]*StageAnyChanges{
+		"install":     {},
+		"beforeSetup": {ExcludeOnly: []string{"docs/**"}},
+		"setup":       {ExcludeOnly: []string{"*.md", "tests/**"}},
+	}
+	if !reflect.DeepEqual(stageDependencies.AnyChanges, expectedAnyChanges) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedAnyChanges, stageDependencies.AnyChanges)
+	}
+}
+
+func TestStageDependencies_anyChanges_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		stageDependencies string
+		errorContains     string
+	}{
+		{
+			"    install:\n      anyChanges: false\n",
+			"`install` should be PATH, [PATH, ...], {anyChanges: true} or {excludeOnly: [PATH, ...]|PATH}!",
+		},
+		{
+			"    install:\n      anyChanges: always\n",
+			"`install: {anyChanges: BOOL}` should be boolean!",
+		},
+		{
+			"    setup:\n      includeOnly: src/**\n",
+			"unknown `setup` option `includeOnly`: expected `anyChanges` or `excludeOnly`!",
+		},
+		{
+			"    beforeSetup:\n      excludeOnly: /docs/**\n",
+			"`beforeSetup: {excludeOnly: [PATH, ...]|PATH}` should be relative paths!",
+		},
+		{
+			"    beforeInstall: /Gemfile.lock\n",
+			"`beforeInstall: [PATH, ...]|PATH` should be relative paths!",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestStageDependencies(t, expectation.stageDependencies)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
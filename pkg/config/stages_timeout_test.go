@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStagesTimeoutAndRetries(t *testing.T) {
+	image, err := parseTestStagesDirectives(t, `
+stagesTimeout:
+  install: 30m
+  setup: 1h30m
+stagesRetries:
+  install: 2
+  setup: 0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTimeout := map[string]time.Duration{"install": 30 * time.Minute, "setup": 90 * time.Minute}
+	if !reflect.DeepEqual(image.StagesTimeout, expectedTimeout) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedTimeout, image.StagesTimeout)
+	}
+
+	expectedRetries := map[string]int{"install": 2, "setup": 0}
+	if !reflect.DeepEqual(image.StagesRetries, expectedRetries) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedRetries, image.StagesRetries)
+	}
+}
+
+func TestStagesTimeoutAndRetries_notSpecified(t *testing.T) {
+	image, err := parseTestStagesDirectives(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if image.StagesTimeout != nil {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", nil, image.StagesTimeout)
+	}
+
+	if image.StagesRetries != nil {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", nil, image.StagesRetries)
+	}
+}
+
+func TestStagesTimeoutAndRetries_negative(t *testing.T) {
+	var negativeExpectations = []struct {
+		directives    string
+		errorContains string
+	}{
+		{
+			"stagesTimeout:\n  compile: 30m\n",
+			"unknown stage `compile` in stagesTimeout: expected one of from, ",
+		},
+		{
+			"stagesTimeout:\n  install: 30\n",
+			"invalid `install: 30` in stagesTimeout: positive duration expected (e.g. 30m or 1h)!",
+		},
+		{
+			"stagesTimeout:\n  install: 0s\n",
+			"invalid `install: 0s` in stagesTimeout: positive duration expected (e.g. 30m or 1h)!",
+		},
+		{
+			"stagesTimeout:\n  install: -5m\n",
+			"invalid `install: -5m` in stagesTimeout: positive duration expected (e.g. 30m or 1h)!",
+		},
+		{
+			"stagesRetries:\n  compile: 1\n",
+			"unknown stage `compile` in stagesRetries: expected one of from, ",
+		},
+		{
+			"stagesRetries:\n  install: -1\n",
+			"invalid `install: -1` in stagesRetries: non-negative number expected!",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestStagesDirectives(t, expectation.directives)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
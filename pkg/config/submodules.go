@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type Submodules struct {
+	Skip      bool
+	Include   []string
+	Revisions map[string]string
+
+	raw *rawSubmodules
+}
+
+func (c *Submodules) validate() error {
+	if c.Skip && (len(c.Include) != 0 || len(c.Revisions) != 0) {
+		return newDetailedConfigError("`skip: true` cannot be used with `include: [PATH, ...]|PATH` or `revisions: {PATH: COMMIT, ...}`!", c.raw, c.raw.rawGit.rawImage.doc)
+	}
+
+	if !allRelativePaths(c.Include) {
+		return newDetailedConfigError("`include: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawImage.doc)
+	}
+
+	commitRegexp := regexp.MustCompile(`^[0-9a-f]{40}$`)
+	for path, commit := range c.Revisions {
+		if !isRelativePath(path) {
+			return newDetailedConfigError(fmt.Sprintf("`revisions: {PATH: COMMIT, ...}` should contain relative paths: `%s`!", path), c.raw, c.raw.rawGit.rawImage.doc)
+		}
+
+		if !commitRegexp.MatchString(commit) {
+			return newDetailedConfigError(fmt.Sprintf("`revisions: {PATH: COMMIT, ...}` should contain full commit hashes: `%s: %s`!", path, commit), c.raw, c.raw.rawGit.rawImage.doc)
+		}
+
+		if len(c.Include) != 0 && !c.isIncluded(path) {
+			return newDetailedConfigError(fmt.Sprintf("`revisions: {PATH: COMMIT, ...}` path `%s` should be specified in `include: [PATH, ...]|PATH`!", path), c.raw, c.raw.rawGit.rawImage.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *Submodules) isIncluded(path string) bool {
+	for _, includePath := range c.Include {
+		if includePath == path {
+			return true
+		}
+	}
+
+	return false
+}
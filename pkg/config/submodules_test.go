@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseTestGitLocal(t *testing.T, gitOptions string) (*GitLocal, error) {
+	werfConfig, err := parseTestWerfConfig(t, `
+project: test
+---
+image: app
+from: alpine:3.9
+git:
+- add: /
+  to: /app
+`+gitOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return werfConfig.Images[0].Git.Local[0], nil
+}
+
+func TestSubmodules(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	var positiveExpectations = []struct {
+		gitOptions string
+		submodules *Submodules
+	}{
+		{
+			"",
+			nil,
+		},
+		{
+			"  submodules:\n    skip: true\n",
+			&Submodules{Skip: true, Include: []string{}},
+		},
+		{
+			"  submodules:\n    include: vendor/lib\n",
+			&Submodules{Include: []string{"vendor/lib"}},
+		},
+		{
+			"  submodules:\n    include: [vendor/lib, vendor/other]\n    revisions:\n      vendor/lib: " + commit + "\n",
+			&Submodules{Include: []string{"vendor/lib", "vendor/other"}, Revisions: map[string]string{"vendor/lib": commit}},
+		},
+		{
+			"  submodules:\n    revisions:\n      vendor/lib: " + commit + "\n",
+			&Submodules{Include: []string{}, Revisions: map[string]string{"vendor/lib": commit}},
+		},
+	}
+
+	for _, expectation := range positiveExpectations {
+		git, err := parseTestGitLocal(t, expectation.gitOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		submodules := git.Submodules
+		if submodules != nil {
+			submodules = &Submodules{Skip: submodules.Skip, Include: submodules.Include, Revisions: submodules.Revisions}
+		}
+
+		if !reflect.DeepEqual(submodules, expectation.submodules) {
+			t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectation.submodules, submodules)
+		}
+	}
+}
+
+func TestSubmodules_negative(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	var negativeExpectations = []struct {
+		gitOptions    string
+		errorContains string
+	}{
+		{
+			"  submodules:\n    skip: true\n    include: vendor/lib\n",
+			"`skip: true` cannot be used with `include: [PATH, ...]|PATH` or `revisions: {PATH: COMMIT, ...}`!",
+		},
+		{
+			"  submodules:\n    include: /vendor/lib\n",
+			"`include: [PATH, ...]|PATH` should be relative paths!",
+		},
+		{
+			"  submodules:\n    revisions:\n      /vendor/lib: " + commit + "\n",
+			"`revisions: {PATH: COMMIT, ...}` should contain relative paths: `/vendor/lib`!",
+		},
+		{
+			"  submodules:\n    revisions:\n      vendor/lib: master\n",
+			"`revisions: {PATH: COMMIT, ...}` should contain full commit hashes: `vendor/lib: master`!",
+		},
+		{
+			"  submodules:\n    include: vendor/other\n    revisions:\n      vendor/lib: " + commit + "\n",
+			"`revisions: {PATH: COMMIT, ...}` path `vendor/lib` should be specified in `include: [PATH, ...]|PATH`!",
+		},
+		{
+			"  submodules:\n    recursive: true\n",
+			"recursive",
+		},
+	}
+
+	for _, expectation := range negativeExpectations {
+		_, err := parseTestGitLocal(t, expectation.gitOptions)
+		expectConfigError(t, err, expectation.errorContains)
+	}
+}
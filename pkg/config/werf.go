@@ -3,4 +3,11 @@ package config
 type WerfConfig struct {
 	Meta   *Meta
 	Images []*Image
+
+	checksum string
+}
+
+// Checksum of the rendered config content
+func (c *WerfConfig) Checksum() string {
+	return c.checksum
 }
@@ -8,21 +8,11 @@ import (
 const BASE_VERSION = "0.2.3"
 
 func BaseImageName() string {
-	return fmt.Sprintf("dappdeps/base:%s", BASE_VERSION)
+	return ImageName("base")
 }
 
 func BaseContainer() (string, error) {
-	container := &container{
-		Name:      fmt.Sprintf("dappdeps_base_%s", BASE_VERSION),
-		ImageName: BaseImageName(),
-		Volume:    fmt.Sprintf("/.dapp/deps/base/%s", BASE_VERSION),
-	}
-
-	if err := container.CreateIfNotExist(); err != nil {
-		return "", err
-	} else {
-		return container.Name, nil
-	}
+	return imageContainer("base")
 }
 
 func RmBinPath() string {
@@ -30,11 +20,11 @@ func RmBinPath() string {
 }
 
 func BaseBinPath(bin string) string {
-	return fmt.Sprintf("/.dapp/deps/base/%s/embedded/bin/%s", BASE_VERSION, bin)
+	return fmt.Sprintf("/.dapp/deps/base/%s/embedded/bin/%s", imageVersion("base"), bin)
 }
 
 func BasePath() string {
-	return fmt.Sprintf("/.dapp/deps/base/%[1]s/embedded/bin:/.dapp/deps/base/%[1]s/embedded/sbin", BASE_VERSION)
+	return fmt.Sprintf("/.dapp/deps/base/%[1]s/embedded/bin:/.dapp/deps/base/%[1]s/embedded/sbin", imageVersion("base"))
 }
 
 func SudoCommand(owner, group string) string {
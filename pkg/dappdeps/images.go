@@ -0,0 +1,91 @@
+package dappdeps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultRepo is a repo of dappdeps images on Docker Hub
+const DefaultRepo = "dappdeps"
+
+var (
+	repo             = DefaultRepo
+	versionOverrides = map[string]string{}
+)
+
+// defaultVersions are versions of dappdeps images, which are used by werf: version is a part of the deps path inside the image
+var defaultVersions = map[string]string{
+	"ansible":     ANSIBLE_VERSION,
+	"base":        BASE_VERSION,
+	"gitartifact": GITARTIFACT_VERSION,
+	"toolchain":   TOOLCHAIN_VERSION,
+}
+
+// Init sets repo, which dappdeps images are pulled from (e.g. registry mirror in air-gapped environment),
+// and overrides of images versions in NAME=VERSION format. Default values are used for empty repo and versions.
+func Init(imagesRepo string, versions []string) error {
+	repo = DefaultRepo
+	if imagesRepo != "" {
+		repo = strings.TrimSuffix(imagesRepo, "/")
+	}
+
+	versionOverrides = map[string]string{}
+	for _, value := range versions {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("bad dappdeps version '%s': NAME=VERSION expected", value)
+		}
+
+		if _, ok := defaultVersions[parts[0]]; !ok {
+			return fmt.Errorf("bad dappdeps version '%s': unknown image '%s', expected one of %s", value, parts[0], strings.Join(ImagesNames(), ", "))
+		}
+
+		versionOverrides[parts[0]] = parts[1]
+	}
+
+	return nil
+}
+
+// ImagesNames returns names of all dappdeps images, which are used by werf
+func ImagesNames() []string {
+	var names []string
+	for name := range defaultVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ImageName returns full name of the dappdeps image with repo and version
+func ImageName(name string) string {
+	return fmt.Sprintf("%s/%s:%s", repo, name, imageVersion(name))
+}
+
+// ImageContainerName returns name of the container, which provides dappdeps image volume to the stages containers
+func ImageContainerName(name string) string {
+	return fmt.Sprintf("dappdeps_%s_%s", name, imageVersion(name))
+}
+
+func imageVersion(name string) string {
+	if version, ok := versionOverrides[name]; ok {
+		return version
+	}
+
+	return defaultVersions[name]
+}
+
+func imageContainer(name string) (string, error) {
+	container := &container{
+		Name:      ImageContainerName(name),
+		ImageName: ImageName(name),
+		Volume:    fmt.Sprintf("/.dapp/deps/%s/%s", name, imageVersion(name)),
+	}
+
+	if err := container.CreateIfNotExist(); err != nil {
+		return "", err
+	} else {
+		return container.Name, nil
+	}
+}
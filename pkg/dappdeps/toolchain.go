@@ -1,21 +1,12 @@
 package dappdeps
 
-import (
-	"fmt"
-)
-
 const TOOLCHAIN_VERSION = "0.1.1"
 
 func ToolchainContainer() (string, error) {
-	container := &container{
-		Name:      fmt.Sprintf("dappdeps_toolchain_%s", TOOLCHAIN_VERSION),
-		ImageName: fmt.Sprintf("dappdeps/toolchain:%s", TOOLCHAIN_VERSION),
-		Volume:    fmt.Sprintf("/.dapp/deps/toolchain/%s", TOOLCHAIN_VERSION),
-	}
+	return imageContainer("toolchain")
+}
 
-	if err := container.CreateIfNotExist(); err != nil {
-		return "", err
-	} else {
-		return container.Name, nil
-	}
+// ToolchainVersion returns version of dappdeps toolchain image with override from --dappdeps-version
+func ToolchainVersion() string {
+	return imageVersion("toolchain")
 }
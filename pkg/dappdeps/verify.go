@@ -0,0 +1,66 @@
+package dappdeps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/client"
+
+	"github.com/flant/werf/pkg/docker"
+)
+
+// ImageStatus describes availability of the dappdeps image and its volume container on the host
+type ImageStatus struct {
+	Name          string
+	ImageName     string
+	ContainerName string
+
+	ImageExists     bool
+	ContainerExists bool
+	// ContainerImageMismatch is set when existing container has been created from another image
+	ContainerImageMismatch bool
+}
+
+func (s *ImageStatus) IsReady() bool {
+	return s.ImageExists && !s.ContainerImageMismatch
+}
+
+// PullImages pulls all dappdeps images from the repo in advance, e.g. to check mirror before the build
+func PullImages(ctx context.Context) error {
+	for _, name := range ImagesNames() {
+		if err := docker.CliPull(ctx, ImageName(name)); err != nil {
+			return fmt.Errorf("unable to pull %s: %s", ImageName(name), err)
+		}
+	}
+
+	return nil
+}
+
+// VerifyImages checks that dappdeps images exist locally and volume containers are created from these images
+func VerifyImages() ([]*ImageStatus, error) {
+	var statuses []*ImageStatus
+
+	for _, name := range ImagesNames() {
+		status := &ImageStatus{Name: name, ImageName: ImageName(name), ContainerName: ImageContainerName(name)}
+
+		inspect, err := docker.ImageInspect(status.ImageName)
+		if err != nil && !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("unable to inspect image %s: %s", status.ImageName, err)
+		}
+		status.ImageExists = err == nil
+
+		containerInspect, err := docker.ContainerInspect(status.ContainerName)
+		if err != nil && !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("unable to inspect container %s: %s", status.ContainerName, err)
+		}
+		status.ContainerExists = err == nil
+
+		if status.ContainerExists && status.ImageExists {
+			status.ContainerImageMismatch = containerInspect.Image != inspect.ID
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
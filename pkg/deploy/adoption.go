@@ -0,0 +1,199 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/ghodss/yaml"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/releaseutil"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+const (
+	// AllowAdoptionByReleaseAnnoName is set manually on the existing resource to allow specified release to adopt it
+	AllowAdoptionByReleaseAnnoName = "werf.io/allow-adoption-by-release"
+	// OwnerReleaseAnnoName is set by werf on all release resources after deploy and used to validate ownership on adoption
+	OwnerReleaseAnnoName = "werf.io/owner-release"
+)
+
+// adoptExistingResources adds existing resources of the chart templates, which are not in the release yet, into the last release manifest,
+// so helm upgrade makes two-way merge of the current resource state and the template instead of failing with "already exists" error.
+// Release record is created if release does not exist. Returns true if resources have been adopted.
+func adoptExistingResources(templates *ChartTemplates, releaseName, namespace string, releaseExist bool, opts HelmChartOptions) (bool, error) {
+	store := releaseStorage()
+
+	var rls *release.Release
+	if releaseExist {
+		history, err := store.History(releaseName)
+		if err != nil {
+			return false, fmt.Errorf("cannot get release %s history: %s", releaseName, err)
+		}
+
+		if len(history) > 0 {
+			releaseutil.Reverse(history, releaseutil.SortByRevision)
+			rls = history[0]
+		}
+	}
+
+	releaseResources := map[string]bool{}
+	if rls != nil {
+		for _, manifest := range releaseutil.SplitManifests(rls.Manifest) {
+			obj, err := decodeManifest(manifest)
+			if err != nil {
+				return false, err
+			}
+
+			if obj != nil {
+				releaseResources[resourceKey(obj.GetKind(), obj.GetNamespace(), obj.GetName(), namespace)] = true
+			}
+		}
+	}
+
+	var adoptedManifests []string
+	for _, template := range *templates {
+		if _, ok := template.Metadata.Annotations[HelmHookAnnoName]; ok {
+			continue
+		}
+
+		if releaseResources[resourceKey(template.Kind, template.Metadata.Namespace, template.Metadata.Name, namespace)] {
+			continue
+		}
+
+		obj, err := getExistingResource(template, namespace)
+		if err != nil {
+			return false, err
+		}
+
+		if obj == nil {
+			continue
+		}
+
+		resourceDesc := fmt.Sprintf("%s/%s", strings.ToLower(template.Kind), template.Metadata.Name)
+		if err := validateAdoption(obj, resourceDesc, releaseName, opts); err != nil {
+			return false, err
+		}
+
+		manifest, err := adoptedResourceManifest(obj)
+		if err != nil {
+			return false, fmt.Errorf("cannot make manifest of %s: %s", resourceDesc, err)
+		}
+
+		fmt.Printf("# Adopting %s into helm release '%s'\n", resourceDesc, releaseName)
+		adoptedManifests = append(adoptedManifests, fmt.Sprintf("---\n# Source: adopted/%s\n%s", resourceDesc, manifest))
+	}
+
+	if len(adoptedManifests) == 0 {
+		return false, nil
+	}
+
+	if rls != nil {
+		rls.Manifest = strings.Join(append([]string{rls.Manifest}, adoptedManifests...), "\n")
+		if err := store.Update(rls); err != nil {
+			return false, fmt.Errorf("cannot update release %s: %s", releaseName, err)
+		}
+
+		return true, nil
+	}
+
+	now := timeconv.Now()
+	rls = &release.Release{
+		Name:      releaseName,
+		Namespace: namespace,
+		Version:   1,
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: releaseName, Version: "0.0.0"}},
+		Config:    &chart.Config{},
+		Manifest:  strings.Join(adoptedManifests, "\n"),
+		Info: &release.Info{
+			Status:        &release.Status{Code: release.Status_DEPLOYED},
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Description:   "Existing resources adopted by werf",
+		},
+	}
+	if err := store.Create(rls); err != nil {
+		return false, fmt.Errorf("cannot create release %s: %s", releaseName, err)
+	}
+
+	return true, nil
+}
+
+// validateAdoption checks that the existing resource is not owned by another release and is allowed to be adopted
+func validateAdoption(obj *unstructured.Unstructured, resourceDesc, releaseName string, opts HelmChartOptions) error {
+	annotations := obj.GetAnnotations()
+
+	if owner := annotations[OwnerReleaseAnnoName]; owner != "" && owner != releaseName {
+		return fmt.Errorf("%s already exists and belongs to helm release '%s'", resourceDesc, owner)
+	}
+
+	allowedRelease := annotations[AllowAdoptionByReleaseAnnoName]
+	switch {
+	case allowedRelease == releaseName:
+		return nil
+	case allowedRelease != "":
+		return fmt.Errorf("%s already exists and can be adopted only by helm release '%s' (%s annotation)", resourceDesc, allowedRelease, AllowAdoptionByReleaseAnnoName)
+	case opts.AllowAdoptionByRelease:
+		return nil
+	default:
+		return fmt.Errorf("%s already exists: set annotation %s=%s on the resource or use --allow-adoption-by-release option to adopt it into the release", resourceDesc, AllowAdoptionByReleaseAnnoName, releaseName)
+	}
+}
+
+func getExistingResource(template *Template, namespace string) (*unstructured.Unstructured, error) {
+	resource, namespaced, err := getResourceName(template.Version, template.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceNamespace := ""
+	if namespaced {
+		resourceNamespace = template.Namespace(namespace)
+	}
+
+	data, err := kube.Kubernetes.Discovery().RESTClient().Get().
+		AbsPath(resourcePath(template.Version, resource, resourceNamespace), template.Metadata.Name).
+		DoRaw()
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot get %s/%s: %s", strings.ToLower(template.Kind), template.Metadata.Name, err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(data, &obj.Object); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// adoptedResourceManifest returns manifest of the current resource state without server-side fields
+func adoptedResourceManifest(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range []string{"uid", "selfLink", "resourceVersion", "generation", "creationTimestamp", "managedFields"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func resourceKey(kind, resourceNamespace, name, namespace string) string {
+	if resourceNamespace == "" {
+		resourceNamespace = namespace
+	}
+
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s", kind, resourceNamespace, name))
+}
@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flant/kubedog/pkg/kube"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// annotateResources adds annotations to the release resources with merge patch.
+// Helm keeps annotations, which are not defined in templates, during upgrade, so patch is needed only after each deploy.
+// Hooks are skipped, because helm manages their lifecycle separately and they could be already deleted.
+func annotateResources(templates *ChartTemplates, namespace string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, template := range *templates {
+		if _, ok := template.Metadata.Annotations[HelmHookAnnoName]; ok {
+			continue
+		}
+
+		resource, namespaced, err := getResourceName(template.Version, template.Kind)
+		if err != nil {
+			return err
+		}
+
+		resourceNamespace := ""
+		if namespaced {
+			resourceNamespace = template.Namespace(namespace)
+		}
+
+		resourceDesc := fmt.Sprintf("%s/%s", strings.ToLower(template.Kind), template.Metadata.Name)
+
+		if debug() {
+			fmt.Printf("Annotating %s\n", resourceDesc)
+		}
+
+		err = kube.Kubernetes.Discovery().RESTClient().Patch(types.MergePatchType).
+			AbsPath(resourcePath(template.Version, resource, resourceNamespace), template.Metadata.Name).
+			Body(patch).
+			Do().
+			Error()
+		if err != nil {
+			return fmt.Errorf("cannot annotate %s: %s", resourceDesc, err)
+		}
+	}
+
+	return nil
+}
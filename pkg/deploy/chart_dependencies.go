@@ -0,0 +1,520 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	ghodssYaml "github.com/ghodss/yaml"
+	"github.com/otiai10/copy"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/resolver"
+
+	"github.com/flant/werf/pkg/download"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// HELM_REPOSITORIES_CACHE_VERSION should be bumped when layout of the helm repositories indexes cache is changed
+const HELM_REPOSITORIES_CACHE_VERSION = "1"
+
+const (
+	ChartRequirementsFile     = "requirements.yaml"
+	ChartRequirementsLockFile = "requirements.lock"
+	ChartFile                 = "Chart.yaml"
+	ChartLockFile             = "Chart.lock"
+)
+
+// ChartDependency is a dependency of requirements.yaml (Chart.yaml for apiVersion v2 charts) or a resolved dependency of the lock file
+type ChartDependency struct {
+	Name       string `yaml:"name" json:"name"`
+	Version    string `yaml:"version" json:"version"`
+	Repository string `yaml:"repository" json:"repository"`
+}
+
+// ChartDependenciesLock has the same format as helm requirements.lock
+type ChartDependenciesLock struct {
+	Dependencies []*ChartDependency `yaml:"dependencies"`
+	Digest       string             `yaml:"digest"`
+	Generated    time.Time          `yaml:"generated"`
+}
+
+type helmRepositoryIndex struct {
+	Entries map[string][]*helmRepositoryChartVersion `yaml:"entries"`
+}
+
+type helmRepositoryChartVersion struct {
+	Version string   `yaml:"version"`
+	Urls    []string `yaml:"urls"`
+	Digest  string   `yaml:"digest"`
+}
+
+func GetHelmRepositoriesDir() string {
+	return filepath.Join(werf.GetHomeDir(), "helm_repositories", HELM_REPOSITORIES_CACHE_VERSION)
+}
+
+// BuildChartDependencies puts dependencies of the chart into charts dir of the chart.
+// Dependencies are taken at versions of the lock file, charts repositories indexes and charts archives are cached in werf home.
+// Local dependencies (file://) are resolved relative to the source chart dir.
+func BuildChartDependencies(ctx context.Context, chartDir, sourceChartDir string) error {
+	requirements, dependencies, lockFile, err := readChartDependencies(chartDir)
+	if err != nil {
+		return err
+	}
+
+	if len(dependencies) == 0 {
+		return nil
+	}
+
+	lockPath := filepath.Join(sourceChartDir, lockFile)
+
+	chartLock, err := readChartDependenciesLock(lockPath)
+	if err != nil {
+		return err
+	}
+
+	var resolved []*ChartDependency
+	if chartLock == nil {
+		logger.LogWarningF("WARNING: Chart dependencies lock file %s not found: dependencies versions are resolved on each deploy, run `werf helm dependency update` to pin versions\n", lockPath)
+
+		resolved, err = resolveChartDependencies(ctx, dependencies, false)
+		if err != nil {
+			return err
+		}
+	} else {
+		digest, err := chartDependenciesDigest(requirements)
+		if err != nil {
+			return err
+		}
+
+		if chartLock.Digest != digest {
+			return fmt.Errorf("chart dependencies lock file %s is out of sync with chart dependencies: run `werf helm dependency update`", lockPath)
+		}
+
+		resolved = chartLock.Dependencies
+	}
+
+	return downloadChartDependencies(ctx, chartDir, sourceChartDir, resolved)
+}
+
+// UpdateChartDependencies resolves dependencies versions using fresh charts repositories indexes, writes the lock file
+// and puts dependencies into charts dir of the chart. Updates of the same chart are serialized with werf lock.
+func UpdateChartDependencies(ctx context.Context, chartDir string) error {
+	lockName := fmt.Sprintf("helm_dependencies.%s", util.Sha256Hash(chartDir))
+
+	return lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		requirements, dependencies, lockFile, err := readChartDependencies(chartDir)
+		if err != nil {
+			return err
+		}
+
+		if len(dependencies) == 0 {
+			logger.LogInfoF("Chart %s has no dependencies\n", chartDir)
+			return nil
+		}
+
+		resolved, err := resolveChartDependencies(ctx, dependencies, true)
+		if err != nil {
+			return err
+		}
+
+		digest, err := chartDependenciesDigest(requirements)
+		if err != nil {
+			return err
+		}
+
+		chartLock := &ChartDependenciesLock{
+			Dependencies: resolved,
+			Digest:       digest,
+			Generated:    time.Now(),
+		}
+
+		data, err := yaml.Marshal(chartLock)
+		if err != nil {
+			return err
+		}
+
+		lockPath := filepath.Join(chartDir, lockFile)
+		if err := ioutil.WriteFile(lockPath, data, 0644); err != nil {
+			return fmt.Errorf("unable to write %s: %s", lockPath, err)
+		}
+
+		logger.LogInfoF("Saved chart dependencies lock file %s\n", lockPath)
+
+		return downloadChartDependencies(ctx, chartDir, chartDir, resolved)
+	})
+}
+
+// readChartDependencies returns full requirements and dependencies of requirements.yaml or Chart.yaml and corresponding lock file name
+func readChartDependencies(chartDir string) (*chartutil.Requirements, []*ChartDependency, string, error) {
+	for _, item := range []struct{ file, lockFile string }{
+		{ChartRequirementsFile, ChartRequirementsLockFile},
+		{ChartFile, ChartLockFile},
+	} {
+		path := filepath.Join(chartDir, item.file)
+
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, nil, "", fmt.Errorf("error reading %s: %s", path, err)
+		}
+
+		// requirements are parsed as helm does, so that digest covers all fields of the dependencies (condition, tags, alias, etc.)
+		requirements := &chartutil.Requirements{}
+		if err := ghodssYaml.Unmarshal(data, requirements); err != nil {
+			return nil, nil, "", fmt.Errorf("bad chart dependencies file %s: %s", path, err)
+		}
+
+		if len(requirements.Dependencies) == 0 {
+			continue
+		}
+
+		var dependencies []*ChartDependency
+		for _, dep := range requirements.Dependencies {
+			if dep.Name == "" || dep.Repository == "" {
+				return nil, nil, "", fmt.Errorf("bad chart dependencies file %s: name and repository are required for each dependency", path)
+			}
+
+			if !isLocalChartRepository(dep.Repository) && !strings.HasPrefix(dep.Repository, "http://") && !strings.HasPrefix(dep.Repository, "https://") {
+				return nil, nil, "", fmt.Errorf("bad chart dependencies file %s: repository `%s` of dependency %s is not supported, chart repository url or file:// path expected", path, dep.Repository, dep.Name)
+			}
+
+			dependencies = append(dependencies, &ChartDependency{Name: dep.Name, Version: dep.Version, Repository: dep.Repository})
+		}
+
+		return requirements, dependencies, item.lockFile, nil
+	}
+
+	return nil, nil, "", nil
+}
+
+// IsChartDependenciesBuilt checks whether charts dir of the chart already contains all dependencies:
+// an archive NAME-VERSION.tgz or a chart dir NAME for each dependency
+func IsChartDependenciesBuilt(chartDir string) (bool, error) {
+	_, dependencies, _, err := readChartDependencies(chartDir)
+	if err != nil {
+		return false, err
+	}
+
+	chartsDir := filepath.Join(chartDir, "charts")
+	for _, dep := range dependencies {
+		if util.FileExists(filepath.Join(chartsDir, dep.Name)) {
+			continue
+		}
+
+		archives, err := filepath.Glob(filepath.Join(chartsDir, fmt.Sprintf("%s-*.tgz", dep.Name)))
+		if err != nil {
+			return false, err
+		}
+
+		if !isChartDependencyArchiveFound(dep, archives) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// isChartDependencyArchiveFound checks archives NAME-VERSION.tgz, versions of the archives should satisfy the dependency version constraint
+func isChartDependencyArchiveFound(dep *ChartDependency, archives []string) bool {
+	constraint := dep.Version
+	if constraint == "" {
+		constraint = "*"
+	}
+
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+
+	for _, archive := range archives {
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(archive), dep.Name+"-"), ".tgz")
+
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+
+		if constraints.Check(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readChartDependenciesLock(path string) (*ChartDependenciesLock, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", path, err)
+	}
+
+	chartLock := &ChartDependenciesLock{}
+	if err := yaml.Unmarshal(data, chartLock); err != nil {
+		return nil, fmt.Errorf("bad chart dependencies lock file %s: %s", path, err)
+	}
+
+	return chartLock, nil
+}
+
+// chartDependenciesDigest is the digest of helm requirements.lock, which is calculated over the full requirements
+func chartDependenciesDigest(requirements *chartutil.Requirements) (string, error) {
+	return resolver.HashReq(requirements)
+}
+
+// resolveChartDependencies selects the latest versions of the charts, which satisfy dependencies constraints
+func resolveChartDependencies(ctx context.Context, dependencies []*ChartDependency, refresh bool) ([]*ChartDependency, error) {
+	var resolved []*ChartDependency
+
+	for _, dep := range dependencies {
+		if isLocalChartRepository(dep.Repository) {
+			resolved = append(resolved, &ChartDependency{Name: dep.Name, Version: dep.Version, Repository: dep.Repository})
+			continue
+		}
+
+		constraint := dep.Version
+		if constraint == "" {
+			constraint = "*"
+		}
+
+		constraints, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("bad version constraint `%s` of chart dependency %s: %s", dep.Version, dep.Name, err)
+		}
+
+		findVersion := func(index *helmRepositoryIndex) *helmRepositoryChartVersion {
+			var versions []*semver.Version
+			byVersion := map[*semver.Version]*helmRepositoryChartVersion{}
+
+			for _, chartVersion := range index.Entries[dep.Name] {
+				v, err := semver.NewVersion(chartVersion.Version)
+				if err != nil || !constraints.Check(v) {
+					continue
+				}
+
+				versions = append(versions, v)
+				byVersion[v] = chartVersion
+			}
+
+			if len(versions) == 0 {
+				return nil
+			}
+
+			sort.Sort(semver.Collection(versions))
+
+			return byVersion[versions[len(versions)-1]]
+		}
+
+		chartVersion, err := findChartVersion(ctx, dep.Repository, refresh, findVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		if chartVersion == nil {
+			return nil, fmt.Errorf("chart %s version `%s` not found in repository %s", dep.Name, constraint, dep.Repository)
+		}
+
+		resolved = append(resolved, &ChartDependency{Name: dep.Name, Version: chartVersion.Version, Repository: dep.Repository})
+	}
+
+	return resolved, nil
+}
+
+// findChartVersion looks for the chart version in the cached repository index, index is fetched again when chart version is not found
+func findChartVersion(ctx context.Context, repository string, refresh bool, find func(*helmRepositoryIndex) *helmRepositoryChartVersion) (*helmRepositoryChartVersion, error) {
+	index, err := getHelmRepositoryIndex(ctx, repository, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if chartVersion := find(index); chartVersion != nil || refresh {
+		return chartVersion, nil
+	}
+
+	index, err = getHelmRepositoryIndex(ctx, repository, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return find(index), nil
+}
+
+func getHelmRepositoryIndex(ctx context.Context, repository string, refresh bool) (*helmRepositoryIndex, error) {
+	indexPath := filepath.Join(GetHelmRepositoriesDir(), fmt.Sprintf("%s-index.yaml", util.Sha256Hash(repository)))
+	lockName := fmt.Sprintf("helm_repository.%s", util.Sha256Hash(repository))
+
+	var data []byte
+	err := lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		var err error
+
+		if !refresh {
+			data, err = ioutil.ReadFile(indexPath)
+			if err == nil {
+				return nil
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("error reading %s: %s", indexPath, err)
+			}
+		}
+
+		data, err = fetchHelmRepositoryIndex(ctx, repository)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(indexPath), os.ModePerm); err != nil {
+			return err
+		}
+
+		tmpPath := fmt.Sprintf("%s.tmp", indexPath)
+		if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+			return fmt.Errorf("unable to write %s: %s", tmpPath, err)
+		}
+
+		return os.Rename(tmpPath, indexPath)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := &helmRepositoryIndex{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("bad index of chart repository %s: %s", repository, err)
+	}
+
+	return index, nil
+}
+
+func fetchHelmRepositoryIndex(ctx context.Context, repository string) ([]byte, error) {
+	indexUrl := strings.TrimSuffix(repository, "/") + "/index.yaml"
+
+	logger.LogInfoF("# Fetching chart repository index %s\n", indexUrl)
+
+	req, err := http.NewRequest(http.MethodGet, indexUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %s", indexUrl, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: %s", indexUrl, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %s", indexUrl, err)
+	}
+
+	return data, nil
+}
+
+// downloadChartDependencies puts charts archives into charts dir, archives and dirs, which already exist in charts dir, are not replaced
+func downloadChartDependencies(ctx context.Context, chartDir, sourceChartDir string, dependencies []*ChartDependency) error {
+	chartsDir := filepath.Join(chartDir, "charts")
+	if err := os.MkdirAll(chartsDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, dep := range dependencies {
+		if isLocalChartRepository(dep.Repository) {
+			localChartDir := strings.TrimPrefix(dep.Repository, "file://")
+			if !filepath.IsAbs(localChartDir) {
+				localChartDir = filepath.Join(sourceChartDir, localChartDir)
+			}
+
+			targetDir := filepath.Join(chartsDir, dep.Name)
+			if util.FileExists(targetDir) {
+				continue
+			}
+
+			if err := copy.Copy(localChartDir, targetDir); err != nil {
+				return fmt.Errorf("unable to copy local chart dependency %s from %s: %s", dep.Name, localChartDir, err)
+			}
+
+			continue
+		}
+
+		archivePath := filepath.Join(chartsDir, fmt.Sprintf("%s-%s.tgz", dep.Name, dep.Version))
+		if util.FileExists(archivePath) {
+			continue
+		}
+
+		chartVersion, err := findChartVersion(ctx, dep.Repository, false, func(index *helmRepositoryIndex) *helmRepositoryChartVersion {
+			for _, chartVersion := range index.Entries[dep.Name] {
+				if chartVersion.Version == dep.Version {
+					return chartVersion
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if chartVersion == nil || len(chartVersion.Urls) == 0 {
+			return fmt.Errorf("chart %s version %s not found in repository %s", dep.Name, dep.Version, dep.Repository)
+		}
+
+		chartUrl, err := resolveChartUrl(dep.Repository, chartVersion.Urls[0])
+		if err != nil {
+			return fmt.Errorf("bad url of chart %s version %s: %s", dep.Name, dep.Version, err)
+		}
+
+		var expectedChecksum string
+		if chartVersion.Digest != "" {
+			expectedChecksum = download.ChecksumPrefix + chartVersion.Digest
+		}
+
+		path, _, err := download.Get(ctx, chartUrl, expectedChecksum)
+		if err != nil {
+			return fmt.Errorf("unable to download chart %s version %s: %s", dep.Name, dep.Version, err)
+		}
+
+		if err := copy.Copy(path, archivePath); err != nil {
+			return fmt.Errorf("unable to copy chart %s into %s: %s", dep.Name, archivePath, err)
+		}
+	}
+
+	return nil
+}
+
+func isLocalChartRepository(repository string) bool {
+	return strings.HasPrefix(repository, "file://")
+}
+
+// resolveChartUrl resolves chart url relative to the repository url as helm does
+func resolveChartUrl(repository, chartUrl string) (string, error) {
+	u, err := url.Parse(chartUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if u.IsAbs() {
+		return chartUrl, nil
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(repository, "/") + "/")
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(u).String(), nil
+}
@@ -0,0 +1,194 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/resolver"
+)
+
+func writeTestChartFiles(t *testing.T, files map[string]string) string {
+	chartDir, err := ioutil.TempDir("", "werf-chart-dependencies-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for relPath, content := range files {
+		path := filepath.Join(chartDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return chartDir
+}
+
+func TestReadChartDependencies(t *testing.T) {
+	chartDir := writeTestChartFiles(t, map[string]string{
+		ChartRequirementsFile: `
+dependencies:
+- name: redis
+  version: ~10.5.0
+  repository: https://kubernetes-charts.storage.googleapis.com
+  condition: redis.enabled
+  alias: cache
+- name: common
+  repository: file://../common
+`,
+	})
+	defer os.RemoveAll(chartDir)
+
+	requirements, dependencies, lockFile, err := readChartDependencies(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lockFile != ChartRequirementsLockFile {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", ChartRequirementsLockFile, lockFile)
+	}
+
+	if len(requirements.Dependencies) != 2 || requirements.Dependencies[0].Condition != "redis.enabled" || requirements.Dependencies[0].Alias != "cache" {
+		t.Errorf("\n[EXPECTED]: full requirements with condition and alias\n[GOT]: %#v", requirements.Dependencies)
+	}
+
+	expectedDependencies := []ChartDependency{
+		{Name: "redis", Version: "~10.5.0", Repository: "https://kubernetes-charts.storage.googleapis.com"},
+		{Name: "common", Repository: "file://../common"},
+	}
+
+	if len(dependencies) != len(expectedDependencies) {
+		t.Fatalf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedDependencies, dependencies)
+	}
+
+	for i, expected := range expectedDependencies {
+		if *dependencies[i] != expected {
+			t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, *dependencies[i])
+		}
+	}
+}
+
+func TestReadChartDependencies_negative(t *testing.T) {
+	var negativeExpectations = []string{
+		"dependencies:\n- name: redis\n",
+		"dependencies:\n- name: redis\n  repository: stable\n",
+		"dependencies:\n- name: redis\n  repository: oci://registry/charts\n",
+	}
+
+	for _, expectation := range negativeExpectations {
+		chartDir := writeTestChartFiles(t, map[string]string{ChartRequirementsFile: expectation})
+
+		if _, _, _, err := readChartDependencies(chartDir); err == nil {
+			t.Errorf("\n[EXPECTED]: error for requirements %q", expectation)
+		}
+
+		os.RemoveAll(chartDir)
+	}
+}
+
+func TestChartDependenciesDigest(t *testing.T) {
+	requirements := &chartutil.Requirements{
+		Dependencies: []*chartutil.Dependency{
+			{Name: "redis", Version: "~10.5.0", Repository: "https://kubernetes-charts.storage.googleapis.com"},
+		},
+	}
+
+	digest, err := chartDependenciesDigest(requirements)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// digest should be compatible with requirements.lock written by helm dependency update
+	helmDigest, err := resolver.HashReq(requirements)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if digest != helmDigest {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", helmDigest, digest)
+	}
+
+	// all fields of the requirements are taken into account, not only name, version and repository
+	requirements.Dependencies[0].Condition = "redis.enabled"
+
+	changedDigest, err := chartDependenciesDigest(requirements)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changedDigest == digest {
+		t.Errorf("\n[EXPECTED]: digest changed by condition\n[GOT]: %#v", changedDigest)
+	}
+}
+
+func TestIsChartDependenciesBuilt(t *testing.T) {
+	requirements := `
+dependencies:
+- name: redis
+  version: ~10.5.0
+  repository: https://kubernetes-charts.storage.googleapis.com
+- name: common
+  repository: file://../common
+`
+
+	var expectations = []struct {
+		files map[string]string
+		built bool
+	}{
+		{
+			map[string]string{},
+			false,
+		},
+		{
+			map[string]string{"charts/redis-10.5.7.tgz": ""},
+			false,
+		},
+		{
+			map[string]string{"charts/redis-10.5.7.tgz": "", "charts/common/Chart.yaml": "name: common\n"},
+			true,
+		},
+		{
+			map[string]string{"charts/redis-10.6.0.tgz": "", "charts/common/Chart.yaml": "name: common\n"},
+			false,
+		},
+		{
+			map[string]string{"charts/redis/Chart.yaml": "name: redis\n", "charts/common/Chart.yaml": "name: common\n"},
+			true,
+		},
+	}
+
+	for _, expectation := range expectations {
+		files := map[string]string{ChartRequirementsFile: requirements}
+		for path, content := range expectation.files {
+			files[path] = content
+		}
+
+		chartDir := writeTestChartFiles(t, files)
+
+		built, err := IsChartDependenciesBuilt(chartDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if built != expectation.built {
+			t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v\n[FILES]: %#v", expectation.built, built, expectation.files)
+		}
+
+		os.RemoveAll(chartDir)
+	}
+
+	chartDir := writeTestChartFiles(t, map[string]string{"Chart.yaml": "name: app\n"})
+	defer os.RemoveAll(chartDir)
+
+	if built, err := IsChartDependenciesBuilt(chartDir); err != nil {
+		t.Fatal(err)
+	} else if !built {
+		t.Errorf("\n[EXPECTED]: chart without dependencies is built")
+	}
+}
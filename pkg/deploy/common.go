@@ -6,10 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/deploy/secret"
 )
 
-func getSafeSecretManager(projectDir string, secretValues []string) (secret.Manager, error) {
+func getSafeSecretManager(projectDir, environment string, secretValues []string, secretsConfig *config.MetaSecrets) (secret.Manager, error) {
 	isSecretsExists := false
 	if _, err := os.Stat(filepath.Join(projectDir, ProjectSecretDir)); !os.IsNotExist(err) {
 		isSecretsExists = true
@@ -17,11 +18,16 @@ func getSafeSecretManager(projectDir string, secretValues []string) (secret.Mana
 	if _, err := os.Stat(filepath.Join(projectDir, ProjectDefaultSecretValuesFile)); !os.IsNotExist(err) {
 		isSecretsExists = true
 	}
+	if environment != "" {
+		if _, err := os.Stat(filepath.Join(projectDir, fmt.Sprintf(ProjectEnvSecretValuesFileTemplate, environment))); !os.IsNotExist(err) {
+			isSecretsExists = true
+		}
+	}
 	if len(secretValues) > 0 {
 		isSecretsExists = true
 	}
 	if isSecretsExists {
-		key, err := secret.GetSecretKey(projectDir)
+		m, err := secret.GetManager(projectDir, secretsConfig)
 		if err != nil {
 			if strings.HasPrefix(err.Error(), "encryption key not found in") {
 				fmt.Fprintln(os.Stderr, err)
@@ -29,19 +35,37 @@ func getSafeSecretManager(projectDir string, secretValues []string) (secret.Mana
 				return nil, err
 			}
 		} else {
-			return secret.NewManager(key, secret.NewManagerOptions{})
+			return m, nil
 		}
 	}
 
 	return secret.NewSafeManager()
 }
 
-func getWerfChart(projectDir string, m secret.Manager, values, secretValues, set, setString []string, serviceValues map[string]interface{}) (*WerfChart, error) {
+func getWerfChart(projectDir, environment string, m secret.Manager, values, secretValues, set, setString []string, serviceValues map[string]interface{}) (*WerfChart, error) {
 	werfChart, err := GenerateWerfChart(projectDir, m)
 	if err != nil {
 		return nil, err
 	}
 
+	if environment != "" {
+		envValues := filepath.Join(projectDir, fmt.Sprintf(ProjectEnvValuesFileTemplate, environment))
+		if _, err := os.Stat(envValues); !os.IsNotExist(err) {
+			err = werfChart.SetValuesFile(envValues)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		envSecretValues := filepath.Join(projectDir, fmt.Sprintf(ProjectEnvSecretValuesFileTemplate, environment))
+		if _, err := os.Stat(envSecretValues); !os.IsNotExist(err) {
+			err = werfChart.SetSecretValuesFile(envSecretValues, m)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	for _, path := range values {
 		err = werfChart.SetValuesFile(path)
 		if err != nil {
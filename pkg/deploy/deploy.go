@@ -9,6 +9,7 @@ import (
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/telemetry"
 )
 
 type DeployOptions struct {
@@ -18,6 +19,10 @@ type DeployOptions struct {
 	SetString       []string
 	Timeout         time.Duration
 	WithoutRegistry bool
+	Validate        bool
+	Annotations     map[string]string
+
+	AllowAdoptionByRelease bool
 
 	Release     string
 	Namespace   string
@@ -40,16 +45,36 @@ func (d *ImageInfoGetterStub) GetName() string {
 }
 
 func (d *ImageInfoGetterStub) GetImageName() string {
+	return fmt.Sprintf("%s:%s", d.GetImageRepository(), d.ImageTag)
+}
+
+func (d *ImageInfoGetterStub) GetImageRepository() string {
 	if d.Name == "" {
-		return fmt.Sprintf("%s:%s", d.Repo, d.ImageTag)
+		return d.Repo
 	}
-	return fmt.Sprintf("%s/%s:%s", d.Repo, d.Name, d.ImageTag)
+	return fmt.Sprintf("%s/%s", d.Repo, d.Name)
+}
+
+func (d *ImageInfoGetterStub) GetImageTag() string {
+	return d.ImageTag
 }
 
 func (d *ImageInfoGetterStub) GetImageId() (string, error) {
 	return docker_registry.ImageId(d.GetImageName())
 }
 
+func (d *ImageInfoGetterStub) GetImageDigest() (string, error) {
+	return docker_registry.ImageDigest(d.GetImageName())
+}
+
+func (d *ImageInfoGetterStub) GetImageGitCommit() (string, error) {
+	configFile, err := docker_registry.ImageConfigFile(d.GetImageName())
+	if err != nil {
+		return "", err
+	}
+	return configFile.Config.Labels["werf-git-commit"], nil
+}
+
 type ImageInfo struct {
 	Config          *config.Image
 	WithoutRegistry bool
@@ -66,10 +91,18 @@ func (d *ImageInfo) GetName() string {
 }
 
 func (d *ImageInfo) GetImageName() string {
+	return fmt.Sprintf("%s:%s", d.GetImageRepository(), d.Tag)
+}
+
+func (d *ImageInfo) GetImageRepository() string {
 	if d.Config.Name == "" {
-		return fmt.Sprintf("%s:%s", d.Repo, d.Tag)
+		return d.Repo
 	}
-	return fmt.Sprintf("%s/%s:%s", d.Repo, d.Config.Name, d.Tag)
+	return fmt.Sprintf("%s/%s", d.Repo, d.Config.Name)
+}
+
+func (d *ImageInfo) GetImageTag() string {
+	return d.Tag
 }
 
 func (d *ImageInfo) GetImageId() (string, error) {
@@ -88,7 +121,48 @@ func (d *ImageInfo) GetImageId() (string, error) {
 	return res, nil
 }
 
-func RunDeploy(projectDir, repo, tag, release, namespace string, werfConfig *config.WerfConfig, opts DeployOptions) error {
+func (d *ImageInfo) GetImageDigest() (string, error) {
+	if d.WithoutRegistry {
+		return "", nil
+	}
+
+	imageName := d.GetImageName()
+
+	res, err := docker_registry.ImageDigest(imageName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR getting image %s digest: %s\n", imageName, err)
+		return "", nil
+	}
+
+	return res, nil
+}
+
+// GetImageGitCommit returns commit of the project git repo, which image has been built from (werf-git-commit label)
+func (d *ImageInfo) GetImageGitCommit() (string, error) {
+	if d.WithoutRegistry {
+		return "", nil
+	}
+
+	imageName := d.GetImageName()
+
+	configFile, err := docker_registry.ImageConfigFile(imageName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR getting image %s config: %s\n", imageName, err)
+		return "", nil
+	}
+
+	return configFile.Config.Labels["werf-git-commit"], nil
+}
+
+func RunDeploy(projectDir, repo, tag, release, namespace string, werfConfig *config.WerfConfig, opts DeployOptions) (err error) {
+	telemetry.SetProject(werfConfig.Meta.Project)
+	span := telemetry.StartSpan("deploy", nil, map[string]string{"werf.project": werfConfig.Meta.Project, "werf.release": release, "werf.namespace": namespace})
+	start := time.Now()
+	defer func() {
+		telemetry.ObserveDeploy(release, time.Since(start), err)
+		span.End(err)
+	}()
+
 	if debug() {
 		fmt.Printf("Deploy options: %#v\n", opts)
 	}
@@ -96,7 +170,7 @@ func RunDeploy(projectDir, repo, tag, release, namespace string, werfConfig *con
 	fmt.Printf("Using Helm release name: %s\n", release)
 	fmt.Printf("Using Kubernetes namespace: %s\n", namespace)
 
-	m, err := getSafeSecretManager(projectDir, opts.SecretValues)
+	m, err := getSafeSecretManager(projectDir, opts.Environment, opts.SecretValues, &werfConfig.Meta.Secrets)
 	if err != nil {
 		return fmt.Errorf("cannot get project secret: %s", err)
 	}
@@ -109,12 +183,12 @@ func RunDeploy(projectDir, repo, tag, release, namespace string, werfConfig *con
 		images = append(images, d)
 	}
 
-	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, localGit, images, ServiceValuesOptions{})
+	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, localGit, images, ServiceValuesOptions{Env: opts.Environment})
 	if err != nil {
 		return fmt.Errorf("error creating service values: %s", err)
 	}
 
-	werfChart, err := getWerfChart(projectDir, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
+	werfChart, err := getWerfChart(projectDir, opts.Environment, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
 	if err != nil {
 		return err
 	}
@@ -123,5 +197,24 @@ func RunDeploy(projectDir, repo, tag, release, namespace string, werfConfig *con
 		defer os.RemoveAll(werfChart.ChartDir)
 	}
 
-	return werfChart.Deploy(release, namespace, HelmChartOptions{CommonHelmOptions: CommonHelmOptions{KubeContext: opts.KubeContext}, Timeout: opts.Timeout})
+	for name, value := range werfConfig.Meta.DeployTemplates.Annotations {
+		if err := werfChart.SetGlobalAnnotation(name, value); err != nil {
+			return err
+		}
+	}
+	for name, value := range opts.Annotations {
+		if err := werfChart.SetGlobalAnnotation(name, value); err != nil {
+			return err
+		}
+	}
+
+	if opts.Validate {
+		return werfChart.Validate(release, namespace, HelmChartOptions{CommonHelmOptions: CommonHelmOptions{KubeContext: opts.KubeContext}})
+	}
+
+	return werfChart.Deploy(release, namespace, HelmChartOptions{
+		CommonHelmOptions:      CommonHelmOptions{KubeContext: opts.KubeContext},
+		Timeout:                opts.Timeout,
+		AllowAdoptionByRelease: opts.AllowAdoptionByRelease,
+	})
 }
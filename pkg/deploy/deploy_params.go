@@ -1,4 +1,4 @@
-package common
+package deploy
 
 import (
 	"bytes"
@@ -11,7 +11,8 @@ import (
 	"github.com/flant/werf/pkg/slug"
 )
 
-func GetHelmRelease(releaseOption string, environmentOption string, werfConfig *config.WerfConfig) (string, error) {
+// GetHelmRelease returns specified release or release rendered by deploy.helmRelease template of werf.yaml
+func GetHelmRelease(releaseOption string, environment string, werfConfig *config.WerfConfig) (string, error) {
 	if releaseOption != "" {
 		err := slug.ValidateHelmRelease(releaseOption)
 		if err != nil {
@@ -22,10 +23,10 @@ func GetHelmRelease(releaseOption string, environmentOption string, werfConfig *
 
 	releaseTemplate := werfConfig.Meta.DeployTemplates.HelmRelease
 	if releaseTemplate == "" {
-		releaseTemplate = "[[ project ]]-[[ environment ]]"
+		releaseTemplate = "[[ project ]]-[[ env ]]"
 	}
 
-	renderedRelease, err := renderDeployParamTemplate("release", releaseTemplate, environmentOption, werfConfig)
+	renderedRelease, err := renderDeployParamTemplate("release", releaseTemplate, environment, werfConfig)
 	if err != nil {
 		return "", fmt.Errorf("cannot render Helm release name by template '%s': %s", releaseTemplate, err)
 	}
@@ -46,7 +47,8 @@ func GetHelmRelease(releaseOption string, environmentOption string, werfConfig *
 	return renderedRelease, nil
 }
 
-func GetKubernetesNamespace(namespaceOption string, environmentOption string, werfConfig *config.WerfConfig) (string, error) {
+// GetKubernetesNamespace returns specified namespace or namespace rendered by deploy.namespace template of werf.yaml
+func GetKubernetesNamespace(namespaceOption string, environment string, werfConfig *config.WerfConfig) (string, error) {
 	if namespaceOption != "" {
 		err := slug.ValidateKubernetesNamespace(namespaceOption)
 		if err != nil {
@@ -57,10 +59,10 @@ func GetKubernetesNamespace(namespaceOption string, environmentOption string, we
 
 	namespaceTemplate := werfConfig.Meta.DeployTemplates.KubernetesNamespace
 	if namespaceTemplate == "" {
-		namespaceTemplate = "[[ project ]]-[[ environment ]]"
+		namespaceTemplate = "[[ project ]]-[[ env ]]"
 	}
 
-	renderedNamespace, err := renderDeployParamTemplate("namespace", namespaceTemplate, environmentOption, werfConfig)
+	renderedNamespace, err := renderDeployParamTemplate("namespace", namespaceTemplate, environment, werfConfig)
 	if err != nil {
 		return "", fmt.Errorf("cannot render Kubernetes namespace by template '%s': %s", namespaceTemplate, err)
 	}
@@ -81,7 +83,7 @@ func GetKubernetesNamespace(namespaceOption string, environmentOption string, we
 	return renderedNamespace, nil
 }
 
-func renderDeployParamTemplate(templateName, templateText string, environmentOption string, werfConfig *config.WerfConfig) (string, error) {
+func renderDeployParamTemplate(templateName, templateText string, environment string, werfConfig *config.WerfConfig) (string, error) {
 	tmpl := template.New(templateName).Delims("[[", "]]")
 
 	funcMap := sprig.TxtFuncMap()
@@ -90,20 +92,28 @@ func renderDeployParamTemplate(templateName, templateText string, environmentOpt
 		return werfConfig.Meta.Project
 	}
 
-	funcMap["environment"] = func() (string, error) {
-		environment := os.Getenv("CI_ENVIRONMENT_SLUG")
-
-		if environment == "" {
-			environment = environmentOption
-		}
-
+	environmentFunc := func() (string, error) {
 		if environment == "" {
-			return "", fmt.Errorf("--environment option or CI_ENVIRONMENT_SLUG variable required to construct name by template '%s'", templateText)
+			return "", fmt.Errorf("--env option or CI_ENVIRONMENT_SLUG variable required to construct name by template '%s'", templateText)
 		}
 
 		return environment, nil
 	}
 
+	funcMap["environment"] = environmentFunc
+
+	// env without arguments returns environment, sprig env with variable name argument is kept
+	funcMap["env"] = func(args ...string) (string, error) {
+		switch len(args) {
+		case 0:
+			return environmentFunc()
+		case 1:
+			return os.Getenv(args[0]), nil
+		default:
+			return "", fmt.Errorf("env function expects zero or one argument, got %d", len(args))
+		}
+	}
+
 	tmpl = tmpl.Funcs(template.FuncMap(funcMap))
 
 	tmpl, err := tmpl.Parse(templateText)
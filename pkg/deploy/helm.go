@@ -81,6 +81,12 @@ type HelmChartOptions struct {
 	DryRun    bool
 	Debug     bool
 	Timeout   time.Duration
+
+	Annotations map[string]string
+
+	// AllowAdoptionByRelease allows to adopt existing resources without werf.io/allow-adoption-by-release annotation
+	AllowAdoptionByRelease bool
+
 	CommonHelmOptions
 }
 
@@ -106,6 +112,17 @@ func doDeployHelmChart(chartPath string, releaseName string, namespace string, o
 		return fmt.Errorf("parsing templates failed: %s", err)
 	}
 
+	if !opts.DryRun {
+		adopted, err := adoptExistingResources(templates, releaseName, namespace, releaseExist, opts)
+		if err != nil {
+			return fmt.Errorf("adoption of existing resources failed: %s", err)
+		}
+
+		if adopted {
+			releaseExist = true
+		}
+	}
+
 	if err := removeOldJobs(templates, namespace); err != nil {
 		return fmt.Errorf("removing old jobs failed: %s", err)
 	}
@@ -148,6 +165,17 @@ func doDeployHelmChart(chartPath string, releaseName string, namespace string, o
 
 	fmt.Printf("%s\n%s\n", stdout, stderr)
 
+	if !opts.DryRun {
+		annotations := map[string]string{OwnerReleaseAnnoName: releaseName}
+		for name, value := range opts.Annotations {
+			annotations[name] = value
+		}
+
+		if err := annotateResources(templates, namespace, annotations); err != nil {
+			return fmt.Errorf("annotating release resources failed: %s", err)
+		}
+	}
+
 	if err := trackPods(templates, deployStartTime, namespace, opts); err != nil {
 		return err
 	}
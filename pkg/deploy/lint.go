@@ -8,6 +8,7 @@ import (
 )
 
 type LintOptions struct {
+	Environment  string
 	Values       []string
 	SecretValues []string
 	Set          []string
@@ -19,7 +20,7 @@ func RunLint(projectDir string, werfConfig *config.WerfConfig, opts LintOptions)
 		fmt.Printf("Lint options: %#v\n", opts)
 	}
 
-	m, err := getSafeSecretManager(projectDir, opts.SecretValues)
+	m, err := getSafeSecretManager(projectDir, opts.Environment, opts.SecretValues, &werfConfig.Meta.Secrets)
 	if err != nil {
 		return fmt.Errorf("cannot get project secret: %s", err)
 	}
@@ -34,12 +35,12 @@ func RunLint(projectDir string, werfConfig *config.WerfConfig, opts LintOptions)
 		images = append(images, d)
 	}
 
-	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, nil, images, ServiceValuesOptions{ForceBranch: "GIT_BRANCH"})
+	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, nil, images, ServiceValuesOptions{ForceBranch: "GIT_BRANCH", Env: opts.Environment})
 	if err != nil {
 		return fmt.Errorf("error creating service values: %s", err)
 	}
 
-	werfChart, err := getWerfChart(projectDir, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
+	werfChart, err := getWerfChart(projectDir, opts.Environment, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
 	if err != nil {
 		return err
 	}
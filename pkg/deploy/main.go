@@ -56,7 +56,7 @@ func HelmVersion() (*version.Version, error) {
 
 func HelmCmd(args ...string) (stdout string, stderr string, err error) {
 	cmd := exec.Command("helm", args...)
-	cmd.Env = os.Environ()
+	cmd.Env = helmCmdEnv()
 
 	var stdoutBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
@@ -70,6 +70,31 @@ func HelmCmd(args ...string) (stdout string, stderr string, err error) {
 	return
 }
 
+// HelmPassthroughCmd runs helm command with output to the terminal
+func HelmPassthroughCmd(args ...string) error {
+	cmd := exec.Command("helm", args...)
+	cmd.Env = helmCmdEnv()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("helm %s failed: %s", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+// helmCmdEnv makes helm use configured release storage namespace as tiller namespace
+func helmCmdEnv() []string {
+	env := os.Environ()
+	if releaseStorageOptions.Namespace != "" {
+		env = append(env, fmt.Sprintf("TILLER_NAMESPACE=%s", releaseStorageOptions.Namespace))
+	}
+
+	return env
+}
+
 func debug() bool {
 	return os.Getenv("WERF_DEPLOY_DEBUG") == "1"
 }
@@ -0,0 +1,105 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// GetProjectReleases returns names of releases deployed by werf for the project.
+// Release belongs to the project if values of its latest revision contain werf service value global.werf.name equal to the project name.
+func GetProjectReleases(projectName string) ([]string, error) {
+	releases, err := releaseStorage().ListReleases()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list releases: %s", err)
+	}
+
+	latestReleases := map[string]*release.Release{}
+	for _, rls := range releases {
+		if latest, ok := latestReleases[rls.Name]; !ok || rls.Version > latest.Version {
+			latestReleases[rls.Name] = rls
+		}
+	}
+
+	var res []string
+	for name, rls := range latestReleases {
+		if releaseProjectName(rls) == projectName {
+			res = append(res, name)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// ValidateProjectRelease returns error if release does not exist or has not been deployed by werf for the project
+func ValidateProjectRelease(releaseName, projectName string) error {
+	history, err := releaseStorage().History(releaseName)
+	if err != nil {
+		return fmt.Errorf("cannot get release %s history: %s", releaseName, err)
+	}
+
+	if len(history) == 0 {
+		return fmt.Errorf("release %s not found", releaseName)
+	}
+
+	var latest *release.Release
+	for _, rls := range history {
+		if latest == nil || rls.Version > latest.Version {
+			latest = rls
+		}
+	}
+
+	if name := releaseProjectName(latest); name != projectName {
+		if name == "" {
+			return fmt.Errorf("release %s has not been deployed by werf: use helm to manage the release", releaseName)
+		}
+		return fmt.Errorf("release %s belongs to project %s, not to project %s", releaseName, name, projectName)
+	}
+
+	return nil
+}
+
+// RollbackHelmRelease rolls back the release to the revision with the release locked like on deploy
+func RollbackHelmRelease(releaseName string, revision int, opts CommonHelmOptions) error {
+	return withLockedHelmRelease(releaseName, func() error {
+		args := []string{"rollback", releaseName, fmt.Sprintf("%d", revision)}
+		if opts.KubeContext != "" {
+			args = append(args, "--kube-context", opts.KubeContext)
+		}
+
+		fmt.Printf("# Rolling back helm release '%s' to revision %d...\n", releaseName, revision)
+
+		return HelmPassthroughCmd(args...)
+	})
+}
+
+// releaseProjectName returns werf service value global.werf.name of the release or empty string
+func releaseProjectName(rls *release.Release) string {
+	if rls.Config == nil {
+		return ""
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(rls.Config.Raw), &values); err != nil {
+		return ""
+	}
+
+	global, ok := values["global"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	werfValues, ok := global["werf"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	name, _ := werfValues["name"].(string)
+
+	return name
+}
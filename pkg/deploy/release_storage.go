@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flant/kubedog/pkg/kube"
+
+	"k8s.io/helm/pkg/storage"
+	"k8s.io/helm/pkg/storage/driver"
+)
+
+const (
+	ConfigMapReleaseStorage = "configmap"
+	SecretReleaseStorage    = "secret"
+
+	defaultTillerNamespace = "kube-system"
+)
+
+// ReleaseStorageOptions should match tiller settings: tiller namespace and --storage option of tiller
+type ReleaseStorageOptions struct {
+	// Type is configmap or secret, configmap is used by default
+	Type string
+	// Namespace is tiller namespace, $TILLER_NAMESPACE or kube-system is used by default
+	Namespace string
+}
+
+var releaseStorageOptions ReleaseStorageOptions
+
+// SetReleaseStorage configures release storage used by werf to read and adopt releases and tiller namespace of helm commands
+func SetReleaseStorage(opts ReleaseStorageOptions) error {
+	switch opts.Type {
+	case "", ConfigMapReleaseStorage, SecretReleaseStorage:
+	default:
+		return fmt.Errorf("bad helm release storage type '%s': %s or %s expected", opts.Type, ConfigMapReleaseStorage, SecretReleaseStorage)
+	}
+
+	releaseStorageOptions = opts
+
+	return nil
+}
+
+func releaseStorage() *storage.Storage {
+	if releaseStorageOptions.Type == SecretReleaseStorage {
+		return storage.Init(driver.NewSecrets(kube.Kubernetes.CoreV1().Secrets(tillerNamespace())))
+	}
+
+	return storage.Init(driver.NewConfigMaps(kube.Kubernetes.CoreV1().ConfigMaps(tillerNamespace())))
+}
+
+func tillerNamespace() string {
+	if releaseStorageOptions.Namespace != "" {
+		return releaseStorageOptions.Namespace
+	}
+
+	if ns := os.Getenv("TILLER_NAMESPACE"); ns != "" {
+		return ns
+	}
+
+	return defaultTillerNamespace
+}
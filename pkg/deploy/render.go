@@ -8,6 +8,7 @@ import (
 )
 
 type RenderOptions struct {
+	Environment  string
 	Values       []string
 	SecretValues []string
 	Set          []string
@@ -19,7 +20,7 @@ func RunRender(projectDir string, werfConfig *config.WerfConfig, opts RenderOpti
 		fmt.Printf("Render options: %#v\n", opts)
 	}
 
-	m, err := getSafeSecretManager(projectDir, opts.SecretValues)
+	m, err := getSafeSecretManager(projectDir, opts.Environment, opts.SecretValues, &werfConfig.Meta.Secrets)
 	if err != nil {
 		return fmt.Errorf("cannot get project secret: %s", err)
 	}
@@ -34,9 +35,9 @@ func RunRender(projectDir string, werfConfig *config.WerfConfig, opts RenderOpti
 		images = append(images, d)
 	}
 
-	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, nil, images, ServiceValuesOptions{ForceBranch: "GIT_BRANCH"})
+	serviceValues, err := GetServiceValues(werfConfig.Meta.Project, repo, namespace, tag, nil, images, ServiceValuesOptions{ForceBranch: "GIT_BRANCH", Env: opts.Environment})
 
-	werfChart, err := getWerfChart(projectDir, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
+	werfChart, err := getWerfChart(projectDir, opts.Environment, m, opts.Values, opts.SecretValues, opts.Set, opts.SetString, serviceValues)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/logger"
 	"github.com/flant/werf/pkg/secret"
 	"github.com/flant/werf/pkg/werf"
@@ -25,76 +26,151 @@ func GenerateSecretKey() ([]byte, error) {
 	return secret.GenerateAexSecretKey()
 }
 
-func GetManager(projectDir string) (Manager, error) {
+func SecretKeyFingerprint(key []byte) string {
+	return secret.AesKeyFingerprint(key)
+}
+
+// GetManager returns manager, which encrypts secrets with the backend from werf.yaml (aes with project secret key by default)
+// and decrypts secrets encrypted by any of the backends
+func GetManager(projectDir string, secretsConfig *config.MetaSecrets) (Manager, error) {
 	var m Manager
 	var key []byte
 	var err error
 
-	key, err = GetSecretKey(projectDir)
-	if err != nil {
-		return nil, err
+	if secretsConfig == nil || secretsConfig.Backend == config.AesSecretsBackend {
+		key, err = GetSecretKey(projectDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if secretsConfig == nil {
+			m, err = NewManager(key, NewManagerOptions{})
+			if err != nil {
+				return nil, err
+			}
+
+			return m, nil
+		}
+	} else {
+		// legacy aes secrets are decrypted only if the key is available
+		key, _ = GetSecretKey(projectDir)
 	}
 
-	m, err = NewManager(key, NewManagerOptions{})
-	if err != nil {
-		return nil, err
+	var legacy secret.Secret
+	if len(key) != 0 {
+		if legacy, err = newSecret(key, NewManagerOptions{}); err != nil {
+			return nil, err
+		}
 	}
 
-	return m, nil
+	encoders := getEncoders(projectDir, secretsConfig)
+
+	var defaultEncoder secret.Encoder
+	for _, encoder := range encoders {
+		if encoder.Scheme() == secretsConfig.Backend {
+			defaultEncoder = encoder
+		}
+	}
+
+	return newBaseManager(secret.NewSchemeSecret(defaultEncoder, legacy, encoders...))
+}
+
+// getEncoders returns all backends, decryption credentials are taken from envs
+func getEncoders(projectDir string, secretsConfig *config.MetaSecrets) []secret.Encoder {
+	gpgRecipientsKeyring := secretsConfig.GPGRecipientsKeyring
+	if gpgRecipientsKeyring != "" && !filepath.IsAbs(gpgRecipientsKeyring) {
+		gpgRecipientsKeyring = filepath.Join(projectDir, gpgRecipientsKeyring)
+	}
+
+	vaultAddress := secretsConfig.VaultAddress
+	if vaultAddress == "" {
+		vaultAddress = os.Getenv("VAULT_ADDR")
+	}
+
+	return []secret.Encoder{
+		&secret.AgeEncoder{
+			Recipients:   secretsConfig.AgeRecipients,
+			IdentityFile: os.Getenv("WERF_SECRET_AGE_IDENTITY_FILE"),
+		},
+		&secret.GPGEncoder{
+			RecipientsKeyringFile: gpgRecipientsKeyring,
+			SecretKeyringFile:     os.Getenv("WERF_SECRET_GPG_KEYRING"),
+			Passphrase:            []byte(os.Getenv("WERF_SECRET_GPG_PASSPHRASE")),
+		},
+		&secret.VaultTransitEncoder{
+			Address: vaultAddress,
+			Mount:   secretsConfig.VaultTransitMount,
+			Key:     secretsConfig.VaultTransitKey,
+			Token:   os.Getenv("VAULT_TOKEN"),
+		},
+	}
+}
+
+// SecretKey is an aes encryption key found in one of the key sources
+type SecretKey struct {
+	// Source is $WERF_SECRET_KEY or path of the key file
+	Source string
+	Key    []byte
 }
 
+// GetSecretKey returns the active key: the first key found in $WERF_SECRET_KEY, project .werf_secret_key file or ~/.werf/.werf_secret_key file
 func GetSecretKey(projectDir string) ([]byte, error) {
-	var secretKey []byte
-	var werfSecretKeyPaths []string
-	var notFoundIn []string
+	keys, sources, err := getSecretKeys(projectDir)
+	if err != nil {
+		return nil, err
+	}
 
-	secretKey = []byte(os.Getenv("WERF_SECRET_KEY"))
-	if len(secretKey) == 0 {
-		notFoundIn = append(notFoundIn, "$WERF_SECRET_KEY")
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("encryption key not found in: '%s'", strings.Join(sources, "', '"))
+	}
 
-		var werfSecretKeyPath string
+	return keys[0].Key, nil
+}
 
-		projectWerfSecretKeyPath, err := filepath.Abs(filepath.Join(projectDir, ".werf_secret_key"))
-		if err != nil {
-			return nil, err
-		}
+// GetSecretKeys returns keys found in all key sources, the first key is the active one
+func GetSecretKeys(projectDir string) ([]*SecretKey, error) {
+	keys, _, err := getSecretKeys(projectDir)
+	return keys, err
+}
 
-		homeWerfSecretKeyPath := filepath.Join(werf.GetHomeDir(), ".werf_secret_key")
+func getSecretKeys(projectDir string) ([]*SecretKey, []string, error) {
+	var keys []*SecretKey
 
-		werfSecretKeyPaths = []string{
-			projectWerfSecretKeyPath,
-			homeWerfSecretKeyPath,
-		}
+	sources := []string{"$WERF_SECRET_KEY"}
+	if secretKey := []byte(os.Getenv("WERF_SECRET_KEY")); len(secretKey) != 0 {
+		keys = append(keys, &SecretKey{Source: "$WERF_SECRET_KEY", Key: secretKey})
+	}
 
-		for _, path := range werfSecretKeyPaths {
-			exist, err := file.FileExists(path)
-			if err != nil {
-				return nil, err
-			}
+	projectWerfSecretKeyPath, err := filepath.Abs(filepath.Join(projectDir, ".werf_secret_key"))
+	if err != nil {
+		return nil, nil, err
+	}
 
-			if exist {
-				werfSecretKeyPath = path
-				break
-			} else {
-				notFoundIn = append(notFoundIn, fmt.Sprintf("%s", path))
-			}
+	homeWerfSecretKeyPath := filepath.Join(werf.GetBaseHomeDir(), ".werf_secret_key")
+
+	for _, path := range []string{projectWerfSecretKeyPath, homeWerfSecretKeyPath} {
+		sources = append(sources, path)
+
+		exist, err := file.FileExists(path)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		if werfSecretKeyPath != "" {
-			data, err := ioutil.ReadFile(werfSecretKeyPath)
-			if err != nil {
-				return nil, err
-			}
+		if !exist {
+			continue
+		}
 
-			secretKey = []byte(strings.TrimSpace(string(data)))
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
 
-	if len(secretKey) == 0 {
-		return nil, fmt.Errorf("encryption key not found in: '%s'", strings.Join(notFoundIn, "', '"))
+		if secretKey := []byte(strings.TrimSpace(string(data))); len(secretKey) != 0 {
+			keys = append(keys, &SecretKey{Source: path, Key: secretKey})
+		}
 	}
 
-	return secretKey, nil
+	return keys, sources, nil
 }
 
 type NewManagerOptions struct {
@@ -102,6 +178,15 @@ type NewManagerOptions struct {
 }
 
 func NewManager(key []byte, options NewManagerOptions) (Manager, error) {
+	ss, err := newSecret(key, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBaseManager(ss)
+}
+
+func newSecret(key []byte, options NewManagerOptions) (secret.Secret, error) {
 	ss, err := secret.NewSecret(key)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "encoding/hex:") {
@@ -113,13 +198,13 @@ func NewManager(key []byte, options NewManagerOptions) (Manager, error) {
 ###################################################################################################`)
 			}
 
-			return NewManager(ruby2GoSecretKey(key), options)
+			return newSecret(ruby2GoSecretKey(key), options)
 		}
 
 		return nil, fmt.Errorf("check encryption key: %s", err)
 	}
 
-	return newBaseManager(ss)
+	return ss, nil
 }
 
 func ruby2GoSecretKey(key []byte) []byte {
@@ -0,0 +1,254 @@
+package secret
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/secret"
+	"github.com/flant/werf/pkg/werf"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const testSecretKey = "11ac8312520b5ff037bae386ea2e8a07"
+
+// initTestSecretKeysDirs creates project and werf home dirs, $WERF_SECRET_KEY is unset till cleanup
+func initTestSecretKeysDirs(t *testing.T) (string, string, func()) {
+	tmpDir, err := ioutil.TempDir("", "werf-secret-keys-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := filepath.Join(tmpDir, "project")
+	homeDir := filepath.Join(tmpDir, "home")
+	for _, dir := range []string{projectDir, homeDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := werf.Init(tmpDir, homeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	secretKey, hasSecretKey := os.LookupEnv("WERF_SECRET_KEY")
+	os.Unsetenv("WERF_SECRET_KEY")
+
+	return projectDir, homeDir, func() {
+		if hasSecretKey {
+			os.Setenv("WERF_SECRET_KEY", secretKey)
+		} else {
+			os.Unsetenv("WERF_SECRET_KEY")
+		}
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetSecretKeys(t *testing.T) {
+	projectDir, homeDir, cleanup := initTestSecretKeysDirs(t)
+	defer cleanup()
+
+	projectKeyPath := filepath.Join(projectDir, ".werf_secret_key")
+	homeKeyPath := filepath.Join(homeDir, ".werf_secret_key")
+
+	os.Setenv("WERF_SECRET_KEY", "env-key")
+	writeTestFile(t, projectKeyPath, "project-key\n")
+	writeTestFile(t, homeKeyPath, "  home-key  \n")
+
+	keys, err := GetSecretKeys(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*SecretKey{
+		{Source: "$WERF_SECRET_KEY", Key: []byte("env-key")},
+		{Source: projectKeyPath, Key: []byte("project-key")},
+		{Source: homeKeyPath, Key: []byte("home-key")},
+	}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, keys)
+	}
+
+	key, err := GetSecretKey(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(key) != "env-key" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "env-key", key)
+	}
+
+	os.Unsetenv("WERF_SECRET_KEY")
+	writeTestFile(t, projectKeyPath, "\n")
+
+	keys, err = GetSecretKeys(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []*SecretKey{{Source: homeKeyPath, Key: []byte("home-key")}}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, keys)
+	}
+}
+
+func TestGetSecretKey_notFound(t *testing.T) {
+	projectDir, homeDir, cleanup := initTestSecretKeysDirs(t)
+	defer cleanup()
+
+	_, err := GetSecretKey(projectDir)
+
+	expected := fmt.Sprintf("encryption key not found in: '$WERF_SECRET_KEY', '%s', '%s'", filepath.Join(projectDir, ".werf_secret_key"), filepath.Join(homeDir, ".werf_secret_key"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", expected, err)
+	}
+}
+
+func TestSecretKeyFingerprint(t *testing.T) {
+	if fingerprint := SecretKeyFingerprint([]byte(testSecretKey)); fingerprint != secret.AesKeyFingerprint([]byte(testSecretKey)) || len(fingerprint) != 16 {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", secret.AesKeyFingerprint([]byte(testSecretKey)), fingerprint)
+	}
+}
+
+// writeTestGPGKeyring generates a key without passphrase and writes armored public and secret keyrings.
+// Preferred hash is specified explicitly: openpgp falls back to RIPEMD160 for keys without preferences.
+// The preference is stored into identity self-signature, when the secret keyring is serialized, so it goes first
+func writeTestGPGKeyring(t *testing.T, publicKeyringPath, secretKeyringPath string) {
+	entity, err := openpgp.NewEntity("werf test", "", "test@werf.io", &packet.Config{DefaultHash: crypto.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keyring := range []struct {
+		path      string
+		blockType string
+		serialize func(buf *bytes.Buffer) error
+	}{
+		{secretKeyringPath, openpgp.PrivateKeyType, func(buf *bytes.Buffer) error { return entity.SerializePrivate(buf, nil) }},
+		{publicKeyringPath, openpgp.PublicKeyType, func(buf *bytes.Buffer) error { return entity.Serialize(buf) }},
+	} {
+		buf := &bytes.Buffer{}
+		w, err := armor.Encode(buf, keyring.blockType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := &bytes.Buffer{}
+		if err := keyring.serialize(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write(data.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		writeTestFile(t, keyring.path, buf.String())
+	}
+}
+
+func TestGetManager(t *testing.T) {
+	projectDir, _, cleanup := initTestSecretKeysDirs(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(projectDir, ".werf_secret_key"), testSecretKey)
+
+	m, err := GetManager(projectDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := m.Generate([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := NewManager([]byte(testSecretKey), NewManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := legacy.Extract(encodedData); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "value" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "value", data)
+	}
+}
+
+func TestGetManager_gpgBackend(t *testing.T) {
+	projectDir, homeDir, cleanup := initTestSecretKeysDirs(t)
+	defer cleanup()
+
+	secretKeyringPath := filepath.Join(homeDir, "secring.asc")
+	writeTestGPGKeyring(t, filepath.Join(projectDir, "pubring.asc"), secretKeyringPath)
+	writeTestFile(t, filepath.Join(projectDir, ".werf_secret_key"), testSecretKey)
+
+	gpgKeyring, hasGPGKeyring := os.LookupEnv("WERF_SECRET_GPG_KEYRING")
+	os.Setenv("WERF_SECRET_GPG_KEYRING", secretKeyringPath)
+	defer func() {
+		if hasGPGKeyring {
+			os.Setenv("WERF_SECRET_GPG_KEYRING", gpgKeyring)
+		} else {
+			os.Unsetenv("WERF_SECRET_GPG_KEYRING")
+		}
+	}()
+
+	m, err := GetManager(projectDir, &config.MetaSecrets{Backend: config.GPGSecretsBackend, GPGRecipientsKeyring: "pubring.asc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := m.Generate([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(encodedData, []byte("$gpg$")) {
+		t.Errorf("\n[EXPECTED]: $gpg$...\n[GOT]: %s", encodedData)
+	}
+
+	legacy, err := NewManager([]byte(testSecretKey), NewManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyEncodedData, err := legacy.Generate([]byte("legacy value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		encodedData []byte
+		data        string
+	}{
+		{encodedData, "value"},
+		{legacyEncodedData, "legacy value"},
+	} {
+		data, err := m.Extract(test.encodedData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != test.data {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.data, data)
+		}
+	}
+}
@@ -23,12 +23,17 @@ type ImageInfoGetter interface {
 	IsNameless() bool
 	GetName() string
 	GetImageName() string
+	GetImageRepository() string
+	GetImageTag() string
 	GetImageId() (string, error)
+	GetImageDigest() (string, error)
+	GetImageGitCommit() (string, error)
 }
 
 type ServiceValuesOptions struct {
 	ForceTag    string
 	ForceBranch string
+	Env         string
 }
 
 func GetServiceValues(projectName, repo, namespace, dockerTag string, localGit GitInfoGetter, images []ImageInfoGetter, opts ServiceValuesOptions) (map[string]interface{}, error) {
@@ -55,6 +60,7 @@ func GetServiceValues(projectName, repo, namespace, dockerTag string, localGit G
 
 	res["global"] = map[string]interface{}{
 		"namespace": namespace,
+		"env":       opts.Env,
 		"werf":      werfInfo,
 	}
 
@@ -114,6 +120,8 @@ func GetServiceValues(projectName, repo, namespace, dockerTag string, localGit G
 		}
 
 		imageData["docker_image"] = image.GetImageName()
+		imageData["docker_image_repo"] = image.GetImageRepository()
+		imageData["docker_tag"] = image.GetImageTag()
 		imageData["docker_image_id"] = TemplateEmptyValue
 
 		imageID, err := image.GetImageId()
@@ -132,6 +140,18 @@ func GetServiceValues(projectName, repo, namespace, dockerTag string, localGit G
 			value = imageID
 		}
 		imageData["docker_image_id"] = value
+
+		imageDigest, err := image.GetImageDigest()
+		if err != nil {
+			return nil, err
+		}
+		imageData["docker_image_digest"] = valueOrTemplateEmptyValue(imageDigest)
+
+		imageGitCommit, err := image.GetImageGitCommit()
+		if err != nil {
+			return nil, err
+		}
+		imageData["git_commit"] = valueOrTemplateEmptyValue(imageGitCommit)
 	}
 
 	if debug() {
@@ -141,3 +161,10 @@ func GetServiceValues(projectName, repo, namespace, dockerTag string, localGit G
 
 	return res, nil
 }
+
+func valueOrTemplateEmptyValue(value string) string {
+	if value == "" {
+		return TemplateEmptyValue
+	}
+	return value
+}
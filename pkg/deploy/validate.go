@@ -0,0 +1,229 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flant/kubedog/pkg/kube"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/helm/pkg/releaseutil"
+)
+
+// Server-side dry-run is beta and enabled by default since kubernetes 1.13
+const serverDryRunMinMinorVersion = 13
+
+func (chart *WerfChart) Validate(releaseName string, namespace string, opts HelmChartOptions) error {
+	return ValidateHelmChart(chart.ChartDir, releaseName, namespace, HelmChartOptions{
+		CommonHelmOptions: CommonHelmOptions{KubeContext: opts.KubeContext},
+		Set:               append(chart.Set, opts.Set...),
+		SetString:         append(chart.SetString, opts.SetString...),
+		Values:            append(chart.Values, opts.Values...),
+	})
+}
+
+// ValidateHelmChart submits rendered chart manifests to the kubernetes API server with server-side dry-run,
+// so schema errors and rejections of admission controllers and webhooks are reported without any real change
+func ValidateHelmChart(chartPath string, releaseName string, namespace string, opts HelmChartOptions) error {
+	supported, err := isServerDryRunSupported()
+	if err != nil {
+		return fmt.Errorf("cannot get kubernetes version: %s", err)
+	}
+
+	if !supported {
+		fmt.Printf("# Server-side dry-run is not supported by kubernetes API server (1.%d+ required), skipping validation\n", serverDryRunMinMinorVersion)
+		return nil
+	}
+
+	manifests, err := renderManifests(chartPath, releaseName, namespace, opts)
+	if err != nil {
+		return err
+	}
+
+	namespaceExists := true
+	if _, err := kube.Kubernetes.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		namespaceExists = false
+	} else if err != nil {
+		return fmt.Errorf("cannot get namespace %s: %s", namespace, err)
+	}
+
+	var rejections []string
+	for _, manifest := range manifests {
+		obj, err := decodeManifest(manifest)
+		if err != nil {
+			return err
+		}
+
+		if obj == nil {
+			continue
+		}
+
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+
+		resourceDesc := fmt.Sprintf("%s/%s", strings.ToLower(obj.GetKind()), obj.GetName())
+
+		fmt.Printf("# Validating %s\n", resourceDesc)
+
+		if err := validateObject(obj, namespaceExists || obj.GetNamespace() != namespace); err != nil {
+			rejections = append(rejections, fmt.Sprintf("%s: %s", resourceDesc, err))
+		}
+	}
+
+	if len(rejections) > 0 {
+		return fmt.Errorf("kubernetes API server rejected resources:\n%s", strings.Join(rejections, "\n"))
+	}
+
+	return nil
+}
+
+func isServerDryRunSupported() (bool, error) {
+	info, err := kube.Kubernetes.Discovery().ServerVersion()
+	if err != nil {
+		return false, err
+	}
+
+	major, err := strconv.Atoi(strings.TrimSuffix(info.Major, "+"))
+	if err != nil {
+		return false, fmt.Errorf("bad major version '%s'", info.Major)
+	}
+
+	minor, err := strconv.Atoi(strings.TrimSuffix(info.Minor, "+"))
+	if err != nil {
+		return false, fmt.Errorf("bad minor version '%s'", info.Minor)
+	}
+
+	return major > 1 || minor >= serverDryRunMinMinorVersion, nil
+}
+
+func renderManifests(chartPath, releaseName, namespace string, opts HelmChartOptions) ([]string, error) {
+	args := []string{"template", chartPath, "--name", releaseName, "--namespace", namespace}
+	for _, s := range opts.Set {
+		args = append(args, "--set", s)
+	}
+	for _, s := range opts.SetString {
+		args = append(args, "--set-string", s)
+	}
+	for _, v := range opts.Values {
+		args = append(args, "--values", v)
+	}
+
+	stdout, stderr, err := HelmCmd(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s\n%s", stdout, stderr)
+	}
+
+	manifestsByName := releaseutil.SplitManifests(stdout)
+
+	var names []string
+	for name := range manifestsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests []string
+	for _, name := range names {
+		manifests = append(manifests, manifestsByName[name])
+	}
+
+	return manifests, nil
+}
+
+func decodeManifest(manifest string) (*unstructured.Unstructured, error) {
+	data, err := k8syaml.ToJSON([]byte(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("bad manifest: %s\n%s", err, manifest)
+	}
+
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(data, &obj.Object); err != nil {
+		return nil, fmt.Errorf("bad manifest: %s\n%s", err, manifest)
+	}
+
+	if obj.GetKind() == "" || obj.GetName() == "" {
+		return nil, nil
+	}
+
+	return obj, nil
+}
+
+func validateObject(obj *unstructured.Unstructured, namespaceExists bool) error {
+	resource, namespaced, err := getResourceName(obj.GetAPIVersion(), obj.GetKind())
+	if err != nil {
+		return err
+	}
+
+	if !namespaced {
+		obj.SetNamespace("")
+	} else if !namespaceExists {
+		fmt.Printf("# Namespace %s does not exist yet, only schema of %s/%s is checked\n", obj.GetNamespace(), strings.ToLower(obj.GetKind()), obj.GetName())
+		obj.SetNamespace("default")
+	}
+
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+
+	path := resourcePath(obj.GetAPIVersion(), resource, obj.GetNamespace())
+	client := kube.Kubernetes.Discovery().RESTClient()
+
+	err = client.Post().
+		AbsPath(path).
+		Param("dryRun", "All").
+		SetHeader("Content-Type", "application/json").
+		Body(data).
+		Do().
+		Error()
+
+	if apierrors.IsAlreadyExists(err) {
+		err = client.Patch(types.MergePatchType).
+			AbsPath(path, obj.GetName()).
+			Param("dryRun", "All").
+			Body(data).
+			Do().
+			Error()
+	}
+
+	return err
+}
+
+func getResourceName(apiVersion, kind string) (string, bool, error) {
+	resources, err := kube.Kubernetes.Discovery().ServerResourcesForGroupVersion(apiVersion)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot get resources of %s: %s", apiVersion, err)
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Kind == kind && !strings.Contains(resource.Name, "/") {
+			return resource.Name, resource.Namespaced, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("kind %s is not supported by %s", kind, apiVersion)
+}
+
+func resourcePath(apiVersion, resource, namespace string) string {
+	path := "/api/" + apiVersion
+	if strings.Contains(apiVersion, "/") {
+		path = "/apis/" + apiVersion
+	}
+
+	if namespace != "" {
+		path = fmt.Sprintf("%s/namespaces/%s", path, namespace)
+	}
+
+	return fmt.Sprintf("%s/%s", path, resource)
+}
@@ -22,6 +22,10 @@ const (
 	ProjectDefaultSecretValuesFile = ProjectHelmChartDir + "/secret-values.yaml"
 	ProjectSecretDir               = ProjectHelmChartDir + "/secret"
 
+	// environment values files are loaded automatically after default values, but before user specified values
+	ProjectEnvValuesFileTemplate       = ProjectHelmChartDir + "/values-%s.yaml"
+	ProjectEnvSecretValuesFileTemplate = ProjectHelmChartDir + "/secret-values-%s.yaml"
+
 	WerfChartDecodedSecretDir = "decoded-secret"
 	WerfChartMoreValuesDir    = "more-values"
 )
@@ -32,11 +36,18 @@ type WerfChart struct {
 	Set       []string
 	SetString []string
 
+	// Annotations are added to all release resources after helm install or upgrade
+	Annotations map[string]string
+
 	moreValuesCounter uint
 }
 
 func (chart *WerfChart) SetGlobalAnnotation(name, value string) error {
-	// TODO: https://github.com/flant/werf/issues/1069
+	if chart.Annotations == nil {
+		chart.Annotations = map[string]string{}
+	}
+	chart.Annotations[name] = value
+
 	return nil
 }
 
@@ -115,6 +126,9 @@ func (chart *WerfChart) Deploy(releaseName string, namespace string, opts HelmCh
 		Values:            append(chart.Values, opts.Values...),
 		DryRun:            opts.DryRun,
 		Debug:             opts.Debug,
+		Annotations:       chart.Annotations,
+
+		AllowAdoptionByRelease: opts.AllowAdoptionByRelease,
 	})
 }
 
@@ -215,6 +229,15 @@ func PrepareWerfChart(projectDir string, targetDir string, m secret.Manager) (*W
 		return nil, fmt.Errorf("unable to copy project helm dir %s into %s: %s", projectHelmDir, targetDir, err)
 	}
 
+	// dependencies, which are vendored into .helm/charts, are used as is without lock file check and charts repositories
+	if built, err := IsChartDependenciesBuilt(targetDir); err != nil {
+		return nil, fmt.Errorf("unable to check chart dependencies: %s", err)
+	} else if !built {
+		if err := BuildChartDependencies(werf.GetContext(), targetDir, projectHelmDir); err != nil {
+			return nil, fmt.Errorf("unable to build chart dependencies: %s", err)
+		}
+	}
+
 	templatesDir := filepath.Join(targetDir, "templates")
 	err = os.MkdirAll(templatesDir, os.ModePerm)
 	if err != nil {
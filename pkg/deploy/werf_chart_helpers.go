@@ -103,6 +103,37 @@ image: {{ tuple $name $context | include "_image2" }}
 {{-   end -}}
 {{- end -}}
 
+{{- define "_image_digest" -}}
+{{-   $context := index . 0 -}}
+{{-   if not $context.Values.global.werf.is_nameless_image -}}
+{{-     required "No image specified for template" nil -}}
+{{-   end -}}
+{{    $context.Values.global.werf.image.docker_image_digest }}
+{{- end -}}
+
+{{- define "_image_digest2" -}}
+{{-   $name := index . 0 -}}
+{{-   $context := index . 1 -}}
+{{-   if $context.Values.global.werf.is_nameless_image -}}
+{{-     required (printf "No image should be specified for template, got '%s'" $name) nil -}}
+{{-   end -}}
+{{    index (required (printf "Unknown image '%s' specified for template" $name) (pluck $name $context.Values.global.werf.image | first)) "docker_image_digest" }}
+{{- end -}}
+
+{{- define "image_digest" -}}
+{{-   if eq (typeOf .) "chartutil.Values" -}}
+{{-     $context := . -}}
+{{      tuple $context | include "_image_digest" }}
+{{-   else if (ge (len .) 2) -}}
+{{-     $name := index . 0 -}}
+{{-     $context := index . 1 -}}
+{{      tuple $name $context | include "_image_digest2" }}
+{{-   else -}}
+{{-     $context := index . 0 -}}
+{{      tuple $context | include "_image_digest" }}
+{{-   end -}}
+{{- end -}}
+
 {{- define "_werf_container_env" -}}
 {{-   $context := index . 0 -}}
 {{-   if $context.Values.global.werf.ci.is_branch -}}
@@ -0,0 +1,219 @@
+package dev_mode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+)
+
+const DevModeLabel = "werf-dev-mode"
+
+// DevContainer is a running container of the built image, changed project files are synced into it
+// and affected user stages are re-run instead of building new git patch stage for each edit
+type DevContainer struct {
+	Name       string
+	ImageName  string
+	ProjectDir string
+	Mappings   []*Mapping
+	UserStages []*UserStage
+}
+
+// GetDevContainerName uses stage containers prefix, so dev containers are removed by werf reset and flush
+func GetDevContainerName(projectName, imageName string) string {
+	if imageName == "" {
+		return fmt.Sprintf("%sdev.%s", image.StageContainerNamePrefix, projectName)
+	}
+	return fmt.Sprintf("%sdev.%s.%s", image.StageContainerNamePrefix, projectName, imageName)
+}
+
+// Run removes previous dev container and runs the new one from the image
+func (c *DevContainer) Run(projectName string) error {
+	if err := c.Remove(); err != nil {
+		return err
+	}
+
+	args := []string{
+		"-d",
+		"--name", c.Name,
+		"--label", fmt.Sprintf("werf=%s", projectName),
+		"--label", fmt.Sprintf("%s=true", DevModeLabel),
+		"--entrypoint", "",
+		c.ImageName,
+		"sh", "-c", "while true; do sleep 3600; done",
+	}
+
+	if err := docker.CliRun(args...); err != nil {
+		return fmt.Errorf("cannot run dev container %s: %s", c.Name, err)
+	}
+
+	return nil
+}
+
+func (c *DevContainer) Remove() error {
+	exist, err := docker.ContainerExist(c.Name)
+	if err != nil {
+		return err
+	}
+
+	if !exist {
+		return nil
+	}
+
+	if err := docker.ContainerRemove(c.Name, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+		return fmt.Errorf("cannot remove dev container %s: %s", c.Name, err)
+	}
+
+	return nil
+}
+
+// Watch syncs uncommitted changes of the project work tree, then polls project directory and syncs changes until ctx is done
+func (c *DevContainer) Watch(ctx context.Context, interval time.Duration) error {
+	prev, err := takeSnapshot(c.ProjectDir)
+	if err != nil {
+		return err
+	}
+
+	gitDir := filepath.Join(c.ProjectDir, ".git")
+	if _, err := os.Stat(gitDir); err == nil {
+		changed, err := true_git.WorkTreeChangedFiles(ctx, gitDir, c.ProjectDir)
+		if err != nil {
+			return err
+		}
+
+		var existing, removed []string
+		for _, relPath := range changed {
+			if _, ok := prev[relPath]; ok {
+				existing = append(existing, relPath)
+			} else {
+				removed = append(removed, relPath)
+			}
+		}
+
+		if err := c.Sync(existing, removed); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := takeSnapshot(c.ProjectDir)
+		if err != nil {
+			return err
+		}
+
+		changed, removed := diffSnapshots(prev, cur)
+		prev = cur
+
+		if err := c.Sync(changed, removed); err != nil {
+			logger.LogWarningF("WARNING: dev container %s sync failed: %s\n", c.Name, err)
+		}
+	}
+}
+
+// Sync copies changed and removes deleted project files in the dev container, then re-runs affected user stages
+func (c *DevContainer) Sync(changed, removed []string) error {
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	affectedStages := map[string]bool{}
+	markAffectedStages := func(mapping *Mapping, relPath string) {
+		for _, s := range c.UserStages {
+			if mapping.IsStageDependency(relPath, s.Name) {
+				affectedStages[s.Name] = true
+			}
+		}
+	}
+
+	synced := false
+	for _, relPath := range changed {
+		for _, mapping := range c.Mappings {
+			containerPath := mapping.ContainerPath(relPath)
+			if containerPath == "" {
+				continue
+			}
+
+			hostPath := filepath.Join(c.ProjectDir, filepath.FromSlash(relPath))
+			if _, err := os.Lstat(hostPath); os.IsNotExist(err) {
+				continue
+			}
+
+			logger.LogInfoF("Sync %s -> %s\n", relPath, containerPath)
+
+			if err := docker.CliExec(c.Name, "mkdir", "-p", path.Dir(containerPath)); err != nil {
+				return fmt.Errorf("cannot create directory %s in dev container: %s", path.Dir(containerPath), err)
+			}
+
+			if err := docker.CliCp(hostPath, fmt.Sprintf("%s:%s", c.Name, containerPath)); err != nil {
+				return fmt.Errorf("cannot copy %s into dev container: %s", relPath, err)
+			}
+
+			markAffectedStages(mapping, relPath)
+			synced = true
+		}
+	}
+
+	for _, relPath := range removed {
+		for _, mapping := range c.Mappings {
+			containerPath := mapping.ContainerPath(relPath)
+			if containerPath == "" {
+				continue
+			}
+
+			logger.LogInfoF("Remove %s\n", containerPath)
+
+			if err := docker.CliExec(c.Name, "rm", "-rf", containerPath); err != nil {
+				return fmt.Errorf("cannot remove %s in dev container: %s", containerPath, err)
+			}
+
+			markAffectedStages(mapping, relPath)
+			synced = true
+		}
+	}
+
+	if !synced {
+		return nil
+	}
+
+	return c.runAffectedStages(affectedStages)
+}
+
+// runAffectedStages re-runs the earliest affected user stage and all the following stages
+func (c *DevContainer) runAffectedStages(affectedStages map[string]bool) error {
+	var stagesToRun []*UserStage
+	for _, s := range c.UserStages {
+		if len(stagesToRun) > 0 || affectedStages[s.Name] {
+			stagesToRun = append(stagesToRun, s)
+		}
+	}
+
+	for _, s := range stagesToRun {
+		err := logger.LogProcess(fmt.Sprintf("Running %s in dev container", s.Name), "", func() error {
+			return docker.CliExec(c.Name, "sh", "-ec", strings.Join(s.Commands, "\n"))
+		})
+		if err != nil {
+			return fmt.Errorf("%s failed: %s", s.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package dev_mode
+
+import (
+	"path"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/true_git"
+)
+
+// Mapping is a local git mapping of the image: project files from Add are synced into To directory of the dev container
+type Mapping struct {
+	Add               string
+	To                string
+	IncludePaths      []string
+	ExcludePaths      []string
+	StageDependencies map[string][]string
+	// StageAnyChanges are exclude patterns of the stages, which depend on all files of the mapping
+	StageAnyChanges map[string][]string
+}
+
+func GetMappings(imageConfig *config.Image) []*Mapping {
+	if imageConfig.Git == nil {
+		return nil
+	}
+
+	var mappings []*Mapping
+	for _, localGit := range imageConfig.Git.Local {
+		mapping := &Mapping{
+			Add:               localGit.Add,
+			To:                localGit.To,
+			IncludePaths:      localGit.IncludePaths,
+			ExcludePaths:      localGit.ExcludePaths,
+			StageDependencies: map[string][]string{},
+			StageAnyChanges:   map[string][]string{},
+		}
+
+		if localGit.StageDependencies != nil {
+			mapping.StageDependencies[Install] = localGit.StageDependencies.Install
+			mapping.StageDependencies[BeforeSetup] = localGit.StageDependencies.BeforeSetup
+			mapping.StageDependencies[Setup] = localGit.StageDependencies.Setup
+
+			for _, stageName := range []string{Install, BeforeSetup, Setup} {
+				if anyChanges, ok := localGit.StageDependencies.AnyChanges[stageName]; ok {
+					mapping.StageAnyChanges[stageName] = anyChanges.ExcludeOnly
+				}
+			}
+		}
+
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings
+}
+
+// ContainerPath returns path of the project file in the dev container or empty string if file is not mapped
+func (m *Mapping) ContainerPath(relPath string) string {
+	filePath := "/" + relPath
+	if !true_git.IsFilePathValid(filePath, m.Add, m.IncludePaths, m.ExcludePaths) {
+		return ""
+	}
+
+	if true_git.NormalizeAbsolutePath(filePath) == true_git.NormalizeAbsolutePath(m.Add) {
+		return m.To
+	}
+
+	return path.Join(m.To, true_git.TrimFileBasePath(filePath, m.Add))
+}
+
+// IsStageDependency checks whether the project file is in stageDependencies of the user stage
+func (m *Mapping) IsStageDependency(relPath, stageName string) bool {
+	if excludeOnly, anyChanges := m.StageAnyChanges[stageName]; anyChanges {
+		filePath := "/" + relPath
+		if !true_git.IsFilePathValid(filePath, m.Add, m.IncludePaths, m.ExcludePaths) {
+			return false
+		}
+
+		return !true_git.IsFilePathMatchesOneOfPatterns(true_git.TrimFileBasePath(filePath, m.Add), excludeOnly)
+	}
+
+	patterns := m.StageDependencies[stageName]
+	if len(patterns) == 0 {
+		return false
+	}
+
+	filePath := "/" + relPath
+	if !true_git.IsFileInBasePath(filePath, m.Add) {
+		return false
+	}
+
+	return true_git.IsFilePathMatchesOneOfPatterns(true_git.TrimFileBasePath(filePath, m.Add), patterns)
+}
@@ -0,0 +1,37 @@
+package dev_mode
+
+import (
+	"github.com/flant/werf/pkg/config"
+)
+
+const (
+	Install     = "install"
+	BeforeSetup = "beforeSetup"
+	Setup       = "setup"
+)
+
+// UserStage is a user stage, which depends on project files and is re-run in the dev container when they are changed
+type UserStage struct {
+	Name     string
+	Commands []string
+}
+
+// GetUserStages returns shell user stages in the build order, ansible stages cannot be re-run in dev mode
+func GetUserStages(imageConfig *config.Image) []*UserStage {
+	if imageConfig.Shell == nil {
+		return nil
+	}
+
+	var stages []*UserStage
+	for _, s := range []*UserStage{
+		{Name: Install, Commands: imageConfig.Shell.Install},
+		{Name: BeforeSetup, Commands: imageConfig.Shell.BeforeSetup},
+		{Name: Setup, Commands: imageConfig.Shell.Setup},
+	} {
+		if len(s.Commands) > 0 {
+			stages = append(stages, s)
+		}
+	}
+
+	return stages
+}
@@ -0,0 +1,63 @@
+package dev_mode
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileState struct {
+	ModTime time.Time
+	Size    int64
+	Mode    os.FileMode
+}
+
+type snapshot map[string]fileState
+
+// takeSnapshot walks project directory, paths are relative to the dir and slash separated, .git directories are skipped
+func takeSnapshot(dir string) (snapshot, error) {
+	res := snapshot{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		res[filepath.ToSlash(relPath)] = fileState{ModTime: info.ModTime(), Size: info.Size(), Mode: info.Mode()}
+
+		return nil
+	})
+
+	return res, err
+}
+
+func diffSnapshots(prev, cur snapshot) (changed, removed []string) {
+	for relPath, state := range cur {
+		if prevState, ok := prev[relPath]; !ok || prevState != state {
+			changed = append(changed, relPath)
+		}
+	}
+
+	for relPath := range prev {
+		if _, ok := cur[relPath]; !ok {
+			removed = append(removed, relPath)
+		}
+	}
+
+	return
+}
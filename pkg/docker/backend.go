@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+const (
+	DockerContainerRuntime  = "docker"
+	BuildahContainerRuntime = "buildah"
+)
+
+// Backend is a container runtime, which runs assembly instructions, stores built images and pushes them.
+// Arguments of Cli* methods are docker cli arguments, other runtimes translate them into the own commands.
+type Backend interface {
+	Name() string
+	Init() error
+
+	ServerVersion() (*types.Version, error)
+
+	Containers(options types.ContainerListOptions) ([]types.Container, error)
+	ContainerExist(ref string) (bool, error)
+	ContainerInspect(ref string) (types.ContainerJSON, error)
+	ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (string, error)
+	ContainerRemove(ref string, options types.ContainerRemoveOptions) error
+	ContainerLogs(ref string, stdout, stderr io.Writer) error
+	ContainerKill(ref string) error
+
+	CliCreate(args ...string) error
+	CliRun(args ...string) error
+	CliRm(args ...string) error
+	CliCp(args ...string) error
+	CliExec(args ...string) error
+
+	Images(options types.ImageListOptions) ([]types.ImageSummary, error)
+	ImageInspect(ref string) (*types.ImageInspect, error)
+
+	CliPull(ctx context.Context, args ...string) error
+	CliPush(ctx context.Context, args ...string) error
+	CliTag(args ...string) error
+	CliRmi(args ...string) error
+	CliSave(args ...string) error
+	CliLoad(args ...string) error
+
+	CliManifestCreate(args ...string) error
+	CliManifestPush(ctx context.Context, args ...string) error
+}
+
+var backend Backend = &dockerBackend{}
+
+// SetContainerRuntime selects container runtime before Init: docker (default) or buildah
+func SetContainerRuntime(name string) error {
+	switch name {
+	case "", DockerContainerRuntime:
+		backend = &dockerBackend{}
+	case BuildahContainerRuntime:
+		backend = &buildahBackend{}
+	default:
+		return fmt.Errorf("unknown container runtime '%s': expected %s or %s", name, DockerContainerRuntime, BuildahContainerRuntime)
+	}
+
+	return nil
+}
+
+func GetContainerRuntime() string {
+	return backend.Name()
+}
+
+func ServerVersion() (*types.Version, error) {
+	return backend.ServerVersion()
+}
+
+func Containers(options types.ContainerListOptions) ([]types.Container, error) {
+	return backend.Containers(options)
+}
+
+func ContainerExist(ref string) (bool, error) {
+	return backend.ContainerExist(ref)
+}
+
+func ContainerInspect(ref string) (types.ContainerJSON, error) {
+	return backend.ContainerInspect(ref)
+}
+
+func ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (string, error) {
+	return backend.ContainerCommit(ref, commitOptions)
+}
+
+func ContainerRemove(ref string, options types.ContainerRemoveOptions) error {
+	return backend.ContainerRemove(ref, options)
+}
+
+// ContainerLogs writes stdout and stderr of the stopped container
+func ContainerLogs(ref string, stdout, stderr io.Writer) error {
+	return backend.ContainerLogs(ref, stdout, stderr)
+}
+
+// ContainerKill kills the running container, the killed container is kept
+func ContainerKill(ref string) error {
+	return backend.ContainerKill(ref)
+}
+
+func CliCreate(args ...string) error {
+	return backend.CliCreate(args...)
+}
+
+func CliRun(args ...string) error {
+	return backend.CliRun(args...)
+}
+
+func CliRm(args ...string) error {
+	return backend.CliRm(args...)
+}
+
+// CliCp copies files between the host and a running container
+func CliCp(args ...string) error {
+	return backend.CliCp(args...)
+}
+
+// CliExec runs command in a running container
+func CliExec(args ...string) error {
+	return backend.CliExec(args...)
+}
+
+func Images(options types.ImageListOptions) ([]types.ImageSummary, error) {
+	return backend.Images(options)
+}
+
+func ImageInspect(ref string) (*types.ImageInspect, error) {
+	return backend.ImageInspect(ref)
+}
+
+func CliPull(ctx context.Context, args ...string) error {
+	return backend.CliPull(ctx, args...)
+}
+
+func CliPush(ctx context.Context, args ...string) error {
+	return backend.CliPush(ctx, args...)
+}
+
+func CliTag(args ...string) error {
+	return backend.CliTag(args...)
+}
+
+func CliRmi(args ...string) error {
+	return backend.CliRmi(args...)
+}
+
+func CliSave(args ...string) error {
+	return backend.CliSave(args...)
+}
+
+func CliLoad(args ...string) error {
+	return backend.CliLoad(args...)
+}
+
+// CliManifestCreate creates local manifest list: first argument is a list name, the rest are platform images
+func CliManifestCreate(args ...string) error {
+	return backend.CliManifestCreate(args...)
+}
+
+func CliManifestPush(ctx context.Context, args ...string) error {
+	return backend.CliManifestPush(ctx, args...)
+}
@@ -0,0 +1,816 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	cliconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"golang.org/x/net/context"
+
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/util"
+)
+
+// buildahBackend is a daemonless container runtime, which uses buildah cli.
+// Buildah works without privileges inside Kubernetes runners: in rootless mode werf should be run with `buildah unshare`.
+// Registry credentials are read from the docker config, which is used by `werf login` and docker autologin.
+type buildahBackend struct {
+	authFile string
+
+	// runCancels stop buildah run commands of the containers, which are killed
+	runCancels      map[string]context.CancelFunc
+	runCancelsMutex sync.Mutex
+}
+
+// buildahObjectNotFoundError satisfies docker client.IsErrNotFound
+type buildahObjectNotFoundError struct {
+	ref string
+}
+
+func (e buildahObjectNotFoundError) Error() string {
+	return fmt.Sprintf("No such object: %s", e.ref)
+}
+
+func (e buildahObjectNotFoundError) NotFound() bool {
+	return true
+}
+
+func (b *buildahBackend) Name() string {
+	return BuildahContainerRuntime
+}
+
+func (b *buildahBackend) Init() error {
+	if _, err := exec.LookPath("buildah"); err != nil {
+		return fmt.Errorf("buildah container runtime is not available: %s", err)
+	}
+
+	b.authFile = filepath.Join(cliconfig.Dir(), cliconfig.ConfigFileName)
+
+	return nil
+}
+
+func (b *buildahBackend) ServerVersion() (*types.Version, error) {
+	output, err := b.output(context.Background(), "version", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(output, &version); err != nil {
+		return nil, fmt.Errorf("bad buildah version output: %s", err)
+	}
+
+	return &types.Version{Version: version.Version, Os: runtime.GOOS, Arch: runtime.GOARCH}, nil
+}
+
+type buildahContainer struct {
+	ID            string `json:"id"`
+	ImageID       string `json:"imageid"`
+	ImageName     string `json:"imagename"`
+	ContainerName string `json:"containername"`
+}
+
+func (b *buildahBackend) buildahContainers() ([]buildahContainer, error) {
+	output, err := b.output(context.Background(), "containers", "--all", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []buildahContainer
+	if len(bytes.TrimSpace(output)) == 0 {
+		return containers, nil
+	}
+
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("bad buildah containers output: %s", err)
+	}
+
+	return containers, nil
+}
+
+// Containers supports only name, id and ancestor filters
+func (b *buildahBackend) Containers(options types.ContainerListOptions) ([]types.Container, error) {
+	for _, key := range options.Filters.Keys() {
+		if key != "name" && key != "id" && key != "ancestor" {
+			return nil, fmt.Errorf("containers filter '%s' is not supported by buildah container runtime", key)
+		}
+	}
+
+	buildahContainers, err := b.buildahContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []types.Container
+	for _, c := range buildahContainers {
+		if !matchContainerFilter(options.Filters.Get("name"), func(value string) bool {
+			matched, err := regexp.MatchString(value, c.ContainerName)
+			return err == nil && matched
+		}) {
+			continue
+		}
+
+		if !matchContainerFilter(options.Filters.Get("id"), func(value string) bool {
+			return strings.HasPrefix(c.ID, value)
+		}) {
+			continue
+		}
+
+		if !matchContainerFilter(options.Filters.Get("ancestor"), func(value string) bool {
+			return value == c.ImageName || strings.TrimPrefix(value, "sha256:") == c.ImageID
+		}) {
+			continue
+		}
+
+		containers = append(containers, types.Container{
+			ID:      c.ID,
+			Names:   []string{"/" + c.ContainerName},
+			Image:   c.ImageName,
+			ImageID: "sha256:" + c.ImageID,
+		})
+	}
+
+	return containers, nil
+}
+
+func matchContainerFilter(values []string, match func(value string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *buildahBackend) ContainerExist(ref string) (bool, error) {
+	if _, err := b.ContainerInspect(ref); err != nil {
+		if _, ok := err.(buildahObjectNotFoundError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *buildahBackend) ContainerInspect(ref string) (types.ContainerJSON, error) {
+	containers, err := b.buildahContainers()
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+
+	for _, c := range containers {
+		if c.ContainerName == ref || c.ID == ref {
+			return types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{ID: c.ID, Name: "/" + c.ContainerName, Image: "sha256:" + c.ImageID},
+				Config:            &container.Config{Image: c.ImageName},
+			}, nil
+		}
+	}
+
+	return types.ContainerJSON{}, buildahObjectNotFoundError{ref: ref}
+}
+
+// ContainerCommit translates docker commit changes into buildah config options and commits container in docker format
+func (b *buildahBackend) ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (string, error) {
+	configArgs := []string{"config"}
+	for _, change := range commitOptions.Changes {
+		parts := strings.SplitN(change, " ", 2)
+		if len(parts) != 2 {
+			return "", fmt.Errorf("bad commit change '%s'", change)
+		}
+
+		var option string
+		switch strings.ToUpper(parts[0]) {
+		case "VOLUME":
+			option = "--volume"
+		case "EXPOSE":
+			option = "--port"
+		case "ENV":
+			option = "--env"
+		case "LABEL":
+			option = "--label"
+		case "CMD":
+			option = "--cmd"
+		case "ENTRYPOINT":
+			option = "--entrypoint"
+		case "ONBUILD":
+			option = "--onbuild"
+		case "WORKDIR":
+			option = "--workingdir"
+		case "USER":
+			option = "--user"
+		case "STOPSIGNAL":
+			option = "--stop-signal"
+		case "HEALTHCHECK":
+			option = "--healthcheck"
+		default:
+			return "", fmt.Errorf("commit change '%s' is not supported by buildah container runtime", change)
+		}
+
+		configArgs = append(configArgs, option, parts[1])
+	}
+
+	if len(configArgs) > 1 {
+		configArgs = append(configArgs, ref)
+		if err := b.run(context.Background(), configArgs...); err != nil {
+			return "", err
+		}
+	}
+
+	output, err := b.output(context.Background(), "commit", "--quiet", "--format", "docker", ref)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + strings.TrimPrefix(strings.TrimSpace(string(output)), "sha256:"), nil
+}
+
+func (b *buildahBackend) ContainerRemove(ref string, _ types.ContainerRemoveOptions) error {
+	return b.run(context.Background(), "rm", ref)
+}
+
+// ContainerKill stops buildah run command of the container
+func (b *buildahBackend) ContainerKill(ref string) error {
+	b.runCancelsMutex.Lock()
+	cancel, ok := b.runCancels[ref]
+	b.runCancelsMutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("container %s is not running", ref)
+	}
+
+	cancel()
+
+	return nil
+}
+
+func (b *buildahBackend) setRunCancel(name string, cancel context.CancelFunc) {
+	b.runCancelsMutex.Lock()
+	defer b.runCancelsMutex.Unlock()
+
+	if b.runCancels == nil {
+		b.runCancels = map[string]context.CancelFunc{}
+	}
+
+	if cancel == nil {
+		delete(b.runCancels, name)
+	} else {
+		b.runCancels[name] = cancel
+	}
+}
+
+// ContainerLogs is not supported: buildah does not keep output of the run command
+func (b *buildahBackend) ContainerLogs(ref string, _, _ io.Writer) error {
+	return fmt.Errorf("logs of container %s are not available: container logs are not supported by buildah container runtime", ref)
+}
+
+type buildahRunArgs struct {
+	Name        string
+	Platform    string
+	Rm          bool
+	Interactive bool
+	Volumes     []string
+	VolumesFrom []string
+	Tmpfs       []string
+	Envs        []string
+	User        string
+	Workdir     string
+	Entrypoint  string
+	Image       string
+	Command     []string
+}
+
+// parseRunArgs parses docker run and docker create arguments, which are used by werf
+func parseRunArgs(args []string) (*buildahRunArgs, error) {
+	runArgs := &buildahRunArgs{}
+
+	for ind := 0; ind < len(args); ind++ {
+		arg := args[ind]
+
+		if !strings.HasPrefix(arg, "-") {
+			runArgs.Image = arg
+			runArgs.Command = args[ind+1:]
+			return runArgs, nil
+		}
+
+		switch arg {
+		case "--rm":
+			runArgs.Rm = true
+			continue
+		case "-ti", "-it", "-t", "-i":
+			runArgs.Interactive = true
+			continue
+		}
+
+		var name, value string
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			name, value = parts[0], parts[1]
+		} else if ind+1 < len(args) {
+			name, value = arg, args[ind+1]
+			ind++
+		} else {
+			return nil, fmt.Errorf("option %s requires value", arg)
+		}
+
+		switch name {
+		case "--name":
+			runArgs.Name = value
+		case "--platform":
+			runArgs.Platform = value
+		case "--volume", "-v":
+			runArgs.Volumes = append(runArgs.Volumes, value)
+		case "--volumes-from":
+			runArgs.VolumesFrom = append(runArgs.VolumesFrom, value)
+		case "--tmpfs":
+			runArgs.Tmpfs = append(runArgs.Tmpfs, value)
+		case "--env", "-e":
+			runArgs.Envs = append(runArgs.Envs, value)
+		case "--label", "-l":
+			// container labels are not used with buildah container runtime
+		case "--user", "-u":
+			runArgs.User = value
+		case "--workdir", "-w":
+			runArgs.Workdir = value
+		case "--entrypoint":
+			runArgs.Entrypoint = value
+		default:
+			return nil, fmt.Errorf("option %s is not supported by buildah container runtime", name)
+		}
+	}
+
+	return nil, fmt.Errorf("image is not specified")
+}
+
+func (b *buildahBackend) fromArgs(runArgs *buildahRunArgs, name string) []string {
+	args := append([]string{"from", "--quiet", "--name", name}, b.authArgs()...)
+	args = append(args, tlsArgs(runArgs.Image)...)
+	if runArgs.Platform != "" {
+		args = append(args, "--platform", runArgs.Platform)
+	}
+	return append(args, runArgs.Image)
+}
+
+// CliCreate creates working container, volumes are saved in the container config to be used with --volumes-from
+func (b *buildahBackend) CliCreate(args ...string) error {
+	runArgs, err := parseRunArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if runArgs.Name == "" {
+		return fmt.Errorf("container name is required by buildah container runtime")
+	}
+
+	if _, err := b.output(context.Background(), b.fromArgs(runArgs, runArgs.Name)...); err != nil {
+		return err
+	}
+
+	if len(runArgs.Volumes) > 0 {
+		configArgs := []string{"config"}
+		for _, volume := range runArgs.Volumes {
+			configArgs = append(configArgs, "--volume", volume)
+		}
+		configArgs = append(configArgs, runArgs.Name)
+
+		if err := b.run(context.Background(), configArgs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CliRun creates working container from the image and runs command in it.
+// Volumes of --volumes-from containers are mounted from the containers root file systems.
+func (b *buildahBackend) CliRun(args ...string) error {
+	runArgs, err := parseRunArgs(args)
+	if err != nil {
+		return err
+	}
+
+	name := runArgs.Name
+	if name == "" {
+		name = fmt.Sprintf("werf.run.%s", util.GenerateConsistentRandomString(10))
+	}
+
+	if _, err := b.output(context.Background(), b.fromArgs(runArgs, name)...); err != nil {
+		return err
+	}
+
+	if runArgs.Rm {
+		defer func() {
+			if err := b.run(context.Background(), "rm", name); err != nil && Debug() {
+				fmt.Printf("Buildah container %s removal failed: %s\n", name, err)
+			}
+		}()
+	}
+
+	buildahArgs := []string{"run"}
+	if runArgs.Interactive {
+		buildahArgs = append(buildahArgs, "--tty")
+	}
+	if runArgs.User != "" {
+		buildahArgs = append(buildahArgs, "--user", runArgs.User)
+	}
+	if runArgs.Workdir != "" {
+		buildahArgs = append(buildahArgs, "--workingdir", runArgs.Workdir)
+	}
+	for _, env := range runArgs.Envs {
+		buildahArgs = append(buildahArgs, "--env", env)
+	}
+	for _, tmpfs := range runArgs.Tmpfs {
+		buildahArgs = append(buildahArgs, "--mount", fmt.Sprintf("type=tmpfs,destination=%s", tmpfs))
+	}
+	for _, volume := range runArgs.Volumes {
+		buildahArgs = append(buildahArgs, "--volume", volume)
+	}
+
+	for _, volumesFrom := range runArgs.VolumesFrom {
+		volumes, err := b.containerVolumes(volumesFrom)
+		if err != nil {
+			return err
+		}
+
+		for _, volume := range volumes {
+			buildahArgs = append(buildahArgs, "--volume", volume)
+		}
+	}
+
+	buildahArgs = append(buildahArgs, name, "--")
+	if runArgs.Entrypoint != "" {
+		buildahArgs = append(buildahArgs, runArgs.Entrypoint)
+	}
+	buildahArgs = append(buildahArgs, runArgs.Command...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.setRunCancel(name, cancel)
+	defer b.setRunCancel(name, nil)
+
+	return b.run(ctx, buildahArgs...)
+}
+
+// containerVolumes returns bind mounts of the container volumes
+func (b *buildahBackend) containerVolumes(ref string) ([]string, error) {
+	output, err := b.output(context.Background(), "inspect", "--type", "container", "--format", "{{range $volume, $_ := .OCIv1.Config.Volumes}}{{$volume}}\n{{end}}", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	mountOutput, err := b.output(context.Background(), "mount", ref)
+	if err != nil {
+		return nil, err
+	}
+	mountPoint := strings.TrimSpace(string(mountOutput))
+
+	var volumes []string
+	for _, volume := range strings.Split(string(output), "\n") {
+		volume = strings.TrimSpace(volume)
+		if volume == "" {
+			continue
+		}
+
+		volumes = append(volumes, fmt.Sprintf("%s:%s", filepath.Join(mountPoint, volume), volume))
+	}
+
+	return volumes, nil
+}
+
+func (b *buildahBackend) CliRm(args ...string) error {
+	var refs []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			refs = append(refs, arg)
+		}
+	}
+
+	return b.run(context.Background(), append([]string{"rm"}, refs...)...)
+}
+
+// CliCp is not supported, because buildah does not run long-living containers
+func (b *buildahBackend) CliCp(args ...string) error {
+	return fmt.Errorf("copying files into running containers is not supported by %s container runtime", BuildahContainerRuntime)
+}
+
+// CliExec is not supported, because buildah does not run long-living containers
+func (b *buildahBackend) CliExec(args ...string) error {
+	return fmt.Errorf("running commands in running containers is not supported by %s container runtime", BuildahContainerRuntime)
+}
+
+type buildahImage struct {
+	ID      string   `json:"id"`
+	Names   []string `json:"names"`
+	Created int64    `json:"created"`
+}
+
+func (b *buildahBackend) Images(options types.ImageListOptions) ([]types.ImageSummary, error) {
+	args := []string{"images", "--json"}
+	if options.All {
+		args = append(args, "--all")
+	}
+	for _, key := range options.Filters.Keys() {
+		for _, value := range options.Filters.Get(key) {
+			args = append(args, "--filter", fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	output, err := b.output(context.Background(), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buildahImages []buildahImage
+	if len(bytes.TrimSpace(output)) != 0 {
+		if err := json.Unmarshal(output, &buildahImages); err != nil {
+			return nil, fmt.Errorf("bad buildah images output: %s", err)
+		}
+	}
+
+	var images []types.ImageSummary
+	for _, img := range buildahImages {
+		images = append(images, types.ImageSummary{
+			ID:       "sha256:" + img.ID,
+			RepoTags: img.Names,
+			Created:  img.Created,
+		})
+	}
+
+	return images, nil
+}
+
+type buildahImageInspect struct {
+	FromImageID     string `json:"FromImageID"`
+	FromImageDigest string `json:"FromImageDigest"`
+	OCIv1           struct {
+		Created      *time.Time `json:"created"`
+		Architecture string     `json:"architecture"`
+		OS           string     `json:"os"`
+		Config       struct {
+			User       string              `json:"User"`
+			Env        []string            `json:"Env"`
+			Entrypoint []string            `json:"Entrypoint"`
+			Cmd        []string            `json:"Cmd"`
+			Volumes    map[string]struct{} `json:"Volumes"`
+			WorkingDir string              `json:"WorkingDir"`
+			Labels     map[string]string   `json:"Labels"`
+			StopSignal string              `json:"StopSignal"`
+		} `json:"config"`
+	} `json:"OCIv1"`
+}
+
+func (b *buildahBackend) ImageInspect(ref string) (*types.ImageInspect, error) {
+	output, err := b.output(context.Background(), "inspect", "--type", "image", ref)
+	if err != nil {
+		if isBuildahNotFoundError(err) {
+			return nil, buildahObjectNotFoundError{ref: ref}
+		}
+		return nil, err
+	}
+
+	var inspect buildahImageInspect
+	if err := json.Unmarshal(output, &inspect); err != nil {
+		return nil, fmt.Errorf("bad buildah inspect output: %s", err)
+	}
+
+	var created string
+	if inspect.OCIv1.Created != nil {
+		created = inspect.OCIv1.Created.Format(time.RFC3339Nano)
+	}
+
+	// buildah stores only the digest the image has been pulled by, report it as docker does
+	var repoDigests []string
+	if inspect.FromImageDigest != "" && !strings.HasPrefix(ref, "sha256:") {
+		repo := ref
+		if ind := strings.Index(repo, "@"); ind != -1 {
+			repo = repo[:ind]
+		} else if ind := strings.LastIndex(repo, ":"); ind != -1 && !strings.Contains(repo[ind:], "/") {
+			repo = repo[:ind]
+		}
+		repoDigests = append(repoDigests, fmt.Sprintf("%s@%s", repo, inspect.FromImageDigest))
+	}
+
+	return &types.ImageInspect{
+		ID:           "sha256:" + inspect.FromImageID,
+		RepoDigests:  repoDigests,
+		Created:      created,
+		Os:           inspect.OCIv1.OS,
+		Architecture: inspect.OCIv1.Architecture,
+		Config: &container.Config{
+			User:       inspect.OCIv1.Config.User,
+			Env:        inspect.OCIv1.Config.Env,
+			Entrypoint: strslice.StrSlice(inspect.OCIv1.Config.Entrypoint),
+			Cmd:        strslice.StrSlice(inspect.OCIv1.Config.Cmd),
+			Volumes:    inspect.OCIv1.Config.Volumes,
+			WorkingDir: inspect.OCIv1.Config.WorkingDir,
+			Labels:     inspect.OCIv1.Config.Labels,
+			StopSignal: inspect.OCIv1.Config.StopSignal,
+		},
+	}, nil
+}
+
+func isBuildahNotFoundError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not known") || strings.Contains(msg, "not found") || strings.Contains(msg, "no such")
+}
+
+func (b *buildahBackend) CliPull(ctx context.Context, args ...string) error {
+	buildahArgs := append([]string{"pull"}, b.authArgs()...)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--platform=") {
+			buildahArgs = append(buildahArgs, "--platform", strings.TrimPrefix(arg, "--platform="))
+		} else if strings.HasPrefix(arg, "-") {
+			buildahArgs = append(buildahArgs, arg)
+		} else {
+			buildahArgs = append(buildahArgs, tlsArgs(arg)...)
+			buildahArgs = append(buildahArgs, arg)
+		}
+	}
+
+	return b.run(ctx, buildahArgs...)
+}
+
+func (b *buildahBackend) CliPush(ctx context.Context, args ...string) error {
+	for _, name := range args {
+		pushArgs := append([]string{"push", "--format", "v2s2"}, b.authArgs()...)
+		pushArgs = append(pushArgs, tlsArgs(name)...)
+		if err := b.run(ctx, append(pushArgs, name, fmt.Sprintf("docker://%s", name))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *buildahBackend) CliTag(args ...string) error {
+	return b.run(context.Background(), append([]string{"tag"}, args...)...)
+}
+
+func (b *buildahBackend) CliRmi(args ...string) error {
+	return b.run(context.Background(), append([]string{"rmi"}, args...)...)
+}
+
+// CliSave supports only `--output FILE IMAGE` arguments, image is saved in docker-archive format
+func (b *buildahBackend) CliSave(args ...string) error {
+	var output, name string
+	for ind := 0; ind < len(args); ind++ {
+		switch {
+		case args[ind] == "-o" || args[ind] == "--output":
+			if ind+1 < len(args) {
+				output = args[ind+1]
+				ind++
+			}
+		case strings.HasPrefix(args[ind], "--output="):
+			output = strings.TrimPrefix(args[ind], "--output=")
+		default:
+			name = args[ind]
+		}
+	}
+
+	if output == "" || name == "" {
+		return fmt.Errorf("buildah container runtime requires output file and image for save")
+	}
+
+	return b.run(context.Background(), "push", name, fmt.Sprintf("docker-archive:%s:%s", output, name))
+}
+
+// CliLoad supports only `--input FILE` arguments
+func (b *buildahBackend) CliLoad(args ...string) error {
+	var input string
+	for ind := 0; ind < len(args); ind++ {
+		switch {
+		case args[ind] == "-i" || args[ind] == "--input":
+			if ind+1 < len(args) {
+				input = args[ind+1]
+				ind++
+			}
+		case strings.HasPrefix(args[ind], "--input="):
+			input = strings.TrimPrefix(args[ind], "--input=")
+		}
+	}
+
+	if input == "" {
+		return fmt.Errorf("buildah container runtime requires input file for load")
+	}
+
+	return b.run(context.Background(), "pull", fmt.Sprintf("docker-archive:%s", input))
+}
+
+func (b *buildahBackend) CliManifestCreate(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("manifest list name is required")
+	}
+
+	listName := args[0]
+	if err := b.run(context.Background(), "manifest", "create", listName); err != nil {
+		return err
+	}
+
+	for _, imageName := range args[1:] {
+		addArgs := append([]string{"manifest", "add"}, b.authArgs()...)
+		addArgs = append(addArgs, tlsArgs(imageName)...)
+		if err := b.run(context.Background(), append(addArgs, listName, fmt.Sprintf("docker://%s", imageName))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *buildahBackend) CliManifestPush(ctx context.Context, args ...string) error {
+	buildahArgs := append([]string{"manifest", "push", "--all"}, b.authArgs()...)
+
+	var listName string
+	for _, arg := range args {
+		if arg == "--purge" || arg == "-p" {
+			buildahArgs = append(buildahArgs, "--rm")
+		} else {
+			listName = arg
+		}
+	}
+
+	buildahArgs = append(buildahArgs, tlsArgs(listName)...)
+
+	return b.run(ctx, append(buildahArgs, listName, fmt.Sprintf("docker://%s", listName))...)
+}
+
+// authArgs passes docker config to buildah, buildah fails if specified auth file does not exist
+func (b *buildahBackend) authArgs() []string {
+	if _, err := os.Stat(b.authFile); err != nil {
+		return nil
+	}
+	return []string{"--authfile", b.authFile}
+}
+
+// tlsArgs disables tls verification for insecure registries and registries with skipped verification,
+// CA files of the registries should be configured in the buildah certs dir (/etc/containers/certs.d)
+func tlsArgs(reference string) []string {
+	options := docker_registry.GetRegistryOptions(docker_registry.RegistryOfReference(reference))
+	if options.Insecure || options.SkipTLSVerify {
+		return []string{"--tls-verify=false"}
+	}
+	return nil
+}
+
+// run executes buildah command with output to the terminal
+func (b *buildahBackend) run(ctx context.Context, args ...string) error {
+	cmd := b.command(ctx, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("buildah %s failed: %s", args[0], err)
+	}
+
+	return nil
+}
+
+// output executes buildah command and returns its stdout
+func (b *buildahBackend) output(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := b.command(ctx, args...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("buildah %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+func (b *buildahBackend) command(ctx context.Context, args ...string) *exec.Cmd {
+	if Debug() {
+		fmt.Printf("# buildah %s\n", strings.Join(args, " "))
+	}
+
+	return exec.CommandContext(ctx, "buildah", args...)
+}
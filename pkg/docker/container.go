@@ -1,19 +1,22 @@
 package docker
 
 import (
+	"io"
+
 	"github.com/docker/cli/cli/command/container"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"golang.org/x/net/context"
 )
 
-func Containers(options types.ContainerListOptions) ([]types.Container, error) {
+func (b *dockerBackend) Containers(options types.ContainerListOptions) ([]types.Container, error) {
 	ctx := context.Background()
 	return apiClient.ContainerList(ctx, options)
 }
 
-func ContainerExist(ref string) (bool, error) {
-	if _, err := ContainerInspect(ref); err != nil {
+func (b *dockerBackend) ContainerExist(ref string) (bool, error) {
+	if _, err := b.ContainerInspect(ref); err != nil {
 		if client.IsErrNotFound(err) {
 			return false, nil
 		}
@@ -22,12 +25,12 @@ func ContainerExist(ref string) (bool, error) {
 	return true, nil
 }
 
-func ContainerInspect(ref string) (types.ContainerJSON, error) {
+func (b *dockerBackend) ContainerInspect(ref string) (types.ContainerJSON, error) {
 	ctx := context.Background()
 	return apiClient.ContainerInspect(ctx, ref)
 }
 
-func ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (string, error) {
+func (b *dockerBackend) ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (string, error) {
 	ctx := context.Background()
 	response, err := apiClient.ContainerCommit(ctx, ref, commitOptions)
 	if err != nil {
@@ -37,7 +40,7 @@ func ContainerCommit(ref string, commitOptions types.ContainerCommitOptions) (st
 	return response.ID, nil
 }
 
-func ContainerRemove(ref string, options types.ContainerRemoveOptions) error {
+func (b *dockerBackend) ContainerRemove(ref string, options types.ContainerRemoveOptions) error {
 	ctx := context.Background()
 	err := apiClient.ContainerRemove(ctx, ref, options)
 	if err != nil {
@@ -47,7 +50,25 @@ func ContainerRemove(ref string, options types.ContainerRemoveOptions) error {
 	return nil
 }
 
-func CliCreate(args ...string) error {
+func (b *dockerBackend) ContainerLogs(ref string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	logs, err := apiClient.ContainerLogs(ctx, ref, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	// containers are run without tty, so stdout and stderr are multiplexed
+	_, err = stdcopy.StdCopy(stdout, stderr, logs)
+	return err
+}
+
+func (b *dockerBackend) ContainerKill(ref string) error {
+	ctx := context.Background()
+	return apiClient.ContainerKill(ctx, ref, "KILL")
+}
+
+func (b *dockerBackend) CliCreate(args ...string) error {
 	cmd := container.NewCreateCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -61,7 +82,7 @@ func CliCreate(args ...string) error {
 	return nil
 }
 
-func CliRun(args ...string) error {
+func (b *dockerBackend) CliRun(args ...string) error {
 	cmd := container.NewRunCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -75,7 +96,7 @@ func CliRun(args ...string) error {
 	return nil
 }
 
-func CliRm(args ...string) error {
+func (b *dockerBackend) CliRm(args ...string) error {
 	cmd := container.NewRmCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -88,3 +109,31 @@ func CliRm(args ...string) error {
 
 	return nil
 }
+
+func (b *dockerBackend) CliCp(args ...string) error {
+	cmd := container.NewCopyCommand(cli)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *dockerBackend) CliExec(args ...string) error {
+	cmd := container.NewExecCommand(cli)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
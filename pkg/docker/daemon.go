@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/flant/werf/pkg/logger"
+)
+
+const (
+	daemonReconnectTimeout  = 5 * time.Minute
+	daemonReconnectMinDelay = time.Second
+	daemonReconnectMaxDelay = 30 * time.Second
+)
+
+// daemonConnectionErrorMessages are parts of docker client and docker cli errors, which are returned when docker daemon socket is not available.
+// Generic network errors (connection refused, broken pipe, EOF) are not listed: registries and git remotes return them too.
+var daemonConnectionErrorMessages = []string{
+	"Cannot connect to the Docker daemon",
+	"Is the docker daemon running?",
+	"docker.sock: connect:",
+}
+
+// IsDaemonConnectionError reports whether the error has been caused by the lost connection to docker daemon.
+// Errors are usually wrapped by callers, so the error message is checked for docker socket errors,
+// other errors are attributed to the daemon only if the daemon does not respond.
+func IsDaemonConnectionError(err error) bool {
+	if err == nil || backend.Name() != DockerContainerRuntime {
+		return false
+	}
+
+	msg := err.Error()
+	for _, part := range daemonConnectionErrorMessages {
+		if strings.Contains(msg, part) {
+			return true
+		}
+	}
+
+	return !isDaemonAlive()
+}
+
+func isDaemonAlive() bool {
+	_, err := backend.ServerVersion()
+	return err == nil
+}
+
+// WaitForDaemon waits until docker daemon responds to requests again, checks are retried with exponential backoff
+func WaitForDaemon(ctx context.Context) error {
+	deadline := time.Now().Add(daemonReconnectTimeout)
+	delay := daemonReconnectMinDelay
+
+	for {
+		_, err := backend.ServerVersion()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("docker daemon is not available for %s: %s", daemonReconnectTimeout, err)
+		}
+
+		logger.LogWarningF("WARNING: docker daemon is not available (%s), reconnecting in %s ...\n", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		delay *= 2
+		if delay > daemonReconnectMaxDelay {
+			delay = daemonReconnectMaxDelay
+		}
+	}
+}
@@ -2,11 +2,16 @@ package docker
 
 import (
 	"github.com/docker/cli/cli/command/image"
+	"github.com/docker/cli/cli/command/manifest"
 	"github.com/docker/docker/api/types"
+	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/telemetry"
 )
 
-func Images(options types.ImageListOptions) ([]types.ImageSummary, error) {
+func (b *dockerBackend) Images(options types.ImageListOptions) ([]types.ImageSummary, error) {
 	ctx := context.Background()
 	images, err := apiClient.ImageList(ctx, options)
 	if err != nil {
@@ -16,7 +21,7 @@ func Images(options types.ImageListOptions) ([]types.ImageSummary, error) {
 	return images, nil
 }
 
-func ImageInspect(ref string) (*types.ImageInspect, error) {
+func (b *dockerBackend) ImageInspect(ref string) (*types.ImageInspect, error) {
 	ctx := context.Background()
 	inspect, _, err := apiClient.ImageInspectWithRaw(ctx, ref)
 	if err != nil {
@@ -26,13 +31,18 @@ func ImageInspect(ref string) (*types.ImageInspect, error) {
 	return &inspect, nil
 }
 
-func CliPull(args ...string) error {
+// CliPull uses docker cli pull command, image is pulled with docker api to count pulled bytes if telemetry is enabled
+func (b *dockerBackend) CliPull(ctx context.Context, args ...string) error {
+	if telemetry.IsEnabled() {
+		return pullWithProgress(ctx, args...)
+	}
+
 	cmd := image.NewPullCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
 	cmd.SetArgs(args)
 
-	err := cmd.Execute()
+	err := executeWithContext(ctx, cmd)
 	if err != nil {
 		return err
 	}
@@ -40,13 +50,24 @@ func CliPull(args ...string) error {
 	return nil
 }
 
-func CliPush(args ...string) error {
+// CliPush renders push progress with werf logger, docker cli push command is used only if progress and telemetry are disabled
+func (b *dockerBackend) CliPush(ctx context.Context, args ...string) error {
+	if logger.GetProgressMode() != logger.ProgressNone || telemetry.IsEnabled() {
+		for _, ref := range args {
+			if err := pushWithProgress(ctx, ref); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	cmd := image.NewPushCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
 	cmd.SetArgs(args)
 
-	err := cmd.Execute()
+	err := executeWithContext(ctx, cmd)
 	if err != nil {
 		return err
 	}
@@ -54,7 +75,42 @@ func CliPush(args ...string) error {
 	return nil
 }
 
-func CliTag(args ...string) error {
+// CliManifestCreate creates local manifest list: first argument is a list name, the rest are platform images
+func (b *dockerBackend) CliManifestCreate(args ...string) error {
+	cmd := manifest.NewManifestCommand(cli)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.SetArgs(append([]string{"create"}, args...))
+
+	return cmd.Execute()
+}
+
+func (b *dockerBackend) CliManifestPush(ctx context.Context, args ...string) error {
+	cmd := manifest.NewManifestCommand(cli)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.SetArgs(append([]string{"push"}, args...))
+
+	return executeWithContext(ctx, cmd)
+}
+
+// executeWithContext returns as soon as ctx is done,
+// docker cli command keeps running in background until process exit.
+func executeWithContext(ctx context.Context, cmd *cobra.Command) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmd.Execute()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *dockerBackend) CliTag(args ...string) error {
 	cmd := image.NewTagCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -68,7 +124,7 @@ func CliTag(args ...string) error {
 	return nil
 }
 
-func CliRmi(args ...string) error {
+func (b *dockerBackend) CliRmi(args ...string) error {
 	cmd := image.NewRemoveCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -82,7 +138,7 @@ func CliRmi(args ...string) error {
 	return nil
 }
 
-func CliSave(args ...string) error {
+func (b *dockerBackend) CliSave(args ...string) error {
 	cmd := image.NewSaveCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -96,7 +152,7 @@ func CliSave(args ...string) error {
 	return nil
 }
 
-func CliLoad(args ...string) error {
+func (b *dockerBackend) CliLoad(args ...string) error {
 	cmd := image.NewLoadCommand(cli)
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
@@ -22,6 +22,22 @@ func Init(dockerConfigDir string) error {
 		cliconfig.SetDir(dockerConfigDir)
 	}
 
+	return backend.Init()
+}
+
+// GetConfigDir returns docker config dir, which contains registries credentials used by werf
+func GetConfigDir() string {
+	return cliconfig.Dir()
+}
+
+// dockerBackend is the default container runtime, which uses Docker daemon
+type dockerBackend struct{}
+
+func (b *dockerBackend) Name() string {
+	return DockerContainerRuntime
+}
+
+func (b *dockerBackend) Init() error {
 	if err := setDockerClient(); err != nil {
 		return err
 	}
@@ -33,7 +49,7 @@ func Init(dockerConfigDir string) error {
 	return nil
 }
 
-func ServerVersion() (*types.Version, error) {
+func (b *dockerBackend) ServerVersion() (*types.Version, error) {
 	ctx := context.Background()
 	version, err := apiClient.ServerVersion(ctx)
 	if err != nil {
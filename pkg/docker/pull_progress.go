@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"golang.org/x/net/context"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/telemetry"
+)
+
+// pullWithProgress pulls images with docker api and decodes pull messages into the single bytes progress of all layers.
+// Args are the same as for the docker cli pull command: image references and optional --platform=PLATFORM.
+// Size of the downloaded layers is reported into the telemetry.
+func pullWithProgress(ctx context.Context, args ...string) error {
+	var platform string
+	var refs []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--platform=") {
+			platform = strings.TrimPrefix(arg, "--platform=")
+		} else {
+			refs = append(refs, arg)
+		}
+	}
+
+	for _, ref := range refs {
+		if err := pullRefWithProgress(ctx, ref, platform); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pullRefWithProgress(ctx context.Context, ref, platform string) error {
+	encodedAuth, err := command.RetrieveAuthTokenFromImage(ctx, cli, ref)
+	if err != nil {
+		return err
+	}
+
+	responseBody, err := cli.Client().ImagePull(ctx, ref, types.ImagePullOptions{RegistryAuth: encodedAuth, Platform: platform})
+	if err != nil {
+		return err
+	}
+	defer responseBody.Close()
+
+	progress := logger.NewBytesProgress(fmt.Sprintf("Pull %s", ref), 0)
+	defer progress.Done()
+
+	layers := map[string]*jsonmessage.JSONProgress{}
+	defer func() {
+		var pulled int64
+		for _, layer := range layers {
+			pulled += layer.Current
+		}
+		telemetry.AddPulledBytes(pulled)
+	}()
+
+	decoder := json.NewDecoder(responseBody)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if msg.ID == "" {
+			continue
+		}
+
+		switch {
+		case msg.Status == "Downloading" && msg.Progress != nil && msg.Progress.Total > 0:
+			layers[msg.ID] = msg.Progress
+		case msg.Status == "Download complete":
+			if layer, ok := layers[msg.ID]; ok {
+				layer.Current = layer.Total
+			}
+		default:
+			continue
+		}
+
+		var current, total int64
+		for _, layer := range layers {
+			current += layer.Current
+			total += layer.Total
+		}
+
+		progress.SetTotal(total)
+		progress.Set(current)
+	}
+
+	return nil
+}
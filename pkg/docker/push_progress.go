@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"golang.org/x/net/context"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/telemetry"
+)
+
+// pushWithProgress pushes image with docker api and decodes push messages into the single bytes progress of all layers.
+// Size of the pushed layers is reported into the telemetry.
+func pushWithProgress(ctx context.Context, ref string) error {
+	encodedAuth, err := command.RetrieveAuthTokenFromImage(ctx, cli, ref)
+	if err != nil {
+		return err
+	}
+
+	responseBody, err := cli.Client().ImagePush(ctx, ref, types.ImagePushOptions{RegistryAuth: encodedAuth})
+	if err != nil {
+		return err
+	}
+	defer responseBody.Close()
+
+	progress := logger.NewBytesProgress(fmt.Sprintf("Push %s", ref), 0)
+	defer progress.Done()
+
+	layers := map[string]*jsonmessage.JSONProgress{}
+	defer func() {
+		var pushed int64
+		for _, layer := range layers {
+			pushed += layer.Current
+		}
+		telemetry.AddPushedBytes(pushed)
+	}()
+
+	decoder := json.NewDecoder(responseBody)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if msg.ID == "" {
+			continue
+		}
+
+		switch {
+		case msg.Progress != nil && msg.Progress.Total > 0:
+			layers[msg.ID] = msg.Progress
+		case msg.Status == "Pushed" || msg.Status == "Layer already exists":
+			if layer, ok := layers[msg.ID]; ok {
+				layer.Current = layer.Total
+			}
+		default:
+			continue
+		}
+
+		var current, total int64
+		for _, layer := range layers {
+			current += layer.Current
+			total += layer.Total
+		}
+
+		progress.SetTotal(total)
+		progress.Set(current)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/flant/werf/pkg/ci_env"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/docker_registry"
 )
@@ -84,6 +85,25 @@ func GetBPDockerAuthorizer(projectTmpDir, pullUsernameOption, pullPasswordOption
 	return getDockerAuthorizer(projectTmpDir, nil, pullCredentials, pushCredentials)
 }
 
+func GetStagesPullDockerAuthorizer(projectTmpDir, pullUsernameOption, pullPasswordOption, repo string) (*DockerAuthorizer, error) {
+	pullCredentials, err := getDefaultCredentials(pullUsernameOption, pullPasswordOption, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get docker credentials for pull: %s", err)
+	}
+
+	return getDockerAuthorizer(projectTmpDir, nil, pullCredentials, nil)
+}
+
+// GetImagesPullDockerAuthorizer authorizes reading of the published images of the repo
+func GetImagesPullDockerAuthorizer(projectTmpDir, pullUsernameOption, pullPasswordOption, repo string) (*DockerAuthorizer, error) {
+	pullCredentials, err := getDefaultCredentials(pullUsernameOption, pullPasswordOption, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get docker credentials for pull: %s", err)
+	}
+
+	return getDockerAuthorizer(projectTmpDir, nil, pullCredentials, nil)
+}
+
 func GetFlushDockerAuthorizer(projectTmpDir, flushUsernameOption, flushPasswordOption string) (*DockerAuthorizer, error) {
 	credentials, err := getFlushCredentials(flushUsernameOption, flushPasswordOption)
 	if err != nil {
@@ -162,7 +182,8 @@ func getPullCredentials(pullUsernameOption, pullPasswordOption string) (*DockerC
 		return creds, nil
 	}
 
-	return getDefaultAutologinCredentials()
+	// base images are pulled with login only from the CI system registry
+	return getDefaultAutologinCredentials(ci_env.GetRegistry())
 }
 
 func getPushCredentials(usernameOption, passwordOption, repo string) (*DockerCredentials, error) {
@@ -200,7 +221,7 @@ func getCleanupCredentials(usernameOption, passwordOption, repo string) (*Docker
 		return nil, nil
 	}
 
-	return getDefaultAutologinCredentials()
+	return getDefaultAutologinCredentials(repo)
 }
 
 func getDefaultCredentials(usernameOption, passwordOption, repo string) (*DockerCredentials, error) {
@@ -217,7 +238,7 @@ func getDefaultCredentials(usernameOption, passwordOption, repo string) (*Docker
 		return nil, nil
 	}
 
-	return getDefaultAutologinCredentials()
+	return getDefaultAutologinCredentials(repo)
 }
 
 func getSpecifiedCredentials(usernameOption, passwordOption string) *DockerCredentials {
@@ -228,13 +249,9 @@ func getSpecifiedCredentials(usernameOption, passwordOption string) *DockerCrede
 	return nil
 }
 
-func getDefaultAutologinCredentials() (*DockerCredentials, error) {
-	if os.Getenv("WERF_IGNORE_CI_DOCKER_AUTOLOGIN") == "" {
-		ciRegistryEnv := os.Getenv("CI_REGISTRY")
-		ciJobTokenEnv := os.Getenv("CI_JOB_TOKEN")
-		if ciRegistryEnv != "" && ciJobTokenEnv != "" {
-			return &DockerCredentials{Username: "gitlab-ci-token", Password: ciJobTokenEnv}, nil
-		}
+func getDefaultAutologinCredentials(repo string) (*DockerCredentials, error) {
+	if creds := ci_env.GetRegistryCredentials(repo); creds != nil {
+		return &DockerCredentials{Username: creds.Username, Password: creds.Password}, nil
 	}
 
 	return nil, nil
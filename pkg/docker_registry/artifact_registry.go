@@ -0,0 +1,213 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+var artifactRegistryRegexp = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev$`)
+
+const (
+	artifactRegistryApiUrl = "https://artifactregistry.googleapis.com/v1"
+	gceMetadataTokenUrl    = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	artifactRegistryOperationTimeout = time.Minute
+)
+
+// artifactRegistryRepoProvider creates docker format repositories of GCP Artifact Registry:
+// image LOCATION-docker.pkg.dev/PROJECT/REPOSITORY/IMAGE can be pushed only into existing REPOSITORY
+type artifactRegistryRepoProvider struct {
+	Location string
+}
+
+func newArtifactRegistryRepoProvider(registry string) *artifactRegistryRepoProvider {
+	match := artifactRegistryRegexp.FindStringSubmatch(registry)
+	if match == nil {
+		return nil
+	}
+
+	return &artifactRegistryRepoProvider{Location: match[1]}
+}
+
+func (p *artifactRegistryRepoProvider) Name() string {
+	return "GCP Artifact Registry"
+}
+
+// CreateRepo creates REPOSITORY, lifecycle policy is a JSON object of the repository cleanup policies
+func (p *artifactRegistryRepoProvider) CreateRepo(repo name.Repository, lifecyclePolicy string) (bool, error) {
+	parts := strings.Split(repo.RepositoryStr(), "/")
+	if len(parts) < 2 {
+		return false, fmt.Errorf("expected LOCATION-docker.pkg.dev/PROJECT/REPOSITORY[/IMAGE] repo")
+	}
+	project, repositoryId := parts[0], parts[1]
+
+	token, err := getGcpAccessToken()
+	if err != nil {
+		return false, err
+	}
+
+	parent := fmt.Sprintf("projects/%s/locations/%s", project, p.Location)
+
+	status, _, err := p.call(token, http.MethodGet, fmt.Sprintf("%s/%s/repositories/%s", artifactRegistryApiUrl, parent, repositoryId), nil)
+	if err != nil {
+		return false, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return false, nil
+	case http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("unexpected repository %s get response status %d", repositoryId, status)
+	}
+
+	repository := map[string]interface{}{"format": "DOCKER"}
+	if lifecyclePolicy != "" {
+		var cleanupPolicies interface{}
+		if err := json.Unmarshal([]byte(lifecyclePolicy), &cleanupPolicies); err != nil {
+			return false, fmt.Errorf("bad cleanup policies json: %s", err)
+		}
+		repository["cleanupPolicies"] = cleanupPolicies
+	}
+
+	createUrl := fmt.Sprintf("%s/%s/repositories?repositoryId=%s", artifactRegistryApiUrl, parent, url.QueryEscape(repositoryId))
+	status, respBody, err := p.call(token, http.MethodPost, createUrl, repository)
+	if err != nil {
+		return false, err
+	}
+
+	switch status {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected response status %d: %s", status, strings.TrimSpace(string(respBody)))
+	}
+
+	var operation struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(respBody, &operation); err != nil {
+		return false, fmt.Errorf("bad create repository response: %s", err)
+	}
+
+	return true, p.waitOperation(token, operation.Name)
+}
+
+func (p *artifactRegistryRepoProvider) waitOperation(token, operationName string) error {
+	deadline := time.Now().Add(artifactRegistryOperationTimeout)
+	for {
+		status, respBody, err := p.call(token, http.MethodGet, fmt.Sprintf("%s/%s", artifactRegistryApiUrl, operationName), nil)
+		if err != nil {
+			return err
+		}
+
+		if status != http.StatusOK {
+			return fmt.Errorf("unexpected operation %s response status %d: %s", operationName, status, strings.TrimSpace(string(respBody)))
+		}
+
+		var operation struct {
+			Done  bool `json:"done"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(respBody, &operation); err != nil {
+			return fmt.Errorf("bad operation %s response: %s", operationName, err)
+		}
+
+		if operation.Done {
+			if operation.Error != nil {
+				return fmt.Errorf("operation %s failed: %s", operationName, operation.Error.Message)
+			}
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("operation %s has not been done in %s", operationName, artifactRegistryOperationTimeout)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+func (p *artifactRegistryRepoProvider) call(token, method, reqUrl string, body interface{}) (int, []byte, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, reqUrl, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// getGcpAccessToken returns GOOGLE_OAUTH_ACCESS_TOKEN, token of the active gcloud account
+// or token of the GCE instance service account
+func getGcpAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if _, err := exec.LookPath("gcloud"); err == nil {
+		output, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+		if err == nil && strings.TrimSpace(string(output)) != "" {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, gceMetadataTokenUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GCP credentials not found: set GOOGLE_OAUTH_ACCESS_TOKEN or login with gcloud")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get GCE service account token: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("bad GCE service account token response: %s", err)
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,120 @@
+package docker_registry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/flant/werf/pkg/logger"
+)
+
+// AutoCreateRepoOptions configure creation of the repositories in the registries, which do not create repository on the first push
+type AutoCreateRepoOptions struct {
+	// LifecyclePolicyFile is a template of the provider lifecycle policy, which is applied to the created repository
+	LifecyclePolicyFile string
+}
+
+// repoProvider creates repositories with the registry provider API
+type repoProvider interface {
+	Name() string
+	// CreateRepo creates the repository with the lifecycle policy if it does not exist, returns false if the repository exists
+	CreateRepo(repo name.Repository, lifecyclePolicy string) (bool, error)
+}
+
+// LifecyclePolicyTemplateData is available in the lifecycle policy template
+type LifecyclePolicyTemplateData struct {
+	// Repository is the full repository name with the registry address
+	Repository string
+	// Registry is the registry address HOST[:PORT]
+	Registry string
+	// RepositoryName is the repository path in the registry
+	RepositoryName string
+}
+
+var (
+	ensuredRepos      = map[string]bool{}
+	ensuredReposMutex sync.Mutex
+)
+
+func getRepoProvider(registry string) repoProvider {
+	if p := newEcrRepoProvider(registry); p != nil {
+		return p
+	}
+
+	if p := newArtifactRegistryRepoProvider(registry); p != nil {
+		return p
+	}
+
+	return nil
+}
+
+// EnsureRepo creates the repository before push for providers, which require it (ECR, GCP Artifact Registry).
+// Nothing is done for other registries. Each repository is checked once per werf process.
+func EnsureRepo(repository string, opts AutoCreateRepoOptions) error {
+	ensuredReposMutex.Lock()
+	defer ensuredReposMutex.Unlock()
+
+	if ensuredRepos[repository] {
+		return nil
+	}
+
+	repo, err := name.NewRepository(repository, name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("parsing repo %q: %v", repository, err)
+	}
+
+	provider := getRepoProvider(repo.RegistryStr())
+	if provider == nil {
+		ensuredRepos[repository] = true
+		return nil
+	}
+
+	var lifecyclePolicy string
+	if opts.LifecyclePolicyFile != "" {
+		lifecyclePolicy, err = renderLifecyclePolicy(opts.LifecyclePolicyFile, LifecyclePolicyTemplateData{
+			Repository:     repository,
+			Registry:       repo.RegistryStr(),
+			RepositoryName: repo.RepositoryStr(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	created, err := provider.CreateRepo(repo, lifecyclePolicy)
+	if err != nil {
+		return fmt.Errorf("unable to create %s repository %s: %s", provider.Name(), repository, err)
+	}
+
+	if created {
+		logger.LogInfoF("Created %s repository %s\n", provider.Name(), repository)
+	}
+
+	ensuredRepos[repository] = true
+
+	return nil
+}
+
+func renderLifecyclePolicy(path string, data LifecyclePolicyTemplateData) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read lifecycle policy template %s: %s", path, err)
+	}
+
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("bad lifecycle policy template %s: %s", path, err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("unable to render lifecycle policy template %s: %s", path, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
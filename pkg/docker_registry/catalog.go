@@ -0,0 +1,125 @@
+package docker_registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// catalogPageSize is a number of repositories requested in one page, registry may return less
+const catalogPageSize = 1000
+
+// ImagesRepositories lists repositories REPO/IMAGE_NAME, where images of the multi-image project with the repo REPO are pushed.
+// Repositories are listed with the registry catalog (/v2/_catalog), ECR repositories are listed with the provider API.
+func ImagesRepositories(reference string) ([]string, error) {
+	repo, err := name.NewRepository(reference, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repo %q: %v", reference, err)
+	}
+
+	var repositoriesNames []string
+	if p := newEcrRepoProvider(repo.RegistryStr()); p != nil {
+		repositoriesNames, err = p.Repositories()
+		if err != nil {
+			return nil, fmt.Errorf("listing %s repositories failed: %s", p.Name(), err)
+		}
+	} else {
+		auth, err := authn.DefaultKeychain.Resolve(repo.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("getting creds for %q: %v", repo.Registry, err)
+		}
+
+		repositoriesNames, err = listCatalog(repo.Registry, auth, getHttpTransport(repo.RegistryStr()))
+		if err != nil {
+			return nil, fmt.Errorf("reading catalog of %q: %v", repo.RegistryStr(), err)
+		}
+	}
+
+	prefix := repo.RepositoryStr() + "/"
+
+	var res []string
+	for _, repositoryName := range repositoriesNames {
+		if !strings.HasPrefix(repositoryName, prefix) {
+			continue
+		}
+
+		imageName := strings.TrimPrefix(repositoryName, prefix)
+		if imageName == "" || strings.Contains(imageName, "/") {
+			continue
+		}
+
+		res = append(res, fmt.Sprintf("%s/%s", reference, imageName))
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// listCatalog requests repositories by pages following Link header, registry should grant registry:catalog:* scope
+func listCatalog(registry name.Registry, auth authn.Authenticator, t http.RoundTripper) ([]string, error) {
+	scopes := []string{"registry:catalog:*"}
+	tr, err := transport.New(registry, auth, t, scopes)
+	if err != nil {
+		return nil, err
+	}
+	c := &http.Client{Transport: tr}
+
+	u := url.URL{
+		Scheme:   registry.Scheme(),
+		Host:     registry.RegistryStr(),
+		Path:     "/v2/_catalog",
+		RawQuery: fmt.Sprintf("n=%d", catalogPageSize),
+	}
+
+	var repositories []string
+	for next := &u; next != nil; {
+		resp, err := c.Get(next.String())
+		if err != nil {
+			return nil, err
+		}
+
+		pageRepositories, err := readCatalogPage(resp)
+		if err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, pageRepositories...)
+
+		next, err = nextPageURL(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repositories, nil
+}
+
+func readCatalogPage(resp *http.Response) ([]string, error) {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unrecognized status code during GET %s: %v; %v", resp.Request.URL, resp.Status, string(body))
+	}
+
+	var page struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("bad catalog response: %s", err)
+	}
+
+	return page.Repositories, nil
+}
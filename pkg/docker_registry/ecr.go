@@ -0,0 +1,116 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+var ecrRegistryRegexp = regexp.MustCompile(`^(\d+)\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+type ecrRepoProvider struct {
+	RegistryId string
+	Region     string
+	ApiHost    string
+}
+
+func newEcrRepoProvider(registry string) *ecrRepoProvider {
+	match := ecrRegistryRegexp.FindStringSubmatch(registry)
+	if match == nil {
+		return nil
+	}
+
+	return &ecrRepoProvider{
+		RegistryId: match[1],
+		Region:     match[2],
+		ApiHost:    fmt.Sprintf("api.ecr.%s.amazonaws.com%s", match[2], match[3]),
+	}
+}
+
+func (p *ecrRepoProvider) Name() string {
+	return "ECR"
+}
+
+func (p *ecrRepoProvider) CreateRepo(repo name.Repository, lifecyclePolicy string) (bool, error) {
+	client, err := p.newClient()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = client.CreateRepository(&ecr.CreateRepositoryInput{
+		RegistryId:     aws.String(p.RegistryId),
+		RepositoryName: aws.String(repo.RepositoryStr()),
+	})
+	if isEcrError(err, ecr.ErrCodeRepositoryAlreadyExistsException) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if lifecyclePolicy != "" {
+		if _, err := client.PutLifecyclePolicy(&ecr.PutLifecyclePolicyInput{
+			RegistryId:          aws.String(p.RegistryId),
+			RepositoryName:      aws.String(repo.RepositoryStr()),
+			LifecyclePolicyText: aws.String(lifecyclePolicy),
+		}); err != nil {
+			return true, fmt.Errorf("unable to put lifecycle policy: %s", err)
+		}
+	}
+
+	return true, nil
+}
+
+// Repositories lists names of all repositories of the registry
+func (p *ecrRepoProvider) Repositories() ([]string, error) {
+	client, err := p.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := client.DescribeRepositoriesPages(&ecr.DescribeRepositoriesInput{
+		RegistryId: aws.String(p.RegistryId),
+		MaxResults: aws.Int64(1000),
+	}, func(page *ecr.DescribeRepositoriesOutput, lastPage bool) bool {
+		for _, repository := range page.Repositories {
+			names = append(names, aws.StringValue(repository.RepositoryName))
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// newClient returns ECR API client, which uses the default AWS credential chain:
+// environment variables, shared credentials and config files (AWS_PROFILE), web identity and ECS/EC2 instance roles
+func (p *ecrRepoProvider) newClient() (*ecr.ECR, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			Region:     aws.String(p.Region),
+			Endpoint:   aws.String(fmt.Sprintf("https://%s", p.ApiHost)),
+			HTTPClient: &http.Client{Transport: getHttpTransport(p.ApiHost), Timeout: time.Minute},
+		},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create AWS session: %s", err)
+	}
+
+	return ecr.New(sess), nil
+}
+
+func isEcrError(err error, code string) bool {
+	if e, ok := err.(awserr.Error); ok {
+		return e.Code() == code
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package docker_registry
+
+import (
+	"testing"
+)
+
+func TestNewEcrRepoProvider(t *testing.T) {
+	tests := []struct {
+		registry string
+		expected *ecrRepoProvider
+	}{
+		{
+			registry: "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			expected: &ecrRepoProvider{RegistryId: "123456789012", Region: "eu-west-1", ApiHost: "api.ecr.eu-west-1.amazonaws.com"},
+		},
+		{
+			registry: "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn",
+			expected: &ecrRepoProvider{RegistryId: "123456789012", Region: "cn-north-1", ApiHost: "api.ecr.cn-north-1.amazonaws.com.cn"},
+		},
+		{registry: "registry.example.com"},
+		{registry: "public.ecr.aws"},
+		{registry: "123456789012.dkr.ecr.eu-west-1.amazonaws.com.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.registry, func(t *testing.T) {
+			p := newEcrRepoProvider(test.registry)
+
+			if test.expected == nil {
+				if p != nil {
+					t.Errorf("\n[EXPECTED]: nil\n[GOT]: %#v", p)
+				}
+				return
+			}
+
+			if p == nil || *p != *test.expected {
+				t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", test.expected, p)
+			}
+		})
+	}
+}
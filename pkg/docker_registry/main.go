@@ -1,12 +1,11 @@
 package docker_registry
 
 import (
-	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strings"
 
@@ -23,6 +22,9 @@ type RepoImage struct {
 	v1.Image
 }
 
+// tagsPageSize is a number of tags requested in one page, registry may return less
+const tagsPageSize = 1000
+
 var GCRUrlPatterns = []string{"^container\\.cloud\\.google\\.com", "^gcr\\.io", "^.*\\.gcr\\.io"}
 
 func IsGCR(reference string) (bool, error) {
@@ -135,8 +137,7 @@ func Tags(reference string) ([]string, error) {
 		return nil, fmt.Errorf("getting creds for %q: %v", repo, err)
 	}
 
-	tags, err := remote.List(repo, auth, getHttpTransport())
-
+	tags, err := listTags(repo, auth, getHttpTransport(repo.RegistryStr()))
 	if err != nil {
 		return nil, fmt.Errorf("reading tags for %q: %v", repo, err)
 	}
@@ -144,6 +145,85 @@ func Tags(reference string) ([]string, error) {
 	return tags, nil
 }
 
+// listTags requests tags by pages following Link header, registries limit number of tags in one response
+func listTags(repo name.Repository, auth authn.Authenticator, t http.RoundTripper) ([]string, error) {
+	scopes := []string{repo.Scope(transport.PullScope)}
+	tr, err := transport.New(repo.Registry, auth, t, scopes)
+	if err != nil {
+		return nil, err
+	}
+	c := &http.Client{Transport: tr}
+
+	u := url.URL{
+		Scheme:   repo.Registry.Scheme(),
+		Host:     repo.RegistryStr(),
+		Path:     fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+		RawQuery: fmt.Sprintf("n=%d", tagsPageSize),
+	}
+
+	var tags []string
+	for next := &u; next != nil; {
+		resp, err := c.Get(next.String())
+		if err != nil {
+			return nil, err
+		}
+
+		pageTags, err := readTagsPage(resp)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, pageTags...)
+
+		next, err = nextPageURL(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+func readTagsPage(resp *http.Response) ([]string, error) {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unrecognized status code during GET %s: %v; %v", resp.Request.URL, resp.Status, string(body))
+	}
+
+	var page struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("bad tags list response: %s", err)
+	}
+
+	return page.Tags, nil
+}
+
+// nextPageURL parses `Link: </v2/<name>/tags/list?n=<n>&last=<last>>; rel="next"` header, returns nil for the last page
+func nextPageURL(resp *http.Response) (*url.URL, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(link, `rel="next"`) || !strings.HasPrefix(link, "<") || !strings.Contains(link, ">") {
+		return nil, nil
+	}
+
+	linkURL, err := url.Parse(link[1:strings.Index(link, ">")])
+	if err != nil {
+		return nil, fmt.Errorf("bad Link header %q: %s", link, err)
+	}
+
+	return resp.Request.URL.ResolveReference(linkURL), nil
+}
+
 func ImageId(reference string) (string, error) {
 	i, _, err := image(reference)
 	if err != nil {
@@ -192,9 +272,10 @@ func ImageDelete(reference string) error {
 		return fmt.Errorf("getting creds for %q: %v", r, err)
 	}
 
-	if err := remote.Delete(r, auth, getHttpTransport()); err != nil {
+	transport := getHttpTransport(r.Context().RegistryStr())
+	if err := remote.Delete(r, auth, transport); err != nil {
 		if strings.Contains(err.Error(), "UNAUTHORIZED") {
-			if gitlabRegistryDeleteErr := GitlabRegistryDelete(r, auth, getHttpTransport()); gitlabRegistryDeleteErr != nil {
+			if gitlabRegistryDeleteErr := GitlabRegistryDelete(r, auth, transport); gitlabRegistryDeleteErr != nil {
 				if strings.Contains(gitlabRegistryDeleteErr.Error(), "UNAUTHORIZED") {
 					return fmt.Errorf("deleting image %q: %v", r, err)
 				}
@@ -268,9 +349,9 @@ func image(reference string) (v1.Image, name.Reference, error) {
 
 	// FIXME: Hack for the go-containerregistry library,
 	// FIXME: that uses default transport without options to change transport to custom.
-	// FIXME: Needed for the insecure https registry to work.
+	// FIXME: Needed for the insecure registries to work.
 	oldDefaultTransport := http.DefaultTransport
-	http.DefaultTransport = getHttpTransport()
+	http.DefaultTransport = getHttpTransport(ref.Context().RegistryStr())
 	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
 	http.DefaultTransport = oldDefaultTransport
 
@@ -280,26 +361,3 @@ func image(reference string) (v1.Image, name.Reference, error) {
 
 	return img, ref, nil
 }
-
-func getHttpTransport() (transport http.RoundTripper) {
-	transport = http.DefaultTransport
-
-	if os.Getenv("WERF_INSECURE_REGISTRY") == "1" {
-		defaultTransport := http.DefaultTransport.(*http.Transport)
-
-		newTransport := &http.Transport{
-			Proxy:                 defaultTransport.Proxy,
-			DialContext:           defaultTransport.DialContext,
-			MaxIdleConns:          defaultTransport.MaxIdleConns,
-			IdleConnTimeout:       defaultTransport.IdleConnTimeout,
-			TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
-			ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-			TLSNextProto:          make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
-		}
-
-		transport = newTransport
-	}
-
-	return
-}
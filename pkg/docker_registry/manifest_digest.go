@@ -0,0 +1,53 @@
+package docker_registry
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ManifestDigest returns digest of the manifest or manifest list published under the tag reference.
+// Unlike ImageDigest it does not resolve manifest list into the platform image.
+func ManifestDigest(reference string) (string, error) {
+	ref, err := name.NewTag(reference, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	c, err := newRepoHttpClient(ref.Context(), transport.PullScope)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, manifestUrl(ref.Context(), ref.TagStr()), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	manifest, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unrecognized status code during GET %s: %v; %v", resp.Request.URL, resp.Status, string(manifest))
+	}
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)), nil
+}
@@ -0,0 +1,150 @@
+package docker_registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RegistryOptions are connection settings of the registry
+type RegistryOptions struct {
+	// Insecure registry is accessed by plain http
+	Insecure bool
+	// SkipTLSVerify disables verification of the registry certificate
+	SkipTLSVerify bool
+	// CAFile is a PEM file with CA certificates, which are trusted in addition to the system ones
+	CAFile string
+}
+
+var registriesOptions = map[string]RegistryOptions{}
+
+// SetRegistriesOptions sets options by registry address (HOST[:PORT]), registries without options use global WERF_INSECURE_REGISTRY setting
+func SetRegistriesOptions(options map[string]RegistryOptions) {
+	registriesOptions = map[string]RegistryOptions{}
+	for registry, registryOptions := range options {
+		registriesOptions[normalizeRegistry(registry)] = registryOptions
+	}
+}
+
+// GetRegistryOptions returns options of the registry, WERF_INSECURE_REGISTRY=1 disables certificate verification for all registries
+func GetRegistryOptions(registry string) RegistryOptions {
+	options := registriesOptions[normalizeRegistry(registry)]
+	if os.Getenv("WERF_INSECURE_REGISTRY") == "1" {
+		options.SkipTLSVerify = true
+	}
+
+	return options
+}
+
+// RegistryOfReference returns registry address of the repo or image reference, docker hub for short names
+func RegistryOfReference(reference string) string {
+	if ref, err := name.ParseReference(reference, name.WeakValidation); err == nil {
+		return ref.Context().RegistryStr()
+	}
+
+	if repo, err := name.NewRepository(reference, name.WeakValidation); err == nil {
+		return repo.RegistryStr()
+	}
+
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+
+	return name.DefaultRegistry
+}
+
+func normalizeRegistry(registry string) string {
+	switch registry {
+	case "docker.io", "registry-1.docker.io":
+		return name.DefaultRegistry
+	}
+	return registry
+}
+
+// originalDefaultTransport is saved before http.DefaultTransport substitution in image function
+var originalDefaultTransport = http.DefaultTransport
+
+func getHttpTransport(registry string) http.RoundTripper {
+	return &rateLimitTransport{Transport: getRegistryTransport(registry)}
+}
+
+func getRegistryTransport(registry string) http.RoundTripper {
+	options := GetRegistryOptions(registry)
+	if options == (RegistryOptions{}) {
+		return originalDefaultTransport
+	}
+
+	defaultTransport := originalDefaultTransport.(*http.Transport)
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.SkipTLSVerify}
+	if options.CAFile != "" {
+		pool, err := certPoolWithCAFile(options.CAFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: unable to load CA file of registry %s: %s\n", registry, err)
+		} else {
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:                 defaultTransport.Proxy,
+		DialContext:           defaultTransport.DialContext,
+		MaxIdleConns:          defaultTransport.MaxIdleConns,
+		IdleConnTimeout:       defaultTransport.IdleConnTimeout,
+		TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
+		ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
+		TLSClientConfig:       tlsConfig,
+		TLSNextProto:          make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
+	}
+
+	if options.Insecure {
+		transport = &plainHttpTransport{Host: registry, Transport: transport}
+	}
+
+	return transport
+}
+
+func certPoolWithCAFile(caFile string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+	}
+
+	return pool, nil
+}
+
+// plainHttpTransport sends requests to the insecure registry host by http,
+// go-containerregistry uses https for all registries except localhost
+type plainHttpTransport struct {
+	Host      string
+	Transport http.RoundTripper
+}
+
+func (t *plainHttpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "https" && req.URL.Host == t.Host {
+		newReq := new(http.Request)
+		*newReq = *req
+		newURL := *req.URL
+		newURL.Scheme = "http"
+		newReq.URL = &newURL
+		req = newReq
+	}
+
+	return t.Transport.RoundTrip(req)
+}
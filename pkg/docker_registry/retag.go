@@ -0,0 +1,104 @@
+package docker_registry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// manifestMediaTypes are accepted when manifest is requested for retagging, manifest is put back with the same media type
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v1+prettyjws",
+}
+
+// Retag puts manifest of the existing tag under the new tag in the same repository, no layers are transferred.
+// Manifest lists are retagged as is with all platform images.
+func Retag(repository, fromTag, toTag string) error {
+	repo, err := name.NewRepository(repository, name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("parsing repo %q: %v", repository, err)
+	}
+
+	c, err := newRepoHttpClient(repo, transport.PushScope)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, manifestUrl(repo, fromTag), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	manifest, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unrecognized status code during GET %s: %v; %v", resp.Request.URL, resp.Status, string(manifest))
+	}
+
+	putReq, err := http.NewRequest(http.MethodPut, manifestUrl(repo, toTag), bytes.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+	putReq.Header.Set("Content-Type", resp.Header.Get("Content-Type"))
+
+	putResp, err := c.Do(putReq)
+	if err != nil {
+		return err
+	}
+	defer putResp.Body.Close()
+
+	switch putResp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return nil
+	default:
+		b, err := ioutil.ReadAll(putResp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("unrecognized status code during PUT %s: %v; %v", putReq.URL, putResp.Status, string(b))
+	}
+}
+
+func newRepoHttpClient(repo name.Repository, scope string) (*http.Client, error) {
+	auth, err := authn.DefaultKeychain.Resolve(repo.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("getting creds for %q: %v", repo, err)
+	}
+
+	tr, err := transport.New(repo.Registry, auth, getHttpTransport(repo.RegistryStr()), []string{repo.Scope(scope)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: tr}, nil
+}
+
+func manifestUrl(repo name.Repository, reference string) string {
+	u := url.URL{
+		Scheme: repo.Registry.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", repo.RepositoryStr(), reference),
+	}
+	return u.String()
+}
@@ -0,0 +1,102 @@
+package docker_registry
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRegistryConcurrency = 5
+
+	maxRateLimitRetries   = 5
+	defaultRateLimitDelay = 5 * time.Second
+	maxRateLimitDelay     = 2 * time.Minute
+)
+
+// registryRequestsSemaphore limits number of concurrent requests to the registries, WERF_REGISTRY_CONCURRENCY overrides default limit
+var registryRequestsSemaphore = make(chan struct{}, registryConcurrency())
+
+func registryConcurrency() int {
+	if value := os.Getenv("WERF_REGISTRY_CONCURRENCY"); value != "" {
+		if concurrency, err := strconv.Atoi(value); err == nil && concurrency > 0 {
+			return concurrency
+		}
+
+		fmt.Fprintf(os.Stderr, "WARNING: bad WERF_REGISTRY_CONCURRENCY value '%s': positive integer expected, using %d\n", value, defaultRegistryConcurrency)
+	}
+
+	return defaultRegistryConcurrency
+}
+
+// rateLimitTransport limits number of concurrent requests and retries requests rejected by the registry rate limit
+// (429 Too Many Requests) after delay from Retry-After header or with exponential backoff
+type rateLimitTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		registryRequestsSemaphore <- struct{}{}
+		resp, err := t.Transport.RoundTrip(req)
+		<-registryRequestsSemaphore
+
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return resp, err
+		}
+
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := rateLimitDelay(resp, attempt)
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		fmt.Fprintf(os.Stderr, "WARNING: registry %s rate limit exceeded (%s), retrying in %s ...\n", req.URL.Host, resp.Status, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			newReq := new(http.Request)
+			*newReq = *req
+			newReq.Body = body
+			req = newReq
+		}
+	}
+}
+
+// rateLimitDelay parses Retry-After header, which contains seconds or http date
+func rateLimitDelay(resp *http.Response, attempt int) time.Duration {
+	delay := defaultRateLimitDelay << uint(attempt)
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(value); err == nil {
+			delay = time.Until(date)
+		}
+	}
+
+	if delay < 0 {
+		delay = 0
+	} else if delay > maxRateLimitDelay {
+		delay = maxRateLimitDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,191 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// DOWNLOADS_CACHE_VERSION should be bumped when layout of the downloads cache is changed
+const DOWNLOADS_CACHE_VERSION = "1"
+
+const ChecksumPrefix = "sha256:"
+
+// Refresh makes werf download files without expected checksum again once per process, so changed content changes stages signatures
+var Refresh bool
+
+var (
+	resolvedChecksums      = map[string]string{}
+	resolvedChecksumsMutex sync.Mutex
+)
+
+func GetDownloadsDir() string {
+	return filepath.Join(werf.GetHomeDir(), "downloads", DOWNLOADS_CACHE_VERSION)
+}
+
+// ValidateChecksum checks checksum format sha256:HEX
+func ValidateChecksum(checksum string) error {
+	if !strings.HasPrefix(checksum, ChecksumPrefix) {
+		return fmt.Errorf("checksum `%s` should have %s prefix", checksum, ChecksumPrefix)
+	}
+
+	sum, err := hex.DecodeString(strings.TrimPrefix(checksum, ChecksumPrefix))
+	if err != nil || len(sum) != sha256.Size {
+		return fmt.Errorf("checksum `%s` should contain %d hex characters after %s prefix", checksum, sha256.Size*2, ChecksumPrefix)
+	}
+
+	return nil
+}
+
+// Get returns path and checksum of the downloaded url content, url is downloaded once into werf home.
+// If expected checksum is specified, downloaded content is verified.
+// Otherwise checksum of the first download is tracked and reused until refresh is requested.
+func Get(ctx context.Context, url, expectedChecksum string) (string, string, error) {
+	expectedChecksum = strings.ToLower(expectedChecksum)
+
+	resolvedChecksumsMutex.Lock()
+	checksum, resolved := resolvedChecksums[url+" "+expectedChecksum]
+	resolvedChecksumsMutex.Unlock()
+
+	if resolved {
+		return getFilePath(checksum), checksum, nil
+	}
+
+	lockName := fmt.Sprintf("download.%s", util.Sha256Hash(url))
+	err := lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		var err error
+		checksum, err = get(ctx, url, expectedChecksum)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	resolvedChecksumsMutex.Lock()
+	resolvedChecksums[url+" "+expectedChecksum] = checksum
+	resolvedChecksumsMutex.Unlock()
+
+	return getFilePath(checksum), checksum, nil
+}
+
+func get(ctx context.Context, url, expectedChecksum string) (string, error) {
+	if expectedChecksum != "" {
+		if util.FileExists(getFilePath(expectedChecksum)) {
+			return expectedChecksum, nil
+		}
+
+		checksum, err := downloadFile(ctx, url)
+		if err != nil {
+			return "", err
+		}
+
+		if checksum != expectedChecksum {
+			return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", url, expectedChecksum, checksum)
+		}
+
+		return checksum, nil
+	}
+
+	trackedChecksumPath := filepath.Join(GetDownloadsDir(), "tracked", util.Sha256Hash(url))
+
+	if !Refresh || werf.IsOffline() {
+		if data, err := ioutil.ReadFile(trackedChecksumPath); err == nil {
+			trackedChecksum := strings.TrimSpace(string(data))
+
+			if trackedChecksum != "" && util.FileExists(getFilePath(trackedChecksum)) {
+				return trackedChecksum, nil
+			}
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("unable to read tracked checksum of %s: %s", url, err)
+		}
+	}
+
+	checksum, err := downloadFile(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(trackedChecksumPath), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(trackedChecksumPath, []byte(checksum+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("unable to write tracked checksum of %s: %s", url, err)
+	}
+
+	return checksum, nil
+}
+
+// downloadFile saves url content into the downloads dir by checksum
+func downloadFile(ctx context.Context, url string) (string, error) {
+	if werf.IsOffline() {
+		return "", fmt.Errorf("%s is not found in the downloads cache: %s", url, werf.OfflineError("download"))
+	}
+
+	logger.LogInfoF("# Downloading %s\n", url)
+
+	filesDir := filepath.Join(GetDownloadsDir(), "files")
+	if err := os.MkdirAll(filesDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("unable to download %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download %s: %s", url, resp.Status)
+	}
+
+	tmpFile, err := ioutil.TempFile(filesDir, "download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmpFile, hash), resp.Body)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to download %s: %s", url, err)
+	}
+
+	checksum := ChecksumPrefix + hex.EncodeToString(hash.Sum(nil))
+
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpFile.Name(), getFilePath(checksum)); err != nil {
+		return "", err
+	}
+
+	return checksum, nil
+}
+
+func getFilePath(checksum string) string {
+	return filepath.Join(GetDownloadsDir(), "files", strings.TrimPrefix(checksum, ChecksumPrefix))
+}
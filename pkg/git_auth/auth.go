@@ -0,0 +1,86 @@
+package git_auth
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+const defaultUsername = "oauth2"
+
+// Credentials are passed to the remote git repo with http basic auth
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// GetCredentials returns credentials of the http(s) remote git repo from the global config or netrc file,
+// nil is returned if the url contains password or there are no credentials for the url
+func GetCredentials(repoUrl string) (*Credentials, error) {
+	u, err := url.Parse(repoUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil
+	}
+
+	var urlUsername string
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return nil, nil
+		}
+
+		urlUsername = u.User.Username()
+		u.User = nil
+	}
+
+	config := matchConfig(u.String())
+	if config == nil {
+		return getNetrcCredentials(u.Hostname(), urlUsername)
+	}
+
+	credentials, err := getConfigCredentials(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get credentials for url '%s': %s", config.Url, err)
+	}
+
+	if urlUsername != "" {
+		credentials.Username = urlUsername
+	}
+
+	return credentials, nil
+}
+
+func getConfigCredentials(config *CredentialsConfig) (*Credentials, error) {
+	credentials := &Credentials{Username: config.Username}
+
+	if config.UsernameEnv != "" {
+		credentials.Username = os.Getenv(config.UsernameEnv)
+		if credentials.Username == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", config.UsernameEnv)
+		}
+	}
+
+	if config.GithubApp != nil {
+		token, err := getGithubAppToken(config.GithubApp)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get GitHub App installation token: %s", err)
+		}
+
+		if credentials.Username == "" {
+			credentials.Username = githubAppUsername
+		}
+		credentials.Password = token
+
+		return credentials, nil
+	}
+
+	credentials.Password = os.Getenv(config.TokenEnv)
+	if credentials.Password == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", config.TokenEnv)
+	}
+
+	if credentials.Username == "" {
+		credentials.Username = defaultUsername
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,107 @@
+package git_auth
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// CredentialsConfig is an entry of the gitCredentials section of the werf global config,
+// which defines credentials of remote git repos with urls starting with Url
+type CredentialsConfig struct {
+	Url string `yaml:"url"`
+	// Username is optional, oauth2 is used by default (x-access-token for GitHub App)
+	Username    string `yaml:"username,omitempty"`
+	UsernameEnv string `yaml:"usernameEnv,omitempty"`
+	// TokenEnv is the environment variable with the access token or password
+	TokenEnv  string           `yaml:"tokenEnv,omitempty"`
+	GithubApp *GithubAppConfig `yaml:"githubApp,omitempty"`
+}
+
+// GithubAppConfig defines GitHub App installation, which access tokens are requested with the app private key
+type GithubAppConfig struct {
+	AppID          int64  `yaml:"appId"`
+	InstallationID int64  `yaml:"installationId"`
+	PrivateKeyFile string `yaml:"privateKeyFile,omitempty"`
+	PrivateKeyEnv  string `yaml:"privateKeyEnv,omitempty"`
+	// ApiUrl is https://api.github.com by default, GitHub Enterprise uses https://HOST/api/v3
+	ApiUrl string `yaml:"apiUrl,omitempty"`
+}
+
+var configs []CredentialsConfig
+
+// SetConfig sets credentials of remote git repos, config entries are matched by the longest url prefix
+func SetConfig(newConfigs []CredentialsConfig) error {
+	for _, config := range newConfigs {
+		if err := config.validate(); err != nil {
+			return err
+		}
+	}
+
+	configs = newConfigs
+	githubAppTokens = map[string]*githubAppToken{}
+
+	return nil
+}
+
+func (c CredentialsConfig) validate() error {
+	if c.Url == "" {
+		return fmt.Errorf("url required")
+	}
+
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("bad url '%s': http or https url expected", c.Url)
+	}
+	if u.User != nil {
+		return fmt.Errorf("bad url '%s': credentials should not be specified in url", c.Url)
+	}
+
+	if c.Username != "" && c.UsernameEnv != "" {
+		return fmt.Errorf("bad credentials of url '%s': username and usernameEnv cannot be used together", c.Url)
+	}
+
+	if (c.TokenEnv == "") == (c.GithubApp == nil) {
+		return fmt.Errorf("bad credentials of url '%s': either tokenEnv or githubApp required", c.Url)
+	}
+
+	if c.GithubApp != nil {
+		if c.GithubApp.AppID <= 0 || c.GithubApp.InstallationID <= 0 {
+			return fmt.Errorf("bad githubApp of url '%s': appId and installationId required", c.Url)
+		}
+		if (c.GithubApp.PrivateKeyFile == "") == (c.GithubApp.PrivateKeyEnv == "") {
+			return fmt.Errorf("bad githubApp of url '%s': either privateKeyFile or privateKeyEnv required", c.Url)
+		}
+	}
+
+	return nil
+}
+
+// matchConfig returns config entry with the longest url prefix of the repo url
+func matchConfig(repoUrl string) *CredentialsConfig {
+	var res *CredentialsConfig
+	for i := range configs {
+		config := &configs[i]
+		if !isUrlPrefix(config.Url, repoUrl) {
+			continue
+		}
+
+		if res == nil || len(config.Url) > len(res.Url) {
+			res = config
+		}
+	}
+
+	return res
+}
+
+// isUrlPrefix checks that prefix matches the whole host or path components of the url
+func isUrlPrefix(prefix, repoUrl string) bool {
+	if len(repoUrl) < len(prefix) || repoUrl[:len(prefix)] != prefix {
+		return false
+	}
+
+	if len(repoUrl) == len(prefix) || prefix[len(prefix)-1] == '/' {
+		return true
+	}
+
+	return repoUrl[len(prefix)] == '/'
+}
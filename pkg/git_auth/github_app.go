@@ -0,0 +1,179 @@
+package git_auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	githubAppUsername    = "x-access-token"
+	defaultGithubApiUrl  = "https://api.github.com"
+	githubAppJWTLifetime = 9 * time.Minute
+	githubAppTokenMinTTL = 5 * time.Minute
+	githubApiTimeout     = 30 * time.Second
+)
+
+type githubAppToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// githubAppTokens caches installation tokens by api url and installation id, token is valid for an hour
+var (
+	githubAppTokens      = map[string]*githubAppToken{}
+	githubAppTokensMutex sync.Mutex
+)
+
+func getGithubAppToken(config *GithubAppConfig) (string, error) {
+	apiUrl := strings.TrimSuffix(config.ApiUrl, "/")
+	if apiUrl == "" {
+		apiUrl = defaultGithubApiUrl
+	}
+
+	key := fmt.Sprintf("%s/%d", apiUrl, config.InstallationID)
+
+	githubAppTokensMutex.Lock()
+	defer githubAppTokensMutex.Unlock()
+
+	if token, ok := githubAppTokens[key]; ok && time.Until(token.ExpiresAt) > githubAppTokenMinTTL {
+		return token.Token, nil
+	}
+
+	privateKey, err := readGithubAppPrivateKey(config)
+	if err != nil {
+		return "", err
+	}
+
+	jwt, err := githubAppJWT(config.AppID, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := requestGithubAppToken(apiUrl, config.InstallationID, jwt)
+	if err != nil {
+		return "", err
+	}
+
+	githubAppTokens[key] = token
+
+	return token.Token, nil
+}
+
+func readGithubAppPrivateKey(config *GithubAppConfig) (*rsa.PrivateKey, error) {
+	var data []byte
+	if config.PrivateKeyEnv != "" {
+		data = []byte(os.Getenv(config.PrivateKeyEnv))
+		if len(data) == 0 {
+			return nil, fmt.Errorf("environment variable %s is not set", config.PrivateKeyEnv)
+		}
+	} else {
+		var err error
+		data, err = ioutil.ReadFile(config.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read private key file: %s", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("bad private key: PEM block expected")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("bad private key: %s", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("bad private key: RSA key expected")
+	}
+
+	return rsaKey, nil
+}
+
+// githubAppJWT creates RS256 token, which authenticates GitHub App requests of installation tokens
+func githubAppJWT(appID int64, privateKey *rsa.PrivateKey) (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// issued time is set in the past to allow clock drift
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("cannot sign GitHub App token: %s", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func requestGithubAppToken(apiUrl string, installationID int64, jwt string) (*githubAppToken, error) {
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", apiUrl, installationID)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(nil))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	client := &http.Client{Timeout: githubApiTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s failed: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response of %s: %s", url, err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("request %s failed: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	token := &githubAppToken{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, fmt.Errorf("bad response of %s: %s", url, err)
+	}
+
+	if token.Token == "" {
+		return nil, fmt.Errorf("bad response of %s: no token", url)
+	}
+
+	return token, nil
+}
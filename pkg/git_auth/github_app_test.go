@@ -0,0 +1,214 @@
+package git_auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func generateTestPrivateKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKey
+}
+
+func decodeJWTPart(t *testing.T, part string, v interface{}) {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGithubAppJWT(t *testing.T) {
+	privateKey := generateTestPrivateKey(t)
+
+	jwt, err := githubAppJWT(12345, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("\n[EXPECTED]: HEADER.CLAIMS.SIGNATURE\n[GOT]: %s", jwt)
+	}
+
+	header := map[string]string{}
+	decodeJWTPart(t, parts[0], &header)
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		t.Errorf("\n[EXPECTED]: RS256 JWT header\n[GOT]: %#v", header)
+	}
+
+	claims := map[string]int64{}
+	decodeJWTPart(t, parts[1], &claims)
+
+	now := time.Now().Unix()
+	if claims["iss"] != 12345 {
+		t.Errorf("\n[EXPECTED]: iss 12345\n[GOT]: %#v", claims)
+	}
+	if claims["iat"] >= now || claims["iat"] < now-120 {
+		t.Errorf("\n[EXPECTED]: iat in the past with clock drift allowance\n[GOT]: %d (now %d)", claims["iat"], now)
+	}
+	if exp := claims["exp"] - now; exp <= 0 || exp > int64(10*time.Minute/time.Second) {
+		t.Errorf("\n[EXPECTED]: exp within 10 minutes\n[GOT]: %d (now %d)", claims["exp"], now)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Errorf("bad JWT signature: %s", err)
+	}
+}
+
+func TestReadGithubAppPrivateKey(t *testing.T) {
+	privateKey := generateTestPrivateKey(t)
+
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1Data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	pkcs8Data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Key})
+
+	dir, err := ioutil.TempDir("", "werf-github-app-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	privateKeyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(privateKeyFile, pkcs1Data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("WERF_TEST_GITHUB_APP_KEY", string(pkcs8Data))
+	defer os.Unsetenv("WERF_TEST_GITHUB_APP_KEY")
+
+	for _, config := range []*GithubAppConfig{
+		{PrivateKeyFile: privateKeyFile},
+		{PrivateKeyEnv: "WERF_TEST_GITHUB_APP_KEY"},
+	} {
+		key, err := readGithubAppPrivateKey(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key.N.Cmp(privateKey.N) != 0 || key.D.Cmp(privateKey.D) != 0 {
+			t.Errorf("\n[EXPECTED]: generated key\n[GOT]: another key from %#v", config)
+		}
+	}
+}
+
+func TestReadGithubAppPrivateKey_negative(t *testing.T) {
+	os.Setenv("WERF_TEST_GITHUB_APP_KEY", "not a key")
+	defer os.Unsetenv("WERF_TEST_GITHUB_APP_KEY")
+
+	os.Setenv("WERF_TEST_GITHUB_APP_BAD_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("bad")})))
+	defer os.Unsetenv("WERF_TEST_GITHUB_APP_BAD_KEY")
+
+	tests := []struct {
+		config        *GithubAppConfig
+		errorContains string
+	}{
+		{
+			config:        &GithubAppConfig{PrivateKeyEnv: "WERF_TEST_GITHUB_APP_KEY_NOT_SET"},
+			errorContains: "environment variable WERF_TEST_GITHUB_APP_KEY_NOT_SET is not set",
+		},
+		{
+			config:        &GithubAppConfig{PrivateKeyFile: "/nonexistent/key.pem"},
+			errorContains: "cannot read private key file",
+		},
+		{
+			config:        &GithubAppConfig{PrivateKeyEnv: "WERF_TEST_GITHUB_APP_KEY"},
+			errorContains: "bad private key: PEM block expected",
+		},
+		{
+			config:        &GithubAppConfig{PrivateKeyEnv: "WERF_TEST_GITHUB_APP_BAD_KEY"},
+			errorContains: "bad private key: ",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := readGithubAppPrivateKey(test.config)
+		if err == nil || !strings.Contains(err.Error(), test.errorContains) {
+			t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %v", test.errorContains, err)
+		}
+	}
+}
+
+func TestGetGithubAppToken(t *testing.T) {
+	privateKey := generateTestPrivateKey(t)
+
+	os.Setenv("WERF_TEST_GITHUB_APP_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})))
+	defer os.Unsetenv("WERF_TEST_GITHUB_APP_KEY")
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/42/access_tokens" {
+			http.Error(w, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+			return
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			http.Error(w, "JWT required", http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "installation-token-%d", "expires_at": %q}`, requests, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	if err := SetConfig(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &GithubAppConfig{AppID: 1, InstallationID: 42, PrivateKeyEnv: "WERF_TEST_GITHUB_APP_KEY", ApiUrl: server.URL + "/api/v3/"}
+
+	for i := 0; i < 2; i++ {
+		token, err := getGithubAppToken(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token != "installation-token-1" {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "installation-token-1", token)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("\n[EXPECTED]: token is requested once and cached\n[GOT]: %d requests", requests)
+	}
+
+	_, err := getGithubAppToken(&GithubAppConfig{AppID: 1, InstallationID: 43, PrivateKeyEnv: "WERF_TEST_GITHUB_APP_KEY", ApiUrl: server.URL + "/api/v3"})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found: unexpected request POST /api/v3/app/installations/43/access_tokens") {
+		t.Errorf("\n[EXPECTED]: request failed error\n[GOT]: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package git_auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// GetNetrcPath returns path of the netrc file from NETRC environment variable or ~/.netrc (~/_netrc on Windows)
+func GetNetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+
+	homeDir := os.Getenv("HOME")
+	if runtime.GOOS == "windows" {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+
+	return filepath.Join(homeDir, name)
+}
+
+type netrcMachine struct {
+	Name     string
+	Login    string
+	Password string
+}
+
+// getNetrcCredentials returns credentials of the host machine or default machine of the netrc file,
+// machine with another login is skipped if the username is specified in the url
+func getNetrcCredentials(host, username string) (*Credentials, error) {
+	path := GetNetrcPath()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read netrc file %s: %s", path, err)
+	}
+
+	for _, machine := range parseNetrc(string(data)) {
+		if machine.Name != "" && machine.Name != host {
+			continue
+		}
+
+		if username != "" && machine.Login != username {
+			continue
+		}
+
+		if machine.Password == "" {
+			return nil, nil
+		}
+
+		return &Credentials{Username: machine.Login, Password: machine.Password}, nil
+	}
+
+	return nil, nil
+}
+
+// parseNetrc returns machines in the file order, default machine has empty name
+func parseNetrc(data string) []*netrcMachine {
+	var machines []*netrcMachine
+	var machine *netrcMachine
+
+	var inMacdef bool
+	for _, line := range strings.Split(data, "\n") {
+		// macdef body ends with an empty line
+		if inMacdef {
+			inMacdef = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			var value string
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+
+			switch fields[i] {
+			case "machine":
+				machine = &netrcMachine{Name: value}
+				machines = append(machines, machine)
+				i++
+			case "default":
+				machine = &netrcMachine{}
+				machines = append(machines, machine)
+			case "login", "password", "account":
+				if machine != nil {
+					if fields[i] == "login" {
+						machine.Login = value
+					} else if fields[i] == "password" {
+						machine.Password = value
+					}
+				}
+				i++
+			case "macdef":
+				inMacdef = true
+				i = len(fields)
+			}
+		}
+	}
+
+	return machines
+}
@@ -14,7 +14,7 @@ type ArchiveFile struct {
 }
 
 func NewTmpArchiveFile() *ArchiveFile {
-	path := filepath.Join("/tmp", fmt.Sprintf("werf-%s.archive.tar", uuid.NewV4().String()))
+	path := filepath.Join(getArchivesDir(), fmt.Sprintf("werf-%s.archive.tar", uuid.NewV4().String()))
 	return &ArchiveFile{FilePath: path}
 }
 
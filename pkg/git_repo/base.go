@@ -1,6 +1,7 @@
 package git_repo
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"errors"
@@ -14,7 +15,9 @@ import (
 	"time"
 
 	"github.com/bmatcuk/doublestar"
+	"github.com/flant/werf/pkg/home_usage"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
 	"github.com/flant/werf/pkg/true_git"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
@@ -123,8 +126,14 @@ func (repo *Base) isEmpty(repoPath string) (bool, error) {
 }
 
 func (repo *Base) withWorkTreeLock(workTree string, f func() error) error {
-	lockName := fmt.Sprintf("git_work_tree %s", workTree)
-	return lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, f)
+	lockName := WorkTreeLockName(workTree)
+	return lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		if err := home_usage.MarkUsed(home_usage.GitWorkTreeKind, workTree, lockName); err != nil {
+			return err
+		}
+
+		return f()
+	})
 }
 
 func (repo *Base) getReferenceForRepo(repoPath string) (*plumbing.Reference, error) {
@@ -160,7 +169,42 @@ func (repo *Base) GetName() string {
 	return repo.Name
 }
 
-func (repo *Base) createPatch(repoPath, gitDir, workTreeDir string, opts PatchOptions) (Patch, error) {
+func (repo *Base) mergeBase(ctx context.Context, gitDir, commit1, commit2 string) (string, error) {
+	commit, err := true_git.MergeBase(ctx, gitDir, commit1, commit2)
+	if err != nil {
+		return "", fmt.Errorf("cannot get merge-base of commits `%s` and `%s` of repo `%s`: %s", commit1, commit2, repo.String(), err)
+	}
+
+	return commit, nil
+}
+
+func (repo *Base) createPatch(ctx context.Context, repoPath, gitDir, workTreeDir string, opts PatchOptions) (Patch, error) {
+	patches, err := repo.createPatches(ctx, repoPath, gitDir, workTreeDir, []PatchOptions{opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return patches[0], nil
+}
+
+// createPatches creates patches of the same commits range for multiple filters with a single git diff invocation,
+// options with different commits range or diff options and repos with submodules are processed one by one
+func (repo *Base) createPatches(ctx context.Context, repoPath, gitDir, workTreeDir string, optsList []PatchOptions) ([]Patch, error) {
+	if len(optsList) > 1 && !isSamePatchRange(optsList) {
+		return repo.createPatchesOneByOne(ctx, repoPath, gitDir, workTreeDir, optsList)
+	}
+
+	opts := optsList[0]
+
+	if opts.FromMergeBase {
+		mergeBase, err := repo.mergeBase(ctx, gitDir, opts.FromCommit, opts.ToCommit)
+		if err != nil {
+			return nil, err
+		}
+		opts.FromCommit = mergeBase
+		opts.FromMergeBase = false
+	}
+
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
@@ -190,54 +234,138 @@ func (repo *Base) createPatch(repoPath, gitDir, workTreeDir string, opts PatchOp
 	if err != nil {
 		return nil, err
 	}
+	hasSubmodules = hasSubmodules && !opts.SkipSubmodules
 
-	patch := NewTmpPatchFile()
+	if len(optsList) > 1 && hasSubmodules {
+		return repo.createPatchesOneByOne(ctx, repoPath, gitDir, workTreeDir, optsList)
+	}
 
-	fileHandler, err := os.OpenFile(patch.GetFilePath(), os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open patch file `%s`: %s", patch.GetFilePath(), err)
+	var patches []*PatchFile
+	var targets []true_git.PatchTarget
+	var fileHandlers []*os.File
+	closeFileHandlers := func() {
+		for _, fileHandler := range fileHandlers {
+			fileHandler.Close()
+		}
+	}
+
+	for _, filterOpts := range optsList {
+		pathFilter := true_git.PathFilter{
+			BasePath:     filterOpts.BasePath,
+			IncludePaths: filterOpts.IncludePaths,
+			ExcludePaths: filterOpts.ExcludePaths,
+		}
+
+		if err := checkPatchFreeSpace(ctx, gitDir, opts, pathFilter); err != nil {
+			closeFileHandlers()
+			return nil, err
+		}
+
+		patch := NewTmpPatchFile()
+
+		if err := os.MkdirAll(filepath.Dir(patch.GetFilePath()), os.ModePerm); err != nil {
+			closeFileHandlers()
+			return nil, fmt.Errorf("cannot create patches dir: %s", err)
+		}
+
+		fileHandler, err := os.OpenFile(patch.GetFilePath(), os.O_RDWR|os.O_CREATE, 0755)
+		if err != nil {
+			closeFileHandlers()
+			return nil, fmt.Errorf("cannot open patch file `%s`: %s", patch.GetFilePath(), err)
+		}
+
+		patches = append(patches, patch)
+		fileHandlers = append(fileHandlers, fileHandler)
+		targets = append(targets, true_git.PatchTarget{Out: fileHandler, PathFilter: pathFilter})
+	}
+
+	if hasSubmodules {
+		if err := checkWorkTreeFreeSpace(ctx, gitDir, workTreeDir, opts.ToCommit); err != nil {
+			closeFileHandlers()
+			return nil, err
+		}
 	}
 
 	patchOpts := true_git.PatchOptions{
-		FromCommit: opts.FromCommit,
-		ToCommit:   opts.ToCommit,
-		PathFilter: true_git.PathFilter{
-			BasePath:     opts.BasePath,
-			IncludePaths: opts.IncludePaths,
-			ExcludePaths: opts.ExcludePaths,
-		},
+		FromCommit:            opts.FromCommit,
+		ToCommit:              opts.ToCommit,
+		PathFilter:            targets[0].PathFilter,
 		WithEntireFileContext: opts.WithEntireFileContext,
 		WithBinary:            opts.WithBinary,
+		DetectRenames:         opts.DetectRenames,
+		DetectCopies:          opts.DetectCopies,
+		Submodules:            opts.toTrueGitSubmodulesOptions(),
 	}
 
-	var desc *true_git.PatchDescriptor
+	var descs []*true_git.PatchDescriptor
 
 	if hasSubmodules {
 		err = repo.withWorkTreeLock(workTreeDir, func() error {
-			desc, err = true_git.PatchWithSubmodules(fileHandler, gitDir, workTreeDir, patchOpts)
+			desc, err := true_git.PatchWithSubmodules(ctx, fileHandlers[0], gitDir, workTreeDir, patchOpts)
+			descs = []*true_git.PatchDescriptor{desc}
 			return err
 		})
 	} else {
-		desc, err = true_git.Patch(fileHandler, gitDir, patchOpts)
+		descs, err = true_git.Patches(ctx, gitDir, patchOpts, targets)
 	}
 
 	if err != nil {
+		closeFileHandlers()
 		return nil, fmt.Errorf("error creating patch between `%s` and `%s` commits: %s", opts.FromCommit, opts.ToCommit, err)
 	}
 
-	patch.Descriptor = desc
+	var res []Patch
+	for ind, patch := range patches {
+		patch.Descriptor = descs[ind]
 
-	err = fileHandler.Close()
-	if err != nil {
-		return nil, fmt.Errorf("error creating patch file `%s`: %s", patch.GetFilePath(), err)
+		if err := fileHandlers[ind].Close(); err != nil {
+			return nil, fmt.Errorf("error creating patch file `%s`: %s", patch.GetFilePath(), err)
+		}
+
+		res = append(res, patch)
+	}
+
+	return res, nil
+}
+
+func (repo *Base) createPatchesOneByOne(ctx context.Context, repoPath, gitDir, workTreeDir string, optsList []PatchOptions) ([]Patch, error) {
+	var patches []Patch
+	for _, opts := range optsList {
+		patch, err := repo.createPatch(ctx, repoPath, gitDir, workTreeDir, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		patches = append(patches, patch)
+	}
+
+	return patches, nil
+}
+
+// isSamePatchRange checks that options differ only in filters, so patches can be created with a single git diff invocation
+func isSamePatchRange(optsList []PatchOptions) bool {
+	first := optsList[0]
+	for _, opts := range optsList[1:] {
+		if first.FromCommit != opts.FromCommit || first.ToCommit != opts.ToCommit || first.FromMergeBase != opts.FromMergeBase ||
+			first.WithEntireFileContext != opts.WithEntireFileContext || first.WithBinary != opts.WithBinary ||
+			first.DetectRenames != opts.DetectRenames || first.DetectCopies != opts.DetectCopies ||
+			first.SkipSubmodules != opts.SkipSubmodules {
+			return false
+		}
 	}
 
-	return patch, nil
+	return true
 }
 
+// HasSubmodulesInCommit checks tree entry only, so blob of .gitmodules is not required in the partial clone
 func HasSubmodulesInCommit(commit *object.Commit) (bool, error) {
-	_, err := commit.File(".gitmodules")
-	if err == object.ErrFileNotFound {
+	tree, err := commit.Tree()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tree.FindEntry(".gitmodules")
+	if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
 		return false, nil
 	}
 	if err != nil {
@@ -246,7 +374,7 @@ func HasSubmodulesInCommit(commit *object.Commit) (bool, error) {
 	return true, nil
 }
 
-func (repo *Base) createArchive(repoPath, gitDir, workTreeDir string, opts ArchiveOptions) (Archive, error) {
+func (repo *Base) createArchive(ctx context.Context, repoPath, gitDir, workTreeDir string, opts ArchiveOptions) (Archive, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
@@ -266,9 +394,34 @@ func (repo *Base) createArchive(repoPath, gitDir, workTreeDir string, opts Archi
 	if err != nil {
 		return nil, err
 	}
+	hasSubmodules = hasSubmodules && !opts.SkipSubmodules
+
+	if err := checkCaseCollisions(commit, true_git.PathFilter{
+		BasePath:     opts.BasePath,
+		IncludePaths: opts.IncludePaths,
+		ExcludePaths: opts.ExcludePaths,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := checkArchiveFreeSpace(ctx, gitDir, opts, true_git.PathFilter{
+		BasePath:     opts.BasePath,
+		IncludePaths: opts.IncludePaths,
+		ExcludePaths: opts.ExcludePaths,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := checkWorkTreeFreeSpace(ctx, gitDir, workTreeDir, opts.Commit); err != nil {
+		return nil, err
+	}
 
 	archive := NewTmpArchiveFile()
 
+	if err := os.MkdirAll(filepath.Dir(archive.GetFilePath()), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("cannot create archives dir: %s", err)
+	}
+
 	fileHandler, err := os.OpenFile(archive.GetFilePath(), os.O_RDWR|os.O_CREATE, 0755)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open archive file: %s", err)
@@ -281,22 +434,33 @@ func (repo *Base) createArchive(repoPath, gitDir, workTreeDir string, opts Archi
 			IncludePaths: opts.IncludePaths,
 			ExcludePaths: opts.ExcludePaths,
 		},
+		Submodules: opts.toTrueGitSubmodulesOptions(),
+	}
+
+	if opts.Deterministic {
+		archiveOpts.Deterministic = true
+		archiveOpts.ModTime = commit.Committer.When
 	}
 
 	var desc *true_git.ArchiveDescriptor
 
+	progress := logger.NewBytesProgress(fmt.Sprintf("Archive %s commit %s", repo.Name, opts.Commit), 0)
+	out := progress.Writer(fileHandler)
+
 	if hasSubmodules {
 		err = repo.withWorkTreeLock(workTreeDir, func() error {
-			desc, err = true_git.ArchiveWithSubmodules(fileHandler, gitDir, workTreeDir, archiveOpts)
+			desc, err = true_git.ArchiveWithSubmodules(ctx, out, gitDir, workTreeDir, archiveOpts)
 			return err
 		})
 	} else {
 		err = repo.withWorkTreeLock(workTreeDir, func() error {
-			desc, err = true_git.Archive(fileHandler, gitDir, workTreeDir, archiveOpts)
+			desc, err = true_git.Archive(ctx, out, gitDir, workTreeDir, archiveOpts)
 			return err
 		})
 	}
 
+	progress.Done()
+
 	if err != nil {
 		return nil, fmt.Errorf("error creating archive for commit `%s`: %s", opts.Commit, err)
 	}
@@ -326,6 +490,120 @@ func (repo *Base) isCommitExists(repoPath string, commit string) (bool, error) {
 	return true, nil
 }
 
+// resolveCommit resolves the revision into the full commit hash, revisions, which are not found, are resolved relative to the remotes branches and tags
+func (repo *Base) resolveCommit(ctx context.Context, gitDir, revision string, remotes []string) (string, error) {
+	revisions := []string{revision}
+	for _, remote := range remotes {
+		revisions = append(revisions, fmt.Sprintf("%s/%s", remote, revision))
+	}
+
+	for _, rev := range revisions {
+		commit, err := true_git.ResolveCommit(ctx, gitDir, rev)
+		if err == true_git.ErrRevisionNotFound {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("cannot resolve commit `%s` of repo `%s`: %s", revision, repo.String(), err)
+		}
+
+		return commit, nil
+	}
+
+	return "", fmt.Errorf("commit `%s` not found in repo `%s`: full or abbreviated commit hash, branch, tag or an expression like HEAD~2 expected", revision, repo.String())
+}
+
+func (repo *Base) getCommitObject(repoPath, commit string) (*object.Commit, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
+	}
+
+	commitHash, err := newHash(commit)
+	if err != nil {
+		return nil, fmt.Errorf("bad commit hash `%s`: %s", commit, err)
+	}
+
+	commitObj, err := repository.CommitObject(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("bad commit `%s`: %s", commit, err)
+	}
+
+	return commitObj, nil
+}
+
+func (repo *Base) isCommitFileExists(repoPath, commit, path string) (bool, error) {
+	commitObj, err := repo.getCommitObject(repoPath, commit)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = commitObj.File(filepath.ToSlash(path))
+	if err == object.ErrFileNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (repo *Base) readCommitFile(repoPath, commit, path string) ([]byte, error) {
+	commitObj, err := repo.getCommitObject(repoPath, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commitObj.File(filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get file `%s` from commit `%s`: %s", path, commit, err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file `%s` from commit `%s`: %s", path, commit, err)
+	}
+
+	return []byte(contents), nil
+}
+
+func (repo *Base) commitFilesList(repoPath, commit, dir string) ([]string, error) {
+	commitObj, err := repo.getCommitObject(repoPath, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	// empty dir means the root of the repo
+	var dirPrefix string
+	if dir := strings.Trim(filepath.ToSlash(dir), "/"); dir != "" && dir != "." {
+		dirPrefix = dir + "/"
+	}
+
+	// tree walker does not read blobs, which could be missing in the partial clone
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	var res []string
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Mode.IsFile() && strings.HasPrefix(name, dirPrefix) {
+			res = append(res, name)
+		}
+	}
+
+	return res, nil
+}
+
 func (repo *Base) tagsList(repoPath string) ([]string, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
@@ -346,6 +624,61 @@ func (repo *Base) tagsList(repoPath string) ([]string, error) {
 	return res, nil
 }
 
+// recentCommitsList returns commits of local and remote branches, which were committed after the since time
+func (repo *Base) recentCommitsList(repoPath string, since time.Time) ([]string, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
+	}
+
+	refs, err := repository.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []*object.Commit
+	err = refs.ForEach(func(r *plumbing.Reference) error {
+		if r.Type() != plumbing.HashReference || !(r.Name().IsBranch() || r.Name().IsRemote()) {
+			return nil
+		}
+
+		commitObj, err := repository.CommitObject(r.Hash())
+		if err != nil {
+			return fmt.Errorf("bad commit `%s` of reference `%s`: %s", r.Hash(), r.Name(), err)
+		}
+
+		queue = append(queue, commitObj)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[plumbing.Hash]bool{}
+	res := make([]string, 0)
+	for len(queue) > 0 {
+		commitObj := queue[0]
+		queue = queue[1:]
+
+		if seen[commitObj.Hash] || commitObj.Committer.When.Before(since) {
+			continue
+		}
+		seen[commitObj.Hash] = true
+		res = append(res, commitObj.Hash.String())
+
+		err := commitObj.Parents().ForEach(func(parent *object.Commit) error {
+			queue = append(queue, parent)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
 func (repo *Base) remoteBranchesList(repoPath string) ([]string, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
@@ -374,7 +707,7 @@ func (repo *Base) remoteBranchesList(repoPath string) ([]string, error) {
 	return res, nil
 }
 
-func (repo *Base) checksum(repoPath, gitDir, workTreeDir string, opts ChecksumOptions) (Checksum, error) {
+func (repo *Base) checksum(ctx context.Context, repoPath, gitDir, workTreeDir string, opts ChecksumOptions) (Checksum, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
@@ -394,20 +727,50 @@ func (repo *Base) checksum(repoPath, gitDir, workTreeDir string, opts ChecksumOp
 	if err != nil {
 		return nil, err
 	}
+	hasSubmodules = hasSubmodules && !opts.SkipSubmodules
+
+	// submodules revisions could point to branches, so checksum of such commit is not stable
+	useCache := !debugChecksum() && !(hasSubmodules && len(opts.SubmodulesRevisions) > 0)
+	cacheKey := checksumCacheKey(commit.TreeHash.String(), opts)
+
+	if useCache {
+		if cachedChecksum := getCachedChecksum(cacheKey); cachedChecksum != nil {
+			return cachedChecksum, nil
+		}
+	}
 
 	checksum := &ChecksumDescriptor{
 		NoMatchPaths: make([]string, 0),
 		Hash:         sha256.New(),
 	}
 
+	pathFilter := true_git.PathFilter{
+		BasePath:     opts.BasePath,
+		IncludePaths: opts.IncludePaths,
+		ExcludePaths: opts.ExcludePaths,
+	}
+
+	if err := checkCaseCollisions(commit, pathFilter); err != nil {
+		return nil, err
+	}
+
+	if err := checkWorkTreeFreeSpace(ctx, gitDir, workTreeDir, opts.Commit); err != nil {
+		return nil, err
+	}
+
 	err = repo.withWorkTreeLock(workTreeDir, func() error {
-		if hasSubmodules {
-			err := true_git.PrepareWorkTreeWithSubmodules(gitDir, workTreeDir, opts.Commit)
+		if !hasSubmodules && true_git.IsPartialClone(gitDir) {
+			err := true_git.PrepareSparseWorkTree(ctx, gitDir, workTreeDir, opts.Commit, pathFilter)
+			if err != nil {
+				return err
+			}
+		} else if hasSubmodules {
+			err := true_git.PrepareWorkTreeWithSubmodules(ctx, gitDir, workTreeDir, opts.Commit, opts.toTrueGitSubmodulesOptions())
 			if err != nil {
 				return err
 			}
 		} else {
-			err := true_git.PrepareWorkTree(gitDir, workTreeDir, opts.Commit)
+			err := true_git.PrepareWorkTree(ctx, gitDir, workTreeDir, opts.Commit)
 			if err != nil {
 				return err
 			}
@@ -433,12 +796,6 @@ func (repo *Base) checksum(repoPath, gitDir, workTreeDir string, opts ChecksumOp
 
 		sort.Strings(paths)
 
-		pathFilter := true_git.PathFilter{
-			BasePath:     opts.BasePath,
-			IncludePaths: opts.IncludePaths,
-			ExcludePaths: opts.ExcludePaths,
-		}
-
 		for _, path := range paths {
 			fullPath := filepath.Join(workTreeDir, path)
 
@@ -460,13 +817,33 @@ func (repo *Base) checksum(repoPath, gitDir, workTreeDir string, opts ChecksumOp
 				return fmt.Errorf("error accessing file `%s`: %s", fullPath, err)
 			}
 
-			_, err = checksum.Hash.Write([]byte(fmt.Sprintf("%o", stat.Mode())))
-			if err != nil {
-				return fmt.Errorf("error calculating checksum of file `%s` mode: %s", fullPath, err)
+			contentPath := fullPath
+			if opts.DereferenceSymlinks && stat.Mode()&os.ModeSymlink != 0 {
+				targetPath, targetStat, err := symlinkTargetFile(workTreeDir, fullPath)
+				if err != nil {
+					return err
+				}
+
+				if targetStat != nil {
+					contentPath, stat = targetPath, targetStat
+				}
+			}
+
+			if !opts.IgnoreMode {
+				_, err = checksum.Hash.Write([]byte(fmt.Sprintf("%o", stat.Mode())))
+				if err != nil {
+					return fmt.Errorf("error calculating checksum of file `%s` mode: %s", fullPath, err)
+				}
+			}
+
+			if opts.Xattrs && stat.Mode().IsRegular() {
+				if err := writeXattrsChecksum(checksum.Hash, contentPath); err != nil {
+					return fmt.Errorf("error calculating checksum of file `%s` extended attributes: %s", fullPath, err)
+				}
 			}
 
 			if stat.Mode().IsRegular() {
-				f, err := os.Open(fullPath)
+				f, err := os.Open(contentPath)
 				if err != nil {
 					return fmt.Errorf("unable to open file `%s`: %s", fullPath, err)
 				}
@@ -529,9 +906,47 @@ func (repo *Base) checksum(repoPath, gitDir, workTreeDir string, opts ChecksumOp
 		fmt.Printf("Calculated checksum %s\n", checksum.String())
 	}
 
+	if useCache {
+		if err := putCachedChecksum(cacheKey, checksum); err != nil {
+			logger.LogWarningF("WARNING: cannot cache checksum of commit `%s`: %s\n", opts.Commit, err)
+		}
+	}
+
 	return checksum, nil
 }
 
+// symlinkTargetFile resolves the symlink, which should point inside the work tree.
+// Returns nil stat if target does not exist or is not a regular file: such symlink is hashed as a link.
+func symlinkTargetFile(workTreeDir, fullPath string) (string, os.FileInfo, error) {
+	targetPath, err := filepath.EvalSymlinks(fullPath)
+	if os.IsNotExist(err) {
+		return "", nil, nil
+	} else if err != nil {
+		return "", nil, fmt.Errorf("cannot resolve symlink `%s`: %s", fullPath, err)
+	}
+
+	resolvedWorkTreeDir, err := filepath.EvalSymlinks(workTreeDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot resolve work tree dir `%s`: %s", workTreeDir, err)
+	}
+
+	relPath, err := filepath.Rel(resolvedWorkTreeDir, targetPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", nil, fmt.Errorf("cannot dereference symlink `%s`: target `%s` is outside of the repo", fullPath, targetPath)
+	}
+
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error accessing file `%s`: %s", targetPath, err)
+	}
+
+	if !stat.Mode().IsRegular() {
+		return "", nil, nil
+	}
+
+	return targetPath, stat, nil
+}
+
 func getFilesByPattern(baseDir, pathPattern string) ([]string, error) {
 	fullPathPattern := filepath.Join(baseDir, pathPattern)
 
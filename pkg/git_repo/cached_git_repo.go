@@ -0,0 +1,221 @@
+package git_repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+// CachedGitRepo reuses patches and archives, which have been already created with the same options.
+// Each call returns a separate copy of the cached file, so the caller is free to move or remove it.
+type CachedGitRepo struct {
+	GitRepo
+	CacheDir string
+
+	patches  map[string]Patch
+	archives map[string]Archive
+}
+
+func NewCachedGitRepo(repo GitRepo, cacheDir string) *CachedGitRepo {
+	return &CachedGitRepo{
+		GitRepo:  repo,
+		CacheDir: cacheDir,
+		patches:  map[string]Patch{},
+		archives: map[string]Archive{},
+	}
+}
+
+type cachedPatch struct {
+	Patch
+	FilePath string
+}
+
+func (p *cachedPatch) GetFilePath() string {
+	return p.FilePath
+}
+
+type cachedArchive struct {
+	Archive
+	FilePath string
+}
+
+func (a *cachedArchive) GetFilePath() string {
+	return a.FilePath
+}
+
+func (repo *CachedGitRepo) CreatePatch(ctx context.Context, opts PatchOptions) (Patch, error) {
+	patches, err := repo.CreatePatches(ctx, []PatchOptions{opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return patches[0], nil
+}
+
+// CreatePatches creates only patches, which are not cached yet, with a single call of the underlying repo
+func (repo *CachedGitRepo) CreatePatches(ctx context.Context, optsList []PatchOptions) ([]Patch, error) {
+	var keys []string
+	var newKeys []string
+	var newOptsList []PatchOptions
+	for _, opts := range optsList {
+		key := patchOptionsKey(repo.GetName(), opts)
+		keys = append(keys, key)
+
+		if _, ok := repo.patches[key]; ok || util.IsStringsContainValue(newKeys, key) {
+			continue
+		}
+
+		newKeys = append(newKeys, key)
+		newOptsList = append(newOptsList, opts)
+	}
+
+	if len(newOptsList) > 0 {
+		newPatches, err := repo.GitRepo.CreatePatches(ctx, newOptsList)
+		if err != nil {
+			return nil, err
+		}
+
+		for ind, newPatch := range newPatches {
+			cacheFilePath := filepath.Join(repo.CacheDir, fmt.Sprintf("%s.patch", newKeys[ind]))
+			if err := moveFile(newPatch.GetFilePath(), cacheFilePath); err != nil {
+				return nil, fmt.Errorf("cannot cache patch: %s", err)
+			}
+
+			repo.patches[newKeys[ind]] = &cachedPatch{Patch: newPatch, FilePath: cacheFilePath}
+		}
+	}
+
+	var patches []Patch
+	for _, key := range keys {
+		patch := repo.patches[key]
+
+		filePath := filepath.Join(getPatchesDir(), fmt.Sprintf("werf-%s.patch", uuid.NewV4().String()))
+		if err := linkOrCopyFile(patch.GetFilePath(), filePath); err != nil {
+			return nil, fmt.Errorf("cannot get cached patch: %s", err)
+		}
+
+		patches = append(patches, &cachedPatch{Patch: patch, FilePath: filePath})
+	}
+
+	return patches, nil
+}
+
+func patchOptionsKey(repoName string, opts PatchOptions) string {
+	return util.Sha256Hash(
+		repoName,
+		opts.FromCommit, opts.ToCommit, fmt.Sprintf("%v", opts.FromMergeBase),
+		fmt.Sprintf("%v", opts.WithEntireFileContext), fmt.Sprintf("%v", opts.WithBinary),
+		fmt.Sprintf("%v", opts.DetectRenames), fmt.Sprintf("%v", opts.DetectCopies),
+		filterOptionsKey(opts.FilterOptions), submodulesOptionsKey(opts.SubmodulesOptions),
+	)
+}
+
+func (repo *CachedGitRepo) CreateArchive(ctx context.Context, opts ArchiveOptions) (Archive, error) {
+	key := util.Sha256Hash(
+		repo.GetName(),
+		opts.Commit, fmt.Sprintf("%v", opts.Deterministic),
+		filterOptionsKey(opts.FilterOptions), submodulesOptionsKey(opts.SubmodulesOptions),
+	)
+
+	archive, ok := repo.archives[key]
+	if !ok {
+		newArchive, err := repo.GitRepo.CreateArchive(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		cacheFilePath := filepath.Join(repo.CacheDir, fmt.Sprintf("%s.archive.tar", key))
+		if err := moveFile(newArchive.GetFilePath(), cacheFilePath); err != nil {
+			return nil, fmt.Errorf("cannot cache archive: %s", err)
+		}
+
+		archive = &cachedArchive{Archive: newArchive, FilePath: cacheFilePath}
+		repo.archives[key] = archive
+	}
+
+	filePath := filepath.Join(getArchivesDir(), fmt.Sprintf("werf-%s.archive.tar", uuid.NewV4().String()))
+	if err := linkOrCopyFile(archive.GetFilePath(), filePath); err != nil {
+		return nil, fmt.Errorf("cannot get cached archive: %s", err)
+	}
+
+	return &cachedArchive{Archive: archive, FilePath: filePath}, nil
+}
+
+func filterOptionsKey(opts FilterOptions) string {
+	return strings.Join([]string{
+		opts.BasePath,
+		strings.Join(opts.IncludePaths, ":"),
+		strings.Join(opts.ExcludePaths, ":"),
+	}, ":::")
+}
+
+func submodulesOptionsKey(opts SubmodulesOptions) string {
+	var revisionsPaths []string
+	for path := range opts.SubmodulesRevisions {
+		revisionsPaths = append(revisionsPaths, path)
+	}
+	sort.Strings(revisionsPaths)
+
+	var revisions []string
+	for _, path := range revisionsPaths {
+		revisions = append(revisions, fmt.Sprintf("%s=%s", path, opts.SubmodulesRevisions[path]))
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf("%v", opts.SkipSubmodules),
+		strings.Join(opts.IncludeSubmodules, ":"),
+		strings.Join(revisions, ":"),
+	}, ":::")
+}
+
+func moveFile(fromPath, toPath string) error {
+	if err := os.MkdirAll(filepath.Dir(toPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(fromPath, toPath); err == nil {
+		return nil
+	}
+
+	if err := copyFile(fromPath, toPath); err != nil {
+		return err
+	}
+
+	return os.Remove(fromPath)
+}
+
+func linkOrCopyFile(fromPath, toPath string) error {
+	if err := os.Link(fromPath, toPath); err == nil {
+		return nil
+	}
+
+	return copyFile(fromPath, toPath)
+}
+
+func copyFile(fromPath, toPath string) error {
+	from, err := os.Open(fromPath)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+
+	to, err := os.OpenFile(toPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(to, from); err != nil {
+		to.Close()
+		return err
+	}
+
+	return to.Close()
+}
@@ -0,0 +1,96 @@
+package git_repo
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+)
+
+// AllowCaseCollisions makes werf only warn about commit paths, which differ only by case, on case-insensitive filesystems
+var AllowCaseCollisions bool
+
+// isCaseInsensitiveFilesystem reports whether work trees are created on the filesystem,
+// which is case-insensitive by default, so only one of the colliding files is checked out
+func isCaseInsensitiveFilesystem() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+// checkCaseCollisions fails if commit files, which satisfy path filter, differ only by case and the work tree filesystem is case-insensitive.
+// Collisions are reported as a warning on other hosts or when AllowCaseCollisions is set.
+func checkCaseCollisions(commit *object.Commit, pathFilter true_git.PathFilter) error {
+	collisions, err := findCaseCollisions(commit, pathFilter)
+	if err != nil {
+		return fmt.Errorf("cannot check commit `%s` paths case collisions: %s", commit.Hash, err)
+	}
+
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, paths := range collisions {
+		lines = append(lines, fmt.Sprintf("  %s", strings.Join(paths, ", ")))
+	}
+	desc := fmt.Sprintf("commit `%s` contains paths, which differ only by case:\n%s", commit.Hash, strings.Join(lines, "\n"))
+
+	if !isCaseInsensitiveFilesystem() {
+		logger.LogWarningF("WARNING: %s\nOnly one of these files is checked out on case-insensitive filesystems (macOS, Windows)\n", desc)
+		return nil
+	}
+
+	if AllowCaseCollisions {
+		logger.LogWarningF("WARNING: %s\nOnly one of these files is added to the archive on the case-insensitive filesystem\n", desc)
+		return nil
+	}
+
+	return fmt.Errorf("%s\nOnly one of these files can be checked out on the case-insensitive filesystem: rename files or use --allow-case-collisions option to continue without other files", desc)
+}
+
+// findCaseCollisions returns groups of commit files paths, which are equal ignoring case
+func findCaseCollisions(commit *object.Commit, pathFilter true_git.PathFilter) ([][]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	// tree walker does not read blobs, which could be missing in the partial clone
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	pathsByLowerPath := map[string][]string{}
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !entry.Mode.IsFile() || !pathFilter.IsFilePathValid(name) {
+			continue
+		}
+
+		lowerPath := strings.ToLower(name)
+		pathsByLowerPath[lowerPath] = append(pathsByLowerPath[lowerPath], name)
+	}
+
+	var res [][]string
+	for _, paths := range pathsByLowerPath {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			res = append(res, paths)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+
+	return res, nil
+}
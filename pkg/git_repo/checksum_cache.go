@@ -0,0 +1,100 @@
+package git_repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// GIT_CHECKSUM_CACHE_VERSION should be bumped when checksum algorithm is changed to invalidate all cached checksums
+const GIT_CHECKSUM_CACHE_VERSION = "1"
+
+func GetBaseChecksumCacheDir() string {
+	return filepath.Join(werf.GetHomeDir(), "git", "checksums", GIT_CHECKSUM_CACHE_VERSION)
+}
+
+// CachedChecksum is a checksum loaded from the persistent checksum cache
+type CachedChecksum struct {
+	Checksum     string   `json:"checksum"`
+	NoMatchPaths []string `json:"noMatchPaths"`
+}
+
+func (c *CachedChecksum) String() string {
+	return c.Checksum
+}
+
+func (c *CachedChecksum) GetNoMatchPaths() []string {
+	return c.NoMatchPaths
+}
+
+// checksumCacheKey identifies checksum by commit tree hash and checksum options:
+// commits with the same tree have the same files, so checksum is reused across commits, branches and repos clones
+func checksumCacheKey(treeHash string, opts ChecksumOptions) string {
+	args := []string{
+		treeHash,
+		strings.Join(opts.Paths, ":"),
+		filterOptionsKey(opts.FilterOptions), submodulesOptionsKey(opts.SubmodulesOptions),
+	}
+
+	// keys of checksums with default path options are kept unchanged
+	if !opts.ChecksumPathOptions.isDefault() {
+		args = append(args, fmt.Sprintf("%+v", opts.ChecksumPathOptions))
+	}
+
+	return util.Sha256Hash(args...)
+}
+
+func checksumCacheFilePath(key string) string {
+	return filepath.Join(GetBaseChecksumCacheDir(), key[:2], fmt.Sprintf("%s.json", key))
+}
+
+// getCachedChecksum returns nil if checksum is not cached, broken cache files are ignored
+func getCachedChecksum(key string) *CachedChecksum {
+	data, err := ioutil.ReadFile(checksumCacheFilePath(key))
+	if err != nil {
+		return nil
+	}
+
+	checksum := &CachedChecksum{}
+	if err := json.Unmarshal(data, checksum); err != nil || checksum.Checksum == "" {
+		return nil
+	}
+
+	return checksum
+}
+
+func putCachedChecksum(key string, checksum Checksum) error {
+	data, err := json.Marshal(&CachedChecksum{
+		Checksum:     checksum.String(),
+		NoMatchPaths: checksum.GetNoMatchPaths(),
+	})
+	if err != nil {
+		return err
+	}
+
+	filePath := checksumCacheFilePath(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	// file is written atomically, because the cache is shared between concurrent werf processes
+	tmpFilePath := fmt.Sprintf("%s.%s.tmp", filePath, uuid.NewV4().String())
+	if err := ioutil.WriteFile(tmpFilePath, data, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFilePath, filePath); err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package git_repo
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// GetClonesDir is a dir of remote git repos clones, which are shared between projects
+func GetClonesDir() string {
+	return filepath.Join(werf.GetHomeDir(), "git", "clones", fmt.Sprintf("%v", RemoteGitRepoCacheVersion))
+}
+
+// GetClonePath returns path of the remote git repo clone keyed by the normalized url,
+// so projects referencing the same repo use the same clone. Partial and full clones are kept separately.
+func GetClonePath(url string, partialClone bool) (string, error) {
+	normalizedUrl, err := NormalizeUrl(url)
+	if err != nil {
+		return "", err
+	}
+
+	name := util.Sha256Hash(normalizedUrl)
+	if partialClone {
+		name += "_partial"
+	}
+
+	return filepath.Join(GetClonesDir(), name), nil
+}
+
+// NormalizeUrl returns the url without user, password and .git suffix with the lowercase host,
+// scp-like url git@host:path is converted to ssh://host/path
+func NormalizeUrl(url string) (string, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return "", fmt.Errorf("bad endpoint url `%s`: %s", url, err)
+	}
+
+	host := strings.ToLower(ep.Host)
+	if ep.Port != 0 {
+		host = fmt.Sprintf("%s:%d", host, ep.Port)
+	}
+
+	path := strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git")
+
+	return fmt.Sprintf("%s://%s/%s", ep.Protocol, host, path), nil
+}
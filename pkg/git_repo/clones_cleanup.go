@@ -0,0 +1,259 @@
+package git_repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/flant/werf/pkg/home_usage"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// ClonesCleanupOptions are retention options of the remote git repos clones, which are shared between projects
+type ClonesCleanupOptions struct {
+	// MaxAge removes clones, which were not used longer than MaxAge, 0 disables removal by age
+	MaxAge time.Duration
+	// MaxSize removes least recently used clones until total size of the clones fits into MaxSize, 0 disables removal by size
+	MaxSize int64
+	DryRun  bool
+}
+
+// cloneLockName is the lock name of the clone, which is used by clone, fetch and removal of the clone.
+// Interrupted clone (see partialClonePath) is locked by the same lock as the clone.
+func cloneLockName(clonePath string) string {
+	return fmt.Sprintf("remote_git_clone.%s", strings.TrimSuffix(filepath.Base(clonePath), ".partial"))
+}
+
+// CleanupClones evicts clones of remote git repos, which were not used longer than MaxAge,
+// and least recently used clones until total size of clones fits into MaxSize.
+// Clones are werf home usage entries, so clones are evicted the same way as by werf home quota: with the clone lock held,
+// clones used within home_usage.MinIdleTimeToEvict are kept. Per-project clones of older werf versions are evicted too.
+func CleanupClones(opts ClonesCleanupOptions) error {
+	entries, err := getClonesEntries()
+	if err != nil {
+		return err
+	}
+
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size
+	}
+
+	logger.LogInfoF("Remote git repos clones %s: %d clones, %s\n", GetClonesDir(), len(entries), units.HumanSize(float64(totalSize)))
+
+	for _, entry := range entries {
+		isExpired := opts.MaxAge != 0 && time.Since(entry.LastUsed) > opts.MaxAge
+		isOverSize := opts.MaxSize != 0 && totalSize > opts.MaxSize
+
+		if !isExpired && !isOverSize {
+			continue
+		}
+
+		if time.Since(entry.LastUsed) < home_usage.MinIdleTimeToEvict {
+			continue
+		}
+
+		evicted, err := home_usage.EvictEntry(entry, opts.DryRun)
+		if err != nil {
+			logger.LogWarningF("WARNING: Unable to evict %s %s: %s\n", entry.Kind, entry.Path, err)
+			continue
+		}
+
+		if evicted {
+			totalSize -= entry.Size
+		}
+	}
+
+	if opts.MaxSize != 0 && totalSize > opts.MaxSize {
+		logger.LogWarningF("WARNING: Remote git repos clones size %s still exceeds %s: clones used during the last %s are not removed\n", units.HumanSize(float64(totalSize)), units.HumanSize(float64(opts.MaxSize)), home_usage.MinIdleTimeToEvict)
+	}
+
+	return nil
+}
+
+// getClonesEntries returns tracked clones, untracked clones of the clones dir (created before usage tracking)
+// and legacy per-project clones sorted from least to most recently used
+func getClonesEntries() ([]*home_usage.Entry, error) {
+	usageEntries, err := home_usage.GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*home_usage.Entry
+	trackedPaths := map[string]bool{}
+	for _, entry := range usageEntries {
+		if entry.Kind == home_usage.GitRepoCloneKind {
+			entries = append(entries, entry)
+			trackedPaths[entry.Path] = true
+		}
+	}
+
+	untrackedEntries, err := getUntrackedClonesEntries(trackedPaths)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, untrackedEntries...)
+
+	legacyEntries, err := getLegacyClonesEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, legacyEntries...)
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.Before(entries[j].LastUsed) })
+
+	return entries, nil
+}
+
+func getUntrackedClonesEntries(trackedPaths map[string]bool) ([]*home_usage.Entry, error) {
+	clonesDir, err := filepath.Abs(GetClonesDir())
+	if err != nil {
+		return nil, err
+	}
+
+	finfos, err := ioutil.ReadDir(clonesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to list clones in %s: %s", clonesDir, err)
+	}
+
+	var entries []*home_usage.Entry
+	for _, finfo := range finfos {
+		path := filepath.Join(clonesDir, finfo.Name())
+		if !finfo.IsDir() || trackedPaths[path] {
+			continue
+		}
+
+		entry, err := newUntrackedCloneEntry(home_usage.GitRepoCloneKind, path, cloneLockName(path))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// getLegacyClonesEntries returns per-project clones of older werf versions, which have not been migrated to the shared clones dir.
+// Migration moves the legacy clone with the lock of the shared clone held, so the legacy clone is evicted with the same lock.
+func getLegacyClonesEntries() ([]*home_usage.Entry, error) {
+	version := fmt.Sprintf("%v", RemoteGitRepoCacheVersion)
+	patterns := []string{
+		filepath.Join(werf.GetHomeDir(), "builds", "*", "remote_git_repo", version, "*", "*"),
+		filepath.Join(werf.GetHomeDir(), "own_git_repo", version, "*"),
+	}
+
+	var entries []*home_usage.Entry
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			if exists, err := util.DirExists(path); err != nil {
+				return nil, err
+			} else if !exists {
+				continue
+			}
+
+			lockName, err := legacyCloneLockName(path)
+			if err != nil {
+				logger.LogWarningF("WARNING: Skipping legacy clone %s: %s\n", path, err)
+				continue
+			}
+
+			entry, err := newUntrackedCloneEntry(home_usage.LegacyGitRepoCloneKind, path, lockName)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// RemoveProjectLegacyClones removes per-project clones of older werf versions from the project build dir.
+// Each clone is removed with the lock of the shared clone held, so the clone is not removed during migration.
+func RemoveProjectLegacyClones(projectBuildDir string, dryRun bool) error {
+	paths, err := filepath.Glob(filepath.Join(projectBuildDir, "remote_git_repo", fmt.Sprintf("%v", RemoteGitRepoCacheVersion), "*", "*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		remove := func() error {
+			if dryRun {
+				fmt.Println(path)
+				return nil
+			}
+
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("unable to remove %s: %s", path, err)
+			}
+
+			return nil
+		}
+
+		// broken clone without origin cannot be migrated, so it is removed without lock
+		lockName, err := legacyCloneLockName(path)
+		if err != nil {
+			if err := remove(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, remove); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyCloneLockName returns lock name of the shared clone, which the legacy clone is migrated to
+func legacyCloneLockName(legacyClonePath string) (string, error) {
+	originUrl, err := (&Base{}).remoteOriginUrl(legacyClonePath)
+	if err != nil {
+		return "", err
+	}
+
+	if originUrl == "" {
+		return "", fmt.Errorf("origin remote not found")
+	}
+
+	clonePath, err := GetClonePath(originUrl, true_git.IsPartialClone(legacyClonePath))
+	if err != nil {
+		return "", err
+	}
+
+	return cloneLockName(clonePath), nil
+}
+
+// newUntrackedCloneEntry makes cache entry of the clone without usage record: modification time of the clone dir is used as last usage time
+func newUntrackedCloneEntry(kind, path, lockName string) (*home_usage.Entry, error) {
+	finfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := util.DirSize(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate size of %s: %s", path, err)
+	}
+
+	return &home_usage.Entry{Kind: kind, Path: path, LockName: lockName, LastUsed: finfo.ModTime(), Size: size}, nil
+}
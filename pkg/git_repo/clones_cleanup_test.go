@@ -0,0 +1,183 @@
+package git_repo
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flant/werf/pkg/home_usage"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+func initTestClone(t *testing.T, path string, lastUsed time.Time) {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "--bare", "--quiet", path},
+		{"--git-dir", path, "remote", "add", "origin", "https://example.com/group/project.git"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s\n%s", args, err, output)
+		}
+	}
+
+	if err := os.Chtimes(path, lastUsed, lastUsed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanupClones(t *testing.T) {
+	homeDir, err := ioutil.TempDir("", "werf-clones-cleanup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	if err := werf.Init(homeDir, homeDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-2 * time.Hour)
+
+	trackedExpired := filepath.Join(GetClonesDir(), "tracked_expired")
+	trackedRecent := filepath.Join(GetClonesDir(), "tracked_recent")
+	untrackedExpired := filepath.Join(GetClonesDir(), "untracked_expired")
+	untrackedRecent := filepath.Join(GetClonesDir(), "untracked_recent")
+	legacyExpired := filepath.Join(homeDir, "builds", "project", "remote_git_repo", "4", "project", "https")
+	legacyOwnRecent := filepath.Join(homeDir, "own_git_repo", "4", "project")
+
+	for path, lastUsed := range map[string]time.Time{
+		trackedExpired:   expired,
+		trackedRecent:    recent,
+		untrackedExpired: expired,
+		untrackedRecent:  recent,
+		legacyExpired:    expired,
+		legacyOwnRecent:  recent,
+	} {
+		initTestClone(t, path, lastUsed)
+	}
+
+	for path, lastUsed := range map[string]time.Time{trackedExpired: expired, trackedRecent: recent} {
+		if err := home_usage.MarkUsed(home_usage.GitRepoCloneKind, path, cloneLockName(path)); err != nil {
+			t.Fatal(err)
+		}
+
+		// usage record is the last usage time of the tracked clone, clone dir time is ignored
+		if err := os.Chtimes(path, time.Now(), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		recordPath := filepath.Join(home_usage.GetUsageRecordsDir(), util.Sha256Hash(path))
+		if err := os.Chtimes(recordPath, lastUsed, lastUsed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := getClonesEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("\n[EXPECTED]: %#v\n[GOT]: %#v", 6, len(entries))
+	}
+
+	if err := CleanupClones(ClonesCleanupOptions{MaxAge: 24 * time.Hour, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{trackedExpired, trackedRecent, untrackedExpired, untrackedRecent, legacyExpired, legacyOwnRecent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s: %s", path, err)
+		}
+	}
+
+	if err := CleanupClones(ClonesCleanupOptions{MaxAge: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expectKept := range map[string]bool{
+		trackedExpired:   false,
+		trackedRecent:    true,
+		untrackedExpired: false,
+		untrackedRecent:  true,
+		legacyExpired:    false,
+		legacyOwnRecent:  true,
+	} {
+		_, err := os.Stat(path)
+		if kept := err == nil; kept != expectKept {
+			t.Errorf("%s\n[EXPECTED]: kept %#v\n[GOT]: kept %#v", path, expectKept, kept)
+		}
+	}
+
+	if err := CleanupClones(ClonesCleanupOptions{MaxSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{trackedRecent, untrackedRecent, legacyOwnRecent} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("least recently used clone %s is not removed by size", path)
+		}
+	}
+}
+
+func TestRemoveProjectLegacyClones(t *testing.T) {
+	homeDir, err := ioutil.TempDir("", "werf-clones-cleanup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	if err := werf.Init(homeDir, homeDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	projectBuildDir := filepath.Join(homeDir, "builds", "project")
+	legacyClone := filepath.Join(projectBuildDir, "remote_git_repo", "4", "project", "https")
+	brokenLegacyClone := filepath.Join(projectBuildDir, "remote_git_repo", "4", "broken", "https")
+	otherProjectLegacyClone := filepath.Join(homeDir, "builds", "other", "remote_git_repo", "4", "project", "https")
+
+	initTestClone(t, legacyClone, time.Now())
+	initTestClone(t, otherProjectLegacyClone, time.Now())
+	if err := os.MkdirAll(brokenLegacyClone, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveProjectLegacyClones(projectBuildDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{legacyClone, brokenLegacyClone, otherProjectLegacyClone} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s: %s", path, err)
+		}
+	}
+
+	if err := RemoveProjectLegacyClones(projectBuildDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expectKept := range map[string]bool{
+		legacyClone:             false,
+		brokenLegacyClone:       false,
+		otherProjectLegacyClone: true,
+	} {
+		_, err := os.Stat(path)
+		if kept := err == nil; kept != expectKept {
+			t.Errorf("%s\n[EXPECTED]: kept %#v\n[GOT]: kept %#v", path, expectKept, kept)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package git_repo
+
+import (
+	"strings"
+	"time"
+)
+
+// CommitInfo is a metadata of the commit, which can be used to stamp provenance of the built images
+type CommitInfo struct {
+	Hash      string
+	Message   string
+	Author    Signature
+	Committer Signature
+}
+
+type Signature struct {
+	Name  string
+	Email string
+	Time  time.Time
+}
+
+// Subject returns the first line of the commit message
+func (c *CommitInfo) Subject() string {
+	return strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+}
+
+func (repo *Base) commitInfo(repoPath, commit string) (*CommitInfo, error) {
+	commitObj, err := repo.getCommitObject(repoPath, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommitInfo{
+		Hash:      commitObj.Hash.String(),
+		Message:   commitObj.Message,
+		Author:    Signature{Name: commitObj.Author.Name, Email: commitObj.Author.Email, Time: commitObj.Author.When},
+		Committer: Signature{Name: commitObj.Committer.Name, Email: commitObj.Committer.Email, Time: commitObj.Committer.When},
+	}, nil
+}
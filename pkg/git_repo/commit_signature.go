@@ -0,0 +1,57 @@
+package git_repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommitSignatureError means that commit is not signed or is signed by the key, which is not in the trusted keyrings
+type CommitSignatureError struct {
+	Commit string
+	Reason string
+}
+
+func (e *CommitSignatureError) Error() string {
+	return fmt.Sprintf("commit %s signature verification failed: %s", e.Commit, e.Reason)
+}
+
+// verifyCommitSignature checks GPG signature of the commit with each of armored keyrings
+// and returns identity of the key the commit is signed by
+func (repo *Base) verifyCommitSignature(repoPath, commit string, armoredKeyRings []string) (string, error) {
+	commitObj, err := repo.getCommitObject(repoPath, commit)
+	if err != nil {
+		return "", err
+	}
+
+	if commitObj.PGPSignature == "" {
+		return "", &CommitSignatureError{Commit: commit, Reason: "commit is not signed"}
+	}
+
+	if len(armoredKeyRings) == 0 {
+		return "", &CommitSignatureError{Commit: commit, Reason: "no trusted keyrings specified"}
+	}
+
+	var errs []string
+	for _, keyRing := range armoredKeyRings {
+		entity, err := commitObj.Verify(keyRing)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		var identities []string
+		for name := range entity.Identities {
+			identities = append(identities, name)
+		}
+		sort.Strings(identities)
+
+		if len(identities) == 0 {
+			return entity.PrimaryKey.KeyIdString(), nil
+		}
+
+		return fmt.Sprintf("%s (%s)", identities[0], entity.PrimaryKey.KeyIdString()), nil
+	}
+
+	return "", &CommitSignatureError{Commit: commit, Reason: fmt.Sprintf("signature does not match trusted keys: %s", strings.Join(errs, "; "))}
+}
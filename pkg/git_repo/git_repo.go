@@ -1,29 +1,78 @@
 package git_repo
 
+import (
+	"context"
+
+	"github.com/flant/werf/pkg/true_git"
+)
+
 type PatchOptions struct {
 	FilterOptions
+	SubmodulesOptions
 	FromCommit, ToCommit string
 
+	// FromMergeBase creates patch from the merge-base of FromCommit and ToCommit, like `git diff FROM...TO`
+	FromMergeBase bool
+
 	WithEntireFileContext bool
 	WithBinary            bool
+	DetectRenames         bool
+	DetectCopies          bool
 }
 
 type ArchiveOptions struct {
 	FilterOptions
+	SubmodulesOptions
 	Commit string
+
+	// Deterministic makes archives of the same tree byte-identical: entries get commit time and normalized permissions and owner
+	Deterministic bool
 }
 
 type ChecksumOptions struct {
 	FilterOptions
+	SubmodulesOptions
+	ChecksumPathOptions
 	Paths  []string
 	Commit string
 }
 
+// ChecksumPathOptions change how files of the checksum paths are hashed, zero value keeps the default behaviour
+type ChecksumPathOptions struct {
+	// DereferenceSymlinks hashes content of the symlink target file instead of the link path,
+	// target should be inside the work tree
+	DereferenceSymlinks bool
+	// Xattrs hashes extended attributes of files (supported on linux only)
+	Xattrs bool
+	// IgnoreMode excludes file mode bits from the checksum
+	IgnoreMode bool
+}
+
+func (opts ChecksumPathOptions) isDefault() bool {
+	return opts == ChecksumPathOptions{}
+}
+
 type FilterOptions struct {
 	BasePath                   string
 	IncludePaths, ExcludePaths []string
 }
 
+type SubmodulesOptions struct {
+	SkipSubmodules      bool
+	IncludeSubmodules   []string
+	SubmodulesRevisions map[string]string
+	// SubmodulesRevisionsSources are repos to fetch SubmodulesRevisions from, when revisions are not available in the submodules origins
+	SubmodulesRevisionsSources map[string]string
+}
+
+func (opts SubmodulesOptions) toTrueGitSubmodulesOptions() true_git.SubmodulesOptions {
+	return true_git.SubmodulesOptions{
+		Include:          opts.IncludeSubmodules,
+		Revisions:        opts.SubmodulesRevisions,
+		RevisionsSources: opts.SubmodulesRevisionsSources,
+	}
+}
+
 type ArchiveType string
 
 const (
@@ -42,11 +91,17 @@ type GitRepo interface {
 	LatestBranchCommit(branch string) (string, error)
 	LatestTagCommit(tag string) (string, error)
 	IsCommitExists(commit string) (bool, error)
+	ResolveCommit(ctx context.Context, revision string) (string, error)
 	FindCommitIdByMessage(regex string) (string, error)
+	VerifyCommitSignature(commit string, armoredKeyRings []string) (string, error)
+	CommitInfo(commit string) (*CommitInfo, error)
+	MergeBase(ctx context.Context, commit1, commit2 string) (string, error)
 
-	CreatePatch(PatchOptions) (Patch, error)
-	CreateArchive(ArchiveOptions) (Archive, error)
-	Checksum(ChecksumOptions) (Checksum, error)
+	CreatePatch(context.Context, PatchOptions) (Patch, error)
+	// CreatePatches creates patches for multiple filters of the same commits range, patches are returned in the options order
+	CreatePatches(context.Context, []PatchOptions) ([]Patch, error)
+	CreateArchive(context.Context, ArchiveOptions) (Archive, error)
+	Checksum(context.Context, ChecksumOptions) (Checksum, error)
 }
 
 type Patch interface {
@@ -1,14 +1,18 @@
 package git_repo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+
+	"github.com/flant/werf/pkg/true_git"
 )
 
 type Local struct {
@@ -45,22 +49,67 @@ func (repo *Local) HeadBranchName() (string, error) {
 	return repo.getHeadBranchName(repo.Path)
 }
 
-func (repo *Local) CreatePatch(opts PatchOptions) (Patch, error) {
-	return repo.createPatch(repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
+func (repo *Local) CreatePatch(ctx context.Context, opts PatchOptions) (Patch, error) {
+	return repo.createPatch(ctx, repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
 }
 
-func (repo *Local) CreateArchive(opts ArchiveOptions) (Archive, error) {
-	return repo.createArchive(repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
+func (repo *Local) CreatePatches(ctx context.Context, optsList []PatchOptions) ([]Patch, error) {
+	if len(optsList) == 0 {
+		return nil, nil
+	}
+	return repo.createPatches(ctx, repo.Path, repo.GitDir, repo.getWorkTreeDir(), optsList)
 }
 
-func (repo *Local) Checksum(opts ChecksumOptions) (Checksum, error) {
-	return repo.checksum(repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
+func (repo *Local) CreateArchive(ctx context.Context, opts ArchiveOptions) (Archive, error) {
+	return repo.createArchive(ctx, repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
+}
+
+func (repo *Local) Checksum(ctx context.Context, opts ChecksumOptions) (Checksum, error) {
+	return repo.checksum(ctx, repo.Path, repo.GitDir, repo.getWorkTreeDir(), opts)
 }
 
 func (repo *Local) IsCommitExists(commit string) (bool, error) {
 	return repo.isCommitExists(repo.Path, commit)
 }
 
+// ResolveCommit returns full hash of the commit specified by the full or abbreviated hash, branch, tag or an expression like HEAD~2
+func (repo *Local) ResolveCommit(ctx context.Context, revision string) (string, error) {
+	return repo.resolveCommit(ctx, repo.GitDir, revision, nil)
+}
+
+func (repo *Local) CommitInfo(commit string) (*CommitInfo, error) {
+	return repo.commitInfo(repo.Path, commit)
+}
+
+func (repo *Local) VerifyCommitSignature(commit string, armoredKeyRings []string) (string, error) {
+	return repo.verifyCommitSignature(repo.Path, commit, armoredKeyRings)
+}
+
+func (repo *Local) MergeBase(ctx context.Context, commit1, commit2 string) (string, error) {
+	return repo.mergeBase(ctx, repo.GitDir, commit1, commit2)
+}
+
+// LatestBranchCommit returns commit of the local branch or, if there is no such local branch, of the origin remote branch
+func (repo *Local) LatestBranchCommit(branch string) (string, error) {
+	rawRepo, err := git.PlainOpen(repo.Path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open repo: %s", err)
+	}
+
+	for _, refName := range []string{fmt.Sprintf("refs/heads/%s", branch), fmt.Sprintf("refs/remotes/origin/%s", branch)} {
+		ref, err := rawRepo.Reference(plumbing.ReferenceName(refName), true)
+		if err == plumbing.ErrReferenceNotFound {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("cannot resolve reference `%s`: %s", refName, err)
+		}
+
+		return ref.Hash().String(), nil
+	}
+
+	return "", fmt.Errorf("unknown branch `%s` of repo `%s`", branch, repo.String())
+}
+
 func (repo *Local) TagsList() ([]string, error) {
 	return repo.tagsList(repo.Path)
 }
@@ -69,6 +118,47 @@ func (repo *Local) RemoteBranchesList() ([]string, error) {
 	return repo.remoteBranchesList(repo.Path)
 }
 
+// TagsRefs returns lightweight and annotated tags with commits, which they point to
+func (repo *Local) TagsRefs() ([]*Ref, error) {
+	return repo.tagsRefs(repo.Path)
+}
+
+// RemoteBranchesRefs returns branches of origin remote with their latest commits
+func (repo *Local) RemoteBranchesRefs() ([]*Ref, error) {
+	return repo.remoteBranchesRefs(repo.Path)
+}
+
+// RecentCommitsList returns commits of local and remote branches, which were committed after the since time
+func (repo *Local) RecentCommitsList(since time.Time) ([]string, error) {
+	return repo.recentCommitsList(repo.Path, since)
+}
+
+// IsCommitFileExists checks file existence in the commit without work tree checkout
+func (repo *Local) IsCommitFileExists(commit, path string) (bool, error) {
+	return repo.isCommitFileExists(repo.Path, commit, path)
+}
+
+// ReadCommitFile reads file content from the commit without work tree checkout
+func (repo *Local) ReadCommitFile(commit, path string) ([]byte, error) {
+	return repo.readCommitFile(repo.Path, commit, path)
+}
+
+// CommitFilesList returns paths of all files in the commit located under the dir
+func (repo *Local) CommitFilesList(commit, dir string) ([]string, error) {
+	return repo.commitFilesList(repo.Path, commit, dir)
+}
+
+// DivergedSubmodules returns submodules of the project work tree, which are checked out at another commit than recorded in the commit
+// or have uncommitted changes
+func (repo *Local) DivergedSubmodules(ctx context.Context, commit string) ([]*true_git.SubmoduleWorkTreeState, error) {
+	return true_git.DivergedSubmodules(ctx, repo.GitDir, repo.Path, commit)
+}
+
+// GetWorkTreeDir returns werf home dir, where work tree of the local repo is created to make archives and patches
+func (repo *Local) GetWorkTreeDir() string {
+	return repo.getWorkTreeDir()
+}
+
 func (repo *Local) getWorkTreeDir() string {
 	pathParts := make([]string, 0)
 
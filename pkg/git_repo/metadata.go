@@ -0,0 +1,49 @@
+package git_repo
+
+import (
+	"fmt"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+// Metadata describes HEAD of the local git repo
+type Metadata struct {
+	// Branch is empty if HEAD is detached
+	Branch string
+	// Tag is one of the tags pointing to HEAD commit or empty string
+	Tag        string
+	Commit     string
+	CommitTime time.Time
+}
+
+func (repo *Local) HeadMetadata() (*Metadata, error) {
+	ref, err := repo.getReferenceForRepo(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get repo `%s` head ref: %s", repo.Path, err)
+	}
+
+	metadata := &Metadata{Commit: ref.Hash().String()}
+
+	if ref.Name().IsBranch() {
+		metadata.Branch = ref.Name().Short()
+	}
+
+	metadata.Tag, err = repo.findTagByCommitID(repo.Path, ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("cannot get repo `%s` head tag: %s", repo.Path, err)
+	}
+
+	repository, err := git.PlainOpen(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo `%s`: %s", repo.Path, err)
+	}
+
+	commit, err := repository.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("cannot get repo `%s` commit %s: %s", repo.Path, metadata.Commit, err)
+	}
+	metadata.CommitTime = commit.Committer.When
+
+	return metadata, nil
+}
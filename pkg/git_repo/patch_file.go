@@ -14,7 +14,7 @@ type PatchFile struct {
 }
 
 func NewTmpPatchFile() *PatchFile {
-	path := filepath.Join("/tmp", fmt.Sprintf("werf-%s.patch", uuid.NewV4().String()))
+	path := filepath.Join(getPatchesDir(), fmt.Sprintf("werf-%s.patch", uuid.NewV4().String()))
 	return &PatchFile{FilePath: path}
 }
 
@@ -0,0 +1,154 @@
+package git_repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const remoteBranchRefPrefix = "refs/remotes/origin/"
+
+// Ref is a branch or a tag of the repo with the commit, which it points to
+type Ref struct {
+	// Name is a short name of the branch or the tag, FullName is a git reference name
+	Name     string `json:"name"`
+	FullName string `json:"fullName"`
+
+	Commit        string    `json:"commit"`
+	AuthorTime    time.Time `json:"authorTime"`
+	CommitterTime time.Time `json:"committerTime"`
+
+	// Tag is set for annotated tags only
+	Tag *AnnotatedTag `json:"annotatedTag,omitempty"`
+}
+
+// AnnotatedTag is a tag object, which the annotated tag reference points to, the tag target is peeled to the Ref commit
+type AnnotatedTag struct {
+	Hash        string    `json:"hash"`
+	Target      string    `json:"target"`
+	TargetType  string    `json:"targetType"`
+	TaggerName  string    `json:"taggerName"`
+	TaggerEmail string    `json:"taggerEmail"`
+	TaggerTime  time.Time `json:"taggerTime"`
+	Message     string    `json:"message"`
+}
+
+// tagsRefs returns lightweight and annotated tags sorted by name, tags, which do not point to a commit, are skipped
+func (repo *Base) tagsRefs(repoPath string) ([]*Ref, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
+	}
+
+	tags, err := repository.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Ref, 0)
+	err = tags.ForEach(func(r *plumbing.Reference) error {
+		ref := &Ref{Name: r.Name().Short(), FullName: r.Name().String()}
+
+		commitHash := r.Hash()
+
+		tagObj, err := repository.TagObject(r.Hash())
+		switch err {
+		case nil:
+			ref.Tag = &AnnotatedTag{
+				Hash:        tagObj.Hash.String(),
+				Target:      tagObj.Target.String(),
+				TargetType:  tagObj.TargetType.String(),
+				TaggerName:  tagObj.Tagger.Name,
+				TaggerEmail: tagObj.Tagger.Email,
+				TaggerTime:  tagObj.Tagger.When,
+				Message:     tagObj.Message,
+			}
+
+			commitObj, err := tagObj.Commit()
+			if err == object.ErrUnsupportedObject {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("bad target `%s` of tag `%s`: %s", tagObj.Target, ref.Name, err)
+			}
+			commitHash = commitObj.Hash
+		case plumbing.ErrObjectNotFound:
+		default:
+			return fmt.Errorf("bad tag `%s`: %s", ref.Name, err)
+		}
+
+		if err := setRefCommit(repository, ref, commitHash); err != nil {
+			return err
+		}
+
+		res = append(res, ref)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// remoteBranchesRefs returns branches of origin remote sorted by name
+func (repo *Base) remoteBranchesRefs(repoPath string) ([]*Ref, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
+	}
+
+	refs, err := repository.References()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Ref, 0)
+	err = refs.ForEach(func(r *plumbing.Reference) error {
+		refName := r.Name().String()
+		if r.Type() != plumbing.HashReference || !strings.HasPrefix(refName, remoteBranchRefPrefix) {
+			return nil
+		}
+
+		name := strings.TrimPrefix(refName, remoteBranchRefPrefix)
+		if name == "HEAD" {
+			return nil
+		}
+
+		ref := &Ref{Name: name, FullName: refName}
+		if err := setRefCommit(repository, ref, r.Hash()); err != nil {
+			return err
+		}
+
+		res = append(res, ref)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+func setRefCommit(repository *git.Repository, ref *Ref, commitHash plumbing.Hash) error {
+	commitObj, err := repository.CommitObject(commitHash)
+	if err != nil {
+		return fmt.Errorf("bad commit `%s` of reference `%s`: %s", commitHash, ref.FullName, err)
+	}
+
+	ref.Commit = commitObj.Hash.String()
+	ref.AuthorTime = commitObj.Author.When
+	ref.CommitterTime = commitObj.Committer.When
+
+	return nil
+}
@@ -1,27 +1,56 @@
 package git_repo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Masterminds/semver"
+	"github.com/flant/werf/pkg/git_auth"
+	"github.com/flant/werf/pkg/home_usage"
 	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
 	ini "gopkg.in/ini.v1"
-	uuid "gopkg.in/satori/go.uuid.v1"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 )
 
 const RemoteGitRepoCacheVersion = 4
 
+// OrphanedTmpCloneMinAge protects clones of running older werf processes from removal
+const OrphanedTmpCloneMinAge = 24 * time.Hour
+
+// ForceRefresh makes werf check existing clones of remote repos: broken clone is cloned again from scratch,
+// head branch of the valid clone is updated to the current remote default branch after fetch
+var ForceRefresh bool
+
+// SkipFetch makes werf use existing clones of remote repos as is, missing clones are still cloned
+var SkipFetch bool
+
 type Remote struct {
 	Base
 	Url       string
-	ClonePath string // TODO: move CacheVersion & path construction here
+	ClonePath string
 	IsDryRun  bool
+
+	// LegacyClonePath is a per-project clone of older werf versions, which is moved to the shared ClonePath (see GetClonePath)
+	LegacyClonePath string
+
+	// PartialClone enables clone without blobs (filter=blob:none) if git supports it:
+	// blobs of files, which satisfy path filters of archives, patches and checksums, are fetched on demand
+	PartialClone bool
+
+	// credentials are resolved from the werf global config or netrc file on clone and fetch and are never saved into the clone config
+	credentials         *git_auth.Credentials
+	credentialsResolved bool
 }
 
 func (repo *Remote) RemoteOriginUrl() (string, error) {
@@ -40,16 +69,130 @@ func (repo *Remote) IsEmpty() (bool, error) {
 	return repo.isEmpty(repo.ClonePath)
 }
 
-func (repo *Remote) CloneAndFetch() error {
-	isCloned, err := repo.Clone()
+func (repo *Remote) CloneAndFetch(ctx context.Context) error {
+	if !repo.IsDryRun && (werf.IsOffline() || SkipFetch) {
+		exists, err := repo.isCloneExists()
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			if err := repo.withRemoteRepoLock(repo.migrateLegacyClone); err != nil {
+				return err
+			}
+
+			if exists, err = repo.isCloneExists(); err != nil {
+				return err
+			}
+		}
+
+		if exists {
+			logger.LogInfoF("Using clone of remote git repo `%s` without fetch\n", repo.String())
+			return home_usage.MarkUsed(home_usage.GitRepoCloneKind, repo.ClonePath, repo.remoteRepoLockName())
+		}
+
+		if werf.IsOffline() {
+			return fmt.Errorf("clone of remote git repo `%s` not found in %s: %s", repo.String(), repo.ClonePath, werf.OfflineError("clone"))
+		}
+	}
+
+	isCloned, err := repo.Clone(ctx)
 	if err != nil {
 		return err
 	}
-	if isCloned {
+	if !isCloned {
+		if ForceRefresh {
+			if err := repo.refreshClone(ctx); err != nil {
+				return err
+			}
+		} else if err := repo.Fetch(ctx); err != nil {
+			return err
+		}
+	}
+
+	if repo.IsDryRun {
+		return nil
+	}
+
+	return home_usage.MarkUsed(home_usage.GitRepoCloneKind, repo.ClonePath, repo.remoteRepoLockName())
+}
+
+// resolveCredentials gets credentials of the repo url and passes them to git commands of the clone
+func (repo *Remote) resolveCredentials() error {
+	if repo.credentialsResolved {
+		return nil
+	}
+
+	credentials, err := git_auth.GetCredentials(repo.Url)
+	if err != nil {
+		return fmt.Errorf("cannot get credentials of remote git repo `%s`: %s", repo.String(), err)
+	}
+
+	repo.credentials = credentials
+	repo.credentialsResolved = true
+
+	true_git.SetRemoteCredentials(repo.ClonePath, credentials)
+	true_git.SetRemoteCredentials(repo.partialClonePath(), credentials)
+
+	return nil
+}
+
+// auth returns go-git auth method, nil means credentials from the url or no credentials
+func (repo *Remote) auth() transport.AuthMethod {
+	if repo.credentials == nil {
+		return nil
+	}
+
+	return &http.BasicAuth{Username: repo.credentials.Username, Password: repo.credentials.Password}
+}
+
+// refreshClone clones the repo again if the existing clone is broken, otherwise fetches the repo and restores head branch
+func (repo *Remote) refreshClone(ctx context.Context) error {
+	if repo.IsDryRun {
+		return nil
+	}
+
+	var isBroken bool
+	if err := repo.withRemoteRepoLock(func() error {
+		fmt.Printf("Checking clone of remote git repo `%s` ...\n", repo.String())
+
+		if err := true_git.CheckConnectivity(ctx, repo.ClonePath); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+
+			logger.LogWarningF("WARNING: Clone of remote git repo `%s` is broken: %s\nRemoving %s and cloning from scratch\n", repo.String(), err, repo.ClonePath)
+
+			isBroken = true
+
+			if err := os.RemoveAll(repo.ClonePath); err != nil {
+				return fmt.Errorf("unable to remove %s: %s", repo.ClonePath, err)
+			}
+		}
+
 		return nil
+	}); err != nil {
+		return err
+	}
+
+	if isBroken {
+		_, err := repo.Clone(ctx)
+		return err
 	}
 
-	return repo.Fetch()
+	if err := repo.Fetch(ctx); err != nil {
+		return err
+	}
+
+	return repo.withRemoteRepoLock(func() error {
+		if err := restoreCloneHead(repo.ClonePath, repo.auth()); err != nil {
+			return fmt.Errorf("cannot update head branch of repo `%s`: %s", repo.String(), err)
+		}
+
+		fmt.Printf("Checking clone of remote git repo `%s` DONE\n", repo.String())
+
+		return nil
+	})
 }
 
 func (repo *Remote) isCloneExists() (bool, error) {
@@ -65,13 +208,17 @@ func (repo *Remote) isCloneExists() (bool, error) {
 	return false, nil
 }
 
-func (repo *Remote) Clone() (bool, error) {
+func (repo *Remote) Clone(ctx context.Context) (bool, error) {
 	if repo.IsDryRun {
 		return false, nil
 	}
 
 	var err error
 
+	if err := repo.resolveCredentials(); err != nil {
+		return false, err
+	}
+
 	exists, err := repo.isCloneExists()
 	if err != nil {
 		return false, err
@@ -80,7 +227,10 @@ func (repo *Remote) Clone() (bool, error) {
 		return false, nil
 	}
 
-	return true, repo.withRemoteRepoLock(func() error {
+	// clone, which has been created by another process or migrated from the legacy clone path, is not fresh and should be fetched
+	isCloned := false
+
+	err = repo.withRemoteRepoLock(func() error {
 		exists, err := repo.isCloneExists()
 		if err != nil {
 			return err
@@ -89,25 +239,58 @@ func (repo *Remote) Clone() (bool, error) {
 			return nil
 		}
 
-		fmt.Printf("Clone remote git repo `%s` ...\n", repo.String())
-
-		path := filepath.Join("/tmp", fmt.Sprintf("werf-git-repo-%s", uuid.NewV4().String()))
+		if err := repo.migrateLegacyClone(); err != nil {
+			return err
+		}
 
-		_, err = git.PlainClone(path, true, &git.CloneOptions{
-			URL:               repo.Url,
-			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-		})
-		if err != nil {
+		if exists, err := repo.isCloneExists(); err != nil {
 			return err
+		} else if exists {
+			return nil
 		}
 
-		defer os.RemoveAll(path)
+		if err := RemoveOrphanedTmpClones(); err != nil {
+			logger.LogWarningF("WARNING: %s\n", err)
+		}
 
 		err = os.MkdirAll(filepath.Dir(repo.ClonePath), 0755)
 		if err != nil {
 			return err
 		}
 
+		// partial clone path is on the same filesystem as the clone path, so that rename is atomic
+		path := repo.partialClonePath()
+
+		resumed := false
+		if _, err := os.Stat(path); err == nil {
+			fmt.Printf("Resume interrupted clone of remote git repo `%s` ...\n", repo.String())
+
+			if err := repo.resumeClone(ctx, path); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+
+				logger.LogWarningF("WARNING: Cannot resume interrupted clone of remote git repo `%s`: %s\nRemoving %s and cloning from scratch\n", repo.String(), err, path)
+
+				if err := os.RemoveAll(path); err != nil {
+					return fmt.Errorf("unable to remove %s: %s", path, err)
+				}
+			} else {
+				resumed = true
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		if !resumed {
+			fmt.Printf("Clone remote git repo `%s` ...\n", repo.String())
+
+			// partial clone directory is kept on interruption to resume by fetch on the next run
+			if err := repo.clone(ctx, path); err != nil {
+				return err
+			}
+		}
+
 		err = os.Rename(path, repo.ClonePath)
 		if err != nil {
 			return err
@@ -115,34 +298,215 @@ func (repo *Remote) Clone() (bool, error) {
 
 		fmt.Printf("Clone remote git repo `%s` DONE\n", repo.String())
 
+		isCloned = true
+
 		return nil
 	})
+
+	return isCloned, err
 }
 
-func (repo *Remote) Fetch() error {
-	if repo.IsDryRun {
+// migrateLegacyClone moves the per-project clone of older werf versions to the shared clone path,
+// the clone is used only if its origin url matches the repo url. Should be called with the remote repo lock held.
+func (repo *Remote) migrateLegacyClone() error {
+	if repo.LegacyClonePath == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(repo.LegacyClonePath); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return lock.WithLock(fmt.Sprintf("remote_git_path.%s", repo.Name), lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		originUrl, err := repo.remoteOriginUrl(repo.LegacyClonePath)
+		if err != nil {
+			logger.LogWarningF("WARNING: Cannot migrate clone %s of remote git repo `%s`: %s\n", repo.LegacyClonePath, repo.String(), err)
+			return nil
+		}
+
+		normalizedOriginUrl, err := NormalizeUrl(originUrl)
+		if err != nil {
+			return nil
+		}
+
+		normalizedUrl, err := NormalizeUrl(repo.Url)
+		if err != nil {
+			return err
+		}
+
+		if normalizedOriginUrl != normalizedUrl {
+			return nil
+		}
+
+		if err := os.MkdirAll(filepath.Dir(repo.ClonePath), 0755); err != nil {
+			return err
+		}
+
+		if err := os.Rename(repo.LegacyClonePath, repo.ClonePath); err != nil {
+			return fmt.Errorf("unable to move clone %s to %s: %s", repo.LegacyClonePath, repo.ClonePath, err)
+		}
+
+		fmt.Printf("Moved clone of remote git repo `%s` from %s to %s\n", repo.String(), repo.LegacyClonePath, repo.ClonePath)
+
 		return nil
+	})
+}
+
+func (repo *Remote) partialClonePath() string {
+	return repo.ClonePath + ".partial"
+}
+
+func (repo *Remote) clone(ctx context.Context, path string) error {
+	if repo.isPartialCloneEnabled() {
+		return true_git.PartialClone(ctx, repo.Url, path)
 	}
 
-	cfgPath := filepath.Join(repo.ClonePath, "config")
+	progress := logger.NewGitProgress(fmt.Sprintf("Clone %s", repo.String()))
+	_, err := git.PlainCloneContext(ctx, path, true, &git.CloneOptions{
+		URL:               repo.Url,
+		Auth:              repo.auth(),
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Progress:          progress,
+	})
+	progress.Done()
+
+	return err
+}
 
-	cfg, err := ini.Load(cfgPath)
+// resumeClone fetches objects, which are missing in the clone interrupted by the killed werf process,
+// and restores head branch, which is set by the clone only after the fetch
+func (repo *Remote) resumeClone(ctx context.Context, path string) error {
+	if true_git.IsPartialClone(path) {
+		if err := true_git.ResumePartialClone(ctx, repo.Url, path); err != nil {
+			return err
+		}
+	} else {
+		rawRepo, err := git.PlainOpen(path)
+		if err != nil {
+			return fmt.Errorf("cannot open repo: %s", err)
+		}
+
+		if _, err := rawRepo.Remote("origin"); err != nil {
+			return fmt.Errorf("cannot get remote `origin`: %s", err)
+		}
+
+		progress := logger.NewGitProgress(fmt.Sprintf("Fetch %s", repo.String()))
+		err = rawRepo.FetchContext(ctx, &git.FetchOptions{RemoteName: "origin", Auth: repo.auth(), Force: true, Progress: progress})
+		progress.Done()
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return fmt.Errorf("fetch failed: %s", err)
+		}
+	}
+
+	return restoreCloneHead(path, repo.auth())
+}
+
+// restoreCloneHead points HEAD and the local head branch to the remote default branch like clone does
+func restoreCloneHead(path string, auth transport.AuthMethod) error {
+	rawRepo, err := git.PlainOpen(path)
 	if err != nil {
-		return fmt.Errorf("cannot load repo `%s` config: %s", repo.String(), err)
+		return fmt.Errorf("cannot open repo: %s", err)
 	}
 
-	remoteName := "origin"
+	remote, err := rawRepo.Remote("origin")
+	if err != nil {
+		return fmt.Errorf("cannot get remote `origin`: %s", err)
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("cannot list remote `origin` references: %s", err)
+	}
+
+	var headBranch plumbing.ReferenceName
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			headBranch = ref.Target()
+		}
+	}
+	if !headBranch.IsBranch() {
+		return fmt.Errorf("cannot detect remote `origin` head branch")
+	}
+
+	remoteBranchRef, err := rawRepo.Reference(plumbing.NewRemoteReferenceName("origin", headBranch.Short()), true)
+	if err != nil {
+		return fmt.Errorf("cannot resolve remote branch `%s`: %s", headBranch.Short(), err)
+	}
 
-	oldUrlKey := cfg.Section(fmt.Sprintf("remote \"%s\"", remoteName)).Key("url")
-	if oldUrlKey != nil && oldUrlKey.Value() != repo.Url {
-		oldUrlKey.SetValue(repo.Url)
-		err := cfg.SaveTo(cfgPath)
+	if err := rawRepo.Storer.SetReference(plumbing.NewHashReference(headBranch, remoteBranchRef.Hash())); err != nil {
+		return err
+	}
+
+	return rawRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, headBranch))
+}
+
+// RemoveOrphanedTmpClones removes clone directories, which were left in /tmp by older werf versions
+func RemoveOrphanedTmpClones() error {
+	paths, err := filepath.Glob(filepath.Join("/tmp", "werf-git-repo-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		finfo, err := os.Stat(path)
 		if err != nil {
-			return fmt.Errorf("cannot update url of repo `%s`: %s", repo.String(), err)
+			continue
+		}
+
+		if time.Since(finfo.ModTime()) < OrphanedTmpCloneMinAge {
+			continue
 		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("unable to remove orphaned clone %s: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (repo *Remote) Fetch(ctx context.Context) error {
+	if repo.IsDryRun {
+		return nil
 	}
 
+	if err := repo.resolveCredentials(); err != nil {
+		return err
+	}
+
+	remoteName := "origin"
+
 	return repo.withRemoteRepoLock(func() error {
+		cfgPath := filepath.Join(repo.ClonePath, "config")
+
+		cfg, err := ini.Load(cfgPath)
+		if err != nil {
+			return fmt.Errorf("cannot load repo `%s` config: %s", repo.String(), err)
+		}
+
+		oldUrlKey := cfg.Section(fmt.Sprintf("remote \"%s\"", remoteName)).Key("url")
+		if oldUrlKey != nil && oldUrlKey.Value() != repo.Url {
+			oldUrlKey.SetValue(repo.Url)
+			err := cfg.SaveTo(cfgPath)
+			if err != nil {
+				return fmt.Errorf("cannot update url of repo `%s`: %s", repo.String(), err)
+			}
+		}
+
+		if true_git.IsPartialClone(repo.ClonePath) {
+			fmt.Printf("Fetching remote `%s` of partial clone repo `%s` ...\n", remoteName, repo.String())
+
+			if err := true_git.PartialFetch(ctx, repo.ClonePath); err != nil {
+				return fmt.Errorf("cannot fetch remote `%s` of repo `%s`: %s", remoteName, repo.String(), err)
+			}
+
+			fmt.Printf("Fetching remote `%s` of partial clone repo `%s` DONE\n", remoteName, repo.String())
+
+			return nil
+		}
+
 		rawRepo, err := git.PlainOpen(repo.ClonePath)
 		if err != nil {
 			return fmt.Errorf("cannot open repo: %s", err)
@@ -150,18 +514,89 @@ func (repo *Remote) Fetch() error {
 
 		fmt.Printf("Fetching remote `%s` of repo `%s` ...\n", remoteName, repo.String())
 
-		err = rawRepo.Fetch(&git.FetchOptions{RemoteName: remoteName, Force: true})
+		progress := logger.NewGitProgress(fmt.Sprintf("Fetch %s", repo.String()))
+		err = rawRepo.FetchContext(ctx, &git.FetchOptions{RemoteName: remoteName, Auth: repo.auth(), Force: true, Tags: git.AllTags, Progress: progress})
+		progress.Done()
 		if err != nil && err != git.NoErrAlreadyUpToDate {
 			return fmt.Errorf("cannot fetch remote `%s` of repo `%s`: %s", remoteName, repo.String(), err)
 		}
 
+		if err := pruneRemoteRefs(rawRepo, remoteName, repo.auth()); err != nil {
+			return fmt.Errorf("cannot prune refs of repo `%s`: %s", repo.String(), err)
+		}
+
 		fmt.Printf("Fetching remote `%s` of repo `%s` DONE\n", remoteName, repo.String())
 
 		return nil
 	})
 }
 
+// pruneRemoteRefs removes remote branches and tags, which have been deleted from the remote
+func pruneRemoteRefs(rawRepo *git.Repository, remoteName string, auth transport.AuthMethod) error {
+	remote, err := rawRepo.Remote(remoteName)
+	if err != nil {
+		return fmt.Errorf("cannot get remote `%s`: %s", remoteName, err)
+	}
+
+	remoteRefs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("cannot list remote `%s` references: %s", remoteName, err)
+	}
+
+	existingRefs := map[plumbing.ReferenceName]bool{
+		plumbing.NewRemoteReferenceName(remoteName, "HEAD"): true,
+	}
+	for _, ref := range remoteRefs {
+		if ref.Name().IsBranch() {
+			existingRefs[plumbing.NewRemoteReferenceName(remoteName, ref.Name().Short())] = true
+		} else if ref.Name().IsTag() {
+			existingRefs[ref.Name()] = true
+		}
+	}
+
+	refs, err := rawRepo.References()
+	if err != nil {
+		return err
+	}
+
+	remoteRefsPrefix := fmt.Sprintf("refs/remotes/%s/", remoteName)
+
+	var staleRefs []plumbing.ReferenceName
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if (name.IsTag() || strings.HasPrefix(name.String(), remoteRefsPrefix)) && !existingRefs[name] {
+			staleRefs = append(staleRefs, name)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, name := range staleRefs {
+		fmt.Printf("Pruning %s deleted from remote `%s`\n", name, remoteName)
+
+		if err := rawRepo.Storer.RemoveReference(name); err != nil {
+			return fmt.Errorf("cannot remove reference `%s`: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
 func (repo *Remote) HeadCommit() (string, error) {
+	var res string
+
+	err := repo.withRemoteRepoReadLock(func() error {
+		var err error
+		res, err = repo.headCommit()
+		return err
+	})
+
+	return res, err
+}
+
+func (repo *Remote) headCommit() (string, error) {
 	repoPath := repo.ClonePath
 
 	repository, err := git.PlainOpen(repoPath)
@@ -212,6 +647,18 @@ func (repo *Remote) findReference(rawRepo *git.Repository, reference string) (st
 }
 
 func (repo *Remote) LatestBranchCommit(branch string) (string, error) {
+	var res string
+
+	err := repo.withRemoteRepoReadLock(func() error {
+		var err error
+		res, err = repo.latestBranchCommit(branch)
+		return err
+	})
+
+	return res, err
+}
+
+func (repo *Remote) latestBranchCommit(branch string) (string, error) {
 	var err error
 
 	rawRepo, err := git.PlainOpen(repo.ClonePath)
@@ -232,7 +679,21 @@ func (repo *Remote) LatestBranchCommit(branch string) (string, error) {
 	return res, nil
 }
 
+// LatestTagCommit returns commit of the tag (annotated tags are peeled to the commit).
+// If there is no such tag and the tag is a semver constraint (e.g. v1.2.x), the latest matching tag is used
 func (repo *Remote) LatestTagCommit(tag string) (string, error) {
+	var res string
+
+	err := repo.withRemoteRepoReadLock(func() error {
+		var err error
+		res, err = repo.latestTagCommit(tag)
+		return err
+	})
+
+	return res, err
+}
+
+func (repo *Remote) latestTagCommit(tag string) (string, error) {
 	var err error
 
 	rawRepo, err := git.PlainOpen(repo.ClonePath)
@@ -244,8 +705,31 @@ func (repo *Remote) LatestTagCommit(tag string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+
 	if res == "" {
-		return "", fmt.Errorf("unknown tag `%s` of repo `%s`", tag, repo.String())
+		if _, err := semver.NewConstraint(tag); err != nil {
+			return "", fmt.Errorf("unknown tag `%s` of repo `%s`", tag, repo.String())
+		}
+
+		latestTag, err := repo.selectLatestSemverTag(rawRepo, tag)
+		if err != nil {
+			return "", err
+		}
+		if latestTag == "" {
+			return "", fmt.Errorf("unknown tag `%s` of repo `%s`: no tags matching semver constraint", tag, repo.String())
+		}
+
+		fmt.Printf("Using tag `%s` of repo `%s` matching `%s`\n", latestTag, repo.String(), tag)
+
+		tag = latestTag
+		if res, err = repo.findReference(rawRepo, fmt.Sprintf("refs/tags/%s", tag)); err != nil {
+			return "", err
+		}
+	}
+
+	res, err = peelTag(rawRepo, res)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve tag `%s` of repo `%s`: %s", tag, repo.String(), err)
 	}
 
 	fmt.Printf("Using commit `%s` of repo `%s` tag `%s`\n", res, repo.String(), tag)
@@ -253,34 +737,177 @@ func (repo *Remote) LatestTagCommit(tag string) (string, error) {
 	return res, nil
 }
 
-func (repo *Remote) CreatePatch(opts PatchOptions) (Patch, error) {
+// SelectLatestSemverTag returns the greatest tag, which is a semantic version matching the constraint (e.g. v1.2.x or ~1.2),
+// or empty string if there are no such tags
+func (repo *Remote) SelectLatestSemverTag(constraint string) (string, error) {
+	rawRepo, err := git.PlainOpen(repo.ClonePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open repo: %s", err)
+	}
+
+	return repo.selectLatestSemverTag(rawRepo, constraint)
+}
+
+func (repo *Remote) selectLatestSemverTag(rawRepo *git.Repository, constraint string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("bad semver constraint `%s`: %s", constraint, err)
+	}
+
+	tags, err := rawRepo.Tags()
+	if err != nil {
+		return "", err
+	}
+
+	var res string
+	var resVersion *semver.Version
+
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+
+		v, err := semver.NewVersion(name)
+		if err != nil {
+			return nil
+		}
+
+		if c.Check(v) && (resVersion == nil || v.GreaterThan(resVersion)) {
+			res, resVersion = name, v
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res, nil
+}
+
+// peelTag returns commit, which annotated tag object points to, or the hash itself for lightweight tag
+func peelTag(rawRepo *git.Repository, hash string) (string, error) {
+	h := plumbing.NewHash(hash)
+
+	for {
+		tagObj, err := rawRepo.TagObject(h)
+		if err == plumbing.ErrObjectNotFound {
+			return h.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if tagObj.TargetType != plumbing.TagObject && tagObj.TargetType != plumbing.CommitObject {
+			return "", fmt.Errorf("tag `%s` points to %s, not to a commit", tagObj.Name, tagObj.TargetType)
+		}
+
+		h = tagObj.Target
+	}
+}
+
+func (repo *Remote) CreatePatch(ctx context.Context, opts PatchOptions) (Patch, error) {
 	workTreeDir, err := repo.getWorkTreeDir()
 	if err != nil {
 		return nil, err
 	}
-	return repo.createPatch(repo.ClonePath, repo.ClonePath, workTreeDir, opts)
+	return repo.createPatch(ctx, repo.ClonePath, repo.ClonePath, workTreeDir, opts)
 }
 
-func (repo *Remote) CreateArchive(opts ArchiveOptions) (Archive, error) {
+func (repo *Remote) CreatePatches(ctx context.Context, optsList []PatchOptions) ([]Patch, error) {
+	if len(optsList) == 0 {
+		return nil, nil
+	}
+
 	workTreeDir, err := repo.getWorkTreeDir()
 	if err != nil {
 		return nil, err
 	}
-	return repo.createArchive(repo.ClonePath, repo.ClonePath, workTreeDir, opts)
+	return repo.createPatches(ctx, repo.ClonePath, repo.ClonePath, workTreeDir, optsList)
 }
 
-func (repo *Remote) Checksum(opts ChecksumOptions) (Checksum, error) {
+func (repo *Remote) CreateArchive(ctx context.Context, opts ArchiveOptions) (Archive, error) {
 	workTreeDir, err := repo.getWorkTreeDir()
 	if err != nil {
 		return nil, err
 	}
-	return repo.checksum(repo.ClonePath, repo.ClonePath, workTreeDir, opts)
+	return repo.createArchive(ctx, repo.ClonePath, repo.ClonePath, workTreeDir, opts)
+}
+
+func (repo *Remote) Checksum(ctx context.Context, opts ChecksumOptions) (Checksum, error) {
+	workTreeDir, err := repo.getWorkTreeDir()
+	if err != nil {
+		return nil, err
+	}
+	return repo.checksum(ctx, repo.ClonePath, repo.ClonePath, workTreeDir, opts)
 }
 
 func (repo *Remote) IsCommitExists(commit string) (bool, error) {
 	return repo.isCommitExists(repo.ClonePath, commit)
 }
 
+// ResolveCommit returns full hash of the commit specified by the full or abbreviated hash, branch, tag or an expression like origin/master~2.
+// Branches are also resolved without origin/ prefix.
+func (repo *Remote) ResolveCommit(ctx context.Context, revision string) (string, error) {
+	return repo.resolveCommit(ctx, repo.ClonePath, revision, []string{"origin"})
+}
+
+func (repo *Remote) CommitInfo(commit string) (*CommitInfo, error) {
+	return repo.commitInfo(repo.ClonePath, commit)
+}
+
+func (repo *Remote) VerifyCommitSignature(commit string, armoredKeyRings []string) (string, error) {
+	return repo.verifyCommitSignature(repo.ClonePath, commit, armoredKeyRings)
+}
+
+func (repo *Remote) MergeBase(ctx context.Context, commit1, commit2 string) (string, error) {
+	return repo.mergeBase(ctx, repo.ClonePath, commit1, commit2)
+}
+
+// IsCommitFileExists checks file existence in the commit without work tree checkout
+func (repo *Remote) IsCommitFileExists(commit, path string) (bool, error) {
+	if err := repo.fetchCommitFile(commit, path); err != nil {
+		return false, err
+	}
+	return repo.isCommitFileExists(repo.ClonePath, commit, path)
+}
+
+// ReadCommitFile reads file content from the commit without work tree checkout
+func (repo *Remote) ReadCommitFile(commit, path string) ([]byte, error) {
+	if err := repo.fetchCommitFile(commit, path); err != nil {
+		return nil, err
+	}
+	return repo.readCommitFile(repo.ClonePath, commit, path)
+}
+
+// fetchCommitFile fetches blob of the commit file if repo is a partial clone, go-git cannot fetch missing blobs itself
+func (repo *Remote) fetchCommitFile(commit, path string) error {
+	if !true_git.IsPartialClone(repo.ClonePath) {
+		return nil
+	}
+
+	_, err := true_git.FetchMissingBlobs(context.Background(), repo.ClonePath, commit, func(filePath string) bool {
+		return filePath == filepath.ToSlash(path)
+	})
+	return err
+}
+
+func (repo *Remote) isPartialCloneEnabled() bool {
+	if !repo.PartialClone {
+		return false
+	}
+
+	if !true_git.IsPartialCloneSupported() {
+		logger.LogWarningF("WARNING: Partial clone of remote git repo `%s` requires git >= %s, your git version is %s: making full clone\n", repo.String(), true_git.MinGitVersionWithPartialCloneConstraint, true_git.GitVersion)
+		return false
+	}
+
+	return true
+}
+
+// CommitFilesList returns paths of all files in the commit located under the dir
+func (repo *Remote) CommitFilesList(commit, dir string) ([]string, error) {
+	return repo.commitFilesList(repo.ClonePath, commit, dir)
+}
+
 func (repo *Remote) getWorkTreeDir() (string, error) {
 	ep, err := transport.NewEndpoint(repo.Url)
 	if err != nil {
@@ -290,9 +917,20 @@ func (repo *Remote) getWorkTreeDir() (string, error) {
 	return filepath.Join(GetBaseWorkTreeDir(), "remote", ep.Host, ep.Path), nil
 }
 
+// withRemoteRepoLock locks the clone exclusively: clone, fetch and removal of the clone are done by one werf process
 func (repo *Remote) withRemoteRepoLock(f func() error) error {
-	lockName := fmt.Sprintf("remote_git_path.%s", repo.Name)
-	return lock.WithLock(lockName, lock.LockOptions{Timeout: 600 * time.Second}, f)
+	return lock.WithLock(repo.remoteRepoLockName(), lock.LockOptions{Timeout: 600 * time.Second}, f)
+}
+
+// withRemoteRepoReadLock allows werf processes of different projects to read refs of the shared clone simultaneously,
+// but not during the fetch, which updates and prunes refs
+func (repo *Remote) withRemoteRepoReadLock(f func() error) error {
+	return lock.WithLock(repo.remoteRepoLockName(), lock.LockOptions{Timeout: 600 * time.Second, ReadOnly: true}, f)
+}
+
+// remoteRepoLockName is the same for all projects using the clone
+func (repo *Remote) remoteRepoLockName() string {
+	return cloneLockName(repo.ClonePath)
 }
 
 func (repo *Remote) TagsList() ([]string, error) {
@@ -302,3 +940,29 @@ func (repo *Remote) TagsList() ([]string, error) {
 func (repo *Remote) RemoteBranchesList() ([]string, error) {
 	return repo.remoteBranchesList(repo.ClonePath)
 }
+
+// TagsRefs returns lightweight and annotated tags with commits, which they point to
+func (repo *Remote) TagsRefs() ([]*Ref, error) {
+	var res []*Ref
+
+	err := repo.withRemoteRepoReadLock(func() error {
+		var err error
+		res, err = repo.tagsRefs(repo.ClonePath)
+		return err
+	})
+
+	return res, err
+}
+
+// RemoteBranchesRefs returns branches of the remote repo with their latest commits
+func (repo *Remote) RemoteBranchesRefs() ([]*Ref, error) {
+	var res []*Ref
+
+	err := repo.withRemoteRepoReadLock(func() error {
+		var err error
+		res, err = repo.remoteBranchesRefs(repo.ClonePath)
+		return err
+	})
+
+	return res, err
+}
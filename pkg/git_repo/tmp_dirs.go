@@ -0,0 +1,100 @@
+package git_repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
+
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// PatchesDir, ArchivesDir and WorkTreesDir override locations of git patches, archives (werf tmp dir by default)
+// and work trees (werf home by default), e.g. to move big files out of the small tmpfs
+var (
+	PatchesDir   string
+	ArchivesDir  string
+	WorkTreesDir string
+)
+
+// tarEntryOverhead is the size of tar header and average padding of the archive entry
+const tarEntryOverhead = 1024
+
+func getPatchesDir() string {
+	if PatchesDir != "" {
+		return PatchesDir
+	}
+	return werf.GetTmpDir()
+}
+
+func getArchivesDir() string {
+	if ArchivesDir != "" {
+		return ArchivesDir
+	}
+	return werf.GetTmpDir()
+}
+
+// checkPatchFreeSpace estimates patch size as total size of old and new versions of the changed files
+func checkPatchFreeSpace(ctx context.Context, gitDir string, opts PatchOptions, pathFilter true_git.PathFilter) error {
+	if true_git.IsPartialClone(gitDir) {
+		return nil
+	}
+
+	size, err := true_git.DiffSize(ctx, gitDir, opts.FromCommit, opts.ToCommit, pathFilter)
+	if err != nil {
+		return fmt.Errorf("cannot estimate size of patch between `%s` and `%s` commits: %s", opts.FromCommit, opts.ToCommit, err)
+	}
+
+	return checkFreeSpace(getPatchesDir(), size, fmt.Sprintf("patch between `%s` and `%s` commits", opts.FromCommit, opts.ToCommit), "--patches-tmp-dir option ($WERF_PATCHES_TMP_DIR)")
+}
+
+// checkArchiveFreeSpace estimates archive size as total size of the archived files with tar headers
+func checkArchiveFreeSpace(ctx context.Context, gitDir string, opts ArchiveOptions, pathFilter true_git.PathFilter) error {
+	if true_git.IsPartialClone(gitDir) {
+		return nil
+	}
+
+	size, count, err := true_git.TreeSize(ctx, gitDir, opts.Commit, pathFilter)
+	if err != nil {
+		return fmt.Errorf("cannot estimate size of archive of commit `%s`: %s", opts.Commit, err)
+	}
+
+	return checkFreeSpace(getArchivesDir(), size+uint64(count)*tarEntryOverhead, fmt.Sprintf("archive of commit `%s`", opts.Commit), "--archives-tmp-dir option ($WERF_ARCHIVES_TMP_DIR)")
+}
+
+// checkWorkTreeFreeSpace estimates size of the new work tree as total size of the commit files, existing work tree is only switched to the commit
+func checkWorkTreeFreeSpace(ctx context.Context, gitDir, workTreeDir, commit string) error {
+	if true_git.IsPartialClone(gitDir) {
+		return nil
+	}
+
+	if _, err := os.Stat(workTreeDir); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	size, _, err := true_git.TreeSize(ctx, gitDir, commit, true_git.PathFilter{})
+	if err != nil {
+		return fmt.Errorf("cannot estimate size of work tree of commit `%s`: %s", commit, err)
+	}
+
+	return checkFreeSpace(workTreeDir, size, fmt.Sprintf("work tree of commit `%s`", commit), "--worktrees-dir option ($WERF_WORKTREES_DIR)")
+}
+
+func checkFreeSpace(dir string, requiredSize uint64, desc, option string) error {
+	freeSpace, err := util.FreeDiskSpace(dir)
+	if err != nil {
+		// free space is unknown on some filesystems, the operation fails later if space is really not enough
+		return nil
+	}
+
+	if requiredSize <= freeSpace {
+		return nil
+	}
+
+	return fmt.Errorf("not enough free space in %s to create %s: %s required (estimated from git objects sizes), %s available\nFree up disk space or use %s to place these files on another filesystem", dir, desc, units.HumanSize(float64(requiredSize)), units.HumanSize(float64(freeSpace)), option)
+}
@@ -1,6 +1,7 @@
 package git_repo
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"github.com/flant/werf/pkg/werf"
@@ -8,6 +9,15 @@ import (
 
 const GIT_WORKTREE_CACHE_VERSION = "1"
 
+// GetBaseWorkTreeDir returns dir of the git work trees in werf home or in WorkTreesDir
 func GetBaseWorkTreeDir() string {
+	if WorkTreesDir != "" {
+		return filepath.Join(WorkTreesDir, GIT_WORKTREE_CACHE_VERSION)
+	}
+
 	return filepath.Join(werf.GetHomeDir(), "git", "worktrees", GIT_WORKTREE_CACHE_VERSION)
 }
+
+func WorkTreeLockName(workTree string) string {
+	return fmt.Sprintf("git_work_tree %s", workTree)
+}
@@ -0,0 +1,56 @@
+package git_repo
+
+import (
+	"bytes"
+	"hash"
+	"sort"
+	"syscall"
+)
+
+// writeXattrsChecksum writes sorted names and values of the file extended attributes into the hash
+func writeXattrsChecksum(h hash.Hash, path string) error {
+	size, err := syscall.Listxattr(path, nil)
+	if err == syscall.ENOTSUP {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) != 0 {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		valueSize, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return err
+		}
+
+		value := make([]byte, valueSize)
+		if valueSize != 0 {
+			valueSize, err = syscall.Getxattr(path, name, value)
+			if err != nil {
+				return err
+			}
+		}
+
+		h.Write([]byte(name))
+		h.Write(value[:valueSize])
+	}
+
+	return nil
+}
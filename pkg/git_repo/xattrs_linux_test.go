@@ -0,0 +1,66 @@
+package git_repo
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func xattrsTestChecksum(t *testing.T, path string) string {
+	h := md5.New()
+	if err := writeXattrsChecksum(h, path); err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// xattrsTestFile creates file and sets user extended attributes in the specified order (name, value, name, value, ...)
+func xattrsTestFile(t *testing.T, attrs ...string) string {
+	f, err := ioutil.TempFile("", "werf-xattrs-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for i := 0; i < len(attrs); i += 2 {
+		if err := syscall.Setxattr(f.Name(), attrs[i], []byte(attrs[i+1]), 0); err == syscall.ENOTSUP || err == syscall.EPERM {
+			os.Remove(f.Name())
+			t.Skipf("user extended attributes are not supported by the filesystem of %s", f.Name())
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return f.Name()
+}
+
+func TestWriteXattrsChecksum(t *testing.T) {
+	withoutAttrs := xattrsTestFile(t)
+	defer os.Remove(withoutAttrs)
+
+	first := xattrsTestFile(t, "user.werf.b", "2", "user.werf.a", "1")
+	defer os.Remove(first)
+
+	second := xattrsTestFile(t, "user.werf.a", "1", "user.werf.b", "2")
+	defer os.Remove(second)
+
+	if xattrsTestChecksum(t, first) != xattrsTestChecksum(t, second) {
+		t.Errorf("checksum depends on the order of extended attributes")
+	}
+
+	if xattrsTestChecksum(t, first) == xattrsTestChecksum(t, withoutAttrs) {
+		t.Errorf("checksum does not depend on extended attributes")
+	}
+
+	if err := syscall.Setxattr(second, "user.werf.a", []byte("3"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if xattrsTestChecksum(t, first) == xattrsTestChecksum(t, second) {
+		t.Errorf("checksum does not depend on values of extended attributes")
+	}
+}
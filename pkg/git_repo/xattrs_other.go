@@ -0,0 +1,10 @@
+// +build !linux
+
+package git_repo
+
+import "hash"
+
+// writeXattrsChecksum does nothing: extended attributes are supported on linux only
+func writeXattrsChecksum(_ hash.Hash, _ string) error {
+	return nil
+}
@@ -0,0 +1,204 @@
+package home_usage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// USAGE_RECORDS_VERSION should be bumped when usage record format is changed
+const USAGE_RECORDS_VERSION = "1"
+
+const (
+	GitRepoCloneKind       = "git repo clone"
+	LegacyGitRepoCloneKind = "legacy git repo clone"
+	GitWorkTreeKind        = "git worktree"
+	AnsibleGalaxyRolesKind = "ansible galaxy roles"
+)
+
+// Entry is a cache entry of werf home (clone or worktree), which can be evicted when werf home quota is exceeded
+type Entry struct {
+	Kind     string
+	Path     string
+	LockName string
+	LastUsed time.Time
+	Size     int64
+
+	// recordPath is a usage record of the tracked entry, modification time of the entry path is the last usage time of untracked entry
+	recordPath string
+}
+
+// lastUsed returns the current last usage time of the entry
+func (e *Entry) lastUsed() (time.Time, error) {
+	path := e.recordPath
+	if path == "" {
+		path = e.Path
+	}
+
+	finfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return finfo.ModTime(), nil
+}
+
+// Category describes disk usage of the werf home part
+type Category struct {
+	Name string
+	Size int64
+}
+
+// Usage summarizes disk usage of werf home
+type Usage struct {
+	HomeDir    string
+	Size       int64
+	Quota      int64
+	Categories []Category
+	// Entries are tracked cache entries in least recently used order
+	Entries []*Entry
+}
+
+func GetUsageRecordsDir() string {
+	return filepath.Join(werf.GetHomeDir(), "usage", USAGE_RECORDS_VERSION)
+}
+
+// MarkUsed records last usage time of the cache entry, the entry is removed with the lock held when werf home quota is exceeded
+func MarkUsed(kind, path, lockName string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("bad cache entry path `%s`: %s", path, err)
+	}
+
+	recordPath := filepath.Join(GetUsageRecordsDir(), util.Sha256Hash(path))
+	if err := os.MkdirAll(filepath.Dir(recordPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	data := strings.Join([]string{kind, path, lockName}, "\n") + "\n"
+	if err := ioutil.WriteFile(recordPath, []byte(data), 0644); err != nil {
+		return fmt.Errorf("cannot write usage record %s: %s", recordPath, err)
+	}
+
+	return nil
+}
+
+// GetEntries returns tracked cache entries, which still exist, sorted from least to most recently used.
+// Records of removed entries are deleted.
+func GetEntries() ([]*Entry, error) {
+	recordsDir := GetUsageRecordsDir()
+
+	finfos, err := ioutil.ReadDir(recordsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to list usage records in %s: %s", recordsDir, err)
+	}
+
+	var entries []*Entry
+	for _, finfo := range finfos {
+		recordPath := filepath.Join(recordsDir, finfo.Name())
+
+		data, err := ioutil.ReadFile(recordPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read usage record %s: %s", recordPath, err)
+		}
+
+		fields := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(fields) != 3 {
+			if err := os.Remove(recordPath); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		entry := &Entry{Kind: fields[0], Path: fields[1], LockName: fields[2], LastUsed: finfo.ModTime(), recordPath: recordPath}
+
+		if _, err := os.Stat(entry.Path); os.IsNotExist(err) {
+			if err := os.Remove(recordPath); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry.Size, err = util.DirSize(entry.Path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to calculate size of %s: %s", entry.Path, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.Before(entries[j].LastUsed) })
+
+	return entries, nil
+}
+
+// GetUsage calculates disk usage of werf home by categories
+func GetUsage() (*Usage, error) {
+	homeDir := werf.GetHomeDir()
+
+	usage := &Usage{HomeDir: homeDir, Quota: werf.GetHomeQuota()}
+
+	var err error
+	usage.Size, err = util.DirSize(homeDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate size of %s: %s", homeDir, err)
+	}
+
+	categories := []struct {
+		Name    string
+		Pattern string
+	}{
+		{"Remote git repos clones", filepath.Join(homeDir, "git", "clones")},
+		{"Legacy remote git repos clones", filepath.Join(homeDir, "builds", "*", "remote_git_repo")},
+		{"Legacy own git repos clones", filepath.Join(homeDir, "own_git_repo")},
+		{"Git worktrees", filepath.Join(homeDir, "git", "worktrees")},
+		{"Git checksums cache", filepath.Join(homeDir, "git", "checksums")},
+		{"Downloads", filepath.Join(homeDir, "downloads")},
+		{"Ansible galaxy roles", filepath.Join(homeDir, "ansible_galaxy_roles")},
+		{"Helm", filepath.Join(homeDir, "helm")},
+		{"Helm repositories", filepath.Join(homeDir, "helm_repositories")},
+		{"Tmp", filepath.Join(homeDir, "tmp")},
+		{"Project homes", filepath.Join(homeDir, "projects")},
+	}
+
+	restSize := usage.Size
+	for _, category := range categories {
+		paths, err := filepath.Glob(category.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		var size int64
+		for _, path := range paths {
+			pathSize, err := util.DirSize(path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to calculate size of %s: %s", path, err)
+			}
+			size += pathSize
+		}
+
+		usage.Categories = append(usage.Categories, Category{Name: category.Name, Size: size})
+		restSize -= size
+	}
+	usage.Categories = append(usage.Categories, Category{Name: "Other", Size: restSize})
+
+	usage.Entries, err = GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,119 @@
+package home_usage
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// MinIdleTimeToEvict protects entries, which can be used by running werf processes, from eviction
+const MinIdleTimeToEvict = time.Hour
+
+// EnforceQuota evicts least recently used cache entries until werf home size fits into the quota.
+// Nothing is done if quota is not configured.
+func EnforceQuota() error {
+	quota := werf.GetHomeQuota()
+	if quota == 0 {
+		return nil
+	}
+
+	return lock.WithLock("home_usage_quota", lock.LockOptions{Timeout: 600 * time.Second}, func() error {
+		size, err := util.DirSize(werf.GetHomeDir())
+		if err != nil {
+			return fmt.Errorf("unable to calculate size of %s: %s", werf.GetHomeDir(), err)
+		}
+
+		if size <= quota {
+			return nil
+		}
+
+		entries, err := GetEntries()
+		if err != nil {
+			return err
+		}
+
+		logger.LogInfoF("Werf home %s size %s exceeds quota %s: evicting least recently used cache entries ...\n", werf.GetHomeDir(), units.HumanSize(float64(size)), units.HumanSize(float64(quota)))
+
+		for _, entry := range entries {
+			if size <= quota {
+				break
+			}
+
+			if time.Since(entry.LastUsed) < MinIdleTimeToEvict {
+				break
+			}
+
+			evicted, err := EvictEntry(entry, false)
+			if err != nil {
+				logger.LogWarningF("WARNING: Unable to evict %s %s: %s\n", entry.Kind, entry.Path, err)
+				continue
+			}
+
+			if evicted {
+				size -= entry.Size
+			}
+		}
+
+		if size > quota {
+			logger.LogWarningF("WARNING: Werf home %s size %s still exceeds quota %s: cache entries used during the last %s are not evicted\n", werf.GetHomeDir(), units.HumanSize(float64(size)), units.HumanSize(float64(quota)), MinIdleTimeToEvict)
+		}
+
+		return nil
+	})
+}
+
+// EvictEntry removes the entry with the entry lock held.
+// The entry, which has been used or removed by another werf process after the entry was listed, is kept and false is returned.
+func EvictEntry(entry *Entry, dryRun bool) (bool, error) {
+	evicted := false
+
+	f := func() error {
+		lastUsed, err := entry.lastUsed()
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if lastUsed.After(entry.LastUsed) {
+			return nil
+		}
+
+		if dryRun {
+			logger.LogInfoF("Evict %s %s (%s, last used %s)\n", entry.Kind, entry.Path, units.HumanSize(float64(entry.Size)), entry.LastUsed.Format(time.RFC3339))
+			evicted = true
+			return nil
+		}
+
+		logger.LogInfoF("Evicting %s %s (%s, last used %s)\n", entry.Kind, entry.Path, units.HumanSize(float64(entry.Size)), entry.LastUsed.Format(time.RFC3339))
+
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return err
+		}
+
+		if entry.recordPath != "" {
+			if err := os.Remove(entry.recordPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		evicted = true
+		return nil
+	}
+
+	var err error
+	if entry.LockName == "" {
+		err = f()
+	} else {
+		err = lock.WithLock(entry.LockName, lock.LockOptions{Timeout: 10 * time.Second}, f)
+	}
+
+	return evicted, err
+}
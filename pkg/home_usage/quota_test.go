@@ -0,0 +1,126 @@
+package home_usage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flant/werf/pkg/werf"
+)
+
+func initTestHome(t *testing.T) string {
+	homeDir, err := ioutil.TempDir("", "werf-home-usage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(homeDir, homeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	return homeDir
+}
+
+func markTestEntryUsed(t *testing.T, path string, lastUsed time.Time) *Entry {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MarkUsed(GitWorkTreeKind, path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := GetEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if entry.Path == path {
+			if err := os.Chtimes(entry.recordPath, lastUsed, lastUsed); err != nil {
+				t.Fatal(err)
+			}
+			entry.LastUsed = lastUsed
+
+			return entry
+		}
+	}
+
+	t.Fatalf("entry %s not found", path)
+	return nil
+}
+
+func TestEvictEntry(t *testing.T) {
+	homeDir := initTestHome(t)
+	defer os.RemoveAll(homeDir)
+
+	tests := []struct {
+		name           string
+		usedAfterList  bool
+		dryRun         bool
+		expectEvicted  bool
+		expectPathKept bool
+	}{
+		{name: "idle entry", expectEvicted: true},
+		{name: "entry used after listing", usedAfterList: true, expectPathKept: true},
+		{name: "dry run", dryRun: true, expectEvicted: true, expectPathKept: true},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(homeDir, "entries", string(rune('a'+i)))
+			entry := markTestEntryUsed(t, path, time.Now().Add(-2*MinIdleTimeToEvict))
+
+			if test.usedAfterList {
+				if err := MarkUsed(entry.Kind, entry.Path, entry.LockName); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			evicted, err := EvictEntry(entry, test.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if evicted != test.expectEvicted {
+				t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", test.expectEvicted, evicted)
+			}
+
+			_, err = os.Stat(path)
+			if pathKept := err == nil; pathKept != test.expectPathKept {
+				t.Errorf("\n[EXPECTED]: path kept %#v\n[GOT]: path kept %#v", test.expectPathKept, pathKept)
+			}
+
+			_, err = os.Stat(entry.recordPath)
+			if recordKept := err == nil; recordKept != test.expectPathKept {
+				t.Errorf("\n[EXPECTED]: record kept %#v\n[GOT]: record kept %#v", test.expectPathKept, recordKept)
+			}
+		})
+	}
+}
+
+func TestEvictEntry_removedEntry(t *testing.T) {
+	homeDir := initTestHome(t)
+	defer os.RemoveAll(homeDir)
+
+	path := filepath.Join(homeDir, "entries", "removed")
+	entry := markTestEntryUsed(t, path, time.Now().Add(-2*MinIdleTimeToEvict))
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(entry.recordPath); err != nil {
+		t.Fatal(err)
+	}
+
+	evicted, err := EvictEntry(entry, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", false, evicted)
+	}
+}
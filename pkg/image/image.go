@@ -1,5 +1,7 @@
 package image
 
+import "context"
+
 type Image struct {
 	*StageImage
 }
@@ -12,6 +14,6 @@ func (i *Image) Tag() error {
 	return i.StageImage.Tag(i.name)
 }
 
-func (i *Image) Export() error {
-	return i.StageImage.Export(i.name)
+func (i *Image) Export(ctx context.Context) error {
+	return i.StageImage.Export(ctx, i.name)
 }
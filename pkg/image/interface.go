@@ -1,8 +1,21 @@
 package image
 
+import (
+	"context"
+	"time"
+)
+
 type BuildOptions struct {
 	IntrospectBeforeError bool
 	IntrospectAfterError  bool
+
+	// LogFile stores stdout and stderr of the stage container, the file is written both for succeeded and failed builds
+	LogFile string
+
+	// Timeout kills the stage container, which runs longer, zero means no timeout
+	Timeout time.Duration
+	// Retries is a number of additional runs of the failed stage container
+	Retries int
 }
 
 type ImageInterface interface {
@@ -17,7 +30,7 @@ type ImageInterface interface {
 
 	SyncDockerState() error
 
-	Pull() error
+	Pull(ctx context.Context) error
 	Untag() error
 
 	SaveInCache() error
@@ -49,6 +62,7 @@ type BuilderContainer interface {
 
 type ContainerOptions interface {
 	AddVolume(volumes ...string)
+	AddTmpfs(paths ...string)
 	AddVolumeFrom(volumesFrom ...string)
 	AddExpose(exposes ...string)
 	AddEnv(envs map[string]string)
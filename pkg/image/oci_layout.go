@@ -0,0 +1,258 @@
+package image
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	OCIManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	OCIConfigMediaType   = "application/vnd.oci.image.config.v1+json"
+	OCILayerMediaType    = "application/vnd.oci.image.layer.v1.tar"
+
+	OCIRefNameAnnotation = "org.opencontainers.image.ref.name"
+	imageNameAnnotation  = "io.containerd.image.name"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+type dockerArchiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+type ociBlob struct {
+	Digest string
+	Size   int64
+}
+
+// DockerArchiveToOCILayout converts `docker save` archive into OCI image layout directory.
+// Images tags are saved as index annotations: tag as org.opencontainers.image.ref.name and full name as io.containerd.image.name.
+func DockerArchiveToOCILayout(archivePath, layoutDir string) error {
+	blobsDir := filepath.Join(layoutDir, "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	blobs := map[string]ociBlob{}
+	links := map[string]string{}
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read archive %s: %s", archivePath, err)
+		}
+
+		name := path.Clean(header.Name)
+
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			blob, err := writeOCIBlob(blobsDir, tr)
+			if err != nil {
+				return err
+			}
+			blobs[name] = blob
+		case tar.TypeSymlink:
+			links[name] = path.Join(path.Dir(name), header.Linkname)
+		case tar.TypeLink:
+			links[name] = path.Clean(header.Linkname)
+		}
+	}
+
+	getBlob := func(name string) (ociBlob, error) {
+		name = path.Clean(name)
+		for i := 0; i < 10; i++ {
+			if blob, ok := blobs[name]; ok {
+				return blob, nil
+			}
+
+			target, ok := links[name]
+			if !ok {
+				break
+			}
+			name = target
+		}
+
+		return ociBlob{}, fmt.Errorf("file %s not found in archive %s", name, archivePath)
+	}
+
+	manifestBlob, err := getBlob("manifest.json")
+	if err != nil {
+		return err
+	}
+
+	var archiveManifests []dockerArchiveManifest
+	if err := readOCIBlobJson(blobsDir, manifestBlob, &archiveManifests); err != nil {
+		return fmt.Errorf("bad manifest.json in archive %s: %s", archivePath, err)
+	}
+
+	usedDigests := map[string]bool{}
+	index := &ociIndex{SchemaVersion: 2}
+
+	for _, archiveManifest := range archiveManifests {
+		configBlob, err := getBlob(archiveManifest.Config)
+		if err != nil {
+			return err
+		}
+		usedDigests[configBlob.Digest] = true
+
+		platform := &ociPlatform{}
+		if err := readOCIBlobJson(blobsDir, configBlob, platform); err != nil {
+			return fmt.Errorf("bad image config %s in archive %s: %s", archiveManifest.Config, archivePath, err)
+		}
+
+		manifest := &ociManifest{
+			SchemaVersion: 2,
+			MediaType:     OCIManifestMediaType,
+			Config:        ociDescriptor{MediaType: OCIConfigMediaType, Digest: configBlob.Digest, Size: configBlob.Size},
+		}
+
+		for _, layer := range archiveManifest.Layers {
+			layerBlob, err := getBlob(layer)
+			if err != nil {
+				return err
+			}
+			usedDigests[layerBlob.Digest] = true
+
+			manifest.Layers = append(manifest.Layers, ociDescriptor{MediaType: OCILayerMediaType, Digest: layerBlob.Digest, Size: layerBlob.Size})
+		}
+
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+
+		manifestBlob, err := writeOCIBlob(blobsDir, strings.NewReader(string(data)))
+		if err != nil {
+			return err
+		}
+		usedDigests[manifestBlob.Digest] = true
+
+		desc := ociDescriptor{MediaType: OCIManifestMediaType, Digest: manifestBlob.Digest, Size: manifestBlob.Size, Platform: platform}
+		if len(archiveManifest.RepoTags) == 0 {
+			index.Manifests = append(index.Manifests, desc)
+		}
+
+		for _, repoTag := range archiveManifest.RepoTags {
+			tagDesc := desc
+			tagDesc.Annotations = map[string]string{
+				OCIRefNameAnnotation: imageTag(repoTag),
+				imageNameAnnotation:  repoTag,
+			}
+			index.Manifests = append(index.Manifests, tagDesc)
+		}
+	}
+
+	// service files of the archive (manifest.json, repositories, legacy layers json) are not a part of the layout
+	for _, blob := range blobs {
+		if !usedDigests[blob.Digest] {
+			if err := os.Remove(ociBlobPath(blobsDir, blob)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	if err := writeJsonFile(filepath.Join(layoutDir, "oci-layout"), map[string]string{"imageLayoutVersion": "1.0.0"}); err != nil {
+		return err
+	}
+
+	return writeJsonFile(filepath.Join(layoutDir, "index.json"), index)
+}
+
+func imageTag(name string) string {
+	if ind := strings.LastIndex(name, ":"); ind != -1 && !strings.Contains(name[ind:], "/") {
+		return name[ind+1:]
+	}
+	return "latest"
+}
+
+func ociBlobPath(blobsDir string, blob ociBlob) string {
+	return filepath.Join(blobsDir, strings.TrimPrefix(blob.Digest, "sha256:"))
+}
+
+// writeOCIBlob saves content into the blobs directory by its sha256 digest
+func writeOCIBlob(blobsDir string, r io.Reader) (ociBlob, error) {
+	tmpFile, err := ioutil.TempFile(blobsDir, ".tmp-")
+	if err != nil {
+		return ociBlob{}, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, h), r)
+	if err != nil {
+		tmpFile.Close()
+		return ociBlob{}, err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return ociBlob{}, err
+	}
+
+	blob := ociBlob{Digest: fmt.Sprintf("sha256:%s", hex.EncodeToString(h.Sum(nil))), Size: size}
+	if err := os.Rename(tmpFile.Name(), ociBlobPath(blobsDir, blob)); err != nil {
+		return ociBlob{}, err
+	}
+
+	return blob, nil
+}
+
+func readOCIBlobJson(blobsDir string, blob ociBlob, v interface{}) error {
+	data, err := ioutil.ReadFile(ociBlobPath(blobsDir, blob))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJsonFile(filePath string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
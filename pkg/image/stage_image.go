@@ -1,12 +1,15 @@
 package image
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/docker/docker/api/types"
 
 	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/logger"
 )
 
 type StageImage struct {
@@ -14,6 +17,7 @@ type StageImage struct {
 	fromImage  *StageImage
 	container  *StageImageContainer
 	buildImage *build
+	platform   string
 }
 
 func NewStageImage(fromImage *StageImage, name string) *StageImage {
@@ -24,6 +28,15 @@ func NewStageImage(fromImage *StageImage, name string) *StageImage {
 	return stage
 }
 
+// SetPlatform sets target platform (os/arch[/variant]) used to pull and run image
+func (i *StageImage) SetPlatform(platform string) {
+	i.platform = platform
+}
+
+func (i *StageImage) GetPlatform() string {
+	return i.platform
+}
+
 func (i *StageImage) Labels() map[string]string {
 	if i.inspect != nil {
 		return i.inspect.Config.Labels
@@ -74,7 +87,37 @@ func (i *StageImage) SyncDockerState() error {
 }
 
 func (i *StageImage) Build(options BuildOptions) error {
-	if containerRunErr := i.container.run(); containerRunErr != nil {
+	var containerRunErr error
+	for attempt := 1; ; attempt++ {
+		containerRunErr = i.container.runWithTimeout(options.Timeout)
+
+		if options.LogFile != "" {
+			if err := i.container.saveLogs(options.LogFile); err != nil {
+				logger.LogWarningF("WARNING: Unable to save logs of container %s into %s: %s\n", i.container.name, options.LogFile, err)
+			}
+		}
+
+		if containerRunErr == nil || attempt > options.Retries {
+			break
+		}
+
+		logger.LogWarningF("WARNING: %s\n", containerRunErr)
+
+		if options.LogFile != "" {
+			attemptLogFile := fmt.Sprintf("%s.attempt-%d.log", strings.TrimSuffix(options.LogFile, ".log"), attempt)
+			if err := os.Rename(options.LogFile, attemptLogFile); err == nil {
+				logger.LogWarningF("WARNING: Logs of the failed attempt are saved into %s\n", attemptLogFile)
+			}
+		}
+
+		logger.LogWarningF("WARNING: Retrying container %s (attempt %d of %d) ...\n", i.container.name, attempt+1, options.Retries+1)
+
+		if err := i.container.rm(); err != nil {
+			return fmt.Errorf("unable to remove failed container %s: %s", i.container.name, err)
+		}
+	}
+
+	if containerRunErr != nil {
 		if strings.HasPrefix(containerRunErr.Error(), "container run failed") {
 			if options.IntrospectBeforeError {
 				fmt.Printf("Launched command: %s\n", strings.Join(i.container.prepareAllRunCommands(), " && "))
@@ -168,8 +211,8 @@ func (i *StageImage) Tag(name string) error {
 	return nil
 }
 
-func (i *StageImage) Pull() error {
-	if err := docker.CliPull(i.name); err != nil {
+func (i *StageImage) Pull(ctx context.Context) error {
+	if err := docker.CliPull(ctx, i.pullArgs(i.name)...); err != nil {
 		return err
 	}
 
@@ -178,14 +221,41 @@ func (i *StageImage) Pull() error {
 	return nil
 }
 
-func (i *StageImage) Push() error {
-	return docker.CliPush(i.name)
+func (i *StageImage) pullArgs(name string) []string {
+	var args []string
+	if i.platform != "" {
+		args = append(args, fmt.Sprintf("--platform=%s", i.platform))
+	}
+	return append(args, name)
+}
+
+// IsPlatformMatched checks that existing image has been built for target platform
+func (i *StageImage) IsPlatformMatched() (bool, error) {
+	if i.platform == "" {
+		return true, nil
+	}
+
+	inspect, err := i.MustGetInspect()
+	if err != nil {
+		return false, err
+	}
+
+	parts := strings.Split(i.platform, "/")
+	if len(parts) < 2 {
+		return false, fmt.Errorf("bad platform '%s': expected os/arch[/variant]", i.platform)
+	}
+
+	return inspect.Os == parts[0] && inspect.Architecture == parts[1], nil
+}
+
+func (i *StageImage) Push(ctx context.Context) error {
+	return docker.CliPush(ctx, i.name)
 }
 
-func (i *StageImage) Import(name string) error {
+func (i *StageImage) Import(ctx context.Context, name string) error {
 	importedImage := newBaseImage(name)
 
-	if err := docker.CliPull(name); err != nil {
+	if err := docker.CliPull(ctx, i.pullArgs(name)...); err != nil {
 		return err
 	}
 
@@ -205,12 +275,12 @@ func (i *StageImage) Import(name string) error {
 	return nil
 }
 
-func (i *StageImage) Export(name string) error {
+func (i *StageImage) Export(ctx context.Context, name string) error {
 	if err := i.Tag(name); err != nil {
 		return err
 	}
 
-	if err := docker.CliPush(name); err != nil {
+	if err := docker.CliPush(ctx, name); err != nil {
 		return err
 	}
 
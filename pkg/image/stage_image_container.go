@@ -3,7 +3,10 @@ package image
 import (
 	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 
@@ -61,6 +64,9 @@ func (c *StageImageContainer) ServiceCommitChangeOptions() ContainerOptions {
 func (c *StageImageContainer) prepareRunArgs() ([]string, error) {
 	var args []string
 	args = append(args, fmt.Sprintf("--name=%s", c.name))
+	if c.image.platform != "" {
+		args = append(args, fmt.Sprintf("--platform=%s", c.image.platform))
+	}
 
 	runOptions, err := c.prepareRunOptions()
 	if err != nil {
@@ -252,6 +258,69 @@ func (c *StageImageContainer) run() error {
 	return nil
 }
 
+// runWithTimeout kills the container, which runs longer than timeout, so logs of the killed container can be saved
+func (c *StageImageContainer) runWithTimeout(timeout time.Duration) error {
+	if timeout == 0 {
+		return c.run()
+	}
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- c.run()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-runErrCh:
+		return err
+	case <-timer.C:
+	}
+
+	// the container does not exist until the base image is pulled and the container is created,
+	// so the kill is retried until it succeeds and the run always finishes before return
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	killed := false
+	for {
+		if !killed {
+			if exist, err := docker.ContainerExist(c.name); err == nil && exist {
+				killed = docker.ContainerKill(c.name) == nil
+			}
+		}
+
+		select {
+		case err := <-runErrCh:
+			if !killed {
+				return err
+			}
+			return fmt.Errorf("container run failed: timeout %s exceeded, container %s is killed", timeout, c.name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveLogs writes stdout and stderr of the finished container into the file
+func (c *StageImageContainer) saveLogs(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := docker.ContainerLogs(c.name, f, f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
 func (c *StageImageContainer) introspect() error {
 	runArgs, err := c.prepareIntrospectArgs()
 	if err != nil {
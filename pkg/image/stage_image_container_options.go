@@ -12,6 +12,7 @@ import (
 type StageImageContainerOptions struct {
 	Volume      []string
 	VolumesFrom []string
+	Tmpfs       []string
 	Expose      []string
 	Env         map[string]string
 	Label       map[string]string
@@ -39,6 +40,11 @@ func (co *StageImageContainerOptions) AddVolumeFrom(volumesFrom ...string) {
 	co.VolumesFrom = append(co.VolumesFrom, volumesFrom...)
 }
 
+// AddTmpfs mounts tmpfs into the container, tmpfs content is not committed into the image
+func (co *StageImageContainerOptions) AddTmpfs(paths ...string) {
+	co.Tmpfs = append(co.Tmpfs, paths...)
+}
+
 func (co *StageImageContainerOptions) AddExpose(exposes ...string) {
 	co.Expose = append(co.Expose, exposes...)
 }
@@ -87,6 +93,7 @@ func (co *StageImageContainerOptions) merge(co2 *StageImageContainerOptions) *St
 	mergedCo := newStageContainerOptions()
 	mergedCo.Volume = append(co.Volume, co2.Volume...)
 	mergedCo.VolumesFrom = append(co.VolumesFrom, co2.VolumesFrom...)
+	mergedCo.Tmpfs = append(co.Tmpfs, co2.Tmpfs...)
 	mergedCo.Expose = append(co.Expose, co2.Expose...)
 
 	for env, value := range co.Env {
@@ -159,6 +166,10 @@ func (co *StageImageContainerOptions) toRunArgs() ([]string, error) {
 		args = append(args, fmt.Sprintf("--volumes-from=%s", volumesFrom))
 	}
 
+	for _, tmpfs := range co.Tmpfs {
+		args = append(args, fmt.Sprintf("--tmpfs=%s", tmpfs))
+	}
+
 	for key, value := range co.Env {
 		args = append(args, fmt.Sprintf("--env=%s=%v", key, value))
 	}
@@ -9,7 +9,7 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/logger"
 )
 
 func NewFileLock(name string, locksDir string) LockObject {
@@ -71,11 +71,11 @@ type fileLocker struct {
 
 	FileLock        *File
 	openFileHandler *os.File
+	infoFilePath    string
 }
 
 func (locker *fileLocker) lockFilePath() string {
-	fileName := util.MurmurHash(locker.FileLock.GetName())
-	return filepath.Join(locker.FileLock.LocksDir, fileName)
+	return filepath.Join(locker.FileLock.LocksDir, lockFileName(locker.FileLock.GetName()))
 }
 
 func (locker *fileLocker) Lock() error {
@@ -97,12 +97,22 @@ func (locker *fileLocker) Lock() error {
 	err = syscall.Flock(fd, mode|syscall.LOCK_NB)
 
 	if err == syscall.EWOULDBLOCK {
-		return locker.OnWait(func() error {
+		err = locker.OnWait(func() error {
 			return locker.pollFlock(fd, mode)
 		})
 	}
 
-	return err
+	if err != nil {
+		locker.openFileHandler.Close()
+		locker.openFileHandler = nil
+		return err
+	}
+
+	if locker.infoFilePath, err = writeLockInfo(locker.FileLock.GetName(), locker.ReadOnly); err != nil {
+		logger.LogWarningF("WARNING: cannot write lock `%s` info: %s\n", locker.FileLock.GetName(), err)
+	}
+
+	return nil
 }
 
 func (locker *fileLocker) pollFlock(fd int, mode int) error {
@@ -126,16 +136,32 @@ func (locker *fileLocker) pollFlock(fd int, mode int) error {
 		}
 	}()
 
-	select {
-	case err := <-flockRes:
-		return err
-	case <-time.After(locker.Timeout):
-		cancelPoll <- true
-		return fmt.Errorf("lock `%s` timeout %s expired", locker.FileLock.GetName(), locker.Timeout)
+	reportTicker := time.NewTicker(WaitReportPeriod)
+	defer reportTicker.Stop()
+
+	timeout := time.After(locker.Timeout)
+
+	for {
+		select {
+		case err := <-flockRes:
+			return err
+		case <-reportTicker.C:
+			reportWaiting(locker.FileLock.GetName())
+		case <-timeout:
+			cancelPoll <- true
+			return fmt.Errorf("lock `%s` timeout %s expired", locker.FileLock.GetName(), locker.Timeout)
+		}
 	}
 }
 
 func (locker *fileLocker) Unlock() error {
+	if locker.infoFilePath != "" {
+		if err := removeLockInfo(locker.infoFilePath); err != nil {
+			logger.LogWarningF("WARNING: cannot remove lock `%s` info: %s\n", locker.FileLock.GetName(), err)
+		}
+		locker.infoFilePath = ""
+	}
+
 	err := locker.openFileHandler.Close()
 	if err != nil {
 		return err
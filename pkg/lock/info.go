@@ -0,0 +1,196 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+// WaitReportPeriod is a period of messages about lock holders while waiting for locked resource
+var WaitReportPeriod = 30 * time.Second
+
+// LockInfo describes process holding the lock
+type LockInfo struct {
+	Name     string    `json:"name"`
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	ReadOnly bool      `json:"readOnly"`
+	Since    time.Time `json:"since"`
+
+	filePath string
+}
+
+// IsStale returns true if lock holder process is not running anymore.
+// Processes of other hosts are considered alive.
+func (info *LockInfo) IsStale() bool {
+	if info.Hostname != getHostname() {
+		return false
+	}
+
+	process, err := os.FindProcess(info.PID)
+	if err != nil {
+		return true
+	}
+
+	return process.Signal(syscall.Signal(0)) != nil
+}
+
+func (info *LockInfo) String() string {
+	mode := "exclusive"
+	if info.ReadOnly {
+		mode = "shared"
+	}
+
+	return fmt.Sprintf("held by PID %d on host %s since %s (%s)", info.PID, info.Hostname, info.Since.Format(time.RFC3339), mode)
+}
+
+func lockFileName(name string) string {
+	return util.MurmurHash(name)
+}
+
+func lockInfoDir() string {
+	return filepath.Join(LocksDir, "info")
+}
+
+// lockInfoSeq numbers lock acquisitions of the process, so each acquisition has its own info file
+var lockInfoSeq uint64
+
+func newLockInfoFilePath(name string) string {
+	return filepath.Join(lockInfoDir(), fmt.Sprintf("%s.%s.%d.%d", lockFileName(name), getHostname(), os.Getpid(), atomic.AddUint64(&lockInfoSeq, 1)))
+}
+
+func getHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// writeLockInfo writes info of the lock acquisition and returns path of the info file
+func writeLockInfo(name string, readOnly bool) (string, error) {
+	info := &LockInfo{
+		Name:     name,
+		PID:      os.Getpid(),
+		Hostname: getHostname(),
+		ReadOnly: readOnly,
+		Since:    time.Now(),
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(lockInfoDir(), 0755); err != nil {
+		return "", err
+	}
+
+	path := newLockInfoFilePath(name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func removeLockInfo(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func readLocksInfo(pattern string) ([]*LockInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(lockInfoDir(), pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*LockInfo
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		info := &LockInfo{filePath: path}
+		if err := json.Unmarshal(data, info); err != nil {
+			return nil, fmt.Errorf("bad lock info file %s: %s", path, err)
+		}
+
+		res = append(res, info)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name == res[j].Name {
+			return res[i].Since.Before(res[j].Since)
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// GetLockHolders returns info about all processes holding the lock
+func GetLockHolders(name string) ([]*LockInfo, error) {
+	return readLocksInfo(fmt.Sprintf("%s.*", lockFileName(name)))
+}
+
+// List returns info about all held locks
+func List() ([]*LockInfo, error) {
+	return readLocksInfo("*")
+}
+
+// ForceRelease removes holders info of the lock. The lock file itself is not removed: the lock is held by the system
+// until the holder process exits, and the removed file would let new processes lock the new file, while the holder still runs.
+// Info of the running holders (holders of other hosts are considered running) is kept unless force is specified.
+func ForceRelease(name string, force bool) error {
+	holders, err := GetLockHolders(name)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		for _, holder := range holders {
+			if !holder.IsStale() {
+				return fmt.Errorf("lock is %s, which is running", holder.String())
+			}
+		}
+	}
+
+	for _, holder := range holders {
+		if err := removeLockInfo(holder.filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func reportWaiting(name string) {
+	holders, err := GetLockHolders(name)
+	if err != nil || len(holders) == 0 {
+		fmt.Printf("Still waiting for locked resource `%s` ...\n", name)
+		return
+	}
+
+	var descs []string
+	for _, holder := range holders {
+		descs = append(descs, holder.String())
+	}
+
+	fmt.Printf("Still waiting for locked resource `%s` %s ...\n", name, strings.Join(descs, ", "))
+}
@@ -0,0 +1,103 @@
+package lock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func initTestLocksDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "werf-locks-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevLocksDir := LocksDir
+	LocksDir = dir
+
+	return func() {
+		LocksDir = prevLocksDir
+		os.RemoveAll(dir)
+	}
+}
+
+func writeTestLockInfo(t *testing.T, name string) string {
+	path, err := writeLockInfo(name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func expectLockHolders(t *testing.T, name string, expected int) {
+	holders, err := GetLockHolders(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(holders) != expected {
+		t.Errorf("\n[EXPECTED]: %d holders\n[GOT]: %#v", expected, holders)
+	}
+}
+
+func TestLockInfo_perAcquisition(t *testing.T) {
+	defer initTestLocksDir(t)()
+
+	first := writeTestLockInfo(t, "test")
+	second := writeTestLockInfo(t, "test")
+	if first == second {
+		t.Fatalf("\n[EXPECTED]: different info files of the acquisitions\n[GOT]: %s", first)
+	}
+	expectLockHolders(t, "test", 2)
+
+	if err := removeLockInfo(first); err != nil {
+		t.Fatal(err)
+	}
+	expectLockHolders(t, "test", 1)
+}
+
+func TestForceRelease(t *testing.T) {
+	defer initTestLocksDir(t)()
+
+	lockFilePath := filepath.Join(LocksDir, lockFileName("test"))
+	if err := ioutil.WriteFile(lockFilePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// holder process of the stale info does not exist
+	if err := os.MkdirAll(lockInfoDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(&LockInfo{Name: "test", PID: 1 << 30, Hostname: getHostname(), Since: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(lockInfoDir(), lockFileName("test")+".stale"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ForceRelease("test", false); err != nil {
+		t.Fatal(err)
+	}
+	expectLockHolders(t, "test", 0)
+
+	writeTestLockInfo(t, "test")
+
+	if err := ForceRelease("test", false); err == nil {
+		t.Errorf("\n[EXPECTED]: error about the running holder")
+	}
+	expectLockHolders(t, "test", 1)
+
+	if err := ForceRelease("test", true); err != nil {
+		t.Fatal(err)
+	}
+	expectLockHolders(t, "test", 0)
+
+	if _, err := os.Stat(lockFilePath); err != nil {
+		t.Errorf("\n[EXPECTED]: lock file is kept\n[GOT]: %s", err)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/flant/werf/pkg/telemetry"
 	"github.com/flant/werf/pkg/werf"
 )
 
@@ -13,11 +15,16 @@ var (
 	LocksDir       string
 	Locks          map[string]LockObject
 	DefaultTimeout = 24 * time.Hour
+
+	locksMux sync.Mutex
 )
 
 func Init() error {
+	locksMux.Lock()
+	defer locksMux.Unlock()
+
 	Locks = make(map[string]LockObject)
-	LocksDir = filepath.Join(werf.GetHomeDir(), "locks")
+	LocksDir = filepath.Join(werf.GetBaseHomeDir(), "locks")
 
 	err := os.MkdirAll(LocksDir, 0755)
 	if err != nil {
@@ -42,12 +49,14 @@ func Lock(name string, opts LockOptions) error {
 }
 
 func Unlock(name string) error {
-	if _, hasKey := Locks[name]; !hasKey {
+	locksMux.Lock()
+	lock, hasKey := Locks[name]
+	locksMux.Unlock()
+
+	if !hasKey {
 		return fmt.Errorf("no such lock `%s` found", name)
 	}
 
-	lock := getLock(name)
-
 	return lock.Unlock()
 }
 
@@ -64,7 +73,9 @@ func WithLock(name string, opts LockOptions, f func() error) error {
 func onWait(name string, doWait func() error) error {
 	fmt.Printf("Waiting for locked resource `%s` ...\n", name)
 
+	start := time.Now()
 	err := doWait()
+	telemetry.ObserveLockWait(time.Since(start))
 	if err != nil {
 		return err
 	}
@@ -82,6 +93,9 @@ func getTimeout(opts LockOptions) time.Duration {
 }
 
 func getLock(name string) LockObject {
+	locksMux.Lock()
+	defer locksMux.Unlock()
+
 	if l, hasKey := Locks[name]; hasKey {
 		return l
 	}
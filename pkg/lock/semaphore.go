@@ -0,0 +1,109 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flant/werf/pkg/telemetry"
+)
+
+// Resources, which usage by werf processes of the host can be limited
+const (
+	BuildResource = "build"
+	PushResource  = "push"
+)
+
+// SlotPollPeriod is a period of checking for the free slot while all slots are busy
+var SlotPollPeriod = time.Second
+
+// errSlotBusy aborts waiting for the busy slot, so the next slot can be checked
+var errSlotBusy = errors.New("slot is busy")
+
+// WithHostSlot runs f holding one of the slots of the resource.
+// WERF_HOST_MAX_BUILDS and WERF_HOST_MAX_PUSHES limit number of builds and pushes of werf processes of all projects on the host,
+// WERF_PROJECT_MAX_BUILDS and WERF_PROJECT_MAX_PUSHES limit the same for processes of the project (no limits by default).
+// Processes of the project wait for the host slot one by one, so the project with many parallel pipelines does not take all host slots.
+func WithHostSlot(resource, project string, f func() error) error {
+	hostSlots := slotsLimit(fmt.Sprintf("WERF_HOST_MAX_%sS", strings.ToUpper(resource)))
+	projectSlots := slotsLimit(fmt.Sprintf("WERF_PROJECT_MAX_%sS", strings.ToUpper(resource)))
+
+	if projectSlots > 0 {
+		projectSlot, err := acquireSlot(fmt.Sprintf("%s.%s", project, resource), projectSlots)
+		if err != nil {
+			return err
+		}
+		defer Unlock(projectSlot)
+	}
+
+	if hostSlots > 0 {
+		var hostSlot string
+		if err := WithLock(fmt.Sprintf("%s.%s.queue", project, resource), LockOptions{}, func() error {
+			var err error
+			hostSlot, err = acquireSlot(fmt.Sprintf("host.%s", resource), hostSlots)
+			return err
+		}); err != nil {
+			return err
+		}
+		defer Unlock(hostSlot)
+	}
+
+	return f()
+}
+
+func slotsLimit(envName string) int {
+	value := os.Getenv(envName)
+	if value == "" {
+		return 0
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: bad %s value '%s': non-negative integer expected, no limit is used\n", envName, value)
+		return 0
+	}
+
+	return limit
+}
+
+// acquireSlot locks one of the free slots of the semaphore and returns lock name of the slot.
+// Slots are exclusive file locks, so slots of killed processes are released by the system.
+func acquireSlot(name string, slots int) (string, error) {
+	var waitStart, lastReport time.Time
+
+	for {
+		for ind := 0; ind < slots; ind++ {
+			slotName := fmt.Sprintf("%s.slot.%d", name, ind)
+
+			err := getLock(slotName).Lock(DefaultTimeout, false, func(_ func() error) error { return errSlotBusy })
+			if err == errSlotBusy {
+				continue
+			} else if err != nil {
+				return "", err
+			}
+
+			if !waitStart.IsZero() {
+				telemetry.ObserveLockWait(time.Since(waitStart))
+				fmt.Printf("Waiting for free slot of `%s` DONE\n", name)
+			}
+
+			return slotName, nil
+		}
+
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+			lastReport = waitStart
+			fmt.Printf("Waiting for free slot of `%s`: all %d slots are busy ...\n", name, slots)
+		} else if time.Since(waitStart) > DefaultTimeout {
+			return "", fmt.Errorf("waiting for free slot of `%s` timeout %s expired", name, DefaultTimeout)
+		} else if time.Since(lastReport) > WaitReportPeriod {
+			lastReport = time.Now()
+			fmt.Printf("Still waiting for free slot of `%s` ...\n", name)
+		}
+
+		time.Sleep(SlotPollPeriod)
+	}
+}
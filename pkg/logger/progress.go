@@ -0,0 +1,319 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flant/werf/pkg/logger/terminal"
+)
+
+const (
+	ProgressAuto  = "auto"
+	ProgressBar   = "bar"
+	ProgressLines = "lines"
+	ProgressNone  = "none"
+
+	progressBarWidth            = 30
+	progressBarRenderInterval   = 100 * time.Millisecond
+	progressLinesRenderInterval = 10 * time.Second
+)
+
+var progressMode = ProgressAuto
+
+// SetProgressMode selects how progress of long operations is shown: auto (bars on TTY, lines otherwise), bar, lines or none
+func SetProgressMode(mode string) error {
+	switch mode {
+	case "":
+		progressMode = ProgressAuto
+	case ProgressAuto, ProgressBar, ProgressLines, ProgressNone:
+		progressMode = mode
+	default:
+		return fmt.Errorf("bad progress mode '%s': expected %s, %s, %s or %s", mode, ProgressAuto, ProgressBar, ProgressLines, ProgressNone)
+	}
+
+	return nil
+}
+
+func GetProgressMode() string {
+	if progressMode == ProgressAuto {
+		if terminal.IsTerminal() {
+			return ProgressBar
+		}
+		return ProgressLines
+	}
+
+	return progressMode
+}
+
+// Progress renders progress of the operation: interactive bar on TTY or periodic percentage lines in CI.
+// Total can be unknown (zero), in this case only counter is shown
+type Progress struct {
+	msg     string
+	isBytes bool
+	mode    string
+
+	total, current int64
+
+	start      time.Time
+	lastRender time.Time
+	rendered   bool
+	done       bool
+	mutex      sync.Mutex
+}
+
+func NewProgress(msg string, total int64) *Progress {
+	return newProgress(msg, total, false)
+}
+
+// NewBytesProgress creates progress, which counters are shown in bytes units
+func NewBytesProgress(msg string, total int64) *Progress {
+	return newProgress(msg, total, true)
+}
+
+func newProgress(msg string, total int64, isBytes bool) *Progress {
+	now := time.Now()
+	return &Progress{msg: msg, total: total, isBytes: isBytes, mode: GetProgressMode(), start: now, lastRender: now}
+}
+
+func (p *Progress) SetTotal(total int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.total = total
+	p.render(false)
+}
+
+func (p *Progress) Set(current int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.current = current
+	p.render(false)
+}
+
+func (p *Progress) Add(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.current += n
+	p.render(false)
+}
+
+// Done renders the final state, progress cannot be updated after that
+func (p *Progress) Done() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.done {
+		return
+	}
+
+	if p.total > 0 && p.current < p.total {
+		p.current = p.total
+	}
+
+	p.render(true)
+	p.done = true
+}
+
+// Writer returns writer, which adds written bytes to the progress
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	return &progressWriter{w: w, progress: p}
+}
+
+type progressWriter struct {
+	w        io.Writer
+	progress *Progress
+}
+
+func (w *progressWriter) Write(data []byte) (int, error) {
+	n, err := w.w.Write(data)
+	w.progress.Add(int64(n))
+	return n, err
+}
+
+func (p *Progress) render(final bool) {
+	if p.done {
+		return
+	}
+
+	now := time.Now()
+	percent := p.percent()
+
+	switch p.mode {
+	case ProgressBar:
+		if !final && now.Sub(p.lastRender) < progressBarRenderInterval {
+			return
+		}
+
+		line := logIndent() + p.msg
+		if p.total > 0 {
+			line += " " + p.bar(percent)
+		}
+		line += " " + p.counter()
+		if final {
+			line += " " + strings.TrimSpace(fmt.Sprintf(logProcessTimeFormat, now.Sub(p.start).Seconds()))
+		}
+
+		if width := terminal.Width(); len(line) > width {
+			line = line[:width]
+		} else {
+			line += strings.Repeat(" ", width-len(line))
+		}
+
+		logBase(os.Stdout, "\r"+line)
+		if final {
+			logBase(os.Stdout, "\n")
+		}
+	case ProgressLines:
+		if !final && now.Sub(p.lastRender) < progressLinesRenderInterval {
+			return
+		}
+
+		// do not spam log with short operations
+		if final && !p.rendered && now.Sub(p.start) < progressLinesRenderInterval {
+			return
+		}
+
+		line := fmt.Sprintf("%s %s", p.msg, p.counter())
+		if p.total > 0 {
+			line = fmt.Sprintf("%s %d%% %s", p.msg, percent, p.counter())
+		}
+		if final {
+			line += " DONE " + strings.TrimSpace(fmt.Sprintf(logProcessTimeFormat, now.Sub(p.start).Seconds()))
+		}
+
+		log(os.Stdout, line)
+	default:
+		return
+	}
+
+	p.lastRender = now
+	p.rendered = true
+}
+
+func (p *Progress) percent() int {
+	if p.total <= 0 {
+		return 0
+	}
+
+	percent := int(p.current * 100 / p.total)
+	if percent > 100 {
+		return 100
+	}
+
+	return percent
+}
+
+func (p *Progress) bar(percent int) string {
+	filled := progressBarWidth * percent / 100
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("[%s] %3d%%", bar, percent)
+}
+
+func (p *Progress) counter() string {
+	format := func(n int64) string {
+		if p.isBytes {
+			return formatBytes(n)
+		}
+		return strconv.FormatInt(n, 10)
+	}
+
+	if p.total > 0 {
+		return fmt.Sprintf("(%s/%s)", format(p.current), format(p.total))
+	}
+
+	return fmt.Sprintf("(%s)", format(p.current))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+var gitProgressLineRegexp = regexp.MustCompile(`^(?:remote: )?([A-Za-z ]+):\s+(\d+)% \((\d+)/(\d+)\)`)
+
+// GitProgress is go-git Progress sideband writer, which renders git phases (counting, compressing, receiving objects) as progress
+type GitProgress struct {
+	msg      string
+	buf      string
+	phase    string
+	progress *Progress
+	mutex    sync.Mutex
+}
+
+func NewGitProgress(msg string) *GitProgress {
+	return &GitProgress{msg: msg}
+}
+
+func (g *GitProgress) Write(data []byte) (int, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.buf += string(data)
+	for {
+		ind := strings.IndexAny(g.buf, "\r\n")
+		if ind == -1 {
+			break
+		}
+
+		g.handleLine(g.buf[:ind])
+		g.buf = g.buf[ind+1:]
+	}
+
+	return len(data), nil
+}
+
+func (g *GitProgress) handleLine(line string) {
+	matches := gitProgressLineRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return
+	}
+
+	phase := strings.TrimSpace(matches[1])
+	current, _ := strconv.ParseInt(matches[3], 10, 64)
+	total, _ := strconv.ParseInt(matches[4], 10, 64)
+
+	if phase != g.phase || g.progress == nil {
+		if g.progress != nil {
+			g.progress.Done()
+		}
+
+		g.phase = phase
+		g.progress = NewProgress(fmt.Sprintf("%s: %s", g.msg, strings.ToLower(phase)), total)
+	}
+
+	g.progress.SetTotal(total)
+	g.progress.Set(current)
+}
+
+// Done finishes progress of the last git phase
+func (g *GitProgress) Done() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.progress != nil {
+		g.progress.Done()
+		g.progress = nil
+	}
+}
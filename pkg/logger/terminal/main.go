@@ -107,3 +107,7 @@ func fitText(text string, contentWidth int) string {
 
 	return result
 }
+
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,56 @@
+package scan
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+type clairVulnerability struct {
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	Severity           string `json:"severity"`
+	NormalizedSeverity string `json:"normalized_severity"`
+	FixedInVersion     string `json:"fixed_in_version"`
+	Package            struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"package"`
+}
+
+// parseClairReport parses clair v4 vulnerability report
+func parseClairReport(data []byte) ([]*Finding, error) {
+	report := struct {
+		Vulnerabilities map[string]clairVulnerability `json:"vulnerabilities"`
+	}{}
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id := range report.Vulnerabilities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var findings []*Finding
+	for _, id := range ids {
+		vuln := report.Vulnerabilities[id]
+
+		severity := vuln.NormalizedSeverity
+		if severity == "" {
+			severity = vuln.Severity
+		}
+
+		findings = append(findings, &Finding{
+			ID:               vuln.Name,
+			Package:          vuln.Package.Name,
+			InstalledVersion: vuln.Package.Version,
+			FixedVersion:     vuln.FixedInVersion,
+			Severity:         NormalizeSeverity(severity),
+			Title:            vuln.Description,
+		})
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,61 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClairReport(t *testing.T) {
+	report := `{
+  "manifest_hash": "sha256:0000",
+  "vulnerabilities": {
+    "2": {
+      "name": "CVE-2019-14697",
+      "description": "musl libc through 1.1.23 has an x87 floating-point stack adjustment imbalance",
+      "severity": "High",
+      "normalized_severity": "High",
+      "fixed_in_version": "1.1.20-r5",
+      "package": {"name": "musl", "version": "1.1.20-r4"}
+    },
+    "1": {
+      "name": "CVE-2018-0000",
+      "severity": "Negligible",
+      "package": {"name": "busybox", "version": "1.29.3-r10"}
+    }
+  }
+}`
+
+	findings, err := parseClairReport([]byte(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Finding{
+		{
+			ID:               "CVE-2018-0000",
+			Package:          "busybox",
+			InstalledVersion: "1.29.3-r10",
+			Severity:         "LOW",
+		},
+		{
+			ID:               "CVE-2019-14697",
+			Package:          "musl",
+			InstalledVersion: "1.1.20-r4",
+			FixedVersion:     "1.1.20-r5",
+			Severity:         "HIGH",
+			Title:            "musl libc through 1.1.23 has an x87 floating-point stack adjustment imbalance",
+		},
+	}
+
+	if !reflect.DeepEqual(expected, findings) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, findings)
+	}
+}
+
+func TestParseClairReport_negative(t *testing.T) {
+	for _, report := range []string{"", "[]", `{"vulnerabilities": []}`} {
+		if _, err := parseClairReport([]byte(report)); err == nil {
+			t.Errorf("\n[EXPECTED]: error for report %q\n[GOT]: nil", report)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// SCAN_CACHE_VERSION should be bumped when format of the cached findings is changed
+const SCAN_CACHE_VERSION = "1"
+
+// CacheTTL limits usage of the cached findings: vulnerability databases are updated, so the same image should be scanned again
+var CacheTTL = 24 * time.Hour
+
+const (
+	TrivyScanner = "trivy"
+	ClairScanner = "clair"
+)
+
+var DefaultScannerImages = map[string]string{
+	TrivyScanner: "aquasec/trivy:0.18.3",
+	ClairScanner: "quay.io/projectquay/clair-action:v0.0.1",
+}
+
+// Finding is a vulnerability found by the scanner in the image package
+type Finding struct {
+	ID               string `json:"id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title,omitempty"`
+}
+
+type Options struct {
+	// Scanner is one of TrivyScanner or ClairScanner
+	Scanner string
+	// ScannerImage overrides default image of the scanner
+	ScannerImage string
+}
+
+func (opts Options) scannerImage() string {
+	if opts.ScannerImage != "" {
+		return opts.ScannerImage
+	}
+
+	return DefaultScannerImages[opts.Scanner]
+}
+
+func ValidateScanner(scanner string) error {
+	if _, ok := DefaultScannerImages[scanner]; !ok {
+		return fmt.Errorf("unknown scanner '%s': expected %s or %s", scanner, TrivyScanner, ClairScanner)
+	}
+
+	return nil
+}
+
+// ScanImage runs the scanner in the container against the local image and returns found vulnerabilities.
+// Findings are cached by the image id, returns true if cached findings are used.
+func ScanImage(imageName, imageID, tmpDir string, opts Options) ([]*Finding, bool, error) {
+	cachePath := filepath.Join(werf.GetHomeDir(), "scan", SCAN_CACHE_VERSION, util.Sha256Hash(opts.Scanner, opts.scannerImage(), imageID))
+
+	if findings, err := readCachedFindings(cachePath); err != nil {
+		return nil, false, err
+	} else if findings != nil {
+		return findings, true, nil
+	}
+
+	findings, err := runScanner(imageName, tmpDir, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := writeCachedFindings(cachePath, findings); err != nil {
+		return nil, false, err
+	}
+
+	return findings, false, nil
+}
+
+func runScanner(imageName, tmpDir string, opts Options) ([]*Finding, error) {
+	scanDir := filepath.Join(tmpDir, fmt.Sprintf("scan-%s", util.GenerateConsistentRandomString(10)))
+	if err := os.MkdirAll(scanDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scanDir)
+
+	// image is passed to the scanner as docker archive, so that scanner does not need access to the container runtime
+	if err := docker.CliSave("--output", filepath.Join(scanDir, "image.tar"), imageName); err != nil {
+		return nil, fmt.Errorf("cannot save image %s: %s", imageName, err)
+	}
+
+	dbDir := filepath.Join(werf.GetHomeDir(), "scan", "db", opts.Scanner)
+	if err := os.MkdirAll(dbDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--rm",
+		"--volume", fmt.Sprintf("%s:/scan", scanDir),
+		"--volume", fmt.Sprintf("%s:/scan-db", dbDir),
+	}
+
+	var parse func(data []byte) ([]*Finding, error)
+	switch opts.Scanner {
+	case TrivyScanner:
+		args = append(args, opts.scannerImage(), "--cache-dir", "/scan-db", "image", "--quiet", "--format", "json", "--output", "/scan/report.json", "--input", "/scan/image.tar")
+		parse = parseTrivyReport
+	case ClairScanner:
+		args = append(args, "--entrypoint", "/bin/sh", opts.scannerImage(), "-c", "clair-action report --image-path=/scan/image.tar --db-path=/scan-db/matcher.db --format=json > /scan/report.json")
+		parse = parseClairReport
+	default:
+		return nil, ValidateScanner(opts.Scanner)
+	}
+
+	if err := docker.CliRun(args...); err != nil {
+		return nil, fmt.Errorf("%s scanner failed: %s", opts.Scanner, err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(scanDir, "report.json"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s scanner report: %s", opts.Scanner, err)
+	}
+
+	findings, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s scanner report: %s", opts.Scanner, err)
+	}
+
+	return findings, nil
+}
+
+func readCachedFindings(path string) ([]*Finding, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if time.Since(info.ModTime()) > CacheTTL {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []*Finding{}
+	if err := json.Unmarshal(data, &findings); err != nil {
+		// broken cache is ignored, image is scanned again
+		return nil, nil
+	}
+
+	return findings, nil
+}
+
+func writeCachedFindings(path string, findings []*Finding) error {
+	if findings == nil {
+		findings = []*Finding{}
+	}
+
+	data, err := json.Marshal(findings)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("cannot write scan cache %s: %s", path, err)
+	}
+
+	return nil
+}
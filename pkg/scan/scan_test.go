@@ -0,0 +1,87 @@
+package scan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCachedFindings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-scan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "scan", SCAN_CACHE_VERSION, "hash")
+
+	if findings, err := readCachedFindings(path); err != nil || findings != nil {
+		t.Errorf("\n[EXPECTED]: no cached findings\n[GOT]: %#v, %v", findings, err)
+	}
+
+	expected := []*Finding{{ID: "CVE-2019-14697", Package: "musl", InstalledVersion: "1.1.20-r4", Severity: "HIGH"}}
+	if err := writeCachedFindings(path, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := readCachedFindings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, findings) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, findings)
+	}
+
+	// image without vulnerabilities is cached too
+	if err := writeCachedFindings(path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings, err := readCachedFindings(path); err != nil || findings == nil || len(findings) != 0 {
+		t.Errorf("\n[EXPECTED]: empty cached findings\n[GOT]: %#v, %v", findings, err)
+	}
+
+	expired := time.Now().Add(-CacheTTL - time.Minute)
+	if err := os.Chtimes(path, expired, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings, err := readCachedFindings(path); err != nil || findings != nil {
+		t.Errorf("\n[EXPECTED]: expired cache is ignored\n[GOT]: %#v, %v", findings, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings, err := readCachedFindings(path); err != nil || findings != nil {
+		t.Errorf("\n[EXPECTED]: broken cache is ignored\n[GOT]: %#v, %v", findings, err)
+	}
+}
+
+func TestValidateScanner(t *testing.T) {
+	for _, scanner := range []string{TrivyScanner, ClairScanner} {
+		if err := ValidateScanner(scanner); err != nil {
+			t.Errorf("\n[EXPECTED]: nil\n[GOT]: %s", err)
+		}
+	}
+
+	expected := "unknown scanner 'grype': expected trivy or clair"
+	if err := ValidateScanner("grype"); err == nil || err.Error() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", expected, err)
+	}
+}
+
+func TestOptions_scannerImage(t *testing.T) {
+	if image := (Options{Scanner: TrivyScanner}).scannerImage(); image != DefaultScannerImages[TrivyScanner] {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", DefaultScannerImages[TrivyScanner], image)
+	}
+
+	if image := (Options{Scanner: ClairScanner, ScannerImage: "registry.local/clair:latest"}).scannerImage(); image != "registry.local/clair:latest" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "registry.local/clair:latest", image)
+	}
+}
@@ -0,0 +1,49 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities are normalized severities of the findings in ascending order
+var Severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// NormalizeSeverity converts severity reported by the scanner into one of Severities
+func NormalizeSeverity(severity string) string {
+	switch s := strings.ToUpper(severity); s {
+	case "NEGLIGIBLE":
+		return "LOW"
+	case "MODERATE", "IMPORTANT":
+		return "MEDIUM"
+	case "DEFCON1":
+		return "CRITICAL"
+	default:
+		if severityLevel(s) == -1 {
+			return "UNKNOWN"
+		}
+		return s
+	}
+}
+
+func ValidateSeverity(severity string) error {
+	if severityLevel(strings.ToUpper(severity)) == -1 {
+		return fmt.Errorf("bad severity '%s': expected one of %s", severity, strings.Join(Severities, ", "))
+	}
+
+	return nil
+}
+
+// IsSeverityAtLeast reports whether normalized severity is equal to or above the threshold
+func IsSeverityAtLeast(severity, threshold string) bool {
+	return severityLevel(severity) >= severityLevel(strings.ToUpper(threshold))
+}
+
+func severityLevel(severity string) int {
+	for ind, s := range Severities {
+		if s == severity {
+			return ind
+		}
+	}
+
+	return -1
+}
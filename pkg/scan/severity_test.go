@@ -0,0 +1,61 @@
+package scan
+
+import (
+	"testing"
+)
+
+func TestNormalizeSeverity(t *testing.T) {
+	tests := map[string]string{
+		"critical":   "CRITICAL",
+		"High":       "HIGH",
+		"MEDIUM":     "MEDIUM",
+		"low":        "LOW",
+		"Negligible": "LOW",
+		"Moderate":   "MEDIUM",
+		"Important":  "MEDIUM",
+		"Defcon1":    "CRITICAL",
+		"Unknown":    "UNKNOWN",
+		"":           "UNKNOWN",
+		"severe":     "UNKNOWN",
+	}
+
+	for severity, expected := range tests {
+		if result := NormalizeSeverity(severity); result != expected {
+			t.Errorf("NormalizeSeverity(%q)\n[EXPECTED]: %s\n[GOT]: %s", severity, expected, result)
+		}
+	}
+}
+
+func TestValidateSeverity(t *testing.T) {
+	for _, severity := range []string{"unknown", "low", "MEDIUM", "High", "critical"} {
+		if err := ValidateSeverity(severity); err != nil {
+			t.Errorf("\n[EXPECTED]: nil\n[GOT]: %s", err)
+		}
+	}
+
+	expected := "bad severity 'moderate': expected one of UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL"
+	if err := ValidateSeverity("moderate"); err == nil || err.Error() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", expected, err)
+	}
+}
+
+func TestIsSeverityAtLeast(t *testing.T) {
+	tests := []struct {
+		severity  string
+		threshold string
+		result    bool
+	}{
+		{"CRITICAL", "high", true},
+		{"HIGH", "HIGH", true},
+		{"MEDIUM", "high", false},
+		{"UNKNOWN", "low", false},
+		{"UNKNOWN", "unknown", true},
+		{"LOW", "unknown", true},
+	}
+
+	for _, test := range tests {
+		if result := IsSeverityAtLeast(test.severity, test.threshold); result != test.result {
+			t.Errorf("IsSeverityAtLeast(%q, %q)\n[EXPECTED]: %v\n[GOT]: %v", test.severity, test.threshold, test.result, result)
+		}
+	}
+}
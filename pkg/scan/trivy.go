@@ -0,0 +1,49 @@
+package scan
+
+import (
+	"encoding/json"
+)
+
+type trivyResult struct {
+	Target          string `json:"Target"`
+	Vulnerabilities []struct {
+		VulnerabilityID  string `json:"VulnerabilityID"`
+		PkgName          string `json:"PkgName"`
+		InstalledVersion string `json:"InstalledVersion"`
+		FixedVersion     string `json:"FixedVersion"`
+		Severity         string `json:"Severity"`
+		Title            string `json:"Title"`
+	} `json:"Vulnerabilities"`
+}
+
+// parseTrivyReport parses json report of trivy: list of results (before 0.20) or object with Results field
+func parseTrivyReport(data []byte) ([]*Finding, error) {
+	var results []trivyResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		report := struct {
+			Results []trivyResult `json:"Results"`
+		}{}
+
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+
+		results = report.Results
+	}
+
+	var findings []*Finding
+	for _, result := range results {
+		for _, vuln := range result.Vulnerabilities {
+			findings = append(findings, &Finding{
+				ID:               vuln.VulnerabilityID,
+				Package:          vuln.PkgName,
+				InstalledVersion: vuln.InstalledVersion,
+				FixedVersion:     vuln.FixedVersion,
+				Severity:         NormalizeSeverity(vuln.Severity),
+				Title:            vuln.Title,
+			})
+		}
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,74 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrivyReport(t *testing.T) {
+	vulnerabilities := `[
+  {
+    "Target": "alpine:3.9 (alpine 3.9.4)",
+    "Vulnerabilities": [
+      {
+        "VulnerabilityID": "CVE-2019-14697",
+        "PkgName": "musl",
+        "InstalledVersion": "1.1.20-r4",
+        "FixedVersion": "1.1.20-r5",
+        "Severity": "HIGH",
+        "Title": "musl libc through 1.1.23 has an x87 floating-point stack adjustment imbalance"
+      }
+    ]
+  },
+  {
+    "Target": "app/package-lock.json",
+    "Vulnerabilities": [
+      {
+        "VulnerabilityID": "GHSA-xxxx",
+        "PkgName": "lodash",
+        "InstalledVersion": "4.17.11",
+        "Severity": "moderate"
+      }
+    ]
+  },
+  {
+    "Target": "app/go.sum"
+  }
+]`
+
+	expected := []*Finding{
+		{
+			ID:               "CVE-2019-14697",
+			Package:          "musl",
+			InstalledVersion: "1.1.20-r4",
+			FixedVersion:     "1.1.20-r5",
+			Severity:         "HIGH",
+			Title:            "musl libc through 1.1.23 has an x87 floating-point stack adjustment imbalance",
+		},
+		{
+			ID:               "GHSA-xxxx",
+			Package:          "lodash",
+			InstalledVersion: "4.17.11",
+			Severity:         "MEDIUM",
+		},
+	}
+
+	for _, report := range []string{vulnerabilities, `{"SchemaVersion": 2, "Results": ` + vulnerabilities + `}`} {
+		findings, err := parseTrivyReport([]byte(report))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(expected, findings) {
+			t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expected, findings)
+		}
+	}
+}
+
+func TestParseTrivyReport_negative(t *testing.T) {
+	for _, report := range []string{"", "not json", `{"Results": "none"}`} {
+		if _, err := parseTrivyReport([]byte(report)); err == nil {
+			t.Errorf("\n[EXPECTED]: error for report %q\n[GOT]: nil", report)
+		}
+	}
+}
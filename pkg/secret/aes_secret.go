@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -27,6 +28,12 @@ func GenerateAexSecretKey() ([]byte, error) {
 	return result, nil
 }
 
+// AesKeyFingerprint identifies the key without disclosing it: first 8 bytes of the key sha256 hash
+func AesKeyFingerprint(key []byte) string {
+	hash := sha256.Sum256(bytes.ToLower(bytes.TrimSpace(key)))
+	return hex.EncodeToString(hash[:8])
+}
+
 func NewAesSecret(key []byte) (*AesSecret, error) {
 	key, err := hexToBinary(key)
 	if err != nil {
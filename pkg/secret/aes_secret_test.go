@@ -181,3 +181,17 @@ func TestAesSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestAesKeyFingerprint(t *testing.T) {
+	expected := "27b96261d61ba1bc"
+
+	for _, key := range []string{"11ac8312520b5ff037bae386ea2e8a07", "11AC8312520B5FF037BAE386EA2E8A07", " 11ac8312520b5ff037bae386ea2e8a07\n"} {
+		if fingerprint := AesKeyFingerprint([]byte(key)); fingerprint != expected {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, fingerprint)
+		}
+	}
+
+	if fingerprint := AesKeyFingerprint([]byte("21ac8312520b5ff037bae386ea2e8a07")); fingerprint == expected {
+		t.Errorf("fingerprints of different keys are equal: %s", fingerprint)
+	}
+}
@@ -0,0 +1,83 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const AgeScheme = "age"
+
+// AgeEncoder encrypts data for age recipients and decrypts data with age identity file using age binary
+type AgeEncoder struct {
+	Recipients   []string
+	IdentityFile string
+}
+
+func (e *AgeEncoder) Scheme() string {
+	return AgeScheme
+}
+
+func (e *AgeEncoder) Encrypt(data []byte) ([]byte, error) {
+	if len(e.Recipients) == 0 {
+		return nil, fmt.Errorf("age recipients are not specified")
+	}
+
+	var args []string
+	for _, recipient := range e.Recipients {
+		args = append(args, "--recipient", recipient)
+	}
+
+	encryptedData, err := runAge(data, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(encryptedData)), nil
+}
+
+func (e *AgeEncoder) Decrypt(data []byte) ([]byte, error) {
+	if e.IdentityFile == "" {
+		return nil, fmt.Errorf("age identity file is not specified")
+	}
+
+	encryptedData, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("bad age encrypted data: %s", err)
+	}
+
+	return runAge(encryptedData, "--decrypt", "--identity", e.IdentityFile)
+}
+
+// AgeIdentityRecipients returns public keys of the identity file using age-keygen binary
+func AgeIdentityRecipients(identityFile string) ([]string, error) {
+	cmd := exec.Command("age-keygen", "-y", identityFile)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("age-keygen failed: %s\n%s", err, stderr.String())
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+func runAge(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(input)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("age failed: %s\n%s", err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
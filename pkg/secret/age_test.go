@@ -0,0 +1,103 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAgeEncoder(t *testing.T) {
+	for _, binary := range []string{"age", "age-keygen"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			t.Skipf("%s binary is not available", binary)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "werf-age-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	identityFile := filepath.Join(dir, "key.txt")
+	if output, err := exec.Command("age-keygen", "-o", identityFile).CombinedOutput(); err != nil {
+		t.Fatalf("age-keygen failed: %s\n%s", err, output)
+	}
+
+	recipients, err := AgeIdentityRecipients(identityFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recipients) != 1 || !strings.HasPrefix(recipients[0], "age1") {
+		t.Fatalf("\n[EXPECTED]: age1... recipient\n[GOT]: %#v", recipients)
+	}
+
+	encoder := &AgeEncoder{Recipients: recipients, IdentityFile: identityFile}
+
+	for _, data := range []string{"", "value", "multi\nline\nvalue"} {
+		encryptedData, err := encoder.Encrypt([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := encoder.Decrypt(encryptedData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data != string(result) {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", data, result)
+		}
+	}
+}
+
+func TestAgeEncoder_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		encoder       *AgeEncoder
+		encrypt       bool
+		data          []byte
+		errorContains string
+	}{
+		{
+			name:          "noRecipients",
+			encoder:       &AgeEncoder{IdentityFile: "key.txt"},
+			encrypt:       true,
+			data:          []byte("value"),
+			errorContains: "age recipients are not specified",
+		},
+		{
+			name:          "noIdentityFile",
+			encoder:       &AgeEncoder{Recipients: []string{"age1recipient"}},
+			data:          []byte("ZGF0YQ=="),
+			errorContains: "age identity file is not specified",
+		},
+		{
+			name:          "badEncryptedData",
+			encoder:       &AgeEncoder{IdentityFile: "key.txt"},
+			data:          []byte("not base64!"),
+			errorContains: "bad age encrypted data",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			if test.encrypt {
+				_, err = test.encoder.Encrypt(test.data)
+			} else {
+				_, err = test.encoder.Decrypt(test.data)
+			}
+
+			if err == nil {
+				t.Errorf("Expected error: %s", test.errorContains)
+			} else if !strings.Contains(err.Error(), test.errorContains) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorContains, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Encoder is a secret encryption backend.
+// Data encrypted by the encoder is prefixed with the scheme header ($SCHEME$),
+// so values encrypted by different backends can be mixed in one repo
+type Encoder interface {
+	Scheme() string
+	Encrypt(data []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+}
+
+// SchemeSecret encrypts data with the default encoder and decrypts data with the encoder of the data scheme header.
+// Data without header is decrypted with the legacy aes secret
+type SchemeSecret struct {
+	DefaultEncoder Encoder
+	Encoders       map[string]Encoder
+	Legacy         Secret
+}
+
+func NewSchemeSecret(defaultEncoder Encoder, legacy Secret, encoders ...Encoder) *SchemeSecret {
+	s := &SchemeSecret{DefaultEncoder: defaultEncoder, Encoders: map[string]Encoder{}, Legacy: legacy}
+	for _, encoder := range append(encoders, defaultEncoder) {
+		if encoder != nil {
+			s.Encoders[encoder.Scheme()] = encoder
+		}
+	}
+
+	return s
+}
+
+func (s *SchemeSecret) Generate(data []byte) ([]byte, error) {
+	if s.DefaultEncoder == nil {
+		if s.Legacy == nil {
+			return nil, fmt.Errorf("no encryption backend configured")
+		}
+		return s.Legacy.Generate(data)
+	}
+
+	encryptedData, err := s.DefaultEncoder.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(schemeHeader(s.DefaultEncoder.Scheme()), encryptedData...), nil
+}
+
+func (s *SchemeSecret) Extract(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	scheme, encryptedData, ok := parseSchemeHeader(data)
+	if !ok {
+		if s.Legacy == nil {
+			return nil, fmt.Errorf("data is not encrypted by any of backends %s and legacy encryption key is not specified", s.schemes())
+		}
+		return s.Legacy.Extract(data)
+	}
+
+	encoder, hasEncoder := s.Encoders[scheme]
+	if !hasEncoder {
+		return nil, fmt.Errorf("unknown secret encryption scheme '%s': expected one of %s", scheme, s.schemes())
+	}
+
+	return encoder.Decrypt(encryptedData)
+}
+
+func (s *SchemeSecret) schemes() string {
+	var schemes []string
+	for scheme := range s.Encoders {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return strings.Join(schemes, ", ")
+}
+
+func schemeHeader(scheme string) []byte {
+	return []byte(fmt.Sprintf("$%s$", scheme))
+}
+
+// parseSchemeHeader splits $SCHEME$DATA, legacy aes data is hex encoded and never has the header
+func parseSchemeHeader(data []byte) (string, []byte, bool) {
+	if len(data) < 2 || data[0] != '$' {
+		return "", nil, false
+	}
+
+	ind := bytes.IndexByte(data[1:], '$')
+	if ind <= 0 {
+		return "", nil, false
+	}
+
+	return string(data[1 : ind+1]), data[ind+2:], true
+}
@@ -0,0 +1,194 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testEncoder reverses data, scheme distinguishes encoders in tests
+type testEncoder struct {
+	scheme string
+}
+
+func (e *testEncoder) Scheme() string {
+	return e.scheme
+}
+
+func (e *testEncoder) Encrypt(data []byte) ([]byte, error) {
+	return reverseBytes(data), nil
+}
+
+func (e *testEncoder) Decrypt(data []byte) ([]byte, error) {
+	return reverseBytes(data), nil
+}
+
+func reverseBytes(data []byte) []byte {
+	res := make([]byte, len(data))
+	for i, b := range data {
+		res[len(data)-1-i] = b
+	}
+	return res
+}
+
+func TestSchemeSecret(t *testing.T) {
+	legacy, err := NewAesSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSchemeSecret(&testEncoder{scheme: "test"}, legacy, &testEncoder{scheme: "other"})
+
+	encodedData, err := s.Generate([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []byte("$test$eulav"); !bytes.Equal(expected, encodedData) {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, encodedData)
+	}
+
+	legacyEncodedData, err := legacy.Generate([]byte("legacy value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		encodedData []byte
+		data        string
+	}{
+		{
+			name:        "defaultEncoder",
+			encodedData: encodedData,
+			data:        "value",
+		},
+		{
+			name:        "anotherEncoder",
+			encodedData: []byte("$other$eulav"),
+			data:        "value",
+		},
+		{
+			name:        "emptyData",
+			encodedData: []byte("$test$"),
+			data:        "",
+		},
+		{
+			name:        "legacy",
+			encodedData: legacyEncodedData,
+			data:        "legacy value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := s.Extract(test.encodedData)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.data != string(data) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.data, data)
+			}
+		})
+	}
+}
+
+func TestSchemeSecret_withoutDefaultEncoder(t *testing.T) {
+	legacy, err := NewAesSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSchemeSecret(nil, legacy, &testEncoder{scheme: "test"})
+
+	encodedData, err := s.Generate([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := parseSchemeHeader(encodedData); ok {
+		t.Errorf("legacy encoded data %s has scheme header", encodedData)
+	}
+
+	data, err := s.Extract(encodedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "value" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "value", data)
+	}
+}
+
+func TestSchemeSecret_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		secret        *SchemeSecret
+		generate      bool
+		encodedData   []byte
+		errorContains string
+	}{
+		{
+			name:          "noBackend",
+			secret:        NewSchemeSecret(nil, nil),
+			generate:      true,
+			errorContains: "no encryption backend configured",
+		},
+		{
+			name:          "unknownScheme",
+			secret:        NewSchemeSecret(&testEncoder{scheme: "test"}, nil, &testEncoder{scheme: "other"}),
+			encodedData:   []byte("$vault$data"),
+			errorContains: "unknown secret encryption scheme 'vault': expected one of other, test",
+		},
+		{
+			name:          "noLegacy",
+			secret:        NewSchemeSecret(&testEncoder{scheme: "test"}, nil),
+			encodedData:   []byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a"),
+			errorContains: "data is not encrypted by any of backends test and legacy encryption key is not specified",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			if test.generate {
+				_, err = test.secret.Generate([]byte("value"))
+			} else {
+				_, err = test.secret.Extract(test.encodedData)
+			}
+
+			if err == nil {
+				t.Errorf("Expected error: %s", test.errorContains)
+			} else if !strings.Contains(err.Error(), test.errorContains) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorContains, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseSchemeHeader(t *testing.T) {
+	tests := []struct {
+		data   string
+		scheme string
+		rest   string
+		ok     bool
+	}{
+		{data: "$age$data", scheme: "age", rest: "data", ok: true},
+		{data: "$gpg$", scheme: "gpg", rest: "", ok: true},
+		{data: "$$data", ok: false},
+		{data: "$age", ok: false},
+		{data: "1000abcdef", ok: false},
+		{data: "", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q", test.data), func(t *testing.T) {
+			scheme, rest, ok := parseSchemeHeader([]byte(test.data))
+			if scheme != test.scheme || string(rest) != test.rest || ok != test.ok {
+				t.Errorf("\n[EXPECTED]: %q %q %v\n[GOT]: %q %q %v", test.scheme, test.rest, test.ok, scheme, rest, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+const GPGScheme = "gpg"
+
+// GPGEncoder encrypts data for the public keys of armored recipients keyring
+// and decrypts data with armored secret keyring (private keys can be protected with passphrase)
+type GPGEncoder struct {
+	RecipientsKeyringFile string
+	SecretKeyringFile     string
+	Passphrase            []byte
+}
+
+func (e *GPGEncoder) Scheme() string {
+	return GPGScheme
+}
+
+func (e *GPGEncoder) Encrypt(data []byte) ([]byte, error) {
+	if e.RecipientsKeyringFile == "" {
+		return nil, fmt.Errorf("gpg recipients keyring is not specified")
+	}
+
+	recipients, err := readArmoredKeyRing(e.RecipientsKeyringFile)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := openpgp.Encrypt(buf, recipients, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gpg encryption failed: %s", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+func (e *GPGEncoder) Decrypt(data []byte) ([]byte, error) {
+	if e.SecretKeyringFile == "" {
+		return nil, fmt.Errorf("gpg secret keyring is not specified")
+	}
+
+	keyring, err := readArmoredKeyRing(e.SecretKeyringFile)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedData, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("bad gpg encrypted data: %s", err)
+	}
+
+	prompted := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if prompted || symmetric {
+			return nil, fmt.Errorf("cannot decrypt private key: check passphrase")
+		}
+		prompted = true
+
+		for _, key := range keys {
+			if key.PrivateKey != nil && key.PrivateKey.Encrypted {
+				if err := key.PrivateKey.Decrypt(e.Passphrase); err != nil {
+					return nil, fmt.Errorf("cannot decrypt private key: %s", err)
+				}
+			}
+		}
+
+		return nil, nil
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(encryptedData), keyring, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gpg decryption failed: %s", err)
+	}
+
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// GPGKeyringFingerprints returns fingerprints of the armored keyring keys with the first identity of each key
+func GPGKeyringFingerprints(path string) ([]string, error) {
+	keyring, err := readArmoredKeyRing(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fingerprints []string
+	for _, entity := range keyring {
+		fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+
+		var names []string
+		for name := range entity.Identities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(names) > 0 {
+			fingerprint = fmt.Sprintf("%s %s", fingerprint, names[0])
+		}
+
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	return fingerprints, nil
+}
+
+func readArmoredKeyRing(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open gpg keyring: %s", err)
+	}
+	defer f.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read gpg keyring %s: %s", path, err)
+	}
+
+	return keyring, nil
+}
@@ -0,0 +1,103 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const VaultScheme = "vault"
+
+// VaultTransitEncoder encrypts and decrypts data with HashiCorp Vault transit secrets engine API
+type VaultTransitEncoder struct {
+	Address string
+	Mount   string
+	Key     string
+	Token   string
+}
+
+func (e *VaultTransitEncoder) Scheme() string {
+	return VaultScheme
+}
+
+func (e *VaultTransitEncoder) Encrypt(data []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+
+	if err := e.request("encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(data)}, &resp); err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.Ciphertext), nil
+}
+
+func (e *VaultTransitEncoder) Decrypt(data []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+
+	if err := e.request("decrypt", map[string]string{"ciphertext": string(data)}, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+func (e *VaultTransitEncoder) request(operation string, body map[string]string, result interface{}) error {
+	if e.Address == "" {
+		return fmt.Errorf("vault address is not specified")
+	}
+
+	if e.Token == "" {
+		return fmt.Errorf("vault token is not specified")
+	}
+
+	mount := e.Mount
+	if mount == "" {
+		mount = "transit"
+	}
+
+	reqData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", strings.TrimSuffix(e.Address, "/"), strings.Trim(mount, "/"), operation, e.Key)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", e.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault transit %s failed: %s", operation, err)
+	}
+	defer resp.Body.Close()
+
+	respData, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault transit %s failed: %s: %s", operation, resp.Status, strings.TrimSpace(string(respData)))
+	}
+
+	var respObj struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respData, &respObj); err != nil {
+		return fmt.Errorf("bad vault response: %s", err)
+	}
+
+	return json.Unmarshal(respObj.Data, result)
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type JobAction string
+
+const (
+	BuildAction  JobAction = "build"
+	PushAction   JobAction = "push"
+	BPAction     JobAction = "bp"
+	DeployAction JobAction = "deploy"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobRequest describes what to do and which project source to use:
+// local project directory (Dir) or commit of the remote git repo (GitUrl and GitCommit)
+type JobRequest struct {
+	Action JobAction `json:"action"`
+
+	Dir       string `json:"dir,omitempty"`
+	GitUrl    string `json:"gitUrl,omitempty"`
+	GitCommit string `json:"gitCommit,omitempty"`
+
+	Images     []string `json:"images,omitempty"`
+	Repo       string   `json:"repo,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	WithStages bool     `json:"withStages,omitempty"`
+
+	Environment string   `json:"environment,omitempty"`
+	Release     string   `json:"release,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	Set         []string `json:"set,omitempty"`
+}
+
+func (r JobRequest) Validate() error {
+	switch r.Action {
+	case BuildAction, PushAction, BPAction, DeployAction:
+	default:
+		return fmt.Errorf("unknown action '%s': expected one of %s, %s, %s, %s", r.Action, BuildAction, PushAction, BPAction, DeployAction)
+	}
+
+	if r.Dir == "" && r.GitUrl == "" {
+		return fmt.Errorf("dir or gitUrl required")
+	}
+
+	if r.Dir != "" && r.GitUrl != "" {
+		return fmt.Errorf("dir and gitUrl cannot be used together")
+	}
+
+	if r.GitUrl != "" && r.GitCommit == "" {
+		return fmt.Errorf("gitCommit required with gitUrl")
+	}
+
+	if r.Action == DeployAction {
+		if r.GitUrl != "" {
+			return fmt.Errorf("deploy action supports only dir project source")
+		}
+
+		if len(r.Tags) != 1 {
+			return fmt.Errorf("exactly one tag should be specified for deploy")
+		}
+	}
+
+	return nil
+}
+
+// queueKey identifies project source: jobs with the same key are processed one by one
+func (r JobRequest) queueKey() string {
+	if r.GitUrl != "" {
+		return r.GitUrl
+	}
+	return r.Dir
+}
+
+type Job struct {
+	ID         string     `json:"id"`
+	Request    JobRequest `json:"request"`
+	Status     JobStatus  `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	mux     sync.Mutex
+	log     []string
+	updated chan struct{}
+	cancel  context.CancelFunc
+}
+
+func newJob(id string, request JobRequest) *Job {
+	return &Job{
+		ID:        id,
+		Request:   request,
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+		updated:   make(chan struct{}),
+	}
+}
+
+// Log appends lines to the job log and wakes up log followers
+func (j *Job) Log(format string, args ...interface{}) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	j.log = append(j.log, strings.Split(msg, "\n")...)
+	j.notify()
+}
+
+// LogLines returns log lines starting from offset, channel closed on the next job update and job finished flag
+func (j *Job) LogLines(offset int) ([]string, <-chan struct{}, bool) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	var lines []string
+	if offset < len(j.log) {
+		lines = append(lines, j.log[offset:]...)
+	}
+
+	return lines, j.updated, j.isFinished()
+}
+
+func (j *Job) snapshot() *Job {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return &Job{
+		ID:         j.ID,
+		Request:    j.Request,
+		Status:     j.Status,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+func (j *Job) start(cancel context.CancelFunc) bool {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.Status != JobQueued {
+		return false
+	}
+
+	now := time.Now()
+	j.Status = JobRunning
+	j.StartedAt = &now
+	j.cancel = cancel
+	j.notify()
+
+	return true
+}
+
+func (j *Job) finish(err error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	now := time.Now()
+	j.FinishedAt = &now
+	j.cancel = nil
+
+	switch {
+	case j.Status == JobCanceled:
+	case err != nil:
+		j.Status = JobFailed
+		j.Error = err.Error()
+	default:
+		j.Status = JobSucceeded
+	}
+
+	j.notify()
+}
+
+// Cancel removes queued job from the queue or interrupts running job
+func (j *Job) Cancel() bool {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	switch j.Status {
+	case JobQueued:
+		now := time.Now()
+		j.Status = JobCanceled
+		j.FinishedAt = &now
+	case JobRunning:
+		j.Status = JobCanceled
+		j.cancel()
+	default:
+		return false
+	}
+
+	j.notify()
+
+	return true
+}
+
+func (j *Job) finishedBefore(t time.Time) bool {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return j.isFinished() && j.FinishedAt.Before(t)
+}
+
+func (j *Job) isFinished() bool {
+	return j.FinishedAt != nil
+}
+
+func (j *Job) notify() {
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Runner performs the job, job log should be written with job.Log
+type Runner func(ctx context.Context, job *Job) error
+
+// DefaultFinishedJobsTTL is how long finished jobs are available through API
+const DefaultFinishedJobsTTL = time.Hour
+
+// Server runs jobs submitted through HTTP API.
+// Jobs are queued by project source and processed one by one: werf keeps state in process globals (docker config, werf home, locks),
+// so runner is never called concurrently. Finished jobs are evicted after FinishedJobsTTL.
+//
+//	POST   /jobs          submit JobRequest, returns Job
+//	GET    /jobs          list jobs
+//	GET    /jobs/ID       get job status
+//	GET    /jobs/ID/log   stream job log until job is finished
+//	DELETE /jobs/ID       cancel job
+type Server struct {
+	FinishedJobsTTL time.Duration
+
+	ctx    context.Context
+	runner Runner
+
+	runMux sync.Mutex
+
+	mux    sync.Mutex
+	jobs   map[string]*Job
+	order  []string
+	queues map[string][]*Job
+}
+
+func NewServer(ctx context.Context, runner Runner) *Server {
+	return &Server{
+		FinishedJobsTTL: DefaultFinishedJobsTTL,
+		ctx:             ctx,
+		runner:          runner,
+		jobs:            map[string]*Job{},
+		queues:          map[string][]*Job{},
+	}
+}
+
+func (s *Server) Submit(request JobRequest) (*Job, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	job := newJob(uuid.NewV4().String(), request)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.evictFinishedJobs()
+
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+
+	key := request.queueKey()
+	s.queues[key] = append(s.queues[key], job)
+	if len(s.queues[key]) == 1 {
+		go s.processQueue(key)
+	}
+
+	return job, nil
+}
+
+func (s *Server) GetJob(id string) *Job {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.jobs[id]
+}
+
+func (s *Server) Jobs() []*Job {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.evictFinishedJobs()
+
+	var res []*Job
+	for _, id := range s.order {
+		res = append(res, s.jobs[id])
+	}
+
+	return res
+}
+
+// evictFinishedJobs removes jobs finished more than FinishedJobsTTL ago, s.mux should be locked
+func (s *Server) evictFinishedJobs() {
+	var order []string
+	for _, id := range s.order {
+		if s.jobs[id].finishedBefore(time.Now().Add(-s.FinishedJobsTTL)) {
+			delete(s.jobs, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+}
+
+func (s *Server) processQueue(key string) {
+	for {
+		s.mux.Lock()
+		job := s.queues[key][0]
+		s.mux.Unlock()
+
+		s.runJob(job)
+
+		s.mux.Lock()
+		s.queues[key] = s.queues[key][1:]
+		if len(s.queues[key]) == 0 {
+			delete(s.queues, key)
+			s.mux.Unlock()
+			return
+		}
+		s.mux.Unlock()
+	}
+}
+
+func (s *Server) runJob(job *Job) {
+	s.runMux.Lock()
+	defer s.runMux.Unlock()
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	if !job.start(cancel) {
+		return
+	}
+
+	err := s.runner(ctx, job)
+	if err != nil {
+		job.Log("Error: %s", err)
+	}
+
+	job.finish(err)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return mux
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var res []*Job
+		for _, job := range s.Jobs() {
+			res = append(res, job.snapshot())
+		}
+		writeJSON(w, http.StatusOK, res)
+	case http.MethodPost:
+		var request JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("bad request body: %s", err))
+			return
+		}
+
+		job, err := s.Submit(request)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, job.snapshot())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+
+	job := s.GetJob(parts[0])
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", parts[0]))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, job.snapshot())
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if !job.Cancel() {
+			writeError(w, http.StatusConflict, fmt.Errorf("job %s is already finished", job.ID))
+			return
+		}
+		writeJSON(w, http.StatusOK, job.snapshot())
+	case len(parts) == 2 && parts[1] == "log" && r.Method == http.MethodGet:
+		streamLog(w, r, job)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown request %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func streamLog(w http.ResponseWriter, r *http.Request, job *Job) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+
+	offset := 0
+	for {
+		lines, updated, finished := job.LogLines(offset)
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return
+			}
+		}
+		offset += len(lines)
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
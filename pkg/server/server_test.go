@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitJob(t *testing.T, job *Job) {
+	timeout := time.After(10 * time.Second)
+
+	for {
+		_, updated, finished := job.LogLines(0)
+		if finished {
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-timeout:
+			t.Fatalf("job %s is not finished", job.ID)
+		}
+	}
+}
+
+func TestServer_runnerIsNotCalledConcurrently(t *testing.T) {
+	var mux sync.Mutex
+	running, maxRunning := 0, 0
+
+	s := NewServer(context.Background(), func(ctx context.Context, job *Job) error {
+		mux.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mux.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mux.Lock()
+		running--
+		mux.Unlock()
+
+		return nil
+	})
+
+	var jobs []*Job
+	for _, dir := range []string{"/project1", "/project2", "/project3", "/project1"} {
+		job, err := s.Submit(JobRequest{Action: BuildAction, Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	for _, job := range jobs {
+		waitJob(t, job)
+
+		if job.snapshot().Status != JobSucceeded {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", JobSucceeded, job.snapshot().Status)
+		}
+	}
+
+	if maxRunning != 1 {
+		t.Errorf("\n[EXPECTED]: %d\n[GOT]: %d", 1, maxRunning)
+	}
+}
+
+func TestServer_evictFinishedJobs(t *testing.T) {
+	release := make(chan struct{})
+	s := NewServer(context.Background(), func(ctx context.Context, job *Job) error {
+		if job.Request.Dir == "/running" {
+			<-release
+		}
+		return nil
+	})
+	defer close(release)
+
+	finishedJob, err := s.Submit(JobRequest{Action: BuildAction, Dir: "/finished"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, finishedJob)
+
+	runningJob, err := s.Submit(JobRequest{Action: BuildAction, Dir: "/running"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Jobs()) != 2 {
+		t.Fatalf("\n[EXPECTED]: %d\n[GOT]: %d", 2, len(s.Jobs()))
+	}
+
+	s.mux.Lock()
+	s.FinishedJobsTTL = time.Millisecond
+	s.mux.Unlock()
+	time.Sleep(5 * time.Millisecond)
+
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].ID != runningJob.ID {
+		t.Errorf("\n[EXPECTED]: [%s]\n[GOT]: %#v", runningJob.ID, jobs)
+	}
+
+	if s.GetJob(finishedJob.ID) != nil {
+		t.Errorf("finished job %s is not evicted", finishedJob.ID)
+	}
+}
+
+func TestJobRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request JobRequest
+		isValid bool
+	}{
+		{"build dir", JobRequest{Action: BuildAction, Dir: "/project"}, true},
+		{"bp git", JobRequest{Action: BPAction, GitUrl: "https://example.com/project.git", GitCommit: "master"}, true},
+		{"deploy dir", JobRequest{Action: DeployAction, Dir: "/project", Tags: []string{"latest"}}, true},
+		{"unknown action", JobRequest{Action: "run", Dir: "/project"}, false},
+		{"no source", JobRequest{Action: BuildAction}, false},
+		{"dir and git", JobRequest{Action: BuildAction, Dir: "/project", GitUrl: "https://example.com/project.git", GitCommit: "master"}, false},
+		{"git without commit", JobRequest{Action: BuildAction, GitUrl: "https://example.com/project.git"}, false},
+		{"deploy git", JobRequest{Action: DeployAction, GitUrl: "https://example.com/project.git", GitCommit: "master", Tags: []string{"latest"}}, false},
+		{"deploy without tag", JobRequest{Action: DeployAction, Dir: "/project"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.request.Validate()
+			if (err == nil) != test.isValid {
+				t.Errorf("\n[EXPECTED]: valid=%v\n[GOT]: %v", test.isValid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package sign
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/flant/werf/pkg/docker"
+)
+
+const (
+	CosignSigner   = "cosign"
+	NotationSigner = "notation"
+)
+
+type Options struct {
+	// Signer is one of CosignSigner or NotationSigner
+	Signer string
+	// Key is a reference to the signing key, which is passed to the signer as is:
+	// key file path, env://VAR or KMS URI (awskms://, gcpkms://, azurekms://, hashivault://) for cosign,
+	// key name from the notation keys list for notation
+	Key string
+}
+
+func ValidateSigner(signer string) error {
+	switch signer {
+	case CosignSigner, NotationSigner:
+		return nil
+	default:
+		return fmt.Errorf("unknown signer '%s': expected %s or %s", signer, CosignSigner, NotationSigner)
+	}
+}
+
+// Validate checks signer and key, key is required by cosign, notation can use default signing key
+func (opts Options) Validate() error {
+	if err := ValidateSigner(opts.Signer); err != nil {
+		return err
+	}
+
+	if opts.Signer == CosignSigner && opts.Key == "" {
+		return fmt.Errorf("signing key is required by %s", CosignSigner)
+	}
+
+	return nil
+}
+
+// DigestReference returns reference to the image manifest by digest, signatures are bound to the digest rather than the tag
+func DigestReference(imageName, digest string) string {
+	repository := imageName
+	if i := strings.LastIndex(imageName, ":"); i > strings.LastIndex(imageName, "/") {
+		repository = imageName[:i]
+	}
+
+	return fmt.Sprintf("%s@%s", repository, digest)
+}
+
+// SignImage signs published image manifest by digest reference and pushes signature into the registry
+func SignImage(ctx context.Context, digestReference string, opts Options) error {
+	var args []string
+	switch opts.Signer {
+	case CosignSigner:
+		args = []string{"sign", "--yes", "--key", opts.Key, digestReference}
+	case NotationSigner:
+		args = []string{"sign"}
+		if opts.Key != "" {
+			args = append(args, "--key", opts.Key)
+		}
+		args = append(args, digestReference)
+	default:
+		return ValidateSigner(opts.Signer)
+	}
+
+	return runSigner(ctx, opts.Signer, args...)
+}
+
+// VerifyImage checks signature of the published image: cosign verifies it with the public key,
+// notation verifies it with the configured trust policy and trust store
+func VerifyImage(ctx context.Context, reference string, opts Options) error {
+	var args []string
+	switch opts.Signer {
+	case CosignSigner:
+		args = []string{"verify", "--key", opts.Key, reference}
+	case NotationSigner:
+		args = []string{"verify", reference}
+	default:
+		return ValidateSigner(opts.Signer)
+	}
+
+	return runSigner(ctx, opts.Signer, args...)
+}
+
+func runSigner(ctx context.Context, signer string, args ...string) error {
+	cmd := exec.CommandContext(ctx, signer, args...)
+	// signer uses the same registries credentials as werf
+	cmd.Env = append(os.Environ(), fmt.Sprintf("DOCKER_CONFIG=%s", docker.GetConfigDir()))
+
+	output := &bytes.Buffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %s\n%s", signer, args[0], err, output.String())
+	}
+
+	return nil
+}
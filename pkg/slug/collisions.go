@@ -0,0 +1,34 @@
+package slug
+
+import (
+	"sync"
+
+	"github.com/flant/werf/pkg/logger"
+)
+
+// sluggedNames keeps source names by result for each kind of slug to detect different names with the same slug
+var (
+	sluggedNames      = map[string]map[string]string{}
+	sluggedNamesMutex sync.Mutex
+)
+
+// checkCollision remembers the source name of the result and warns when another name of the same kind has been already converted into the result
+func checkCollision(kind, name, result string) string {
+	sluggedNamesMutex.Lock()
+	defer sluggedNamesMutex.Unlock()
+
+	names, ok := sluggedNames[kind]
+	if !ok {
+		names = map[string]string{}
+		sluggedNames[kind] = names
+	}
+
+	if previousName, ok := names[result]; ok && previousName != name {
+		logger.LogWarningF("WARNING: '%s' and '%s' have the same %s slug '%s': resources named by these values will conflict\n", previousName, name, kind, result)
+		return result
+	}
+
+	names[result] = name
+
+	return result
+}
@@ -0,0 +1,61 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckCollision(t *testing.T) {
+	tests := []struct {
+		name          string
+		names         [][2]string
+		expectWarning string
+	}{
+		{
+			name:  "different results",
+			names: [][2]string{{"feature/a", "feature-a"}, {"feature/b", "feature-b"}},
+		},
+		{
+			name:  "same name converted twice",
+			names: [][2]string{{"feature/a", "feature-a"}, {"feature/a", "feature-a"}},
+		},
+		{
+			name:          "different names with the same result",
+			names:         [][2]string{{"feature/a", "feature-a"}, {"feature_a", "feature-a"}},
+			expectWarning: "'feature/a' and 'feature_a' have the same test slug 'feature-a'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sluggedNames = map[string]map[string]string{}
+
+			output := captureStderr(t, func() {
+				for _, name := range test.names {
+					if result := checkCollision("test", name[0], name[1]); result != name[1] {
+						t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", name[1], result)
+					}
+				}
+			})
+
+			if test.expectWarning == "" && output != "" {
+				t.Errorf("\n[EXPECTED]: no warning\n[GOT]: %s", output)
+			} else if !strings.Contains(output, test.expectWarning) {
+				t.Errorf("\n[EXPECTED]: warning containing %q\n[GOT]: %s", test.expectWarning, output)
+			}
+		})
+	}
+}
+
+func TestCheckCollision_kinds(t *testing.T) {
+	sluggedNames = map[string]map[string]string{}
+
+	output := captureStderr(t, func() {
+		checkCollision("docker tag", "feature/a", "feature-a")
+		checkCollision("helm release", "feature_a", "feature-a")
+	})
+
+	if output != "" {
+		t.Errorf("\n[EXPECTED]: no warning for different kinds\n[GOT]: %s", output)
+	}
+}
@@ -0,0 +1,196 @@
+package slug
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/flant/werf/pkg/logger"
+)
+
+// Policy defines naming rules of docker tags, helm releases, kubernetes namespaces and stages images.
+// Slug functions of the package use the policy set by SetPolicy and warn when different names get the same slug.
+type Policy interface {
+	DockerTag(tag string) string
+	ValidateDockerTag(tag string) error
+	HelmRelease(name string) string
+	ValidateHelmRelease(name string) error
+	KubernetesNamespace(namespace string) string
+	ValidateKubernetesNamespace(namespace string) error
+	StageImagePrefix() string
+}
+
+const DefaultStageImagePrefix = "image-stage"
+
+var policy Policy = &defaultPolicy{}
+
+func SetPolicy(p Policy) {
+	policy = p
+}
+
+func GetPolicy() Policy {
+	return policy
+}
+
+func DockerTag(tag string) string {
+	return checkCollision("docker tag", tag, policy.DockerTag(tag))
+}
+
+func ValidateDockerTag(tag string) error {
+	return policy.ValidateDockerTag(tag)
+}
+
+func HelmRelease(name string) string {
+	return checkCollision("helm release", name, policy.HelmRelease(name))
+}
+
+func ValidateHelmRelease(name string) error {
+	return policy.ValidateHelmRelease(name)
+}
+
+func KubernetesNamespace(namespace string) string {
+	return checkCollision("kubernetes namespace", namespace, policy.KubernetesNamespace(namespace))
+}
+
+func ValidateKubernetesNamespace(namespace string) error {
+	return policy.ValidateKubernetesNamespace(namespace)
+}
+
+func StageImagePrefix() string {
+	return policy.StageImagePrefix()
+}
+
+type defaultPolicy struct{}
+
+func (p *defaultPolicy) StageImagePrefix() string {
+	return DefaultStageImagePrefix
+}
+
+// NameRule restricts names with regex and max size, empty rule fields mean default werf rules
+type NameRule struct {
+	Regex   string `yaml:"regex,omitempty"`
+	MaxSize int    `yaml:"maxSize,omitempty"`
+}
+
+// RulesPolicyConfig is a naming section of the werf global config
+type RulesPolicyConfig struct {
+	DockerTag           NameRule `yaml:"dockerTag,omitempty"`
+	HelmRelease         NameRule `yaml:"helmRelease,omitempty"`
+	KubernetesNamespace NameRule `yaml:"kubernetesNamespace,omitempty"`
+	StageImagePrefix    string   `yaml:"stageImagePrefix,omitempty"`
+}
+
+// RulesPolicy is a policy with the configurable naming rules, names not matching the rules are slugged with werf slug algorithm
+type RulesPolicy struct {
+	dockerTag           *nameRule
+	helmRelease         *nameRule
+	kubernetesNamespace *nameRule
+	stageImagePrefix    string
+}
+
+type nameRule struct {
+	kind    string
+	regexp  *regexp.Regexp
+	maxSize int
+}
+
+func NewRulesPolicy(config RulesPolicyConfig) (*RulesPolicy, error) {
+	p := &RulesPolicy{stageImagePrefix: DefaultStageImagePrefix}
+
+	if config.StageImagePrefix != "" {
+		if !dockerTagRegexp.MatchString(config.StageImagePrefix) {
+			return nil, fmt.Errorf("bad stage image prefix '%s': should comply with regex '%s'", config.StageImagePrefix, dockerTagRegexp)
+		}
+		p.stageImagePrefix = config.StageImagePrefix
+	}
+
+	var err error
+	if p.dockerTag, err = newNameRule("docker tag", config.DockerTag, dockerTagRegexp, dockerTagMaxSize); err != nil {
+		return nil, err
+	}
+	if p.helmRelease, err = newNameRule("helm release", config.HelmRelease, helmReleaseRegexp, helmReleaseMaxSize); err != nil {
+		return nil, err
+	}
+	if p.kubernetesNamespace, err = newNameRule("kubernetes namespace", config.KubernetesNamespace, dnsLabelRegex, dnsLabelMaxSize); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func newNameRule(kind string, rule NameRule, defaultRegexp *regexp.Regexp, defaultMaxSize int) (*nameRule, error) {
+	res := &nameRule{kind: kind, regexp: defaultRegexp, maxSize: defaultMaxSize}
+
+	if rule.Regex != "" {
+		r, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s regex '%s': %s", kind, rule.Regex, err)
+		}
+		res.regexp = r
+	}
+
+	if rule.MaxSize != 0 {
+		// the rule cannot be looser than the default one, because names should be accepted by docker, helm and kubernetes
+		if rule.MaxSize < 0 || rule.MaxSize > defaultMaxSize {
+			return nil, fmt.Errorf("bad %s max size %d: should be in range 1..%d", kind, rule.MaxSize, defaultMaxSize)
+		}
+		res.maxSize = rule.MaxSize
+	}
+
+	// names not matching the rule are converted with werf slug algorithm, so the regex should accept werf slugs
+	for _, name := range []string{"feature/Branch_1", strings.Repeat("a_", res.maxSize)} {
+		if result := slug(name, res.maxSize); !res.regexp.MatchString(result) {
+			return nil, fmt.Errorf("bad %s regex '%s': werf slug '%s' of '%s' does not comply with the regex", kind, res.regexp, result, name)
+		}
+	}
+
+	return res, nil
+}
+
+func (r *nameRule) slug(data string) string {
+	if shouldNotBeSlugged(data, r.regexp, r.maxSize) {
+		return data
+	}
+
+	result := slug(data, r.maxSize)
+	if !r.regexp.MatchString(result) {
+		logger.LogWarningF("WARNING: %s slug '%s' of '%s' does not comply with regex '%s' of the naming rule\n", r.kind, result, data, r.regexp)
+	}
+
+	return result
+}
+
+func (r *nameRule) validate(data string) error {
+	if shouldNotBeSlugged(data, r.regexp, r.maxSize) {
+		return nil
+	}
+	return fmt.Errorf("%s should comply with regex '%s' and be maximum %d chars", r.kind, r.regexp, r.maxSize)
+}
+
+func (p *RulesPolicy) DockerTag(tag string) string {
+	return p.dockerTag.slug(tag)
+}
+
+func (p *RulesPolicy) ValidateDockerTag(tag string) error {
+	return p.dockerTag.validate(tag)
+}
+
+func (p *RulesPolicy) HelmRelease(name string) string {
+	return p.helmRelease.slug(name)
+}
+
+func (p *RulesPolicy) ValidateHelmRelease(name string) error {
+	return p.helmRelease.validate(name)
+}
+
+func (p *RulesPolicy) KubernetesNamespace(namespace string) string {
+	return p.kubernetesNamespace.slug(namespace)
+}
+
+func (p *RulesPolicy) ValidateKubernetesNamespace(namespace string) error {
+	return p.kubernetesNamespace.validate(namespace)
+}
+
+func (p *RulesPolicy) StageImagePrefix() string {
+	return p.stageImagePrefix
+}
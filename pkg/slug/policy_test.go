@@ -0,0 +1,184 @@
+package slug
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+// captureStderr returns output of f into stderr
+func captureStderr(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(output)
+}
+
+func TestNewRulesPolicy(t *testing.T) {
+	p, err := NewRulesPolicy(RulesPolicyConfig{
+		DockerTag:           NameRule{Regex: `^[a-z0-9][a-z0-9.-]*$`, MaxSize: 40},
+		HelmRelease:         NameRule{MaxSize: 30},
+		KubernetesNamespace: NameRule{Regex: `^[a-z0-9-]+$`},
+		StageImagePrefix:    "stage",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		slug   func(string) string
+		data   string
+		result string
+	}{
+		{
+			name:   "dockerTag_matchingRule",
+			slug:   p.DockerTag,
+			data:   "v1.0.0",
+			result: "v1.0.0",
+		},
+		{
+			name:   "dockerTag_notMatchRegexp",
+			slug:   p.DockerTag,
+			data:   "Feature_A",
+			result: "feature-a-" + util.MurmurHash("Feature_A"),
+		},
+		{
+			name:   "dockerTag_maxSizeExceeded",
+			slug:   p.DockerTag,
+			data:   strings.Repeat("x", 40),
+			result: strings.Repeat("x", 40-servicePartSize) + "-" + util.MurmurHash(strings.Repeat("x", 40)),
+		},
+		{
+			name:   "helmRelease_defaultRegexp",
+			slug:   p.HelmRelease,
+			data:   "my_release",
+			result: "my_release",
+		},
+		{
+			name:   "helmRelease_maxSizeExceeded",
+			slug:   p.HelmRelease,
+			data:   strings.Repeat("x", 30),
+			result: strings.Repeat("x", 30-servicePartSize) + "-" + util.MurmurHash(strings.Repeat("x", 30)),
+		},
+		{
+			name:   "kubernetesNamespace_notMatchRegexp",
+			slug:   p.KubernetesNamespace,
+			data:   "Namespace",
+			result: "namespace-" + util.MurmurHash("Namespace"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.slug(test.data)
+			if test.result != result {
+				t.Errorf("\n[EXPECTED]: %s (%d)\n[GOT]: %s (%d)", test.result, len(test.result), result, len(result))
+			}
+		})
+	}
+
+	if p.StageImagePrefix() != "stage" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "stage", p.StageImagePrefix())
+	}
+
+	if err := p.ValidateDockerTag("Feature_A"); err == nil {
+		t.Errorf("\n[EXPECTED]: error\n[GOT]: nil")
+	}
+
+	if err := p.ValidateHelmRelease(strings.Repeat("x", 29)); err != nil {
+		t.Errorf("\n[EXPECTED]: nil\n[GOT]: %s", err)
+	}
+}
+
+func TestNewRulesPolicy_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        RulesPolicyConfig
+		errorContains string
+	}{
+		{
+			name:          "badStageImagePrefix",
+			config:        RulesPolicyConfig{StageImagePrefix: "-stage"},
+			errorContains: "bad stage image prefix '-stage'",
+		},
+		{
+			name:          "badRegex",
+			config:        RulesPolicyConfig{DockerTag: NameRule{Regex: "["}},
+			errorContains: "bad docker tag regex '['",
+		},
+		{
+			name:          "maxSizeExceedsDefault",
+			config:        RulesPolicyConfig{KubernetesNamespace: NameRule{MaxSize: dnsLabelMaxSize + 1}},
+			errorContains: "bad kubernetes namespace max size 64: should be in range 1..63",
+		},
+		{
+			name:          "negativeMaxSize",
+			config:        RulesPolicyConfig{HelmRelease: NameRule{MaxSize: -1}},
+			errorContains: "bad helm release max size -1",
+		},
+		{
+			name:          "regexRejectsSlugs",
+			config:        RulesPolicyConfig{DockerTag: NameRule{Regex: `^[a-z.]+$`}},
+			errorContains: "bad docker tag regex '^[a-z.]+$': werf slug 'feature-branch-1-",
+		},
+		{
+			name:          "regexRejectsLongSlugs",
+			config:        RulesPolicyConfig{DockerTag: NameRule{Regex: `^[a-z0-9-]{1,30}$`}},
+			errorContains: "does not comply with the regex",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewRulesPolicy(test.config)
+			if err == nil {
+				t.Fatalf("\n[EXPECTED]: error containing %q\n[GOT]: nil", test.errorContains)
+			}
+
+			if !strings.Contains(err.Error(), test.errorContains) {
+				t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %s", test.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestRulesPolicy_slugNotComplyingWithRule(t *testing.T) {
+	p, err := NewRulesPolicy(RulesPolicyConfig{DockerTag: NameRule{Regex: `^[^x]*$`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result string
+	output := captureStderr(t, func() {
+		result = p.DockerTag("xx_")
+	})
+
+	if expected := slug("xx_", dockerTagMaxSize); result != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, result)
+	}
+
+	if expected := "WARNING: docker tag slug '" + result + "' of 'xx_' does not comply with regex '^[^x]*$' of the naming rule"; !strings.Contains(output, expected) {
+		t.Errorf("\n[EXPECTED]: %q\n[GOT]: %q", expected, output)
+	}
+}
@@ -55,7 +55,7 @@ func ValidateProject(name string) error {
 	return fmt.Errorf("Project name should comply with regex '%s' and be maximum %d chars", projectNameRegex, projectNameMaxSize)
 }
 
-func DockerTag(tag string) string {
+func (p *defaultPolicy) DockerTag(tag string) string {
 	if shouldNotBeSlugged(tag, dockerTagRegexp, dockerTagMaxSize) {
 		return tag
 	}
@@ -63,14 +63,14 @@ func DockerTag(tag string) string {
 	return slug(tag, dockerTagMaxSize)
 }
 
-func ValidateDockerTag(tag string) error {
+func (p *defaultPolicy) ValidateDockerTag(tag string) error {
 	if shouldNotBeSlugged(tag, dockerTagRegexp, dockerTagMaxSize) {
 		return nil
 	}
 	return fmt.Errorf("Docker tag should comply with regex '%s' and be maximum %d chars", dockerTagRegexp, dockerTagMaxSize)
 }
 
-func KubernetesNamespace(namespace string) string {
+func (p *defaultPolicy) KubernetesNamespace(namespace string) string {
 	if shouldNotBeSlugged(namespace, dnsLabelRegex, dnsLabelMaxSize) {
 		return namespace
 	}
@@ -78,14 +78,14 @@ func KubernetesNamespace(namespace string) string {
 	return slug(namespace, dnsLabelMaxSize)
 }
 
-func ValidateKubernetesNamespace(namespace string) error {
+func (p *defaultPolicy) ValidateKubernetesNamespace(namespace string) error {
 	if shouldNotBeSlugged(namespace, dnsLabelRegex, dnsLabelMaxSize) {
 		return nil
 	}
 	return fmt.Errorf("Kubernetes namespace should comply with DNS Label requirements: %s and %d bytes max", dnsLabelRegex, dnsLabelMaxSize)
 }
 
-func HelmRelease(name string) string {
+func (p *defaultPolicy) HelmRelease(name string) string {
 	if shouldNotBeSlugged(name, helmReleaseRegexp, helmReleaseMaxSize) {
 		return name
 	}
@@ -93,7 +93,7 @@ func HelmRelease(name string) string {
 	return slug(name, helmReleaseMaxSize)
 }
 
-func ValidateHelmRelease(name string) error {
+func (p *defaultPolicy) ValidateHelmRelease(name string) error {
 	if shouldNotBeSlugged(name, helmReleaseRegexp, helmReleaseMaxSize) {
 		return nil
 	}
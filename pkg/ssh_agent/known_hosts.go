@@ -0,0 +1,133 @@
+package ssh_agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+type HostKeyCheckingOptions struct {
+	// KnownHostsFiles are used in addition to the system ~/.ssh/known_hosts and /etc/ssh/ssh_known_hosts
+	KnownHostsFiles []string
+	// KnownHosts are known_hosts lines
+	KnownHosts []string
+	// StrictHostKeyChecking rejects hosts, which keys are not in known_hosts; changed keys are rejected anyway
+	StrictHostKeyChecking bool
+}
+
+var tmpKnownHostsPath string
+
+// setupHostKeyChecking makes go-git ssh transport use ssh agent with werf keys and check host keys with specified known_hosts
+func setupHostKeyChecking(opts HostKeyCheckingOptions) error {
+	var knownHostsFiles []string
+
+	for _, path := range []string{filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"), "/etc/ssh/ssh_known_hosts"} {
+		if util.FileExists(path) {
+			knownHostsFiles = append(knownHostsFiles, path)
+		}
+	}
+
+	for _, path := range opts.KnownHostsFiles {
+		if !util.FileExists(path) {
+			return fmt.Errorf("specified known_hosts file %s does not exist", path)
+		}
+		knownHostsFiles = append(knownHostsFiles, path)
+	}
+
+	if len(opts.KnownHosts) > 0 {
+		path, err := writeTmpKnownHosts(opts.KnownHosts)
+		if err != nil {
+			return err
+		}
+		knownHostsFiles = append(knownHostsFiles, path)
+	}
+
+	callback, err := knownhosts.New(knownHostsFiles...)
+	if err != nil {
+		return fmt.Errorf("cannot load known_hosts: %s", err)
+	}
+
+	hostKeyCallback := newHostKeyCallback(callback, opts.StrictHostKeyChecking)
+
+	gitssh.DefaultAuthBuilder = func(user string) (gitssh.AuthMethod, error) {
+		if SSHAuthSock == "" {
+			return nil, fmt.Errorf("ssh agent is not available: specify --ssh-key option or run ssh-agent with SSH_AUTH_SOCK")
+		}
+
+		return &gitssh.PublicKeysCallback{
+			User: user,
+			Callback: func() ([]ssh.Signer, error) {
+				conn, err := net.Dial("unix", SSHAuthSock)
+				if err != nil {
+					return nil, fmt.Errorf("error dialing with ssh agent %s: %s", SSHAuthSock, err)
+				}
+
+				return agent.NewClient(conn).Signers()
+			},
+			HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{HostKeyCallback: hostKeyCallback},
+		}, nil
+	}
+
+	return nil
+}
+
+// newHostKeyCallback replaces opaque knownhosts errors with errors naming the offending host key
+func newHostKeyCallback(callback ssh.HostKeyCallback, strict bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		keyErr, ok := err.(*knownhosts.KeyError)
+		if !ok {
+			return err
+		}
+
+		keyDesc := fmt.Sprintf("%s %s", key.Type(), ssh.FingerprintSHA256(key))
+
+		if len(keyErr.Want) > 0 {
+			var knownKeys []string
+			for _, knownKey := range keyErr.Want {
+				knownKeys = append(knownKeys, fmt.Sprintf("%s %s (%s:%d)", knownKey.Key.Type(), ssh.FingerprintSHA256(knownKey.Key), knownKey.Filename, knownKey.Line))
+			}
+
+			return fmt.Errorf("host key %s of %s does not match known_hosts keys %s: host key has been changed or connection is intercepted", keyDesc, hostname, strings.Join(knownKeys, ", "))
+		}
+
+		if !strict {
+			logger.LogWarningF("WARNING: Accepting unknown host key %s of %s, because strict host key checking is disabled\n", keyDesc, hostname)
+			return nil
+		}
+
+		return fmt.Errorf("host key %s of %s is unknown: add the key with --ssh-known-hosts or --ssh-known-hosts-file option (known_hosts line for %s) or disable checking with --ssh-strict-host-key-checking=false", keyDesc, hostname, knownhosts.Normalize(hostname))
+	}
+}
+
+func writeTmpKnownHosts(lines []string) (string, error) {
+	path := filepath.Join(werf.GetTmpDir(), "werf-known-hosts", uuid.NewV4().String())
+	tmpKnownHostsPath = path
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("cannot write known_hosts file %s: %s", path, err)
+	}
+
+	return path, nil
+}
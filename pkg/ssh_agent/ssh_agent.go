@@ -22,13 +22,17 @@ var (
 	tmpSockPath string
 )
 
-func Init(keys []string) error {
+func Init(keys []string, hostKeyCheckingOptions HostKeyCheckingOptions) error {
 	for _, key := range keys {
 		if !util.FileExists(key) {
 			return fmt.Errorf("specified ssh key %s does not exist", key)
 		}
 	}
 
+	if err := setupHostKeyChecking(hostKeyCheckingOptions); err != nil {
+		return err
+	}
+
 	if len(keys) > 0 {
 		agentSock, err := runSSHAgentWithKeys(keys)
 		if err != nil {
@@ -83,6 +87,13 @@ func Init(keys []string) error {
 }
 
 func Terminate() error {
+	if tmpKnownHostsPath != "" {
+		err := os.RemoveAll(tmpKnownHostsPath)
+		if err != nil {
+			return fmt.Errorf("unable to remove tmp known_hosts file %s: %s", tmpKnownHostsPath, err)
+		}
+	}
+
 	if tmpSockPath != "" {
 		err := os.RemoveAll(tmpSockPath)
 		if err != nil {
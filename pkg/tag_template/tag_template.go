@@ -0,0 +1,92 @@
+package tag_template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/slug"
+)
+
+const defaultDateLayout = "20060102"
+
+// Validate checks template syntax without git metadata
+func Validate(text string) error {
+	_, err := parse(text, &git_repo.Metadata{})
+	return err
+}
+
+// Render evaluates template against git metadata and makes valid docker tag of the result with tag slug.
+// Template functions: branch, tag, commit, commit_short, date [LAYOUT], timestamp, env NAME.
+func Render(text string, metadata *git_repo.Metadata) (string, error) {
+	tmpl, err := parse(text, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, nil); err != nil {
+		return "", fmt.Errorf("cannot render tag template '%s': %s", text, err)
+	}
+
+	res := strings.TrimSpace(buf.String())
+	if res == "" {
+		return "", fmt.Errorf("tag template '%s' rendered to empty string", text)
+	}
+
+	return slug.DockerTag(res), nil
+}
+
+func parse(text string, metadata *git_repo.Metadata) (*template.Template, error) {
+	tmpl, err := template.New("tag").Funcs(funcMap(metadata)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("bad tag template '%s': %s", text, err)
+	}
+
+	return tmpl, nil
+}
+
+func funcMap(metadata *git_repo.Metadata) template.FuncMap {
+	return template.FuncMap{
+		"branch": func() (string, error) {
+			if metadata.Branch == "" {
+				return "", errors.New("git branch is not detected: HEAD is detached")
+			}
+			return metadata.Branch, nil
+		},
+		"tag": func() (string, error) {
+			if metadata.Tag == "" {
+				return "", errors.New("git tag is not detected: there is no tag pointing to HEAD")
+			}
+			return metadata.Tag, nil
+		},
+		"commit": func() string {
+			return metadata.Commit
+		},
+		"commit_short": func() string {
+			if len(metadata.Commit) > 8 {
+				return metadata.Commit[:8]
+			}
+			return metadata.Commit
+		},
+		"date": func(layout ...string) (string, error) {
+			if len(layout) > 1 {
+				return "", errors.New("date function accepts only one layout argument")
+			} else if len(layout) == 1 {
+				return metadata.CommitTime.UTC().Format(layout[0]), nil
+			}
+			return metadata.CommitTime.UTC().Format(defaultDateLayout), nil
+		},
+		"timestamp": func() string {
+			return strconv.FormatInt(metadata.CommitTime.Unix(), 10)
+		},
+		"env": func(name string) string {
+			return os.Getenv(name)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package tag_template
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/slug"
+)
+
+var testMetadata = &git_repo.Metadata{
+	Branch:     "master",
+	Tag:        "v1.2.3",
+	Commit:     "0123456789abcdef0123456789abcdef01234567",
+	CommitTime: time.Date(2019, 7, 15, 23, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+}
+
+func TestRender(t *testing.T) {
+	os.Setenv("WERF_TEST_PIPELINE_ID", "1042")
+	defer os.Unsetenv("WERF_TEST_PIPELINE_ID")
+
+	tests := []struct {
+		name     string
+		text     string
+		metadata *git_repo.Metadata
+		result   string
+	}{
+		{
+			name:     "branchAndCommitShort",
+			text:     "{{ branch }}-{{ commit_short }}",
+			metadata: testMetadata,
+			result:   "master-01234567",
+		},
+		{
+			name:     "tagAndCommit",
+			text:     "{{ tag }}.{{ commit }}",
+			metadata: testMetadata,
+			result:   "v1.2.3.0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			name:     "defaultDateLayoutInUTC",
+			text:     "{{ date }}",
+			metadata: testMetadata,
+			result:   "20190715",
+		},
+		{
+			name:     "dateLayout",
+			text:     `{{ date "2006.01.02-1504" }}`,
+			metadata: testMetadata,
+			result:   "2019.07.15-2030",
+		},
+		{
+			name:     "timestampAndEnv",
+			text:     `{{ timestamp }}-{{ env "WERF_TEST_PIPELINE_ID" }}`,
+			metadata: testMetadata,
+			result:   "1563222600-1042",
+		},
+		{
+			name:     "shortCommit",
+			text:     "{{ commit_short }}",
+			metadata: &git_repo.Metadata{Commit: "0123"},
+			result:   "0123",
+		},
+		{
+			name:     "spacesAreTrimmed",
+			text:     " {{ branch }}\n",
+			metadata: testMetadata,
+			result:   "master",
+		},
+		{
+			name:     "resultIsSlugified",
+			text:     "{{ branch }}",
+			metadata: &git_repo.Metadata{Branch: "feature/Tag_Template"},
+			result:   slug.DockerTag("feature/Tag_Template"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Render(test.text, test.metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.result != result {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.result, result)
+			}
+		})
+	}
+}
+
+func TestRender_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		metadata      *git_repo.Metadata
+		errorContains string
+	}{
+		{
+			name:          "detachedHead",
+			text:          "{{ branch }}",
+			metadata:      &git_repo.Metadata{Commit: testMetadata.Commit},
+			errorContains: "git branch is not detected: HEAD is detached",
+		},
+		{
+			name:          "noTag",
+			text:          "{{ tag }}",
+			metadata:      &git_repo.Metadata{Commit: testMetadata.Commit},
+			errorContains: "git tag is not detected: there is no tag pointing to HEAD",
+		},
+		{
+			name:          "dateLayouts",
+			text:          `{{ date "2006" "01" }}`,
+			metadata:      testMetadata,
+			errorContains: "date function accepts only one layout argument",
+		},
+		{
+			name:          "emptyResult",
+			text:          `{{ env "WERF_TEST_NOT_SET" }}`,
+			metadata:      testMetadata,
+			errorContains: "tag template '{{ env \"WERF_TEST_NOT_SET\" }}' rendered to empty string",
+		},
+		{
+			name:          "unknownFunction",
+			text:          "{{ pipeline_id }}",
+			metadata:      testMetadata,
+			errorContains: "bad tag template '{{ pipeline_id }}': ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Render(test.text, test.metadata)
+			if err == nil || !strings.Contains(err.Error(), test.errorContains) {
+				t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %v", test.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, text := range []string{"{{ branch }}-{{ commit_short }}", `{{ date "2006" }}`, "static"} {
+		if err := Validate(text); err != nil {
+			t.Errorf("\n[EXPECTED]: nil\n[GOT]: %s", err)
+		}
+	}
+
+	for _, text := range []string{"{{ branch", "{{ unknown }}"} {
+		if err := Validate(text); err == nil || !strings.HasPrefix(err.Error(), "bad tag template") {
+			t.Errorf("\n[EXPECTED]: bad tag template error\n[GOT]: %v", err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+)
+
+var (
+	stageBuildDuration = &metricFamily{
+		Name:       "werf_stage_build_duration_seconds",
+		Help:       "Duration of the stages build",
+		Type:       histogramType,
+		LabelNames: []string{"image", "stage"},
+		Buckets:    []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}
+	stageCacheHits = &metricFamily{
+		Name:       "werf_stage_cache_hits_total",
+		Help:       "Number of stages taken from the local or the stages repo cache",
+		Type:       counterType,
+		LabelNames: []string{"image", "stage"},
+	}
+	stageCacheMisses = &metricFamily{
+		Name:       "werf_stage_cache_misses_total",
+		Help:       "Number of stages, which have been built",
+		Type:       counterType,
+		LabelNames: []string{"image", "stage"},
+	}
+	stageCacheHitRatio = &metricFamily{
+		Name: "werf_stage_cache_hit_ratio",
+		Help: "Ratio of the stages taken from the cache to all processed stages",
+		Type: gaugeType,
+	}
+	pushedBytes = &metricFamily{
+		Name: "werf_push_bytes_total",
+		Help: "Size of the image layers pushed into the registries",
+		Type: counterType,
+	}
+	pulledBytes = &metricFamily{
+		Name: "werf_pull_bytes_total",
+		Help: "Size of the image layers pulled from the registries",
+		Type: counterType,
+	}
+	lockWaitDuration = &metricFamily{
+		Name:    "werf_lock_wait_duration_seconds",
+		Help:    "Time spent waiting for locked resources",
+		Type:    histogramType,
+		Buckets: []float64{0.1, 1, 5, 15, 60, 300, 900, 3600},
+	}
+	deployDuration = &metricFamily{
+		Name:       "werf_deploy_duration_seconds",
+		Help:       "Duration of the helm releases deploy",
+		Type:       histogramType,
+		LabelNames: []string{"release", "status"},
+		Buckets:    []float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800},
+	}
+
+	metricFamilies = []*metricFamily{stageBuildDuration, stageCacheHits, stageCacheMisses, stageCacheHitRatio, pushedBytes, pulledBytes, lockWaitDuration, deployDuration}
+
+	metricsMutex sync.Mutex
+)
+
+type metricFamily struct {
+	Name       string
+	Help       string
+	Type       string
+	LabelNames []string
+	Buckets    []float64
+
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	LabelValues []string
+
+	// Value is a counter or gauge value, or a sum of the histogram observations
+	Value        float64
+	Count        uint64
+	BucketCounts []uint64
+}
+
+func ObserveStageBuild(imageName, stageName string, duration time.Duration) {
+	observe(stageBuildDuration, duration.Seconds(), imageName, stageName)
+}
+
+func IncStageCacheHits(imageName, stageName string) {
+	add(stageCacheHits, 1, imageName, stageName)
+}
+
+func IncStageCacheMisses(imageName, stageName string) {
+	add(stageCacheMisses, 1, imageName, stageName)
+}
+
+func AddPushedBytes(n int64) {
+	add(pushedBytes, float64(n))
+}
+
+func AddPulledBytes(n int64) {
+	add(pulledBytes, float64(n))
+}
+
+func ObserveLockWait(duration time.Duration) {
+	observe(lockWaitDuration, duration.Seconds())
+}
+
+// ObserveDeploy records duration of the release deploy, status is succeeded or failed depending on the deploy error
+func ObserveDeploy(release string, duration time.Duration, err error) {
+	status := "succeeded"
+	if err != nil {
+		status = "failed"
+	}
+
+	observe(deployDuration, duration.Seconds(), release, status)
+}
+
+func add(family *metricFamily, value float64, labelValues ...string) {
+	if !isMetricsEnabled() {
+		return
+	}
+
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	family.getSeries(labelValues).Value += value
+}
+
+func observe(family *metricFamily, value float64, labelValues ...string) {
+	if !isMetricsEnabled() {
+		return
+	}
+
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	s := family.getSeries(labelValues)
+	s.Value += value
+	s.Count++
+	for i, bound := range family.Buckets {
+		if value <= bound {
+			s.BucketCounts[i]++
+		}
+	}
+}
+
+func (f *metricFamily) getSeries(labelValues []string) *metricSeries {
+	if f.series == nil {
+		f.series = map[string]*metricSeries{}
+	}
+
+	key := strings.Join(labelValues, "\x00")
+	if s, hasKey := f.series[key]; hasKey {
+		return s
+	}
+
+	s := &metricSeries{LabelValues: labelValues, BucketCounts: make([]uint64, len(f.Buckets))}
+	f.series[key] = s
+
+	return s
+}
+
+func updateStageCacheHitRatio() {
+	var hits, misses float64
+	for _, s := range stageCacheHits.series {
+		hits += s.Value
+	}
+	for _, s := range stageCacheMisses.series {
+		misses += s.Value
+	}
+
+	if hits+misses > 0 {
+		stageCacheHitRatio.getSeries(nil).Value = hits / (hits + misses)
+	}
+}
+
+// encodeMetrics renders collected metrics in Prometheus text exposition format
+func encodeMetrics() []byte {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	updateStageCacheHitRatio()
+
+	buf := &bytes.Buffer{}
+	for _, family := range metricFamilies {
+		if len(family.series) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "# HELP %s %s\n", family.Name, family.Help)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", family.Name, family.Type)
+
+		var keys []string
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := family.series[key]
+
+			if family.Type != histogramType {
+				fmt.Fprintf(buf, "%s%s %s\n", family.Name, formatLabels(family.LabelNames, s.LabelValues), formatFloat(s.Value))
+				continue
+			}
+
+			bucketLabelNames := append(append([]string{}, family.LabelNames...), "le")
+			for i, bound := range family.Buckets {
+				bucketLabelValues := append(append([]string{}, s.LabelValues...), formatFloat(bound))
+				fmt.Fprintf(buf, "%s_bucket%s %d\n", family.Name, formatLabels(bucketLabelNames, bucketLabelValues), s.BucketCounts[i])
+			}
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", family.Name, formatLabels(bucketLabelNames, append(append([]string{}, s.LabelValues...), "+Inf")), s.Count)
+			fmt.Fprintf(buf, "%s_sum%s %s\n", family.Name, formatLabels(family.LabelNames, s.LabelValues), formatFloat(s.Value))
+			fmt.Fprintf(buf, "%s_count%s %d\n", family.Name, formatLabels(family.LabelNames, s.LabelValues), s.Count)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var pairs []string
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(values[i])
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, value))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// writeMetricsFile replaces the file atomically, so that the collector never reads partially written metrics
+func writeMetricsFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmpPath := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := ioutil.WriteFile(tmpPath, encodeMetrics(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// pushMetrics replaces metrics of the werf job group (and project, if set) in the Pushgateway
+func pushMetrics(pushgatewayUrl string) error {
+	groupPath := "/metrics/job/werf"
+	if project != "" {
+		groupPath += "/project/" + url.PathEscape(project)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(pushgatewayUrl, "/")+groupPath, bytes.NewReader(encodeMetrics()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Options configure exporters of werf operations telemetry, telemetry is disabled if no exporter is configured
+type Options struct {
+	// MetricsFile is a file to write metrics in Prometheus text format into, e.g. for node_exporter textfile collector
+	MetricsFile string
+	// PushgatewayUrl is a Prometheus Pushgateway address to push metrics into
+	PushgatewayUrl string
+	// OtlpEndpoint is an OTLP/HTTP collector address to export traces into
+	OtlpEndpoint string
+}
+
+const exportTimeout = 10 * time.Second
+
+var (
+	options Options
+	project string
+
+	httpClient = &http.Client{Timeout: exportTimeout}
+)
+
+func Init(opts Options) error {
+	for _, u := range []string{opts.PushgatewayUrl, opts.OtlpEndpoint} {
+		if u == "" {
+			continue
+		}
+
+		parsedUrl, err := url.Parse(u)
+		if err != nil {
+			return fmt.Errorf("bad telemetry url '%s': %s", u, err)
+		}
+
+		if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+			return fmt.Errorf("bad telemetry url '%s': expected http or https scheme", u)
+		}
+	}
+
+	options = opts
+
+	return nil
+}
+
+func IsEnabled() bool {
+	return isMetricsEnabled() || isTracingEnabled()
+}
+
+func isMetricsEnabled() bool {
+	return options.MetricsFile != "" || options.PushgatewayUrl != ""
+}
+
+func isTracingEnabled() bool {
+	return options.OtlpEndpoint != ""
+}
+
+// SetProject sets project name, which is added to the pushed metrics grouping key and to the traces resource
+func SetProject(name string) {
+	project = name
+}
+
+// Flush writes collected metrics into the metrics file, pushes them into the Pushgateway and exports finished spans.
+// All exporters are tried, the first error is returned.
+func Flush() error {
+	var errs []error
+
+	if options.MetricsFile != "" {
+		if err := writeMetricsFile(options.MetricsFile); err != nil {
+			errs = append(errs, fmt.Errorf("unable to write metrics file %s: %s", options.MetricsFile, err))
+		}
+	}
+
+	if options.PushgatewayUrl != "" {
+		if err := pushMetrics(options.PushgatewayUrl); err != nil {
+			errs = append(errs, fmt.Errorf("unable to push metrics into %s: %s", options.PushgatewayUrl, err))
+		}
+	}
+
+	if options.OtlpEndpoint != "" {
+		if err := exportSpans(options.OtlpEndpoint); err != nil {
+			errs = append(errs, fmt.Errorf("unable to export traces into %s: %s", options.OtlpEndpoint, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetTelemetry initializes telemetry with options and drops collected metrics and spans
+func resetTelemetry(t *testing.T, opts Options) {
+	if err := Init(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	SetProject("")
+
+	for _, family := range metricFamilies {
+		family.series = nil
+	}
+
+	finishedSpans = nil
+}
+
+// testCollector records requests of the Pushgateway or OTLP collector
+type testCollector struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests []*testCollectorRequest
+}
+
+type testCollectorRequest struct {
+	Method      string
+	Path        string
+	ContentType string
+	Body        []byte
+}
+
+func newTestCollector(statusCode int) *testCollector {
+	c := &testCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		c.mutex.Lock()
+		c.requests = append(c.requests, &testCollectorRequest{Method: r.Method, Path: r.URL.EscapedPath(), ContentType: r.Header.Get("Content-Type"), Body: body})
+		c.mutex.Unlock()
+
+		w.WriteHeader(statusCode)
+		if statusCode/100 != 2 {
+			w.Write([]byte("collector error\n"))
+		}
+	}))
+
+	return c
+}
+
+func TestInit_negative(t *testing.T) {
+	defer resetTelemetry(t, Options{})
+
+	tests := []struct {
+		opts          Options
+		errorContains string
+	}{
+		{
+			opts:          Options{PushgatewayUrl: "pushgateway:9091"},
+			errorContains: "bad telemetry url 'pushgateway:9091'",
+		},
+		{
+			opts:          Options{OtlpEndpoint: "grpc://collector:4317"},
+			errorContains: "bad telemetry url 'grpc://collector:4317': expected http or https scheme",
+		},
+		{
+			opts:          Options{OtlpEndpoint: "http://collector:port"},
+			errorContains: "bad telemetry url 'http://collector:port'",
+		},
+	}
+
+	for _, test := range tests {
+		err := Init(test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.errorContains) {
+			t.Errorf("\n[EXPECTED]: error containing %q\n[GOT]: %v", test.errorContains, err)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	resetTelemetry(t, Options{})
+
+	if IsEnabled() {
+		t.Errorf("\n[EXPECTED]: telemetry is disabled\n[GOT]: enabled")
+	}
+
+	IncStageCacheHits("app", "from")
+	ObserveLockWait(time.Second)
+
+	if metrics := encodeMetrics(); len(metrics) != 0 {
+		t.Errorf("\n[EXPECTED]: no metrics\n[GOT]: %s", metrics)
+	}
+
+	span := StartSpan("build", nil, map[string]string{"image": "app"})
+	if span != nil {
+		t.Errorf("\n[EXPECTED]: nil span\n[GOT]: %#v", span)
+	}
+
+	span.SetAttribute("stage", "install")
+	span.End(nil)
+
+	if len(finishedSpans) != 0 {
+		t.Errorf("\n[EXPECTED]: no finished spans\n[GOT]: %d", len(finishedSpans))
+	}
+
+	if err := Flush(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeMetrics(t *testing.T) {
+	resetTelemetry(t, Options{MetricsFile: "metrics.prom"})
+	defer resetTelemetry(t, Options{})
+
+	ObserveStageBuild("app", "install", 20*time.Second)
+	ObserveStageBuild("app", "install", 40*time.Second)
+	IncStageCacheHits("app", "from")
+	IncStageCacheHits("app", "from")
+	IncStageCacheHits("app", "setup")
+	IncStageCacheMisses("app", "install")
+	AddPushedBytes(1024)
+	AddPushedBytes(512)
+	ObserveDeploy("release \"x\"", 10*time.Second, errors.New("failed"))
+
+	expected := `# HELP werf_stage_build_duration_seconds Duration of the stages build
+# TYPE werf_stage_build_duration_seconds histogram
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="1"} 0
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="5"} 0
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="15"} 0
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="30"} 1
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="60"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="120"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="300"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="600"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="1200"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="1800"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="3600"} 2
+werf_stage_build_duration_seconds_bucket{image="app",stage="install",le="+Inf"} 2
+werf_stage_build_duration_seconds_sum{image="app",stage="install"} 60
+werf_stage_build_duration_seconds_count{image="app",stage="install"} 2
+# HELP werf_stage_cache_hits_total Number of stages taken from the local or the stages repo cache
+# TYPE werf_stage_cache_hits_total counter
+werf_stage_cache_hits_total{image="app",stage="from"} 2
+werf_stage_cache_hits_total{image="app",stage="setup"} 1
+# HELP werf_stage_cache_misses_total Number of stages, which have been built
+# TYPE werf_stage_cache_misses_total counter
+werf_stage_cache_misses_total{image="app",stage="install"} 1
+# HELP werf_stage_cache_hit_ratio Ratio of the stages taken from the cache to all processed stages
+# TYPE werf_stage_cache_hit_ratio gauge
+werf_stage_cache_hit_ratio 0.75
+# HELP werf_push_bytes_total Size of the image layers pushed into the registries
+# TYPE werf_push_bytes_total counter
+werf_push_bytes_total 1536
+# HELP werf_deploy_duration_seconds Duration of the helm releases deploy
+# TYPE werf_deploy_duration_seconds histogram
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="5"} 0
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="15"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="30"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="60"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="120"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="300"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="600"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="1200"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="1800"} 1
+werf_deploy_duration_seconds_bucket{release="release \"x\"",status="failed",le="+Inf"} 1
+werf_deploy_duration_seconds_sum{release="release \"x\"",status="failed"} 10
+werf_deploy_duration_seconds_count{release="release \"x\"",status="failed"} 1
+`
+
+	if metrics := string(encodeMetrics()); expected != metrics {
+		t.Errorf("\n[EXPECTED]:\n%s\n[GOT]:\n%s", expected, metrics)
+	}
+}
+
+func TestFlush_metrics(t *testing.T) {
+	pushgateway := newTestCollector(http.StatusOK)
+	defer pushgateway.Close()
+
+	dir, err := ioutil.TempDir("", "werf-telemetry-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metricsFile := filepath.Join(dir, "textfile", "werf.prom")
+
+	resetTelemetry(t, Options{MetricsFile: metricsFile, PushgatewayUrl: pushgateway.URL + "/"})
+	defer resetTelemetry(t, Options{})
+
+	SetProject("my project")
+	AddPulledBytes(2048)
+
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedMetrics := "# HELP werf_pull_bytes_total Size of the image layers pulled from the registries\n# TYPE werf_pull_bytes_total counter\nwerf_pull_bytes_total 2048\n"
+
+	data, err := ioutil.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expectedMetrics != string(data) {
+		t.Errorf("\n[EXPECTED]:\n%s\n[GOT]:\n%s", expectedMetrics, data)
+	}
+
+	if len(pushgateway.requests) != 1 {
+		t.Fatalf("\n[EXPECTED]: 1 request\n[GOT]: %d", len(pushgateway.requests))
+	}
+
+	req := pushgateway.requests[0]
+	if req.Method != http.MethodPut || req.Path != "/metrics/job/werf/project/my%20project" || req.ContentType != "text/plain; version=0.0.4" || string(req.Body) != expectedMetrics {
+		t.Errorf("\n[EXPECTED]: PUT /metrics/job/werf/project/my%%20project\n%s\n[GOT]: %s %s (%s)\n%s", expectedMetrics, req.Method, req.Path, req.ContentType, req.Body)
+	}
+}
+
+func TestFlush_spans(t *testing.T) {
+	collector := newTestCollector(http.StatusOK)
+	defer collector.Close()
+
+	resetTelemetry(t, Options{OtlpEndpoint: collector.URL})
+	defer resetTelemetry(t, Options{})
+
+	SetProject("test")
+
+	root := StartSpan("build", nil, map[string]string{"images": "app"})
+	child := StartSpan("stage", root, map[string]string{"stage": "install"})
+	child.SetAttribute("image", "app")
+	child.End(errors.New("stage failed"))
+	root.End(nil)
+
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(collector.requests) != 1 {
+		t.Fatalf("\n[EXPECTED]: 1 request\n[GOT]: %d", len(collector.requests))
+	}
+
+	req := collector.requests[0]
+	if req.Method != http.MethodPost || req.Path != "/v1/traces" || req.ContentType != "application/json" {
+		t.Errorf("\n[EXPECTED]: POST /v1/traces (application/json)\n[GOT]: %s %s (%s)", req.Method, req.Path, req.ContentType)
+	}
+
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []otlpAttribute `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(req.Body, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	resourceAttributes := map[string]string{}
+	for _, attr := range request.ResourceSpans[0].Resource.Attributes {
+		resourceAttributes[attr.Key] = attr.Value.StringValue
+	}
+	if resourceAttributes["service.name"] != "werf" || resourceAttributes["werf.project"] != "test" {
+		t.Errorf("\n[EXPECTED]: werf service and test project resource attributes\n[GOT]: %#v", resourceAttributes)
+	}
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("\n[EXPECTED]: 2 spans\n[GOT]: %#v", spans)
+	}
+
+	childSpan, rootSpan := spans[0], spans[1]
+
+	if rootSpan.Name != "build" || rootSpan.ParentSpanId != "" || len(rootSpan.TraceId) != 32 || len(rootSpan.SpanId) != 16 || rootSpan.Status.Code != otlpStatusCodeOk {
+		t.Errorf("\n[EXPECTED]: ok root build span\n[GOT]: %#v", rootSpan)
+	}
+
+	if childSpan.Name != "stage" || childSpan.TraceId != rootSpan.TraceId || childSpan.ParentSpanId != rootSpan.SpanId || childSpan.Status.Code != otlpStatusCodeError || childSpan.Status.Message != "stage failed" {
+		t.Errorf("\n[EXPECTED]: failed stage span of the build span\n[GOT]: %#v", childSpan)
+	}
+
+	if attrs := childSpan.Attributes; len(attrs) != 2 || attrs[0].Key != "image" || attrs[0].Value.StringValue != "app" || attrs[1].Key != "stage" || attrs[1].Value.StringValue != "install" {
+		t.Errorf("\n[EXPECTED]: sorted image and stage attributes\n[GOT]: %#v", attrs)
+	}
+
+	start, _ := strconv.ParseInt(childSpan.StartTimeUnixNano, 10, 64)
+	end, _ := strconv.ParseInt(childSpan.EndTimeUnixNano, 10, 64)
+	if start == 0 || start > end {
+		t.Errorf("\n[EXPECTED]: span start before end\n[GOT]: %s > %s", childSpan.StartTimeUnixNano, childSpan.EndTimeUnixNano)
+	}
+
+	if len(finishedSpans) != 0 {
+		t.Errorf("\n[EXPECTED]: exported spans are dropped\n[GOT]: %d spans", len(finishedSpans))
+	}
+}
+
+func TestFlush_errors(t *testing.T) {
+	pushgateway := newTestCollector(http.StatusInternalServerError)
+	defer pushgateway.Close()
+
+	collector := newTestCollector(http.StatusBadRequest)
+	defer collector.Close()
+
+	resetTelemetry(t, Options{PushgatewayUrl: pushgateway.URL, OtlpEndpoint: collector.URL + "/v1/traces"})
+	defer resetTelemetry(t, Options{})
+
+	IncStageCacheMisses("app", "install")
+	StartSpan("build", nil, nil).End(nil)
+
+	err := Flush()
+
+	expected := "unable to push metrics into " + pushgateway.URL + ": unexpected response 500 Internal Server Error: collector error"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", expected, err)
+	}
+
+	if len(collector.requests) != 1 || collector.requests[0].Path != "/v1/traces" {
+		t.Errorf("\n[EXPECTED]: spans are exported after the push error\n[GOT]: %d requests", len(collector.requests))
+	}
+}
@@ -0,0 +1,216 @@
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flant/werf/pkg/werf"
+)
+
+// Span is a timed operation of the trace, nil span is a no-op span used when tracing is disabled
+type Span struct {
+	traceId      string
+	spanId       string
+	parentSpanId string
+
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	errMessage string
+}
+
+var (
+	finishedSpans []*Span
+	spansMutex    sync.Mutex
+)
+
+// StartSpan starts a new trace if parent is nil or a child span of the parent
+func StartSpan(name string, parent *Span, attributes map[string]string) *Span {
+	if !isTracingEnabled() {
+		return nil
+	}
+
+	s := &Span{name: name, start: time.Now(), spanId: randomId(8), attributes: map[string]string{}}
+	if parent != nil {
+		s.traceId = parent.traceId
+		s.parentSpanId = parent.spanId
+	} else {
+		s.traceId = randomId(16)
+	}
+
+	for key, value := range attributes {
+		s.attributes[key] = value
+	}
+
+	return s
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.attributes[key] = value
+}
+
+// End finishes the span, the span is marked failed if err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.end = time.Now()
+	if err != nil {
+		s.errMessage = err.Error()
+	}
+
+	spansMutex.Lock()
+	defer spansMutex.Unlock()
+
+	finishedSpans = append(finishedSpans, s)
+}
+
+func randomId(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// OTLP span kind and status codes
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOk     = 1
+	otlpStatusCodeError  = 2
+)
+
+func newOtlpAttributes(attributes map[string]string) []otlpAttribute {
+	var keys []string
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var res []otlpAttribute
+	for _, key := range keys {
+		attr := otlpAttribute{Key: key}
+		attr.Value.StringValue = attributes[key]
+		res = append(res, attr)
+	}
+
+	return res
+}
+
+// exportSpans sends finished spans to the collector with OTLP/HTTP protocol in JSON encoding
+func exportSpans(endpoint string) error {
+	spansMutex.Lock()
+	spans := finishedSpans
+	finishedSpans = nil
+	spansMutex.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	var otlpSpans []otlpSpan
+	for _, s := range spans {
+		otlpS := otlpSpan{
+			TraceId:           s.traceId,
+			SpanId:            s.spanId,
+			ParentSpanId:      s.parentSpanId,
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        newOtlpAttributes(s.attributes),
+		}
+
+		if s.errMessage != "" {
+			otlpS.Status.Code = otlpStatusCodeError
+			otlpS.Status.Message = s.errMessage
+		} else {
+			otlpS.Status.Code = otlpStatusCodeOk
+		}
+
+		otlpSpans = append(otlpSpans, otlpS)
+	}
+
+	resourceAttributes := map[string]string{
+		"service.name":    "werf",
+		"service.version": werf.Version,
+	}
+	if project != "" {
+		resourceAttributes["werf.project"] = project
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{"attributes": newOtlpAttributes(resourceAttributes)},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "werf", "version": werf.Version},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	tracesUrl := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(tracesUrl, "/v1/traces") {
+		tracesUrl += "/v1/traces"
+	}
+
+	resp, err := httpClient.Post(tracesUrl, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
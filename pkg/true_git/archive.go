@@ -2,6 +2,7 @@ package true_git
 
 import (
 	"archive/tar"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 )
@@ -17,6 +19,13 @@ import (
 type ArchiveOptions struct {
 	Commit     string
 	PathFilter PathFilter
+
+	Submodules SubmodulesOptions
+
+	// Deterministic makes archive of the same tree byte-identical: entries get ModTime, normalized permissions and root owner.
+	// Entries are always written in lexical order.
+	Deterministic bool
+	ModTime       time.Time
 }
 
 type ArchiveDescriptor struct {
@@ -31,19 +40,19 @@ const (
 	DirectoryArchive ArchiveType = "directory"
 )
 
-func ArchiveWithSubmodules(out io.Writer, gitDir, workTreeDir string, opts ArchiveOptions) (*ArchiveDescriptor, error) {
-	return writeArchive(out, gitDir, workTreeDir, true, opts)
+func ArchiveWithSubmodules(ctx context.Context, out io.Writer, gitDir, workTreeDir string, opts ArchiveOptions) (*ArchiveDescriptor, error) {
+	return writeArchive(ctx, out, gitDir, workTreeDir, true, opts)
 }
 
-func Archive(out io.Writer, gitDir, workTreeDir string, opts ArchiveOptions) (*ArchiveDescriptor, error) {
-	return writeArchive(out, gitDir, workTreeDir, false, opts)
+func Archive(ctx context.Context, out io.Writer, gitDir, workTreeDir string, opts ArchiveOptions) (*ArchiveDescriptor, error) {
+	return writeArchive(ctx, out, gitDir, workTreeDir, false, opts)
 }
 
 func debugArchive() bool {
 	return os.Getenv("WERF_TRUE_GIT_DEBUG_ARCHIVE") == "1"
 }
 
-func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool, opts ArchiveOptions) (*ArchiveDescriptor, error) {
+func writeArchive(ctx context.Context, out io.Writer, gitDir, workTreeDir string, withSubmodules bool, opts ArchiveOptions) (*ArchiveDescriptor, error) {
 	var err error
 
 	gitDir, err = filepath.Abs(gitDir)
@@ -63,7 +72,11 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 		}
 	}
 
-	err = switchWorkTree(gitDir, workTreeDir, opts.Commit)
+	if !withSubmodules && IsPartialClone(gitDir) {
+		err = switchSparseWorkTree(ctx, gitDir, workTreeDir, opts.Commit, opts.PathFilter)
+	} else {
+		err = switchWorkTree(ctx, gitDir, workTreeDir, opts.Commit)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot reset work tree `%s` to commit `%s`: %s", workTreeDir, opts.Commit, err)
 	}
@@ -71,12 +84,12 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 	if withSubmodules {
 		var err error
 
-		err = deinitSubmodules(gitDir, workTreeDir)
+		err = deinitSubmodules(ctx, gitDir, workTreeDir)
 		if err != nil {
 			return nil, fmt.Errorf("cannot deinit submodules: %s", err)
 		}
 
-		err = updateSubmodules(gitDir, workTreeDir)
+		err = updateSubmodules(ctx, gitDir, workTreeDir, opts.Submodules)
 		if err != nil {
 			return nil, fmt.Errorf("cannot update submodules: %s", err)
 		}
@@ -93,6 +106,10 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 			return fmt.Errorf("error accessing `%s`: %s", absPath, accessErr)
 		}
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		baseName := filepath.Base(absPath)
 		for _, p := range []string{".git"} {
 			if baseName == p {
@@ -144,7 +161,7 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 				return fmt.Errorf("cannot read symlink `%s`: %s", absPath, err)
 			}
 
-			err = tw.WriteHeader(&tar.Header{
+			header := &tar.Header{
 				Format:     tar.FormatGNU,
 				Typeflag:   tar.TypeSymlink,
 				Name:       archivePath,
@@ -154,7 +171,12 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 				ModTime:    info.ModTime(),
 				AccessTime: info.ModTime(),
 				ChangeTime: info.ModTime(),
-			})
+			}
+			if opts.Deterministic {
+				normalizeHeader(header, info, opts.ModTime)
+			}
+
+			err = tw.WriteHeader(header)
 			if err != nil {
 				return fmt.Errorf("unable to write tar symlink header for file `%s`: %s", archivePath, err)
 			}
@@ -166,7 +188,7 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 			return nil
 		}
 
-		err = tw.WriteHeader(&tar.Header{
+		header := &tar.Header{
 			Format:     tar.FormatGNU,
 			Name:       archivePath,
 			Mode:       int64(info.Mode()),
@@ -174,7 +196,12 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 			ModTime:    info.ModTime(),
 			AccessTime: info.ModTime(),
 			ChangeTime: info.ModTime(),
-		})
+		}
+		if opts.Deterministic {
+			normalizeHeader(header, info, opts.ModTime)
+		}
+
+		err = tw.WriteHeader(header)
 		if err != nil {
 			return fmt.Errorf("unable to write tar header for file `%s`: %s", archivePath, err)
 		}
@@ -217,6 +244,29 @@ func writeArchive(out io.Writer, gitDir, workTreeDir string, withSubmodules bool
 	return desc, nil
 }
 
+// normalizeHeader removes work tree specific attributes from the archive entry: times are set to modTime,
+// permissions are set the same way git stores them (0644 or 0755 for files, 0777 for symlinks), owner is root
+func normalizeHeader(header *tar.Header, info os.FileInfo, modTime time.Time) {
+	modTime = modTime.UTC().Truncate(time.Second)
+
+	header.ModTime = modTime
+	header.AccessTime = modTime
+	header.ChangeTime = modTime
+
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		header.Mode = 0777
+		header.Size = 0
+	case info.Mode()&0111 != 0:
+		header.Mode = 0755
+	default:
+		header.Mode = 0644
+	}
+}
+
 func startMemprofile() {
 	runtime.MemProfileRate = 1
 }
@@ -0,0 +1,58 @@
+package true_git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/flant/werf/pkg/git_auth"
+)
+
+const (
+	credentialsUsernameEnv = "WERF_GIT_CREDENTIALS_USERNAME"
+	credentialsPasswordEnv = "WERF_GIT_CREDENTIALS_PASSWORD"
+)
+
+// credentialHelper passes credentials from the environment of git command, so they never appear in the command line or the repo config
+var credentialHelper = fmt.Sprintf(`!f() { test "$1" = get && echo "username=$%s" && echo "password=$%s"; }; f`, credentialsUsernameEnv, credentialsPasswordEnv)
+
+var (
+	remoteCredentials      = map[string]*git_auth.Credentials{}
+	remoteCredentialsMutex sync.Mutex
+)
+
+// SetRemoteCredentials sets credentials of the repo remote, which are used by git commands running with the git dir
+func SetRemoteCredentials(gitDir string, credentials *git_auth.Credentials) {
+	remoteCredentialsMutex.Lock()
+	defer remoteCredentialsMutex.Unlock()
+
+	if credentials == nil {
+		delete(remoteCredentials, filepath.Clean(gitDir))
+		return
+	}
+
+	remoteCredentials[filepath.Clean(gitDir)] = credentials
+}
+
+func getRemoteCredentials(gitDir string) *git_auth.Credentials {
+	remoteCredentialsMutex.Lock()
+	defer remoteCredentialsMutex.Unlock()
+
+	return remoteCredentials[filepath.Clean(gitDir)]
+}
+
+// credentialsArgsAndEnv returns git options, which replace configured credential helpers, and environment of the command
+func credentialsArgsAndEnv(credentials *git_auth.Credentials) ([]string, []string) {
+	if credentials == nil {
+		return nil, nil
+	}
+
+	args := []string{"-c", "credential.helper=", "-c", fmt.Sprintf("credential.helper=%s", credentialHelper)}
+	env := append(os.Environ(),
+		fmt.Sprintf("%s=%s", credentialsUsernameEnv, credentials.Username),
+		fmt.Sprintf("%s=%s", credentialsPasswordEnv, credentials.Password),
+	)
+
+	return args, env
+}
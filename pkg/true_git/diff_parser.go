@@ -31,6 +31,8 @@ const (
 	deleteFileDiff parserState = "deleteFileDiff"
 	modifyFileDiff parserState = "modifyFileDiff"
 	ignoreDiff     parserState = "ignoreDiff"
+
+	crossFilterDiff parserState = "crossFilterDiff"
 )
 
 type diffParser struct {
@@ -44,8 +46,13 @@ type diffParser struct {
 	BinaryPaths   []string
 	LastSeenPaths []string
 
+	// CrossFilterPaths are paths of renames and copies between filtered and not filtered paths
+	CrossFilterPaths []string
+
 	state   parserState
 	lineBuf []byte
+
+	crossFilterPathA, crossFilterPathB string
 }
 
 func appendUnique(list []string, value string) []string {
@@ -126,6 +133,21 @@ func (p *diffParser) handleDiffLine(line string) error {
 		}
 		return nil
 
+	case crossFilterDiff:
+		if strings.HasPrefix(line, "rename from ") {
+			return p.handleCrossFilterRenameOrCopy(true)
+		}
+		if strings.HasPrefix(line, "copy from ") {
+			return p.handleCrossFilterRenameOrCopy(false)
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			return p.handleDiffBegin(line)
+		}
+		if strings.HasPrefix(line, "Submodule ") {
+			return p.handleSubmoduleLine(line)
+		}
+		return nil
+
 	case diffBegin:
 		if strings.HasPrefix(line, "deleted file mode ") {
 			return p.handleDeleteFileDiff(line)
@@ -139,12 +161,20 @@ func (p *diffParser) handleDiffLine(line string) error {
 		if strings.HasPrefix(line, "index ") {
 			return p.handleModifyFileDiff(line)
 		}
+		if strings.HasPrefix(line, "similarity index ") {
+			return p.handleModifyFileDiff(line)
+		}
 		return fmt.Errorf("unexpected diff line in state `%s`: %#v", p.state, line)
 
 	case modifyFileDiff:
 		if strings.HasPrefix(line, "new mode ") {
 			return p.writeOutLine(line)
 		}
+		for _, prefix := range []string{"rename from ", "rename to ", "copy from ", "copy to "} {
+			if strings.HasPrefix(line, prefix) {
+				return p.handleRenameOrCopyPath(line, prefix)
+			}
+		}
 		if strings.HasPrefix(line, "--- ") {
 			return p.handleModifyFilePathA(line)
 		}
@@ -219,40 +249,63 @@ func (p *diffParser) handleDiffBegin(line string) error {
 
 	a, b := lineParts[2], lineParts[3]
 
-	trimmedPaths := make(map[string]string)
-
 	p.LastSeenPaths = nil
+	p.crossFilterPathA, p.crossFilterPathB = "", ""
+
+	type diffPath struct {
+		PathWithPrefix, Prefix string
+		Path                   string
+		IsQuoted, IsValid      bool
+	}
 
+	var diffPaths []*diffPath
 	for _, data := range []struct{ PathWithPrefix, Prefix string }{{a, "a/"}, {b, "b/"}} {
-		if strings.HasPrefix(data.PathWithPrefix, "\"") && strings.HasSuffix(data.PathWithPrefix, "\"") {
-			pathWithPrefix, err := strconv.Unquote(data.PathWithPrefix)
+		pathWithPrefix := data.PathWithPrefix
+		isQuoted := strings.HasPrefix(data.PathWithPrefix, "\"") && strings.HasSuffix(data.PathWithPrefix, "\"")
+		if isQuoted {
+			var err error
+			pathWithPrefix, err = strconv.Unquote(data.PathWithPrefix)
 			if err != nil {
 				return fmt.Errorf("unable to unqoute diff path %#v: %s", data.PathWithPrefix, err)
 			}
+		}
 
-			path := strings.TrimPrefix(pathWithPrefix, data.Prefix)
-			if !p.PathFilter.IsFilePathValid(path) {
-				p.state = ignoreDiff
-				return nil
-			}
+		path := strings.TrimPrefix(pathWithPrefix, data.Prefix)
+		diffPaths = append(diffPaths, &diffPath{
+			PathWithPrefix: data.PathWithPrefix,
+			Prefix:         data.Prefix,
+			Path:           path,
+			IsQuoted:       isQuoted,
+			IsValid:        p.PathFilter.IsFilePathValid(path),
+		})
+	}
 
-			newPath := p.PathFilter.TrimFileBasePath(path)
-			p.Paths = appendUnique(p.Paths, newPath)
-			p.LastSeenPaths = appendUnique(p.LastSeenPaths, newPath)
+	if !diffPaths[0].IsValid && !diffPaths[1].IsValid {
+		p.state = ignoreDiff
+		return nil
+	}
 
-			newPathWithPrefix := data.Prefix + newPath
-			trimmedPaths[data.PathWithPrefix] = strconv.Quote(newPathWithPrefix)
+	// Rename or copy between filtered and not filtered paths
+	if !diffPaths[0].IsValid || !diffPaths[1].IsValid {
+		if diffPaths[0].IsValid {
+			p.crossFilterPathA = diffPaths[0].Path
 		} else {
-			path := strings.TrimPrefix(data.PathWithPrefix, data.Prefix)
-			if !p.PathFilter.IsFilePathValid(path) {
-				p.state = ignoreDiff
-				return nil
-			}
+			p.crossFilterPathB = diffPaths[1].Path
+		}
+
+		p.state = crossFilterDiff
+		return nil
+	}
 
-			newPath := p.PathFilter.TrimFileBasePath(path)
-			p.Paths = appendUnique(p.Paths, newPath)
-			p.LastSeenPaths = appendUnique(p.LastSeenPaths, newPath)
+	trimmedPaths := make(map[string]string)
+	for _, data := range diffPaths {
+		newPath := p.PathFilter.TrimFileBasePath(data.Path)
+		p.Paths = appendUnique(p.Paths, newPath)
+		p.LastSeenPaths = appendUnique(p.LastSeenPaths, newPath)
 
+		if data.IsQuoted {
+			trimmedPaths[data.PathWithPrefix] = strconv.Quote(data.Prefix + newPath)
+		} else {
 			trimmedPaths[data.PathWithPrefix] = data.Prefix + newPath
 		}
 	}
@@ -264,6 +317,37 @@ func (p *diffParser) handleDiffBegin(line string) error {
 	return p.writeOutLine(newLine)
 }
 
+// handleCrossFilterRenameOrCopy collects paths, which should be added or deleted by the separate diff without renames detection:
+// the destination path of rename or copy into filtered paths and the source path of rename from filtered paths
+func (p *diffParser) handleCrossFilterRenameOrCopy(isRename bool) error {
+	if p.crossFilterPathB != "" {
+		p.CrossFilterPaths = appendUnique(p.CrossFilterPaths, p.crossFilterPathB)
+	}
+
+	if isRename && p.crossFilterPathA != "" {
+		p.CrossFilterPaths = appendUnique(p.CrossFilterPaths, p.crossFilterPathA)
+	}
+
+	p.state = ignoreDiff
+
+	return nil
+}
+
+func (p *diffParser) handleRenameOrCopyPath(line, prefix string) error {
+	path := strings.TrimPrefix(line, prefix)
+
+	if strings.HasPrefix(path, "\"") && strings.HasSuffix(path, "\"") {
+		unquotedPath, err := strconv.Unquote(path)
+		if err != nil {
+			return fmt.Errorf("unable to unqoute diff path %#v: %s", path, err)
+		}
+
+		return p.writeOutLine(prefix + strconv.Quote(p.PathFilter.TrimFileBasePath(unquotedPath)))
+	}
+
+	return p.writeOutLine(prefix + p.PathFilter.TrimFileBasePath(path))
+}
+
 func (p *diffParser) handleDeleteFileDiff(line string) error {
 	p.state = deleteFileDiff
 	return p.writeOutLine(line)
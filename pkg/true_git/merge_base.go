@@ -0,0 +1,27 @@
+package true_git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// MergeBase returns the best common ancestor of two commits
+func MergeBase(ctx context.Context, gitDir, commit1, commit2 string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", gitDir, "merge-base", commit1, commit2)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok && stderr.Len() == 0 {
+			return "", fmt.Errorf("commits `%s` and `%s` have no common ancestor", commit1, commit2)
+		}
+		return "", fmt.Errorf("git merge-base failed: %s\n%s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
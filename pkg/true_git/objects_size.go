@@ -0,0 +1,106 @@
+package true_git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TreeSize returns total size and number of the commit files, which satisfy the path filter.
+// Sizes are taken from git objects, so blobs are not read, but missing blobs of the partial clone would be fetched.
+func TreeSize(ctx context.Context, gitDir, commit string, pathFilter PathFilter) (uint64, int, error) {
+	output, err := gitOutput(ctx, gitDir, "ls-tree", "-r", "-l", "-z", "--full-tree", commit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size uint64
+	var count int
+
+	for _, entry := range strings.Split(output, "\x00") {
+		if entry == "" {
+			continue
+		}
+
+		// <mode> SP <type> SP <object> SP+ <size> TAB <file>
+		parts := strings.SplitN(entry, "\t", 2)
+		if len(parts) != 2 {
+			return 0, 0, fmt.Errorf("unexpected git ls-tree output line %q", entry)
+		}
+
+		fields := strings.Fields(parts[0])
+		if len(fields) != 4 || fields[1] != "blob" || !pathFilter.IsFilePathValid(parts[1]) {
+			continue
+		}
+
+		objectSize, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected git ls-tree output line %q: %s", entry, err)
+		}
+
+		size += objectSize
+		count++
+	}
+
+	return size, count, nil
+}
+
+// DiffSize returns total size of old and new versions of the files, which have been changed between commits and satisfy the path filter
+func DiffSize(ctx context.Context, gitDir, fromCommit, toCommit string, pathFilter PathFilter) (uint64, error) {
+	output, err := gitOutput(ctx, gitDir, "diff-tree", "-r", "-z", "--no-renames", fromCommit, toCommit)
+	if err != nil {
+		return 0, err
+	}
+
+	var objects []string
+
+	// :<src mode> SP <dst mode> SP <src object> SP <dst object> SP <status> NUL <path> NUL
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		meta := strings.Fields(strings.TrimPrefix(fields[i], ":"))
+		if len(meta) != 5 {
+			return 0, fmt.Errorf("unexpected git diff-tree output %q", fields[i])
+		}
+
+		if !pathFilter.IsFilePathValid(fields[i+1]) {
+			continue
+		}
+
+		for ind, object := range meta[2:4] {
+			// gitlinks (160000) are submodules commits
+			if strings.Trim(object, "0") == "" || meta[ind] == "160000" {
+				continue
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	if len(objects) == 0 {
+		return 0, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", gitDir, "cat-file", "--batch-check=%(objectsize)")
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	sizes, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("git cat-file failed: %s\n%s", err, stderr.String())
+	}
+
+	var size uint64
+	for _, line := range strings.Fields(string(sizes)) {
+		objectSize, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected git cat-file output line %q: %s", line, err)
+		}
+		size += objectSize
+	}
+
+	return size, nil
+}
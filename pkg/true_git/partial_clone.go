@@ -0,0 +1,379 @@
+package true_git
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/flant/werf/pkg/git_auth"
+)
+
+// MinGitVersionWithPartialCloneConstraint is a version of git, which supports partial clone with blob:none filter and lazy fetching
+const MinGitVersionWithPartialCloneConstraint = "2.22.0"
+
+// fetchObjectsChunkSize limits number of objects and paths passed to git in one command
+const fetchObjectsChunkSize = 500
+
+func IsPartialCloneSupported() bool {
+	c, err := semver.NewConstraint(fmt.Sprintf(">= %s", MinGitVersionWithPartialCloneConstraint))
+	if err != nil {
+		panic(err)
+	}
+
+	return gitVersionObj != nil && c.Check(gitVersionObj)
+}
+
+// PartialClone makes bare clone without blobs (filter=blob:none): missing blobs are fetched on demand.
+// Remote branches are stored as refs/remotes/origin/* like in go-git clone.
+// Credentials set by SetRemoteCredentials for the git dir are used.
+func PartialClone(ctx context.Context, url, gitDir string) error {
+	if _, err := gitOutputWithCredentials(ctx, "", getRemoteCredentials(gitDir), "clone", "--bare", "--filter=blob:none", "--no-tags", url, gitDir); err != nil {
+		return fmt.Errorf("partial clone failed: %s", err)
+	}
+
+	return configurePartialClone(ctx, url, gitDir)
+}
+
+// ResumePartialClone fetches commits and trees, which are missing in the interrupted partial clone
+func ResumePartialClone(ctx context.Context, url, gitDir string) error {
+	return configurePartialClone(ctx, url, gitDir)
+}
+
+func configurePartialClone(ctx context.Context, url, gitDir string) error {
+	if err := runGit(ctx, gitDir, "config", "remote.origin.url", url); err != nil {
+		return err
+	}
+
+	if err := runGit(ctx, gitDir, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return err
+	}
+
+	return PartialFetch(ctx, gitDir)
+}
+
+// PartialFetch fetches new commits and trees of the partial clone without blobs,
+// branches and tags removed from the remote are pruned
+func PartialFetch(ctx context.Context, gitDir string) error {
+	if err := runGit(ctx, gitDir, "fetch", "--filter=blob:none", "--force", "--tags", "--prune", "--prune-tags", "origin"); err != nil {
+		return fmt.Errorf("partial fetch failed: %s", err)
+	}
+
+	return nil
+}
+
+// CheckConnectivity checks that all objects reachable from the repo refs exist (missing objects of the partial clone are allowed)
+func CheckConnectivity(ctx context.Context, gitDir string) error {
+	if err := runGit(ctx, gitDir, "fsck", "--connectivity-only", "--no-dangling"); err != nil {
+		return fmt.Errorf("connectivity check failed: %s", err)
+	}
+
+	return nil
+}
+
+// IsPartialClone checks whether repo is a partial clone with promisor remote
+// (extensions.partialClone is set by older git versions, remote.<name>.promisor by newer ones)
+func IsPartialClone(gitDir string) bool {
+	cmd := exec.Command("git", "--git-dir", gitDir, "config", "--get-regexp", `^(extensions\.partialclone|remote\..*\.promisor)$`)
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) != ""
+}
+
+// FetchMissingBlobs fetches missing blobs of the commit files, which satisfy isPathValid, with batch requests
+// instead of lazy fetching of each blob by git. Returns paths of all such files of the commit.
+func FetchMissingBlobs(ctx context.Context, gitDir, commit string, isPathValid func(path string) bool) ([]string, error) {
+	missing, err := missingObjects(ctx, gitDir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := gitOutput(ctx, gitDir, "ls-tree", "-r", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths, objectsToFetch []string
+	for _, entry := range strings.Split(output, "\x00") {
+		parts := strings.SplitN(entry, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		fields := strings.Fields(parts[0])
+		if len(fields) != 3 || fields[1] != "blob" || !isPathValid(parts[1]) {
+			continue
+		}
+
+		paths = append(paths, parts[1])
+		if missing[fields[2]] {
+			objectsToFetch = append(objectsToFetch, fields[2])
+		}
+	}
+
+	if err := fetchObjects(ctx, gitDir, objectsToFetch); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// fetchChangedBlobs fetches missing blobs of the files changed between commits, which satisfy any of path filters.
+// Returns paths of such files.
+func fetchChangedBlobs(ctx context.Context, gitDir, fromCommit, toCommit string, pathFilters ...PathFilter) ([]string, error) {
+	missing, err := missingObjects(ctx, gitDir, fromCommit, toCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	// raw diff entry: ":<old mode> <new mode> <old blob> <new blob> <status>\x00<path>\x00"
+	output, err := gitOutput(ctx, gitDir, "diff", "--raw", "-z", "--no-renames", "--no-abbrev", fromCommit, toCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths, objectsToFetch []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(entries); i += 2 {
+		fields := strings.Fields(entries[i])
+		path := entries[i+1]
+		if len(fields) != 5 || !isFilePathValidForAnyFilter(path, pathFilters) {
+			continue
+		}
+
+		paths = append(paths, path)
+		for _, object := range fields[2:4] {
+			if missing[object] {
+				objectsToFetch = append(objectsToFetch, object)
+			}
+		}
+	}
+
+	if err := fetchObjects(ctx, gitDir, objectsToFetch); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+func isFilePathValidForAnyFilter(path string, pathFilters []PathFilter) bool {
+	for _, pathFilter := range pathFilters {
+		if pathFilter.IsFilePathValid(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// missingObjects lists objects of the commits trees, which are not fetched yet
+func missingObjects(ctx context.Context, gitDir string, commits ...string) (map[string]bool, error) {
+	args := []string{"rev-list", "--objects", "--missing=print"}
+	for _, commit := range commits {
+		args = append(args, fmt.Sprintf("%s^{tree}", commit))
+	}
+
+	output, err := gitOutput(ctx, gitDir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "?") {
+			missing[strings.TrimPrefix(line, "?")] = true
+		}
+	}
+
+	return missing, nil
+}
+
+func fetchObjects(ctx context.Context, gitDir string, objects []string) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Fetching %d missing blobs ...\n", len(objects))
+
+	for _, chunk := range chunks(objects, fetchObjectsChunkSize) {
+		// objects are requested by hash: protocol v2 allows wants of any reachable objects, noop negotiation skips walking of local commits
+		args := append([]string{"-c", "protocol.version=2", "-c", "fetch.negotiationAlgorithm=noop", "fetch", "--no-tags", "--filter=blob:none", "origin"}, chunk...)
+		if err := runGit(ctx, gitDir, args...); err != nil {
+			return fmt.Errorf("cannot fetch missing blobs: %s", err)
+		}
+	}
+
+	fmt.Printf("Fetching %d missing blobs DONE\n", len(objects))
+
+	return nil
+}
+
+// PrepareSparseWorkTree is PrepareWorkTree for the partial clone: only files, which satisfy path filter, are checked out
+func PrepareSparseWorkTree(ctx context.Context, gitDir, workTreeDir string, commit string, pathFilter PathFilter) error {
+	var err error
+
+	gitDir, err = filepath.Abs(gitDir)
+	if err != nil {
+		return fmt.Errorf("bad git dir `%s`: %s", gitDir, err)
+	}
+
+	workTreeDir, err = filepath.Abs(workTreeDir)
+	if err != nil {
+		return fmt.Errorf("bad work tree dir `%s`: %s", workTreeDir, err)
+	}
+
+	if err := switchSparseWorkTree(ctx, gitDir, workTreeDir, commit, pathFilter); err != nil {
+		return fmt.Errorf("cannot switch sparse work tree `%s` to commit `%s`: %s", workTreeDir, commit, err)
+	}
+
+	return nil
+}
+
+// switchSparseWorkTree replaces content of the work tree with the commit files, which satisfy path filter,
+// so blobs of other files of the partial clone are not fetched
+func switchSparseWorkTree(ctx context.Context, gitDir, workTreeDir, commit string, pathFilter PathFilter) error {
+	fmt.Printf("Switch sparse work tree `%s` to commit `%s` ...\n", workTreeDir, commit)
+
+	paths, err := FetchMissingBlobs(ctx, gitDir, commit, pathFilter.IsFilePathValid)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(workTreeDir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(workTreeDir, pathFilter.BasePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks(paths, fetchObjectsChunkSize) {
+		args := []string{"--git-dir", gitDir, "archive", "--format=tar", commit, "--"}
+		for _, path := range chunk {
+			args = append(args, fmt.Sprintf(":(literal)%s", path))
+		}
+
+		cmd := exec.CommandContext(ctx, "git", args...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+
+		extractErr := extractTar(stdout, workTreeDir)
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("git archive failed: %s\n%s", err, stderr.String())
+		}
+		if extractErr != nil {
+			return extractErr
+		}
+	}
+
+	fmt.Printf("Switch sparse work tree `%s` to commit `%s` OK\n", workTreeDir, commit)
+
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read git archive: %s", err)
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func runGit(ctx context.Context, gitDir string, args ...string) error {
+	_, err := gitOutput(ctx, gitDir, args...)
+	return err
+}
+
+// gitOutput runs git command with credentials of the repo remote, missing objects of the partial clone can be fetched by any command
+func gitOutput(ctx context.Context, gitDir string, args ...string) (string, error) {
+	var credentials *git_auth.Credentials
+	if gitDir != "" {
+		credentials = getRemoteCredentials(gitDir)
+	}
+
+	return gitOutputWithCredentials(ctx, gitDir, credentials, args...)
+}
+
+func gitOutputWithCredentials(ctx context.Context, gitDir string, credentials *git_auth.Credentials, args ...string) (string, error) {
+	if gitDir != "" {
+		args = append([]string{"--git-dir", gitDir}, args...)
+	}
+
+	credentialsArgs, env := credentialsArgsAndEnv(credentials)
+
+	cmd := exec.CommandContext(ctx, "git", append(credentialsArgs, args...)...)
+	cmd.Env = env
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return string(output), nil
+}
+
+func chunks(list []string, size int) [][]string {
+	var res [][]string
+	for len(list) > size {
+		res = append(res, list[:size])
+		list = list[size:]
+	}
+	if len(list) > 0 {
+		res = append(res, list)
+	}
+	return res
+}
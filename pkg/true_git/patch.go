@@ -1,6 +1,7 @@
 package true_git
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -16,6 +17,11 @@ type PatchOptions struct {
 
 	WithEntireFileContext bool
 	WithBinary            bool
+
+	// DetectRenames enables renames detection, DetectCopies enables copies detection (implies renames detection)
+	DetectRenames, DetectCopies bool
+
+	Submodules SubmodulesOptions
 }
 
 type PatchDescriptor struct {
@@ -23,19 +29,46 @@ type PatchDescriptor struct {
 	BinaryPaths []string
 }
 
-func PatchWithSubmodules(out io.Writer, gitDir, workTreeDir string, opts PatchOptions) (*PatchDescriptor, error) {
-	return writePatch(out, gitDir, workTreeDir, true, opts)
+// PatchTarget is an output of one of the path-filtered patches created by Patches
+type PatchTarget struct {
+	Out        io.Writer
+	PathFilter PathFilter
 }
 
-func Patch(out io.Writer, gitDir string, opts PatchOptions) (*PatchDescriptor, error) {
-	return writePatch(out, gitDir, "", false, opts)
+func PatchWithSubmodules(ctx context.Context, out io.Writer, gitDir, workTreeDir string, opts PatchOptions) (*PatchDescriptor, error) {
+	return writePatch(ctx, out, gitDir, workTreeDir, true, opts)
+}
+
+func Patch(ctx context.Context, out io.Writer, gitDir string, opts PatchOptions) (*PatchDescriptor, error) {
+	return writePatch(ctx, out, gitDir, "", false, opts)
+}
+
+// Patches creates patches of the same commits range for multiple path filters with a single git diff invocation,
+// instead of running git diff for each path filter separately.
+// Patch of each target is written into the target output, descriptors are returned in the targets order.
+// PathFilter of opts is ignored, submodules are not supported.
+func Patches(ctx context.Context, gitDir string, opts PatchOptions, targets []PatchTarget) ([]*PatchDescriptor, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	return writePatches(ctx, gitDir, "", false, opts, targets)
 }
 
 func debugPatch() bool {
 	return os.Getenv("WERF_TRUE_GIT_DEBUG_PATCH") == "1"
 }
 
-func writePatch(out io.Writer, gitDir, workTreeDir string, withSubmodules bool, opts PatchOptions) (*PatchDescriptor, error) {
+func writePatch(ctx context.Context, out io.Writer, gitDir, workTreeDir string, withSubmodules bool, opts PatchOptions) (*PatchDescriptor, error) {
+	descs, err := writePatches(ctx, gitDir, workTreeDir, withSubmodules, opts, []PatchTarget{{Out: out, PathFilter: opts.PathFilter}})
+	if err != nil {
+		return nil, err
+	}
+
+	return descs[0], nil
+}
+
+func writePatches(ctx context.Context, gitDir, workTreeDir string, withSubmodules bool, opts PatchOptions, targets []PatchTarget) ([]*PatchDescriptor, error) {
 	var err error
 
 	gitDir, err = filepath.Abs(gitDir)
@@ -76,62 +109,159 @@ func writePatch(out io.Writer, gitDir, workTreeDir string, withSubmodules bool,
 		diffOpts = append(diffOpts, "--binary")
 	}
 
-	var cmd *exec.Cmd
+	renameDiffOpts := []string{}
+	if opts.DetectCopies {
+		renameDiffOpts = append(renameDiffOpts, "-C")
+	} else if opts.DetectRenames {
+		renameDiffOpts = append(renameDiffOpts, "-M")
+	}
+
+	var pathspecExcludes []string
 
 	if withSubmodules {
 		var err error
 
-		err = switchWorkTree(gitDir, workTreeDir, opts.ToCommit)
+		err = switchWorkTree(ctx, gitDir, workTreeDir, opts.ToCommit)
 		if err != nil {
 			return nil, fmt.Errorf("cannot reset work tree `%s` to commit `%s`: %s", workTreeDir, opts.ToCommit, err)
 		}
 
-		err = deinitSubmodules(gitDir, workTreeDir)
+		err = deinitSubmodules(ctx, gitDir, workTreeDir)
 		if err != nil {
 			return nil, fmt.Errorf("cannot deinit submodules: %s", err)
 		}
 
-		err = updateSubmodules(gitDir, workTreeDir)
+		err = updateSubmodules(ctx, gitDir, workTreeDir, opts.Submodules)
 		if err != nil {
 			return nil, fmt.Errorf("cannot update submodules: %s", err)
 		}
 
-		gitArgs := append(commonGitOpts, "--work-tree", workTreeDir)
+		pathspecExcludes, err = getSubmodulesPathspecExcludes(ctx, workTreeDir, opts.Submodules)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get submodules pathspec: %s", err)
+		}
+	}
+
+	diffCmd := func(extraDiffOpts, pathspecs []string) *exec.Cmd {
+		gitArgs := append([]string{}, commonGitOpts...)
+		if withSubmodules {
+			gitArgs = append(gitArgs, "--work-tree", workTreeDir)
+		}
 		gitArgs = append(gitArgs, "diff")
 		gitArgs = append(gitArgs, diffOpts...)
+		gitArgs = append(gitArgs, extraDiffOpts...)
 		gitArgs = append(gitArgs, opts.FromCommit, opts.ToCommit)
+		if len(pathspecs) > 0 {
+			gitArgs = append(gitArgs, "--")
+			gitArgs = append(gitArgs, pathspecs...)
+		}
 
 		if debugPatch() {
 			fmt.Printf("# git %s\n", strings.Join(gitArgs, " "))
 		}
 
-		cmd = exec.Command("git", gitArgs...)
+		cmd := exec.CommandContext(ctx, "git", gitArgs...)
+		if withSubmodules {
+			cmd.Dir = workTreeDir // required for `git diff` with submodules
+		}
 
-		cmd.Dir = workTreeDir // required for `git diff` with submodules
-	} else {
-		gitArgs := append(commonGitOpts, "diff")
-		gitArgs = append(gitArgs, diffOpts...)
-		gitArgs = append(gitArgs, opts.FromCommit, opts.ToCommit)
+		return cmd
+	}
 
+	var parsers []*diffParser
+	var pathFilters []PathFilter
+	for _, target := range targets {
+		out := target.Out
 		if debugPatch() {
-			fmt.Printf("# git %s\n", strings.Join(gitArgs, " "))
+			out = io.MultiWriter(out, os.Stdout)
+		}
+
+		parsers = append(parsers, makeDiffParser(out, target.PathFilter))
+		pathFilters = append(pathFilters, target.PathFilter)
+	}
+
+	var pathspecs []string
+	if len(pathspecExcludes) > 0 {
+		pathspecs = append([]string{"."}, pathspecExcludes...)
+	}
+
+	// diff of the partial clone is limited to the changed files, which satisfy any of path filters, with literal pathspecs:
+	// git diff without pathspecs lazily fetches blobs of all changed files one by one to print and to detect renames,
+	// though the parsers drop patches of the files, which do not satisfy path filters
+	if !withSubmodules && IsPartialClone(gitDir) {
+		paths, err := fetchChangedBlobs(ctx, gitDir, opts.FromCommit, opts.ToCommit, pathFilters...)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(paths) == 0 {
+			var descs []*PatchDescriptor
+			for range targets {
+				descs = append(descs, &PatchDescriptor{})
+			}
+			return descs, nil
+		}
+
+		for _, path := range paths {
+			pathspecs = append(pathspecs, fmt.Sprintf(":(literal)%s", path))
+		}
+	}
+
+	if err := runDiff(ctx, diffCmd(renameDiffOpts, pathspecs), parsers...); err != nil {
+		return nil, err
+	}
+
+	var descs []*PatchDescriptor
+	for _, p := range parsers {
+		// Renames and copies between filtered and not filtered paths cannot be applied partially:
+		// such files are added or deleted with the separate diff without rename detection
+		if len(p.CrossFilterPaths) > 0 {
+			var crossFilterPathspecs []string
+			for _, path := range p.CrossFilterPaths {
+				crossFilterPathspecs = append(crossFilterPathspecs, fmt.Sprintf(":(literal)%s", path))
+			}
+			crossFilterPathspecs = append(crossFilterPathspecs, pathspecExcludes...)
+
+			if err := runDiff(ctx, diffCmd([]string{"--no-renames"}, crossFilterPathspecs), p); err != nil {
+				return nil, err
+			}
+		}
+
+		desc := &PatchDescriptor{
+			Paths:       p.Paths,
+			BinaryPaths: p.BinaryPaths,
 		}
 
-		cmd = exec.Command("git", gitArgs...)
+		if debugPatch() {
+			fmt.Printf("Patch %s paths count is %d, binary paths count is %d\n", p.PathFilter.String(), len(desc.Paths), len(desc.BinaryPaths))
+			for _, path := range desc.Paths {
+				fmt.Printf("Patch path `%s`\n", path)
+			}
+			for _, path := range desc.BinaryPaths {
+				fmt.Printf("Binary patch path `%s`\n", path)
+			}
+		}
+
+		descs = append(descs, desc)
 	}
 
+	return descs, nil
+}
+
+// runDiff passes output of the single git diff to all parsers, each parser writes its own path-filtered patch
+func runDiff(ctx context.Context, cmd *exec.Cmd, parsers ...*diffParser) error {
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("error creating git diff stdout pipe: %s", err)
+		return fmt.Errorf("error creating git diff stdout pipe: %s", err)
 	}
 
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("error creating git diff stderr pipe: %s", err)
+		return fmt.Errorf("error creating git diff stderr pipe: %s", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("error starting git diff: %s", err)
+		return fmt.Errorf("error starting git diff: %s", err)
 	}
 
 	outputErrChan := make(chan error)
@@ -180,24 +310,22 @@ func writePatch(out io.Writer, gitDir, workTreeDir string, withSubmodules bool,
 		doneChan <- true
 	}()
 
-	if debugPatch() {
-		out = io.MultiWriter(out, os.Stdout)
-	}
-
-	p := makeDiffParser(out, opts.PathFilter)
-
 WaitForData:
 	for {
 		select {
 		case err := <-outputErrChan:
-			return nil, fmt.Errorf("error getting git diff output: %s\nunrecognized output:\n%s", err, p.UnrecognizedCapture.String())
+			return fmt.Errorf("error getting git diff output: %s\nunrecognized output:\n%s", err, parsers[0].UnrecognizedCapture.String())
 		case stdoutData := <-stdoutChan:
-			if err := p.HandleStdout(stdoutData); err != nil {
-				return nil, err
+			for _, p := range parsers {
+				if err := p.HandleStdout(stdoutData); err != nil {
+					return err
+				}
 			}
 		case stderrData := <-stderrChan:
-			if err := p.HandleStderr(stderrData); err != nil {
-				return nil, err
+			for _, p := range parsers {
+				if err := p.HandleStderr(stderrData); err != nil {
+					return err
+				}
 			}
 		case <-doneChan:
 			break WaitForData
@@ -205,25 +333,13 @@ WaitForData:
 	}
 
 	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("git diff error: %s\nunrecognized output:\n%s", err, p.UnrecognizedCapture.String())
-	}
-
-	desc := &PatchDescriptor{
-		Paths:       p.Paths,
-		BinaryPaths: p.BinaryPaths,
-	}
-
-	if debugPatch() {
-		fmt.Printf("Patch paths count is %d, binary paths count is %d\n", len(desc.Paths), len(desc.BinaryPaths))
-		for _, path := range desc.Paths {
-			fmt.Printf("Patch path `%s`\n", path)
-		}
-		for _, path := range desc.BinaryPaths {
-			fmt.Printf("Binary patch path `%s`\n", path)
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		return fmt.Errorf("git diff error: %s\nunrecognized output:\n%s", err, parsers[0].UnrecognizedCapture.String())
 	}
 
-	return desc, nil
+	return nil
 }
 
 func consumePipeOutput(pipe io.ReadCloser, handleChunk func(data []byte) error) error {
@@ -0,0 +1,198 @@
+package true_git
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=werf", "-c", "user.email=werf@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// initTestRepo creates repo with two commits, which change files in several directories, and returns git dir and commits
+func initTestRepo(t *testing.T) (string, string, string) {
+	dir, err := ioutil.TempDir("", "werf-true-git-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runTestGit(t, dir, "init", "-q")
+	writeFiles(t, dir, map[string]string{
+		"app/main.go":       "package main\n",
+		"app/config.yaml":   "a: 1\n",
+		"docs/index.md":     "# index\n",
+		"docs/old.md":       "# old\n",
+		"lib/util/util.go":  "package util\n",
+		"lib/util/const.go": "package util\n\nconst A = 1\n",
+	})
+	runTestGit(t, dir, "add", "-A")
+	runTestGit(t, dir, "commit", "-q", "-m", "first")
+	fromCommit := runTestGit(t, dir, "rev-parse", "HEAD")
+
+	writeFiles(t, dir, map[string]string{
+		"app/main.go":       "package main\n\nfunc main() {}\n",
+		"app/new.go":        "package main\n",
+		"docs/index.md":     "# index\n\nchanged\n",
+		"lib/util/const.go": "package util\n\nconst A = 2\n",
+	})
+	runTestGit(t, dir, "rm", "-q", "docs/old.md")
+	runTestGit(t, dir, "mv", "lib/util/util.go", "app/util.go")
+	runTestGit(t, dir, "add", "-A")
+	runTestGit(t, dir, "commit", "-q", "-m", "second")
+	toCommit := runTestGit(t, dir, "rev-parse", "HEAD")
+
+	return filepath.Join(dir, ".git"), fromCommit, toCommit
+}
+
+func TestPatches(t *testing.T) {
+	gitDir, fromCommit, toCommit := initTestRepo(t)
+	defer os.RemoveAll(filepath.Dir(gitDir))
+
+	pathFilters := []PathFilter{
+		{},
+		{BasePath: "app"},
+		{BasePath: "docs", ExcludePaths: []string{"old.md"}},
+		{IncludePaths: []string{"lib/**/*.go", "app/util.go"}},
+		{BasePath: "lib/util/const.go"},
+		{BasePath: "nonexistent"},
+	}
+
+	for _, detectRenames := range []bool{false, true} {
+		opts := PatchOptions{FromCommit: fromCommit, ToCommit: toCommit, DetectRenames: detectRenames}
+
+		var targets []PatchTarget
+		var outs []*bytes.Buffer
+		for _, pathFilter := range pathFilters {
+			out := &bytes.Buffer{}
+			outs = append(outs, out)
+			targets = append(targets, PatchTarget{Out: out, PathFilter: pathFilter})
+		}
+
+		descs, err := Patches(context.Background(), gitDir, opts, targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for ind, pathFilter := range pathFilters {
+			patchOpts := opts
+			patchOpts.PathFilter = pathFilter
+
+			expectedOut := &bytes.Buffer{}
+			expectedDesc, err := Patch(context.Background(), expectedOut, gitDir, patchOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(expectedDesc, descs[ind]) {
+				t.Errorf("%s (detectRenames=%v)\n[EXPECTED]: %#v\n[GOT]: %#v", pathFilter.String(), detectRenames, expectedDesc, descs[ind])
+			}
+
+			if expectedOut.String() != outs[ind].String() {
+				t.Errorf("%s (detectRenames=%v)\n[EXPECTED]:\n%s\n[GOT]:\n%s", pathFilter.String(), detectRenames, expectedOut.String(), outs[ind].String())
+			}
+		}
+	}
+}
+
+func TestPatches_noTargets(t *testing.T) {
+	descs, err := Patches(context.Background(), "/nonexistent", PatchOptions{}, nil)
+	if err != nil || descs != nil {
+		t.Errorf("\n[EXPECTED]: <nil>, <nil>\n[GOT]: %#v, %v", descs, err)
+	}
+}
+
+func TestPatches_partialClone(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if !IsPartialCloneSupported() {
+		t.Skipf("git %s does not support partial clone", GitVersion)
+	}
+
+	gitDir, fromCommit, toCommit := initTestRepo(t)
+	defer os.RemoveAll(filepath.Dir(gitDir))
+	runTestGit(t, filepath.Dir(gitDir), "config", "uploadpack.allowFilter", "true")
+	runTestGit(t, filepath.Dir(gitDir), "config", "uploadpack.allowAnySHA1InWant", "true")
+
+	cloneDir, err := ioutil.TempDir("", "werf-true-git-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cloneDir)
+
+	cloneGitDir := filepath.Join(cloneDir, "repo.git")
+	if err := PartialClone(context.Background(), "file://"+filepath.Dir(gitDir), cloneGitDir); err != nil {
+		t.Fatal(err)
+	}
+
+	pathFilter := PathFilter{BasePath: "docs"}
+	opts := PatchOptions{FromCommit: fromCommit, ToCommit: toCommit, DetectRenames: true}
+
+	out := &bytes.Buffer{}
+	descs, err := Patches(context.Background(), cloneGitDir, opts, []PatchTarget{{Out: out, PathFilter: pathFilter}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patchOpts := opts
+	patchOpts.PathFilter = pathFilter
+
+	expectedOut := &bytes.Buffer{}
+	expectedDesc, err := Patch(context.Background(), expectedOut, gitDir, patchOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expectedDesc, descs[0]) {
+		t.Errorf("\n[EXPECTED]: %#v\n[GOT]: %#v", expectedDesc, descs[0])
+	}
+
+	if expectedOut.String() != out.String() {
+		t.Errorf("\n[EXPECTED]:\n%s\n[GOT]:\n%s", expectedOut.String(), out.String())
+	}
+
+	// git diff is limited to the changed paths of the filter, otherwise git lazily fetches blobs of all changed files
+	missing, err := missingObjects(context.Background(), cloneGitDir, fromCommit, toCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, object := range []string{toCommit + ":app/main.go", toCommit + ":app/new.go", fromCommit + ":lib/util/const.go"} {
+		blob := runTestGit(t, filepath.Dir(gitDir), "rev-parse", object)
+		if !missing[blob] {
+			t.Errorf("\n[EXPECTED]: blob %s of %s is not fetched\n[GOT]: fetched", blob, object)
+		}
+	}
+
+	for _, object := range []string{toCommit + ":docs/index.md", fromCommit + ":docs/old.md"} {
+		blob := runTestGit(t, filepath.Dir(gitDir), "rev-parse", object)
+		if missing[blob] {
+			t.Errorf("\n[EXPECTED]: blob %s of %s is fetched\n[GOT]: not fetched", blob, object)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package true_git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ErrRevisionNotFound is returned by ResolveCommit when the revision does not point to any commit of the repo
+var ErrRevisionNotFound = errors.New("revision not found")
+
+var (
+	fullCommitRegexp   = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	abbrevCommitRegexp = regexp.MustCompile(`^[0-9a-f]{4,39}$`)
+)
+
+// ResolveCommit returns full commit hash for the revision: full or abbreviated commit hash, branch or tag name
+// or an expression like HEAD~2 (see gitrevisions(7)).
+// Abbreviated hashes and ref names, which match several objects or refs, are not resolved.
+func ResolveCommit(ctx context.Context, gitDir, revision string) (string, error) {
+	if revision == "" {
+		return "", fmt.Errorf("empty revision")
+	}
+
+	if strings.HasPrefix(revision, "-") {
+		return "", fmt.Errorf("bad revision `%s`", revision)
+	}
+
+	commit, err := revParse(ctx, gitDir, "--verify", fmt.Sprintf("%s^{commit}", revision))
+	if err != nil {
+		return "", err
+	}
+
+	// ref names take precedence over abbreviated hashes, so that the ref can hide the commit with the same hash prefix
+	if abbrevCommitRegexp.MatchString(revision) && !strings.HasPrefix(commit, revision) {
+		if objects, err := revParse(ctx, gitDir, fmt.Sprintf("--disambiguate=%s", revision)); err == nil && objects != "" {
+			return "", fmt.Errorf("revision `%s` is ambiguous: it is both a ref name and an abbreviated object hash, specify full commit hash or full ref name", revision)
+		}
+	}
+
+	if !fullCommitRegexp.MatchString(commit) {
+		return "", fmt.Errorf("git rev-parse returned unexpected output for revision `%s`: %q", revision, commit)
+	}
+
+	return commit, nil
+}
+
+func revParse(ctx context.Context, gitDir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", gitDir, "rev-parse"}, args...)...)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+
+	if strings.Contains(stderr.String(), "is ambiguous") {
+		return "", fmt.Errorf("revision is ambiguous, specify full commit hash or full ref name (e.g. refs/tags/NAME):\n%s", strings.TrimSpace(stderr.String()))
+	}
+
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok && strings.Contains(stderr.String(), "Needed a single revision") {
+			return "", ErrRevisionNotFound
+		}
+		return "", fmt.Errorf("git rev-parse failed: %s\n%s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,66 @@
+package true_git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// WorkTreeChangedFiles returns paths of modified, added, deleted and untracked files of the work tree relative to the work tree dir
+func WorkTreeChangedFiles(ctx context.Context, gitDir, workTreeDir string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", gitDir, "--work-tree", workTreeDir, "status", "--porcelain", "-z", "--untracked-files=all")
+	cmd.Dir = workTreeDir
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %s\n%s", err, stderr.String())
+	}
+
+	var res []string
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		res = append(res, entry[3:])
+
+		// renamed and copied entries are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+			if i < len(entries) && entries[i] != "" {
+				res = append(res, entries[i])
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// CommitsChangedFiles returns paths of files changed between two commits relative to the work tree dir
+func CommitsChangedFiles(ctx context.Context, gitDir, fromCommit, toCommit string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", gitDir, "diff", "--name-only", "--no-renames", "-z", fromCommit, toCommit)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %s\n%s", err, stderr.String())
+	}
+
+	var res []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if entry != "" {
+			res = append(res, entry)
+		}
+	}
+
+	return res, nil
+}
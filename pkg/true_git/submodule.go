@@ -1,14 +1,55 @@
 package true_git
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
-func deinitSubmodules(repoDir, workTreeDir string) error {
+type SubmodulesOptions struct {
+	// Include limits processed submodules to the specified paths (all submodules are processed by default)
+	Include []string
+	// Revisions overrides commits recorded in the superproject by submodule path
+	Revisions map[string]string
+	// RevisionsSources are repos to fetch revisions from by submodule path, when revision is not available in the submodule origin
+	// (e.g. not pushed commit of the submodule work tree in the project directory)
+	RevisionsSources map[string]string
+}
+
+func (opts *SubmodulesOptions) isSubmoduleIncluded(path string) bool {
+	if len(opts.Include) == 0 {
+		return true
+	}
+
+	for _, includePath := range opts.Include {
+		if filepath.Clean(includePath) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (opts *SubmodulesOptions) isSubmodulePinned(path string) bool {
+	for revisionPath := range opts.Revisions {
+		if filepath.Clean(revisionPath) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func deinitSubmodules(ctx context.Context, repoDir, workTreeDir string) error {
 	fmt.Printf("Deinit submodules in work tree `%s` ...\n", workTreeDir)
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		"git", "--git-dir", repoDir, "--work-tree", workTreeDir,
 		"submodule", "deinit", "--all", "--force",
 	)
@@ -27,10 +68,11 @@ func deinitSubmodules(repoDir, workTreeDir string) error {
 	return nil
 }
 
-func syncSubmodules(repoDir, workTreeDir string) error {
+func syncSubmodules(ctx context.Context, repoDir, workTreeDir string) error {
 	fmt.Printf("Sync submodules in work tree `%s` ...\n", workTreeDir)
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		"git", "--git-dir", repoDir, "--work-tree", workTreeDir,
 		"submodule", "sync", "--recursive",
 	)
@@ -49,13 +91,19 @@ func syncSubmodules(repoDir, workTreeDir string) error {
 	return nil
 }
 
-func updateSubmodules(repoDir, workTreeDir string) error {
+func updateSubmodules(ctx context.Context, repoDir, workTreeDir string, opts SubmodulesOptions) error {
 	fmt.Printf("Update submodules in work tree `%s` ...\n", workTreeDir)
 
-	cmd := exec.Command(
-		"git", "--git-dir", repoDir, "--work-tree", workTreeDir,
+	gitArgs := []string{
+		"--git-dir", repoDir, "--work-tree", workTreeDir,
 		"submodule", "update", "--checkout", "--force", "--init", "--recursive",
-	)
+	}
+	if len(opts.Include) > 0 {
+		gitArgs = append(gitArgs, "--")
+		gitArgs = append(gitArgs, opts.Include...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
 
 	cmd.Dir = workTreeDir // required for `git submodule` to work
 
@@ -66,7 +114,186 @@ func updateSubmodules(repoDir, workTreeDir string) error {
 		return fmt.Errorf("`git submodule update` failed: %s\n%s", err, output.String())
 	}
 
+	if err := checkoutSubmodulesRevisions(ctx, workTreeDir, opts); err != nil {
+		return err
+	}
+
 	fmt.Printf("Update submodules in work tree `%s` OK\n", workTreeDir)
 
 	return nil
 }
+
+func checkoutSubmodulesRevisions(ctx context.Context, workTreeDir string, opts SubmodulesOptions) error {
+	var paths []string
+	for path := range opts.Revisions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if !opts.isSubmoduleIncluded(path) {
+			continue
+		}
+
+		revision := opts.Revisions[path]
+		submoduleWorkTreeDir := filepath.Join(workTreeDir, path)
+
+		fmt.Printf("Checkout submodule `%s` revision `%s` ...\n", path, revision)
+
+		err := checkoutSubmoduleRevision(ctx, submoduleWorkTreeDir, revision)
+		if err != nil {
+			fetchArgs := []string{"fetch", "--quiet", "origin"}
+			if source, ok := opts.RevisionsSources[path]; ok {
+				// HEAD of the source repo is the revision to checkout
+				fetchArgs = []string{"fetch", "--quiet", source, "HEAD"}
+			}
+
+			fetchCmd := exec.CommandContext(ctx, "git", fetchArgs...)
+			fetchCmd.Dir = submoduleWorkTreeDir
+			fetchOutput := setCommandRecordingLiveOutput(fetchCmd)
+			if err := fetchCmd.Run(); err != nil {
+				return fmt.Errorf("`git fetch` in submodule `%s` failed: %s\n%s", path, err, fetchOutput.String())
+			}
+
+			if err := checkoutSubmoduleRevision(ctx, submoduleWorkTreeDir, revision); err != nil {
+				return fmt.Errorf("cannot checkout submodule `%s` revision `%s`: %s", path, revision, err)
+			}
+		}
+
+		fmt.Printf("Checkout submodule `%s` revision `%s` OK\n", path, revision)
+	}
+
+	return nil
+}
+
+func checkoutSubmoduleRevision(ctx context.Context, submoduleWorkTreeDir, revision string) error {
+	cmd := exec.CommandContext(ctx, "git", "checkout", "--force", "--detach", revision)
+	cmd.Dir = submoduleWorkTreeDir
+
+	output := setCommandRecordingLiveOutput(cmd)
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("`git checkout` failed: %s\n%s", err, output.String())
+	}
+
+	return nil
+}
+
+func getSubmodulesPaths(ctx context.Context, workTreeDir string) ([]string, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"git", "config", "--file", filepath.Join(workTreeDir, ".gitmodules"),
+		"--get-regexp", "^submodule\\..*\\.path$",
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); ok && output.Len() == 0 {
+		// no submodules defined
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read `.gitmodules`: %s\n%s", err, output.String())
+	}
+
+	var paths []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(parts) == 2 {
+			paths = append(paths, parts[1])
+		}
+	}
+
+	return paths, nil
+}
+
+// getSubmodulesPathspecExcludes returns pathspecs for submodules which should not be diffed:
+// submodules that are not included and submodules with pinned revisions.
+func getSubmodulesPathspecExcludes(ctx context.Context, workTreeDir string, opts SubmodulesOptions) ([]string, error) {
+	if len(opts.Include) == 0 && len(opts.Revisions) == 0 {
+		return nil, nil
+	}
+
+	paths, err := getSubmodulesPaths(ctx, workTreeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var excludes []string
+	for _, path := range paths {
+		if !opts.isSubmoduleIncluded(path) || opts.isSubmodulePinned(path) {
+			excludes = append(excludes, fmt.Sprintf(":(exclude)%s", path))
+		}
+	}
+
+	return excludes, nil
+}
+
+// SubmoduleWorkTreeState describes top-level submodule of the project work tree,
+// which is checked out at another commit than recorded in the superproject commit or has uncommitted changes
+type SubmoduleWorkTreeState struct {
+	Path           string
+	Commit         string
+	WorkTreeCommit string
+	IsDirty        bool
+}
+
+func (s *SubmoduleWorkTreeState) IsDiverged() bool {
+	return s.Commit != s.WorkTreeCommit
+}
+
+// DivergedSubmodules returns states of the work tree submodules, which differ from the superproject commit.
+// Not initialized submodules are skipped.
+func DivergedSubmodules(ctx context.Context, gitDir, workTreeDir, commit string) ([]*SubmoduleWorkTreeState, error) {
+	paths, err := getSubmodulesPaths(ctx, workTreeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*SubmoduleWorkTreeState
+	for _, path := range paths {
+		submoduleWorkTreeDir := filepath.Join(workTreeDir, path)
+		if _, err := os.Stat(filepath.Join(submoduleWorkTreeDir, ".git")); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		recordedCommit, err := gitOutput(ctx, gitDir, "ls-tree", commit, "--", path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get submodule `%s` commit: %s", path, err)
+		}
+
+		// gitlink entry: 160000 commit SHA\tPATH
+		fields := strings.Fields(recordedCommit)
+		if len(fields) < 3 || fields[1] != "commit" {
+			continue
+		}
+
+		workTreeCommit, err := gitOutput(ctx, "", "-C", submoduleWorkTreeDir, "rev-parse", "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("cannot get submodule `%s` work tree commit: %s", path, err)
+		}
+
+		status, err := gitOutput(ctx, "", "-C", submoduleWorkTreeDir, "status", "--porcelain", "--untracked-files=no")
+		if err != nil {
+			return nil, fmt.Errorf("cannot get submodule `%s` work tree status: %s", path, err)
+		}
+
+		state := &SubmoduleWorkTreeState{
+			Path:           path,
+			Commit:         fields[2],
+			WorkTreeCommit: strings.TrimSpace(workTreeCommit),
+			IsDirty:        strings.TrimSpace(status) != "",
+		}
+
+		if state.IsDiverged() || state.IsDirty {
+			res = append(res, state)
+		}
+	}
+
+	return res, nil
+}
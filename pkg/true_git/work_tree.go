@@ -2,21 +2,22 @@ package true_git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 )
 
-func PrepareWorkTree(gitDir, workTreeDir string, commit string) error {
-	return prepareWorkTree(gitDir, workTreeDir, commit, false)
+func PrepareWorkTree(ctx context.Context, gitDir, workTreeDir string, commit string) error {
+	return prepareWorkTree(ctx, gitDir, workTreeDir, commit, false, SubmodulesOptions{})
 }
 
-func PrepareWorkTreeWithSubmodules(gitDir, workTreeDir string, commit string) error {
-	return prepareWorkTree(gitDir, workTreeDir, commit, true)
+func PrepareWorkTreeWithSubmodules(ctx context.Context, gitDir, workTreeDir string, commit string, submodulesOpts SubmodulesOptions) error {
+	return prepareWorkTree(ctx, gitDir, workTreeDir, commit, true, submodulesOpts)
 }
 
-func prepareWorkTree(gitDir, workTreeDir string, commit string, withSubmodules bool) error {
+func prepareWorkTree(ctx context.Context, gitDir, workTreeDir string, commit string, withSubmodules bool, submodulesOpts SubmodulesOptions) error {
 	var err error
 
 	gitDir, err = filepath.Abs(gitDir)
@@ -36,7 +37,7 @@ func prepareWorkTree(gitDir, workTreeDir string, commit string, withSubmodules b
 		}
 	}
 
-	err = switchWorkTree(gitDir, workTreeDir, commit)
+	err = switchWorkTree(ctx, gitDir, workTreeDir, commit)
 	if err != nil {
 		return fmt.Errorf("cannot reset work tree `%s` to commit `%s`: %s", workTreeDir, commit, err)
 	}
@@ -44,12 +45,12 @@ func prepareWorkTree(gitDir, workTreeDir string, commit string, withSubmodules b
 	if withSubmodules {
 		var err error
 
-		err = deinitSubmodules(gitDir, workTreeDir)
+		err = deinitSubmodules(ctx, gitDir, workTreeDir)
 		if err != nil {
 			return fmt.Errorf("cannot deinit submodules: %s", err)
 		}
 
-		err = updateSubmodules(gitDir, workTreeDir)
+		err = updateSubmodules(ctx, gitDir, workTreeDir, submodulesOpts)
 		if err != nil {
 			return fmt.Errorf("cannot update submodules: %s", err)
 		}
@@ -58,7 +59,7 @@ func prepareWorkTree(gitDir, workTreeDir string, commit string, withSubmodules b
 	return nil
 }
 
-func switchWorkTree(repoDir, workTreeDir string, commit string) error {
+func switchWorkTree(ctx context.Context, repoDir, workTreeDir string, commit string) error {
 	fmt.Printf("Switch work tree `%s` to commit `%s` ...\n", workTreeDir, commit)
 
 	var err error
@@ -71,7 +72,8 @@ func switchWorkTree(repoDir, workTreeDir string, commit string) error {
 	var cmd *exec.Cmd
 	var output *bytes.Buffer
 
-	cmd = exec.Command(
+	cmd = exec.CommandContext(
+		ctx,
 		"git", "--git-dir", repoDir, "--work-tree", workTreeDir,
 		"reset", "--hard", commit,
 	)
@@ -81,7 +83,8 @@ func switchWorkTree(repoDir, workTreeDir string, commit string) error {
 		return fmt.Errorf("git reset failed: %s\n%s", err, output.String())
 	}
 
-	cmd = exec.Command(
+	cmd = exec.CommandContext(
+		ctx,
 		"git", "--git-dir", repoDir, "--work-tree", workTreeDir,
 		"clean", "-d", "-f", "-f", "-x",
 	)
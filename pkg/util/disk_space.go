@@ -0,0 +1,22 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// existingParentDir returns the path or its nearest existing parent, so free space can be checked before the dir is created
+func existingParentDir(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,17 @@
+// +build linux darwin
+
+package util
+
+import (
+	"syscall"
+)
+
+// FreeDiskSpace returns number of bytes available to unprivileged user on the filesystem of the path
+func FreeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParentDir(path), &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,26 @@
+// +build windows
+
+package util
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// FreeDiskSpace returns number of bytes available to the user on the volume of the path
+func FreeDiskSpace(path string) (uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(existingParentDir(path))
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable uint64
+	res, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&freeBytesAvailable)), 0, 0)
+	if res == 0 {
+		return 0, err
+	}
+
+	return freeBytesAvailable, nil
+}
@@ -1,6 +1,9 @@
 package util
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -15,3 +18,24 @@ func DirExists(path string) (bool, error) {
 
 	return fileInfo.IsDir(), nil
 }
+
+// DirSize returns total size of regular files in the directory tree, files removed during the walk are skipped
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,45 @@
+package werf
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	globalContext, cancelGlobalContext = context.WithCancel(context.Background())
+	globalContextMutex                 sync.Mutex
+)
+
+// GetContext returns context that is done on termination signal or when global timeout is exceeded
+func GetContext() context.Context {
+	globalContextMutex.Lock()
+	defer globalContextMutex.Unlock()
+
+	return globalContext
+}
+
+func SetGlobalTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	globalContextMutex.Lock()
+	defer globalContextMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(globalContext, timeout)
+	cancelParent := cancelGlobalContext
+
+	globalContext = ctx
+	cancelGlobalContext = func() {
+		cancel()
+		cancelParent()
+	}
+}
+
+func Terminate() {
+	globalContextMutex.Lock()
+	defer globalContextMutex.Unlock()
+
+	cancelGlobalContext()
+}
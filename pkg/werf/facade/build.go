@@ -0,0 +1,78 @@
+package facade
+
+import (
+	"context"
+	"time"
+
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/ssh_agent"
+)
+
+// BuildOptions are options of Build operation, which is the same as werf build command
+type BuildOptions struct {
+	CommonOptions
+
+	// Images are names of werf.yaml images to build, all images are built by default
+	Images []string
+	// Platforms are target platforms os/arch[/variant], docker daemon platform by default
+	Platforms []string
+
+	// StagesRepo enables registry-backed stages cache, publish.stagesRepo from werf.yaml is used by default
+	StagesRepo string
+	// PullUsername and PullPassword authorize pull of base images
+	PullUsername string
+	PullPassword string
+
+	// StageTimeout and StageRetries are defaults for stages without stagesTimeout and stagesRetries in werf.yaml
+	StageTimeout time.Duration
+	StageRetries int
+
+	// ReadOnlyStages forbids building: stages should exist locally or in the stages repo
+	ReadOnlyStages bool
+	// RefreshBaseImages resolves digests of base images again instead of using pinned ones
+	RefreshBaseImages bool
+}
+
+// Build builds stages of werf.yaml images
+func Build(ctx context.Context, opts BuildOptions) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	p, err := openProject(opts.CommonOptions)
+	if err != nil {
+		return err
+	}
+	defer p.release()
+
+	stagesRepo := opts.StagesRepo
+	if stagesRepo == "" {
+		stagesRepo = p.Config.Meta.Publish.StagesRepo
+	}
+
+	var dockerAuthorizer *docker_authorizer.DockerAuthorizer
+	if stagesRepo != "" {
+		dockerAuthorizer, err = docker_authorizer.GetBPDockerAuthorizer(p.TmpDir, opts.PullUsername, opts.PullPassword, opts.PullUsername, opts.PullPassword, stagesRepo)
+	} else {
+		dockerAuthorizer, err = docker_authorizer.GetBuildDockerAuthorizer(p.TmpDir, opts.PullUsername, opts.PullPassword)
+	}
+	if err != nil {
+		return err
+	}
+
+	c := build.NewConveyor(p.Config, opts.Images, p.Dir, p.BuildDir, p.TmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
+	c.SetPlatforms(opts.Platforms)
+	if stagesRepo != "" {
+		c.SetStagesRepo(stagesRepo)
+	}
+
+	return c.Build(ctx, build.BuildOptions{
+		ImageBuildOptions: image.BuildOptions{
+			Timeout: opts.StageTimeout,
+			Retries: opts.StageRetries,
+		},
+		ReadOnlyStages:    opts.ReadOnlyStages,
+		RefreshBaseImages: opts.RefreshBaseImages,
+	})
+}
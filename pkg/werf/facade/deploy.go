@@ -0,0 +1,133 @@
+package facade
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flant/kubedog/pkg/kube"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/docker_authorizer"
+)
+
+// DeployOptions are options of Deploy operation, which is the same as werf deploy command
+type DeployOptions struct {
+	CommonOptions
+
+	// Repo is a docker repo of the deployed images, publish.repo from werf.yaml or CI_REGISTRY_IMAGE is used by default
+	Repo string
+	// Tag of the deployed images, latest by default
+	Tag string
+	// Username and Password authorize pull of the images by werf
+	Username string
+	Password string
+	// WithoutRegistry deploys chart without images info
+	WithoutRegistry bool
+
+	// Environment is used in release and namespace templates
+	Environment string
+	// Release and Namespace are generated by deploy.helmRelease and deploy.namespace of werf.yaml by default
+	Release   string
+	Namespace string
+	// KubeContext is a context of kube config, current context by default
+	KubeContext string
+	// ReleaseStorage is helm release storage settings
+	ReleaseStorage ReleaseStorageOptions
+
+	Values       []string
+	SecretValues []string
+	Set          []string
+	SetString    []string
+	Annotations  map[string]string
+
+	// Timeout of resources tracking, zero means no timeout
+	Timeout  time.Duration
+	Validate bool
+}
+
+// ReleaseStorageOptions are settings of the storage of helm releases
+type ReleaseStorageOptions struct {
+	// Type is configmap (default) or secret
+	Type string
+	// Namespace is tiller namespace, $TILLER_NAMESPACE or kube-system is used by default
+	Namespace string
+}
+
+// Deploy installs or upgrades helm release of the project chart.
+// Context is checked before the release is started: the started release is not interrupted.
+func Deploy(ctx context.Context, opts DeployOptions) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	p, err := openProject(opts.CommonOptions)
+	if err != nil {
+		return err
+	}
+	defer p.release()
+
+	if err := deploy.Init(); err != nil {
+		return err
+	}
+
+	if err := deploy.SetReleaseStorage(deploy.ReleaseStorageOptions{
+		Type:      opts.ReleaseStorage.Type,
+		Namespace: opts.ReleaseStorage.Namespace,
+	}); err != nil {
+		return err
+	}
+
+	var repo string
+	if !opts.WithoutRegistry {
+		repo, err = config.GetRequiredRepoName(p.Config, opts.Repo)
+		if err != nil {
+			return err
+		}
+
+		dockerAuthorizer, err := docker_authorizer.GetDeployDockerAuthorizer(p.TmpDir, opts.Username, opts.Password, repo)
+		if err != nil {
+			return err
+		}
+
+		if err := dockerAuthorizer.Login(repo); err != nil {
+			return fmt.Errorf("docker login failed: %s", err)
+		}
+	}
+
+	tag := opts.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	if err := kube.Init(kube.InitOptions{KubeContext: opts.KubeContext}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	release, err := deploy.GetHelmRelease(opts.Release, opts.Environment, p.Config)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := deploy.GetKubernetesNamespace(opts.Namespace, opts.Environment, p.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return deploy.RunDeploy(p.Dir, repo, tag, release, namespace, p.Config, deploy.DeployOptions{
+		Environment:     opts.Environment,
+		Values:          opts.Values,
+		SecretValues:    opts.SecretValues,
+		Set:             opts.Set,
+		SetString:       opts.SetString,
+		Timeout:         opts.Timeout,
+		WithoutRegistry: opts.WithoutRegistry,
+		Validate:        opts.Validate,
+		Annotations:     opts.Annotations,
+		KubeContext:     opts.KubeContext,
+	})
+}
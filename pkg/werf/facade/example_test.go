@@ -0,0 +1,40 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flant/werf/pkg/werf/facade"
+)
+
+// Build and push images of the project, then deploy the project chart with the pushed images
+func Example() {
+	ctx := context.Background()
+	common := facade.CommonOptions{ProjectDir: "/path/to/project"}
+
+	if err := facade.Build(ctx, facade.BuildOptions{CommonOptions: common}); err != nil {
+		fmt.Printf("build failed: %s\n", err)
+		return
+	}
+
+	if err := facade.Push(ctx, facade.PushOptions{
+		CommonOptions: common,
+		Repo:          "registry.example.com/project",
+		Tags:          []string{"v1.0.0"},
+	}); err != nil {
+		fmt.Printf("push failed: %s\n", err)
+		return
+	}
+
+	if err := facade.Deploy(ctx, facade.DeployOptions{
+		CommonOptions:  common,
+		Repo:           "registry.example.com/project",
+		Tag:            "v1.0.0",
+		Environment:    "production",
+		ReleaseStorage: facade.ReleaseStorageOptions{Type: "secret"},
+		Timeout:        10 * time.Minute,
+	}); err != nil {
+		fmt.Printf("deploy failed: %s\n", err)
+	}
+}
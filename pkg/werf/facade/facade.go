@@ -0,0 +1,155 @@
+// Package facade is a Go API of werf for tools, which embed werf instead of running werf cli.
+// All options are passed explicitly: werf cli options are not used, environment variables are used only where werf itself reads them (e.g. WERF_HOME).
+// Werf keeps state in process globals, so calls are serialized.
+package facade
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/lock"
+	"github.com/flant/werf/pkg/logger"
+	"github.com/flant/werf/pkg/project_tmp_dir"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var mutex sync.Mutex
+
+// CommonOptions are options of all werf operations
+type CommonOptions struct {
+	// ProjectDir is a dir with werf.yaml, required
+	ProjectDir string
+	// ConfigPath is a path of werf config relative to ProjectDir, werf.yaml or werf.yml is used by default
+	ConfigPath string
+	// ConfigOverlays are local files (relative to the project dir) or https urls of config overlays, which are merged over werf config in the specified order
+	ConfigOverlays []string
+
+	// HomeDir is ~/.werf by default
+	HomeDir string
+	// TmpDir is system tmp dir by default
+	TmpDir string
+
+	// ContainerRuntime is docker (default) or buildah
+	ContainerRuntime string
+
+	// SSHKeys are used for remote git repos instead of the system ssh agent
+	SSHKeys []string
+	// KnownHosts and KnownHostsFiles are used to check host keys of remote git repos in addition to the system known_hosts files
+	KnownHosts      []string
+	KnownHostsFiles []string
+	// SkipStrictHostKeyChecking allows hosts, which keys are not in known_hosts
+	SkipStrictHostKeyChecking bool
+}
+
+// project is an initialized werf project, release must be called when operation is finished
+type project struct {
+	Dir             string
+	Config          *config.WerfConfig
+	BuildDir        string
+	TmpDir          string
+	tmpDirAcquired  bool
+	sshAgentStarted bool
+}
+
+func openProject(opts CommonOptions) (*project, error) {
+	if opts.ProjectDir == "" {
+		return nil, fmt.Errorf("project dir required")
+	}
+
+	if err := werf.Init(opts.TmpDir, opts.HomeDir); err != nil {
+		return nil, fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := lock.Init(); err != nil {
+		return nil, err
+	}
+
+	if err := true_git.Init(); err != nil {
+		return nil, err
+	}
+
+	if err := docker.SetContainerRuntime(opts.ContainerRuntime); err != nil {
+		return nil, err
+	}
+
+	if err := docker.Init(docker_authorizer.GetHomeDockerConfigDir()); err != nil {
+		return nil, err
+	}
+
+	if _, err := werf.InitProjectHome(opts.ProjectDir); err != nil {
+		return nil, err
+	}
+
+	p := &project{Dir: opts.ProjectDir}
+
+	var err error
+	p.Config, err = config.GetWerfConfig(opts.ProjectDir, opts.ConfigPath, opts.ConfigOverlays...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse werf config: %s", err)
+	}
+
+	p.initRegistriesOptions()
+
+	p.BuildDir, err = werf.GetProjectBuildDir(p.Config.Meta.Project)
+	if err != nil {
+		return nil, fmt.Errorf("getting project build dir failed: %s", err)
+	}
+
+	p.TmpDir, err = project_tmp_dir.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	p.tmpDirAcquired = true
+
+	hostKeyCheckingOptions := ssh_agent.HostKeyCheckingOptions{
+		KnownHosts:            opts.KnownHosts,
+		KnownHostsFiles:       opts.KnownHostsFiles,
+		StrictHostKeyChecking: !opts.SkipStrictHostKeyChecking,
+	}
+	if err := ssh_agent.Init(opts.SSHKeys, hostKeyCheckingOptions); err != nil {
+		p.release()
+		return nil, fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	p.sshAgentStarted = true
+
+	return p, nil
+}
+
+func (p *project) release() {
+	if p.sshAgentStarted {
+		if err := ssh_agent.Terminate(); err != nil {
+			logger.LogWarningF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}
+
+	if p.tmpDirAcquired {
+		project_tmp_dir.Release(p.TmpDir)
+	}
+}
+
+// initRegistriesOptions configures registries connection settings from werf.yaml registries
+func (p *project) initRegistriesOptions() {
+	options := map[string]docker_registry.RegistryOptions{}
+
+	for _, registry := range p.Config.Meta.Registries {
+		caFile := registry.CAFile
+		if caFile != "" && !filepath.IsAbs(caFile) {
+			caFile = filepath.Join(p.Dir, caFile)
+		}
+
+		options[registry.Address] = docker_registry.RegistryOptions{
+			Insecure:      registry.Insecure,
+			SkipTLSVerify: registry.SkipTLSVerify,
+			CAFile:        caFile,
+		}
+	}
+
+	docker_registry.SetRegistriesOptions(options)
+}
@@ -0,0 +1,23 @@
+package facade
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOperations_projectDirRequired(t *testing.T) {
+	operations := map[string]func() error{
+		"build":  func() error { return Build(context.Background(), BuildOptions{}) },
+		"push":   func() error { return Push(context.Background(), PushOptions{}) },
+		"deploy": func() error { return Deploy(context.Background(), DeployOptions{}) },
+	}
+
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			err := operation()
+			if err == nil || err.Error() != "project dir required" {
+				t.Errorf("\n[EXPECTED]: project dir required\n[GOT]: %v", err)
+			}
+		})
+	}
+}
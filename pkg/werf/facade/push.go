@@ -0,0 +1,75 @@
+package facade
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/docker_authorizer"
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/ssh_agent"
+)
+
+// PushOptions are options of Push operation, which is the same as werf push command
+type PushOptions struct {
+	CommonOptions
+
+	// Images are names of werf.yaml images to push, all images are pushed by default
+	Images []string
+	// Platforms are platforms os/arch[/variant] of the built images, docker daemon platform by default
+	Platforms []string
+
+	// Repo is a docker repo to push images into, publish.repo from werf.yaml or CI_REGISTRY_IMAGE is used by default
+	Repo string
+	// Tags of the pushed images, latest by default
+	Tags []string
+	// PushUsername and PushPassword authorize push into the repo
+	PushUsername string
+	PushPassword string
+
+	// WithStages pushes stages cache of the images too
+	WithStages bool
+
+	// Labels are added to the pushed images in addition to the labels from werf.yaml
+	Labels map[string]string
+}
+
+// Push pushes built images into the docker repo
+func Push(ctx context.Context, opts PushOptions) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	p, err := openProject(opts.CommonOptions)
+	if err != nil {
+		return err
+	}
+	defer p.release()
+
+	repo, err := config.GetRequiredRepoName(p.Config, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	tagOpts := build.TagOptions{Tags: opts.Tags}
+	if len(tagOpts.Tags) == 0 {
+		tagOpts.Tags = []string{"latest"}
+	}
+
+	for _, tag := range tagOpts.Tags {
+		if err := slug.ValidateDockerTag(tag); err != nil {
+			return fmt.Errorf("bad tag '%s': %s", tag, err)
+		}
+	}
+
+	dockerAuthorizer, err := docker_authorizer.GetPushDockerAuthorizer(p.TmpDir, opts.PushUsername, opts.PushPassword, repo)
+	if err != nil {
+		return err
+	}
+
+	c := build.NewConveyor(p.Config, opts.Images, p.Dir, p.BuildDir, p.TmpDir, ssh_agent.SSHAuthSock, dockerAuthorizer)
+	c.SetPlatforms(opts.Platforms)
+	c.SetLabels(opts.Labels)
+
+	return c.Push(ctx, repo, build.PushOptions{TagOptions: tagOpts, WithStages: opts.WithStages})
+}
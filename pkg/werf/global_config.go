@@ -0,0 +1,97 @@
+package werf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/flant/werf/pkg/git_auth"
+	"github.com/flant/werf/pkg/slug"
+)
+
+// GlobalConfig is an optional host-wide werf config stored in werf home dir (~/.werf/config.yaml by default)
+type GlobalConfig struct {
+	Naming *slug.RulesPolicyConfig `yaml:"naming,omitempty"`
+	// HomeQuota limits disk usage of werf home dir, e.g. 10GiB; least recently used clones and worktrees are evicted before builds when quota is exceeded
+	HomeQuota string `yaml:"homeQuota,omitempty"`
+	// GitCredentials are used for remote git repos with matching url prefix, netrc file is used for other http(s) repos
+	GitCredentials []git_auth.CredentialsConfig `yaml:"gitCredentials,omitempty"`
+}
+
+var homeQuota int64
+
+// GetHomeQuota returns werf home quota in bytes from WERF_HOME_QUOTA or global config, 0 means no quota
+func GetHomeQuota() int64 {
+	return homeQuota
+}
+
+func GetGlobalConfigPath() string {
+	if val, ok := os.LookupEnv("WERF_GLOBAL_CONFIG"); ok {
+		return val
+	}
+
+	return filepath.Join(GetHomeDir(), "config.yaml")
+}
+
+func loadGlobalConfig() error {
+	path := GetGlobalConfigPath()
+
+	homeQuota = 0
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return loadHomeQuota("")
+	} else if err != nil {
+		return err
+	}
+
+	config := &GlobalConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return fmt.Errorf("bad global config %s: %s", path, err)
+	}
+
+	if config.Naming != nil {
+		policy, err := slug.NewRulesPolicy(*config.Naming)
+		if err != nil {
+			return fmt.Errorf("bad naming section of global config %s: %s", path, err)
+		}
+
+		slug.SetPolicy(policy)
+	}
+
+	if err := loadHomeQuota(config.HomeQuota); err != nil {
+		return fmt.Errorf("bad homeQuota of global config %s: %s", path, err)
+	}
+
+	if err := git_auth.SetConfig(config.GitCredentials); err != nil {
+		return fmt.Errorf("bad gitCredentials section of global config %s: %s", path, err)
+	}
+
+	return nil
+}
+
+func loadHomeQuota(value string) error {
+	if val, ok := os.LookupEnv("WERF_HOME_QUOTA"); ok {
+		value = val
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	quota, err := units.RAMInBytes(value)
+	if err != nil {
+		return fmt.Errorf("bad werf home quota '%s': %s", value, err)
+	}
+	if quota < 0 {
+		return fmt.Errorf("bad werf home quota '%s': positive size expected", value)
+	}
+
+	homeQuota = quota
+
+	return nil
+}
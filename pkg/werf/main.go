@@ -21,6 +21,17 @@ func GetHomeDir() string {
 	return homeDir
 }
 
+// GetProjectBuildDir returns project dir for build artifacts in werf home, dir is created if not exists
+func GetProjectBuildDir(projectName string) (string, error) {
+	projectBuildDir := filepath.Join(GetHomeDir(), "builds", projectName)
+
+	if err := os.MkdirAll(projectBuildDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	return projectBuildDir, nil
+}
+
 func GetTmpDir() string {
 	if tmpDir == "" {
 		panic("bug: init required!")
@@ -46,5 +57,12 @@ func Init(tmpDirOption, homeDirOption string) error {
 		homeDir = filepath.Join(os.Getenv("HOME"), ".werf")
 	}
 
+	baseHomeDir = homeDir
+	projectHomeDir = ""
+
+	if err := loadGlobalConfig(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,20 @@
+package werf
+
+import "fmt"
+
+// offline forbids network operations: remote git repos are neither cloned nor fetched, base images are not pulled,
+// files are not downloaded and images are not pushed, so only data available on the host is used
+var offline bool
+
+func SetOffline(value bool) {
+	offline = value
+}
+
+func IsOffline() bool {
+	return offline
+}
+
+// OfflineError describes network operation, which cannot be done in offline mode
+func OfflineError(operation string) error {
+	return fmt.Errorf("%s is not allowed in offline mode", operation)
+}
@@ -0,0 +1,96 @@
+package werf
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const projectDirMarkerFile = "project_dir"
+
+var (
+	projectHomeEnabled bool
+	baseHomeDir        string
+	projectHomeDir     string
+)
+
+// SetProjectHomeEnabled makes werf store caches, locks and tmp files of the project in the project subtree of werf home,
+// so that werf processes of unrelated projects do not share locks and cache entries
+func SetProjectHomeEnabled(enabled bool) {
+	projectHomeEnabled = enabled
+}
+
+func IsProjectHomeEnabled() bool {
+	return projectHomeEnabled
+}
+
+// GetBaseHomeDir returns werf home dir, which is shared by all projects: global config and secret key are stored there
+func GetBaseHomeDir() string {
+	if baseHomeDir == "" {
+		panic("bug: init required!")
+	}
+
+	return baseHomeDir
+}
+
+func GetProjectsHomeDir() string {
+	return filepath.Join(GetBaseHomeDir(), "projects")
+}
+
+// GetProjectHomeDir returns project subtree of werf home, which is named by hash of the absolute project dir path
+func GetProjectHomeDir(projectDir string) (string, error) {
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", fmt.Errorf("bad project dir `%s`: %s", projectDir, err)
+	}
+
+	return filepath.Join(GetProjectsHomeDir(), fmt.Sprintf("%x", sha256.Sum256([]byte(absProjectDir)))[:16]), nil
+}
+
+// PrepareProjectHomeDir creates project subtree of werf home and saves project dir path into the subtree to find out the project later
+func PrepareProjectHomeDir(projectDir string) (string, error) {
+	dir, err := GetProjectHomeDir(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	markerPath := filepath.Join(dir, projectDirMarkerFile)
+	if err := ioutil.WriteFile(markerPath, []byte(absProjectDir+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("cannot write %s: %s", markerPath, err)
+	}
+
+	return dir, nil
+}
+
+// InitProjectHome switches werf home to the project subtree if project home is enabled.
+// Returns true if werf home has been switched, so that state based on the werf home should be initialized again.
+func InitProjectHome(projectDir string) (bool, error) {
+	if !projectHomeEnabled || baseHomeDir == "" {
+		return false, nil
+	}
+
+	dir, err := PrepareProjectHomeDir(projectDir)
+	if err != nil {
+		return false, fmt.Errorf("cannot prepare project werf home: %s", err)
+	}
+
+	if dir == projectHomeDir {
+		return false, nil
+	}
+
+	projectHomeDir = dir
+	homeDir = dir
+
+	return true, nil
+}
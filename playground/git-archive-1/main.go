@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/flant/werf/pkg/true_git"
@@ -17,7 +18,7 @@ func main() {
 		panic(err)
 	}
 
-	_, err = true_git.ArchiveWithSubmodules(p, os.Args[1], "my-work-tree", true_git.ArchiveOptions{
+	_, err = true_git.ArchiveWithSubmodules(context.Background(), p, os.Args[1], "my-work-tree", true_git.ArchiveOptions{
 		Commit:     os.Args[2],
 		PathFilter: true_git.PathFilter{},
 	})
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -18,7 +19,7 @@ func main() {
 		panic(err)
 	}
 
-	p, err := true_git.PatchWithSubmodules(f, os.Args[1], "my-work-tree", true_git.PatchOptions{
+	p, err := true_git.PatchWithSubmodules(context.Background(), f, os.Args[1], "my-work-tree", true_git.PatchOptions{
 		FromCommit: os.Args[2],
 		ToCommit:   os.Args[3],
 		PathFilter: true_git.PathFilter{},
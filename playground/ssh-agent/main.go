@@ -11,7 +11,7 @@ import (
 func main() {
 	fmt.Printf("keys: %v\n", os.Args[1:])
 
-	err := ssh_agent.Init(os.Args[1:])
+	err := ssh_agent.Init(os.Args[1:], ssh_agent.HostKeyCheckingOptions{StrictHostKeyChecking: true})
 	if err != nil {
 		panic(err)
 	}